@@ -0,0 +1,127 @@
+package dbdiff
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// ANNOTATIONS - Reviewer comments attached to findings
+// ============================================================================
+
+// Annotation is a reviewer comment on a finding. Key is a Finding.Key() such
+// as "column:users.email" and may contain glob wildcards
+// ("index:audit_*.*") to cover several findings at once.
+type Annotation struct {
+	Key     string `json:"key"`
+	Comment string `json:"comment"`
+	Author  string `json:"author,omitempty"`
+	Created string `json:"created,omitempty"`
+}
+
+// AnnotationFile is the on-disk format of --annotations
+type AnnotationFile struct {
+	Annotations []*Annotation `json:"annotations"`
+}
+
+// LoadAnnotations reads an annotation file; a missing file yields an empty set
+func LoadAnnotations(path string) (*AnnotationFile, error) {
+	data, err := readTextFile(path)
+	if os.IsNotExist(err) {
+		return &AnnotationFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var af AnnotationFile
+	if err := json.Unmarshal(data, &af); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &af, nil
+}
+
+// Save writes the annotation file with stable ordering
+func (af *AnnotationFile) Save(path string) error {
+	sort.SliceStable(af.Annotations, func(i, j int) bool { return af.Annotations[i].Key < af.Annotations[j].Key })
+	data, err := json.MarshalIndent(af, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Lookup returns the annotation for a finding; exact keys win over globs
+func (af *AnnotationFile) Lookup(f Finding) *Annotation {
+	key := f.Key()
+	var glob *Annotation
+	for _, a := range af.Annotations {
+		if a.Key == key {
+			return a
+		}
+		if ok, _ := path.Match(a.Key, key); ok && glob == nil {
+			glob = a
+		}
+	}
+	return glob
+}
+
+// Apply attaches the annotations matching the diff's findings to the diff
+func (af *AnnotationFile) Apply(diff *SchemaDiff) {
+	diff.Annotations = nil
+	for _, f := range NewResult(diff).Findings() {
+		if a := af.Lookup(f); a != nil {
+			diff.Annotations = append(diff.Annotations, &Annotation{Key: f.Key(), Comment: a.Comment, Author: a.Author, Created: a.Created})
+		}
+	}
+}
+
+// Annotate interactively asks for a comment on every finding that has none
+// yet. An empty answer skips the finding.
+func (af *AnnotationFile) Annotate(diff *SchemaDiff, in io.Reader, out io.Writer) {
+	author := currentUser()
+	scanner := bufio.NewScanner(in)
+	for _, f := range NewResult(diff).Findings() {
+		if af.Lookup(f) != nil {
+			continue
+		}
+		fmt.Fprintf(out, "%s\n  comment (enter to skip): ", describeFinding(f))
+		if !scanner.Scan() {
+			return
+		}
+		comment := strings.TrimSpace(scanner.Text())
+		if comment == "" {
+			continue
+		}
+		af.Annotations = append(af.Annotations, &Annotation{
+			Key:     f.Key(),
+			Comment: comment,
+			Author:  author,
+			Created: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// currentUser is the login name of whoever runs dbdiff, for annotations and
+// audit records
+func currentUser() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return os.Getenv("USERNAME")
+}
+
+// annotationIndex maps finding keys to comments for report rendering
+func annotationIndex(diff *SchemaDiff) map[string]string {
+	notes := make(map[string]string)
+	for _, a := range diff.Annotations {
+		notes[a.Key] = a.Comment
+	}
+	return notes
+}
@@ -0,0 +1,184 @@
+package dbdiff
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ============================================================================
+// APPEND-ONLY TABLES - Event stores and logs that must only grow
+// ============================================================================
+
+// AppendOnlyWarning is a destructive change to a table configured as
+// append-only: dropping it, dropping or retyping a column, or changing its
+// primary key or partitioning loses or rewrites history
+type AppendOnlyWarning struct {
+	Table   string `json:"table"`
+	Warning string `json:"warning"`
+}
+
+// isAppendOnly reports whether a table matches one of the append_only
+// patterns of the config file
+func isAppendOnly(table string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, table); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendOnlyWarnings lists the destructive changes diff makes to the tables
+// matching patterns, going from source to target
+func AppendOnlyWarnings(diff *SchemaDiff, patterns []string) []*AppendOnlyWarning {
+	var warnings []*AppendOnlyWarning
+	warn := func(table, format string, args ...any) {
+		warnings = append(warnings, &AppendOnlyWarning{Table: table, Warning: fmt.Sprintf(format, args...)})
+	}
+	for _, table := range diff.TablesOnlyInSource {
+		if isAppendOnly(table, patterns) {
+			warn(table, "table is dropped")
+		}
+	}
+	for _, td := range diff.TableDiffs {
+		if !isAppendOnly(td.TableName, patterns) {
+			continue
+		}
+		for _, col := range td.ColumnsOnlyInSource {
+			warn(td.TableName, "column %s is dropped", col)
+		}
+		for _, cd := range td.ColumnDiffs {
+			for _, c := range cd.Changes {
+				switch c.Attribute {
+				case "type", "generated", "length", "precision", "scale":
+					warn(td.TableName, "column %s changes %s", cd.ColumnName, c)
+				}
+			}
+		}
+		if td.PrimaryKeyDiff != nil {
+			warn(td.TableName, "primary key changes: %s", *td.PrimaryKeyDiff)
+		}
+		if td.PartitioningDiff != nil {
+			warn(td.TableName, "partitioning changes: %s", *td.PartitioningDiff)
+		}
+		if td.InheritanceDiff != nil {
+			warn(td.TableName, "inheritance changes: %s", *td.InheritanceDiff)
+		}
+	}
+	return warnings
+}
+
+// AppendOnlyRows compares an append-only table by its row count and the
+// highest value of its single-column primary key: rows are never updated
+// or deleted, so the two sides hold the same data up to the lower key
+type AppendOnlyRows struct {
+	Table        string `json:"table"`
+	SourceRows   int64  `json:"source_rows"`
+	TargetRows   int64  `json:"target_rows"`
+	SourceMaxKey string `json:"source_max_key,omitempty"`
+	TargetMaxKey string `json:"target_max_key,omitempty"`
+}
+
+// Match reports whether both sides hold as many rows up to the same key
+func (r *AppendOnlyRows) Match() bool {
+	return r.SourceRows == r.TargetRows && r.SourceMaxKey == r.TargetMaxKey
+}
+
+// CompareAppendOnlyRows counts the rows and reads the highest primary key of
+// the append-only tables present on both sides. Tables without a
+// single-column primary key are compared by count only.
+func CompareAppendOnlyRows(sourceDriver, sourceConn, targetDriver, targetConn string, source, target *Schema, patterns []string) ([]*AppendOnlyRows, error) {
+	var tables []string
+	for _, name := range getSortedKeys(source.Tables) {
+		if target.Tables[name] != nil && isAppendOnly(name, patterns) {
+			tables = append(tables, name)
+		}
+	}
+	if len(tables) == 0 {
+		return nil, nil
+	}
+
+	type stats struct {
+		rows   int64
+		maxKey string
+	}
+	read := func(driver, conn string, schema *Schema) (map[string]stats, error) {
+		family := engineFamily(driver)
+		if family != "postgres" && family != "mysql" {
+			return nil, fmt.Errorf("comparing append-only rows is not supported for %s", driver)
+		}
+		quote := func(name string) string {
+			if family == "postgres" {
+				return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+			}
+			return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+		}
+		db, err := openDB(driver, conn)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+
+		result := make(map[string]stats, len(tables))
+		for _, name := range tables {
+			maxKey := "NULL"
+			if pk := schema.Tables[name].PrimaryKey; pk != nil && len(pk.Columns) == 1 {
+				maxKey = "MAX(" + quote(pk.Columns[0]) + ")"
+			}
+			var s stats
+			var key sql.NullString
+			query := fmt.Sprintf("SELECT COUNT(*), %s FROM %s", maxKey, quote(name))
+			if err := db.QueryRow(query).Scan(&s.rows, &key); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			s.maxKey = key.String
+			result[name] = s
+		}
+		return result, nil
+	}
+	sourceStats, err := read(sourceDriver, sourceConn, source)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	targetStats, err := read(targetDriver, targetConn, target)
+	if err != nil {
+		return nil, fmt.Errorf("target: %w", err)
+	}
+
+	var rows []*AppendOnlyRows
+	for _, name := range tables {
+		src, tgt := sourceStats[name], targetStats[name]
+		rows = append(rows, &AppendOnlyRows{
+			Table: name, SourceRows: src.rows, TargetRows: tgt.rows, SourceMaxKey: src.maxKey, TargetMaxKey: tgt.maxKey,
+		})
+	}
+	return rows, nil
+}
+
+// printAppendOnly lists the destructive changes to append-only tables and
+// the row comparisons that don't match
+func printAppendOnly(w io.Writer, warnings []*AppendOnlyWarning, rows []*AppendOnlyRows) {
+	var mismatched []*AppendOnlyRows
+	for _, r := range rows {
+		if !r.Match() {
+			mismatched = append(mismatched, r)
+		}
+	}
+	if len(warnings) == 0 && len(mismatched) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n⚠️  Append-only tables:")
+	for _, aw := range warnings {
+		fmt.Fprintf(w, "  ! %s: %s\n", aw.Table, aw.Warning)
+	}
+	for _, r := range mismatched {
+		line := fmt.Sprintf("  ~ %s: rows %d → %d", r.Table, r.SourceRows, r.TargetRows)
+		if r.SourceMaxKey != r.TargetMaxKey {
+			line += fmt.Sprintf(", max key %s → %s", firstNonEmpty(r.SourceMaxKey, "none"), firstNonEmpty(r.TargetMaxKey, "none"))
+		}
+		fmt.Fprintln(w, line)
+	}
+}
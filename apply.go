@@ -0,0 +1,637 @@
+package dbdiff
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// ============================================================================
+// APPLY - Run a reviewed migration with live progress
+// ============================================================================
+
+// ApplyOptions configures ApplyMigration
+type ApplyOptions struct {
+	// ProgressInterval is how often a running statement's lock waits are
+	// reported; 0 disables monitoring
+	ProgressInterval time.Duration
+	// Pause asks on In before every statement
+	Pause bool
+	// PauseFile holds back the next statement while the file exists, so that
+	// an apply can be paused from another shell
+	PauseFile string
+	// LockTimeout bounds how long a statement waits for a lock, and
+	// StatementTimeout how long it runs (Postgres only); 0 leaves the
+	// server's setting
+	LockTimeout      time.Duration
+	StatementTimeout time.Duration
+	// Retries is how often a statement that timed out waiting for a lock is
+	// retried, waiting RetryBackoff before the first retry and twice as long
+	// before each next one
+	Retries      int
+	RetryBackoff time.Duration
+	// State, if set, skips the statements it records as applied and records
+	// every statement applied, saving to StatePath after each one
+	State     *ApplyState
+	StatePath string
+	In        io.Reader
+	Out       io.Writer
+}
+
+// ApplyState is the on-disk record of the statements an apply has run, so
+// that an interrupted apply can be resumed
+type ApplyState struct {
+	// Driver and Target (its password redacted) name the database the
+	// statements were applied to; see Bind
+	Driver  string              `json:"driver,omitempty"`
+	Target  string              `json:"target,omitempty"`
+	Applied []*AppliedStatement `json:"applied"`
+}
+
+// AppliedStatement is one statement of an ApplyState
+type AppliedStatement struct {
+	// Key identifies the statement by content; see statementKeys
+	Key       string `json:"key"`
+	Statement string `json:"statement"`
+	AppliedAt string `json:"applied_at"`
+}
+
+// LoadApplyState reads a state file; a missing file is an empty state
+func LoadApplyState(path string) (*ApplyState, error) {
+	data, err := readTextFile(path)
+	if os.IsNotExist(err) {
+		return &ApplyState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st ApplyState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &st, nil
+}
+
+// Save writes the state file through a temporary file, so that a crash
+// while saving cannot lose the record of what was applied
+func (st *ApplyState) Save(path string) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Bind ties the state to the database it is applied to. A state that has
+// recorded statements for another database is refused unless force is set,
+// since skipping them there would leave the migration half applied.
+func (st *ApplyState) Bind(driver, conn string, force bool) error {
+	target := redactDSN(conn)
+	if len(st.Applied) > 0 && !force && (st.Driver != driver || st.Target != target) {
+		return fmt.Errorf("state was recorded for %s %s, not %s %s (use --force to resume anyway)",
+			orNone(st.Driver), orNone(st.Target), driver, target)
+	}
+	st.Driver, st.Target = driver, target
+	return nil
+}
+
+// lookup returns the record of an applied statement, or nil
+func (st *ApplyState) lookup(key string) *AppliedStatement {
+	for _, a := range st.Applied {
+		if a.Key == key {
+			return a
+		}
+	}
+	return nil
+}
+
+// statementKeys identifies statements by a hash of their text, so that a
+// script edited after an interrupted apply (a statement fixed or added)
+// still resumes correctly. Repeated statements are told apart by their
+// occurrence.
+func statementKeys(stmts []string) []string {
+	keys := make([]string, len(stmts))
+	seen := make(map[string]int)
+	for i, stmt := range stmts {
+		sum := sha256.Sum256([]byte(stmt))
+		hash := hex.EncodeToString(sum[:8])
+		seen[hash]++
+		keys[i] = fmt.Sprintf("%s#%d", hash, seen[hash])
+	}
+	return keys
+}
+
+// LockWait is a session blocking a running statement
+type LockWait struct {
+	// Lock describes what is waited for, e.g. Lock:relation
+	Lock string
+	// Waiting is how long the statement has waited so far
+	Waiting time.Duration
+	// BlockingSession is the backend or connection ID holding the lock
+	BlockingSession int64
+	// BlockingQuery is the state and last query of the blocking session
+	BlockingQuery string
+}
+
+// applyMonitor is implemented by dialects that can report on a running
+// migration: the session it runs in, the rows a statement will touch and
+// the locks it waits for
+type applyMonitor interface {
+	sessionID(ctx context.Context, conn *sql.Conn) (int64, error)
+	estimateRows(ctx context.Context, db *sql.DB, table string) (int64, error)
+	lockWaits(ctx context.Context, db *sql.DB, session int64) ([]LockWait, error)
+}
+
+// applyGuard is implemented by dialects that can bound the lock waits and
+// run time of migration statements, so that a statement queued behind a
+// long-running transaction gives up instead of blocking everything queued
+// behind it in turn
+type applyGuard interface {
+	setTimeouts(ctx context.Context, conn *sql.Conn, lockTimeout, statementTimeout time.Duration) error
+	isLockTimeout(err error) bool
+}
+
+// migrationTablePattern finds the table a migration statement works on
+var migrationTablePattern = regexp.MustCompile(`(?is)^\s*(?:ALTER\s+TABLE(?:\s+ONLY)?(?:\s+IF\s+EXISTS)?|CREATE\s+(?:UNIQUE\s+)?INDEX(?:\s+CONCURRENTLY)?(?:\s+IF\s+NOT\s+EXISTS)?(?:\s+\S+)?\s+ON(?:\s+ONLY)?|DROP\s+TABLE(?:\s+IF\s+EXISTS)?|UPDATE|DELETE\s+FROM|INSERT\s+INTO)\s+([^\s(;,]+)`)
+
+// MigrationStatements splits a migration script into its statements,
+// leaving out comments, so that the scaffolding lines --migration emits as
+// comments are never run
+func MigrationStatements(script, driver string) []string {
+	var stmts []string
+	for _, stmt := range splitDDLStatements(script, engineFamily(driver) == "mysql") {
+		if stmt = stripSQLComments(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+// stripSQLComments drops the whole-line comments around a statement
+func stripSQLComments(stmt string) string {
+	var lines []string
+	for _, line := range strings.Split(stmt, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// statementSummary is the first line of a statement, shortened for progress
+func statementSummary(stmt string) string {
+	line, _, more := strings.Cut(stmt, "\n")
+	if runes := []rune(line); len(runes) > 100 {
+		line, more = string(runes[:100]), true
+	}
+	if more {
+		line += " …"
+	}
+	return line
+}
+
+// ApplyMigration runs statements one at a time on a single connection,
+// reporting each statement's duration and affected rows on opts.Out, the
+// estimated size of the table it works on and, while it runs, the sessions
+// blocking it. It returns the number of statements it ran, not counting those
+// opts.State records as applied before; on error the statements after the
+// failing one are not run.
+func ApplyMigration(ctx context.Context, driver string, db *sql.DB, stmts []string, opts ApplyOptions) (int, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	monitor, _ := getDialect(driver).(applyMonitor)
+	var session int64
+	if monitor != nil {
+		if session, err = monitor.sessionID(ctx, conn); err != nil {
+			return 0, fmt.Errorf("reading session id: %w", err)
+		}
+	}
+	guard, _ := getDialect(driver).(applyGuard)
+	if opts.LockTimeout > 0 || opts.StatementTimeout > 0 {
+		if guard == nil {
+			return 0, fmt.Errorf("lock and statement timeouts are not supported for %s", driver)
+		}
+		if err := guard.setTimeouts(ctx, conn, opts.LockTimeout, opts.StatementTimeout); err != nil {
+			return 0, fmt.Errorf("setting timeouts: %w", err)
+		}
+	}
+	var prompt *bufio.Scanner
+	if opts.Pause {
+		prompt = bufio.NewScanner(opts.In)
+	}
+
+	keys := statementKeys(stmts)
+	applied, skipped := 0, 0
+	start := time.Now()
+	for i, stmt := range stmts {
+		if opts.State != nil {
+			if a := opts.State.lookup(keys[i]); a != nil {
+				fmt.Fprintf(opts.Out, "[%d/%d] %s\n      ↷ already applied at %s\n", i+1, len(stmts), statementSummary(stmt), a.AppliedAt)
+				skipped++
+				continue
+			}
+		}
+		if err := waitForPauseFile(ctx, opts); err != nil {
+			return applied, err
+		}
+		if prompt != nil {
+			fmt.Fprintf(opts.Out, "Next: %s\n  run it? [Y/n] ", statementSummary(stmt))
+			if !prompt.Scan() || strings.EqualFold(strings.TrimSpace(prompt.Text()), "n") {
+				return applied, fmt.Errorf("stopped before statement %d", i+1)
+			}
+		}
+
+		line := fmt.Sprintf("[%d/%d] %s", i+1, len(stmts), statementSummary(stmt))
+		if m := migrationTablePattern.FindStringSubmatch(stmt); m != nil && monitor != nil {
+			if rows, err := monitor.estimateRows(ctx, db, m[1]); err == nil && rows >= 0 {
+				line += fmt.Sprintf("  (~%d rows in %s)", rows, m[1])
+			}
+		}
+		fmt.Fprintln(opts.Out, line)
+
+		stmtStart := time.Now()
+		result, err := execMonitored(ctx, conn, db, monitor, session, stmt, opts)
+		backoff := opts.RetryBackoff
+		for attempt := 1; err != nil && attempt <= opts.Retries && guard != nil && guard.isLockTimeout(err); attempt++ {
+			fmt.Fprintf(opts.Out, "      ↻ lock timeout, retry %d/%d in %s\n", attempt, opts.Retries, backoff)
+			select {
+			case <-ctx.Done():
+				return applied, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			stmtStart = time.Now()
+			result, err = execMonitored(ctx, conn, db, monitor, session, stmt, opts)
+		}
+		if err != nil {
+			fmt.Fprintf(opts.Out, "      ✗ failed after %s: %v\n", time.Since(stmtStart).Round(time.Millisecond), err)
+			return applied, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		affected := ""
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			affected = fmt.Sprintf(", %d rows affected", n)
+		}
+		fmt.Fprintf(opts.Out, "      ✓ %s%s\n", time.Since(stmtStart).Round(time.Millisecond), affected)
+		applied++
+		if opts.State != nil {
+			opts.State.Applied = append(opts.State.Applied, &AppliedStatement{
+				Key:       keys[i],
+				Statement: stmt,
+				AppliedAt: time.Now().UTC().Format(time.RFC3339),
+			})
+			if err := opts.State.Save(opts.StatePath); err != nil {
+				return applied, fmt.Errorf("saving state after statement %d: %w", i+1, err)
+			}
+		}
+	}
+	fmt.Fprintf(opts.Out, "Applied %d statement(s) in %s", applied, time.Since(start).Round(time.Millisecond))
+	if skipped > 0 {
+		fmt.Fprintf(opts.Out, ", skipped %d applied before", skipped)
+	}
+	fmt.Fprintln(opts.Out)
+	return applied, nil
+}
+
+// execMonitored runs stmt on conn, reporting its lock waits every
+// opts.ProgressInterval until it finishes
+func execMonitored(ctx context.Context, conn *sql.Conn, db *sql.DB, monitor applyMonitor, session int64, stmt string, opts ApplyOptions) (sql.Result, error) {
+	if monitor == nil || opts.ProgressInterval <= 0 {
+		return conn.ExecContext(ctx, stmt)
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(opts.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				waits, err := monitor.lockWaits(ctx, db, session)
+				if err != nil {
+					fmt.Fprintf(opts.Out, "      ? lock waits unavailable: %v\n", err)
+					continue
+				}
+				for _, w := range waits {
+					fmt.Fprintf(opts.Out, "      ⏳ waiting %s on %s, blocked by session %d (%s)\n",
+						w.Waiting.Round(time.Second), w.Lock, w.BlockingSession, w.BlockingQuery)
+				}
+			}
+		}
+	}()
+	result, err := conn.ExecContext(ctx, stmt)
+	close(done)
+	wg.Wait()
+	return result, err
+}
+
+// waitForPauseFile blocks while opts.PauseFile exists
+func waitForPauseFile(ctx context.Context, opts ApplyOptions) error {
+	if opts.PauseFile == "" {
+		return nil
+	}
+	announced := false
+	for {
+		if _, err := os.Stat(opts.PauseFile); err != nil {
+			return nil
+		}
+		if !announced {
+			fmt.Fprintf(opts.Out, "Paused: remove %s to continue\n", opts.PauseFile)
+			announced = true
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (p *PostgresDialect) sessionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var pid int64
+	err := conn.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid)
+	return pid, err
+}
+
+func (p *PostgresDialect) estimateRows(ctx context.Context, db *sql.DB, table string) (int64, error) {
+	// reltuples is -1 for a table that was never vacuumed or analyzed
+	var rows sql.NullFloat64
+	err := db.QueryRowContext(ctx, "SELECT reltuples FROM pg_class WHERE oid = to_regclass($1)", table).Scan(&rows)
+	if err != nil || !rows.Valid {
+		return -1, err
+	}
+	return int64(rows.Float64), nil
+}
+
+func (p *PostgresDialect) lockWaits(ctx context.Context, db *sql.DB, session int64) ([]LockWait, error) {
+	query := `
+		SELECT
+			COALESCE(a.wait_event_type || ':' || a.wait_event, 'lock'),
+			EXTRACT(EPOCH FROM now() - a.state_change),
+			b.pid,
+			b.state || ': ' || LEFT(b.query, 80)
+		FROM pg_stat_activity a
+		CROSS JOIN LATERAL unnest(pg_blocking_pids(a.pid)) AS blocker(pid)
+		JOIN pg_stat_activity b ON b.pid = blocker.pid
+		WHERE a.pid = $1
+	`
+	rows, err := db.QueryContext(ctx, query, session)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var waits []LockWait
+	for rows.Next() {
+		var w LockWait
+		var seconds float64
+		var blocking sql.NullString
+		if err := rows.Scan(&w.Lock, &seconds, &w.BlockingSession, &blocking); err != nil {
+			return nil, err
+		}
+		w.Waiting = time.Duration(seconds * float64(time.Second))
+		w.BlockingQuery = blocking.String
+		waits = append(waits, w)
+	}
+	return waits, rows.Err()
+}
+
+func (p *PostgresDialect) setTimeouts(ctx context.Context, conn *sql.Conn, lockTimeout, statementTimeout time.Duration) error {
+	// Both settings take milliseconds; 0 keeps the server's value
+	if lockTimeout > 0 {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET lock_timeout = %d", lockTimeout.Milliseconds())); err != nil {
+			return err
+		}
+	}
+	if statementTimeout > 0 {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET statement_timeout = %d", statementTimeout.Milliseconds())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isLockTimeout matches SQLSTATE 55P03 (lock_not_available) as reported for
+// lock_timeout, whatever the language of the server's messages
+func (p *PostgresDialect) isLockTimeout(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "55P03"
+}
+
+func (m *MySQLDialect) setTimeouts(ctx context.Context, conn *sql.Conn, lockTimeout, statementTimeout time.Duration) error {
+	if statementTimeout > 0 {
+		// max_execution_time only applies to SELECT
+		return fmt.Errorf("MySQL cannot bound the run time of DDL; leave the statement timeout unset")
+	}
+	if lockTimeout <= 0 {
+		return nil
+	}
+	// Whole seconds, at least 1: lock_wait_timeout bounds metadata locks
+	// (what DDL waits for), innodb_lock_wait_timeout row locks
+	seconds := max(int64(lockTimeout.Round(time.Second)/time.Second), 1)
+	for _, setting := range []string{"lock_wait_timeout", "innodb_lock_wait_timeout"} {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET SESSION %s = %d", setting, seconds)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isLockTimeout matches error 1205 (ER_LOCK_WAIT_TIMEOUT), raised for both
+// metadata and row lock timeouts
+func (m *MySQLDialect) isLockTimeout(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1205
+}
+
+func (m *MySQLDialect) sessionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var id int64
+	err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&id)
+	return id, err
+}
+
+func (m *MySQLDialect) estimateRows(ctx context.Context, db *sql.DB, table string) (int64, error) {
+	var rows sql.NullInt64
+	query := "SELECT table_rows FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	err := db.QueryRowContext(ctx, query, strings.Trim(table, "`")).Scan(&rows)
+	if err != nil || !rows.Valid {
+		return -1, err
+	}
+	return rows.Int64, nil
+}
+
+func (m *MySQLDialect) lockWaits(ctx context.Context, db *sql.DB, session int64) ([]LockWait, error) {
+	// Metadata locks (the usual wait of DDL) and InnoDB row locks, from the
+	// sys schema of MySQL 5.7 and later
+	query := `
+		SELECT CONCAT('metadata lock:', w.waiting_lock_type), w.waiting_query_secs, w.blocking_pid,
+		       CONCAT(COALESCE(p.command, ''), ': ', LEFT(COALESCE(p.info, ''), 80))
+		FROM sys.schema_table_lock_waits w
+		LEFT JOIN information_schema.processlist p ON p.id = w.blocking_pid
+		WHERE w.waiting_pid = ?
+		UNION ALL
+		SELECT CONCAT('row lock:', w.locked_type), w.wait_age_secs, w.blocking_pid,
+		       CONCAT(COALESCE(p.command, ''), ': ', LEFT(COALESCE(p.info, ''), 80))
+		FROM sys.innodb_lock_waits w
+		LEFT JOIN information_schema.processlist p ON p.id = w.blocking_pid
+		WHERE w.waiting_pid = ?
+	`
+	rows, err := db.QueryContext(ctx, query, session, session)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var waits []LockWait
+	for rows.Next() {
+		var w LockWait
+		var seconds float64
+		if err := rows.Scan(&w.Lock, &seconds, &w.BlockingSession, &w.BlockingQuery); err != nil {
+			return nil, err
+		}
+		w.Waiting = time.Duration(seconds * float64(time.Second))
+		waits = append(waits, w)
+	}
+	return waits, rows.Err()
+}
+
+func runApply(args []string) {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	conn := fs.String("target", "", "Database connection string to apply the migration to")
+	driver := fs.String("target-driver", "", "Database driver; lock waits are reported for postgres, yugabyte, mysql and tidb")
+	file := fs.String("file", "", "Reviewed migration script, e.g. the edited output of --migration (- for stdin)")
+	interval := fs.Duration("progress-interval", 5*time.Second, "How often to report lock waits of a running statement (0 = never)")
+	pause := fs.Bool("pause", false, "Ask before running each statement")
+	pauseFile := fs.String("pause-file", "", "Hold back the next statement while this file exists")
+	lockTimeout := fs.Duration("lock-timeout", 5*time.Second, "Give up on a statement waiting longer than this for a lock (0 = server setting)")
+	statementTimeout := fs.Duration("statement-timeout", 0, "Cancel a statement running longer than this (Postgres only; 0 = server setting)")
+	retries := fs.Int("retries", 5, "Retries of a statement that hit the lock timeout")
+	retryBackoff := fs.Duration("retry-backoff", 2*time.Second, "Wait before the first retry; doubled for each next one")
+	auditDest := fs.String("audit", "", "Append an audit record of the apply to this file (JSON lines), or POST it to this http(s) URL")
+	statePath := fs.String("state", "", "State file recording applied statements; a rerun skips them and resumes where it stopped")
+	force := fs.Bool("force", false, "Resume from a --state file recorded for another target")
+	noUnicode := fs.Bool("no-unicode", false, "Use ASCII instead of symbols in the progress output")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff apply --target <conn> --target-driver <driver> --file migration.sql [--pause] [--pause-file <path>]")
+		fmt.Fprintln(os.Stderr, "\nRuns the statements of a reviewed migration script one at a time, reporting the")
+		fmt.Fprintln(os.Stderr, "duration, affected rows and lock waits of each. Commented lines are not run.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *conn == "" || *driver == "" || *file == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *pause && *file == "-" {
+		fmt.Fprintln(os.Stderr, "--pause reads answers from stdin and cannot be combined with --file -")
+		os.Exit(1)
+	}
+	dialect := getDialect(*driver)
+	if dialect == nil {
+		fmt.Fprintf(os.Stderr, "unsupported driver: %s\n", *driver)
+		os.Exit(1)
+	}
+	// The default lock timeout only applies where it can be set; asking for
+	// one explicitly on other databases is an error
+	if _, ok := dialect.(applyGuard); !ok {
+		explicit := false
+		fs.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == "lock-timeout" })
+		if !explicit {
+			*lockTimeout = 0
+		}
+	}
+
+	var script []byte
+	var err error
+	if *file == "-" {
+		script, err = io.ReadAll(os.Stdin)
+	} else {
+		script, err = readTextFile(*file)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading migration: %v\n", err)
+		os.Exit(1)
+	}
+	stmts := MigrationStatements(string(decodeText(script)), *driver)
+	if len(stmts) == 0 {
+		fmt.Fprintln(os.Stderr, "No statements to apply")
+		return
+	}
+
+	var state *ApplyState
+	if *statePath != "" {
+		if state, err = LoadApplyState(*statePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+			os.Exit(1)
+		}
+		if err := state.Bind(*driver, *conn, *force); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	db, err := openDB(*driver, *conn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	opts := ApplyOptions{
+		ProgressInterval: *interval,
+		Pause:            *pause,
+		PauseFile:        *pauseFile,
+		LockTimeout:      *lockTimeout,
+		StatementTimeout: *statementTimeout,
+		Retries:          *retries,
+		RetryBackoff:     *retryBackoff,
+		State:            state,
+		StatePath:        *statePath,
+		In:               os.Stdin,
+		Out:              consoleOutput(os.Stderr, *noUnicode),
+	}
+	applied, err := ApplyMigration(context.Background(), *driver, db, stmts, opts)
+	if *auditDest != "" {
+		rec := newAuditRecord("apply", "", "", *driver, *conn)
+		rec.SQL = strings.Join(stmts, ";\n") + ";"
+		rec.Applied = applied
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		if err := WriteAudit(*auditDest, rec); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing audit record: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (%d statement(s) applied in this run)\n", err, applied)
+		os.Exit(1)
+	}
+}
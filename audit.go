@@ -0,0 +1,137 @@
+package dbdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// AUDIT LOG - Record of every diff and apply for change tracking
+// ============================================================================
+
+// AuditRecord describes one dbdiff run: who ran it where, against which
+// databases, what it found and the exact SQL it generated or applied.
+// Connection strings are recorded with their passwords redacted.
+type AuditRecord struct {
+	Command      string `json:"command"`
+	User         string `json:"user"`
+	Host         string `json:"host"`
+	Time         string `json:"time"`
+	Source       string `json:"source,omitempty"`
+	SourceDriver string `json:"source_driver,omitempty"`
+	Target       string `json:"target"`
+	TargetDriver string `json:"target_driver"`
+	// Summary counts the findings of a diff
+	Summary *AuditSummary `json:"summary,omitempty"`
+	// SQL is the migration generated by a diff or run by an apply
+	SQL string `json:"sql,omitempty"`
+	// Applied is the number of statements an apply ran
+	Applied int    `json:"applied,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// AuditSummary counts the findings of a diff
+type AuditSummary struct {
+	Findings           int `json:"findings"`
+	Breaking           int `json:"breaking"`
+	TablesOnlyInSource int `json:"tables_only_in_source"`
+	TablesOnlyInTarget int `json:"tables_only_in_target"`
+	TablesChanged      int `json:"tables_changed"`
+	// Categories and Tables count findings by category and by table;
+	// findings not tied to a table (e.g. sequences) only count by category
+	Categories map[string]int `json:"categories,omitempty"`
+	Tables     map[string]int `json:"tables,omitempty"`
+}
+
+// newAuditRecord starts a record for the current user and host
+func newAuditRecord(command, sourceDriver, source, targetDriver, target string) *AuditRecord {
+	host, _ := os.Hostname()
+	return &AuditRecord{
+		Command:      command,
+		User:         currentUser(),
+		Host:         host,
+		Time:         time.Now().UTC().Format(time.RFC3339),
+		Source:       redactDSN(source),
+		SourceDriver: sourceDriver,
+		Target:       redactDSN(target),
+		TargetDriver: targetDriver,
+	}
+}
+
+// summarizeDiff fills the audit summary of a diff
+func summarizeDiff(diff *SchemaDiff) *AuditSummary {
+	summary := &AuditSummary{
+		TablesOnlyInSource: len(diff.TablesOnlyInSource),
+		TablesOnlyInTarget: len(diff.TablesOnlyInTarget),
+		TablesChanged:      len(diff.TableDiffs),
+	}
+	for _, f := range NewResult(diff).Findings() {
+		summary.Findings++
+		if f.Breaking() {
+			summary.Breaking++
+		}
+		if summary.Categories == nil {
+			summary.Categories = make(map[string]int)
+			summary.Tables = make(map[string]int)
+		}
+		summary.Categories[f.Category]++
+		if f.Table != "" {
+			summary.Tables[f.Table]++
+		}
+	}
+	return summary
+}
+
+var (
+	dsnUserinfoPattern = regexp.MustCompile(`^([^:@/]+):[^@]*@`)
+	dsnPasswordPattern = regexp.MustCompile(`(?i)\b(password|pwd)\s*=\s*('[^']*'|[^\s;&]*)`)
+)
+
+// redactDSN hides the password of a connection string: URLs, MySQL
+// user:pass@tcp(...) DSNs and key=value strings. Passwords in the query of a
+// URL (?password=...) are hidden too.
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.User != nil {
+		dsn = u.Redacted()
+	} else {
+		dsn = dsnUserinfoPattern.ReplaceAllString(dsn, "$1:xxxxx@")
+	}
+	return dsnPasswordPattern.ReplaceAllString(dsn, "$1=xxxxx")
+}
+
+// WriteAudit appends rec as one JSON line to a file, or POSTs it as JSON
+// when dest is an http(s) URL
+func WriteAudit(dest string, rec *AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(dest, "http://") || strings.HasPrefix(dest, "https://") {
+		client := &http.Client{Timeout: discoveryTimeout}
+		resp, err := client.Post(os.ExpandEnv(dest), "application/json", bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("POST %s: %s", resp.Request.URL.Redacted(), resp.Status)
+		}
+		return nil
+	}
+	f, err := os.OpenFile(dest, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,18 @@
+package dbdiff
+
+import "testing"
+
+func TestRedactDSN(t *testing.T) {
+	for _, tc := range []struct{ dsn, want string }{
+		{"postgres://app:s3cret@db:5432/shop", "postgres://app:xxxxx@db:5432/shop"},
+		{"postgres://app:s3cret@db/shop?password=s3cret&sslmode=require", "postgres://app:xxxxx@db/shop?password=xxxxx&sslmode=require"},
+		{"postgres://db/shop?user=app&password=s3cret", "postgres://db/shop?user=app&password=xxxxx"},
+		{"app:s3cret@tcp(db:3306)/shop", "app:xxxxx@tcp(db:3306)/shop"},
+		{"host=db user=app password='s 3cret' dbname=shop", "host=db user=app password=xxxxx dbname=shop"},
+		{"Server=db;User Id=app;Pwd=s3cret;Database=shop", "Server=db;User Id=app;Pwd=xxxxx;Database=shop"},
+	} {
+		if got := redactDSN(tc.dsn); got != tc.want {
+			t.Errorf("redactDSN(%s) = %s, want %s", tc.dsn, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package dbdiff
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// BENCH - Extraction timing breakdown
+// ============================================================================
+
+func runBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	conn := fs.String("source", "", "Database connection string")
+	driver := fs.String("source-driver", "", "Database driver (postgres or mysql)")
+	skipParallel := fs.Bool("skip-parallel", false, "Don't time a full parallel extraction for comparison")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff bench --source <conn> --source-driver <driver> [--skip-parallel]")
+		fmt.Fprintln(os.Stderr, "\nTimes each schema extraction phase per metadata category and compares")
+		fmt.Fprintln(os.Stderr, "sequential with parallel extraction.")
+	}
+	fs.Parse(args)
+
+	if *conn == "" || *driver == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	dialect := getDialect(*driver)
+	planner, ok := dialect.(extractionPlanner)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unsupported driver: %s\n", *driver)
+		os.Exit(1)
+	}
+
+	db, err := openDB(*driver, *conn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error pinging database: %v\n", err)
+		os.Exit(1)
+	}
+
+	start := time.Now()
+	ctx := context.Background()
+	tables, steps, err := planner.extractionPlan(ctx, db, ExtractOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing tables: %v\n", err)
+		os.Exit(1)
+	}
+	listTime := time.Since(start)
+
+	timings := make([]time.Duration, len(steps))
+	for _, tableName := range tables {
+		table := newTable(tableName)
+		for i, step := range steps {
+			stepStart := time.Now()
+			if err := step.fn(ctx, tableName, table); err != nil {
+				fmt.Fprintf(os.Stderr, "Error extracting %s for %s: %v\n", step.label(), tableName, err)
+				os.Exit(1)
+			}
+			timings[i] += time.Since(stepStart)
+		}
+	}
+
+	sequential := listTime
+	for _, t := range timings {
+		sequential += t
+	}
+
+	fmt.Printf("Extraction benchmark: %s, %d tables, %d queries\n", *driver, len(tables), 1+len(tables)*len(steps))
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("%-24s %12s %8s %12s\n", "Phase", "Total", "Share", "Per table")
+	fmt.Println(strings.Repeat("-", 60))
+	share := func(d time.Duration) string {
+		if sequential == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", 100*float64(d)/float64(sequential))
+	}
+	fmt.Printf("%-24s %12s %8s %12s\n", "list tables", listTime.Round(time.Microsecond), share(listTime), "-")
+	for i, step := range steps {
+		perTable := "-"
+		if len(tables) > 0 {
+			perTable = (timings[i] / time.Duration(len(tables))).Round(time.Microsecond).String()
+		}
+		fmt.Printf("%-24s %12s %8s %12s\n", step.label(), timings[i].Round(time.Microsecond), share(timings[i]), perTable)
+	}
+	fmt.Println(strings.Repeat("-", 60))
+	fmt.Printf("%-24s %12s\n", "sequential total", sequential.Round(time.Microsecond))
+
+	if *skipParallel {
+		return
+	}
+
+	start = time.Now()
+	if _, err := dialect.ExtractSchema(ctx, db, ExtractOptions{Concurrency: parallelExtractConcurrency}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error in parallel extraction: %v\n", err)
+		os.Exit(1)
+	}
+	parallel := time.Since(start)
+	fmt.Printf("%-24s %12s\n", "parallel total", parallel.Round(time.Microsecond))
+
+	fmt.Println()
+	if parallel > 0 && float64(sequential)/float64(parallel) >= 1.5 {
+		fmt.Printf("Hint: --parallel is %.1fx faster on this database.\n", float64(sequential)/float64(parallel))
+	} else {
+		fmt.Println("Hint: --parallel brings little here; catalog queries are not the bottleneck or the server is saturated.")
+	}
+}
@@ -0,0 +1,361 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// CAPABILITIES - What each driver extracts and compares
+// ============================================================================
+
+// Object types compared outside the schema, with --compare-grants and
+// --compare-settings
+const (
+	ObjectGrants   = "grants"
+	ObjectSettings = "settings"
+)
+
+// ObjectForeignTables are Postgres foreign tables and foreign servers,
+// extracted with --include-foreign-tables
+const ObjectForeignTables = "foreign_tables"
+
+// ObjectEvents are MySQL scheduled events, extracted with --include-events
+// rather than selected through ExtractOptions.Objects
+const ObjectEvents = "events"
+
+// capabilityObjects lists every object type, in display order
+var capabilityObjects = []string{
+	ObjectColumns, ObjectComments, ObjectAccessMethods, ObjectReplicaIdentity, ObjectPartitions,
+	ObjectPrimaryKeys, ObjectForeignKeys, ObjectUniques, ObjectIndexes,
+	ObjectChecks, ObjectTriggers, ObjectRewriteRules, ObjectTypes, ObjectSequences,
+	ObjectRoutines, ObjectEvents, ObjectExtensions, ObjectForeignTables, ObjectCollations, ObjectTableOptions,
+	ObjectGrants, ObjectSettings,
+}
+
+// capabilityDrivers lists the drivers of the capability matrix
+var capabilityDrivers = []string{
+	"postgres", "yugabyte", "mysql", "tidb", "sqlserver", "oracle", "firebird", "bigquery", DriverDDL, DriverSnapshot,
+}
+
+// driverObjects are the schema object types each driver extracts. They
+// mirror the extraction plans and ExtractSchema of the dialects, and the DDL
+// statements the ddl driver parses.
+var driverObjects = map[string][]string{
+	"postgres": {ObjectColumns, ObjectComments, ObjectAccessMethods, ObjectReplicaIdentity, ObjectPartitions, ObjectPrimaryKeys, ObjectForeignKeys,
+		ObjectUniques, ObjectIndexes, ObjectChecks, ObjectTriggers, ObjectRewriteRules, ObjectTypes, ObjectSequences, ObjectRoutines,
+		ObjectExtensions, ObjectForeignTables, ObjectCollations},
+	"mysql": {ObjectColumns, ObjectComments, ObjectPartitions, ObjectPrimaryKeys, ObjectForeignKeys, ObjectUniques,
+		ObjectIndexes, ObjectChecks, ObjectTriggers, ObjectSequences, ObjectRoutines, ObjectEvents},
+	"tidb": {ObjectColumns, ObjectComments, ObjectPartitions, ObjectPrimaryKeys, ObjectForeignKeys, ObjectUniques,
+		ObjectIndexes, ObjectChecks, ObjectTriggers, ObjectSequences, ObjectRoutines, ObjectEvents, ObjectTableOptions},
+	"sqlserver": {ObjectColumns, ObjectComments, ObjectPrimaryKeys, ObjectForeignKeys, ObjectUniques, ObjectIndexes, ObjectChecks},
+	"oracle":    {ObjectColumns, ObjectComments, ObjectPrimaryKeys, ObjectForeignKeys, ObjectUniques, ObjectIndexes, ObjectChecks},
+	"firebird":  {ObjectColumns, ObjectComments, ObjectPrimaryKeys, ObjectForeignKeys, ObjectUniques, ObjectIndexes, ObjectChecks},
+	"bigquery":  {ObjectColumns, ObjectComments, ObjectPrimaryKeys, ObjectTableOptions},
+	DriverDDL: {ObjectColumns, ObjectComments, ObjectAccessMethods, ObjectReplicaIdentity, ObjectPartitions, ObjectPrimaryKeys, ObjectForeignKeys,
+		ObjectUniques, ObjectIndexes, ObjectChecks, ObjectTriggers, ObjectRewriteRules, ObjectTypes, ObjectSequences, ObjectRoutines,
+		ObjectEvents, ObjectExtensions, ObjectForeignTables},
+	DriverSnapshot: {ObjectColumns, ObjectComments, ObjectAccessMethods, ObjectReplicaIdentity, ObjectPartitions, ObjectPrimaryKeys, ObjectForeignKeys,
+		ObjectUniques, ObjectIndexes, ObjectChecks, ObjectTriggers, ObjectRewriteRules, ObjectTypes, ObjectSequences, ObjectRoutines,
+		ObjectEvents, ObjectExtensions, ObjectForeignTables, ObjectCollations, ObjectTableOptions},
+}
+
+// capabilityNotes qualify what is compared of an object type, by driver
+var capabilityNotes = map[string]map[string]string{
+	"postgres": {
+		ObjectIndexes:       "including access method, operator classes, ordering, INCLUDE and storage options",
+		ObjectTypes:         "enums and domains",
+		ObjectPartitions:    "declarative partitions and INHERITS parents",
+		ObjectCollations:    "collations used or defined in public, with their library versions",
+		ObjectTriggers:      "the trigger function is compared by name, not by body",
+		ObjectForeignTables: "with --include-foreign-tables; servers, their options and user mapping roles, and the server and options of foreign tables",
+	},
+	"mysql": {
+		ObjectIndexes:   "index type and FULLTEXT/SPATIAL; no operator classes, INCLUDE or options",
+		ObjectSequences: "AUTO_INCREMENT columns, with the server's increment and offset",
+		ObjectChecks:    "MySQL 8.0.16+ and MariaDB 10.3.10+; skipped on older servers",
+		ObjectEvents:    "with --include-events; schedule without STARTS, status and body",
+	},
+	"tidb": {
+		ObjectSequences:    "AUTO_INCREMENT columns, with the server's increment and offset",
+		ObjectEvents:       "with --include-events",
+		ObjectTableOptions: "clustered index, placement policy and row ID sharding",
+	},
+	"bigquery": {
+		ObjectPrimaryKeys:  "NOT ENFORCED keys",
+		ObjectTableOptions: "partitioning, clustering and the other TABLE_OPTIONS",
+	},
+}
+
+// driverScopes say which tables a driver reads
+var driverScopes = map[string]string{
+	"postgres":     "tables of the public schema",
+	"mysql":        "tables of the connection's database",
+	"tidb":         "tables of the connection's database",
+	"sqlserver":    "tables of every schema, named schema.table outside dbo",
+	"oracle":       "tables of the current schema; unquoted names are folded to lowercase",
+	"firebird":     "user tables; unquoted names are folded to lowercase",
+	"bigquery":     "tables of the dataset, or of every dataset of a location as dataset.table",
+	DriverDDL:      "what the script declares; statements the parser doesn't know are skipped",
+	DriverSnapshot: "what the live driver extracted when the snapshot was taken",
+}
+
+// Capability says whether one object type is compared
+type Capability struct {
+	Object   string `json:"object"`
+	Compared bool   `json:"compared"`
+	Note     string `json:"note,omitempty"`
+}
+
+// DriverCapabilities lists what a driver compares, for a server version when
+// one was detected
+type DriverCapabilities struct {
+	Driver        string        `json:"driver"`
+	ServerVersion string        `json:"server_version,omitempty"`
+	Scope         string        `json:"scope"`
+	Capabilities  []*Capability `json:"capabilities"`
+}
+
+// Capabilities returns what driver extracts and compares. version is the
+// server version reported by serverVersion, or empty when unknown; it refines
+// what depends on the server (YugabyteDB and TiDB options behind the postgres
+// and mysql drivers, MySQL check constraints, Postgres collation versions).
+func Capabilities(driver, version string) (*DriverCapabilities, error) {
+	canonical := driver
+	switch driver {
+	case "mssql":
+		canonical = "sqlserver"
+	case "yugabyte":
+		canonical = "postgres"
+	}
+	objects, ok := driverObjects[canonical]
+	if !ok {
+		return nil, fmt.Errorf("unknown driver: %s", driver)
+	}
+	compared := makeSet(objects)
+	notes := make(map[string]string)
+	for object, note := range capabilityNotes[canonical] {
+		notes[object] = note
+	}
+
+	switch canonical {
+	case "postgres":
+		switch {
+		case driver == "yugabyte" || strings.Contains(version, "-YB-"):
+			compared[ObjectTableOptions] = true
+			notes[ObjectTableOptions] = "colocation, hash/range sharding and tablet splits"
+		case version == "":
+			notes[ObjectTableOptions] = "YugabyteDB sharding options, when the server is YugabyteDB"
+		}
+		if version != "" && !versionAtLeast(version, 15) {
+			notes[ObjectCollations] += "; the database default collation's version is only tracked since Postgres 15"
+		}
+	case "mysql":
+		switch {
+		case strings.Contains(version, "-TiDB-"):
+			compared[ObjectTableOptions] = true
+			notes[ObjectTableOptions] = capabilityNotes["tidb"][ObjectTableOptions]
+		case version == "":
+			notes[ObjectTableOptions] = "TiDB options, when the server is TiDB"
+		}
+		switch {
+		case version == "" || strings.Contains(version, "-TiDB-"):
+		case strings.Contains(version, "MariaDB") && !versionAtLeast(version, 10, 3, 10),
+			!strings.Contains(version, "MariaDB") && !versionAtLeast(version, 8, 0, 16):
+			compared[ObjectChecks] = false
+			notes[ObjectChecks] = "not supported by this server version"
+		default:
+			delete(notes, ObjectChecks)
+		}
+	}
+
+	dialect := getDialect(driver)
+	if _, ok := dialect.(grantsReader); ok {
+		compared[ObjectGrants] = true
+		notes[ObjectGrants] = "with --compare-grants, between live databases"
+	}
+	if _, ok := dialect.(settingsReader); ok {
+		compared[ObjectSettings] = true
+		notes[ObjectSettings] = "with --compare-settings, between live databases"
+	}
+
+	caps := &DriverCapabilities{Driver: driver, ServerVersion: version, Scope: driverScopes[canonical]}
+	for _, object := range capabilityObjects {
+		caps.Capabilities = append(caps.Capabilities, &Capability{Object: object, Compared: compared[object], Note: notes[object]})
+	}
+	return caps, nil
+}
+
+// versionAtLeast reports whether the leading dotted number of version (as in
+// "8.0.35-log" or "PostgreSQL 16.2") is at least want
+func versionAtLeast(version string, want ...int) bool {
+	digits := regexp.MustCompile(`\d+(\.\d+)*`).FindString(version)
+	parts := strings.Split(digits, ".")
+	for i, w := range want {
+		n := 0
+		if i < len(parts) {
+			n, _ = strconv.Atoi(parts[i])
+		}
+		if n != w {
+			return n > w
+		}
+	}
+	return true
+}
+
+// serverVersion returns the version string a live database reports
+func serverVersion(ctx context.Context, driver string, db *sql.DB) (string, error) {
+	var query string
+	switch driver {
+	case "postgres", "yugabyte":
+		query = "SHOW server_version"
+	case "mysql", "tidb":
+		query = "SELECT VERSION()"
+	case "sqlserver", "mssql":
+		query = "SELECT CAST(SERVERPROPERTY('ProductVersion') AS nvarchar(128))"
+	case "oracle":
+		query = "SELECT version FROM product_component_version WHERE ROWNUM = 1"
+	case "firebird":
+		query = "SELECT rdb$get_context('SYSTEM', 'ENGINE_VERSION') FROM rdb$database"
+	default:
+		return "", nil
+	}
+	var version string
+	err := db.QueryRowContext(ctx, query).Scan(&version)
+	return strings.TrimSpace(version), err
+}
+
+func printCapabilities(w io.Writer, caps *DriverCapabilities) {
+	title := "Capabilities of the " + caps.Driver + " driver"
+	if caps.ServerVersion != "" {
+		title += " (server " + caps.ServerVersion + ")"
+	}
+	fmt.Fprintln(w, title)
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+	fmt.Fprintf(w, "Reads %s.\n", caps.Scope)
+	for _, section := range []struct {
+		heading  string
+		compared bool
+	}{{"Compared", true}, {"Skipped", false}} {
+		fmt.Fprintf(w, "\n%s:\n", section.heading)
+		for _, c := range caps.Capabilities {
+			if c.Compared != section.compared {
+				continue
+			}
+			if c.Note != "" {
+				fmt.Fprintf(w, "  %-20s %s\n", c.Object, c.Note)
+			} else {
+				fmt.Fprintf(w, "  %s\n", c.Object)
+			}
+		}
+	}
+}
+
+// printCapabilityMatrix prints one row per object type and one column per
+// driver
+func printCapabilityMatrix(w io.Writer, all []*DriverCapabilities) {
+	row := func(first string, cells []string) {
+		line := fmt.Sprintf("%-20s", first)
+		for _, cell := range cells {
+			line += fmt.Sprintf(" %-10s", cell)
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+	var drivers []string
+	for _, caps := range all {
+		drivers = append(drivers, caps.Driver)
+	}
+	row("", drivers)
+	for i, object := range capabilityObjects {
+		cells := make([]string, len(all))
+		for j, caps := range all {
+			cells[j] = "-"
+			if caps.Capabilities[i].Compared {
+				cells[j] = "yes"
+			}
+		}
+		row(object, cells)
+	}
+	fmt.Fprintln(w, "\nRun dbdiff capabilities --driver <driver> for what each driver reads and compares of them.")
+}
+
+func runCapabilities(args []string) {
+	fs := flag.NewFlagSet("capabilities", flag.ExitOnError)
+	driver := fs.String("driver", "", "Driver to describe; all drivers if empty")
+	sourceConn := fs.String("source", "", "Live database whose server version refines the answer")
+	sourceDriver := fs.String("source-driver", "", "Driver of --source")
+	format := fs.String("format", FormatPretty, "Output format: pretty or json")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff capabilities [--driver <driver> | --source <conn> --source-driver <driver>] [--format pretty|json]")
+		fmt.Fprintln(os.Stderr, "\nLists the object types each driver extracts and compares, and those it skips, so")
+		fmt.Fprintln(os.Stderr, "that \"no differences found\" can be read for what it covers. With --source, the")
+		fmt.Fprintln(os.Stderr, "server version is detected and taken into account.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if (*sourceConn == "") != (*sourceDriver == "") || (*sourceDriver != "" && *driver != "" && *driver != *sourceDriver) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != FormatPretty && *format != FormatJSON {
+		fmt.Fprintf(os.Stderr, "Invalid format: %s (expected pretty or json)\n", *format)
+		os.Exit(1)
+	}
+
+	var version string
+	if *sourceConn != "" {
+		*driver = *sourceDriver
+		if isFileDriver(*driver) || getDialect(*driver) == nil {
+			fmt.Fprintf(os.Stderr, "--source needs a live database driver, not %s\n", *driver)
+			os.Exit(1)
+		}
+		db, err := openDB(*driver, *sourceConn)
+		if err == nil {
+			version, err = serverVersion(context.Background(), *driver, db)
+			db.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading the server version: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var all []*DriverCapabilities
+	drivers := capabilityDrivers
+	if *driver != "" {
+		drivers = []string{*driver}
+	}
+	for _, d := range drivers {
+		caps, err := Capabilities(d, version)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		all = append(all, caps)
+	}
+
+	switch {
+	case *format == FormatJSON && *driver != "":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(all[0])
+	case *format == FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(all)
+	case *driver != "":
+		printCapabilities(os.Stdout, all[0])
+	default:
+		printCapabilityMatrix(os.Stdout, all)
+	}
+}
@@ -0,0 +1,113 @@
+package dbdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// CHANGE SETS - Findings grouped by the migration they likely came from
+// ============================================================================
+
+// ChangeSet is a labeled group of findings, by Finding.Key
+type ChangeSet struct {
+	Label    string   `json:"label"`
+	Findings []string `json:"findings"`
+}
+
+// GroupChangeSets groups the findings of diff into likely change sets: a
+// table present on one side only together with the foreign keys pointing
+// at it from other tables (and the columns and indexes those keys use) and
+// the sequences it owns; then all remaining changes of each table; then
+// each schema object on its own. source and target are the compared
+// schemas, used to resolve what foreign keys reference.
+func GroupChangeSets(diff *SchemaDiff, source, target *Schema) []*ChangeSet {
+	findings := NewResult(diff).Findings()
+	assigned := make(map[string]bool)
+	var sets []*ChangeSet
+	add := func(cs *ChangeSet, f Finding) {
+		cs.Findings = append(cs.Findings, f.Key())
+		assigned[f.Key()] = true
+	}
+
+	// The sides as the user named them; a target-to-source diff compares
+	// the target first
+	first, second := "SOURCE", "TARGET"
+	if diff.Direction == DirectionTargetToSource {
+		first, second = second, first
+	}
+	for _, tf := range findings {
+		if tf.Category != CategoryTable || tf.Change == ChangeModified {
+			continue
+		}
+		schema, side := target, second
+		if tf.Change == ChangeOnlyInSource {
+			schema, side = source, first
+		}
+		cs := &ChangeSet{Label: fmt.Sprintf("table %s only in %s", tf.Name, side)}
+		add(cs, tf)
+
+		// Columns of other tables referencing the new table
+		fkColumns := make(map[string]bool)
+		for _, f := range findings {
+			if f.Category != CategoryForeignKey || f.Change != tf.Change || assigned[f.Key()] {
+				continue
+			}
+			table := schema.Tables[f.Table]
+			if table == nil || table.ForeignKeys[f.Name] == nil || table.ForeignKeys[f.Name].RefTable != tf.Name {
+				continue
+			}
+			add(cs, f)
+			for _, col := range table.ForeignKeys[f.Name].Columns {
+				fkColumns[f.Table+"."+col] = true
+			}
+		}
+		for _, f := range findings {
+			if f.Change != tf.Change || assigned[f.Key()] {
+				continue
+			}
+			switch f.Category {
+			case CategoryColumn:
+				if fkColumns[f.Table+"."+f.Name] {
+					add(cs, f)
+				}
+			case CategoryIndex:
+				if table := schema.Tables[f.Table]; table != nil && table.Indexes[f.Name] != nil {
+					covered := len(table.Indexes[f.Name].Columns) > 0
+					for _, col := range table.Indexes[f.Name].Columns {
+						covered = covered && fkColumns[f.Table+"."+col]
+					}
+					if covered {
+						add(cs, f)
+					}
+				}
+			case CategorySequence:
+				if seq := schema.Sequences[f.Name]; seq != nil && strings.HasPrefix(seq.OwnedBy, tf.Name+".") {
+					add(cs, f)
+				}
+			}
+		}
+		sets = append(sets, cs)
+	}
+
+	byTable := make(map[string]*ChangeSet)
+	for _, f := range findings {
+		if assigned[f.Key()] {
+			continue
+		}
+		if f.Table == "" {
+			cs := &ChangeSet{Label: describeFinding(f)}
+			add(cs, f)
+			sets = append(sets, cs)
+			continue
+		}
+		cs := byTable[f.Table]
+		if cs == nil {
+			cs = &ChangeSet{Label: fmt.Sprintf("table %s changes", f.Table)}
+			byTable[f.Table] = cs
+			sets = append(sets, cs)
+		}
+		add(cs, f)
+	}
+	return sets
+}
@@ -0,0 +1,8 @@
+// Command dbdiff compares database schemas; see the README for its usage
+package main
+
+import "github.com/nidhey27/dbdiff"
+
+func main() {
+	dbdiff.Main()
+}
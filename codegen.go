@@ -0,0 +1,419 @@
+package dbdiff
+
+import (
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// MODELS CODEGEN - ORM model skeletons from an extracted schema
+// ============================================================================
+
+// Model generators supported by GenerateModels
+const (
+	ModelsGORM = "gorm"
+	ModelsEnt  = "ent"
+	ModelsSqlc = "sqlc"
+)
+
+// goInitialisms are the name parts Go spells in upper case
+var goInitialisms = map[string]bool{
+	"id": true, "url": true, "uuid": true, "api": true, "json": true,
+	"http": true, "ip": true, "sql": true, "html": true, "uri": true,
+}
+
+// goName turns a snake_case table or column name into an exported Go name,
+// e.g. user_id → UserID
+func goName(name string) string {
+	var sb strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == ' ' || r == '.' }) {
+		if goInitialisms[strings.ToLower(part)] {
+			sb.WriteString(strings.ToUpper(part))
+			continue
+		}
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	out := sb.String()
+	if out == "" || (out[0] >= '0' && out[0] <= '9') {
+		out = "X" + out
+	}
+	return out
+}
+
+// goFieldType maps a column type to the Go type of a model field; nullable
+// columns become pointers. Unknown types fall back to string.
+func goFieldType(col *Column) string {
+	t := normalizeType(col.DataType)
+	base, _, _ := strings.Cut(t, "(")
+	goType := "string"
+	switch {
+	case strings.HasSuffix(t, "[]") || t == "array":
+		goType = "[]string"
+	case base == "bigint" || base == "bigserial" || base == "int8":
+		goType = "int64"
+	case base == "integer" || base == "serial" || base == "mediumint":
+		goType = "int32"
+	case base == "smallint" || base == "smallserial":
+		goType = "int16"
+	case t == "tinyint(1)" || base == "boolean" || base == "bit":
+		goType = "bool"
+	case base == "tinyint":
+		goType = "int8"
+	case base == "real" || base == "float":
+		goType = "float32"
+	case base == "double precision":
+		goType = "float64"
+	case strings.HasPrefix(base, "timestamp") || base == "date" || base == "datetime" || strings.HasPrefix(base, "time"):
+		goType = "time.Time"
+	case base == "json" || base == "jsonb":
+		goType = "json.RawMessage"
+	case base == "bytea" || strings.HasSuffix(base, "blob") || strings.HasSuffix(base, "binary"):
+		goType = "[]byte"
+	}
+	if col.IsNullable && !strings.HasPrefix(goType, "[]") && goType != "json.RawMessage" {
+		goType = "*" + goType
+	}
+	return goType
+}
+
+// modelColumns orders a table's columns for a model: primary key columns in
+// key order, then the others by name
+func modelColumns(table *Table) []*Column {
+	var cols []*Column
+	seen := make(map[string]bool)
+	if table.PrimaryKey != nil {
+		for _, name := range table.PrimaryKey.Columns {
+			if col := table.Columns[name]; col != nil {
+				cols = append(cols, col)
+				seen[name] = true
+			}
+		}
+	}
+	for _, name := range getSortedKeys(table.Columns) {
+		if !seen[name] {
+			cols = append(cols, table.Columns[name])
+		}
+	}
+	return cols
+}
+
+// GenerateModels renders tables of schema as model skeletons: GORM structs,
+// ent schema types, or for sqlc the schema.sql it generates models from.
+// Go output is gofmt'ed; it is a starting point to edit, not a complete
+// mapping (relations are only listed in comments).
+func GenerateModels(schema *Schema, tables []string, kind, pkg, driver string) ([]byte, error) {
+	if kind == ModelsSqlc {
+		sub := &Schema{Tables: make(map[string]*Table), Types: schema.Types, Sequences: schema.Sequences, Extensions: schema.Extensions}
+		for _, name := range tables {
+			sub.Tables[name] = schema.Tables[name]
+		}
+		stmts, err := SchemaDDL(sub, driver)
+		if err != nil {
+			return nil, err
+		}
+		return []byte("-- schema.sql for sqlc, generated by dbdiff\n\n" + strings.Join(stmts, ";\n\n") + ";\n"), nil
+	}
+
+	var body strings.Builder
+	imports := make(map[string]bool)
+	for _, name := range tables {
+		table := schema.Tables[name]
+		switch kind {
+		case ModelsGORM:
+			writeGORMModel(&body, table, imports)
+		case ModelsEnt:
+			writeEntSchema(&body, schema, table, driver, imports)
+		default:
+			return nil, fmt.Errorf("unknown model kind %q (expected gorm, ent or sqlc)", kind)
+		}
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "// Code generated by dbdiff models; edit as needed.\n\npackage %s\n\n", pkg)
+	if len(imports) > 0 {
+		// Standard library first, then a group of third-party packages
+		var std, thirdParty []string
+		for _, imp := range getSortedKeys(imports) {
+			if strings.Contains(imp, ".") {
+				thirdParty = append(thirdParty, fmt.Sprintf("\t%q\n", imp))
+			} else {
+				std = append(std, fmt.Sprintf("\t%q\n", imp))
+			}
+		}
+		src.WriteString("import (\n" + strings.Join(std, ""))
+		if len(std) > 0 && len(thirdParty) > 0 {
+			src.WriteString("\n")
+		}
+		src.WriteString(strings.Join(thirdParty, "") + ")\n\n")
+	}
+	src.WriteString(body.String())
+	return format.Source([]byte(src.String()))
+}
+
+// writeFKComments lists a table's foreign keys for the relations to be
+// filled in by hand
+func writeFKComments(w *strings.Builder, table *Table, indent string) {
+	for _, name := range getSortedKeys(table.ForeignKeys) {
+		fk := table.ForeignKeys[name]
+		fmt.Fprintf(w, "%s// %s: (%s) → %s(%s)\n", indent, name, strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", "))
+	}
+}
+
+// writeGORMModel writes a GORM struct with column, key, index and default
+// tags and a TableName method
+func writeGORMModel(w *strings.Builder, table *Table, imports map[string]bool) {
+	structName := goName(table.Name)
+	pk := make(map[string]bool)
+	if table.PrimaryKey != nil {
+		for _, col := range table.PrimaryKey.Columns {
+			pk[col] = true
+		}
+	}
+	indexTags := make(map[string][]string)
+	for _, name := range getSortedKeys(table.Indexes) {
+		idx := table.Indexes[name]
+		kind := "index"
+		if idx.IsUnique {
+			kind = "uniqueIndex"
+		}
+		for _, col := range idx.Columns {
+			indexTags[col] = append(indexTags[col], kind+":"+name)
+		}
+	}
+	for _, name := range getSortedKeys(table.UniqueConstraints) {
+		for _, col := range table.UniqueConstraints[name].Columns {
+			indexTags[col] = append(indexTags[col], "uniqueIndex:"+name)
+		}
+	}
+
+	if table.Comment != "" {
+		fmt.Fprintf(w, "// %s %s\n", structName, table.Comment)
+	} else {
+		fmt.Fprintf(w, "// %s maps table %s\n", structName, table.Name)
+	}
+	if len(table.ForeignKeys) > 0 {
+		w.WriteString("//\n// Foreign keys:\n")
+		writeFKComments(w, table, "")
+	}
+	fmt.Fprintf(w, "type %s struct {\n", structName)
+	for _, col := range modelColumns(table) {
+		goType := goFieldType(col)
+		if strings.Contains(goType, "time.") {
+			imports["time"] = true
+		}
+		if strings.Contains(goType, "json.") {
+			imports["encoding/json"] = true
+		}
+		tags := []string{"column:" + col.Name}
+		if pk[col.Name] {
+			tags = append(tags, "primaryKey")
+		}
+		tags = append(tags, "type:"+columnTypeName(col))
+		if !col.IsNullable {
+			tags = append(tags, "not null")
+		}
+		if col.DefaultValue != nil {
+			tags = append(tags, "default:"+strings.ReplaceAll(*col.DefaultValue, ";", `\;`))
+		}
+		if col.IsGenerated {
+			// Computed by the database: read, never written
+			tags = append(tags, "->")
+		}
+		tags = append(tags, indexTags[col.Name]...)
+		tag := fmt.Sprintf("gorm:%q json:%q", strings.Join(tags, ";"), col.Name)
+		fmt.Fprintf(w, "\t%s %s `%s`", goName(col.Name), goType, tag)
+		if col.Comment != "" {
+			fmt.Fprintf(w, " // %s", col.Comment)
+		}
+		w.WriteString("\n")
+	}
+	w.WriteString("}\n\n")
+	fmt.Fprintf(w, "// TableName returns the table %s is stored in\n", structName)
+	fmt.Fprintf(w, "func (%s) TableName() string { return %q }\n\n", structName, table.Name)
+}
+
+// entFieldBuilders maps Go field types to ent field constructors
+var entFieldBuilders = map[string]string{
+	"int64":     "field.Int64",
+	"int32":     "field.Int32",
+	"int16":     "field.Int16",
+	"int8":      "field.Int8",
+	"bool":      "field.Bool",
+	"float32":   "field.Float32",
+	"float64":   "field.Float",
+	"time.Time": "field.Time",
+	"[]byte":    "field.Bytes",
+	"string":    "field.String",
+}
+
+// writeEntSchema writes an ent schema type with its Fields and Indexes.
+// Enum columns become field.Enum with the type's labels; the database type
+// is kept with SchemaType for the driver's dialect.
+func writeEntSchema(w *strings.Builder, schema *Schema, table *Table, driver string, imports map[string]bool) {
+	imports["entgo.io/ent"] = true
+	imports["entgo.io/ent/schema/field"] = true
+	typeName := goName(table.Name)
+
+	fmt.Fprintf(w, "// %s holds the schema definition of table %s\n", typeName, table.Name)
+	fmt.Fprintf(w, "type %s struct {\n\tent.Schema\n}\n\n", typeName)
+	fmt.Fprintf(w, "// Fields of %s\n", typeName)
+	fmt.Fprintf(w, "func (%s) Fields() []ent.Field {\n\treturn []ent.Field{\n", typeName)
+	for _, col := range modelColumns(table) {
+		goType := strings.TrimPrefix(goFieldType(col), "*")
+		var def string
+		switch {
+		case col.UserType != "" && schema.Types[col.UserType] != nil && schema.Types[col.UserType].Kind == UserTypeEnum:
+			labels := make([]string, len(schema.Types[col.UserType].Labels))
+			for i, label := range schema.Types[col.UserType].Labels {
+				labels[i] = strconv.Quote(label)
+			}
+			def = fmt.Sprintf("field.Enum(%q).Values(%s)", col.Name, strings.Join(labels, ", "))
+		case goType == "json.RawMessage":
+			imports["encoding/json"] = true
+			def = fmt.Sprintf("field.JSON(%q, json.RawMessage{})", col.Name)
+		case goType == "[]string":
+			def = fmt.Sprintf("field.Strings(%q)", col.Name)
+		case goType == "string" && normalizeType(col.DataType) == "text":
+			def = fmt.Sprintf("field.Text(%q)", col.Name)
+		default:
+			def = fmt.Sprintf("%s(%q)", entFieldBuilders[goType], col.Name)
+		}
+		if family := engineFamily(driver); family == "postgres" || family == "mysql" {
+			def += fmt.Sprintf(".SchemaType(map[string]string{%q: %q})", family, columnTypeName(col))
+		}
+		if col.IsNullable {
+			def += ".Optional().Nillable()"
+		}
+		if col.Comment != "" {
+			def += fmt.Sprintf(".Comment(%q)", col.Comment)
+		}
+		if col.DefaultValue != nil {
+			def += fmt.Sprintf(", // default: %s\n", *col.DefaultValue)
+		} else {
+			def += ",\n"
+		}
+		w.WriteString("\t\t" + def)
+	}
+	w.WriteString("\t}\n}\n\n")
+
+	if len(table.Indexes) > 0 || len(table.UniqueConstraints) > 0 {
+		imports["entgo.io/ent/schema/index"] = true
+		fmt.Fprintf(w, "// Indexes of %s\n", typeName)
+		fmt.Fprintf(w, "func (%s) Indexes() []ent.Index {\n\treturn []ent.Index{\n", typeName)
+		for _, name := range getSortedKeys(table.Indexes) {
+			idx := table.Indexes[name]
+			def := fmt.Sprintf("index.Fields(%s)", quotedList(idx.Columns))
+			if idx.IsUnique {
+				def += ".Unique()"
+			}
+			fmt.Fprintf(w, "\t\t%s.StorageKey(%q),\n", def, name)
+		}
+		for _, name := range getSortedKeys(table.UniqueConstraints) {
+			fmt.Fprintf(w, "\t\tindex.Fields(%s).Unique().StorageKey(%q),\n", quotedList(table.UniqueConstraints[name].Columns), name)
+		}
+		w.WriteString("\t}\n}\n\n")
+	}
+
+	fmt.Fprintf(w, "// Edges of %s\n", typeName)
+	fmt.Fprintf(w, "func (%s) Edges() []ent.Edge {\n", typeName)
+	if len(table.ForeignKeys) > 0 {
+		w.WriteString("\t// Foreign keys to turn into edges:\n")
+		writeFKComments(w, table, "\t")
+	}
+	w.WriteString("\treturn nil\n}\n\n")
+}
+
+// quotedList renders names as a list of Go string literals
+func quotedList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func runModels(args []string) {
+	fs := flag.NewFlagSet("models", flag.ExitOnError)
+	sourceConn := fs.String("source", "", "Database connection string, or snapshot/ddl file")
+	sourceDriver := fs.String("source-driver", "", "Database driver (postgres, yugabyte, mysql, tidb, sqlserver, oracle, firebird, bigquery, snapshot, ddl or fake)")
+	targetConn := fs.String("target", "", "With a target, emit only the tables the target adds or extends")
+	targetDriver := fs.String("target-driver", "", "Target database driver")
+	kind := fs.String("orm", ModelsGORM, "Model flavor: gorm, ent or sqlc")
+	pkg := fs.String("package", "", "Go package name (default: models for gorm, schema for ent)")
+	out := fs.String("out", "", "Write to this file instead of stdout")
+	filterOpts := addFilterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff models --source <conn> --source-driver <driver> [--orm gorm|ent|sqlc] [--target <conn> --target-driver <driver>] [--out file]")
+		fmt.Fprintln(os.Stderr, "\nEmits model skeletons for the tables of a schema: GORM structs, ent schemas, or the")
+		fmt.Fprintln(os.Stderr, "schema.sql sqlc generates models from. With --target, only the tables the target adds")
+		fmt.Fprintln(os.Stderr, "or adds columns to are emitted, as defined in the target.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *sourceConn == "" || *sourceDriver == "" || (*targetConn == "") != (*targetDriver == "") {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *pkg == "" {
+		*pkg = "models"
+		if *kind == ModelsEnt {
+			*pkg = "schema"
+		}
+	}
+	filter, err := filterOpts.build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts := filterOpts.extractOptions(filter)
+
+	schema, err := loadSchema(*sourceDriver, *sourceConn, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading source schema: %v\n", err)
+		os.Exit(1)
+	}
+	driver := migrationDriver(*sourceDriver, *sourceDriver)
+	var tables []string
+	if *targetConn == "" {
+		for _, name := range getSortedKeys(schema.Tables) {
+			if !filter.ShouldIgnoreTable(name) {
+				tables = append(tables, name)
+			}
+		}
+	} else {
+		target, err := loadSchema(*targetDriver, *targetConn, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading target schema: %v\n", err)
+			os.Exit(1)
+		}
+		diff := ComputeDiff(schema, target, filter)
+		tables = append(tables, diff.TablesOnlyInTarget...)
+		for _, td := range diff.TableDiffs {
+			if len(td.ColumnsOnlyInTarget) > 0 {
+				tables = append(tables, td.TableName)
+			}
+		}
+		sort.Strings(tables)
+		schema, driver = target, migrationDriver(*sourceDriver, *targetDriver)
+	}
+
+	code, err := GenerateModels(schema, tables, *kind, *pkg, driver)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating models: %v\n", err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		os.Stdout.Write(code)
+		return
+	}
+	if err := os.WriteFile(*out, code, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing models: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,87 @@
+package dbdiff
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/lib/pq"
+)
+
+// ============================================================================
+// COLLATION VERSIONS - Collation library drift behind index corruption
+// ============================================================================
+
+// CollationWarning is a collation whose library version differs between
+// source and target, or from the version recorded in one database's
+// catalog. Indexes on text sorted by one version can be corrupt under
+// another, silently.
+type CollationWarning struct {
+	Collation string `json:"collation"`
+	Source    string `json:"source,omitempty"`
+	Target    string `json:"target,omitempty"`
+	Warning   string `json:"warning"`
+}
+
+// CollationVersionWarnings compares the versions of the collations both
+// schemas use, and checks each side against the versions its catalog
+// recorded
+func CollationVersionWarnings(source, target *Schema) []*CollationWarning {
+	var warnings []*CollationWarning
+	for _, name := range getSortedKeys(source.Collations) {
+		s, t := source.Collations[name], target.Collations[name]
+		if t == nil || s.Version == "" || t.Version == "" || s.Version == t.Version {
+			continue
+		}
+		warning := fmt.Sprintf("%s version differs: indexes built on one side may not match the sort order of the other", s.Provider)
+		if s.Locale != t.Locale {
+			warning = fmt.Sprintf("%s version and locale differ (%s → %s)", s.Provider, orNone(s.Locale), orNone(t.Locale))
+		}
+		warnings = append(warnings, &CollationWarning{Collation: name, Source: s.Version, Target: t.Version, Warning: warning})
+	}
+	for _, side := range []struct {
+		name   string
+		schema *Schema
+	}{{"source", source}, {"target", target}} {
+		for _, name := range getSortedKeys(side.schema.Collations) {
+			c := side.schema.Collations[name]
+			if c.Version == "" || c.RecordedVersion == "" || c.Version == c.RecordedVersion {
+				continue
+			}
+			refresh := "ALTER COLLATION " + pq.QuoteIdentifier(c.Name) + " REFRESH VERSION"
+			if c.Schema != "" {
+				refresh = "ALTER COLLATION " + pq.QuoteIdentifier(c.Schema) + "." + pq.QuoteIdentifier(c.Name) + " REFRESH VERSION"
+			}
+			if name == "default" {
+				refresh = "ALTER DATABASE ... REFRESH COLLATION VERSION"
+			}
+			w := &CollationWarning{
+				Collation: name,
+				Warning: fmt.Sprintf("%s catalog recorded version %s but the library provides %s: REINDEX the dependent indexes, then %s",
+					side.name, c.RecordedVersion, c.Version, refresh),
+			}
+			if side.name == "source" {
+				w.Source = c.Version
+			} else {
+				w.Target = c.Version
+			}
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings
+}
+
+// printCollationWarnings lists the collation version mismatches
+func printCollationWarnings(w io.Writer, warnings []*CollationWarning) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n⚠️  Collation versions:")
+	for _, cw := range warnings {
+		line := "  ~ " + cw.Collation
+		if cw.Source != "" && cw.Target != "" {
+			line += fmt.Sprintf(": %s → %s", cw.Source, cw.Target)
+		}
+		fmt.Fprintln(w, line)
+		fmt.Fprintf(w, "      warning: %s\n", cw.Warning)
+	}
+}
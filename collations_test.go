@@ -0,0 +1,25 @@
+package dbdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollationVersionWarningsQuoteRefresh(t *testing.T) {
+	source := &Schema{Collations: map[string]*Collation{
+		"en_US.utf8": {Name: "en_US.utf8", Schema: "pg_catalog", Provider: "libc", Version: "2.36", RecordedVersion: "2.31"},
+		`my"coll`:    {Name: `my"coll`, Provider: "icu", Version: "153.120", RecordedVersion: "153.14"},
+	}}
+	var refreshes []string
+	for _, w := range CollationVersionWarnings(source, &Schema{}) {
+		_, refresh, _ := strings.Cut(w.Warning, "then ")
+		refreshes = append(refreshes, refresh)
+	}
+	want := []string{
+		`ALTER COLLATION "pg_catalog"."en_US.utf8" REFRESH VERSION`,
+		`ALTER COLLATION "my""coll" REFRESH VERSION`,
+	}
+	if strings.Join(refreshes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("refresh statements = %q, want %q", refreshes, want)
+	}
+}
@@ -0,0 +1,392 @@
+package dbdiff
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// COMMON SUBSET - Shared schema of several databases
+// ============================================================================
+
+// CommonReport is the schema shared by several databases and what each of
+// them has on top of it
+type CommonReport struct {
+	Databases []string `json:"databases"`
+	Common    *Schema  `json:"common"`
+	// Additions diffs each database (source) against the common schema
+	// (target): tables and objects only in source are its additions, changed
+	// ones are definitions the databases disagree on
+	Additions map[string]*SchemaDiff `json:"additions"`
+	// Conflicts are the objects every database has, but not with the same
+	// definition, by finding key (e.g. "column:users.email")
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// CommonSchema returns the tables, columns, keys, indexes and types present
+// with identical definitions in every schema
+func CommonSchema(schemas []*Schema, filter *FilterConfig) *Schema {
+	common := &Schema{Tables: make(map[string]*Table)}
+	if len(schemas) == 0 {
+		return common
+	}
+	first := schemas[0]
+
+	for name, ut := range first.Types {
+		shared := true
+		for _, other := range schemas[1:] {
+			if _, ok := other.Types[name]; !ok {
+				shared = false
+				break
+			}
+			if _, differs := diffUserTypes(first.Types, other.Types)[name]; differs {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			if common.Types == nil {
+				common.Types = make(map[string]*UserType)
+			}
+			common.Types[name] = ut
+		}
+	}
+
+	for name, seq := range first.Sequences {
+		if filter.ShouldIgnoreSequence(seq) {
+			continue
+		}
+		shared := true
+		for _, other := range schemas[1:] {
+			if o, ok := other.Sequences[name]; !ok || len(compareSequence(seq, o)) > 0 {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			if common.Sequences == nil {
+				common.Sequences = make(map[string]*Sequence)
+			}
+			common.Sequences[name] = seq
+		}
+	}
+
+	for name, ext := range first.Extensions {
+		if filter.ShouldIgnoreExtension(ext) {
+			continue
+		}
+		shared := true
+		for _, other := range schemas[1:] {
+			if o, ok := other.Extensions[name]; !ok || len(compareExtension(ext, o)) > 0 {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			if common.Extensions == nil {
+				common.Extensions = make(map[string]*Extension)
+			}
+			common.Extensions[name] = ext
+		}
+	}
+
+	for name, routine := range first.Routines {
+		if filter.ShouldIgnoreRoutine(routine) {
+			continue
+		}
+		shared := true
+		for _, other := range schemas[1:] {
+			if o, ok := other.Routines[name]; !ok || len(compareRoutine(routine, o)) > 0 {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			if common.Routines == nil {
+				common.Routines = make(map[string]*Routine)
+			}
+			common.Routines[name] = routine
+		}
+	}
+
+	for name, event := range first.Events {
+		if filter.ShouldIgnoreEvent(event) {
+			continue
+		}
+		shared := true
+		for _, other := range schemas[1:] {
+			if o, ok := other.Events[name]; !ok || len(compareEvent(event, o)) > 0 {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			if common.Events == nil {
+				common.Events = make(map[string]*Event)
+			}
+			common.Events[name] = event
+		}
+	}
+
+	for name, server := range first.ForeignServers {
+		if filter.ShouldIgnoreForeignServer(server) {
+			continue
+		}
+		shared := true
+		for _, other := range schemas[1:] {
+			if o, ok := other.ForeignServers[name]; !ok || len(compareForeignServer(server, o)) > 0 {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			if common.ForeignServers == nil {
+				common.ForeignServers = make(map[string]*ForeignServer)
+			}
+			common.ForeignServers[name] = server
+		}
+	}
+
+	for name, table := range first.Tables {
+		if filter.ShouldIgnoreTable(name) {
+			continue
+		}
+		tables := []*Table{table}
+		for _, other := range schemas[1:] {
+			if t, ok := other.Tables[name]; ok {
+				tables = append(tables, t)
+			}
+		}
+		if len(tables) < len(schemas) {
+			continue
+		}
+
+		shared := newTable(name)
+		shared.Columns = commonObjects(tables, func(t *Table) map[string]*Column { return t.Columns },
+			func(a, b *Column) bool { return len(compareColumn(a, b, filter, nil)) == 0 })
+		shared.ForeignKeys = commonObjects(tables, func(t *Table) map[string]*ForeignKey { return t.ForeignKeys },
+			func(a, b *ForeignKey) bool { return len(compareForeignKey(a, b)) == 0 })
+		shared.UniqueConstraints = commonObjects(tables, func(t *Table) map[string]*Unique { return t.UniqueConstraints },
+			func(a, b *Unique) bool { return len(compareUnique(a, b)) == 0 })
+		shared.Indexes = commonObjects(tables, func(t *Table) map[string]*Index { return t.Indexes },
+			func(a, b *Index) bool { return len(compareIndex(a, b)) == 0 })
+		shared.CheckConstraints = commonObjects(tables, func(t *Table) map[string]*CheckConstr { return t.CheckConstraints },
+			func(a, b *CheckConstr) bool { return len(compareCheck(a, b)) == 0 })
+		shared.Triggers = commonObjects(tables, func(t *Table) map[string]*Trigger { return t.Triggers },
+			func(a, b *Trigger) bool { return len(compareTrigger(a, b)) == 0 })
+		if rules := commonObjects(tables, func(t *Table) map[string]*RewriteRule { return t.RewriteRules },
+			func(a, b *RewriteRule) bool { return len(compareRewriteRule(a, b)) == 0 }); len(rules) > 0 {
+			shared.RewriteRules = rules
+		}
+		if options := commonObjects(tables, func(t *Table) map[string]string { return t.Options },
+			func(a, b string) bool { return a == b }); len(options) > 0 {
+			shared.Options = options
+		}
+
+		shared.PrimaryKey, shared.Comment, shared.AccessMethod = table.PrimaryKey, table.Comment, table.AccessMethod
+		shared.ReplicaIdentity, shared.Inherits = table.ReplicaIdentity, table.Inherits
+		shared.Partitioning, shared.PartitionOf = table.Partitioning, table.PartitionOf
+		shared.Foreign = table.Foreign
+		for _, t := range tables[1:] {
+			if len(comparePrimaryKey(shared.PrimaryKey, t.PrimaryKey)) > 0 {
+				shared.PrimaryKey = nil
+			}
+			if t.Comment != shared.Comment {
+				shared.Comment = ""
+			}
+			if t.AccessMethod != shared.AccessMethod {
+				shared.AccessMethod = ""
+			}
+			if t.ReplicaIdentity != shared.ReplicaIdentity {
+				shared.ReplicaIdentity = ""
+			}
+			if !equalStringSlices(t.Inherits, shared.Inherits) {
+				shared.Inherits = nil
+			}
+			if describeForeignTable(t.Foreign) != describeForeignTable(shared.Foreign) {
+				shared.Foreign = nil
+			}
+			if len(comparePartitioning(shared, t)) > 0 {
+				shared.Partitioning, shared.PartitionOf = nil, ""
+			}
+		}
+		common.Tables[name] = shared
+	}
+	return common
+}
+
+// commonObjects returns the objects of tables[0] that every other table has
+// under the same name with a definition same considers equal
+func commonObjects[T any](tables []*Table, objects func(*Table) map[string]T, same func(a, b T) bool) map[string]T {
+	shared := make(map[string]T)
+	for name, obj := range objects(tables[0]) {
+		keep := true
+		for _, t := range tables[1:] {
+			other, ok := objects(t)[name]
+			if !ok || !same(obj, other) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			shared[name] = obj
+		}
+	}
+	return shared
+}
+
+// BuildCommonReport computes the common schema of the named schemas and the
+// additions of each
+func BuildCommonReport(names []string, schemas []*Schema, filter *FilterConfig) *CommonReport {
+	report := &CommonReport{
+		Databases: names,
+		Common:    CommonSchema(schemas, filter),
+		Additions: make(map[string]*SchemaDiff),
+	}
+	seen := make(map[string]int)
+	for i, name := range names {
+		diff := ComputeDiff(schemas[i], report.Common, filter)
+		SortDiff(diff, SortByName)
+		report.Additions[name] = diff
+		for _, f := range NewResult(diff).Findings() {
+			seen[f.Key()]++
+		}
+	}
+	for key, n := range seen {
+		if n == len(names) {
+			report.Conflicts = append(report.Conflicts, key)
+		}
+	}
+	sort.Strings(report.Conflicts)
+	return report
+}
+
+func printCommonReport(w io.Writer, report *CommonReport) {
+	columns := 0
+	for _, t := range report.Common.Tables {
+		columns += len(t.Columns)
+	}
+	fmt.Fprintf(w, "Common schema of %s: %d table(s), %d column(s)\n", strings.Join(report.Databases, ", "), len(report.Common.Tables), columns)
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+	conflicts := makeSet(report.Conflicts)
+
+	for _, name := range report.Databases {
+		findings := NewResult(report.Additions[name]).Findings()
+		if len(findings) == 0 {
+			fmt.Fprintf(w, "\n%s adds nothing\n", name)
+			continue
+		}
+		fmt.Fprintf(w, "\n%s adds (%d):\n", name, len(findings))
+		for _, f := range findings {
+			marker, object := "+", f.Category+" "+f.Table+"."+f.Name
+			if f.Category == CategoryTable {
+				object = f.Category + " " + f.Name
+			}
+			switch {
+			case conflicts[f.Key()]:
+				marker, object = "~", object+" (not defined the same everywhere)"
+			case f.Change != ChangeOnlyInSource:
+				marker = "~"
+				if f.Detail != "" {
+					object += " (" + f.Detail + ")"
+				}
+			}
+			fmt.Fprintf(w, "  %s %s\n", marker, object)
+		}
+	}
+}
+
+// resolveSchemaSource turns a name from the command line into a driver and
+// connection: a named connection of the config file, or a snapshot or DDL
+// (.sql) file
+func resolveSchemaSource(name string, cfg *Config) (driver, conn string, err error) {
+	if c, ok := cfg.Connections[name]; ok {
+		return c.Driver, c.DSN, nil
+	}
+	if _, err := os.Stat(name); err != nil {
+		return "", "", fmt.Errorf("%q is neither a connection in the config file nor a readable file", name)
+	}
+	return schemaFileDriver(name), name, nil
+}
+
+// schemaFileDriver is the driver reading a schema file: ddl for .sql files,
+// snapshot otherwise
+func schemaFileDriver(path string) string {
+	if strings.HasSuffix(strings.ToLower(path), ".sql") {
+		return DriverDDL
+	}
+	return DriverSnapshot
+}
+
+func runCommon(args []string) {
+	fs := flag.NewFlagSet("common", flag.ExitOnError)
+	targetsFlag := fs.String("targets", "", "Comma-separated connection names from the config file, or snapshot/DDL files")
+	configPath := fs.String("config", "", "Config file with named connections (default: discovered)")
+	out := fs.String("out", "", "Also save the common schema as a snapshot (compressed for .gz/.zst)")
+	format := fs.String("format", FormatPretty, "Output format: pretty or json")
+	parallel := fs.Bool("parallel", false, "Use parallel schema extraction")
+	noUnicode := fs.Bool("no-unicode", false, "Use ASCII instead of symbols in the pretty output")
+	filterOpts := addFilterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff common --targets a,b,c [--config dbdiff.yaml] [--out common.json.gz] [--format pretty|json] [filter options]")
+		fmt.Fprintln(os.Stderr, "\nReports the schema shared by all targets and what each of them adds on top.")
+	}
+	fs.Parse(args)
+
+	names := splitList(*targetsFlag)
+	if len(names) < 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != FormatPretty && *format != FormatJSON {
+		fmt.Fprintf(os.Stderr, "Invalid format: %s (expected pretty or json)\n", *format)
+		os.Exit(1)
+	}
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	filter, err := filterOpts.build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts := filterOpts.extractOptions(filter)
+	if *parallel {
+		opts.Concurrency = parallelExtractConcurrency
+	}
+
+	schemas := make([]*Schema, len(names))
+	for i, name := range names {
+		driver, conn, err := resolveSchemaSource(name, cfg)
+		if err == nil {
+			schemas[i], err = loadSchema(driver, conn, opts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+
+	report := BuildCommonReport(names, schemas, filter)
+	if *out != "" {
+		if err := SaveSnapshot(report.Common, *out, compressionForPath(*out)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing common schema: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *format == FormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		printCommonReport(consoleOutput(os.Stdout, *noUnicode), report)
+	}
+}
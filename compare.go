@@ -0,0 +1,1744 @@
+package dbdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ============================================================================
+// DIFF ENGINE
+// ============================================================================
+
+func ComputeDiff(source, target *Schema, filter *FilterConfig) *SchemaDiff {
+	diff := &SchemaDiff{}
+	typeDiffs := diffUserTypes(source.Types, target.Types)
+
+	// Partitions matched by --match-partitions are compared once per group;
+	// with --collapse-partitions, declarative partitions only show up in
+	// their parent's partitioning diff
+	grouped := make(map[string]bool)
+	sourceTemplates := make(map[string]*Table)
+	targetTemplates := make(map[string]*Table)
+	if filter.CollapsePartitions {
+		for _, schema := range []*Schema{source, target} {
+			for name, table := range schema.Tables {
+				if table.PartitionOf != "" {
+					grouped[name] = true
+				}
+			}
+		}
+	}
+	if filter.MatchPartitions {
+		diff.PartitionGroups = matchPartitions(source, target, filter)
+		for _, g := range diff.PartitionGroups {
+			for _, name := range g.sourceTables {
+				grouped[name] = true
+			}
+			for _, name := range g.targetTables {
+				grouped[name] = true
+			}
+			switch {
+			case g.SourceTemplate == "":
+				diff.TablesOnlyInTarget = append(diff.TablesOnlyInTarget, g.Name)
+				targetTemplates[g.Name] = partitionTemplate(target.Tables[g.TargetTemplate], g.Name)
+			case g.TargetTemplate == "":
+				diff.TablesOnlyInSource = append(diff.TablesOnlyInSource, g.Name)
+				sourceTemplates[g.Name] = partitionTemplate(source.Tables[g.SourceTemplate], g.Name)
+			default:
+				tableDiff := compareTable(partitionTemplate(source.Tables[g.SourceTemplate], g.Name),
+					partitionTemplate(target.Tables[g.TargetTemplate], g.Name), filter, typeDiffs)
+				if !isTableDiffEmpty(tableDiff) {
+					diff.TableDiffs = append(diff.TableDiffs, tableDiff)
+				}
+			}
+		}
+	}
+
+	// Find tables only in source or target
+	sourceTableNames := getSortedKeys(source.Tables)
+	targetTableNames := getSortedKeys(target.Tables)
+
+	sourceSet := makeSet(sourceTableNames)
+	targetSet := makeSet(targetTableNames)
+
+	for _, name := range sourceTableNames {
+		if !targetSet[name] && !grouped[name] && !filter.ShouldIgnoreTable(name) {
+			diff.TablesOnlyInSource = append(diff.TablesOnlyInSource, name)
+		}
+	}
+
+	for _, name := range targetTableNames {
+		if !sourceSet[name] && !grouped[name] && !filter.ShouldIgnoreTable(name) {
+			diff.TablesOnlyInTarget = append(diff.TablesOnlyInTarget, name)
+		}
+	}
+
+	// Compare common tables
+	for _, tableName := range sourceTableNames {
+		if targetSet[tableName] && !grouped[tableName] && !filter.ShouldIgnoreTable(tableName) {
+			tableDiff := compareTable(source.Tables[tableName], target.Tables[tableName], filter, typeDiffs)
+			if filter.IgnoreCommentOnlyTables && isCommentOnlyTableDiff(tableDiff) {
+				diff.SuppressedTables = append(diff.SuppressedTables, tableName)
+				continue
+			}
+			if !isTableDiffEmpty(tableDiff) {
+				diff.TableDiffs = append(diff.TableDiffs, tableDiff)
+			}
+		}
+	}
+
+	for _, name := range getSortedKeys(source.Types) {
+		if filter.ShouldIgnoreType(source.Types[name]) {
+			continue
+		}
+		if other, ok := target.Types[name]; !ok {
+			diff.TypesOnlyInSource = append(diff.TypesOnlyInSource, name)
+		} else if changes := userTypeChanges(source.Types[name], other); len(changes) > 0 {
+			diff.TypeDiffs = append(diff.TypeDiffs, &TypeDiff{Name: name, Diff: renderChanges(changes), Changes: changes})
+		}
+	}
+	for _, name := range getSortedKeys(target.Types) {
+		if _, ok := source.Types[name]; !ok && !filter.ShouldIgnoreType(target.Types[name]) {
+			diff.TypesOnlyInTarget = append(diff.TypesOnlyInTarget, name)
+		}
+	}
+
+	partitionSequence := func(seq *Sequence) bool {
+		table, _, _ := splitOwnedBy(seq.OwnedBy)
+		return grouped[table]
+	}
+	for _, name := range getSortedKeys(source.Sequences) {
+		seq := source.Sequences[name]
+		if filter.ShouldIgnoreSequence(seq) || partitionSequence(seq) {
+			continue
+		}
+		if other, ok := target.Sequences[name]; !ok {
+			diff.SequencesOnlyInSource = append(diff.SequencesOnlyInSource, name)
+		} else if changes := compareSequence(seq, other); len(changes) > 0 {
+			diff.SequenceDiffs = append(diff.SequenceDiffs, &SequenceDiff{Name: name, Diff: renderChanges(changes), Changes: changes})
+		}
+	}
+	for _, name := range getSortedKeys(target.Sequences) {
+		if _, ok := source.Sequences[name]; !ok && !filter.ShouldIgnoreSequence(target.Sequences[name]) && !partitionSequence(target.Sequences[name]) {
+			diff.SequencesOnlyInTarget = append(diff.SequencesOnlyInTarget, name)
+		}
+	}
+
+	for _, name := range getSortedKeys(source.Routines) {
+		routine := source.Routines[name]
+		if filter.ShouldIgnoreRoutine(routine) {
+			continue
+		}
+		if other, ok := target.Routines[name]; !ok {
+			diff.RoutinesOnlyInSource = append(diff.RoutinesOnlyInSource, name)
+		} else if changes := compareRoutine(routine, other); len(changes) > 0 {
+			diff.RoutineDiffs = append(diff.RoutineDiffs, &RoutineDiff{Name: name, Diff: renderChanges(changes), Changes: changes})
+		}
+	}
+	for _, name := range getSortedKeys(target.Routines) {
+		if _, ok := source.Routines[name]; !ok && !filter.ShouldIgnoreRoutine(target.Routines[name]) {
+			diff.RoutinesOnlyInTarget = append(diff.RoutinesOnlyInTarget, name)
+		}
+	}
+
+	for _, name := range getSortedKeys(source.Events) {
+		event := source.Events[name]
+		if filter.ShouldIgnoreEvent(event) {
+			continue
+		}
+		if other, ok := target.Events[name]; !ok {
+			diff.EventsOnlyInSource = append(diff.EventsOnlyInSource, name)
+		} else if changes := compareEvent(event, other); len(changes) > 0 {
+			diff.EventDiffs = append(diff.EventDiffs, &EventDiff{Name: name, Diff: renderChanges(changes), Changes: changes})
+		}
+	}
+	for _, name := range getSortedKeys(target.Events) {
+		if _, ok := source.Events[name]; !ok && !filter.ShouldIgnoreEvent(target.Events[name]) {
+			diff.EventsOnlyInTarget = append(diff.EventsOnlyInTarget, name)
+		}
+	}
+
+	for _, name := range getSortedKeys(source.Extensions) {
+		if filter.ShouldIgnoreExtension(source.Extensions[name]) {
+			continue
+		}
+		if other, ok := target.Extensions[name]; !ok {
+			diff.ExtensionsOnlyInSource = append(diff.ExtensionsOnlyInSource, name)
+		} else if changes := compareExtension(source.Extensions[name], other); len(changes) > 0 {
+			diff.ExtensionDiffs = append(diff.ExtensionDiffs, &ExtensionDiff{Name: name, Diff: renderChanges(changes), Changes: changes})
+		}
+	}
+	for _, name := range getSortedKeys(target.Extensions) {
+		if _, ok := source.Extensions[name]; !ok && !filter.ShouldIgnoreExtension(target.Extensions[name]) {
+			diff.ExtensionsOnlyInTarget = append(diff.ExtensionsOnlyInTarget, name)
+		}
+	}
+
+	for _, name := range getSortedKeys(source.ForeignServers) {
+		server := source.ForeignServers[name]
+		if filter.ShouldIgnoreForeignServer(server) {
+			continue
+		}
+		if other, ok := target.ForeignServers[name]; !ok {
+			diff.ForeignServersOnlyInSource = append(diff.ForeignServersOnlyInSource, name)
+		} else if changes := compareForeignServer(server, other); len(changes) > 0 {
+			diff.ForeignServerDiffs = append(diff.ForeignServerDiffs, &ForeignServerDiff{Name: name, Diff: renderChanges(changes), Changes: changes})
+		}
+	}
+	for _, name := range getSortedKeys(target.ForeignServers) {
+		if _, ok := source.ForeignServers[name]; !ok && !filter.ShouldIgnoreForeignServer(target.ForeignServers[name]) {
+			diff.ForeignServersOnlyInTarget = append(diff.ForeignServersOnlyInTarget, name)
+		}
+	}
+
+	diff.SourceOnly = missingObjects(source, sourceTemplates, diff.TablesOnlyInSource, diff.TypesOnlyInSource,
+		diff.SequencesOnlyInSource, diff.RoutinesOnlyInSource, diff.EventsOnlyInSource, diff.ExtensionsOnlyInSource, diff.ForeignServersOnlyInSource)
+	diff.TargetOnly = missingObjects(target, targetTemplates, diff.TablesOnlyInTarget, diff.TypesOnlyInTarget,
+		diff.SequencesOnlyInTarget, diff.RoutinesOnlyInTarget, diff.EventsOnlyInTarget, diff.ExtensionsOnlyInTarget, diff.ForeignServersOnlyInTarget)
+
+	return diff
+}
+
+// missingObjects picks the definitions of the named one-sided objects from
+// schema; templates stand in for partition groups, which aren't tables of
+// their own. It returns nil when nothing is named.
+func missingObjects(schema *Schema, templates map[string]*Table, tables, types, sequences, routines, events, extensions, servers []string) *MissingObjects {
+	m := &MissingObjects{
+		Tables:         pickObjects(schema.Tables, tables),
+		Types:          pickObjects(schema.Types, types),
+		Sequences:      pickObjects(schema.Sequences, sequences),
+		Routines:       pickObjects(schema.Routines, routines),
+		Events:         pickObjects(schema.Events, events),
+		Extensions:     pickObjects(schema.Extensions, extensions),
+		ForeignServers: pickObjects(schema.ForeignServers, servers),
+	}
+	for _, name := range tables {
+		if t, ok := templates[name]; ok {
+			if m.Tables == nil {
+				m.Tables = make(map[string]*Table)
+			}
+			m.Tables[name] = t
+		}
+	}
+	if m.Tables == nil && m.Types == nil && m.Sequences == nil && m.Routines == nil && m.Events == nil && m.Extensions == nil &&
+		m.ForeignServers == nil {
+		return nil
+	}
+	return m
+}
+
+// missingTableObjects picks the definitions of the named one-sided columns,
+// constraints, indexes and triggers from table, or returns nil when nothing
+// is named
+func missingTableObjects(table *Table, columns, foreignKeys, uniques, indexes, checks, triggers []string) *MissingTableObjects {
+	m := &MissingTableObjects{
+		Columns:           pickObjects(table.Columns, columns),
+		ForeignKeys:       pickObjects(table.ForeignKeys, foreignKeys),
+		UniqueConstraints: pickObjects(table.UniqueConstraints, uniques),
+		Indexes:           pickObjects(table.Indexes, indexes),
+		CheckConstraints:  pickObjects(table.CheckConstraints, checks),
+		Triggers:          pickObjects(table.Triggers, triggers),
+	}
+	if m.Columns == nil && m.ForeignKeys == nil && m.UniqueConstraints == nil && m.Indexes == nil &&
+		m.CheckConstraints == nil && m.Triggers == nil {
+		return nil
+	}
+	return m
+}
+
+// pickObjects returns the entries of m named in names, or nil if there are none
+func pickObjects[T any](m map[string]T, names []string) map[string]T {
+	var picked map[string]T
+	for _, name := range names {
+		if obj, ok := m[name]; ok {
+			if picked == nil {
+				picked = make(map[string]T)
+			}
+			picked[name] = obj
+		}
+	}
+	return picked
+}
+
+// attributeChange is a change rendered as "attr: from → to"
+func attributeChange(attr string, from, to any) AttributeChange {
+	return AttributeChange{Attribute: attr, From: fmt.Sprint(from), To: fmt.Sprint(to)}
+}
+
+// quotedChange is a change of free-form values, rendered with %q
+func quotedChange(attr, from, to string) AttributeChange {
+	return AttributeChange{Attribute: attr, From: from, To: to, quoted: true}
+}
+
+// String renders the change as it appears in a Diff, e.g. "type: int →
+// bigint (capacity change: ...)" or "options: fillfactor: 70 → 80"
+func (c AttributeChange) String() string {
+	if c.Key != "" {
+		return c.Attribute + ": " + c.Key + ": " + c.value()
+	}
+	return c.Attribute + ": " + c.value()
+}
+
+func (c AttributeChange) value() string {
+	from, to := c.From, c.To
+	if c.quoted {
+		from, to = strconv.Quote(from), strconv.Quote(to)
+	}
+	value := from + " → " + to
+	switch {
+	case c.oneSided && c.To == "":
+		value = from
+	case c.oneSided:
+		value = to
+	}
+	if c.Note != "" {
+		value += " (" + c.Note + ")"
+	}
+	return value
+}
+
+// renderChanges renders the changes of an object as its Diff, separated by
+// "; ". The entries of a map-valued attribute are listed under it, e.g.
+// "options: fillfactor: 70 → 80, autovacuum_enabled: on → off".
+func renderChanges(changes []AttributeChange) string {
+	var parts []string
+	for i, c := range changes {
+		if c.Key != "" && i > 0 && changes[i-1].Key != "" && changes[i-1].Attribute == c.Attribute {
+			parts[len(parts)-1] += ", " + c.Key + ": " + c.value()
+			continue
+		}
+		parts = append(parts, c.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// compareExtension describes a version difference, e.g. "version: 1.1 → 1.2".
+// An unknown version matches any.
+func compareExtension(source, target *Extension) []AttributeChange {
+	if source.Version == "" || target.Version == "" || source.Version == target.Version {
+		return nil
+	}
+	return []AttributeChange{attributeChange("version", source.Version, target.Version)}
+}
+
+// PartitionGroup is a set of time-suffixed tables (events_2024_04,
+// events_2024_05, ...) compared as one table named after their pattern
+// (events_YYYY_MM). Each side is represented by its template: the latest
+// partition both sides have, or else the latest partition of each side.
+type PartitionGroup struct {
+	Name             string `json:"name"`
+	SourceTemplate   string `json:"source_template,omitempty"`
+	TargetTemplate   string `json:"target_template,omitempty"`
+	SourcePartitions int    `json:"source_partitions"`
+	TargetPartitions int    `json:"target_partitions"`
+
+	sourceTables, targetTables []string
+}
+
+// partitionSuffixPattern matches a table name ending in a year, optionally
+// followed by a month and day: events_2024, events_2024_05, events_p20240501
+var partitionSuffixPattern = regexp.MustCompile(`^(.+)_(p?)((?:19|20)\d{2})(?:(_?)(0[1-9]|1[0-2])(?:(_?)(0[1-9]|[12]\d|3[01]))?)?$`)
+
+// partitionPattern returns the name pattern of a time-suffixed table, e.g.
+// events_YYYY_MM for events_2024_05, or "" for other tables
+func partitionPattern(table string) string {
+	m := partitionSuffixPattern.FindStringSubmatch(table)
+	if m == nil {
+		return ""
+	}
+	pattern := m[1] + "_" + m[2] + "YYYY"
+	if m[5] != "" {
+		pattern += m[4] + "MM"
+	}
+	if m[7] != "" {
+		pattern += m[6] + "DD"
+	}
+	return pattern
+}
+
+// matchPartitions groups the time-suffixed tables kept by filter by name
+// pattern. Patterns with a single table on each side are left alone, so a
+// lone report_2023 table is still compared by name.
+func matchPartitions(source, target *Schema, filter *FilterConfig) []*PartitionGroup {
+	groups := make(map[string]*PartitionGroup)
+	collect := func(tables map[string]*Table, add func(g *PartitionGroup, name string)) {
+		for _, name := range getSortedKeys(tables) {
+			pattern := partitionPattern(name)
+			if pattern == "" || filter.ShouldIgnoreTable(name) {
+				continue
+			}
+			if groups[pattern] == nil {
+				groups[pattern] = &PartitionGroup{Name: pattern}
+			}
+			add(groups[pattern], name)
+		}
+	}
+	collect(source.Tables, func(g *PartitionGroup, name string) { g.sourceTables = append(g.sourceTables, name) })
+	collect(target.Tables, func(g *PartitionGroup, name string) { g.targetTables = append(g.targetTables, name) })
+
+	var matched []*PartitionGroup
+	for _, pattern := range getSortedKeys(groups) {
+		g := groups[pattern]
+		g.SourcePartitions, g.TargetPartitions = len(g.sourceTables), len(g.targetTables)
+		if g.SourcePartitions < 2 && g.TargetPartitions < 2 {
+			continue
+		}
+		// Names sort chronologically within a pattern; pick the latest
+		// partition present on both sides if there is one
+		inTarget := makeSet(g.targetTables)
+		for i := len(g.sourceTables) - 1; i >= 0; i-- {
+			if inTarget[g.sourceTables[i]] {
+				g.SourceTemplate, g.TargetTemplate = g.sourceTables[i], g.sourceTables[i]
+				break
+			}
+		}
+		if g.SourceTemplate == "" {
+			if len(g.sourceTables) > 0 {
+				g.SourceTemplate = g.sourceTables[len(g.sourceTables)-1]
+			}
+			if len(g.targetTables) > 0 {
+				g.TargetTemplate = g.targetTables[len(g.targetTables)-1]
+			}
+		}
+		matched = append(matched, g)
+	}
+	return matched
+}
+
+// partitionTemplate copies a partition with its name, and the names of its
+// constraints, indexes and sequences derived from it, replaced by the
+// group's pattern, so that templates of different partitions line up
+func partitionTemplate(table *Table, pattern string) *Table {
+	data, err := json.Marshal(table)
+	if err != nil {
+		return table
+	}
+	data = bytes.ReplaceAll(data, []byte(table.Name), []byte(pattern))
+	var tpl Table
+	if err := json.Unmarshal(data, &tpl); err != nil {
+		return table
+	}
+	return &tpl
+}
+
+// compareRoutine describes how two routines with the same signature differ.
+// Bodies are reported by their first differing line, e.g.
+// "body: line 3: "x := 1;" → "x := 2;"".
+func compareRoutine(source, target *Routine) []AttributeChange {
+	var diffs []AttributeChange
+	if source.Kind != target.Kind {
+		diffs = append(diffs, attributeChange("kind", source.Kind, target.Kind))
+	}
+	if source.Arguments != target.Arguments {
+		diffs = append(diffs, quotedChange("arguments", source.Arguments, target.Arguments))
+	}
+	if source.Returns != target.Returns {
+		diffs = append(diffs, attributeChange("returns", source.Returns, target.Returns))
+	}
+	if !strings.EqualFold(source.Language, target.Language) {
+		diffs = append(diffs, attributeChange("language", source.Language, target.Language))
+	}
+	if source.Body != target.Body {
+		diffs = append(diffs, compareBody(source.Body, target.Body))
+	}
+	return diffs
+}
+
+// compareBody reports two different normalized bodies by their first
+// differing line
+func compareBody(source, target string) AttributeChange {
+	from, to := strings.Split(source, "\n"), strings.Split(target, "\n")
+	line := 0
+	for line < len(from) && line < len(to) && from[line] == to[line] {
+		line++
+	}
+	at := func(lines []string) string {
+		if line < len(lines) {
+			return lines[line]
+		}
+		return ""
+	}
+	change := quotedChange("body", at(from), at(to))
+	change.Key = fmt.Sprintf("line %d", line+1)
+	return change
+}
+
+// compareEvent describes how two scheduled events differ, e.g.
+// "schedule: EVERY 1 DAY → EVERY 1 HOUR; status: ENABLED → DISABLED"
+func compareEvent(source, target *Event) []AttributeChange {
+	var diffs []AttributeChange
+	if source.Schedule != target.Schedule {
+		diffs = append(diffs, attributeChange("schedule", source.Schedule, target.Schedule))
+	}
+	if source.Status != target.Status {
+		diffs = append(diffs, attributeChange("status", source.Status, target.Status))
+	}
+	if source.OnCompletion != target.OnCompletion {
+		diffs = append(diffs, attributeChange("on_completion", source.OnCompletion, target.OnCompletion))
+	}
+	if source.Body != target.Body {
+		diffs = append(diffs, compareBody(source.Body, target.Body))
+	}
+	return diffs
+}
+
+// compareForeignServer describes how two foreign servers differ, e.g.
+// "options: dbname=app, host=db1 → dbname=app, host=db2"
+func compareForeignServer(source, target *ForeignServer) []AttributeChange {
+	var diffs []AttributeChange
+	if source.Wrapper != target.Wrapper {
+		diffs = append(diffs, attributeChange("wrapper", source.Wrapper, target.Wrapper))
+	}
+	if !equalStringSlices(source.Options, target.Options) {
+		diffs = append(diffs, attributeChange("options", orNone(strings.Join(source.Options, ", ")), orNone(strings.Join(target.Options, ", "))))
+	}
+	if !equalStringSlices(source.UserMappings, target.UserMappings) {
+		diffs = append(diffs, attributeChange("user_mappings", orNone(strings.Join(source.UserMappings, ", ")), orNone(strings.Join(target.UserMappings, ", "))))
+	}
+	return diffs
+}
+
+// compareSequence describes how two sequences differ, e.g.
+// "increment: 1 → 10; owned_by: "" → "orders.id""
+func compareSequence(source, target *Sequence) []AttributeChange {
+	var diffs []AttributeChange
+	add := func(attr string, from, to any) {
+		diffs = append(diffs, attributeChange(attr, from, to))
+	}
+	if source.DataType != target.DataType {
+		add("data_type", source.DataType, target.DataType)
+	}
+	if source.Start != target.Start {
+		add("start", source.Start, target.Start)
+	}
+	if source.Increment != target.Increment {
+		add("increment", source.Increment, target.Increment)
+	}
+	if source.MinValue != target.MinValue {
+		add("min_value", source.MinValue, target.MinValue)
+	}
+	if source.MaxValue != target.MaxValue {
+		add("max_value", source.MaxValue, target.MaxValue)
+	}
+	if source.Cache != target.Cache {
+		add("cache", source.Cache, target.Cache)
+	}
+	if source.Cycle != target.Cycle {
+		add("cycle", source.Cycle, target.Cycle)
+	}
+	if source.OwnedBy != target.OwnedBy {
+		diffs = append(diffs, quotedChange("owned_by", source.OwnedBy, target.OwnedBy))
+	}
+	return diffs
+}
+
+// CountFilteredFindings returns how many findings of an unfiltered
+// comparison of source and target are absent from diff, i.e. were ignored,
+// normalized away or suppressed
+func CountFilteredFindings(source, target *Schema, diff *SchemaDiff) int {
+	raw := ComputeDiff(source, target, NewFilterConfig())
+	hidden := len(NewResult(raw).Findings()) - len(NewResult(diff).Findings())
+	if hidden < 0 {
+		return 0
+	}
+	return hidden
+}
+
+func compareTable(source, target *Table, filter *FilterConfig, typeDiffs map[string][]AttributeChange) *TableDiff {
+	diff := &TableDiff{TableName: source.Name}
+	if filter.MatchByDefinition {
+		target = matchByDefinition(source, target)
+	}
+
+	// Compare columns
+	sourceColNames := getSortedKeys(source.Columns)
+	targetColNames := getSortedKeys(target.Columns)
+
+	sourceColSet := makeSet(sourceColNames)
+	targetColSet := makeSet(targetColNames)
+
+	for _, name := range sourceColNames {
+		if !targetColSet[name] && !filter.ShouldIgnoreColumn(source.Name, name) {
+			if filter.NormalizeInvisiblePK && isGIPKColumn(source, name) {
+				continue
+			}
+			diff.ColumnsOnlyInSource = append(diff.ColumnsOnlyInSource, name)
+		}
+	}
+
+	for _, name := range targetColNames {
+		if !sourceColSet[name] && !filter.ShouldIgnoreColumn(target.Name, name) {
+			if filter.NormalizeInvisiblePK && isGIPKColumn(target, name) {
+				continue
+			}
+			diff.ColumnsOnlyInTarget = append(diff.ColumnsOnlyInTarget, name)
+		}
+	}
+
+	for _, colName := range sourceColNames {
+		if targetColSet[colName] && !filter.ShouldIgnoreColumn(source.Name, colName) {
+			changes := compareColumn(source.Columns[colName], target.Columns[colName], filter, typeDiffs)
+			if len(changes) > 0 {
+				diff.ColumnDiffs = append(diff.ColumnDiffs, &ColumnDiff{
+					ColumnName: colName,
+					Diff:       renderChanges(changes),
+					Changes:    changes,
+				})
+			}
+		}
+	}
+
+	// Table-level changes are rendered one field per attribute and kept
+	// together in diff.Changes, in the order of the report
+	tableChanges := func(field **string, changes ...AttributeChange) {
+		if len(changes) > 0 {
+			rendered := renderChanges(changes)
+			*field = &rendered
+			diff.Changes = append(diff.Changes, changes...)
+		}
+	}
+
+	// Compare access methods; an empty one (MySQL, Postgres before 12, older
+	// snapshots) is unknown rather than different
+	if source.AccessMethod != "" && target.AccessMethod != "" && source.AccessMethod != target.AccessMethod {
+		tableChanges(&diff.AccessMethodDiff, attributeChange("access_method", source.AccessMethod, target.AccessMethod))
+	}
+
+	// Compare replica identities, which decide what logical replication and
+	// CDC pipelines see of updated and deleted rows; empty is unknown
+	if source.ReplicaIdentity != "" && target.ReplicaIdentity != "" && source.ReplicaIdentity != target.ReplicaIdentity {
+		tableChanges(&diff.ReplicaIdentityDiff, attributeChange("replica_identity", source.ReplicaIdentity, target.ReplicaIdentity))
+	}
+
+	// Compare table options present on both sides; like access methods, an
+	// option missing on one side is unknown (another database, an older
+	// snapshot) rather than different
+	var optionDiffs []AttributeChange
+	for _, key := range getSortedKeys(source.Options) {
+		if t, ok := target.Options[key]; ok && t != source.Options[key] {
+			change := attributeChange("options", source.Options[key], t)
+			change.Key = key
+			optionDiffs = append(optionDiffs, change)
+		}
+	}
+	tableChanges(&diff.OptionsDiff, optionDiffs...)
+
+	tableChanges(&diff.PartitioningDiff, comparePartitioning(source, target)...)
+	if !equalStringSlices(source.Inherits, target.Inherits) {
+		tableChanges(&diff.InheritanceDiff, attributeChange("inherits", orNone(strings.Join(source.Inherits, ", ")), orNone(strings.Join(target.Inherits, ", "))))
+	}
+	if ftDiff := describeForeignTable(source.Foreign); ftDiff != describeForeignTable(target.Foreign) {
+		tableChanges(&diff.ForeignTableDiff, attributeChange("foreign", ftDiff, describeForeignTable(target.Foreign)))
+	}
+
+	// Compare table comments
+	if !filter.IgnoreComments && source.Comment != target.Comment {
+		tableChanges(&diff.CommentDiff, quotedChange("comment", source.Comment, target.Comment))
+	}
+
+	// Compare primary keys
+	sourcePK, targetPK := source.PrimaryKey, target.PrimaryKey
+	if filter.NormalizeInvisiblePK {
+		if sourcePK != nil && sourcePK.Invisible {
+			sourcePK = nil
+		}
+		if targetPK != nil && targetPK.Invisible {
+			targetPK = nil
+		}
+	}
+	if changes := comparePrimaryKey(sourcePK, targetPK); len(changes) > 0 {
+		pkDiff := renderChanges(changes)
+		diff.PrimaryKeyDiff, diff.PrimaryKeyChanges = &pkDiff, changes
+	}
+
+	// Compare foreign keys
+	if !filter.IgnoreForeignKeys {
+		compareMaps(
+			source.ForeignKeys, target.ForeignKeys,
+			&diff.ForeignKeysOnlyInSource, &diff.ForeignKeysOnlyInTarget,
+			func(s, t *ForeignKey) []AttributeChange { return compareForeignKey(s, t) },
+			&diff.ForeignKeyDiffs,
+		)
+	}
+
+	// Compare unique constraints
+	compareMaps(
+		source.UniqueConstraints, target.UniqueConstraints,
+		&diff.UniquesOnlyInSource, &diff.UniquesOnlyInTarget,
+		func(s, t *Unique) []AttributeChange { return compareUnique(s, t) },
+		&diff.UniqueDiffs,
+	)
+
+	// Compare indexes
+	if !filter.IgnoreIndexes {
+		compareMaps(
+			source.Indexes, target.Indexes,
+			&diff.IndexesOnlyInSource, &diff.IndexesOnlyInTarget,
+			func(s, t *Index) []AttributeChange { return compareIndex(s, t) },
+			&diff.IndexDiffs,
+		)
+	}
+
+	// Report a unique constraint and its backing index as one finding
+	collapseUniqueIndexOverlap(diff, source, target)
+
+	// Drop constraints and indexes that were merely renamed
+	if filter.IgnoreConstraintNames {
+		pairByDefinition(source.ForeignKeys, target.ForeignKeys, &diff.ForeignKeysOnlyInSource, &diff.ForeignKeysOnlyInTarget, foreignKeySignature)
+		pairByDefinition(source.UniqueConstraints, target.UniqueConstraints, &diff.UniquesOnlyInSource, &diff.UniquesOnlyInTarget, func(u *Unique) string { return strings.Join(u.Columns, ",") })
+		pairByDefinition(source.Indexes, target.Indexes, &diff.IndexesOnlyInSource, &diff.IndexesOnlyInTarget, indexSignature)
+	}
+
+	// Compare check constraints
+	if !filter.IgnoreChecks {
+		compareMaps(
+			source.CheckConstraints, target.CheckConstraints,
+			&diff.ChecksOnlyInSource, &diff.ChecksOnlyInTarget,
+			func(s, t *CheckConstr) []AttributeChange { return compareCheck(s, t) },
+			&diff.CheckDiffs,
+		)
+		if filter.IgnoreConstraintNames {
+			pairByDefinition(source.CheckConstraints, target.CheckConstraints, &diff.ChecksOnlyInSource, &diff.ChecksOnlyInTarget, func(c *CheckConstr) string { return normalizeCheckExpression(c.Expression) })
+		}
+	}
+
+	// Compare triggers
+	if !filter.IgnoreTriggers {
+		compareMaps(
+			source.Triggers, target.Triggers,
+			&diff.TriggersOnlyInSource, &diff.TriggersOnlyInTarget,
+			func(s, t *Trigger) []AttributeChange { return compareTrigger(s, t) },
+			&diff.TriggerDiffs,
+		)
+	}
+
+	// Compare rewrite rules
+	compareMaps(
+		source.RewriteRules, target.RewriteRules,
+		&diff.RewriteRulesOnlyInSource, &diff.RewriteRulesOnlyInTarget,
+		func(s, t *RewriteRule) []AttributeChange { return compareRewriteRule(s, t) },
+		&diff.RewriteRuleDiffs,
+	)
+
+	diff.SourceOnly = missingTableObjects(source, diff.ColumnsOnlyInSource, diff.ForeignKeysOnlyInSource,
+		diff.UniquesOnlyInSource, diff.IndexesOnlyInSource, diff.ChecksOnlyInSource, diff.TriggersOnlyInSource)
+	diff.TargetOnly = missingTableObjects(target, diff.ColumnsOnlyInTarget, diff.ForeignKeysOnlyInTarget,
+		diff.UniquesOnlyInTarget, diff.IndexesOnlyInTarget, diff.ChecksOnlyInTarget, diff.TriggersOnlyInTarget)
+
+	return diff
+}
+
+// comparePartitioning describes how the partitioning of two tables differs:
+// the strategy and key, each partition and its bound, and the parent of a
+// partition. Every part starts with "partition"
+func comparePartitioning(source, target *Table) []AttributeChange {
+	var diffs []AttributeChange
+	sourceScheme, targetScheme := partitionScheme(source.Partitioning), partitionScheme(target.Partitioning)
+	if sourceScheme != targetScheme {
+		diffs = append(diffs, attributeChange("partitioning", sourceScheme, targetScheme))
+	} else if source.Partitioning != nil {
+		sourceBounds, targetBounds := partitionBounds(source.Partitioning), partitionBounds(target.Partitioning)
+		for _, name := range getSortedKeys(sourceBounds) {
+			targetBound, ok := targetBounds[name]
+			switch {
+			case !ok:
+				diffs = append(diffs, attributeChange("partition "+name, sourceBounds[name], "none"))
+			case normalizeBound(sourceBounds[name]) != normalizeBound(targetBound):
+				diffs = append(diffs, attributeChange("partition "+name, sourceBounds[name], targetBound))
+			}
+		}
+		for _, name := range getSortedKeys(targetBounds) {
+			if _, ok := sourceBounds[name]; !ok {
+				diffs = append(diffs, attributeChange("partition "+name, "none", targetBounds[name]))
+			}
+		}
+	}
+	if source.PartitionOf != target.PartitionOf {
+		diffs = append(diffs, attributeChange("partition_of", orNone(source.PartitionOf), orNone(target.PartitionOf)))
+	}
+	return diffs
+}
+
+// partitionScheme renders a partitioning scheme as "range (created_at)",
+// ignoring case, quoting and spacing
+func partitionScheme(p *Partitioning) string {
+	if p == nil {
+		return "none"
+	}
+	key := strings.NewReplacer("`", "", `"`, "", " ", "").Replace(strings.ToLower(p.Key))
+	return strings.ToLower(strings.Join(strings.Fields(p.Strategy), " ")) + " (" + key + ")"
+}
+
+// partitionBounds maps partition names to their bounds; partitions without
+// one (MySQL HASH and KEY) are "present"
+func partitionBounds(p *Partitioning) map[string]string {
+	bounds := make(map[string]string)
+	if p == nil {
+		return bounds
+	}
+	for _, part := range p.Partitions {
+		bounds[part.Name] = part.Bound
+		if part.Bound == "" {
+			bounds[part.Name] = "present"
+		}
+	}
+	return bounds
+}
+
+func normalizeBound(bound string) string {
+	return strings.ToLower(strings.Join(strings.Fields(bound), " "))
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// compareColumn describes how two columns differ. typeDiffs holds the
+// definition differences of user types by name (see diffUserTypes); they are
+// reported on every column using the type, since the column's behavior
+// changes even though its type name matches.
+func compareColumn(source, target *Column, filter *FilterConfig, typeDiffs map[string][]AttributeChange) []AttributeChange {
+	var diffs []AttributeChange
+
+	srcType, tgtType := columnTypeName(source), columnTypeName(target)
+	if filter.NormalizeTypes {
+		srcType, tgtType = normalizeType(srcType), normalizeType(tgtType)
+	}
+	if class, ok := filter.TypeEquivalence[normalizeType(srcType)]; ok && class == filter.TypeEquivalence[normalizeType(tgtType)] {
+		tgtType = srcType
+	}
+	if source.LargeObject != target.LargeObject {
+		// e.g. bytea on one side and large objects on the other: the same
+		// data, stored and accessed differently
+		diffs = append(diffs, attributeChange("storage", columnStorage(source), columnStorage(target)))
+	} else if srcType != tgtType {
+		artifact, note := lengthSemantics(srcType, tgtType)
+		if !artifact || !filter.NormalizeTypes {
+			change := attributeChange("type", columnTypeName(source), columnTypeName(target))
+			change.Note = note
+			diffs = append(diffs, change)
+		}
+	} else if mods := compareTypeModifiers(source, target); len(mods) > 0 {
+		diffs = append(diffs, mods...)
+	} else if typeDiff, ok := typeDiffs[referencedType(source)]; ok && referencedType(source) != "" {
+		diffs = append(diffs, typeDiff...)
+	}
+
+	if source.IsNullable != target.IsNullable {
+		diffs = append(diffs, attributeChange("nullable", source.IsNullable, target.IsNullable))
+	}
+
+	if source.Identity != target.Identity {
+		diffs = append(diffs, attributeChange("identity", orNone(source.Identity), orNone(target.Identity)))
+	}
+
+	// Defaults drawing from a sequence compare by the sequence they are
+	// linked to, not by how the nextval() call is spelled. Otherwise the
+	// nextval() default of a serial belongs to its identity, which is
+	// compared above.
+	srcDefault := serialDefault(source)
+	tgtDefault := serialDefault(target)
+	if filter.IgnoreDefaults {
+		srcDefault, tgtDefault = "", ""
+	} else if srcSeq, tgtSeq := defaultSequence(source), defaultSequence(target); srcSeq != "" && tgtSeq != "" {
+		if srcSeq != tgtSeq {
+			diffs = append(diffs, attributeChange("sequence", srcSeq, tgtSeq))
+		}
+		srcDefault, tgtDefault = "", ""
+	}
+	if filter.NormalizeDefaults {
+		if normalizeDefault(srcDefault) == normalizeDefault(tgtDefault) {
+			tgtDefault = srcDefault
+		}
+	}
+	if srcDefault != tgtDefault {
+		diffs = append(diffs, quotedChange("default", srcDefault, tgtDefault))
+	}
+
+	if source.IsGenerated != target.IsGenerated {
+		diffs = append(diffs, attributeChange("generated", source.IsGenerated, target.IsGenerated))
+	} else if source.IsGenerated {
+		srcExpr, tgtExpr := source.GenerationExpression, target.GenerationExpression
+		if filter.NormalizeDefaults && normalizeDefault(srcExpr) == normalizeDefault(tgtExpr) {
+			tgtExpr = srcExpr
+		}
+		if srcExpr != tgtExpr {
+			diffs = append(diffs, quotedChange("generation_expression", srcExpr, tgtExpr))
+		}
+		if source.GenerationStorage != target.GenerationStorage {
+			diffs = append(diffs, attributeChange("generation_storage", source.GenerationStorage, target.GenerationStorage))
+		}
+	}
+
+	if !filter.IgnoreComments && source.Comment != target.Comment {
+		diffs = append(diffs, quotedChange("comment", source.Comment, target.Comment))
+	}
+
+	return diffs
+}
+
+// serialDefault is the default of c for comparison: empty when it is the
+// nextval() of a serial column
+func serialDefault(c *Column) string {
+	if c.DefaultValue == nil {
+		return ""
+	}
+	if c.Identity == IdentitySerial && strings.HasPrefix(strings.ToLower(*c.DefaultValue), "nextval(") {
+		return ""
+	}
+	return *c.DefaultValue
+}
+
+// defaultSequence is the sequence a nextval() default draws from, or empty
+// when the default is something else. Unquoted names are lowercased the way
+// Postgres resolves them and the public schema is dropped, so that
+// nextval('public.users_id_seq'::regclass) and nextval('users_id_seq') name the
+// same sequence.
+func defaultSequence(c *Column) string {
+	if c.DefaultValue == nil {
+		return ""
+	}
+	m := nextvalPattern.FindStringSubmatch(strings.TrimSpace(*c.DefaultValue))
+	if m == nil {
+		return ""
+	}
+	var parts []string
+	name := strings.ReplaceAll(m[1], "''", "'")
+	for name != "" {
+		var part string
+		if strings.HasPrefix(name, `"`) {
+			end := strings.Index(name[1:], `"`)
+			if end < 0 {
+				return ""
+			}
+			part, name = name[1:end+1], name[end+2:]
+		} else if dot := strings.Index(name, "."); dot >= 0 {
+			part, name = strings.ToLower(strings.TrimSpace(name[:dot])), name[dot:]
+		} else {
+			part, name = strings.ToLower(strings.TrimSpace(name)), ""
+		}
+		parts = append(parts, part)
+		name = strings.TrimPrefix(strings.TrimSpace(name), ".")
+	}
+	if len(parts) == 2 && parts[0] == "public" {
+		parts = parts[1:]
+	}
+	return strings.Join(parts, ".")
+}
+
+// columnStorage describes how a column stores its value, for storage diffs
+func columnStorage(c *Column) string {
+	if c.LargeObject {
+		return "large object (" + columnTypeName(c) + ")"
+	}
+	return columnTypeName(c)
+}
+
+// columnTypeName is the user type of a column if it has one, else its data type
+func columnTypeName(c *Column) string {
+	if c.UserType != "" {
+		return c.UserType
+	}
+	if c.DataType == "ARRAY" && c.ElementType != "" {
+		return c.ElementType + "[]"
+	}
+	return c.DataType
+}
+
+// referencedType is the user type a column's values are made of: its own
+// type, or the element type of an array
+func referencedType(c *Column) string {
+	if c.UserType != "" {
+		return c.UserType
+	}
+	return c.ElementType
+}
+
+// columnTypeModifiers renders the length, precision and scale of a column,
+// e.g. "(50)" or "(10,2)"; empty when it has none
+func columnTypeModifiers(c *Column) string {
+	switch {
+	case c.CharMaxLength != nil:
+		return fmt.Sprintf("(%d)", *c.CharMaxLength)
+	case c.NumericPrecision != nil && c.NumericScale != nil:
+		return fmt.Sprintf("(%d,%d)", *c.NumericPrecision, *c.NumericScale)
+	case c.NumericPrecision != nil:
+		return fmt.Sprintf("(%d)", *c.NumericPrecision)
+	case c.DatetimePrecision != nil:
+		return fmt.Sprintf("(%d)", *c.DatetimePrecision)
+	}
+	return ""
+}
+
+// pgColumnType renders the data type of a Postgres column with its
+// modifiers, e.g. "character varying(50)" or "timestamp(3) with time zone"
+func pgColumnType(c *Column) string {
+	if c.DataType == "ARRAY" && c.ElementType != "" {
+		return c.ElementType + "[]"
+	}
+	mods := columnTypeModifiers(c)
+	if name, zone, ok := strings.Cut(c.DataType, " with"); ok && mods != "" {
+		return name + mods + " with" + zone
+	}
+	return c.DataType + mods
+}
+
+// compareTypeModifiers describes how the length, precision and scale of two
+// columns of the same type differ, e.g. "length: 50 → 255"
+func compareTypeModifiers(source, target *Column) []AttributeChange {
+	var diffs []AttributeChange
+	value := func(n *int64) string {
+		if n == nil {
+			return "none"
+		}
+		return strconv.FormatInt(*n, 10)
+	}
+	for _, attr := range []struct {
+		name     string
+		from, to *int64
+	}{
+		{"length", source.CharMaxLength, target.CharMaxLength},
+		{"precision", source.NumericPrecision, target.NumericPrecision},
+		{"scale", source.NumericScale, target.NumericScale},
+		{"datetime_precision", source.DatetimePrecision, target.DatetimePrecision},
+	} {
+		if from, to := value(attr.from), value(attr.to); from != to {
+			diffs = append(diffs, attributeChange(attr.name, from, to))
+		}
+	}
+	return diffs
+}
+
+// diffUserTypes compares the enums and domains defined on both sides and
+// returns the changes of each type whose definition differs, with the
+// attributes named after the type, e.g. "type status_t labels: [a b] → [a b c]"
+func diffUserTypes(source, target map[string]*UserType) map[string][]AttributeChange {
+	diffs := make(map[string][]AttributeChange)
+	for name, s := range source {
+		t, ok := target[name]
+		if !ok {
+			continue
+		}
+		for _, change := range userTypeChanges(s, t) {
+			change.Attribute = "type " + name + " " + change.Attribute
+			diffs[name] = append(diffs[name], change)
+		}
+	}
+	return diffs
+}
+
+// userTypeChanges lists how two user types differ, one change per attribute
+func userTypeChanges(s, t *UserType) []AttributeChange {
+	var parts []AttributeChange
+	add := func(attr string, from, to any) {
+		parts = append(parts, attributeChange(attr, from, to))
+	}
+	if s.Kind != t.Kind {
+		add("kind", s.Kind, t.Kind)
+	}
+	if !equalStringSlices(s.Labels, t.Labels) {
+		change := attributeChange("labels", s.Labels, t.Labels)
+		change.Note = describeLabelChange(s.Labels, t.Labels)
+		parts = append(parts, change)
+	}
+	if s.BaseType != t.BaseType {
+		add("base_type", s.BaseType, t.BaseType)
+	}
+	if s.NotNull != t.NotNull {
+		add("not_null", s.NotNull, t.NotNull)
+	}
+	srcDefault, tgtDefault := "", ""
+	if s.Default != nil {
+		srcDefault = *s.Default
+	}
+	if t.Default != nil {
+		tgtDefault = *t.Default
+	}
+	if srcDefault != tgtDefault {
+		parts = append(parts, quotedChange("default", srcDefault, tgtDefault))
+	}
+	if !equalStringSlices(s.Checks, t.Checks) {
+		add("checks", s.Checks, t.Checks)
+	}
+	if from, to := typeAttributeList(s.Attributes), typeAttributeList(t.Attributes); !equalStringSlices(from, to) {
+		change := attributeChange("attributes", "("+strings.Join(from, ", ")+")", "("+strings.Join(to, ", ")+")")
+		change.Note = describeAttributeChange(s.Attributes, t.Attributes)
+		parts = append(parts, change)
+	}
+	return parts
+}
+
+// typeAttributeList renders the attributes of a composite type as
+// "name type" strings
+func typeAttributeList(attrs []*TypeAttribute) []string {
+	var list []string
+	for _, a := range attrs {
+		list = append(list, a.Name+" "+a.Type)
+	}
+	return list
+}
+
+// describeAttributeChange classifies how the attributes of a composite type
+// changed, e.g. "added [note text], changed [amount: integer → numeric]"
+func describeAttributeChange(source, target []*TypeAttribute) string {
+	types := func(attrs []*TypeAttribute) map[string]string {
+		m := make(map[string]string, len(attrs))
+		for _, a := range attrs {
+			m[a.Name] = a.Type
+		}
+		return m
+	}
+	sourceTypes, targetTypes := types(source), types(target)
+	var added, removed, changed, keptSource, keptTarget []string
+	for _, a := range source {
+		to, ok := targetTypes[a.Name]
+		switch {
+		case !ok:
+			removed = append(removed, a.Name)
+			continue
+		case to != a.Type:
+			changed = append(changed, fmt.Sprintf("%s: %s → %s", a.Name, a.Type, to))
+		}
+		keptSource = append(keptSource, a.Name)
+	}
+	for _, a := range target {
+		if _, ok := sourceTypes[a.Name]; ok {
+			keptTarget = append(keptTarget, a.Name)
+		} else {
+			added = append(added, a.Name+" "+a.Type)
+		}
+	}
+	var changes []string
+	if len(added) > 0 {
+		changes = append(changes, fmt.Sprintf("added [%s]", strings.Join(added, ", ")))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, fmt.Sprintf("removed [%s]", strings.Join(removed, ", ")))
+	}
+	if len(changed) > 0 {
+		changes = append(changes, fmt.Sprintf("changed [%s]", strings.Join(changed, ", ")))
+	}
+	if !equalStringSlices(keptSource, keptTarget) {
+		changes = append(changes, "reordered")
+	}
+	return strings.Join(changes, ", ")
+}
+
+// describeLabelChange classifies how the labels of an enum changed, e.g.
+// "added [archived], reordered"
+func describeLabelChange(source, target []string) string {
+	inSource, inTarget := makeSet(source), makeSet(target)
+	var added, removed, keptSource, keptTarget []string
+	for _, label := range source {
+		if inTarget[label] {
+			keptSource = append(keptSource, label)
+		} else {
+			removed = append(removed, label)
+		}
+	}
+	for _, label := range target {
+		if inSource[label] {
+			keptTarget = append(keptTarget, label)
+		} else {
+			added = append(added, label)
+		}
+	}
+	var changes []string
+	if len(added) > 0 {
+		changes = append(changes, fmt.Sprintf("added %v", added))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, fmt.Sprintf("removed %v", removed))
+	}
+	if !equalStringSlices(keptSource, keptTarget) {
+		changes = append(changes, "reordered")
+	}
+	return strings.Join(changes, ", ")
+}
+
+func comparePrimaryKey(source, target *PrimaryKey) []AttributeChange {
+	if source == nil && target == nil {
+		return nil
+	}
+	if source == nil {
+		return []AttributeChange{{Attribute: "added", To: describePrimaryKey(target), oneSided: true}}
+	}
+	if target == nil {
+		return []AttributeChange{{Attribute: "removed", From: describePrimaryKey(source), oneSided: true}}
+	}
+	if !equalStringSlices(source.Columns, target.Columns) || source.Invisible != target.Invisible {
+		return []AttributeChange{attributeChange("columns", describePrimaryKey(source), describePrimaryKey(target))}
+	}
+	return nil
+}
+
+func describePrimaryKey(pk *PrimaryKey) string {
+	if pk.Invisible {
+		return fmt.Sprintf("%v (generated invisible)", pk.Columns)
+	}
+	return fmt.Sprintf("%v", pk.Columns)
+}
+
+func compareForeignKey(source, target *ForeignKey) []AttributeChange {
+	var diffs []AttributeChange
+
+	if !equalStringSlices(source.Columns, target.Columns) {
+		diffs = append(diffs, attributeChange("columns", source.Columns, target.Columns))
+	}
+
+	if source.RefTable != target.RefTable {
+		diffs = append(diffs, attributeChange("ref_table", source.RefTable, target.RefTable))
+	}
+
+	if !equalStringSlices(source.RefColumns, target.RefColumns) {
+		diffs = append(diffs, attributeChange("ref_columns", source.RefColumns, target.RefColumns))
+	}
+
+	if source.OnDelete != target.OnDelete {
+		diffs = append(diffs, attributeChange("on_delete", source.OnDelete, target.OnDelete))
+	}
+
+	if source.OnUpdate != target.OnUpdate {
+		diffs = append(diffs, attributeChange("on_update", source.OnUpdate, target.OnUpdate))
+	}
+
+	if source.Status != target.Status {
+		diffs = append(diffs, attributeChange("status", describeConstraintStatus(source.Status), describeConstraintStatus(target.Status)))
+	}
+
+	return diffs
+}
+
+func compareUnique(source, target *Unique) []AttributeChange {
+	if !equalStringSlices(source.Columns, target.Columns) {
+		return []AttributeChange{attributeChange("columns", source.Columns, target.Columns)}
+	}
+	return nil
+}
+
+func compareIndex(source, target *Index) []AttributeChange {
+	var diffs []AttributeChange
+
+	if !equalStringSlices(source.Columns, target.Columns) {
+		diffs = append(diffs, attributeChange("columns", source.Columns, target.Columns))
+	}
+
+	if source.IsUnique != target.IsUnique {
+		diffs = append(diffs, attributeChange("unique", source.IsUnique, target.IsUnique))
+	}
+
+	// Indexes without method and kind come from engines that don't report
+	// them or older snapshots; their kind is unknown rather than regular
+	classified := func(idx *Index) bool { return idx.Method != "" || idx.Kind != "" }
+	if classified(source) && classified(target) && source.Kind != target.Kind {
+		diffs = append(diffs, attributeChange("kind", indexKind(source.Kind), indexKind(target.Kind)))
+	}
+
+	// An empty method is unknown (engines that don't report one, older
+	// snapshots) rather than different
+	if source.Method != "" && target.Method != "" && source.Method != target.Method {
+		diffs = append(diffs, attributeChange("method", source.Method, target.Method))
+	}
+
+	defaulted := func(items []string) []string {
+		out := make([]string, len(items))
+		for i, item := range items {
+			out[i] = orDefault(item)
+		}
+		return out
+	}
+	if s, t := indexColumnAttributes(source, source.OpClasses), indexColumnAttributes(target, target.OpClasses); !equalStringSlices(s, t) {
+		diffs = append(diffs, attributeChange("op_classes", defaulted(s), defaulted(t)))
+	}
+	if s, t := indexColumnAttributes(source, source.Orders), indexColumnAttributes(target, target.Orders); !equalStringSlices(s, t) {
+		diffs = append(diffs, attributeChange("orders", defaulted(s), defaulted(t)))
+	}
+	if s, t := indexColumnAttributes(source, source.PrefixLengths), indexColumnAttributes(target, target.PrefixLengths); !equalStringSlices(s, t) {
+		diffs = append(diffs, attributeChange("prefix_lengths", indexKeyParts(source), indexKeyParts(target)))
+	}
+
+	if !equalStringSlices(source.Include, target.Include) {
+		diffs = append(diffs, attributeChange("include", source.Include, target.Include))
+	}
+
+	// A storage parameter set on one side only differs from the default
+	keys := makeSet(append(getSortedKeys(source.Options), getSortedKeys(target.Options)...))
+	for _, key := range getSortedKeys(keys) {
+		if s, t := source.Options[key], target.Options[key]; s != t {
+			change := attributeChange("options", orDefault(s), orDefault(t))
+			change.Key = key
+			diffs = append(diffs, change)
+		}
+	}
+
+	return diffs
+}
+
+// orDefault renders an unset attribute as "default"
+func orDefault(s string) string {
+	if s == "" {
+		return "default"
+	}
+	return s
+}
+
+// indexSignature identifies an index by definition rather than name
+func indexSignature(idx *Index) string {
+	return fmt.Sprintf("%v|%v|%s|%s|%v|%v|%v|%v|%v", idx.Columns, idx.IsUnique, idx.Kind, idx.Method,
+		indexColumnAttributes(idx, idx.OpClasses), indexColumnAttributes(idx, idx.Orders),
+		indexColumnAttributes(idx, idx.PrefixLengths), idx.Include, idx.Options)
+}
+
+// indexKeyParts renders the key columns of an index with their prefix
+// lengths, e.g. [name(20) email]
+func indexKeyParts(idx *Index) []string {
+	prefixLengths := indexColumnAttributes(idx, idx.PrefixLengths)
+	parts := make([]string, len(idx.Columns))
+	for i, col := range idx.Columns {
+		parts[i] = col
+		if prefixLengths[i] != "" {
+			parts[i] += "(" + prefixLengths[i] + ")"
+		}
+	}
+	return parts
+}
+
+// indexClause renders the part of CREATE INDEX after the table name: the
+// access method, key columns with operator classes and ordering, INCLUDE
+// columns and storage parameters. quote quotes plain column names.
+func indexClause(idx *Index, driver string, quote func(string) string) string {
+	pg := engineFamily(driver) == "postgres"
+	opClasses := indexColumnAttributes(idx, idx.OpClasses)
+	orders := indexColumnAttributes(idx, idx.Orders)
+	prefixLengths := indexColumnAttributes(idx, idx.PrefixLengths)
+	keys := make([]string, len(idx.Columns))
+	for i, col := range idx.Columns {
+		key := col
+		if !strings.ContainsAny(col, "( ") {
+			key = quote(col)
+		}
+		if prefixLengths[i] != "" && !pg {
+			key += "(" + prefixLengths[i] + ")"
+		}
+		if opClasses[i] != "" && pg {
+			key += " " + opClasses[i]
+		}
+		if orders[i] != "" {
+			key += " " + orders[i]
+		}
+		keys[i] = key
+	}
+	clause := ""
+	if pg && idx.Method != "" && idx.Method != "btree" {
+		clause = "USING " + idx.Method + " "
+	}
+	clause += "(" + strings.Join(keys, ", ") + ")"
+	if len(idx.Include) > 0 && pg {
+		include := make([]string, len(idx.Include))
+		for i, col := range idx.Include {
+			include[i] = quote(col)
+		}
+		clause += " INCLUDE (" + strings.Join(include, ", ") + ")"
+	}
+	if len(idx.Options) > 0 && pg {
+		var params []string
+		for _, key := range getSortedKeys(idx.Options) {
+			params = append(params, key+" = "+idx.Options[key])
+		}
+		clause += " WITH (" + strings.Join(params, ", ") + ")"
+	}
+	if !pg && idx.Method == "hash" {
+		clause += " USING HASH"
+	}
+	return clause
+}
+
+func compareCheck(source, target *CheckConstr) []AttributeChange {
+	var diffs []AttributeChange
+
+	if normalizeCheckExpression(source.Expression) != normalizeCheckExpression(target.Expression) {
+		diffs = append(diffs, attributeChange("expression", source.Expression, target.Expression))
+	}
+
+	if source.Status != target.Status {
+		diffs = append(diffs, attributeChange("status", describeConstraintStatus(source.Status), describeConstraintStatus(target.Status)))
+	}
+
+	return diffs
+}
+
+// compareRewriteRule describes how two rewrite rules differ, e.g.
+// "instead: false → true; actions: "NOTHING" → "INSERT INTO ...""
+func compareRewriteRule(source, target *RewriteRule) []AttributeChange {
+	var diffs []AttributeChange
+	if source.Event != target.Event {
+		diffs = append(diffs, attributeChange("event", source.Event, target.Event))
+	}
+	if source.Instead != target.Instead {
+		diffs = append(diffs, attributeChange("instead", source.Instead, target.Instead))
+	}
+	if source.Condition != target.Condition {
+		diffs = append(diffs, quotedChange("condition", source.Condition, target.Condition))
+	}
+	if source.Actions != target.Actions {
+		diffs = append(diffs, quotedChange("actions", source.Actions, target.Actions))
+	}
+	return diffs
+}
+
+func compareTrigger(source, target *Trigger) []AttributeChange {
+	var diffs []AttributeChange
+
+	if source.Timing != target.Timing {
+		diffs = append(diffs, attributeChange("timing", source.Timing, target.Timing))
+	}
+	if !equalStringSlices(source.Events, target.Events) {
+		diffs = append(diffs, attributeChange("events", strings.Join(source.Events, " OR "), strings.Join(target.Events, " OR ")))
+	}
+	if source.Level != target.Level {
+		diffs = append(diffs, attributeChange("level", source.Level, target.Level))
+	}
+	if source.Condition != target.Condition {
+		diffs = append(diffs, quotedChange("condition", source.Condition, target.Condition))
+	}
+	if source.Function != target.Function {
+		diffs = append(diffs, attributeChange("function", source.Function, target.Function))
+	}
+	if strings.Join(strings.Fields(source.Body), " ") != strings.Join(strings.Fields(target.Body), " ") {
+		diffs = append(diffs, quotedChange("body", source.Body, target.Body))
+	}
+	if source.Status != target.Status {
+		diffs = append(diffs, attributeChange("status", describeTriggerStatus(source.Status), describeTriggerStatus(target.Status)))
+	}
+
+	return diffs
+}
+
+// describeTriggerStatus renders a trigger status for diffs
+func describeTriggerStatus(status string) string {
+	if status == "" {
+		return "enabled"
+	}
+	return status
+}
+
+// collapseUniqueIndexOverlap merges findings where a UNIQUE constraint on one
+// side is the same logical object as a unique index on the other (or where
+// both the constraint and its backing index were reported), so one change
+// yields one finding instead of two.
+func collapseUniqueIndexOverlap(diff *TableDiff, source, target *Table) {
+	var remaining []string
+	for _, name := range diff.UniquesOnlyInSource {
+		idx, ok := target.Indexes[name]
+		if !ok || !idx.IsUnique {
+			remaining = append(remaining, name)
+			continue
+		}
+		changes := []AttributeChange{attributeChange("kind", "constraint", "unique index")}
+		if cols := source.UniqueConstraints[name].Columns; !equalStringSlices(cols, idx.Columns) {
+			changes = append(changes, attributeChange("columns", cols, idx.Columns))
+		}
+		diff.UniqueDiffs = append(diff.UniqueDiffs, &UniqueDiff{Name: name, Diff: renderChanges(changes), Changes: changes})
+		diff.IndexesOnlyInTarget = removeNames(diff.IndexesOnlyInTarget, name)
+	}
+	diff.UniquesOnlyInSource = remaining
+
+	remaining = nil
+	for _, name := range diff.UniquesOnlyInTarget {
+		idx, ok := source.Indexes[name]
+		if !ok || !idx.IsUnique {
+			remaining = append(remaining, name)
+			continue
+		}
+		changes := []AttributeChange{attributeChange("kind", "unique index", "constraint")}
+		if cols := target.UniqueConstraints[name].Columns; !equalStringSlices(idx.Columns, cols) {
+			changes = append(changes, attributeChange("columns", idx.Columns, cols))
+		}
+		diff.UniqueDiffs = append(diff.UniqueDiffs, &UniqueDiff{Name: name, Diff: renderChanges(changes), Changes: changes})
+		diff.IndexesOnlyInSource = removeNames(diff.IndexesOnlyInSource, name)
+	}
+	diff.UniquesOnlyInTarget = remaining
+
+	// A constraint reported on one side must not be echoed by its backing index
+	diff.IndexesOnlyInSource = removeNames(diff.IndexesOnlyInSource, diff.UniquesOnlyInSource...)
+	diff.IndexesOnlyInTarget = removeNames(diff.IndexesOnlyInTarget, diff.UniquesOnlyInTarget...)
+
+	uniqueDiffNames := make(map[string]bool)
+	for _, d := range diff.UniqueDiffs {
+		uniqueDiffNames[d.Name] = true
+	}
+	// The backing index of a changed constraint keeps the changes the
+	// constraint does not cover, e.g. its method or storage parameters. The
+	// attributes lined up with the columns follow a change of the columns.
+	var indexDiffs []*IndexDiff
+	for _, d := range diff.IndexDiffs {
+		if !uniqueDiffNames[d.Name] {
+			indexDiffs = append(indexDiffs, d)
+			continue
+		}
+		covered := map[string]bool{"columns": true, "unique": true}
+		for _, c := range d.Changes {
+			if c.Attribute == "columns" {
+				covered["op_classes"], covered["orders"], covered["prefix_lengths"] = true, true, true
+			}
+		}
+		var changes []AttributeChange
+		for _, c := range d.Changes {
+			if !covered[c.Attribute] {
+				changes = append(changes, c)
+			}
+		}
+		if len(changes) > 0 {
+			indexDiffs = append(indexDiffs, &IndexDiff{Name: d.Name, Diff: renderChanges(changes), Changes: changes})
+		}
+	}
+	diff.IndexDiffs = indexDiffs
+
+	sort.SliceStable(diff.UniqueDiffs, func(i, j int) bool { return diff.UniqueDiffs[i].Name < diff.UniqueDiffs[j].Name })
+}
+
+// matchByDefinition returns a copy of target whose constraints and indexes
+// that exist under another name in source are renamed to it when they cover
+// the same thing: foreign keys the same columns and referenced table, unique
+// constraints and indexes the same key parts, checks the same expression.
+// Their remaining attributes are then compared as usual, so auto-generated
+// names (users_email_key vs uq_users_email) stop showing up as drift.
+func matchByDefinition(source, target *Table) *Table {
+	t := *target
+	t.ForeignKeys = renameByDefinition(source.ForeignKeys, target.ForeignKeys, func(fk *ForeignKey) string {
+		return strings.Join(fk.Columns, ",") + "|" + fk.RefTable
+	})
+	t.UniqueConstraints = renameByDefinition(source.UniqueConstraints, target.UniqueConstraints, func(u *Unique) string {
+		return strings.Join(u.Columns, ",")
+	})
+	t.Indexes = renameByDefinition(source.Indexes, target.Indexes, func(idx *Index) string {
+		return strings.Join(idx.Columns, ",")
+	})
+	t.CheckConstraints = renameByDefinition(source.CheckConstraints, target.CheckConstraints, func(c *CheckConstr) string {
+		return normalizeCheckExpression(c.Expression)
+	})
+	return &t
+}
+
+// renameByDefinition renames the entries of target that have no namesake in
+// source to the name of a source entry with the same key and no namesake in
+// target. Pairs are made in name order, so the result is deterministic.
+func renameByDefinition[T any](source, target map[string]T, key func(T) string) map[string]T {
+	unmatched := make(map[string][]string)
+	for _, name := range getSortedKeys(target) {
+		if _, ok := source[name]; !ok {
+			k := key(target[name])
+			unmatched[k] = append(unmatched[k], name)
+		}
+	}
+	renamed := make(map[string]T, len(target))
+	for name, obj := range target {
+		renamed[name] = obj
+	}
+	for _, name := range getSortedKeys(source) {
+		if _, ok := target[name]; ok {
+			continue
+		}
+		k := key(source[name])
+		if candidates := unmatched[k]; len(candidates) > 0 {
+			delete(renamed, candidates[0])
+			renamed[name] = target[candidates[0]]
+			unmatched[k] = candidates[1:]
+		}
+	}
+	return renamed
+}
+
+var (
+	// checkLiteralPattern matches string literals, with the character set
+	// introducer MySQL puts in front of them (_utf8mb4'a')
+	checkLiteralPattern = regexp.MustCompile(`(?:_[a-z0-9]+)?('(?:[^']|'')*')`)
+	checkCastPattern    = regexp.MustCompile(`::\s*(?:character varying|double precision|(?:timestamp|time) (?:with|without) time zone|bit varying|[a-z_][a-z0-9_]*)(?:\s*\(\s*\d+(?:\s*,\s*\d+)?\s*\))?(?:\[\])*`)
+	// checkAtomParenPattern matches parentheses around a single column,
+	// literal, number or array that aren't those of a function call
+	checkAtomParenPattern = regexp.MustCompile(`(^|[^a-z0-9_$])\(([a-z_][a-z0-9_$.]*|'\d+'|-?\d+(?:\.\d+)?|array\[[^\[\]]*\])\)`)
+	// checkTermParenPattern matches parentheses around a term without AND
+	// or OR that stands between AND and OR operators, which bind looser
+	checkTermParenPattern = regexp.MustCompile(`(^|\(| and | or )\(([^()]*)\)($|\)| and | or )`)
+	checkAnyArrayPattern  = regexp.MustCompile(`(=|<>) (any|all) array\[([^\[\]]*)\]`)
+	checkCommaPattern     = regexp.MustCompile(`\s*,\s*`)
+	// checkLiteralRefPattern matches the placeholders string literals are
+	// replaced by while normalizing
+	checkLiteralRefPattern = regexp.MustCompile(`'\d+'`)
+)
+
+// normalizeCheckExpression reduces a check constraint expression to the
+// form compared: without the CHECK keyword, casts, identifier quotes and
+// redundant parentheses, lower case outside string literals and quoted
+// identifiers, and with
+// Postgres' "= ANY (ARRAY[...])" spelled as the IN list it was written as.
+// Expression keeps the extracted text for reports and migrations.
+func normalizeCheckExpression(expr string) string {
+	expr = strings.ReplaceAll(strings.TrimSpace(expr), `\'`, "'")
+	var literals []string
+	expr = checkLiteralPattern.ReplaceAllStringFunc(expr, func(lit string) string {
+		literals = append(literals, checkLiteralPattern.FindStringSubmatch(lit)[1])
+		return fmt.Sprintf("'%d'", len(literals)-1)
+	})
+	expr = lowerOutsideQuotes(strings.Join(strings.Fields(expr), " "))
+	if strings.HasPrefix(expr, "check") {
+		expr = strings.TrimSpace(expr[len("check"):])
+	}
+	expr = strings.NewReplacer("`", "", `"`, "", "( ", "(", " )", ")").Replace(expr)
+	expr = checkCastPattern.ReplaceAllString(expr, "")
+	expr = checkCommaPattern.ReplaceAllString(expr, ", ")
+	for {
+		next := checkAtomParenPattern.ReplaceAllString(expr, "$1$2")
+		next = checkTermParenPattern.ReplaceAllStringFunc(next, func(m string) string {
+			parts := checkTermParenPattern.FindStringSubmatch(m)
+			if strings.Contains(parts[2], " and ") || strings.Contains(parts[2], " or ") {
+				return m
+			}
+			return parts[1] + parts[2] + parts[3]
+		})
+		next = stripWrappingParens(next)
+		if next == expr {
+			break
+		}
+		expr = next
+	}
+	expr = checkAnyArrayPattern.ReplaceAllStringFunc(expr, func(m string) string {
+		parts := checkAnyArrayPattern.FindStringSubmatch(m)
+		switch parts[1] + " " + parts[2] {
+		case "= any":
+			return "in (" + parts[3] + ")"
+		case "<> all":
+			return "not in (" + parts[3] + ")"
+		}
+		return m
+	})
+	return checkLiteralRefPattern.ReplaceAllStringFunc(expr, func(m string) string {
+		i, _ := strconv.Atoi(m[1 : len(m)-1])
+		return literals[i]
+	})
+}
+
+// lowerOutsideQuotes lower-cases expr except for its double-quoted
+// identifiers, whose case is significant
+func lowerOutsideQuotes(expr string) string {
+	var b strings.Builder
+	quoted := false
+	for _, r := range expr {
+		if r == '"' {
+			quoted = !quoted
+		} else if !quoted {
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// stripWrappingParens removes parentheses enclosing all of expr
+func stripWrappingParens(expr string) string {
+	for strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+		depth := 0
+		for i, r := range expr {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 && i < len(expr)-1 {
+				return expr
+			}
+		}
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+	return expr
+}
+
+// pairByDefinition removes one-sided names that have an identically defined
+// counterpart under a different name on the other side
+func pairByDefinition[T any](sourceMap, targetMap map[string]T, onlyInSource, onlyInTarget *[]string, signature func(T) string) {
+	unmatched := make(map[string][]string)
+	for _, name := range *onlyInTarget {
+		sig := signature(targetMap[name])
+		unmatched[sig] = append(unmatched[sig], name)
+	}
+
+	var paired []string
+	var remaining []string
+	for _, name := range *onlyInSource {
+		sig := signature(sourceMap[name])
+		if candidates := unmatched[sig]; len(candidates) > 0 {
+			paired = append(paired, candidates[0])
+			unmatched[sig] = candidates[1:]
+			continue
+		}
+		remaining = append(remaining, name)
+	}
+
+	*onlyInSource = remaining
+	*onlyInTarget = removeNames(*onlyInTarget, paired...)
+}
+
+func foreignKeySignature(fk *ForeignKey) string {
+	return fmt.Sprintf("%v|%s|%v|%s|%s", fk.Columns, fk.RefTable, fk.RefColumns, fk.OnDelete, fk.OnUpdate)
+}
+
+// Generic comparison helper for maps
+func compareMaps[T any, D any](
+	sourceMap, targetMap map[string]T,
+	onlyInSource, onlyInTarget *[]string,
+	compareFn func(T, T) []AttributeChange,
+	diffs *[]D,
+) {
+	sourceKeys := getSortedKeys(sourceMap)
+	targetKeys := getSortedKeys(targetMap)
+
+	sourceSet := makeSet(sourceKeys)
+	targetSet := makeSet(targetKeys)
+
+	for _, key := range sourceKeys {
+		if !targetSet[key] {
+			*onlyInSource = append(*onlyInSource, key)
+		}
+	}
+
+	for _, key := range targetKeys {
+		if !sourceSet[key] {
+			*onlyInTarget = append(*onlyInTarget, key)
+		}
+	}
+
+	for _, key := range sourceKeys {
+		if targetSet[key] {
+			changes := compareFn(sourceMap[key], targetMap[key])
+			if len(changes) > 0 {
+				diffStr := renderChanges(changes)
+				// Use reflection to create the appropriate diff type
+				var diff D
+				switch any(diff).(type) {
+				case *FKDiff:
+					*diffs = append(*diffs, any(&FKDiff{Name: key, Diff: diffStr, Changes: changes}).(D))
+				case *UniqueDiff:
+					*diffs = append(*diffs, any(&UniqueDiff{Name: key, Diff: diffStr, Changes: changes}).(D))
+				case *IndexDiff:
+					*diffs = append(*diffs, any(&IndexDiff{Name: key, Diff: diffStr, Changes: changes}).(D))
+				case *CheckDiff:
+					*diffs = append(*diffs, any(&CheckDiff{Name: key, Diff: diffStr, Changes: changes}).(D))
+				case *TriggerDiff:
+					*diffs = append(*diffs, any(&TriggerDiff{Name: key, Diff: diffStr, Changes: changes}).(D))
+				case *RewriteRuleDiff:
+					*diffs = append(*diffs, any(&RewriteRuleDiff{Name: key, Diff: diffStr, Changes: changes}).(D))
+				}
+			}
+		}
+	}
+}
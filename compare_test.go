@@ -0,0 +1,166 @@
+package dbdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareKeepsChangesWhole(t *testing.T) {
+	source, target := newTable("orders"), newTable("orders")
+	stringPtr := func(s string) *string { return &s }
+	source.Columns["note"] = &Column{Name: "note", DataType: "text", IsNullable: true, DefaultValue: stringPtr("'a; b'")}
+	target.Columns["note"] = &Column{Name: "note", DataType: "text", DefaultValue: stringPtr("'a → b'")}
+	source.CheckConstraints["note_check"] = &CheckConstr{Name: "note_check", Expression: "note <> 'x; y → z'"}
+	target.CheckConstraints["note_check"] = &CheckConstr{Name: "note_check", Expression: "note <> 'x'"}
+	source.Options = map[string]string{"fillfactor": "70"}
+	target.Options = map[string]string{"fillfactor": "80"}
+
+	diff := compareTable(source, target, NewFilterConfig(), nil)
+
+	if len(diff.ColumnDiffs) != 1 || len(diff.CheckDiffs) != 1 {
+		t.Fatalf("diff = %+v, want one column and one check diff", diff)
+	}
+	if want := []AttributeChange{attributeChange("nullable", true, false), quotedChange("default", "'a; b'", "'a → b'")}; !reflect.DeepEqual(diff.ColumnDiffs[0].Changes, want) {
+		t.Errorf("column changes = %+v, want %+v", diff.ColumnDiffs[0].Changes, want)
+	}
+	if want := `nullable: true → false; default: "'a; b'" → "'a → b'"`; diff.ColumnDiffs[0].Diff != want {
+		t.Errorf("column diff = %s, want %s", diff.ColumnDiffs[0].Diff, want)
+	}
+	check := diff.CheckDiffs[0]
+	if len(check.Changes) != 1 || check.Changes[0].From != "note <> 'x; y → z'" || check.Changes[0].To != "note <> 'x'" {
+		t.Errorf("check changes = %+v, want the expressions whole", check.Changes)
+	}
+	if want := "options: fillfactor: 70 → 80"; diff.OptionsDiff == nil || *diff.OptionsDiff != want {
+		t.Errorf("options diff = %v, want %s", diff.OptionsDiff, want)
+	}
+}
+
+func TestUserTypeChangesSetNotesApart(t *testing.T) {
+	source := &UserType{Name: "money_t", Kind: UserTypeComposite, Attributes: []*TypeAttribute{{Name: "amount", Type: "integer"}}}
+	target := &UserType{Name: "money_t", Kind: UserTypeComposite, Attributes: []*TypeAttribute{{Name: "amount", Type: "numeric"}}}
+
+	changes := userTypeChanges(source, target)
+	want := AttributeChange{Attribute: "attributes", From: "(amount integer)", To: "(amount numeric)", Note: "changed [amount: integer → numeric]"}
+	if len(changes) != 1 || changes[0] != want {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	if got, want := renderChanges(changes), "attributes: (amount integer) → (amount numeric) (changed [amount: integer → numeric])"; got != want {
+		t.Errorf("rendered = %s, want %s", got, want)
+	}
+}
+
+func TestFindingsCarryChanges(t *testing.T) {
+	source := &Schema{Tables: map[string]*Table{"users": newTable("users")}}
+	target := &Schema{Tables: map[string]*Table{"users": newTable("users")}}
+	source.Tables["users"].Columns["id"] = &Column{Name: "id", DataType: "integer"}
+	target.Tables["users"].Columns["id"] = &Column{Name: "id", DataType: "bigint"}
+	source.Tables["users"].Comment = "app users"
+
+	findings := NewResult(ComputeDiff(source, target, NewFilterConfig())).Findings()
+	if len(findings) != 2 {
+		t.Fatalf("findings = %+v, want the comment and the column type", findings)
+	}
+	for _, f := range findings {
+		if len(f.Changes) != 1 || renderChanges(f.Changes) != f.Detail {
+			t.Errorf("finding %s has changes %+v for detail %s", f.Key(), f.Changes, f.Detail)
+		}
+	}
+}
+
+func TestNormalizeCheckExpression(t *testing.T) {
+	for _, tc := range []struct{ a, b string }{
+		{`CHECK ((price > (0)::numeric))`, `price > 0`},
+		{`(status = ANY (ARRAY['a'::text, 'b'::text]))`, `status IN ('a','b')`},
+		{`"Status" <> 'X'`, `"Status"  <>  'X'`},
+	} {
+		if a, b := normalizeCheckExpression(tc.a), normalizeCheckExpression(tc.b); a != b {
+			t.Errorf("normalizeCheckExpression(%s) = %s, want %s like %s", tc.a, a, b, tc.b)
+		}
+	}
+	// Quoted identifiers and string literals keep their case
+	for _, tc := range []struct{ a, b string }{
+		{`"Status" <> 'x'`, `status <> 'x'`},
+		{`status <> 'X'`, `status <> 'x'`},
+	} {
+		if a, b := normalizeCheckExpression(tc.a), normalizeCheckExpression(tc.b); a == b {
+			t.Errorf("normalizeCheckExpression(%s) = normalizeCheckExpression(%s) = %s, want them apart", tc.a, tc.b, a)
+		}
+	}
+}
+
+func TestComputeDiffFiltersTypesAndExtensionsByIgnoreLists(t *testing.T) {
+	source := &Schema{Tables: map[string]*Table{"orders": newTable("orders")}, Types: map[string]*UserType{
+		"status_t": {Name: "status_t", Kind: UserTypeEnum, Labels: []string{"new"}},
+		"tmp_t":    {Name: "tmp_t", Kind: UserTypeEnum, Labels: []string{"a"}},
+	}}
+	target := &Schema{Tables: map[string]*Table{"orders": newTable("orders")}, Extensions: map[string]*Extension{
+		"postgis": {Name: "postgis"},
+		"tmp_ext": {Name: "tmp_ext"},
+	}}
+
+	filter := NewFilterConfig()
+	filter.OnlyTables = []string{"orders"}
+	filter.IgnoreTableGlobs = []string{"tmp_*"}
+	diff := ComputeDiff(source, target, filter)
+	if want := []string{"status_t"}; !reflect.DeepEqual(diff.TypesOnlyInSource, want) {
+		t.Errorf("types only in source = %v, want %v: --only-tables names tables, the ignore lists apply", diff.TypesOnlyInSource, want)
+	}
+	if want := []string{"postgis"}; !reflect.DeepEqual(diff.ExtensionsOnlyInTarget, want) {
+		t.Errorf("extensions only in target = %v, want %v", diff.ExtensionsOnlyInTarget, want)
+	}
+}
+
+func TestCollapseUniqueIndexOverlapKeepsIndexOnlyChanges(t *testing.T) {
+	source, target := newTable("users"), newTable("users")
+	source.UniqueConstraints["users_email_key"] = &Unique{Name: "users_email_key", Columns: []string{"email"}}
+	target.UniqueConstraints["users_email_key"] = &Unique{Name: "users_email_key", Columns: []string{"email", "tenant_id"}}
+	source.Indexes["users_email_key"] = &Index{Name: "users_email_key", Columns: []string{"email"}, IsUnique: true, Method: "btree"}
+	target.Indexes["users_email_key"] = &Index{Name: "users_email_key", Columns: []string{"email", "tenant_id"}, IsUnique: true, Method: "hash"}
+
+	diff := compareTable(source, target, NewFilterConfig(), nil)
+	if len(diff.UniqueDiffs) != 1 || diff.UniqueDiffs[0].Changes[0].Attribute != "columns" {
+		t.Errorf("unique diffs = %+v, want the columns change", diff.UniqueDiffs)
+	}
+	if len(diff.IndexDiffs) != 1 {
+		t.Fatalf("index diffs = %v, want only the method change", diff.IndexDiffs)
+	}
+	if want := []AttributeChange{attributeChange("method", "btree", "hash")}; !reflect.DeepEqual(diff.IndexDiffs[0].Changes, want) {
+		t.Errorf("index diffs = %v, want only the method change", diff.IndexDiffs[0].Diff)
+	}
+}
+
+func TestOnlyTablesKeepsSchemaObjects(t *testing.T) {
+	source := &Schema{Tables: map[string]*Table{"orders": newTable("orders")}, Routines: map[string]*Routine{
+		"total(integer)": {Name: "total", Kind: "function", Body: "SELECT 1"},
+		"tmp_fix()":      {Name: "tmp_fix", Kind: "function", Body: "SELECT 2"},
+	}, Sequences: map[string]*Sequence{
+		"invoice_no":    {Name: "invoice_no", DataType: "bigint", Start: 1, Increment: 1},
+		"tmp_counter":   {Name: "tmp_counter", DataType: "bigint", Start: 1, Increment: 1},
+		"users_id_seq":  {Name: "users_id_seq", DataType: "integer", Start: 1, Increment: 1, OwnedBy: "users.id"},
+		"orders_id_seq": {Name: "orders_id_seq", DataType: "integer", Start: 1, Increment: 1, OwnedBy: "orders.id"},
+	}}
+	target := &Schema{Tables: map[string]*Table{"orders": newTable("orders")}, Events: map[string]*Event{
+		"purge_sessions": {Name: "purge_sessions", Schedule: "EVERY 1 DAY"},
+		"tmp_backfill":   {Name: "tmp_backfill", Schedule: "EVERY 1 HOUR"},
+	}, ForeignServers: map[string]*ForeignServer{
+		"remote":   {Name: "remote", Wrapper: "postgres_fdw"},
+		"tmp_link": {Name: "tmp_link", Wrapper: "postgres_fdw"},
+	}}
+
+	filter := NewFilterConfig()
+	filter.OnlyTables = []string{"orders"}
+	filter.IgnoreTableGlobs = []string{"tmp_*"}
+	diff := ComputeDiff(source, target, filter)
+	if want := []string{"total(integer)"}; !reflect.DeepEqual(diff.RoutinesOnlyInSource, want) {
+		t.Errorf("routines only in source = %v, want %v", diff.RoutinesOnlyInSource, want)
+	}
+	if want := []string{"purge_sessions"}; !reflect.DeepEqual(diff.EventsOnlyInTarget, want) {
+		t.Errorf("events only in target = %v, want %v", diff.EventsOnlyInTarget, want)
+	}
+	if want := []string{"invoice_no", "orders_id_seq"}; !reflect.DeepEqual(diff.SequencesOnlyInSource, want) {
+		t.Errorf("sequences only in source = %v, want %v", diff.SequencesOnlyInSource, want)
+	}
+	if want := []string{"remote"}; !reflect.DeepEqual(diff.ForeignServersOnlyInTarget, want) {
+		t.Errorf("foreign servers only in target = %v, want %v", diff.ForeignServersOnlyInTarget, want)
+	}
+}
@@ -0,0 +1,110 @@
+package dbdiff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// CONFIG FILE - Named connections and shared settings
+// ============================================================================
+
+// Config is the optional dbdiff configuration file (YAML or JSON)
+type Config struct {
+	Connections map[string]*ConnectionConfig `yaml:"connections" json:"connections"`
+	Serve       ServeConfig                  `yaml:"serve" json:"serve"`
+	// TypeEquivalences are extra classes of types that compare equal across
+	// engines, added to defaultTypeEquivalences
+	TypeEquivalences [][]string `yaml:"type_equivalences" json:"type_equivalences"`
+	// Tickets configures --create-ticket
+	Tickets TicketConfig `yaml:"tickets" json:"tickets"`
+	// Sensitive tags columns holding personal or secret data
+	Sensitive SensitiveConfig `yaml:"sensitive" json:"sensitive"`
+	// ExcludePresets define or replace presets of --exclude-preset: lists
+	// of table names, with * and ? wildcards
+	ExcludePresets map[string][]string `yaml:"exclude_presets" json:"exclude_presets"`
+	// AppendOnly are the tables, with * and ? wildcards, that must only
+	// grow: event stores, audit logs
+	AppendOnly []string `yaml:"append_only" json:"append_only"`
+	// SchemaMap pairs source schemas with the target schemas holding the
+	// same tables under another name, e.g. {app: app_v2}
+	SchemaMap map[string]string `yaml:"schema_map" json:"schema_map"`
+}
+
+// ConnectionConfig is a named database connection
+type ConnectionConfig struct {
+	Driver string `yaml:"driver" json:"driver"`
+	DSN    string `yaml:"dsn" json:"dsn"`
+}
+
+// ServeConfig holds defaults for `dbdiff serve`
+type ServeConfig struct {
+	Listen  string `yaml:"listen" json:"listen"`
+	Refresh string `yaml:"refresh" json:"refresh"`
+	// Token is the bearer token requests must send; DBDIFF_SERVE_TOKEN
+	// overrides it
+	Token string `yaml:"token" json:"token"`
+}
+
+// configFileNames are looked up, in order, in the working directory
+var configFileNames = []string{"dbdiff.yaml", "dbdiff.yml", "dbdiff.json", ".dbdiff.yaml", ".dbdiff.yml", ".dbdiff.json"}
+
+// findConfigFile returns the first config file found in the working directory
+// or the user config directory (~/.config/dbdiff/config.yaml), or ""
+func findConfigFile() string {
+	for _, name := range configFileNames {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		for _, name := range []string{"config.yaml", "config.yml", "config.json"} {
+			candidate := filepath.Join(dir, "dbdiff", name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// LoadConfig reads a config file. An empty path triggers discovery; when no
+// file is found an empty config is returned.
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		path = findConfigFile()
+	}
+	cfg := &Config{Connections: make(map[string]*ConnectionConfig)}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := readTextFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// YAML is a superset of JSON, so one decoder handles both
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for name, conn := range cfg.Connections {
+		if conn == nil || conn.Driver == "" || conn.DSN == "" {
+			return nil, fmt.Errorf("%s: connection %q needs driver and dsn", path, name)
+		}
+		conn.DSN = os.ExpandEnv(conn.DSN)
+	}
+	cfg.Serve.Token = os.ExpandEnv(cfg.Serve.Token)
+	if j := cfg.Tickets.Jira; j != nil {
+		j.URL, j.User, j.Token = os.ExpandEnv(j.URL), os.ExpandEnv(j.User), os.ExpandEnv(j.Token)
+	}
+	if sn := cfg.Tickets.ServiceNow; sn != nil {
+		sn.URL, sn.User, sn.Password = os.ExpandEnv(sn.URL), os.ExpandEnv(sn.User), os.ExpandEnv(sn.Password)
+	}
+	if _, err := invertSchemaMap(cfg.SchemaMap); err != nil {
+		return nil, fmt.Errorf("%s: schema_map: %w", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,334 @@
+package dbdiff
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// ============================================================================
+// CONFORMANCE KIT - Fixtures every dialect must read back
+// ============================================================================
+
+// ConformanceFixture is DDL that a dialect must extract as Expected. The DDL
+// is kept portable across the supported databases; fixtures for a specific
+// database can be loaded from a JSON file with the same fields.
+type ConformanceFixture struct {
+	Name string `json:"name"`
+	// Setup creates the fixture tables; Teardown drops them again
+	Setup    []string `json:"setup"`
+	Teardown []string `json:"teardown"`
+	// Expected is the snapshot JSON of the fixture tables
+	Expected json.RawMessage `json:"expected"`
+}
+
+// ConformanceFixtures are the built-in fixtures. Table names start with
+// dbdiff_conf_ so that they cannot clash with the objects of a scratch
+// database.
+var ConformanceFixtures = []ConformanceFixture{
+	{
+		Name: "columns",
+		Setup: []string{`CREATE TABLE dbdiff_conf_items (
+			id INTEGER NOT NULL,
+			label VARCHAR(100),
+			price NUMERIC(10,2) NOT NULL,
+			stock INTEGER DEFAULT 0 NOT NULL,
+			CONSTRAINT dbdiff_conf_items_pk PRIMARY KEY (id)
+		)`},
+		Teardown: []string{"DROP TABLE dbdiff_conf_items"},
+		Expected: json.RawMessage(`{"tables": {"dbdiff_conf_items": {
+			"columns": {
+				"id": {"name": "id", "data_type": "integer", "is_nullable": false},
+				"label": {"name": "label", "data_type": "varchar", "is_nullable": true},
+				"price": {"name": "price", "data_type": "numeric", "is_nullable": false},
+				"stock": {"name": "stock", "data_type": "integer", "is_nullable": false, "default_value": "0"}
+			},
+			"primary_key": {"name": "dbdiff_conf_items_pk", "columns": ["id"]}
+		}}}`),
+	},
+	{
+		Name: "unique constraints and indexes",
+		Setup: []string{
+			`CREATE TABLE dbdiff_conf_users (
+				id INTEGER NOT NULL,
+				email VARCHAR(100) NOT NULL,
+				team INTEGER,
+				handle VARCHAR(50),
+				CONSTRAINT dbdiff_conf_users_pk PRIMARY KEY (id),
+				CONSTRAINT dbdiff_conf_users_email_uq UNIQUE (email)
+			)`,
+			"CREATE INDEX dbdiff_conf_users_team_idx ON dbdiff_conf_users (team, id)",
+			"CREATE UNIQUE INDEX dbdiff_conf_users_handle_idx ON dbdiff_conf_users (handle)",
+		},
+		Teardown: []string{"DROP TABLE dbdiff_conf_users"},
+		Expected: json.RawMessage(`{"tables": {"dbdiff_conf_users": {
+			"columns": {
+				"id": {"name": "id", "data_type": "integer", "is_nullable": false},
+				"email": {"name": "email", "data_type": "varchar", "is_nullable": false},
+				"team": {"name": "team", "data_type": "integer", "is_nullable": true},
+				"handle": {"name": "handle", "data_type": "varchar", "is_nullable": true}
+			},
+			"primary_key": {"name": "dbdiff_conf_users_pk", "columns": ["id"]},
+			"unique_constraints": {
+				"dbdiff_conf_users_email_uq": {"name": "dbdiff_conf_users_email_uq", "columns": ["email"]}
+			},
+			"indexes": {
+				"dbdiff_conf_users_team_idx": {"name": "dbdiff_conf_users_team_idx", "columns": ["team", "id"], "is_unique": false},
+				"dbdiff_conf_users_handle_idx": {"name": "dbdiff_conf_users_handle_idx", "columns": ["handle"], "is_unique": true}
+			}
+		}}}`),
+	},
+	{
+		Name: "foreign keys",
+		Setup: []string{
+			`CREATE TABLE dbdiff_conf_parents (
+				id INTEGER NOT NULL,
+				CONSTRAINT dbdiff_conf_parents_pk PRIMARY KEY (id)
+			)`,
+			`CREATE TABLE dbdiff_conf_children (
+				id INTEGER NOT NULL,
+				parent_id INTEGER NOT NULL,
+				CONSTRAINT dbdiff_conf_children_pk PRIMARY KEY (id),
+				CONSTRAINT dbdiff_conf_children_parent_fk FOREIGN KEY (parent_id)
+					REFERENCES dbdiff_conf_parents (id) ON DELETE CASCADE
+			)`,
+		},
+		Teardown: []string{"DROP TABLE dbdiff_conf_children", "DROP TABLE dbdiff_conf_parents"},
+		Expected: json.RawMessage(`{"tables": {
+			"dbdiff_conf_parents": {
+				"columns": {"id": {"name": "id", "data_type": "integer", "is_nullable": false}},
+				"primary_key": {"name": "dbdiff_conf_parents_pk", "columns": ["id"]}
+			},
+			"dbdiff_conf_children": {
+				"columns": {
+					"id": {"name": "id", "data_type": "integer", "is_nullable": false},
+					"parent_id": {"name": "parent_id", "data_type": "integer", "is_nullable": false}
+				},
+				"primary_key": {"name": "dbdiff_conf_children_pk", "columns": ["id"]},
+				"foreign_keys": {
+					"dbdiff_conf_children_parent_fk": {
+						"name": "dbdiff_conf_children_parent_fk", "columns": ["parent_id"],
+						"ref_table": "dbdiff_conf_parents", "ref_columns": ["id"],
+						"on_delete": "CASCADE", "on_update": "NO ACTION"
+					}
+				}
+			}
+		}}`),
+	},
+	{
+		Name: "check constraints",
+		Setup: []string{`CREATE TABLE dbdiff_conf_orders (
+			id INTEGER NOT NULL,
+			qty INTEGER NOT NULL,
+			CONSTRAINT dbdiff_conf_orders_pk PRIMARY KEY (id),
+			CONSTRAINT dbdiff_conf_orders_qty_ck CHECK (qty > 0)
+		)`},
+		Teardown: []string{"DROP TABLE dbdiff_conf_orders"},
+		Expected: json.RawMessage(`{"tables": {"dbdiff_conf_orders": {
+			"columns": {
+				"id": {"name": "id", "data_type": "integer", "is_nullable": false},
+				"qty": {"name": "qty", "data_type": "integer", "is_nullable": false}
+			},
+			"primary_key": {"name": "dbdiff_conf_orders_pk", "columns": ["id"]},
+			"check_constraints": {
+				"dbdiff_conf_orders_qty_ck": {"name": "dbdiff_conf_orders_qty_ck", "expression": ""}
+			}
+		}}}`),
+	},
+}
+
+// ConformanceResult is the outcome of one fixture
+type ConformanceResult struct {
+	Fixture string `json:"fixture"`
+	// Findings are the differences between Expected (source) and what the
+	// dialect extracted (target)
+	Findings []Finding `json:"findings,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Passed reports whether the dialect read the fixture back as expected
+func (r ConformanceResult) Passed() bool {
+	return r.Error == "" && len(r.Findings) == 0
+}
+
+// conformanceTypes maps the base type spellings of the supported databases
+// onto the ones used by the fixtures
+var conformanceTypes = map[string]string{
+	"int":      "integer",
+	"varchar2": "varchar",
+	"nvarchar": "varchar",
+	"number":   "numeric",
+}
+
+// conformanceShape reduces a schema to what the fixtures pin down across
+// databases: base types without modifiers and the presence, not the
+// dialect-specific text, of check expressions
+func conformanceShape(schema *Schema) {
+	for _, table := range schema.Tables {
+		for _, col := range table.Columns {
+			t := typeModifierPattern.ReplaceAllString(normalizeType(col.DataType), "")
+			if alias, ok := conformanceTypes[t]; ok {
+				t = alias
+			}
+			col.DataType = t
+			col.CharMaxLength, col.NumericPrecision, col.NumericScale, col.DatetimePrecision = nil, nil, nil, nil
+		}
+		for _, check := range table.CheckConstraints {
+			check.Expression = ""
+		}
+	}
+}
+
+// RunConformance creates each fixture in db, extracts it with dialect and
+// compares it with the expected schema. db should be a scratch database: the
+// fixture tables are dropped before and after each fixture.
+func RunConformance(ctx context.Context, dialect Dialect, db *sql.DB, fixtures []ConformanceFixture) []ConformanceResult {
+	results := make([]ConformanceResult, 0, len(fixtures))
+	for _, fixture := range fixtures {
+		result := ConformanceResult{Fixture: fixture.Name}
+		findings, err := runConformanceFixture(ctx, dialect, db, fixture)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Findings = findings
+		results = append(results, result)
+	}
+	return results
+}
+
+func runConformanceFixture(ctx context.Context, dialect Dialect, db *sql.DB, fixture ConformanceFixture) ([]Finding, error) {
+	expected, err := decodeSnapshot(bytes.NewReader(fixture.Expected))
+	if err != nil {
+		return nil, fmt.Errorf("expected schema: %w", err)
+	}
+
+	// Leftovers of an interrupted run; most of these statements fail
+	for _, stmt := range fixture.Teardown {
+		db.ExecContext(ctx, stmt)
+	}
+	defer func() {
+		for _, stmt := range fixture.Teardown {
+			db.ExecContext(ctx, stmt)
+		}
+	}()
+	for _, stmt := range fixture.Setup {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("setup: %w", err)
+		}
+	}
+
+	filter := &FilterConfig{
+		OnlyTables:            getSortedKeys(expected.Tables),
+		NormalizeDefaults:     true,
+		IgnoreConstraintNames: true,
+	}
+	actual, err := dialect.ExtractSchema(ctx, db, ExtractOptions{Filter: filter})
+	if err != nil {
+		return nil, fmt.Errorf("extract: %w", err)
+	}
+	conformanceShape(expected)
+	conformanceShape(actual)
+
+	diff := ComputeDiff(expected, actual, filter)
+	SortDiff(diff, SortByName)
+	return NewResult(diff).Findings(), nil
+}
+
+// loadConformanceFixtures reads fixtures from a JSON array
+func loadConformanceFixtures(path string) ([]ConformanceFixture, error) {
+	data, err := readTextFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []ConformanceFixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+func runConformance(args []string) {
+	fs := flag.NewFlagSet("conformance", flag.ExitOnError)
+	driver := fs.String("driver", "", "Database driver (postgres, mysql, sqlserver or oracle)")
+	conn := fs.String("conn", "", "Connection string of a scratch database")
+	fixturesPath := fs.String("fixtures", "", "JSON file of additional fixtures")
+	only := fs.Bool("only-fixtures", false, "Run only the fixtures of --fixtures, not the built-in ones")
+	format := fs.String("format", FormatPretty, "Output format: pretty or json")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff conformance --driver <driver> --conn <scratch db> [--fixtures extra.json [--only-fixtures]] [--format pretty|json]")
+		fmt.Fprintln(os.Stderr, "\nCreates the conformance fixtures in a scratch database and checks that the")
+		fmt.Fprintln(os.Stderr, "dialect extracts them as expected. Tables named dbdiff_conf_* are dropped.")
+	}
+	fs.Parse(args)
+
+	if *driver == "" || *conn == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != FormatPretty && *format != FormatJSON {
+		fmt.Fprintf(os.Stderr, "Invalid format: %s (expected pretty or json)\n", *format)
+		os.Exit(1)
+	}
+	dialect := getDialect(*driver)
+	if dialect == nil {
+		fmt.Fprintf(os.Stderr, "Unsupported driver: %s\n", *driver)
+		os.Exit(1)
+	}
+
+	fixtures := ConformanceFixtures
+	if *fixturesPath != "" {
+		extra, err := loadConformanceFixtures(*fixturesPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading fixtures: %v\n", err)
+			os.Exit(1)
+		}
+		if *only {
+			fixtures = extra
+		} else {
+			fixtures = append(append([]ConformanceFixture{}, fixtures...), extra...)
+		}
+	}
+
+	db, err := openDB(*driver, *conn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	results := RunConformance(context.Background(), dialect, db, fixtures)
+	failed := 0
+	for _, r := range results {
+		if !r.Passed() {
+			failed++
+		}
+	}
+
+	if *format == FormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(results)
+	} else {
+		for _, r := range results {
+			status := "PASS"
+			if !r.Passed() {
+				status = "FAIL"
+			}
+			fmt.Printf("%s  %s\n", status, r.Fixture)
+			if r.Error != "" {
+				fmt.Printf("      %s\n", r.Error)
+			}
+			for _, f := range r.Findings {
+				fmt.Printf("      %s %s.%s %s %s\n", f.Category, f.Table, f.Name, f.Change, f.Detail)
+			}
+		}
+		fmt.Printf("\n%d/%d fixture(s) passed\n", len(results)-failed, len(results))
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,29 @@
+// Package conformancetest runs the dbdiff conformance kit from Go tests, so
+// that a Dialect implementation can be checked with go test.
+package conformancetest
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/nidhey27/dbdiff"
+)
+
+// Run creates each fixture in db, extracts it with dialect and compares it
+// with the fixture's expected schema in a subtest named after the fixture,
+// which fails with every difference found. db should be a scratch database:
+// the fixture tables are dropped before and after each fixture.
+func Run(t *testing.T, dialect dbdiff.Dialect, db *sql.DB, fixtures []dbdiff.ConformanceFixture) {
+	t.Helper()
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			result := dbdiff.RunConformance(t.Context(), dialect, db, []dbdiff.ConformanceFixture{fixture})[0]
+			if result.Error != "" {
+				t.Error(result.Error)
+			}
+			for _, f := range result.Findings {
+				t.Errorf("%s %s.%s %s %s", f.Category, f.Table, f.Name, f.Change, f.Detail)
+			}
+		})
+	}
+}
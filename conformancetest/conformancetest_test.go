@@ -0,0 +1,64 @@
+package conformancetest_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nidhey27/dbdiff"
+	"github.com/nidhey27/dbdiff/conformancetest"
+)
+
+// builtinDialects are the dialects the built-in fixtures are written for,
+// with the database/sql driver they connect through and the variable naming
+// a scratch database for them
+var builtinDialects = []struct {
+	name    string
+	dialect dbdiff.Dialect
+	driver  string
+	env     string
+}{
+	{"postgres", &dbdiff.PostgresDialect{}, "postgres", "DBDIFF_CONFORMANCE_POSTGRES"},
+	{"yugabyte", &dbdiff.YugabyteDialect{}, "postgres", "DBDIFF_CONFORMANCE_YUGABYTE"},
+	{"mysql", &dbdiff.MySQLDialect{}, "mysql", "DBDIFF_CONFORMANCE_MYSQL"},
+	{"tidb", &dbdiff.TiDBDialect{}, "mysql", "DBDIFF_CONFORMANCE_TIDB"},
+	{"sqlserver", &dbdiff.MSSQLDialect{}, "sqlserver", "DBDIFF_CONFORMANCE_SQLSERVER"},
+	{"oracle", &dbdiff.OracleDialect{}, "oracle", "DBDIFF_CONFORMANCE_ORACLE"},
+}
+
+func TestBuiltinDialects(t *testing.T) {
+	for _, d := range builtinDialects {
+		t.Run(d.name, func(t *testing.T) {
+			dsn := os.Getenv(d.env)
+			if dsn == "" {
+				t.Skipf("set %s to a scratch database to run the conformance kit", d.env)
+			}
+			db, err := sql.Open(d.driver, dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			conformancetest.Run(t, d.dialect, db, dbdiff.ConformanceFixtures)
+		})
+	}
+}
+
+func TestBuiltinFixturesOnlyUseTheirTables(t *testing.T) {
+	for _, fixture := range dbdiff.ConformanceFixtures {
+		var expected dbdiff.Schema
+		if err := json.Unmarshal(fixture.Expected, &expected); err != nil {
+			t.Errorf("%s: expected schema: %v", fixture.Name, err)
+			continue
+		}
+		if len(expected.Tables) == 0 {
+			t.Errorf("%s: expects no tables", fixture.Name)
+		}
+		for name := range expected.Tables {
+			if !strings.HasPrefix(name, "dbdiff_conf_") {
+				t.Errorf("%s: table %s would clash with a scratch database's own tables", fixture.Name, name)
+			}
+		}
+	}
+}
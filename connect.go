@@ -0,0 +1,149 @@
+package dbdiff
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ============================================================================
+// CONNECTION HELPERS - Unix sockets and cloud proxies without hand-written DSNs
+// ============================================================================
+
+// defaultCloudSQLDir is where the Cloud SQL Auth Proxy creates its sockets
+// with --unix-socket
+const defaultCloudSQLDir = "/cloudsql"
+
+// ConnectionHelper describes how to reach a database; ApplyConnectionHelper
+// turns it into the transport part of a driver's DSN. At most one of Socket,
+// CloudSQL and RDSProxy is set.
+type ConnectionHelper struct {
+	// Socket is a Unix socket: the socket file, or for Postgres also the
+	// directory holding .s.PGSQL.<port>
+	Socket string
+	// CloudSQL is a Cloud SQL instance connection name
+	// (project:region:instance) served by the Auth Proxy under CloudSQLDir
+	CloudSQL    string
+	CloudSQLDir string
+	// RDSProxy is an RDS Proxy endpoint, host[:port]; RDS Proxy requires TLS
+	RDSProxy string
+}
+
+// ApplyConnectionHelper rewrites dsn so that driver connects through h.
+// Credentials, database name and other parameters of dsn are kept.
+func ApplyConnectionHelper(driver, dsn string, h ConnectionHelper) (string, error) {
+	set := 0
+	for _, v := range []string{h.Socket, h.CloudSQL, h.RDSProxy} {
+		if v != "" {
+			set++
+		}
+	}
+	switch {
+	case set == 0:
+		return dsn, nil
+	case set > 1:
+		return "", fmt.Errorf("use only one of a socket, a Cloud SQL instance and an RDS Proxy endpoint")
+	}
+	if h.CloudSQLDir == "" {
+		h.CloudSQLDir = defaultCloudSQLDir
+	}
+
+	switch engineFamily(driver) {
+	case "postgres":
+		params := make(map[string]string)
+		switch {
+		case h.Socket != "":
+			// libpq takes the directory of the socket and the port naming it
+			params["host"] = h.Socket
+			if dir, file := filepath.Split(h.Socket); strings.HasPrefix(file, ".s.PGSQL.") {
+				params["host"], params["port"] = filepath.Clean(dir), strings.TrimPrefix(file, ".s.PGSQL.")
+			}
+		case h.CloudSQL != "":
+			params["host"] = filepath.Join(h.CloudSQLDir, h.CloudSQL)
+		default:
+			host, port := splitHostPort(h.RDSProxy, "5432")
+			params["host"], params["port"] = host, port
+			if !strings.Contains(dsn, "sslmode=verify") {
+				params["sslmode"] = "require"
+			}
+		}
+		return setPostgresParams(dsn, params)
+	case "mysql":
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case h.Socket != "":
+			cfg.Net, cfg.Addr = "unix", h.Socket
+		case h.CloudSQL != "":
+			// For MySQL the proxy's socket is the file named after the instance
+			cfg.Net, cfg.Addr = "unix", filepath.Join(h.CloudSQLDir, h.CloudSQL)
+		default:
+			host, port := splitHostPort(h.RDSProxy, "3306")
+			cfg.Net, cfg.Addr = "tcp", host+":"+port
+			if cfg.TLSConfig == "" || cfg.TLSConfig == "false" {
+				cfg.TLSConfig = "true"
+			}
+		}
+		return cfg.FormatDSN(), nil
+	}
+	return "", fmt.Errorf("socket and proxy helpers are not supported for driver %s", driver)
+}
+
+// splitHostPort splits host[:port], defaulting the port
+func splitHostPort(addr, defaultPort string) (host, port string) {
+	if i := strings.LastIndex(addr, ":"); i > 0 && !strings.HasSuffix(addr, "]") {
+		return addr[:i], addr[i+1:]
+	}
+	return addr, defaultPort
+}
+
+// setPostgresParams sets connection parameters on a Postgres DSN, either a
+// postgres:// URL or a key=value string
+func setPostgresParams(dsn string, params map[string]string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+		// A socket directory cannot be the URL host; lib/pq reads it from
+		// the query instead
+		u.Host = ""
+		q := u.Query()
+		for _, key := range getSortedKeys(params) {
+			q.Set(key, params[key])
+		}
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	// In key=value strings a later setting overrides an earlier one
+	for _, key := range getSortedKeys(params) {
+		value := strings.ReplaceAll(strings.ReplaceAll(params[key], `\`, `\\`), "'", `\'`)
+		dsn = strings.TrimSpace(dsn + " " + key + "='" + value + "'")
+	}
+	return dsn, nil
+}
+
+// connectionFlags holds the socket and proxy flags of one side of a diff
+type connectionFlags struct {
+	socket   *string
+	cloudSQL *string
+	rdsProxy *string
+}
+
+func addConnectionFlags(fs *flag.FlagSet, side string) *connectionFlags {
+	return &connectionFlags{
+		socket:   fs.String(side+"-socket", "", "Connect the "+side+" through this Unix socket (file, or directory for Postgres)"),
+		cloudSQL: fs.String(side+"-cloudsql", "", "Connect the "+side+" through the Cloud SQL Auth Proxy socket of this instance (project:region:instance)"),
+		rdsProxy: fs.String(side+"-rds-proxy", "", "Connect the "+side+" through this RDS Proxy endpoint (host[:port]), with TLS"),
+	}
+}
+
+func (c *connectionFlags) helper(cloudSQLDir string) ConnectionHelper {
+	return ConnectionHelper{Socket: *c.socket, CloudSQL: *c.cloudSQL, CloudSQLDir: cloudSQLDir, RDSProxy: *c.rdsProxy}
+}
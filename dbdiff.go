@@ -0,0 +1,566 @@
+// Package dbdiff compares database schemas. It extracts schemas from live
+// databases, snapshots and DDL scripts, diffs them into a SchemaDiff and wraps
+// the result in a Result for applications to act on; Main runs the dbdiff
+// command line tool.
+package dbdiff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ============================================================================
+// MODELS - Schema representation
+// ============================================================================
+
+type Schema struct {
+	Tables    map[string]*Table    `json:"tables"`
+	Types     map[string]*UserType `json:"types,omitempty"`
+	Sequences map[string]*Sequence `json:"sequences,omitempty"`
+	// Routines are keyed by signature, name(argument types), so that
+	// overloaded Postgres functions are told apart
+	Routines map[string]*Routine `json:"routines,omitempty"`
+	// Extensions are the Postgres extensions installed in the database
+	Extensions map[string]*Extension `json:"extensions,omitempty"`
+	// Collations are the Postgres collations the schema uses, and the
+	// database default as "default"
+	Collations map[string]*Collation `json:"collations,omitempty"`
+	// Events are the MySQL scheduled events, extracted with IncludeEvents
+	Events map[string]*Event `json:"events,omitempty"`
+	// ForeignServers are the Postgres foreign servers, extracted with
+	// IncludeForeignTables
+	ForeignServers map[string]*ForeignServer `json:"foreign_servers,omitempty"`
+	// NotComparable are the tables and catalogs the extracting role was
+	// not allowed to read; see SetAsideNotComparable
+	NotComparable []*NotComparable `json:"not_comparable,omitempty"`
+}
+
+// Collation is a Postgres collation with the version of its library (glibc
+// or ICU). Version is what the library provides now, RecordedVersion what
+// the catalog recorded when the collation was created or last refreshed.
+type Collation struct {
+	Name string `json:"name"`
+	// Schema is the schema the collation is defined in, e.g. pg_catalog
+	// for the predefined ones
+	Schema          string `json:"schema,omitempty"`
+	Provider        string `json:"provider"` // libc, icu or builtin
+	Locale          string `json:"locale,omitempty"`
+	Version         string `json:"version,omitempty"`
+	RecordedVersion string `json:"recorded_version,omitempty"`
+}
+
+// Extension is an installed Postgres extension. Version is empty when it
+// isn't known, as for CREATE EXTENSION statements of a DDL file.
+type Extension struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// UserType is a Postgres enum, domain or composite type that columns can
+// reference
+type UserType struct {
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"` // enum, domain or composite
+	Labels   []string `json:"labels,omitempty"`
+	BaseType string   `json:"base_type,omitempty"`
+	NotNull  bool     `json:"not_null,omitempty"`
+	Default  *string  `json:"default,omitempty"`
+	Checks   []string `json:"checks,omitempty"`
+	// Attributes are the members of a composite type, in order
+	Attributes []*TypeAttribute `json:"attributes,omitempty"`
+}
+
+// TypeAttribute is a member of a composite type; Type is rendered like
+// format_type(), e.g. "numeric(10,2)" or "text[]"
+type TypeAttribute struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// User type kinds
+const (
+	UserTypeEnum      = "enum"
+	UserTypeDomain    = "domain"
+	UserTypeComposite = "composite"
+)
+
+// Sequence is a Postgres sequence, or the implicit counter of a MySQL
+// AUTO_INCREMENT column (named <table>_<column>_seq like a serial's sequence)
+type Sequence struct {
+	Name      string `json:"name"`
+	DataType  string `json:"data_type"`
+	Start     int64  `json:"start"`
+	Increment int64  `json:"increment"`
+	MinValue  int64  `json:"min_value"`
+	MaxValue  int64  `json:"max_value"`
+	Cache     int64  `json:"cache"`
+	Cycle     bool   `json:"cycle,omitempty"`
+	// OwnedBy is the table.column the sequence belongs to (serial, identity
+	// and AUTO_INCREMENT columns, or OWNED BY); empty for free-standing ones
+	OwnedBy string `json:"owned_by,omitempty"`
+}
+
+// splitOwnedBy splits a sequence's OwnedBy into table and column. The table
+// may itself be qualified (schema.table), the column never is.
+func splitOwnedBy(ownedBy string) (table, column string, ok bool) {
+	i := strings.LastIndex(ownedBy, ".")
+	if i < 0 {
+		return ownedBy, "", false
+	}
+	return ownedBy[:i], ownedBy[i+1:], true
+}
+
+// Routine is a stored function or procedure. Body is normalized with
+// normalizeRoutineBody so that formatting and comments don't count as drift.
+type Routine struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"` // function or procedure
+	Arguments string `json:"arguments"`
+	Returns   string `json:"returns,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Body      string `json:"body"`
+}
+
+// Routine kinds
+const (
+	RoutineFunction  = "function"
+	RoutineProcedure = "procedure"
+)
+
+// Event is a MySQL scheduled event. Schedule reads like "EVERY 1 DAY" or
+// "AT 2024-01-01 00:00:00"; STARTS is left out because it defaults to the
+// creation time, which differs between environments. Body is normalized
+// like routine bodies.
+type Event struct {
+	Name         string `json:"name"`
+	Schedule     string `json:"schedule"`
+	Status       string `json:"status"`        // ENABLED, DISABLED or SLAVESIDE_DISABLED
+	OnCompletion string `json:"on_completion"` // PRESERVE or NOT PRESERVE
+	Body         string `json:"body"`
+}
+
+// ForeignServer is a Postgres foreign server (CREATE SERVER). Options are
+// "key=value" pairs in name order; UserMappings are only the role names of
+// its user mappings ("public" for PUBLIC), as their options hold credentials.
+type ForeignServer struct {
+	Name         string   `json:"name"`
+	Wrapper      string   `json:"wrapper"` // the foreign-data wrapper, e.g. postgres_fdw
+	Options      []string `json:"options,omitempty"`
+	UserMappings []string `json:"user_mappings,omitempty"`
+}
+
+// ForeignTable is what makes a table a Postgres foreign table: its server
+// and its options, "key=value" pairs in name order
+type ForeignTable struct {
+	Server  string   `json:"server"`
+	Options []string `json:"options,omitempty"`
+}
+
+// foreignOptionsClause renders "key=value" options for CREATE SERVER and
+// CREATE FOREIGN TABLE, e.g. " OPTIONS (host 'db1', port '5432')"
+func foreignOptionsClause(options []string) string {
+	if len(options) == 0 {
+		return ""
+	}
+	clauses := make([]string, len(options))
+	for i, option := range options {
+		key, value, _ := strings.Cut(option, "=")
+		clauses[i] = key + " '" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return " OPTIONS (" + strings.Join(clauses, ", ") + ")"
+}
+
+// describeForeignTable renders the foreign side of a table for diffs, e.g.
+// "server remote (schema_name=public, table_name=orders)"
+func describeForeignTable(ft *ForeignTable) string {
+	if ft == nil {
+		return "none"
+	}
+	if len(ft.Options) == 0 {
+		return "server " + ft.Server
+	}
+	return fmt.Sprintf("server %s (%s)", ft.Server, strings.Join(ft.Options, ", "))
+}
+
+// routineKey identifies a routine by name and input argument types
+func routineKey(name string, argTypes []string) string {
+	return name + "(" + strings.Join(argTypes, ", ") + ")"
+}
+
+// mysqlRoutineKey identifies a MySQL routine by kind as well, since functions
+// and procedures live in separate namespaces and may share a name
+func mysqlRoutineKey(kind, name string, argTypes []string) string {
+	return kind + " " + routineKey(name, argTypes)
+}
+
+// normalizeRoutineBody strips comments, blank lines and indentation from a
+// routine body and collapses runs of whitespace, leaving string literals
+// untouched
+func normalizeRoutineBody(body string) string {
+	var sb strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '-' && i+1 < len(body) && body[i+1] == '-':
+			for i < len(body) && body[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(body) && body[i+1] == '*':
+			end := strings.Index(body[i+2:], "*/")
+			if end < 0 {
+				i = len(body)
+			} else {
+				i += end + 3
+			}
+			sb.WriteByte(' ')
+		case c == '\'':
+			end := skipQuoted(body, i, false)
+			sb.WriteString(body[i : end+1])
+			i = end
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	var lines []string
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type Table struct {
+	Name              string                  `json:"name"`
+	Columns           map[string]*Column      `json:"columns"`
+	PrimaryKey        *PrimaryKey             `json:"primary_key,omitempty"`
+	ForeignKeys       map[string]*ForeignKey  `json:"foreign_keys"`
+	UniqueConstraints map[string]*Unique      `json:"unique_constraints"`
+	Indexes           map[string]*Index       `json:"indexes"`
+	CheckConstraints  map[string]*CheckConstr `json:"check_constraints"`
+	Triggers          map[string]*Trigger     `json:"triggers,omitempty"`
+	Comment           string                  `json:"comment,omitempty"`
+	// AccessMethod is the Postgres table access method (heap, columnar, ...)
+	AccessMethod string `json:"access_method,omitempty"`
+	// ReplicaIdentity is the Postgres REPLICA IDENTITY of the table, which
+	// decides the old row values logical replication sends: default, full,
+	// nothing or "index <name>"; empty when unknown
+	ReplicaIdentity string `json:"replica_identity,omitempty"`
+	// Options are database-specific table options, e.g. the TiDB clustered
+	// index and placement policy
+	Options map[string]string `json:"options,omitempty"`
+	// Partitioning is set on partitioned tables; PartitionOf names the parent
+	// of a Postgres partition
+	Partitioning *Partitioning `json:"partitioning,omitempty"`
+	PartitionOf  string        `json:"partition_of,omitempty"`
+	// Inherits are the parents of a Postgres table created with INHERITS,
+	// in declaration order; declarative partitions use PartitionOf instead
+	Inherits []string `json:"inherits,omitempty"`
+	// RewriteRules are the Postgres rewrite rules of the table (CREATE RULE)
+	RewriteRules map[string]*RewriteRule `json:"rewrite_rules,omitempty"`
+	// Foreign is set on Postgres foreign tables, extracted with
+	// IncludeForeignTables
+	Foreign *ForeignTable `json:"foreign,omitempty"`
+}
+
+// Replica identities besides "index <name>"
+const (
+	ReplicaIdentityDefault = "default"
+	ReplicaIdentityFull    = "full"
+	ReplicaIdentityNothing = "nothing"
+)
+
+// replicaIdentityClause renders a replica identity for ALTER TABLE ...
+// REPLICA IDENTITY, e.g. FULL or USING INDEX "orders_uuid_key"
+func replicaIdentityClause(identity string, quote func(string) string) string {
+	if index, ok := strings.CutPrefix(identity, "index "); ok {
+		return "USING INDEX " + quote(index)
+	}
+	return strings.ToUpper(identity)
+}
+
+// Partitioning is the partitioning scheme of a table
+type Partitioning struct {
+	Strategy   string       `json:"strategy"` // range, list, hash, key, range columns, ...
+	Key        string       `json:"key"`
+	Partitions []*Partition `json:"partitions,omitempty"`
+}
+
+// Partition is one partition of a partitioned table. Bound is the
+// FOR VALUES clause (Postgres) or VALUES LESS THAN / VALUES IN clause
+// (MySQL); hash partitions of MySQL have none
+type Partition struct {
+	Name  string `json:"name"`
+	Bound string `json:"bound,omitempty"`
+}
+
+type Column struct {
+	Name         string  `json:"name"`
+	DataType     string  `json:"data_type"`
+	IsNullable   bool    `json:"is_nullable"`
+	DefaultValue *string `json:"default_value,omitempty"`
+	IsInvisible  bool    `json:"is_invisible,omitempty"`
+	Comment      string  `json:"comment,omitempty"`
+	// UserType names the enum, domain or extension type of the column
+	UserType string `json:"user_type,omitempty"`
+	// LargeObject marks a Postgres column holding large object references: an
+	// lo column, or an oid column whose objects are managed by lo_manage
+	LargeObject bool `json:"large_object,omitempty"`
+	// Sensitive marks a column tagged as holding personal or secret data;
+	// see MarkSensitiveColumns
+	Sensitive bool `json:"sensitive,omitempty"`
+	// IsGenerated marks a computed column (GENERATED ALWAYS AS, MySQL
+	// VIRTUAL/STORED); GenerationStorage is "stored" or "virtual"
+	IsGenerated          bool   `json:"is_generated,omitempty"`
+	GenerationExpression string `json:"generation_expression,omitempty"`
+	GenerationStorage    string `json:"generation_storage,omitempty"`
+	// Identity is how the column numbers new rows, one of the Identity*
+	// kinds; empty for ordinary columns
+	Identity string `json:"identity,omitempty"`
+	// ElementType is the element type of a Postgres array column, whose
+	// DataType is ARRAY, e.g. "text" or "character varying(20)"
+	ElementType string `json:"element_type,omitempty"`
+	// CharMaxLength, NumericPrecision, NumericScale and DatetimePrecision
+	// are the type modifiers Postgres reports apart from data_type; nil when
+	// the type has none, as for text or a numeric without precision
+	CharMaxLength     *int64 `json:"character_maximum_length,omitempty"`
+	NumericPrecision  *int64 `json:"numeric_precision,omitempty"`
+	NumericScale      *int64 `json:"numeric_scale,omitempty"`
+	DatetimePrecision *int64 `json:"datetime_precision,omitempty"`
+}
+
+// Column identity kinds
+const (
+	IdentityAlways        = "identity always"     // GENERATED ALWAYS AS IDENTITY
+	IdentityByDefault     = "identity by default" // GENERATED BY DEFAULT AS IDENTITY
+	IdentitySerial        = "serial"              // nextval() default of a sequence owned by the column
+	IdentityAutoIncrement = "auto_increment"
+)
+
+type PrimaryKey struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	// Invisible marks a MySQL generated invisible primary key (GIPK)
+	Invisible bool `json:"invisible,omitempty"`
+}
+
+type ForeignKey struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+	OnDelete   string   `json:"on_delete"`
+	OnUpdate   string   `json:"on_update"`
+	// Status is empty for a validated, enforced constraint; see
+	// ConstraintNotValid
+	Status string `json:"status,omitempty"`
+}
+
+type Unique struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+type Index struct {
+	Name     string   `json:"name"`
+	Columns  []string `json:"columns"`
+	IsUnique bool     `json:"is_unique"`
+	// Method is the index access method (btree, hash, gin, gist, brin, ...);
+	// empty when unknown
+	Method string `json:"method,omitempty"`
+	// OpClasses and Orders line up with Columns: the operator class of a key
+	// column when it isn't the type's default, and its ordering (DESC,
+	// NULLS FIRST, DESC NULLS LAST) when it isn't ascending with nulls last
+	OpClasses []string `json:"op_classes,omitempty"`
+	Orders    []string `json:"orders,omitempty"`
+	// PrefixLengths line up with Columns too: the number of leading
+	// characters or bytes a MySQL key part indexes, e.g. "20" for name(20),
+	// empty when the whole column is indexed
+	PrefixLengths []string `json:"prefix_lengths,omitempty"`
+	// Include are the non-key columns of a covering index (INCLUDE)
+	Include []string `json:"include,omitempty"`
+	// Options are the storage parameters of the index, e.g. fillfactor
+	Options map[string]string `json:"options,omitempty"`
+	// Kind is IndexFullText or IndexSpatial for full-text and spatial
+	// indexes, empty for ordinary ones; see classifyIndex
+	Kind string `json:"kind,omitempty"`
+}
+
+// Index kinds besides ordinary indexes
+const (
+	IndexFullText = "fulltext"
+	IndexSpatial  = "spatial"
+)
+
+// spatialTypes are the geometric types of Postgres and PostGIS
+var spatialTypes = map[string]bool{
+	"geometry": true, "geography": true, "point": true, "line": true, "lseg": true,
+	"box": true, "path": true, "polygon": true, "circle": true,
+}
+
+// classifyIndex tells full-text and spatial Postgres indexes apart from
+// ordinary ones: GIN or GiST over tsvector columns or to_tsvector()
+// expressions are full-text, GiST, SP-GiST or BRIN over geometric and PostGIS
+// types spatial. columnType returns the type of a table column, or "".
+// MySQL reports FULLTEXT and SPATIAL indexes itself.
+func classifyIndex(idx *Index, columnType func(string) string) string {
+	for _, key := range idx.Columns {
+		dataType := strings.ToLower(columnType(key))
+		base, _, _ := strings.Cut(dataType, "(")
+		switch idx.Method {
+		case "gin", "gist":
+			if dataType == "tsvector" || strings.Contains(strings.ToLower(key), "to_tsvector(") {
+				return IndexFullText
+			}
+		}
+		switch idx.Method {
+		case "gist", "spgist", "brin":
+			if spatialTypes[base] {
+				return IndexSpatial
+			}
+		}
+	}
+	return ""
+}
+
+// indexKind renders an index kind for diffs
+func indexKind(kind string) string {
+	if kind == "" {
+		return "regular"
+	}
+	return kind
+}
+
+// indexPrefix is the keyword CREATE INDEX needs for the kind of idx:
+// UNIQUE, or FULLTEXT and SPATIAL on MySQL
+func indexPrefix(idx *Index, driver string) string {
+	switch {
+	case idx.IsUnique:
+		return "UNIQUE "
+	case idx.Kind != "" && engineFamily(driver) == "mysql":
+		return strings.ToUpper(idx.Kind) + " "
+	}
+	return ""
+}
+
+// indexOrder renders the ordering of an index key column, leaving out the
+// defaults: ascending sorts nulls last, descending sorts them first
+func indexOrder(desc, nullsFirst bool) string {
+	switch {
+	case desc && !nullsFirst:
+		return "DESC NULLS LAST"
+	case desc:
+		return "DESC"
+	case nullsFirst:
+		return "NULLS FIRST"
+	}
+	return ""
+}
+
+// indexColumnAttributes returns the operator classes, orders or prefix
+// lengths of an index padded to its key columns, so that lists of both sides can be compared
+func indexColumnAttributes(idx *Index, attrs []string) []string {
+	padded := make([]string, len(idx.Columns))
+	copy(padded, attrs)
+	return padded
+}
+
+// compactList returns nil when every item is empty, so that per-column
+// attributes without a value are left out of snapshots
+func compactList(items []string) []string {
+	for _, item := range items {
+		if item != "" {
+			return items
+		}
+	}
+	return nil
+}
+
+type CheckConstr struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Status     string `json:"status,omitempty"`
+}
+
+// Trigger is a row or statement trigger of a table. Postgres triggers call
+// Function; MySQL triggers carry their Body.
+type Trigger struct {
+	Name      string   `json:"name"`
+	Timing    string   `json:"timing"` // BEFORE, AFTER or INSTEAD OF
+	Events    []string `json:"events"` // INSERT, UPDATE, DELETE, TRUNCATE
+	Level     string   `json:"level"`  // ROW or STATEMENT
+	Condition string   `json:"condition,omitempty"`
+	Function  string   `json:"function,omitempty"`
+	Body      string   `json:"body,omitempty"`
+	// Status is empty for an enabled trigger, else one of the TriggerDisabled,
+	// TriggerReplica and TriggerAlways states of Postgres
+	Status string `json:"status,omitempty"`
+}
+
+// RewriteRule is a Postgres rewrite rule. Condition and Actions are normalized with
+// normalizeRuleText, since pg_dump qualifies names with public and the
+// catalog of a database on that search_path doesn't.
+type RewriteRule struct {
+	Name      string `json:"name"`
+	Event     string `json:"event"` // SELECT, INSERT, UPDATE or DELETE
+	Instead   bool   `json:"instead"`
+	Condition string `json:"condition,omitempty"`
+	Actions   string `json:"actions"`
+}
+
+// ruleDefinitionPattern splits pg_get_ruledef output, "CREATE RULE name AS
+// ON INSERT TO public.t WHERE (...) DO INSTEAD ...;"
+var ruleDefinitionPattern = regexp.MustCompile(`(?is)^CREATE\s+RULE\s+\S+\s+AS\s+ON\s+(\w+)\s+TO\s+\S+\s*(?:WHERE\s+(.*?)\s+)?DO\s+(?:(INSTEAD|ALSO)\s+)?(.*?);?\s*$`)
+
+// publicQualifierPattern matches the public schema qualifier of a name
+var publicQualifierPattern = regexp.MustCompile(`\b(?i:public)\.`)
+
+// normalizeRuleText collapses whitespace, drops the public schema qualifier
+// and the trailing semicolon of a rule condition or action list
+func normalizeRuleText(text string) string {
+	text = publicQualifierPattern.ReplaceAllString(text, "")
+	return strings.TrimSuffix(strings.Join(strings.Fields(text), " "), ";")
+}
+
+// rewriteRuleStatement renders the CREATE RULE statement of a rule on a table
+func rewriteRuleStatement(name, table string, rule *RewriteRule) string {
+	stmt := fmt.Sprintf("CREATE RULE %s AS ON %s TO %s", name, rule.Event, table)
+	if rule.Condition != "" {
+		stmt += " WHERE " + rule.Condition
+	}
+	stmt += " DO "
+	if rule.Instead {
+		stmt += "INSTEAD "
+	}
+	return stmt + rule.Actions
+}
+
+// Trigger states besides enabled: never fires, fires only when
+// session_replication_role is replica, or fires in every role
+const (
+	TriggerDisabled = "disabled"
+	TriggerReplica  = "replica"
+	TriggerAlways   = "always"
+)
+
+// Constraint statuses: existing rows were never checked (Postgres NOT VALID,
+// SQL Server WITH NOCHECK, Oracle NOVALIDATE), or the constraint is not
+// checked at all (MySQL and Postgres NOT ENFORCED, disabled on SQL Server
+// and Oracle)
+const (
+	ConstraintNotValid    = "not_valid"
+	ConstraintNotEnforced = "not_enforced"
+)
+
+// describeConstraintStatus renders a constraint status for diffs
+func describeConstraintStatus(status string) string {
+	switch status {
+	case ConstraintNotValid:
+		return "NOT VALID"
+	case ConstraintNotEnforced:
+		return "NOT ENFORCED"
+	}
+	return "validated"
+}
@@ -0,0 +1,2341 @@
+package dbdiff
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// DDL PARSER - Schemas from SQL scripts (pg_dump --schema-only, mysqldump -d)
+// ============================================================================
+
+// Pseudo-drivers that read a schema from a file, or stdin for "-", instead of
+// connecting to a database. The fake driver serves built-in fixtures; see
+// loadFakeSchema.
+const (
+	DriverSnapshot = "snapshot"
+	DriverDDL      = "ddl"
+	DriverFake     = "fake"
+)
+
+var mysqlEnginePattern = regexp.MustCompile(`(?i)\bENGINE\s*=`)
+
+func isFileDriver(driver string) bool {
+	return driver == DriverSnapshot || driver == DriverDDL || driver == DriverFake
+}
+
+// ReadSchema reads a snapshot (JSON) or a DDL script
+func ReadSchema(driver string, r io.Reader) (*Schema, error) {
+	switch driver {
+	case DriverSnapshot:
+		return decodeSnapshot(r)
+	case DriverDDL:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return ParseDDL(string(decodeText(data)))
+	default:
+		return nil, fmt.Errorf("unsupported file driver %q", driver)
+	}
+}
+
+// ParseDDL builds a schema from CREATE TABLE, ALTER TABLE ... ADD, CREATE
+// INDEX and COMMENT ON statements; anything else is skipped. Scripts with
+// backtick quoting or ENGINE= options are read with MySQL conventions
+// (case-sensitive names, PRIMARY/<table>_ibfk_N default constraint names),
+// everything else with Postgres ones. Only tables of the public schema are
+// kept for Postgres, matching live extraction.
+func ParseDDL(src string) (*Schema, error) {
+	mysql := strings.Contains(src, "`") || mysqlEnginePattern.MatchString(src)
+	schema := &Schema{Tables: make(map[string]*Table), Types: make(map[string]*UserType), Sequences: make(map[string]*Sequence), Routines: make(map[string]*Routine), Events: make(map[string]*Event), Extensions: make(map[string]*Extension),
+		ForeignServers: make(map[string]*ForeignServer)}
+	accessMethod := ""
+
+	for i, stmt := range splitDDLStatements(src, mysql) {
+		toks, err := tokenizeDDL(stmt, mysql)
+		if err != nil {
+			return nil, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		p := &ddlParser{src: stmt, toks: toks, mysql: mysql, schema: schema, accessMethod: &accessMethod}
+		if err := p.statement(); err != nil {
+			return nil, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+	}
+
+	for _, table := range schema.Tables {
+		if table.PrimaryKey == nil {
+			continue
+		}
+		for _, name := range table.PrimaryKey.Columns {
+			if col, ok := table.Columns[name]; ok {
+				col.IsNullable = false
+			}
+		}
+	}
+	// pg_dump writes serial columns as a nextval() default of a sequence
+	// that is OWNED BY the column
+	for _, seq := range schema.Sequences {
+		tableName, colName, _ := splitOwnedBy(seq.OwnedBy)
+		table := schema.Tables[tableName]
+		if table == nil {
+			continue
+		}
+		if col := table.Columns[colName]; col != nil && col.Identity == "" && col.DefaultValue != nil &&
+			strings.HasPrefix(strings.ToLower(*col.DefaultValue), "nextval(") {
+			col.Identity = IdentitySerial
+		}
+	}
+	// Partitions inherit NOT NULL from their parent
+	for _, table := range schema.Tables {
+		parent := schema.Tables[table.PartitionOf]
+		if parent == nil {
+			continue
+		}
+		for name, col := range table.Columns {
+			if parentCol, ok := parent.Columns[name]; ok && !parentCol.IsNullable {
+				col.IsNullable = false
+			}
+		}
+	}
+	return schema, nil
+}
+
+// splitDDLStatements splits a script on semicolons outside of quotes,
+// comments and dollar-quoted bodies, dropping psql meta-commands. MySQL
+// scripts may switch the delimiter with the client's DELIMITER command, as
+// mysqldump does around routine bodies.
+func splitDDLStatements(src string, mysql bool) []string {
+	var stmts []string
+	start := 0
+	lineStart := true
+	delimiter := ";"
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case lineStart && mysql && len(src)-i > 10 && strings.EqualFold(src[i:i+10], "DELIMITER "):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			if d := strings.TrimSpace(src[i+10 : i+end]); d != "" {
+				delimiter = d
+			}
+			src = src[:i] + strings.Repeat(" ", end) + src[i+end:]
+			i += end - 1
+		case lineStart && c == '\\':
+			// psql meta-command such as \connect or \restrict
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			src = src[:i] + strings.Repeat(" ", end) + src[i+end:]
+			i += end - 1
+		case c == '-' && i+1 < len(src) && src[i+1] == '-':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end + 3
+			}
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(src, i, mysql && c == '\'')
+		case c == '$':
+			if tag := dollarTag(src[i:]); tag != "" {
+				end := strings.Index(src[i+len(tag):], tag)
+				if end < 0 {
+					i = len(src)
+				} else {
+					i += len(tag) + end + len(tag) - 1
+				}
+			}
+		case strings.HasPrefix(src[i:], delimiter):
+			stmts = append(stmts, src[start:i])
+			start = i + len(delimiter)
+			i += len(delimiter) - 1
+		}
+		if i < len(src) {
+			lineStart = src[i] == '\n' || (lineStart && (src[i] == ' ' || src[i] == '\t'))
+		}
+	}
+	if rest := strings.TrimSpace(src[start:]); rest != "" {
+		stmts = append(stmts, src[start:])
+	}
+	return stmts
+}
+
+// skipQuoted returns the index of the quote closing the one at i; doubled
+// quotes (and backslash escapes when allowed) don't close it
+func skipQuoted(src string, i int, backslash bool) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch {
+		case backslash && src[j] == '\\':
+			j++
+		case src[j] == quote:
+			if j+1 < len(src) && src[j+1] == quote {
+				j++
+				continue
+			}
+			return j
+		}
+	}
+	return len(src) - 1
+}
+
+var dollarTagPattern = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+func dollarTag(s string) string {
+	return dollarTagPattern.FindString(s)
+}
+
+// DDL token kinds
+const (
+	ddlIdent = iota
+	ddlString
+	ddlNumber
+	ddlPunct
+)
+
+// ddlToken is a lexical token; Start and End index the statement text so
+// that types and expressions can be kept verbatim
+type ddlToken struct {
+	Kind   int
+	Text   string // identifier without quotes, literal value or punctuation
+	Quoted bool
+	Start  int
+	End    int
+}
+
+func tokenizeDDL(stmt string, mysql bool) ([]ddlToken, error) {
+	var toks []ddlToken
+	versioned := 0
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case mysql && strings.HasPrefix(stmt[i:], "/*!"):
+			// mysqldump wraps PARTITION BY and other newer syntax in
+			// executable comments (/*!50100 ... */); their content is DDL
+			for i += 3; i < len(stmt) && stmt[i] >= '0' && stmt[i] <= '9'; i++ {
+			}
+			versioned++
+		case versioned > 0 && strings.HasPrefix(stmt[i:], "*/"):
+			versioned--
+			i += 2
+		case c == '-' && i+1 < len(stmt) && stmt[i+1] == '-':
+			for i < len(stmt) && stmt[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(stmt) && stmt[i+1] == '*':
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 4
+		case c == '\'':
+			end := skipQuoted(stmt, i, mysql)
+			if end >= len(stmt) || stmt[end] != '\'' || end == i {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			text := strings.ReplaceAll(stmt[i+1:end], "''", "'")
+			if mysql {
+				text = strings.ReplaceAll(text, `\'`, "'")
+				text = strings.ReplaceAll(text, `\\`, `\`)
+			}
+			toks = append(toks, ddlToken{Kind: ddlString, Text: text, Start: i, End: end + 1})
+			i = end + 1
+		case c == '"' || c == '`':
+			end := skipQuoted(stmt, i, false)
+			if end >= len(stmt) || stmt[end] != c || end == i {
+				return nil, fmt.Errorf("unterminated quoted identifier")
+			}
+			text := strings.ReplaceAll(stmt[i+1:end], string([]byte{c, c}), string(c))
+			toks = append(toks, ddlToken{Kind: ddlIdent, Text: text, Quoted: true, Start: i, End: end + 1})
+			i = end + 1
+		case c == '$' && dollarTag(stmt[i:]) != "":
+			tag := dollarTag(stmt[i:])
+			end := strings.Index(stmt[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string")
+			}
+			stop := i + len(tag) + end + len(tag)
+			toks = append(toks, ddlToken{Kind: ddlString, Text: stmt[i+len(tag) : i+len(tag)+end], Start: i, End: stop})
+			i = stop
+		case isIdentByte(c):
+			j := i
+			for j < len(stmt) && (isIdentByte(stmt[j]) || stmt[j] == '$') {
+				j++
+			}
+			kind := ddlIdent
+			if c >= '0' && c <= '9' {
+				kind = ddlNumber
+				// Decimal fractions such as 1.5
+				if j < len(stmt)-1 && stmt[j] == '.' && stmt[j+1] >= '0' && stmt[j+1] <= '9' {
+					for j++; j < len(stmt) && stmt[j] >= '0' && stmt[j] <= '9'; j++ {
+					}
+				}
+			}
+			toks = append(toks, ddlToken{Kind: kind, Text: stmt[i:j], Start: i, End: j})
+			i = j
+		case c == ':' && i+1 < len(stmt) && stmt[i+1] == ':':
+			toks = append(toks, ddlToken{Kind: ddlPunct, Text: "::", Start: i, End: i + 2})
+			i += 2
+		default:
+			toks = append(toks, ddlToken{Kind: ddlPunct, Text: string(c), Start: i, End: i + 1})
+			i++
+		}
+	}
+	return toks, nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// ddlParser walks the tokens of one statement (or of one element of a
+// CREATE TABLE body) and records what it finds in schema
+type ddlParser struct {
+	src    string
+	toks   []ddlToken
+	pos    int
+	mysql  bool
+	schema *Schema
+	// accessMethod tracks pg_dump's SET default_table_access_method
+	accessMethod *string
+}
+
+func (p *ddlParser) sub(toks []ddlToken) *ddlParser {
+	return &ddlParser{src: p.src, toks: toks, mysql: p.mysql, schema: p.schema, accessMethod: p.accessMethod}
+}
+
+func (p *ddlParser) done() bool { return p.pos >= len(p.toks) }
+
+func (p *ddlParser) peekIs(offset int, word string) bool {
+	i := p.pos + offset
+	if i >= len(p.toks) {
+		return false
+	}
+	t := p.toks[i]
+	if t.Kind == ddlPunct {
+		return t.Text == word
+	}
+	return t.Kind == ddlIdent && !t.Quoted && strings.EqualFold(t.Text, word)
+}
+
+// accept consumes the given sequence of keywords/punctuation if it comes next
+func (p *ddlParser) accept(words ...string) bool {
+	for i, w := range words {
+		if !p.peekIs(i, w) {
+			return false
+		}
+	}
+	p.pos += len(words)
+	return true
+}
+
+func (p *ddlParser) next() ddlToken {
+	t := p.toks[p.pos]
+	p.pos++
+	return t
+}
+
+// ident returns an identifier as the database would store it: quoted names
+// verbatim, unquoted ones folded to lower case by Postgres
+func (p *ddlParser) ident(t ddlToken) string {
+	if t.Quoted || p.mysql {
+		return t.Text
+	}
+	return strings.ToLower(t.Text)
+}
+
+// qualifiedName reads schema.name and returns name; ok is false for Postgres
+// objects outside the public schema
+func (p *ddlParser) qualifiedName() (name string, ok bool) {
+	if p.done() {
+		return "", false
+	}
+	parts := []string{p.ident(p.next())}
+	for p.peekIs(0, ".") && p.pos+1 < len(p.toks) {
+		p.pos++
+		parts = append(parts, p.ident(p.next()))
+	}
+	name = parts[len(parts)-1]
+	if len(parts) > 1 && !p.mysql && parts[len(parts)-2] != "public" {
+		return name, false
+	}
+	return name, true
+}
+
+// balanced returns the tokens between the parenthesis at the current
+// position and its match, consuming both
+func (p *ddlParser) balanced() ([]ddlToken, error) {
+	if !p.accept("(") {
+		return nil, fmt.Errorf("expected ( near %q", p.near())
+	}
+	start := p.pos
+	for depth := 1; !p.done(); p.pos++ {
+		switch {
+		case p.peekIs(0, "("):
+			depth++
+		case p.peekIs(0, ")"):
+			depth--
+			if depth == 0 {
+				inner := p.toks[start:p.pos]
+				p.pos++
+				return inner, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unbalanced parentheses")
+}
+
+// splitTopLevel splits tokens on commas outside parentheses
+func splitTopLevel(toks []ddlToken) [][]ddlToken {
+	var parts [][]ddlToken
+	depth, start := 0, 0
+	for i, t := range toks {
+		if t.Kind != ddlPunct {
+			continue
+		}
+		switch t.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+			if depth == 0 {
+				parts = append(parts, toks[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(toks) {
+		parts = append(parts, toks[start:])
+	}
+	return parts
+}
+
+func (p *ddlParser) raw(toks []ddlToken) string {
+	if len(toks) == 0 {
+		return ""
+	}
+	return p.src[toks[0].Start:toks[len(toks)-1].End]
+}
+
+func (p *ddlParser) near() string {
+	if p.done() {
+		return "end of statement"
+	}
+	return p.raw(p.toks[p.pos:min(p.pos+3, len(p.toks))])
+}
+
+func (p *ddlParser) statement() error {
+	switch {
+	case p.accept("CREATE"):
+		p.accept("OR", "REPLACE")
+		for p.accept("GLOBAL") || p.accept("LOCAL") || p.accept("TEMPORARY") || p.accept("TEMP") || p.accept("UNLOGGED") {
+		}
+		if p.mysql && p.accept("DEFINER") {
+			for !p.done() && !p.peekIs(0, "TRIGGER") && !p.peekIs(0, "FUNCTION") && !p.peekIs(0, "PROCEDURE") && !p.peekIs(0, "EVENT") {
+				p.pos++
+			}
+		}
+		switch {
+		case p.accept("TABLE"):
+			return p.createTable()
+		case p.accept("UNIQUE", "INDEX"):
+			return p.createIndex(true, "")
+		case p.accept("INDEX"):
+			return p.createIndex(false, "")
+		case p.accept("FULLTEXT", "INDEX"):
+			return p.createIndex(false, IndexFullText)
+		case p.accept("SPATIAL", "INDEX"):
+			return p.createIndex(false, IndexSpatial)
+		case p.accept("TYPE"):
+			return p.createEnum()
+		case p.accept("DOMAIN"):
+			return p.createDomain()
+		case p.accept("SEQUENCE"):
+			return p.createSequence()
+		case p.accept("TRIGGER"), p.accept("CONSTRAINT", "TRIGGER"):
+			return p.createTrigger()
+		case p.accept("FUNCTION"):
+			return p.createRoutine(RoutineFunction)
+		case p.accept("PROCEDURE"):
+			return p.createRoutine(RoutineProcedure)
+		case p.mysql && p.accept("EVENT"):
+			return p.createEvent()
+		case !p.mysql && p.accept("RULE"):
+			return p.createRule()
+		case !p.mysql && p.accept("FOREIGN", "TABLE"):
+			return p.createTable()
+		case !p.mysql && p.accept("SERVER"):
+			return p.createServer()
+		case !p.mysql && p.accept("USER", "MAPPING"):
+			return p.createUserMapping()
+		case p.accept("EXTENSION"):
+			return p.createExtension()
+		}
+	case p.accept("ALTER", "TABLE"):
+		return p.alterTable()
+	case p.accept("ALTER", "TYPE"):
+		name, ok := p.qualifiedName()
+		if ut := p.schema.Types[name]; ok && ut != nil && ut.Kind == UserTypeEnum {
+			p.alterEnum(ut)
+		}
+	case p.accept("ALTER", "SEQUENCE"):
+		p.accept("IF", "EXISTS")
+		name, ok := p.qualifiedName()
+		if seq := p.schema.Sequences[name]; ok && seq != nil {
+			p.sequenceOptions(seq, false)
+		}
+	case p.accept("COMMENT", "ON"):
+		return p.commentOn()
+	case p.accept("SET", "default_table_access_method"):
+		if (p.accept("=") || p.accept("TO")) && !p.done() {
+			*p.accessMethod = p.next().Text
+		}
+	}
+	return nil
+}
+
+func (p *ddlParser) createTable() error {
+	p.accept("IF", "NOT", "EXISTS")
+	name, ok := p.qualifiedName()
+	if ok && !p.mysql && p.accept("PARTITION", "OF") {
+		return p.partitionOf(name)
+	}
+	if !ok || !p.peekIs(0, "(") {
+		// Other schemas and CREATE TABLE ... AS are not tracked
+		return nil
+	}
+	body, err := p.balanced()
+	if err != nil {
+		return fmt.Errorf("table %s: %w", name, err)
+	}
+
+	table := newTable(name)
+	if !p.mysql {
+		table.AccessMethod = *p.accessMethod
+		table.ReplicaIdentity = ReplicaIdentityDefault
+	}
+	p.schema.Tables[name] = table
+	for _, element := range splitTopLevel(body) {
+		if err := p.sub(element).tableElement(table); err != nil {
+			return fmt.Errorf("table %s: %w", name, err)
+		}
+	}
+	return p.tableOptions(table)
+}
+
+// tableOptions parses what follows the body of CREATE TABLE: INHERITS
+// (...), USING columnar and the SERVER and OPTIONS of foreign tables
+// (Postgres), ENGINE=InnoDB COMMENT='...' (MySQL) and PARTITION BY
+func (p *ddlParser) tableOptions(table *Table) error {
+	for !p.done() {
+		if !p.mysql && p.accept("SERVER") && !p.done() {
+			// Foreign tables have no storage and no replica identity
+			table.Foreign = &ForeignTable{Server: p.ident(p.next())}
+			table.AccessMethod, table.ReplicaIdentity = "", ""
+			continue
+		}
+		if table.Foreign != nil && p.peekIs(0, "OPTIONS") && p.peekIs(1, "(") {
+			p.pos++
+			options, err := p.foreignOptions()
+			if err != nil {
+				return fmt.Errorf("table %s: %w", table.Name, err)
+			}
+			table.Foreign.Options = options
+			continue
+		}
+		if !p.mysql && p.peekIs(0, "INHERITS") && p.peekIs(1, "(") {
+			p.pos++
+			parents, err := p.balanced()
+			if err != nil {
+				return fmt.Errorf("table %s: %w", table.Name, err)
+			}
+			for _, parent := range splitTopLevel(parents) {
+				if parentName, ok := p.sub(parent).qualifiedName(); ok {
+					table.Inherits = append(table.Inherits, parentName)
+					inheritFrom(table, p.schema.Tables[parentName])
+				}
+			}
+			continue
+		}
+		if p.accept("PARTITION", "BY") {
+			if err := p.partitionBy(table); err != nil {
+				return fmt.Errorf("table %s: %w", table.Name, err)
+			}
+			continue
+		}
+		if !p.mysql && p.accept("USING") && !p.done() {
+			table.AccessMethod = p.next().Text
+			continue
+		}
+		if p.accept("COMMENT") {
+			p.accept("=")
+			if !p.done() && p.toks[p.pos].Kind == ddlString {
+				table.Comment = p.next().Text
+			}
+			continue
+		}
+		p.pos++
+	}
+	return nil
+}
+
+// tableElement parses a column definition or a table constraint
+// partitionOf parses the rest of CREATE TABLE name PARTITION OF parent:
+// the partition gets the columns of its parent and is added to its
+// partition list
+func (p *ddlParser) partitionOf(name string) error {
+	parentName, ok := p.qualifiedName()
+	parent := p.schema.Tables[parentName]
+	if !ok || parent == nil {
+		return nil
+	}
+	table := newTable(name)
+	table.AccessMethod = *p.accessMethod
+	table.ReplicaIdentity = ReplicaIdentityDefault
+	table.PartitionOf = parentName
+	for colName, col := range parent.Columns {
+		clone := *col
+		table.Columns[colName] = &clone
+	}
+	p.schema.Tables[name] = table
+
+	if p.peekIs(0, "(") {
+		body, err := p.balanced()
+		if err != nil {
+			return fmt.Errorf("table %s: %w", name, err)
+		}
+		for _, element := range splitTopLevel(body) {
+			if e := p.sub(element); e.isConstraintStart() {
+				if err := e.tableConstraint(table); err != nil {
+					return fmt.Errorf("table %s: %w", name, err)
+				}
+			}
+		}
+	}
+	bound, err := p.partitionBound()
+	if err != nil {
+		return fmt.Errorf("table %s: %w", name, err)
+	}
+	if parent.Partitioning != nil {
+		parent.Partitioning.Partitions = append(parent.Partitioning.Partitions, &Partition{Name: name, Bound: bound})
+	}
+	return p.tableOptions(table)
+}
+
+// inheritFrom gives an inheriting table the columns and check constraints
+// of its parent that it does not declare itself, as pg_dump leaves them out
+func inheritFrom(table, parent *Table) {
+	if parent == nil {
+		return
+	}
+	for colName, col := range parent.Columns {
+		if _, ok := table.Columns[colName]; !ok {
+			clone := *col
+			table.Columns[colName] = &clone
+		}
+	}
+	for checkName, check := range parent.CheckConstraints {
+		if _, ok := table.CheckConstraints[checkName]; !ok {
+			clone := *check
+			table.CheckConstraints[checkName] = &clone
+		}
+	}
+}
+
+// partitionBound parses a partition bound: FOR VALUES IN (...) | FROM (...)
+// TO (...) | WITH (...) or DEFAULT (Postgres), VALUES LESS THAN (...) |
+// MAXVALUE or VALUES IN (...) (MySQL); it returns the bound as written
+func (p *ddlParser) partitionBound() (string, error) {
+	start := p.pos
+	if p.accept("DEFAULT") {
+		return p.raw(p.toks[start:p.pos]), nil
+	}
+	p.accept("FOR")
+	if !p.accept("VALUES") {
+		return "", nil
+	}
+	switch {
+	case p.accept("LESS", "THAN"):
+		if !p.peekIs(0, "(") {
+			p.accept("MAXVALUE")
+			break
+		}
+		fallthrough
+	case p.accept("IN"), p.accept("WITH"):
+		if _, err := p.balanced(); err != nil {
+			return "", err
+		}
+	case p.accept("FROM"):
+		if _, err := p.balanced(); err != nil {
+			return "", err
+		}
+		if !p.accept("TO") {
+			return "", fmt.Errorf("expected TO near %q", p.near())
+		}
+		if _, err := p.balanced(); err != nil {
+			return "", err
+		}
+	}
+	return p.raw(p.toks[start:p.pos]), nil
+}
+
+// partitionBy parses PARTITION BY strategy (key), followed on MySQL by
+// PARTITIONS n or a list of partition definitions
+func (p *ddlParser) partitionBy(table *Table) error {
+	var strategy []string
+	for !p.done() && !p.peekIs(0, "(") {
+		strategy = append(strategy, strings.ToLower(p.next().Text))
+	}
+	key, err := p.balanced()
+	if err != nil {
+		return err
+	}
+	table.Partitioning = &Partitioning{Strategy: strings.Join(strategy, " "), Key: strings.ReplaceAll(p.raw(key), "`", "")}
+	if !p.mysql {
+		return nil
+	}
+
+	if p.accept("PARTITIONS") && !p.done() {
+		// Unnamed partitions are called p0, p1, ...
+		n, _ := strconv.Atoi(p.next().Text)
+		for i := 0; i < n; i++ {
+			table.Partitioning.Partitions = append(table.Partitioning.Partitions, &Partition{Name: fmt.Sprintf("p%d", i)})
+		}
+	}
+	if p.accept("SUBPARTITION", "BY") {
+		for !p.done() && !p.peekIs(0, "(") {
+			p.pos++
+		}
+		if _, err := p.balanced(); err != nil {
+			return err
+		}
+		if p.accept("SUBPARTITIONS") && !p.done() {
+			p.pos++
+		}
+	}
+	if !p.peekIs(0, "(") {
+		return nil
+	}
+	defs, err := p.balanced()
+	if err != nil {
+		return err
+	}
+	table.Partitioning.Partitions = nil
+	for _, def := range splitTopLevel(defs) {
+		d := p.sub(def)
+		if !d.accept("PARTITION") || d.done() {
+			continue
+		}
+		part := &Partition{Name: d.ident(d.next())}
+		if part.Bound, err = d.partitionBound(); err != nil {
+			return fmt.Errorf("partition %s: %w", part.Name, err)
+		}
+		table.Partitioning.Partitions = append(table.Partitioning.Partitions, part)
+	}
+	return nil
+}
+
+func (p *ddlParser) tableElement(table *Table) error {
+	if p.done() {
+		return nil
+	}
+	if p.peekIs(0, "LIKE") || p.peekIs(0, "EXCLUDE") {
+		return nil
+	}
+	if p.isConstraintStart() {
+		return p.tableConstraint(table)
+	}
+	if kind := strings.ToLower(p.toks[p.pos].Text); p.accept("FULLTEXT") || p.accept("SPATIAL") {
+		_ = p.accept("KEY") || p.accept("INDEX")
+		return p.indexElement(table, kind)
+	}
+	if p.accept("KEY") || p.accept("INDEX") {
+		return p.indexElement(table, "")
+	}
+	return p.column(table)
+}
+
+func (p *ddlParser) isConstraintStart() bool {
+	return p.peekIs(0, "CONSTRAINT") || p.peekIs(0, "PRIMARY") || p.peekIs(0, "UNIQUE") ||
+		p.peekIs(0, "FOREIGN") || p.peekIs(0, "CHECK")
+}
+
+// tableConstraint parses [CONSTRAINT name] PRIMARY KEY | UNIQUE | FOREIGN
+// KEY | CHECK, as found in CREATE TABLE bodies and ALTER TABLE ... ADD
+func (p *ddlParser) tableConstraint(table *Table) error {
+	name := ""
+	if p.accept("CONSTRAINT") {
+		if !p.isConstraintStart() && !p.done() {
+			name = p.ident(p.next())
+		}
+	}
+	switch {
+	case p.accept("PRIMARY", "KEY"):
+		p.skipIndexName()
+		cols, err := p.columnList()
+		if err != nil {
+			return err
+		}
+		table.PrimaryKey = &PrimaryKey{Name: p.primaryKeyName(table, name), Columns: cols}
+	case p.accept("UNIQUE"):
+		_ = p.accept("KEY") || p.accept("INDEX")
+		if idxName := p.skipIndexName(); name == "" {
+			name = idxName
+		}
+		cols, err := p.columnList()
+		if err != nil {
+			return err
+		}
+		p.addUnique(table, name, cols)
+	case p.accept("FOREIGN", "KEY"):
+		if idxName := p.skipIndexName(); name == "" {
+			name = idxName
+		}
+		cols, err := p.columnList()
+		if err != nil {
+			return err
+		}
+		return p.references(table, name, cols)
+	case p.accept("CHECK"):
+		return p.check(table, name, "")
+	}
+	return nil
+}
+
+// skipIndexName consumes the optional index name MySQL allows before a
+// column list (UNIQUE KEY name (...)) and returns it
+func (p *ddlParser) skipIndexName() string {
+	name := ""
+	if !p.done() && !p.peekIs(0, "(") && p.toks[p.pos].Kind == ddlIdent && !p.peekIs(0, "USING") {
+		name = p.ident(p.next())
+	}
+	if p.accept("USING") && !p.done() {
+		p.pos++
+	}
+	return name
+}
+
+// columnList reads (a, b DESC, lower(c)) into column names; expressions are
+// kept verbatim
+func (p *ddlParser) columnList() ([]string, error) {
+	inner, err := p.balanced()
+	if err != nil {
+		return nil, err
+	}
+	var cols []string
+	for _, part := range splitTopLevel(inner) {
+		if len(part) == 0 {
+			continue
+		}
+		if part[0].Kind == ddlIdent && (len(part) == 1 || part[1].Kind != ddlPunct || part[1].Text != "(" || part[0].Quoted || p.mysql) {
+			cols = append(cols, p.ident(part[0]))
+		} else {
+			cols = append(cols, p.raw(part))
+		}
+	}
+	return cols, nil
+}
+
+func (p *ddlParser) indexElement(table *Table, kind string) error {
+	name := p.skipIndexName()
+	cols, _, orders, prefixLengths, err := p.indexKeys()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = cols[0]
+	}
+	idx := &Index{Name: name, Columns: cols, Orders: orders, PrefixLengths: prefixLengths, Kind: kind}
+	if kind == "" {
+		idx.Method = "btree"
+	}
+	table.Indexes[name] = idx
+	return nil
+}
+
+func (p *ddlParser) primaryKeyName(table *Table, name string) string {
+	switch {
+	case p.mysql:
+		return "PRIMARY"
+	case name != "":
+		return name
+	default:
+		return table.Name + "_pkey"
+	}
+}
+
+func (p *ddlParser) addUnique(table *Table, name string, cols []string) {
+	if name == "" {
+		if p.mysql {
+			name = cols[0]
+		} else {
+			name = table.Name + "_" + strings.Join(cols, "_") + "_key"
+		}
+	}
+	table.UniqueConstraints[name] = &Unique{Name: name, Columns: cols}
+}
+
+// references parses REFERENCES table [(cols)] [ON DELETE ...] [ON UPDATE ...]
+func (p *ddlParser) references(table *Table, name string, cols []string) error {
+	if !p.accept("REFERENCES") {
+		return fmt.Errorf("expected REFERENCES near %q", p.near())
+	}
+	refTable, _ := p.qualifiedName()
+	var refCols []string
+	if p.peekIs(0, "(") {
+		var err error
+		if refCols, err = p.columnList(); err != nil {
+			return err
+		}
+	}
+	fk := &ForeignKey{Columns: cols, RefTable: refTable, RefColumns: refCols, OnDelete: "NO ACTION", OnUpdate: "NO ACTION"}
+options:
+	for !p.done() {
+		switch {
+		case p.accept("ON", "DELETE"):
+			fk.OnDelete = p.referentialAction()
+		case p.accept("ON", "UPDATE"):
+			fk.OnUpdate = p.referentialAction()
+		case p.accept("MATCH"), p.accept("INITIALLY"):
+			p.pos++
+		case p.accept("NOT", "VALID"):
+			fk.Status = ConstraintNotValid
+		case p.accept("NOT", "ENFORCED"):
+			fk.Status = ConstraintNotEnforced
+		case p.accept("DEFERRABLE"), p.accept("NOT", "DEFERRABLE"), p.accept("ENFORCED"):
+		default:
+			break options
+		}
+	}
+	if name == "" {
+		if p.mysql {
+			name = fmt.Sprintf("%s_ibfk_%d", table.Name, len(table.ForeignKeys)+1)
+		} else {
+			name = table.Name + "_" + strings.Join(cols, "_") + "_fkey"
+		}
+	}
+	fk.Name = name
+	table.ForeignKeys[name] = fk
+	return nil
+}
+
+func (p *ddlParser) referentialAction() string {
+	for _, action := range [][]string{{"NO", "ACTION"}, {"SET", "NULL"}, {"SET", "DEFAULT"}, {"CASCADE"}, {"RESTRICT"}} {
+		if p.accept(action...) {
+			return strings.Join(action, " ")
+		}
+	}
+	return "NO ACTION"
+}
+
+// check parses CHECK (expr). Postgres stores the definition like
+// pg_get_constraintdef (CHECK (...)), MySQL just the expression.
+func (p *ddlParser) check(table *Table, name, column string) error {
+	start := p.pos
+	inner, err := p.balanced()
+	if err != nil {
+		return err
+	}
+	expr := "CHECK " + p.raw(p.toks[start:p.pos])
+	if p.mysql {
+		expr = p.raw(inner)
+	}
+	status := ""
+	switch {
+	case p.accept("NOT", "VALID"):
+		status = ConstraintNotValid
+	case p.accept("NOT", "ENFORCED"):
+		status = ConstraintNotEnforced
+	default:
+		p.accept("ENFORCED")
+	}
+	if name == "" {
+		switch {
+		case p.mysql:
+			name = fmt.Sprintf("%s_chk_%d", table.Name, len(table.CheckConstraints)+1)
+		case column != "":
+			name = table.Name + "_" + column + "_check"
+		default:
+			name = table.Name + "_check"
+		}
+	}
+	table.CheckConstraints[name] = &CheckConstr{Name: name, Expression: expr, Status: status}
+	return nil
+}
+
+// columnStopWords end a column's type or default expression
+var columnStopWords = []string{
+	"NOT", "NULL", "DEFAULT", "PRIMARY", "UNIQUE", "REFERENCES", "CHECK", "CONSTRAINT", "COLLATE",
+	"GENERATED", "AUTO_INCREMENT", "COMMENT", "ON", "INVISIBLE", "VISIBLE", "CHARSET", "AS", "STORAGE",
+}
+
+func (p *ddlParser) atColumnStop() bool {
+	if p.done() {
+		return true
+	}
+	if p.peekIs(0, "CHARACTER") && p.peekIs(1, "SET") {
+		return true
+	}
+	for _, w := range columnStopWords {
+		if p.peekIs(0, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipUntilStop consumes tokens, including parenthesized groups, up to the
+// next column keyword and returns them
+func (p *ddlParser) skipUntilStop() []ddlToken {
+	start := p.pos
+	for !p.done() && (p.pos == start || !p.atColumnStop()) {
+		if p.peekIs(0, "(") {
+			p.balanced()
+			continue
+		}
+		p.pos++
+	}
+	return p.toks[start:p.pos]
+}
+
+func (p *ddlParser) column(table *Table) error {
+	name := p.ident(p.next())
+	typeToks := p.skipUntilStop()
+	if len(typeToks) == 0 {
+		return fmt.Errorf("column %s has no type", name)
+	}
+	dataType, userType := p.dataType(typeToks)
+	col := &Column{Name: name, DataType: dataType, UserType: userType, IsNullable: true}
+	if !p.mysql && dataType == "ARRAY" {
+		col.ElementType = pgArrayElement(p.raw(typeToks))
+	} else if !p.mysql && userType == "" {
+		pgTypeModifiers(col, p.raw(typeToks))
+	}
+	// The lo type of the lo extension; lo_manage triggers are not parsed
+	col.LargeObject = columnTypeName(col) == "lo"
+	table.Columns[name] = col
+	if !p.mysql && len(typeToks) == 1 && strings.Contains(strings.ToLower(typeToks[0].Text), "serial") {
+		col.Identity = IdentitySerial
+		p.ownedSequence(table, col, nil)
+	}
+
+	constraintName := ""
+	for !p.done() {
+		switch {
+		case p.accept("CONSTRAINT"):
+			if !p.done() {
+				constraintName = p.ident(p.next())
+			}
+			continue
+		case p.accept("NOT", "NULL"):
+			col.IsNullable = false
+		case p.accept("NULL"):
+			col.IsNullable = true
+		case p.accept("DEFAULT"):
+			p.columnDefault(col)
+		case p.accept("PRIMARY", "KEY"):
+			table.PrimaryKey = &PrimaryKey{Name: p.primaryKeyName(table, constraintName), Columns: []string{name}}
+		case p.accept("UNIQUE"):
+			p.accept("KEY")
+			p.addUnique(table, constraintName, []string{name})
+		case p.peekIs(0, "REFERENCES"):
+			if err := p.references(table, constraintName, []string{name}); err != nil {
+				return err
+			}
+		case p.accept("CHECK"):
+			if err := p.check(table, constraintName, name); err != nil {
+				return err
+			}
+		case p.accept("COMMENT"):
+			if !p.done() && p.toks[p.pos].Kind == ddlString {
+				col.Comment = p.next().Text
+			}
+		case p.accept("INVISIBLE"):
+			col.IsInvisible = true
+		case p.accept("ON", "UPDATE"):
+			p.columnDefault(&Column{})
+		case p.accept("GENERATED"):
+			kind := IdentityAlways
+			p.accept("ALWAYS")
+			if p.accept("BY", "DEFAULT") {
+				kind = IdentityByDefault
+			}
+			p.accept("AS")
+			if p.accept("IDENTITY") && !p.mysql {
+				col.Identity = kind
+				if err := p.identity(table, col); err != nil {
+					return err
+				}
+			} else if err := p.generationExpression(col); err != nil {
+				return err
+			}
+		case p.mysql && p.accept("AUTO_INCREMENT"):
+			col.Identity = IdentityAutoIncrement
+			seq := autoIncrementSequence(table.Name, name, col.DataType, 1, 1)
+			p.schema.Sequences[seq.Name] = seq
+		case p.accept("AS"):
+			if err := p.generationExpression(col); err != nil {
+				return err
+			}
+		case p.accept("COLLATE"), p.accept("CHARSET"), p.accept("CHARACTER", "SET"), p.accept("STORAGE"):
+			if !p.done() {
+				p.pos++
+			}
+		default:
+			// AUTO_INCREMENT, VISIBLE and anything unknown
+			p.pos++
+		}
+		constraintName = ""
+	}
+	return nil
+}
+
+// generationExpression parses (expr) [STORED | VIRTUAL | PERSISTENT] of a
+// generated column. MySQL columns are virtual unless declared STORED;
+// Postgres (before 18) only has stored ones
+func (p *ddlParser) generationExpression(col *Column) error {
+	if !p.peekIs(0, "(") {
+		return nil
+	}
+	expr, err := p.balanced()
+	if err != nil {
+		return fmt.Errorf("column %s: %w", col.Name, err)
+	}
+	col.IsGenerated = true
+	col.GenerationExpression = strings.ReplaceAll(p.raw(expr), "`", "")
+	col.GenerationStorage = "virtual"
+	if !p.mysql {
+		col.GenerationStorage = "stored"
+	}
+	switch {
+	case p.accept("STORED"), p.accept("PERSISTENT"):
+		col.GenerationStorage = "stored"
+	case p.accept("VIRTUAL"):
+		col.GenerationStorage = "virtual"
+	}
+	return nil
+}
+
+// pgSequenceMax is the largest value of each Postgres sequence type
+var pgSequenceMax = map[string]int64{
+	"smallint": math.MaxInt16,
+	"integer":  math.MaxInt32,
+	"bigint":   math.MaxInt64,
+}
+
+// createSequence records CREATE SEQUENCE name [options]
+func (p *ddlParser) createSequence() error {
+	p.accept("IF", "NOT", "EXISTS")
+	name, ok := p.qualifiedName()
+	if !ok {
+		return nil
+	}
+	seq := &Sequence{Name: name, DataType: "bigint", Increment: 1, Cache: 1}
+	p.sequenceOptions(seq, true)
+	p.schema.Sequences[name] = seq
+	return nil
+}
+
+// createExtension records CREATE EXTENSION [IF NOT EXISTS] name [WITH]
+// [SCHEMA s] [VERSION v] [CASCADE]
+func (p *ddlParser) createExtension() error {
+	p.accept("IF", "NOT", "EXISTS")
+	if p.done() {
+		return nil
+	}
+	ext := &Extension{Name: p.next().Text}
+	for !p.done() {
+		if p.accept("VERSION") && !p.done() {
+			ext.Version = p.next().Text
+			continue
+		}
+		p.pos++
+	}
+	p.schema.Extensions[ext.Name] = ext
+	return nil
+}
+
+// identity records the sequence of GENERATED ... AS IDENTITY [(options)]
+func (p *ddlParser) identity(table *Table, col *Column) error {
+	var options []ddlToken
+	if p.peekIs(0, "(") {
+		var err error
+		if options, err = p.balanced(); err != nil {
+			return fmt.Errorf("column %s: %w", col.Name, err)
+		}
+	}
+	p.ownedSequence(table, col, options)
+	return nil
+}
+
+// ownedSequence records the sequence backing a serial or identity column,
+// named <table>_<column>_seq unless options say otherwise
+func (p *ddlParser) ownedSequence(table *Table, col *Column, options []ddlToken) {
+	dataType := col.DataType
+	if _, ok := pgSequenceMax[dataType]; !ok {
+		dataType = "bigint"
+	}
+	seq := &Sequence{
+		Name:      table.Name + "_" + col.Name + "_seq",
+		DataType:  dataType,
+		Increment: 1,
+		Cache:     1,
+		OwnedBy:   table.Name + "." + col.Name,
+	}
+	p.sub(options).sequenceOptions(seq, true)
+	p.schema.Sequences[seq.Name] = seq
+}
+
+// sequenceOptions applies the options of CREATE/ALTER SEQUENCE and identity
+// columns to seq. When creating, unset bounds and start get Postgres'
+// defaults for the sequence's type and direction.
+func (p *ddlParser) sequenceOptions(seq *Sequence, create bool) {
+	var minSet, maxSet, startSet bool
+	for !p.done() {
+		switch {
+		case p.accept("AS"):
+			if !p.done() {
+				seq.DataType, _ = p.dataType([]ddlToken{p.next()})
+			}
+		case p.accept("INCREMENT"):
+			p.accept("BY")
+			seq.Increment = p.integer()
+		case p.accept("NO", "MINVALUE"), p.accept("NO", "MAXVALUE"):
+		case p.accept("MINVALUE"):
+			seq.MinValue, minSet = p.integer(), true
+		case p.accept("MAXVALUE"):
+			seq.MaxValue, maxSet = p.integer(), true
+		case p.accept("START"):
+			p.accept("WITH")
+			seq.Start, startSet = p.integer(), true
+		case p.accept("CACHE"):
+			seq.Cache = p.integer()
+		case p.accept("NO", "CYCLE"):
+			seq.Cycle = false
+		case p.accept("CYCLE"):
+			seq.Cycle = true
+		case p.accept("OWNED", "BY"):
+			var parts []string
+			for !p.done() && !p.peekIs(0, ")") {
+				if t := p.next(); t.Kind == ddlIdent {
+					parts = append(parts, p.ident(t))
+				}
+			}
+			seq.OwnedBy = ""
+			if len(parts) >= 2 {
+				seq.OwnedBy = parts[len(parts)-2] + "." + parts[len(parts)-1]
+			}
+		case p.accept("SEQUENCE", "NAME"):
+			if name, ok := p.qualifiedName(); ok {
+				seq.Name = name
+			}
+		default:
+			p.pos++
+		}
+	}
+	if !create {
+		return
+	}
+
+	typeMax := pgSequenceMax[seq.DataType]
+	if !minSet {
+		seq.MinValue = 1
+		if seq.Increment < 0 {
+			seq.MinValue = -typeMax - 1
+		}
+	}
+	if !maxSet {
+		seq.MaxValue = typeMax
+		if seq.Increment < 0 {
+			seq.MaxValue = -1
+		}
+	}
+	if !startSet {
+		seq.Start = seq.MinValue
+		if seq.Increment < 0 {
+			seq.Start = seq.MaxValue
+		}
+	}
+}
+
+// integer reads an optionally signed integer literal; anything else is 0
+func (p *ddlParser) integer() int64 {
+	sign := int64(1)
+	if p.accept("-") {
+		sign = -1
+	} else {
+		p.accept("+")
+	}
+	if p.done() || p.toks[p.pos].Kind != ddlNumber {
+		return 0
+	}
+	n, _ := strconv.ParseInt(p.next().Text, 10, 64)
+	return sign * n
+}
+
+func (p *ddlParser) columnDefault(col *Column) {
+	toks := p.skipUntilStop()
+	if len(toks) == 1 && toks[0].Kind == ddlIdent && strings.EqualFold(toks[0].Text, "NULL") {
+		col.DefaultValue = nil
+		return
+	}
+	value := p.raw(toks)
+	if p.mysql && len(toks) == 1 && toks[0].Kind == ddlString {
+		// information_schema reports MySQL string defaults unquoted
+		value = toks[0].Text
+	}
+	if !p.mysql {
+		// pg_dump qualifies sequences and types because it clears search_path
+		value = strings.ReplaceAll(value, "nextval('public.", "nextval('")
+		value = strings.ReplaceAll(value, "::public.", "::")
+	}
+	col.DefaultValue = &value
+}
+
+// dataType spells a column type the way live extraction reports it: MySQL's
+// column_type keeps modifiers ("decimal(10,2)", "int unsigned"), while
+// Postgres' information_schema data_type drops them and resolves aliases
+// ("varchar(255)" becomes "character varying"). userType names the enum,
+// domain or other user-defined type a Postgres column uses.
+func (p *ddlParser) dataType(toks []ddlToken) (dataType, userType string) {
+	t := strings.ToLower(strings.Join(strings.Fields(p.raw(toks)), " "))
+	t = strings.ReplaceAll(t, ", ", ",")
+	t = strings.ReplaceAll(t, " (", "(")
+	if p.mysql {
+		return t, ""
+	}
+
+	if strings.HasSuffix(t, "]") || strings.HasPrefix(t, "_") {
+		return "ARRAY", ""
+	}
+	last := toks[len(toks)-1]
+	qualified := len(toks) >= 3 && toks[len(toks)-2].Text == "."
+	if last.Kind == ddlIdent && (len(toks) == 1 || qualified) {
+		name := p.ident(last)
+		ut, known := p.schema.Types[name]
+		switch {
+		case known && ut.Kind == UserTypeDomain:
+			return pgDataType(ut.BaseType), name
+		case known || qualified || last.Quoted:
+			return "USER-DEFINED", name
+		}
+	}
+	return pgDataType(t), ""
+}
+
+// pgDataType strips type modifiers and resolves aliases
+func pgDataType(t string) string {
+	t = typeModifierPattern.ReplaceAllString(t, "")
+	if alias, ok := pgDataTypes[t]; ok {
+		return alias
+	}
+	return t
+}
+
+// pgTypeModifiers sets the length, precision and scale of a column from the
+// modifier of its DDL type, the way information_schema reports them: char
+// without a length is char(1), numeric without precision has none and time
+// types default to microseconds
+func pgTypeModifiers(col *Column, t string) {
+	var mods []int64
+	if m := typeModifierPattern.FindString(t); m != "" {
+		for _, part := range strings.Split(strings.Trim(strings.TrimSpace(m), "()"), ",") {
+			n, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				return
+			}
+			mods = append(mods, n)
+		}
+	}
+	modifier := func(i int, fallback *int64) *int64 {
+		if i < len(mods) {
+			return &mods[i]
+		}
+		return fallback
+	}
+	one, zero, micro := int64(1), int64(0), int64(6)
+	switch col.DataType {
+	case "character varying", "bit varying":
+		col.CharMaxLength = modifier(0, nil)
+	case "character", "bit":
+		col.CharMaxLength = modifier(0, &one)
+	case "numeric":
+		if len(mods) > 0 {
+			col.NumericPrecision, col.NumericScale = modifier(0, nil), modifier(1, &zero)
+		}
+	case "timestamp without time zone", "timestamp with time zone", "time without time zone", "time with time zone":
+		col.DatetimePrecision = modifier(0, &micro)
+	}
+}
+
+// pgFormatType resolves aliases but keeps modifiers, like format_type():
+// "varchar(20)" is "character varying(20)" and "timestamptz(3)"
+// "timestamp(3) with time zone"
+func pgFormatType(t string) string {
+	if element, ok := strings.CutSuffix(t, "[]"); ok {
+		return pgFormatType(strings.TrimSpace(element)) + "[]"
+	}
+	modifier := strings.TrimSpace(typeModifierPattern.FindString(t))
+	name := pgDataType(t)
+	if base, zone, ok := strings.Cut(name, " with"); ok && modifier != "" {
+		return base + modifier + " with" + zone
+	}
+	return name + modifier
+}
+
+// pgArrayElement returns the element type of an array type written as
+// text[], text[][] or _text, as format_type() renders it
+func pgArrayElement(t string) string {
+	t = strings.ToLower(strings.Join(strings.Fields(t), " "))
+	t = strings.ReplaceAll(strings.ReplaceAll(t, ", ", ","), " (", "(")
+	if i := strings.Index(t, "["); i >= 0 {
+		return pgFormatType(strings.TrimSpace(t[:i]))
+	}
+	return pgFormatType(strings.TrimPrefix(t, "_"))
+}
+
+// createEnum records CREATE TYPE name AS ENUM (...) and composite types,
+// CREATE TYPE name AS (attribute type, ...); other kinds of types are only
+// known by name
+func (p *ddlParser) createEnum() error {
+	name, ok := p.qualifiedName()
+	if !ok || !p.accept("AS") {
+		return nil
+	}
+	if p.peekIs(0, "(") {
+		return p.createComposite(name)
+	}
+	if !p.accept("ENUM") {
+		return nil
+	}
+	inner, err := p.balanced()
+	if err != nil {
+		return fmt.Errorf("type %s: %w", name, err)
+	}
+	ut := &UserType{Name: name, Kind: UserTypeEnum}
+	for _, t := range inner {
+		if t.Kind == ddlString {
+			ut.Labels = append(ut.Labels, t.Text)
+		}
+	}
+	p.schema.Types[name] = ut
+	return nil
+}
+
+// createComposite records the attributes of CREATE TYPE name AS (...)
+func (p *ddlParser) createComposite(name string) error {
+	inner, err := p.balanced()
+	if err != nil {
+		return fmt.Errorf("type %s: %w", name, err)
+	}
+	ut := &UserType{Name: name, Kind: UserTypeComposite}
+	for _, part := range splitTopLevel(inner) {
+		if len(part) < 2 {
+			continue
+		}
+		// COLLATE is the only attribute option
+		typeToks := part[1:]
+		for i, t := range typeToks {
+			if strings.EqualFold(t.Text, "COLLATE") && t.Kind == ddlIdent && !t.Quoted {
+				typeToks = typeToks[:i]
+				break
+			}
+		}
+		attrType := strings.ToLower(strings.Join(strings.Fields(p.raw(typeToks)), " "))
+		attrType = strings.ReplaceAll(strings.ReplaceAll(attrType, ", ", ","), " (", "(")
+		if strings.HasPrefix(attrType, "_") {
+			attrType = strings.TrimPrefix(attrType, "_") + "[]"
+		}
+		ut.Attributes = append(ut.Attributes, &TypeAttribute{Name: p.ident(part[0]), Type: pgFormatType(attrType)})
+	}
+	p.schema.Types[name] = ut
+	return nil
+}
+
+// alterEnum applies ALTER TYPE ... ADD VALUE [IF NOT EXISTS] 'label'
+// [BEFORE|AFTER 'label'] and RENAME VALUE 'old' TO 'new'
+func (p *ddlParser) alterEnum(ut *UserType) {
+	label := func() (string, bool) {
+		if p.done() || p.toks[p.pos].Kind != ddlString {
+			return "", false
+		}
+		return p.next().Text, true
+	}
+	switch {
+	case p.accept("ADD", "VALUE"):
+		p.accept("IF", "NOT", "EXISTS")
+		value, ok := label()
+		if !ok || makeSet(ut.Labels)[value] {
+			return
+		}
+		at := len(ut.Labels)
+		before := p.accept("BEFORE")
+		if before || p.accept("AFTER") {
+			if neighbor, ok := label(); ok {
+				for i, l := range ut.Labels {
+					if l == neighbor {
+						at = i
+						if !before {
+							at++
+						}
+					}
+				}
+			}
+		}
+		ut.Labels = append(ut.Labels[:at], append([]string{value}, ut.Labels[at:]...)...)
+	case p.accept("RENAME", "VALUE"):
+		from, ok := label()
+		if !ok || !p.accept("TO") {
+			return
+		}
+		if to, ok := label(); ok {
+			for i, l := range ut.Labels {
+				if l == from {
+					ut.Labels[i] = to
+				}
+			}
+		}
+	}
+}
+
+// createDomain records CREATE DOMAIN name [AS] type [DEFAULT ...] [NOT NULL]
+// [CONSTRAINT name] CHECK (...)
+func (p *ddlParser) createDomain() error {
+	name, ok := p.qualifiedName()
+	if !ok {
+		return nil
+	}
+	p.accept("AS")
+	typeToks := p.skipUntilStop()
+	if len(typeToks) == 0 {
+		return fmt.Errorf("domain %s has no type", name)
+	}
+	base := strings.ToLower(strings.Join(strings.Fields(p.raw(typeToks)), " "))
+	ut := &UserType{Name: name, Kind: UserTypeDomain, BaseType: pgFormatType(strings.ReplaceAll(base, ", ", ","))}
+	for !p.done() {
+		switch {
+		case p.accept("DEFAULT"):
+			col := &Column{}
+			p.columnDefault(col)
+			ut.Default = col.DefaultValue
+		case p.accept("NOT", "NULL"):
+			ut.NotNull = true
+		case p.accept("CHECK"):
+			start := p.pos - 1
+			if _, err := p.balanced(); err != nil {
+				return fmt.Errorf("domain %s: %w", name, err)
+			}
+			ut.Checks = append(ut.Checks, p.raw(p.toks[start:p.pos]))
+		default:
+			p.pos++
+		}
+	}
+	sort.Strings(ut.Checks)
+	p.schema.Types[name] = ut
+	return nil
+}
+
+var typeModifierPattern = regexp.MustCompile(`\s*\([^)]*\)`)
+
+// pgDataTypes maps type spellings accepted in DDL to information_schema's
+var pgDataTypes = map[string]string{
+	"int":         "integer",
+	"int4":        "integer",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"int8":        "bigint",
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+	"int2":        "smallint",
+	"smallserial": "smallint",
+	"serial2":     "smallint",
+	"bool":        "boolean",
+	"varchar":     "character varying",
+	"char":        "character",
+	"bpchar":      "character",
+	"float8":      "double precision",
+	"float":       "double precision",
+	"float4":      "real",
+	"decimal":     "numeric",
+	"timestamp":   "timestamp without time zone",
+	"timestamptz": "timestamp with time zone",
+	"time":        "time without time zone",
+	"timetz":      "time with time zone",
+	"varbit":      "bit varying",
+}
+
+// triggerEventOrder is the order trigger events are listed in, matching
+// extraction
+var triggerEventOrder = map[string]int{"INSERT": 0, "UPDATE": 1, "DELETE": 2, "TRUNCATE": 3}
+
+// createTrigger records CREATE TRIGGER name timing events ON table ...,
+// keeping the function a Postgres trigger executes or the body of a MySQL one
+func (p *ddlParser) createTrigger() error {
+	if p.done() {
+		return nil
+	}
+	trigger := &Trigger{Name: p.ident(p.next()), Level: "STATEMENT"}
+	if p.mysql {
+		trigger.Level = "ROW"
+	}
+	switch {
+	case p.accept("BEFORE"):
+		trigger.Timing = "BEFORE"
+	case p.accept("AFTER"):
+		trigger.Timing = "AFTER"
+	case p.accept("INSTEAD", "OF"):
+		trigger.Timing = "INSTEAD OF"
+	}
+	for !p.done() && !p.peekIs(0, "ON") {
+		t := p.next()
+		if _, ok := triggerEventOrder[strings.ToUpper(t.Text)]; ok && t.Kind == ddlIdent && !t.Quoted {
+			trigger.Events = append(trigger.Events, strings.ToUpper(t.Text))
+		}
+	}
+	sort.Slice(trigger.Events, func(i, j int) bool {
+		return triggerEventOrder[trigger.Events[i]] < triggerEventOrder[trigger.Events[j]]
+	})
+	if !p.accept("ON") {
+		return nil
+	}
+	tableName, ok := p.qualifiedName()
+	table := p.schema.Tables[tableName]
+	if !ok || table == nil {
+		return nil
+	}
+
+	for !p.done() {
+		switch {
+		case p.accept("FOR"):
+			p.accept("EACH")
+			if p.accept("ROW") {
+				trigger.Level = "ROW"
+			} else if p.accept("STATEMENT") {
+				trigger.Level = "STATEMENT"
+			}
+			if p.mysql {
+				if p.accept("FOLLOWS") || p.accept("PRECEDES") {
+					p.pos++
+				}
+				trigger.Body = strings.TrimSpace(p.raw(p.toks[p.pos:]))
+				p.pos = len(p.toks)
+			}
+		case p.accept("WHEN"):
+			inner, err := p.balanced()
+			if err != nil {
+				return fmt.Errorf("trigger %s: %w", trigger.Name, err)
+			}
+			trigger.Condition = p.raw(inner)
+		case p.accept("EXECUTE"):
+			_ = p.accept("FUNCTION") || p.accept("PROCEDURE")
+			trigger.Function = strings.TrimPrefix(p.raw(p.toks[p.pos:]), "public.")
+			p.pos = len(p.toks)
+		default:
+			p.pos++
+		}
+	}
+	table.Triggers[trigger.Name] = trigger
+	return nil
+}
+
+// routineOptionWords end the RETURNS type of a Postgres routine
+var routineOptionWords = []string{
+	"LANGUAGE", "AS", "IMMUTABLE", "STABLE", "VOLATILE", "STRICT", "CALLED", "RETURNS", "SECURITY",
+	"EXTERNAL", "PARALLEL", "COST", "ROWS", "SUPPORT", "SET", "WINDOW", "LEAKPROOF", "NOT", "TRANSFORM",
+	"BEGIN", "RETURN",
+}
+
+// createRoutine records CREATE FUNCTION/PROCEDURE name (args) [RETURNS type]
+// with its language and body: the AS string for Postgres, everything after
+// the characteristics for MySQL
+func (p *ddlParser) createRoutine(kind string) error {
+	name, ok := p.qualifiedName()
+	if !ok || !p.peekIs(0, "(") {
+		return nil
+	}
+	argToks, err := p.balanced()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", kind, name, err)
+	}
+	routine := &Routine{Name: name, Kind: kind}
+	args, argTypes := p.routineArguments(argToks, kind)
+	routine.Arguments = strings.Join(args, ", ")
+
+	if p.accept("RETURNS") {
+		if p.mysql {
+			start := p.pos
+			if !p.done() {
+				p.pos++
+			}
+			if p.peekIs(0, "(") {
+				p.balanced()
+			}
+			for p.accept("UNSIGNED") || p.accept("SIGNED") || p.accept("ZEROFILL") {
+			}
+			routine.Returns = mysqlRoutineType(p.raw(p.toks[start:p.pos]))
+			for (p.accept("CHARSET") || p.accept("CHARACTER", "SET") || p.accept("COLLATE")) && !p.done() {
+				p.pos++
+			}
+		} else {
+			routine.Returns = p.routineReturns()
+		}
+	}
+
+	if p.mysql {
+		routine.Language = "SQL"
+		for !p.done() {
+			switch {
+			case p.accept("COMMENT"):
+				p.pos++
+			case p.accept("LANGUAGE", "SQL"), p.accept("NOT", "DETERMINISTIC"), p.accept("DETERMINISTIC"),
+				p.accept("CONTAINS", "SQL"), p.accept("NO", "SQL"), p.accept("READS", "SQL", "DATA"),
+				p.accept("MODIFIES", "SQL", "DATA"):
+			case p.accept("SQL", "SECURITY"):
+				p.pos++
+			default:
+				routine.Body = normalizeRoutineBody(p.raw(p.toks[p.pos:]))
+				p.pos = len(p.toks)
+			}
+		}
+	} else {
+		for !p.done() {
+			switch {
+			case p.accept("LANGUAGE") && !p.done():
+				routine.Language = strings.ToLower(p.next().Text)
+			case p.accept("AS") && !p.done():
+				routine.Body = normalizeRoutineBody(p.next().Text)
+			default:
+				p.pos++
+			}
+		}
+	}
+	if p.mysql {
+		p.schema.Routines[mysqlRoutineKey(kind, name, argTypes)] = routine
+	} else {
+		p.schema.Routines[routineKey(name, argTypes)] = routine
+	}
+	return nil
+}
+
+// createEvent records CREATE EVENT name ON SCHEDULE ... DO body the way
+// information_schema.events describes it: timestamps unquoted, STARTS
+// dropped, ENABLED and NOT PRESERVE unless stated otherwise
+func (p *ddlParser) createEvent() error {
+	p.accept("IF", "NOT", "EXISTS")
+	name, ok := p.qualifiedName()
+	if !ok || !p.accept("ON", "SCHEDULE") {
+		return nil
+	}
+	event := &Event{Name: name, Status: "ENABLED", OnCompletion: "NOT PRESERVE"}
+	// value renders the tokens up to the next clause, unquoting a lone
+	// timestamp literal
+	value := func() string {
+		start := p.pos
+		for !p.done() && !p.peekIs(0, "STARTS") && !p.peekIs(0, "ENDS") && !p.peekIs(0, "ON") &&
+			!p.peekIs(0, "ENABLE") && !p.peekIs(0, "DISABLE") && !p.peekIs(0, "COMMENT") && !p.peekIs(0, "DO") {
+			p.pos++
+		}
+		if p.pos == start+1 && p.toks[start].Kind == ddlString {
+			return p.toks[start].Text
+		}
+		return p.raw(p.toks[start:p.pos])
+	}
+	if p.accept("AT") {
+		event.Schedule = "AT " + value()
+	} else if p.accept("EVERY") && !p.done() {
+		event.Schedule = "EVERY " + p.next().Text
+		if !p.done() {
+			event.Schedule += " " + strings.ToUpper(p.next().Text)
+		}
+		if p.accept("STARTS") {
+			value()
+		}
+		if p.accept("ENDS") {
+			event.Schedule += " ENDS " + value()
+		}
+	}
+	for !p.done() {
+		switch {
+		case p.accept("ON", "COMPLETION", "NOT", "PRESERVE"):
+			event.OnCompletion = "NOT PRESERVE"
+		case p.accept("ON", "COMPLETION", "PRESERVE"):
+			event.OnCompletion = "PRESERVE"
+		case p.accept("ENABLE"):
+			event.Status = "ENABLED"
+		case p.accept("DISABLE", "ON", "SLAVE"), p.accept("DISABLE", "ON", "REPLICA"):
+			event.Status = "SLAVESIDE_DISABLED"
+		case p.accept("DISABLE"):
+			event.Status = "DISABLED"
+		case p.accept("COMMENT"):
+			p.pos++
+		case p.accept("DO"):
+			event.Body = normalizeRoutineBody(p.raw(p.toks[p.pos:]))
+			p.pos = len(p.toks)
+		default:
+			p.pos++
+		}
+	}
+	p.schema.Events[name] = event
+	return nil
+}
+
+// routineArguments renders each argument like pg_get_function_arguments
+// ("OUT total numeric", "n integer DEFAULT 1") or MySQL's parameter list,
+// and returns the types that make up the routine's signature
+func (p *ddlParser) routineArguments(toks []ddlToken, kind string) (args, argTypes []string) {
+	for _, arg := range splitTopLevel(toks) {
+		if len(arg) == 0 {
+			continue
+		}
+		mode := ""
+		if t := arg[0]; t.Kind == ddlIdent && !t.Quoted {
+			switch up := strings.ToUpper(t.Text); up {
+			case "IN", "OUT", "INOUT", "VARIADIC":
+				mode, arg = up, arg[1:]
+			}
+		}
+		if p.mysql && mode == "" && kind == RoutineProcedure {
+			mode = "IN"
+		}
+		if !p.mysql && mode == "IN" {
+			mode = ""
+		}
+
+		var defaultToks []ddlToken
+		for i, t := range arg {
+			if t.Kind == ddlPunct && t.Text == "=" || t.Kind == ddlIdent && !t.Quoted && strings.EqualFold(t.Text, "DEFAULT") {
+				arg, defaultToks = arg[:i], arg[i+1:]
+				break
+			}
+		}
+		name := ""
+		if len(arg) > 1 && (p.mysql || routineArgumentNamed(arg)) {
+			name, arg = p.ident(arg[0]), arg[1:]
+		}
+		if len(arg) == 0 {
+			continue
+		}
+
+		dataType := p.routineType(arg)
+		parts := []string{}
+		if mode != "" {
+			parts = append(parts, mode)
+		}
+		if name != "" {
+			parts = append(parts, name)
+		}
+		parts = append(parts, dataType)
+		if len(defaultToks) > 0 {
+			parts = append(parts, "DEFAULT", p.raw(defaultToks))
+		}
+		args = append(args, strings.Join(parts, " "))
+		// Since Postgres 14, OUT arguments of procedures are part of their
+		// signature too
+		if mode != "OUT" || kind == RoutineProcedure {
+			argTypes = append(argTypes, dataType)
+		}
+	}
+	return args, argTypes
+}
+
+// routineArgumentNamed tells "name type" from multi-word Postgres types
+// such as "double precision" or "timestamp with time zone"
+func routineArgumentNamed(arg []ddlToken) bool {
+	switch strings.ToLower(arg[0].Text) {
+	case "double", "character", "char", "bit", "timestamp", "time", "interval", "national":
+		switch strings.ToLower(arg[1].Text) {
+		case "precision", "varying", "with", "without", "(", "[":
+			return false
+		}
+	}
+	return true
+}
+
+// routineType spells an argument or result type like format_type() without
+// modifiers (Postgres) or like information_schema's dtd_identifier (MySQL)
+func (p *ddlParser) routineType(toks []ddlToken) string {
+	t := strings.ToLower(strings.Join(strings.Fields(p.raw(toks)), " "))
+	t = strings.ReplaceAll(t, ", ", ",")
+	t = strings.ReplaceAll(t, " (", "(")
+	if p.mysql {
+		return mysqlRoutineType(t)
+	}
+	t = strings.TrimPrefix(t, "public.")
+	array := ""
+	if i := strings.Index(t, "["); i >= 0 {
+		t, array = strings.TrimSpace(t[:i]), "[]"
+	}
+	return pgDataType(t) + array
+}
+
+// routineReturns reads a Postgres RETURNS clause: a type, SETOF type or
+// TABLE (columns), spelled like pg_get_function_result()
+func (p *ddlParser) routineReturns() string {
+	if p.accept("TABLE") {
+		inner, err := p.balanced()
+		if err != nil {
+			return ""
+		}
+		var columns []string
+		for _, col := range splitTopLevel(inner) {
+			if len(col) > 1 {
+				columns = append(columns, p.ident(col[0])+" "+p.routineType(col[1:]))
+			}
+		}
+		return "TABLE(" + strings.Join(columns, ", ") + ")"
+	}
+	prefix := ""
+	if p.accept("SETOF") {
+		prefix = "SETOF "
+	}
+	start := p.pos
+	for !p.done() {
+		stop := false
+		for _, w := range routineOptionWords {
+			if p.peekIs(0, w) {
+				stop = true
+				break
+			}
+		}
+		if stop {
+			break
+		}
+		if p.peekIs(0, "(") {
+			p.balanced()
+			continue
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return ""
+	}
+	return prefix + p.routineType(p.toks[start:p.pos])
+}
+
+func (p *ddlParser) createIndex(unique bool, kind string) error {
+	p.accept("CONCURRENTLY")
+	p.accept("IF", "NOT", "EXISTS")
+	name := ""
+	if !p.peekIs(0, "ON") {
+		name = p.ident(p.next())
+	}
+	if !p.accept("ON") {
+		return nil
+	}
+	p.accept("ONLY")
+	tableName, ok := p.qualifiedName()
+	table := p.schema.Tables[tableName]
+	if !ok || table == nil {
+		return nil
+	}
+	// InnoDB builds B-trees whatever USING asks for; FULLTEXT and SPATIAL
+	// indexes have no method
+	method := ""
+	if kind == "" {
+		method = "btree"
+	}
+	if p.accept("USING") && !p.done() {
+		if t := p.next(); !p.mysql {
+			method = strings.ToLower(t.Text)
+		}
+	}
+	cols, opClasses, orders, prefixLengths, err := p.indexKeys()
+	if err != nil {
+		return fmt.Errorf("index %s: %w", name, err)
+	}
+	if name == "" {
+		name = tableName + "_" + strings.Join(cols, "_") + "_idx"
+	}
+	if unique && p.mysql {
+		table.UniqueConstraints[name] = &Unique{Name: name, Columns: cols}
+		return nil
+	}
+	idx := &Index{Name: name, Columns: cols, IsUnique: unique, Method: method, OpClasses: opClasses, Orders: orders, PrefixLengths: prefixLengths, Kind: kind}
+	if !p.mysql && p.accept("INCLUDE") {
+		if idx.Include, err = p.columnList(); err != nil {
+			return fmt.Errorf("index %s: %w", name, err)
+		}
+	}
+	if !p.mysql && p.accept("WITH") && p.peekIs(0, "(") {
+		params, err := p.balanced()
+		if err != nil {
+			return fmt.Errorf("index %s: %w", name, err)
+		}
+		for _, param := range splitTopLevel(params) {
+			if len(param) > 2 && param[1].Kind == ddlPunct && param[1].Text == "=" {
+				if idx.Options == nil {
+					idx.Options = make(map[string]string)
+				}
+				idx.Options[strings.ToLower(param[0].Text)] = p.raw(param[2:])
+			}
+		}
+	}
+	if !p.mysql && kind == "" {
+		idx.Kind = classifyIndex(idx, func(key string) string {
+			if col := table.Columns[key]; col != nil {
+				return columnTypeName(col)
+			}
+			return ""
+		})
+	}
+	table.Indexes[name] = idx
+	return nil
+}
+
+// indexKeys reads the key list of CREATE INDEX like columnList, along with
+// the operator class and ordering of each key (Postgres only; MySQL keys
+// only yield their columns)
+func (p *ddlParser) indexKeys() (cols, opClasses, orders, prefixLengths []string, err error) {
+	inner, err := p.balanced()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	for _, part := range splitTopLevel(inner) {
+		if len(part) == 0 {
+			continue
+		}
+		// The key is a column, or an expression running to its closing
+		// parenthesis; operator class and ordering follow it
+		end := 1
+		if part[0].Kind == ddlPunct || (len(part) > 1 && part[1].Kind == ddlPunct && part[1].Text == "(" && !part[0].Quoted && !p.mysql) {
+			depth := 0
+			for end = 0; end < len(part); end++ {
+				if part[end].Kind != ddlPunct {
+					continue
+				}
+				if part[end].Text == "(" {
+					depth++
+				} else if part[end].Text == ")" {
+					if depth--; depth == 0 {
+						end++
+						break
+					}
+				}
+			}
+		}
+		if end == 1 && part[0].Kind == ddlIdent {
+			cols = append(cols, p.ident(part[0]))
+		} else {
+			cols = append(cols, p.raw(part[:end]))
+		}
+
+		desc, nulls, opClass, prefixLength := false, "", "", ""
+		rest := part[end:]
+		for i := 0; i < len(rest); i++ {
+			// MySQL indexes the leading characters of a column with name(20)
+			if p.mysql && rest[i].Kind == ddlPunct && rest[i].Text == "(" && i+1 < len(rest) && rest[i+1].Kind == ddlNumber {
+				prefixLength = rest[i+1].Text
+				i++
+				continue
+			}
+			if rest[i].Kind != ddlIdent {
+				continue
+			}
+			switch word := strings.ToUpper(rest[i].Text); {
+			case rest[i].Quoted:
+				opClass = p.ident(rest[i])
+			case word == "ASC":
+			case word == "DESC":
+				desc = true
+			case word == "NULLS" && i+1 < len(rest):
+				nulls = strings.ToUpper(rest[i+1].Text)
+				i++
+			case word == "COLLATE":
+				i++
+			default:
+				opClass = p.ident(rest[i])
+			}
+		}
+		nullsFirst := desc
+		if nulls != "" {
+			nullsFirst = nulls == "FIRST"
+		}
+		opClasses = append(opClasses, opClass)
+		prefixLengths = append(prefixLengths, prefixLength)
+		orders = append(orders, indexOrder(desc, nullsFirst))
+	}
+	if p.mysql {
+		return cols, nil, compactList(orders), compactList(prefixLengths), nil
+	}
+	return cols, compactList(opClasses), compactList(orders), nil, nil
+}
+
+func (p *ddlParser) alterTable() error {
+	p.accept("IF", "EXISTS")
+	p.accept("ONLY")
+	tableName, ok := p.qualifiedName()
+	table := p.schema.Tables[tableName]
+	if !ok || table == nil {
+		return nil
+	}
+	for _, action := range splitTopLevel(p.toks[p.pos:]) {
+		a := p.sub(action)
+		switch {
+		case a.accept("ADD"):
+			switch {
+			case a.isConstraintStart():
+				if err := a.tableConstraint(table); err != nil {
+					return fmt.Errorf("table %s: %w", tableName, err)
+				}
+			case a.accept("KEY"), a.accept("INDEX"):
+				if err := a.indexElement(table, ""); err != nil {
+					return fmt.Errorf("table %s: %w", tableName, err)
+				}
+			default:
+				a.accept("COLUMN")
+				a.accept("IF", "NOT", "EXISTS")
+				if err := a.column(table); err != nil {
+					return fmt.Errorf("table %s: %w", tableName, err)
+				}
+			}
+		case a.accept("ATTACH", "PARTITION"):
+			// pg_dump creates partitions as plain tables and attaches them
+			childName, ok := a.qualifiedName()
+			if !ok || table.Partitioning == nil {
+				continue
+			}
+			bound, err := a.partitionBound()
+			if err != nil {
+				return fmt.Errorf("table %s: %w", tableName, err)
+			}
+			table.Partitioning.Partitions = append(table.Partitioning.Partitions, &Partition{Name: childName, Bound: bound})
+			if child := p.schema.Tables[childName]; child != nil {
+				child.PartitionOf = tableName
+			}
+		case a.accept("INHERIT"):
+			if parentName, ok := a.qualifiedName(); ok {
+				table.Inherits = append(table.Inherits, parentName)
+			}
+		case a.accept("NO", "INHERIT"):
+			if parentName, ok := a.qualifiedName(); ok {
+				var inherits []string
+				for _, name := range table.Inherits {
+					if name != parentName {
+						inherits = append(inherits, name)
+					}
+				}
+				table.Inherits = inherits
+			}
+		case a.accept("REPLICA", "IDENTITY") && !a.done():
+			switch {
+			case a.accept("USING", "INDEX") && !a.done():
+				table.ReplicaIdentity = "index " + a.ident(a.next())
+			default:
+				table.ReplicaIdentity = strings.ToLower(a.next().Text)
+			}
+		case a.peekIs(0, "ENABLE") || a.peekIs(0, "DISABLE"):
+			status := ""
+			switch {
+			case a.accept("DISABLE"):
+				status = TriggerDisabled
+			case a.accept("ENABLE", "REPLICA"):
+				status = TriggerReplica
+			case a.accept("ENABLE", "ALWAYS"):
+				status = TriggerAlways
+			default:
+				a.accept("ENABLE")
+			}
+			if !a.accept("TRIGGER") || a.done() {
+				continue
+			}
+			if trigger := table.Triggers[a.ident(a.next())]; trigger != nil {
+				trigger.Status = status
+			}
+		case a.accept("ALTER"):
+			a.accept("COLUMN")
+			if a.done() {
+				continue
+			}
+			col := table.Columns[a.ident(a.next())]
+			if col == nil {
+				continue
+			}
+			switch {
+			case a.accept("SET", "DEFAULT"):
+				a.columnDefault(col)
+			case a.accept("DROP", "DEFAULT"):
+				col.DefaultValue = nil
+			case a.accept("SET", "NOT", "NULL"):
+				col.IsNullable = false
+			case a.accept("DROP", "NOT", "NULL"):
+				col.IsNullable = true
+			case a.accept("ADD", "GENERATED"):
+				kind := IdentityAlways
+				a.accept("ALWAYS")
+				if a.accept("BY", "DEFAULT") {
+					kind = IdentityByDefault
+				}
+				if a.accept("AS", "IDENTITY") && !a.mysql {
+					col.Identity = kind
+					if err := a.identity(table, col); err != nil {
+						return fmt.Errorf("table %s: %w", tableName, err)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// createRule parses CREATE RULE name AS ON event TO table [WHERE condition]
+// DO [ALSO | INSTEAD] actions; rules on other schemas are not tracked
+func (p *ddlParser) createRule() error {
+	if p.done() {
+		return nil
+	}
+	name := p.ident(p.next())
+	if !p.accept("AS", "ON") || p.done() {
+		return fmt.Errorf("rule %s: expected AS ON near %q", name, p.near())
+	}
+	rule := &RewriteRule{Name: name, Event: strings.ToUpper(p.next().Text)}
+	if !p.accept("TO") {
+		return fmt.Errorf("rule %s: expected TO near %q", name, p.near())
+	}
+	tableName, ok := p.qualifiedName()
+	table := p.schema.Tables[tableName]
+	if !ok || table == nil {
+		return nil
+	}
+	if p.accept("WHERE") {
+		start := p.pos
+		for depth := 0; !p.done() && (depth > 0 || !p.peekIs(0, "DO")); p.pos++ {
+			switch {
+			case p.peekIs(0, "("):
+				depth++
+			case p.peekIs(0, ")"):
+				depth--
+			}
+		}
+		rule.Condition = normalizeRuleText(p.raw(p.toks[start:p.pos]))
+	}
+	if !p.accept("DO") {
+		return fmt.Errorf("rule %s: expected DO near %q", name, p.near())
+	}
+	rule.Instead = p.accept("INSTEAD")
+	p.accept("ALSO")
+	rule.Actions = normalizeRuleText(p.raw(p.toks[p.pos:]))
+	if table.RewriteRules == nil {
+		table.RewriteRules = make(map[string]*RewriteRule)
+	}
+	table.RewriteRules[name] = rule
+	return nil
+}
+
+// createServer parses CREATE SERVER name [TYPE '...'] [VERSION '...']
+// FOREIGN DATA WRAPPER wrapper [OPTIONS (...)]
+func (p *ddlParser) createServer() error {
+	p.accept("IF", "NOT", "EXISTS")
+	if p.done() {
+		return nil
+	}
+	server := &ForeignServer{Name: p.ident(p.next())}
+	for !p.done() {
+		switch {
+		case p.accept("FOREIGN", "DATA", "WRAPPER") && !p.done():
+			server.Wrapper = p.ident(p.next())
+		case p.peekIs(0, "OPTIONS") && p.peekIs(1, "("):
+			p.pos++
+			options, err := p.foreignOptions()
+			if err != nil {
+				return fmt.Errorf("server %s: %w", server.Name, err)
+			}
+			server.Options = options
+		default:
+			p.pos++
+		}
+	}
+	p.schema.ForeignServers[server.Name] = server
+	return nil
+}
+
+// createUserMapping records the role of CREATE USER MAPPING FOR role SERVER
+// name on its server; the options, which hold credentials, are skipped
+func (p *ddlParser) createUserMapping() error {
+	p.accept("IF", "NOT", "EXISTS")
+	if !p.accept("FOR") || p.done() {
+		return fmt.Errorf("expected FOR near %q", p.near())
+	}
+	role := p.ident(p.next())
+	if !p.accept("SERVER") || p.done() {
+		return fmt.Errorf("user mapping for %s: expected SERVER near %q", role, p.near())
+	}
+	if server := p.schema.ForeignServers[p.ident(p.next())]; server != nil {
+		server.UserMappings = append(server.UserMappings, role)
+		sort.Strings(server.UserMappings)
+	}
+	return nil
+}
+
+// foreignOptions parses OPTIONS (key 'value', ...) into "key=value" pairs
+// in name order
+func (p *ddlParser) foreignOptions() ([]string, error) {
+	body, err := p.balanced()
+	if err != nil {
+		return nil, err
+	}
+	var options []string
+	for _, option := range splitTopLevel(body) {
+		if len(option) >= 2 {
+			options = append(options, p.ident(option[len(option)-2])+"="+option[len(option)-1].Text)
+		}
+	}
+	sort.Strings(options)
+	return options, nil
+}
+
+func (p *ddlParser) commentOn() error {
+	var tableName, columnName string
+	switch {
+	case p.accept("TABLE"):
+		name, ok := p.qualifiedName()
+		if !ok {
+			return nil
+		}
+		tableName = name
+	case p.accept("COLUMN"):
+		parts := []ddlToken{p.next()}
+		for p.accept(".") && !p.done() {
+			parts = append(parts, p.next())
+		}
+		if len(parts) < 2 || (len(parts) > 2 && p.ident(parts[len(parts)-3]) != "public") {
+			return nil
+		}
+		tableName, columnName = p.ident(parts[len(parts)-2]), p.ident(parts[len(parts)-1])
+	default:
+		return nil
+	}
+	if !p.accept("IS") || p.done() {
+		return nil
+	}
+	comment := ""
+	if t := p.next(); t.Kind == ddlString {
+		comment = t.Text
+	}
+
+	table := p.schema.Tables[tableName]
+	switch {
+	case table == nil:
+	case columnName == "":
+		table.Comment = comment
+	case table.Columns[columnName] != nil:
+		table.Columns[columnName].Comment = comment
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package dbdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseDDLKeysMySQLRoutinesByKind(t *testing.T) {
+	schema, err := ParseDDL("CREATE TABLE t (id INT) ENGINE=InnoDB;\n" +
+		"CREATE FUNCTION f(x INT) RETURNS INT DETERMINISTIC RETURN x;\n" +
+		"CREATE PROCEDURE f(IN x INT) BEGIN SELECT x; END;\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, kind := range map[string]string{"function f(int)": RoutineFunction, "procedure f(int)": RoutineProcedure} {
+		if r := schema.Routines[key]; r == nil || r.Kind != kind {
+			t.Errorf("routine %q = %+v, want a %s", key, r, kind)
+		}
+	}
+}
+
+// describeSchema renders the tables of a schema, sorted, as one line each:
+// columns with their types (! for NOT NULL), primary key, foreign keys,
+// unique constraints and comments
+func describeSchema(schema *Schema) string {
+	var lines []string
+	for _, name := range getSortedKeys(schema.Tables) {
+		table := schema.Tables[name]
+		var parts []string
+		for _, colName := range getSortedKeys(table.Columns) {
+			col := table.Columns[colName]
+			part := colName + " " + col.DataType
+			if !col.IsNullable {
+				part += "!"
+			}
+			if col.Comment != "" {
+				part += fmt.Sprintf(" %q", col.Comment)
+			}
+			parts = append(parts, part)
+		}
+		line := name + "(" + strings.Join(parts, ", ") + ")"
+		if table.PrimaryKey != nil {
+			line += fmt.Sprintf(" pk%v", table.PrimaryKey.Columns)
+		}
+		for _, fkName := range getSortedKeys(table.ForeignKeys) {
+			fk := table.ForeignKeys[fkName]
+			line += fmt.Sprintf(" fk %s%v→%s%v", fkName, fk.Columns, fk.RefTable, fk.RefColumns)
+		}
+		for _, uqName := range getSortedKeys(table.UniqueConstraints) {
+			line += fmt.Sprintf(" unique %s%v", uqName, table.UniqueConstraints[uqName].Columns)
+		}
+		if table.Comment != "" {
+			line += fmt.Sprintf(" %q", table.Comment)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestReadSchemaDDL(t *testing.T) {
+	for _, tc := range []struct {
+		name, ddl, want string
+	}{
+		{
+			name: "postgres quoted identifiers",
+			ddl:  `CREATE TABLE public."Order Items" ("Id" integer NOT NULL, "select" text, "say ""hi""" varchar(10));`,
+			want: `Order Items(Id integer!, say "hi" character varying, select text)`,
+		},
+		{
+			name: "postgres multi-statement dump",
+			ddl: `SET statement_timeout = 0;
+SELECT pg_catalog.set_config('search_path', '', false);
+CREATE TABLE public.users (id bigint NOT NULL, email text);
+CREATE TABLE public.orders (id bigint NOT NULL, user_id bigint);
+CREATE TABLE audit.log (id bigint);
+ALTER TABLE ONLY public.users ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
+`,
+			want: "orders(id bigint!, user_id bigint) fk orders_user_id_fkey[user_id]→users[id]\n" +
+				"users(email text, id bigint!) pk[id] unique users_email_key[email]",
+		},
+		{
+			name: "postgres comments",
+			ddl: `-- leading comment; with a semicolon
+CREATE TABLE t (
+    id integer, /* block; comment */
+    note text -- trailing
+);
+COMMENT ON TABLE t IS 'it''s a table';
+COMMENT ON COLUMN t.note IS 'free text';`,
+			want: `t(id integer, note text "free text") "it's a table"`,
+		},
+		{
+			name: "postgres with BOM",
+			ddl:  "\ufeffCREATE TABLE t (id integer PRIMARY KEY);",
+			want: "t(id integer!) pk[id]",
+		},
+		{
+			name: "mysql quoted identifiers",
+			ddl:  "CREATE TABLE `Order Items` (`Id` int NOT NULL, `a``b` varchar(10)) ENGINE=InnoDB;",
+			want: "Order Items(Id int!, a`b varchar(10))",
+		},
+		{
+			name: "mysql dump",
+			ddl: "/*!40101 SET NAMES utf8mb4 */;\n" +
+				"-- Table structure for table `users`\n" +
+				"DROP TABLE IF EXISTS `users`;\n" +
+				"CREATE TABLE `users` (\n" +
+				"  `id` int NOT NULL AUTO_INCREMENT,\n" +
+				"  `email` varchar(255) DEFAULT NULL COMMENT 'login; unique',\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  UNIQUE KEY `email` (`email`)\n" +
+				") ENGINE=InnoDB COMMENT='accounts';\n" +
+				"CREATE TABLE `orders` (`id` int NOT NULL, `user_id` int) ENGINE=InnoDB;\n" +
+				"ALTER TABLE `orders` ADD CONSTRAINT `orders_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`);\n",
+			want: "orders(id int!, user_id int) fk orders_ibfk_1[user_id]→users[id]\n" +
+				`users(email varchar(255) "login; unique", id int!) pk[id] unique email[email] "accounts"`,
+		},
+		{
+			name: "mysql with BOM",
+			ddl:  "\ufeffCREATE TABLE `t` (`id` int PRIMARY KEY) ENGINE=InnoDB;",
+			want: "t(id int!) pk[id]",
+		},
+	} {
+		schema, err := ReadSchema(DriverDDL, strings.NewReader(tc.ddl))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got := describeSchema(schema); got != tc.want {
+			t.Errorf("%s: schema =\n%s\nwant\n%s", tc.name, got, tc.want)
+		}
+	}
+}
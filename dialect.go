@@ -0,0 +1,243 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// DIALECT INTERFACE - Database-specific schema extraction
+// ============================================================================
+
+type Dialect interface {
+	// ExtractSchema reads the schema of db. Tables are extracted with up to
+	// opts.Concurrency queries in flight; cancelling ctx aborts extraction.
+	ExtractSchema(ctx context.Context, db *sql.DB, opts ExtractOptions) (*Schema, error)
+}
+
+// parallelExtractConcurrency is the number of tables extracted at once
+// with --parallel
+const parallelExtractConcurrency = 16
+
+// Object types selectable with ExtractOptions.Objects
+const (
+	ObjectColumns         = "columns"
+	ObjectComments        = "comments"
+	ObjectAccessMethods   = "access_methods"
+	ObjectReplicaIdentity = "replica_identity"
+	ObjectPrimaryKeys     = "primary_keys"
+	ObjectForeignKeys     = "foreign_keys"
+	ObjectUniques         = "unique_constraints"
+	ObjectIndexes         = "indexes"
+	ObjectChecks          = "check_constraints"
+	ObjectTriggers        = "triggers"
+	ObjectTypes           = "types"
+	ObjectSequences       = "sequences"
+	ObjectRoutines        = "routines"
+	ObjectExtensions      = "extensions"
+	ObjectTableOptions    = "table_options"
+	ObjectPartitions      = "partitions"
+	ObjectCollations      = "collations"
+	ObjectRewriteRules    = "rewrite_rules"
+)
+
+// ExtractOptions tunes schema extraction
+type ExtractOptions struct {
+	// Filter, if set, skips tables it ignores before querying their metadata
+	Filter *FilterConfig
+	// Objects limits extraction to these object types; empty extracts all
+	Objects []string
+	// Concurrency is the number of tables extracted at once; 0 or 1 is sequential
+	Concurrency int
+	// IncludeExtensionObjects keeps Postgres tables owned by extensions
+	// (PostGIS, pg_stat_statements, ...), which are skipped by default
+	IncludeExtensionObjects bool
+	// IncludeEvents extracts MySQL scheduled events, which are left out by
+	// default
+	IncludeEvents bool
+	// IncludeForeignTables extracts Postgres foreign tables and foreign
+	// servers, which are left out by default
+	IncludeForeignTables bool
+	// MaxQPS caps the catalog queries per second: the table list, the
+	// per-table queries across all concurrent tables and the schema-level
+	// ones; 0 is unlimited
+	MaxQPS float64
+	// Schemas are the MySQL databases to extract instead of the connection's
+	// current one. With more than one, tables are named schema.table.
+	Schemas []string
+}
+
+func (o ExtractOptions) wants(object string) bool {
+	if len(o.Objects) == 0 {
+		return true
+	}
+	for _, obj := range o.Objects {
+		if obj == object {
+			return true
+		}
+	}
+	return false
+}
+
+// tableFilterSQL returns WHERE conditions restricting column to the exact
+// table names kept by filter, appending their values to args. placeholder
+// renders the n-th (1-based) bind parameter. Patterns can't be translated to
+// SQL reliably and are applied by runExtractionPlan instead.
+func tableFilterSQL(filter *FilterConfig, column string, args []any, placeholder func(n int) string) (string, []any) {
+	if filter == nil {
+		return "", args
+	}
+	var sb strings.Builder
+	inList := func(op string, names []string) {
+		if len(names) == 0 {
+			return
+		}
+		marks := make([]string, len(names))
+		for i, name := range names {
+			args = append(args, name)
+			marks[i] = placeholder(len(args))
+		}
+		fmt.Fprintf(&sb, " AND %s %s (%s)", column, op, strings.Join(marks, ", "))
+	}
+	inList("IN", filter.OnlyTables)
+	inList("NOT IN", filter.IgnoreTables)
+	return sb.String(), args
+}
+
+// extractStep is one per-table metadata extraction phase
+type extractStep struct {
+	object string
+	fn     func(ctx context.Context, tableName string, table *Table) error
+}
+
+// label is the step's object type in human-readable form
+func (s extractStep) label() string {
+	return strings.ReplaceAll(s.object, "_", " ")
+}
+
+// extractionPlanner exposes a dialect's table list and per-table phases so
+// that they can be run concurrently or timed individually
+type extractionPlanner interface {
+	extractionPlan(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]string, []extractStep, error)
+}
+
+// queryLimitKey is the context key of the ticker pacing catalog queries
+type queryLimitKey struct{}
+
+// limitQueries returns a context in which waitQuery allows at most qps
+// catalog queries per second, and a function stopping the limiter. A context
+// that is already limited, or a qps of 0, is returned as is.
+func limitQueries(ctx context.Context, qps float64) (context.Context, func()) {
+	if qps <= 0 || ctx.Value(queryLimitKey{}) != nil {
+		return ctx, func() {}
+	}
+	// Rates above one query per nanosecond would truncate to a zero interval
+	ticker := time.NewTicker(max(time.Duration(float64(time.Second)/qps), time.Nanosecond))
+	return context.WithValue(ctx, queryLimitKey{}, ticker.C), ticker.Stop
+}
+
+// waitQuery blocks until the limiter of ctx, if any, allows another catalog
+// query
+func waitQuery(ctx context.Context) error {
+	limit, _ := ctx.Value(queryLimitKey{}).(<-chan time.Time)
+	if limit == nil {
+		return nil
+	}
+	select {
+	case <-limit:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// paced reports extract, first waiting for the limiter of ctx when true, so
+// that schema-level catalog queries are spaced like the per-table ones. A
+// cancelled wait still reports true; the query then fails with the context's
+// error.
+func paced(ctx context.Context, extract bool) bool {
+	if extract {
+		_ = waitQuery(ctx)
+	}
+	return extract
+}
+
+// runExtractionPlan extracts the selected objects of every table, up to
+// opts.Concurrency tables at a time. The first failure cancels the rest.
+func runExtractionPlan(ctx context.Context, tables []string, steps []extractStep, opts ExtractOptions) (map[string]*Table, []*NotComparable, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var selected []extractStep
+	for _, step := range steps {
+		if opts.wants(step.object) {
+			selected = append(selected, step)
+		}
+	}
+
+	result := make(map[string]*Table, len(tables))
+	var denied []*NotComparable
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(opts.Concurrency, 1))
+
+	// Each step is about one catalog query; the limiter of the extraction,
+	// or one of its own when run on its own, spaces them out
+	ctx, stop := limitQueries(ctx, opts.MaxQPS)
+	defer stop()
+
+	for _, tableName := range tables {
+		if opts.Filter != nil && opts.Filter.ShouldIgnoreTable(tableName) {
+			continue
+		}
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(tName string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			table := newTable(tName)
+			for _, step := range selected {
+				if waitQuery(ctx) != nil {
+					return
+				}
+				if err := step.fn(ctx, tName, table); err != nil {
+					mu.Lock()
+					if isPermissionDenied(err) {
+						// A table the role may not read is reported, not fatal
+						denied = append(denied, &NotComparable{Kind: CategoryTable, Name: tName, Reason: step.label() + ": " + err.Error()})
+					} else if firstErr == nil {
+						firstErr = fmt.Errorf("error extracting %s for %s: %w", step.label(), tName, err)
+						cancel()
+					}
+					mu.Unlock()
+					return
+				}
+			}
+
+			mu.Lock()
+			result[tName] = table
+			mu.Unlock()
+		}(tableName)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(denied, func(i, j int) bool { return denied[i].Name < denied[j].Name })
+	return result, denied, nil
+}
@@ -0,0 +1,534 @@
+package dbdiff
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ============================================================================
+// BIGQUERY DIALECT
+// ============================================================================
+
+// BigQueryDialect extracts BigQuery tables from INFORMATION_SCHEMA: column
+// types, descriptions, primary keys (not enforced by BigQuery), partitioning,
+// clustering and table options. A connection reads one dataset, or every
+// dataset of a region; in the latter case tables are named dataset.table.
+//
+// BigQuery has no database/sql driver in this module's dependencies, so
+// queries go through the REST API by way of bigQueryDriver below.
+type BigQueryDialect struct{}
+
+// BigQuery table option keys; the other TABLE_OPTIONS (e.g.
+// partition_expiration_days, require_partition_filter) keep their names
+const (
+	BigQueryPartitionBy = "partition_by"
+	BigQueryClusterBy   = "cluster_by"
+)
+
+var (
+	bigQueryPartitionPattern = regexp.MustCompile(`(?m)^PARTITION BY (.+)$`)
+	bigQueryClusterPattern   = regexp.MustCompile(`(?m)^CLUSTER BY (.+)$`)
+)
+
+// bigQueryConfig is a parsed connection string:
+// bigquery://project/dataset or bigquery://project?location=US
+type bigQueryConfig struct {
+	Project  string
+	Dataset  string
+	Location string
+}
+
+func parseBigQueryDSN(dsn string) (*bigQueryConfig, error) {
+	rest, ok := strings.CutPrefix(dsn, "bigquery://")
+	if !ok {
+		return nil, fmt.Errorf("bigquery connection %q should look like bigquery://project/dataset", dsn)
+	}
+	rest, query, _ := strings.Cut(rest, "?")
+	cfg := &bigQueryConfig{}
+	cfg.Project, cfg.Dataset, _ = strings.Cut(rest, "/")
+	for _, param := range strings.Split(query, "&") {
+		if v, ok := strings.CutPrefix(param, "location="); ok {
+			cfg.Location = v
+		}
+	}
+	if cfg.Project == "" {
+		return nil, fmt.Errorf("bigquery connection %q names no project", dsn)
+	}
+	if cfg.Dataset == "" && cfg.Location == "" {
+		return nil, fmt.Errorf("bigquery connection %q needs a dataset or a location to read every dataset of", dsn)
+	}
+	return cfg, nil
+}
+
+// infoSchema is the qualifier of the INFORMATION_SCHEMA views read
+func (c *bigQueryConfig) infoSchema() string {
+	if c.Dataset != "" {
+		return fmt.Sprintf("`%s`.`%s`.INFORMATION_SCHEMA", c.Project, c.Dataset)
+	}
+	return fmt.Sprintf("`%s`.`region-%s`.INFORMATION_SCHEMA", c.Project, strings.ToLower(c.Location))
+}
+
+// bigQueryTable is a table by schema (dataset) and name
+type bigQueryTable struct {
+	Dataset string
+	Name    string
+}
+
+func (b *BigQueryDialect) ExtractSchema(ctx context.Context, db *sql.DB, opts ExtractOptions) (*Schema, error) {
+	ctx, stop := limitQueries(ctx, opts.MaxQPS)
+	defer stop()
+	tables, steps, err := b.extractionPlan(ctx, db, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{}
+	if schema.Tables, schema.NotComparable, err = runExtractionPlan(ctx, tables, steps, opts); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (b *BigQueryDialect) extractionPlan(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]string, []extractStep, error) {
+	d, ok := db.Driver().(*bigQueryDriver)
+	if !ok {
+		return nil, nil, fmt.Errorf("the bigquery dialect needs a connection opened with the bigquery driver")
+	}
+	cfg := d.connector.config
+	names, err := b.getTables(ctx, db, cfg, opts.Filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	tables := make([]string, 0, len(names))
+	for name := range names {
+		tables = append(tables, name)
+	}
+	sort.Strings(tables)
+
+	step := func(fn func(ctx context.Context, db *sql.DB, schema string, t bigQueryTable, table *Table) error) func(context.Context, string, *Table) error {
+		return func(ctx context.Context, t string, table *Table) error {
+			return fn(ctx, db, cfg.infoSchema(), names[t], table)
+		}
+	}
+	return tables, []extractStep{
+		{ObjectColumns, step(b.extractColumns)},
+		{ObjectComments, step(b.extractTableComment)},
+		{ObjectPrimaryKeys, step(b.extractPrimaryKey)},
+		{ObjectTableOptions, step(b.extractTableOptions)},
+	}, nil
+}
+
+func (b *BigQueryDialect) getTables(ctx context.Context, db *sql.DB, cfg *bigQueryConfig, filter *FilterConfig) (map[string]bigQueryTable, error) {
+	if err := waitQuery(ctx); err != nil {
+		return nil, err
+	}
+	nameSQL := "table_name"
+	if cfg.Dataset == "" {
+		nameSQL = "CONCAT(table_schema, '.', table_name)"
+	}
+	filterSQL, args := tableFilterSQL(filter, nameSQL, nil, func(int) string { return "?" })
+	query := `
+		SELECT table_schema, table_name
+		FROM ` + cfg.infoSchema() + `.TABLES
+		WHERE table_type = 'BASE TABLE'` + filterSQL
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make(map[string]bigQueryTable)
+	for rows.Next() {
+		var t bigQueryTable
+		if err := rows.Scan(&t.Dataset, &t.Name); err != nil {
+			return nil, err
+		}
+		name := t.Name
+		if cfg.Dataset == "" {
+			name = t.Dataset + "." + t.Name
+		}
+		tables[name] = t
+	}
+	return tables, rows.Err()
+}
+
+func (b *BigQueryDialect) extractColumns(ctx context.Context, db *sql.DB, schema string, t bigQueryTable, table *Table) error {
+	query := `
+		SELECT c.column_name, LOWER(c.data_type), c.is_nullable, c.column_default, p.description
+		FROM ` + schema + `.COLUMNS c
+		LEFT JOIN ` + schema + `.COLUMN_FIELD_PATHS p
+			ON p.table_schema = c.table_schema AND p.table_name = c.table_name
+			AND p.column_name = c.column_name AND p.field_path = c.column_name
+		WHERE c.table_schema = ? AND c.table_name = ?
+		ORDER BY c.ordinal_position
+	`
+	rows, err := db.QueryContext(ctx, query, t.Dataset, t.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, dataType, nullable string
+		var defaultVal, comment sql.NullString
+		if err := rows.Scan(&name, &dataType, &nullable, &defaultVal, &comment); err != nil {
+			return err
+		}
+
+		col := &Column{
+			Name:       name,
+			DataType:   dataType,
+			IsNullable: nullable == "YES",
+			Comment:    comment.String,
+		}
+		// Columns without a default read "NULL"
+		if defaultVal.Valid && defaultVal.String != "NULL" {
+			col.DefaultValue = &defaultVal.String
+		}
+		table.Columns[col.Name] = col
+	}
+	return rows.Err()
+}
+
+func (b *BigQueryDialect) extractTableComment(ctx context.Context, db *sql.DB, schema string, t bigQueryTable, table *Table) error {
+	query := `
+		SELECT option_value
+		FROM ` + schema + `.TABLE_OPTIONS
+		WHERE table_schema = ? AND table_name = ? AND option_name = 'description'
+	`
+	var description string
+	err := db.QueryRowContext(ctx, query, t.Dataset, t.Name).Scan(&description)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Option values are SQL literals: the description is a quoted string
+	if unquoted, err := strconv.Unquote(description); err == nil {
+		description = unquoted
+	}
+	table.Comment = description
+	return nil
+}
+
+func (b *BigQueryDialect) extractPrimaryKey(ctx context.Context, db *sql.DB, schema string, t bigQueryTable, table *Table) error {
+	query := `
+		SELECT k.constraint_name, k.column_name
+		FROM ` + schema + `.TABLE_CONSTRAINTS tc
+		JOIN ` + schema + `.KEY_COLUMN_USAGE k
+			ON k.constraint_schema = tc.constraint_schema AND k.constraint_name = tc.constraint_name
+		WHERE tc.table_schema = ? AND tc.table_name = ? AND tc.constraint_type = 'PRIMARY KEY'
+		ORDER BY k.ordinal_position
+	`
+	rows, err := db.QueryContext(ctx, query, t.Dataset, t.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, column string
+		if err := rows.Scan(&name, &column); err != nil {
+			return err
+		}
+		if table.PrimaryKey == nil {
+			table.PrimaryKey = &PrimaryKey{Name: name}
+		}
+		table.PrimaryKey.Columns = append(table.PrimaryKey.Columns, column)
+	}
+	return rows.Err()
+}
+
+// extractTableOptions reads the partitioning and clustering clauses of the
+// table's DDL and its TABLE_OPTIONS other than the description
+func (b *BigQueryDialect) extractTableOptions(ctx context.Context, db *sql.DB, schema string, t bigQueryTable, table *Table) error {
+	var ddl string
+	err := db.QueryRowContext(ctx, `SELECT ddl FROM `+schema+`.TABLES WHERE table_schema = ? AND table_name = ?`, t.Dataset, t.Name).Scan(&ddl)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Unpartitioned and unclustered tables say so, since options missing on
+	// one side are not compared
+	options := map[string]string{BigQueryPartitionBy: "none", BigQueryClusterBy: "none"}
+	if m := bigQueryPartitionPattern.FindStringSubmatch(ddl); m != nil {
+		options[BigQueryPartitionBy] = strings.TrimSpace(m[1])
+	}
+	if m := bigQueryClusterPattern.FindStringSubmatch(ddl); m != nil {
+		options[BigQueryClusterBy] = strings.TrimSpace(m[1])
+	}
+
+	query := `
+		SELECT option_name, option_value
+		FROM ` + schema + `.TABLE_OPTIONS
+		WHERE table_schema = ? AND table_name = ? AND option_name != 'description'
+	`
+	rows, err := db.QueryContext(ctx, query, t.Dataset, t.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		options[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	table.Options = options
+	return nil
+}
+
+// bigQueryConnector is a minimal database/sql connector running queries
+// through the BigQuery REST API (jobs.query). It supports what the dialect
+// needs: read-only queries with positional string, integer and boolean
+// parameters. Requests are authorized with GOOGLE_OAUTH_ACCESS_TOKEN, or else
+// the token of `gcloud auth print-access-token`, fetched once.
+type bigQueryConnector struct {
+	config *bigQueryConfig
+	once   sync.Once
+	token  string
+	err    error
+}
+
+// bigQueryDriver exposes the connector, and so the connection settings, to
+// the dialect through db.Driver()
+type bigQueryDriver struct {
+	connector *bigQueryConnector
+}
+
+const bigQueryAPI = "https://bigquery.googleapis.com/bigquery/v2"
+
+// openBigQuery opens a BigQuery connection string as a *sql.DB
+func openBigQuery(dsn string) (*sql.DB, error) {
+	cfg, err := parseBigQueryDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&bigQueryConnector{config: cfg}), nil
+}
+
+func (c *bigQueryConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.once.Do(func() {
+		if c.token = os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); c.token != "" {
+			return
+		}
+		out, err := exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output()
+		if err != nil {
+			c.err = fmt.Errorf("bigquery: set GOOGLE_OAUTH_ACCESS_TOKEN or log in with gcloud: %w", err)
+			return
+		}
+		c.token = strings.TrimSpace(string(out))
+	})
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &bigQueryConn{config: c.config, token: c.token, client: &http.Client{Timeout: discoveryTimeout}}, nil
+}
+
+func (c *bigQueryConnector) Driver() driver.Driver {
+	return &bigQueryDriver{connector: c}
+}
+
+func (d *bigQueryDriver) Open(dsn string) (driver.Conn, error) {
+	cfg, err := parseBigQueryDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return (&bigQueryConnector{config: cfg}).Connect(context.Background())
+}
+
+type bigQueryConn struct {
+	config *bigQueryConfig
+	token  string
+	client *http.Client
+}
+
+func (c *bigQueryConn) Prepare(query string) (driver.Stmt, error) {
+	return &bigQueryStmt{conn: c, query: query}, nil
+}
+
+func (c *bigQueryConn) Close() error { return nil }
+
+func (c *bigQueryConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("bigquery: transactions are not supported")
+}
+
+// bigQueryResponse is the part of a jobs.query or jobs.getQueryResults
+// response that the driver reads
+type bigQueryResponse struct {
+	JobComplete  bool `json:"jobComplete"`
+	JobReference struct {
+		JobID    string `json:"jobId"`
+		Location string `json:"location"`
+	} `json:"jobReference"`
+	Schema struct {
+		Fields []struct {
+			Name string `json:"name"`
+		} `json:"fields"`
+	} `json:"schema"`
+	Rows []struct {
+		F []struct {
+			V any `json:"v"`
+		} `json:"f"`
+	} `json:"rows"`
+	PageToken string `json:"pageToken"`
+	Error     *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *bigQueryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	params := make([]map[string]any, len(args))
+	for i, arg := range args {
+		typ := "STRING"
+		switch arg.Value.(type) {
+		case int64:
+			typ = "INT64"
+		case bool:
+			typ = "BOOL"
+		}
+		params[i] = map[string]any{
+			"parameterType":  map[string]string{"type": typ},
+			"parameterValue": map[string]string{"value": fmt.Sprint(arg.Value)},
+		}
+	}
+	body := map[string]any{
+		"query":           query,
+		"useLegacySql":    false,
+		"parameterMode":   "POSITIONAL",
+		"queryParameters": params,
+	}
+	if c.config.Location != "" {
+		body["location"] = c.config.Location
+	}
+	resp, err := c.call(ctx, http.MethodPost, fmt.Sprintf("%s/projects/%s/queries", bigQueryAPI, url.PathEscape(c.config.Project)), body)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &bigQueryRows{}
+	for _, f := range resp.Schema.Fields {
+		rows.columns = append(rows.columns, f.Name)
+	}
+	// Poll until the job completes, then page through the results
+	for {
+		for _, r := range resp.Rows {
+			values := make([]driver.Value, len(r.F))
+			for i, f := range r.F {
+				if v, ok := f.V.(string); ok {
+					values[i] = v
+				}
+			}
+			rows.values = append(rows.values, values)
+		}
+		if resp.JobComplete && resp.PageToken == "" {
+			break
+		}
+		query := url.Values{"location": {resp.JobReference.Location}}
+		if resp.PageToken != "" {
+			query.Set("pageToken", resp.PageToken)
+		}
+		endpoint := fmt.Sprintf("%s/projects/%s/queries/%s?%s",
+			bigQueryAPI, url.PathEscape(c.config.Project), url.PathEscape(resp.JobReference.JobID), query.Encode())
+		if resp, err = c.call(ctx, http.MethodGet, endpoint, nil); err != nil {
+			return nil, err
+		}
+		if rows.columns == nil {
+			for _, f := range resp.Schema.Fields {
+				rows.columns = append(rows.columns, f.Name)
+			}
+		}
+	}
+	return rows, nil
+}
+
+func (c *bigQueryConn) call(ctx context.Context, method, endpoint string, body any) (*bigQueryResponse, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	httpResp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	var resp bigQueryResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("bigquery: decoding response (HTTP %d): %w", httpResp.StatusCode, err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("bigquery: %s", resp.Error.Message)
+	}
+	return &resp, nil
+}
+
+type bigQueryStmt struct {
+	conn  *bigQueryConn
+	query string
+}
+
+func (s *bigQueryStmt) Close() error  { return nil }
+func (s *bigQueryStmt) NumInput() int { return -1 }
+
+func (s *bigQueryStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("bigquery: only queries are supported")
+}
+
+func (s *bigQueryStmt) Query(args []driver.Value) (driver.Rows, error) {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return s.conn.QueryContext(context.Background(), s.query, named)
+}
+
+// bigQueryRows holds a fully read result; values are strings or nil, which
+// database/sql converts on Scan
+type bigQueryRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *bigQueryRows) Columns() []string { return r.columns }
+func (r *bigQueryRows) Close() error      { return nil }
+
+func (r *bigQueryRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
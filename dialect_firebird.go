@@ -0,0 +1,390 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	_ "github.com/nakagami/firebirdsql"
+)
+
+// ============================================================================
+// FIREBIRD DIALECT
+// ============================================================================
+
+// FirebirdDialect extracts Firebird schemas (2.5 or later) from the RDB$
+// system tables. Like Oracle, Firebird stores unquoted identifiers in
+// uppercase, padded to the length of the name column; names are trimmed and
+// folded the same way.
+type FirebirdDialect struct{}
+
+// firebirdName trims and folds a Firebird identifier
+func firebirdName(name string) string {
+	return oracleName(strings.TrimSpace(name))
+}
+
+// firebirdTrimKeyword strips the leading keyword Firebird keeps in the
+// source of defaults and checks
+func firebirdTrimKeyword(source, keyword string) string {
+	source = strings.TrimSpace(source)
+	if len(source) >= len(keyword) && strings.EqualFold(source[:len(keyword)], keyword) {
+		source = strings.TrimSpace(source[len(keyword):])
+	}
+	return source
+}
+
+func firebirdPlaceholder(int) string {
+	return "?"
+}
+
+// Firebird RDB$FIELDS.RDB$FIELD_TYPE codes
+var firebirdTypes = map[int]string{
+	7:   "smallint",
+	8:   "integer",
+	10:  "float",
+	12:  "date",
+	13:  "time",
+	14:  "char",
+	16:  "bigint",
+	23:  "boolean",
+	27:  "double precision",
+	35:  "timestamp",
+	37:  "varchar",
+	261: "blob",
+}
+
+// firebirdType renders a column type from its RDB$FIELDS entry. Exact
+// numerics are stored as integers with a negative scale and a sub type of 1
+// (NUMERIC) or 2 (DECIMAL); text blobs have sub type 1.
+func firebirdType(fieldType, subType, precision, scale, length int) string {
+	name, ok := firebirdTypes[fieldType]
+	if !ok {
+		return fmt.Sprintf("unknown(%d)", fieldType)
+	}
+	switch {
+	case (fieldType == 7 || fieldType == 8 || fieldType == 16) && (subType == 1 || subType == 2):
+		if subType == 2 {
+			return fmt.Sprintf("decimal(%d,%d)", precision, -scale)
+		}
+		return fmt.Sprintf("numeric(%d,%d)", precision, -scale)
+	case fieldType == 14 || fieldType == 37:
+		return fmt.Sprintf("%s(%d)", name, length)
+	case fieldType == 261 && subType == 1:
+		return "blob sub_type text"
+	}
+	return name
+}
+
+// firebirdRule maps a referential action; Firebird stores NO ACTION, its
+// default, as RESTRICT
+func firebirdRule(rule string) string {
+	rule = strings.TrimSpace(rule)
+	if rule == "RESTRICT" {
+		return "NO ACTION"
+	}
+	return rule
+}
+
+func (f *FirebirdDialect) ExtractSchema(ctx context.Context, db *sql.DB, opts ExtractOptions) (*Schema, error) {
+	ctx, stop := limitQueries(ctx, opts.MaxQPS)
+	defer stop()
+	tables, steps, err := f.extractionPlan(ctx, db, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{}
+	if schema.Tables, schema.NotComparable, err = runExtractionPlan(ctx, tables, steps, opts); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (f *FirebirdDialect) extractionPlan(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]string, []extractStep, error) {
+	// Catalog names of the tables, by dbdiff (folded) name
+	names, err := f.getTables(ctx, db, opts.Filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	tables := make([]string, 0, len(names))
+	for name := range names {
+		tables = append(tables, name)
+	}
+	sort.Strings(tables)
+
+	step := func(fn func(ctx context.Context, db *sql.DB, tableName string, table *Table) error) func(context.Context, string, *Table) error {
+		return func(ctx context.Context, t string, table *Table) error { return fn(ctx, db, names[t], table) }
+	}
+	return tables, []extractStep{
+		{ObjectColumns, step(f.extractColumns)},
+		{ObjectComments, step(f.extractTableComment)},
+		{ObjectPrimaryKeys, step(f.extractPrimaryKey)},
+		{ObjectForeignKeys, step(f.extractForeignKeys)},
+		{ObjectUniques, step(f.extractUniqueConstraints)},
+		{ObjectIndexes, step(f.extractIndexes)},
+		{ObjectChecks, step(f.extractCheckConstraints)},
+	}, nil
+}
+
+func (f *FirebirdDialect) getTables(ctx context.Context, db *sql.DB, filter *FilterConfig) (map[string]string, error) {
+	if err := waitQuery(ctx); err != nil {
+		return nil, err
+	}
+	filterSQL, args := tableFilterSQL(filter, oracleFoldSQL("TRIM(RDB$RELATION_NAME)"), nil, firebirdPlaceholder)
+	query := `
+		SELECT TRIM(RDB$RELATION_NAME)
+		FROM RDB$RELATIONS
+		WHERE RDB$SYSTEM_FLAG = 0
+		  AND RDB$VIEW_BLR IS NULL
+		  AND COALESCE(RDB$RELATION_TYPE, 0) = 0` + filterSQL
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make(map[string]string)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables[firebirdName(name)] = name
+	}
+	return tables, rows.Err()
+}
+
+func (f *FirebirdDialect) extractColumns(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT
+			TRIM(rf.RDB$FIELD_NAME),
+			TRIM(rf.RDB$FIELD_SOURCE),
+			fd.RDB$FIELD_TYPE,
+			COALESCE(fd.RDB$FIELD_SUB_TYPE, 0),
+			COALESCE(fd.RDB$FIELD_PRECISION, 0),
+			COALESCE(fd.RDB$FIELD_SCALE, 0),
+			COALESCE(fd.RDB$CHARACTER_LENGTH, fd.RDB$FIELD_LENGTH, 0),
+			COALESCE(rf.RDB$NULL_FLAG, fd.RDB$NULL_FLAG, 0),
+			CAST(COALESCE(rf.RDB$DEFAULT_SOURCE, fd.RDB$DEFAULT_SOURCE) AS VARCHAR(8191)),
+			CAST(rf.RDB$DESCRIPTION AS VARCHAR(8191))
+		FROM RDB$RELATION_FIELDS rf
+		JOIN RDB$FIELDS fd ON fd.RDB$FIELD_NAME = rf.RDB$FIELD_SOURCE
+		WHERE rf.RDB$RELATION_NAME = ?
+		  AND fd.RDB$COMPUTED_BLR IS NULL
+		ORDER BY rf.RDB$FIELD_POSITION
+	`
+	rows, err := db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, source string
+		var fieldType, subType, precision, scale, length, notNull int
+		var defaultVal, comment sql.NullString
+		if err := rows.Scan(&name, &source, &fieldType, &subType, &precision, &scale, &length, &notNull, &defaultVal, &comment); err != nil {
+			return err
+		}
+
+		col := &Column{
+			Name:       firebirdName(name),
+			DataType:   firebirdType(fieldType, subType, precision, scale, length),
+			IsNullable: notNull == 0,
+			Comment:    comment.String,
+		}
+		// Columns declared with a domain have a user-named field source;
+		// the others get a generated RDB$nnn one
+		if !strings.HasPrefix(source, "RDB$") {
+			col.UserType = firebirdName(source)
+		}
+		// The default is kept as typed, including the DEFAULT keyword
+		if d := firebirdTrimKeyword(defaultVal.String, "DEFAULT"); defaultVal.Valid && d != "" && !strings.EqualFold(d, "NULL") {
+			col.DefaultValue = &d
+		}
+		table.Columns[col.Name] = col
+	}
+	return rows.Err()
+}
+
+func (f *FirebirdDialect) extractTableComment(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT CAST(RDB$DESCRIPTION AS VARCHAR(8191))
+		FROM RDB$RELATIONS
+		WHERE RDB$RELATION_NAME = ?
+	`
+	var comment sql.NullString
+	err := db.QueryRowContext(ctx, query, tableName).Scan(&comment)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	table.Comment = comment.String
+	return nil
+}
+
+// firebirdConstraints returns the name and columns of the constraints of one
+// type (PRIMARY KEY or UNIQUE) of a table. Firebird's LIST() does not
+// guarantee an order, so the columns are collected here.
+func (f *FirebirdDialect) firebirdConstraints(ctx context.Context, db *sql.DB, tableName, constraintType string) (map[string][]string, error) {
+	query := `
+		SELECT TRIM(rc.RDB$CONSTRAINT_NAME), TRIM(s.RDB$FIELD_NAME)
+		FROM RDB$RELATION_CONSTRAINTS rc
+		JOIN RDB$INDEX_SEGMENTS s ON s.RDB$INDEX_NAME = rc.RDB$INDEX_NAME
+		WHERE rc.RDB$RELATION_NAME = ? AND rc.RDB$CONSTRAINT_TYPE = ?
+		ORDER BY rc.RDB$CONSTRAINT_NAME, s.RDB$FIELD_POSITION
+	`
+	rows, err := db.QueryContext(ctx, query, tableName, constraintType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	constraints := make(map[string][]string)
+	for rows.Next() {
+		var name, column string
+		if err := rows.Scan(&name, &column); err != nil {
+			return nil, err
+		}
+		name = firebirdName(name)
+		constraints[name] = append(constraints[name], firebirdName(column))
+	}
+	return constraints, rows.Err()
+}
+
+func (f *FirebirdDialect) extractPrimaryKey(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	constraints, err := f.firebirdConstraints(ctx, db, tableName, "PRIMARY KEY")
+	if err != nil {
+		return err
+	}
+	for name, columns := range constraints {
+		table.PrimaryKey = &PrimaryKey{Name: name, Columns: columns}
+	}
+	return nil
+}
+
+func (f *FirebirdDialect) extractUniqueConstraints(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	constraints, err := f.firebirdConstraints(ctx, db, tableName, "UNIQUE")
+	if err != nil {
+		return err
+	}
+	for name, columns := range constraints {
+		table.UniqueConstraints[name] = &Unique{Name: name, Columns: columns}
+	}
+	return nil
+}
+
+func (f *FirebirdDialect) extractForeignKeys(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	// The referenced columns are the segments of the referenced key's index
+	// at the same positions
+	query := `
+		SELECT
+			TRIM(rc.RDB$CONSTRAINT_NAME),
+			TRIM(s.RDB$FIELD_NAME),
+			TRIM(pk.RDB$RELATION_NAME),
+			TRIM(ps.RDB$FIELD_NAME),
+			ref.RDB$DELETE_RULE,
+			ref.RDB$UPDATE_RULE
+		FROM RDB$RELATION_CONSTRAINTS rc
+		JOIN RDB$REF_CONSTRAINTS ref ON ref.RDB$CONSTRAINT_NAME = rc.RDB$CONSTRAINT_NAME
+		JOIN RDB$RELATION_CONSTRAINTS pk ON pk.RDB$CONSTRAINT_NAME = ref.RDB$CONST_NAME_UQ
+		JOIN RDB$INDEX_SEGMENTS s ON s.RDB$INDEX_NAME = rc.RDB$INDEX_NAME
+		JOIN RDB$INDEX_SEGMENTS ps ON ps.RDB$INDEX_NAME = pk.RDB$INDEX_NAME AND ps.RDB$FIELD_POSITION = s.RDB$FIELD_POSITION
+		WHERE rc.RDB$RELATION_NAME = ? AND rc.RDB$CONSTRAINT_TYPE = 'FOREIGN KEY'
+		ORDER BY rc.RDB$CONSTRAINT_NAME, s.RDB$FIELD_POSITION
+	`
+	rows, err := db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, column, refTable, refColumn, deleteRule, updateRule string
+		if err := rows.Scan(&name, &column, &refTable, &refColumn, &deleteRule, &updateRule); err != nil {
+			return err
+		}
+
+		name = firebirdName(name)
+		fk, ok := table.ForeignKeys[name]
+		if !ok {
+			fk = &ForeignKey{
+				Name:     name,
+				RefTable: firebirdName(refTable),
+				OnDelete: firebirdRule(deleteRule),
+				OnUpdate: firebirdRule(updateRule),
+			}
+			table.ForeignKeys[name] = fk
+		}
+		fk.Columns = append(fk.Columns, firebirdName(column))
+		fk.RefColumns = append(fk.RefColumns, firebirdName(refColumn))
+	}
+	return rows.Err()
+}
+
+func (f *FirebirdDialect) extractIndexes(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	// Expression indexes have no segments and are left out
+	query := `
+		SELECT TRIM(i.RDB$INDEX_NAME), TRIM(s.RDB$FIELD_NAME), COALESCE(i.RDB$UNIQUE_FLAG, 0)
+		FROM RDB$INDICES i
+		JOIN RDB$INDEX_SEGMENTS s ON s.RDB$INDEX_NAME = i.RDB$INDEX_NAME
+		WHERE i.RDB$RELATION_NAME = ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM RDB$RELATION_CONSTRAINTS rc
+			WHERE rc.RDB$INDEX_NAME = i.RDB$INDEX_NAME
+		  )
+		ORDER BY i.RDB$INDEX_NAME, s.RDB$FIELD_POSITION
+	`
+	rows, err := db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, column string
+		var unique int
+		if err := rows.Scan(&name, &column, &unique); err != nil {
+			return err
+		}
+
+		name = firebirdName(name)
+		idx, ok := table.Indexes[name]
+		if !ok {
+			idx = &Index{Name: name, IsUnique: unique == 1}
+			table.Indexes[name] = idx
+		}
+		idx.Columns = append(idx.Columns, firebirdName(column))
+	}
+	return rows.Err()
+}
+
+func (f *FirebirdDialect) extractCheckConstraints(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	// A check constraint is enforced by a pair of insert and update triggers
+	// that both carry its source
+	query := `
+		SELECT DISTINCT TRIM(rc.RDB$CONSTRAINT_NAME), CAST(t.RDB$TRIGGER_SOURCE AS VARCHAR(8191))
+		FROM RDB$RELATION_CONSTRAINTS rc
+		JOIN RDB$CHECK_CONSTRAINTS cc ON cc.RDB$CONSTRAINT_NAME = rc.RDB$CONSTRAINT_NAME
+		JOIN RDB$TRIGGERS t ON t.RDB$TRIGGER_NAME = cc.RDB$TRIGGER_NAME
+		WHERE rc.RDB$RELATION_NAME = ? AND rc.RDB$CONSTRAINT_TYPE = 'CHECK'
+	`
+	rows, err := db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, source string
+		if err := rows.Scan(&name, &source); err != nil {
+			return err
+		}
+
+		// The source reads "CHECK (price > 0)"
+		check := &CheckConstr{Name: firebirdName(name), Expression: firebirdTrimKeyword(source, "CHECK")}
+		table.CheckConstraints[check.Name] = check
+	}
+	return rows.Err()
+}
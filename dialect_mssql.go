@@ -0,0 +1,363 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/microsoft/go-mssqldb"
+)
+
+// ============================================================================
+// SQL SERVER DIALECT
+// ============================================================================
+
+// MSSQLDialect extracts SQL Server schemas (2017 or later). Tables of every
+// schema are extracted; those outside dbo are named "schema.table".
+type MSSQLDialect struct{}
+
+// mssqlTableName is the dbdiff name of a table, as SQL
+const mssqlTableName = `CASE WHEN s.name = 'dbo' THEN t.name ELSE s.name + '.' + t.name END`
+
+func mssqlPlaceholder(n int) string {
+	return fmt.Sprintf("@p%d", n)
+}
+
+// mssqlObjectName returns the quoted two-part name of a table for OBJECT_ID
+func mssqlObjectName(tableName string) string {
+	schema, table := "dbo", tableName
+	if i := strings.Index(tableName, "."); i >= 0 {
+		schema, table = tableName[:i], tableName[i+1:]
+	}
+	quote := func(name string) string { return "[" + strings.ReplaceAll(name, "]", "]]") + "]" }
+	return quote(schema) + "." + quote(table)
+}
+
+// mssqlAction turns NO_ACTION and friends into the spelling used by the
+// other dialects
+func mssqlAction(desc string) string {
+	return strings.ReplaceAll(desc, "_", " ")
+}
+
+func (d *MSSQLDialect) ExtractSchema(ctx context.Context, db *sql.DB, opts ExtractOptions) (*Schema, error) {
+	ctx, stop := limitQueries(ctx, opts.MaxQPS)
+	defer stop()
+	tables, steps, err := d.extractionPlan(ctx, db, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{}
+	if schema.Tables, schema.NotComparable, err = runExtractionPlan(ctx, tables, steps, opts); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (d *MSSQLDialect) extractionPlan(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]string, []extractStep, error) {
+	tables, err := d.getTables(ctx, db, opts.Filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tables, []extractStep{
+		{ObjectColumns, func(ctx context.Context, t string, table *Table) error { return d.extractColumns(ctx, db, t, table) }},
+		{ObjectComments, func(ctx context.Context, t string, table *Table) error {
+			return d.extractTableComment(ctx, db, t, table)
+		}},
+		{ObjectPrimaryKeys, func(ctx context.Context, t string, table *Table) error { return d.extractPrimaryKey(ctx, db, t, table) }},
+		{ObjectForeignKeys, func(ctx context.Context, t string, table *Table) error {
+			return d.extractForeignKeys(ctx, db, t, table)
+		}},
+		{ObjectUniques, func(ctx context.Context, t string, table *Table) error {
+			return d.extractUniqueConstraints(ctx, db, t, table)
+		}},
+		{ObjectIndexes, func(ctx context.Context, t string, table *Table) error { return d.extractIndexes(ctx, db, t, table) }},
+		{ObjectChecks, func(ctx context.Context, t string, table *Table) error {
+			return d.extractCheckConstraints(ctx, db, t, table)
+		}},
+	}, nil
+}
+
+func (d *MSSQLDialect) getTables(ctx context.Context, db *sql.DB, filter *FilterConfig) ([]string, error) {
+	if err := waitQuery(ctx); err != nil {
+		return nil, err
+	}
+	filterSQL, args := tableFilterSQL(filter, mssqlTableName, nil, mssqlPlaceholder)
+	query := `
+		SELECT ` + mssqlTableName + `
+		FROM sys.tables t
+		JOIN sys.schemas s ON s.schema_id = t.schema_id
+		WHERE t.is_ms_shipped = 0` + filterSQL + `
+		ORDER BY 1
+	`
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+func (d *MSSQLDialect) extractColumns(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT
+			c.name,
+			CASE
+				WHEN bt.name IN ('varchar', 'char', 'varbinary', 'binary')
+					THEN bt.name + '(' + CASE WHEN c.max_length = -1 THEN 'max' ELSE CAST(c.max_length AS varchar(10)) END + ')'
+				WHEN bt.name IN ('nvarchar', 'nchar')
+					THEN bt.name + '(' + CASE WHEN c.max_length = -1 THEN 'max' ELSE CAST(c.max_length / 2 AS varchar(10)) END + ')'
+				WHEN bt.name IN ('decimal', 'numeric')
+					THEN bt.name + '(' + CAST(c.precision AS varchar(10)) + ',' + CAST(c.scale AS varchar(10)) + ')'
+				WHEN bt.name IN ('datetime2', 'datetimeoffset', 'time')
+					THEN bt.name + '(' + CAST(c.scale AS varchar(10)) + ')'
+				ELSE bt.name
+			END,
+			c.is_nullable,
+			dc.definition,
+			c.is_hidden,
+			CAST(ep.value AS nvarchar(max)),
+			CASE WHEN ty.is_user_defined = 1 THEN ty.name END
+		FROM sys.columns c
+		JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+		JOIN sys.types bt ON bt.user_type_id = c.system_type_id
+		LEFT JOIN sys.default_constraints dc ON dc.object_id = c.default_object_id
+		LEFT JOIN sys.extended_properties ep
+			ON ep.class = 1 AND ep.major_id = c.object_id AND ep.minor_id = c.column_id AND ep.name = 'MS_Description'
+		WHERE c.object_id = OBJECT_ID(@p1)
+		ORDER BY c.column_id
+	`
+	rows, err := db.QueryContext(ctx, query, mssqlObjectName(tableName))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, dataType string
+		var isNullable, isHidden bool
+		var defaultVal, comment, userType sql.NullString
+		if err := rows.Scan(&name, &dataType, &isNullable, &defaultVal, &isHidden, &comment, &userType); err != nil {
+			return err
+		}
+
+		col := &Column{
+			Name:        name,
+			DataType:    dataType,
+			IsNullable:  isNullable,
+			IsInvisible: isHidden,
+			Comment:     comment.String,
+			UserType:    userType.String,
+		}
+		if defaultVal.Valid {
+			col.DefaultValue = &defaultVal.String
+		}
+		table.Columns[name] = col
+	}
+	return rows.Err()
+}
+
+func (d *MSSQLDialect) extractTableComment(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT CAST(value AS nvarchar(max))
+		FROM sys.extended_properties
+		WHERE class = 1 AND major_id = OBJECT_ID(@p1) AND minor_id = 0 AND name = 'MS_Description'
+	`
+	var comment sql.NullString
+	err := db.QueryRowContext(ctx, query, mssqlObjectName(tableName)).Scan(&comment)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	table.Comment = comment.String
+	return nil
+}
+
+// mssqlKeyConstraints returns the name and columns of the primary key ("PK")
+// or unique ("UQ") constraints of a table
+func (d *MSSQLDialect) mssqlKeyConstraints(ctx context.Context, db *sql.DB, tableName, kind string) (map[string][]string, error) {
+	query := `
+		SELECT
+			kc.name,
+			STRING_AGG(c.name, ',') WITHIN GROUP (ORDER BY ic.key_ordinal)
+		FROM sys.key_constraints kc
+		JOIN sys.index_columns ic ON ic.object_id = kc.parent_object_id AND ic.index_id = kc.unique_index_id
+		JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE kc.parent_object_id = OBJECT_ID(@p1)
+		  AND kc.type = @p2
+		GROUP BY kc.name
+	`
+	rows, err := db.QueryContext(ctx, query, mssqlObjectName(tableName), kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string][]string)
+	for rows.Next() {
+		var name, columns string
+		if err := rows.Scan(&name, &columns); err != nil {
+			return nil, err
+		}
+		keys[name] = strings.Split(columns, ",")
+	}
+	return keys, rows.Err()
+}
+
+func (d *MSSQLDialect) extractPrimaryKey(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	keys, err := d.mssqlKeyConstraints(ctx, db, tableName, "PK")
+	if err != nil {
+		return err
+	}
+	for name, columns := range keys {
+		table.PrimaryKey = &PrimaryKey{Name: name, Columns: columns}
+	}
+	return nil
+}
+
+func (d *MSSQLDialect) extractUniqueConstraints(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	keys, err := d.mssqlKeyConstraints(ctx, db, tableName, "UQ")
+	if err != nil {
+		return err
+	}
+	for name, columns := range keys {
+		table.UniqueConstraints[name] = &Unique{Name: name, Columns: columns}
+	}
+	return nil
+}
+
+func (d *MSSQLDialect) extractForeignKeys(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT
+			fk.name,
+			STRING_AGG(pc.name, ',') WITHIN GROUP (ORDER BY fkc.constraint_column_id),
+			CASE WHEN rs.name = 'dbo' THEN rt.name ELSE rs.name + '.' + rt.name END,
+			STRING_AGG(rc.name, ',') WITHIN GROUP (ORDER BY fkc.constraint_column_id),
+			fk.update_referential_action_desc,
+			fk.delete_referential_action_desc,
+			fk.is_disabled,
+			fk.is_not_trusted
+		FROM sys.foreign_keys fk
+		JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+		JOIN sys.columns pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+		JOIN sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+		JOIN sys.tables rt ON rt.object_id = fk.referenced_object_id
+		JOIN sys.schemas rs ON rs.schema_id = rt.schema_id
+		WHERE fk.parent_object_id = OBJECT_ID(@p1)
+		GROUP BY fk.name, rs.name, rt.name, fk.update_referential_action_desc, fk.delete_referential_action_desc, fk.is_disabled, fk.is_not_trusted
+	`
+	rows, err := db.QueryContext(ctx, query, mssqlObjectName(tableName))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, columns, refTable, refColumns, updateRule, deleteRule string
+		var disabled, notTrusted bool
+		if err := rows.Scan(&name, &columns, &refTable, &refColumns, &updateRule, &deleteRule, &disabled, &notTrusted); err != nil {
+			return err
+		}
+
+		fk := &ForeignKey{
+			Name:       name,
+			Columns:    strings.Split(columns, ","),
+			RefTable:   refTable,
+			RefColumns: strings.Split(refColumns, ","),
+			OnUpdate:   mssqlAction(updateRule),
+			OnDelete:   mssqlAction(deleteRule),
+			Status:     mssqlConstraintStatus(disabled, notTrusted),
+		}
+		table.ForeignKeys[name] = fk
+	}
+	return rows.Err()
+}
+
+func (d *MSSQLDialect) extractIndexes(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT
+			i.name,
+			STRING_AGG(c.name, ',') WITHIN GROUP (ORDER BY ic.key_ordinal, ic.index_column_id),
+			i.is_unique
+		FROM sys.indexes i
+		JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id AND ic.is_included_column = 0
+		JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE i.object_id = OBJECT_ID(@p1)
+		  AND i.type > 0  -- Skip the heap pseudo-index
+		  AND i.is_primary_key = 0
+		  AND i.is_unique_constraint = 0
+		  AND i.is_hypothetical = 0
+		GROUP BY i.name, i.is_unique
+	`
+	rows, err := db.QueryContext(ctx, query, mssqlObjectName(tableName))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, columns string
+		var isUnique bool
+		if err := rows.Scan(&name, &columns, &isUnique); err != nil {
+			return err
+		}
+
+		idx := &Index{
+			Name:     name,
+			Columns:  strings.Split(columns, ","),
+			IsUnique: isUnique,
+		}
+		table.Indexes[name] = idx
+	}
+	return rows.Err()
+}
+
+func (d *MSSQLDialect) extractCheckConstraints(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT name, definition, is_disabled, is_not_trusted
+		FROM sys.check_constraints
+		WHERE parent_object_id = OBJECT_ID(@p1)
+	`
+	rows, err := db.QueryContext(ctx, query, mssqlObjectName(tableName))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, expr string
+		var disabled, notTrusted bool
+		if err := rows.Scan(&name, &expr, &disabled, &notTrusted); err != nil {
+			return err
+		}
+
+		check := &CheckConstr{
+			Name:       name,
+			Expression: expr,
+			Status:     mssqlConstraintStatus(disabled, notTrusted),
+		}
+		table.CheckConstraints[name] = check
+	}
+	return rows.Err()
+}
+
+// mssqlConstraintStatus maps is_disabled and is_not_trusted (created or
+// re-enabled WITH NOCHECK) to a constraint status
+func mssqlConstraintStatus(disabled, notTrusted bool) string {
+	switch {
+	case disabled:
+		return ConstraintNotEnforced
+	case notTrusted:
+		return ConstraintNotValid
+	}
+	return ""
+}
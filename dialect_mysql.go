@@ -0,0 +1,813 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ============================================================================
+// MYSQL DIALECT
+// ============================================================================
+
+type MySQLDialect struct{}
+
+func (m *MySQLDialect) ExtractSchema(ctx context.Context, db *sql.DB, opts ExtractOptions) (*Schema, error) {
+	ctx, stop := limitQueries(ctx, opts.MaxQPS)
+	defer stop()
+	dbs, err := m.databases(ctx, db, opts)
+	if err != nil {
+		return nil, err
+	}
+	tables, steps, err := m.extractionPlan(ctx, db, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{}
+	if schema.Tables, schema.NotComparable, err = runExtractionPlan(ctx, tables, steps, opts); err != nil {
+		return nil, err
+	}
+	if err := m.extractSchemaObjects(ctx, db, dbs, schema, opts); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// extractSchemaObjects reads the objects extracted after the tables:
+// AUTO_INCREMENT counters, routines and events
+func (m *MySQLDialect) extractSchemaObjects(ctx context.Context, db *sql.DB, dbs []string, schema *Schema, opts ExtractOptions) error {
+	var err error
+	if paced(ctx, opts.wants(ObjectSequences)) {
+		if schema.Sequences, err = m.extractSequences(ctx, db, dbs, schema.Tables); schema.skipDenied(ObjectSequences, err) != nil {
+			return err
+		}
+	}
+	if paced(ctx, opts.wants(ObjectRoutines)) {
+		if schema.Routines, err = m.extractRoutines(ctx, db, dbs); schema.skipDenied(ObjectRoutines, err) != nil {
+			return err
+		}
+	}
+	if paced(ctx, opts.IncludeEvents) {
+		if schema.Events, err = m.extractEvents(ctx, db, dbs); schema.skipDenied(ObjectEvents, err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// databases lists the databases to extract: the ones of opts.Schemas, or the
+// connection's current database
+func (m *MySQLDialect) databases(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]string, error) {
+	if len(opts.Schemas) > 0 {
+		return opts.Schemas, nil
+	}
+	var dbName sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&dbName); err != nil {
+		return nil, err
+	}
+	if !dbName.Valid {
+		return nil, fmt.Errorf("the connection selects no database; name one in the DSN or list the databases to compare")
+	}
+	return []string{dbName.String}, nil
+}
+
+// mysqlSchemaIn is the condition and arguments restricting column to dbs
+func mysqlSchemaIn(column string, dbs []string) (string, []any) {
+	marks := make([]string, len(dbs))
+	args := make([]any, len(dbs))
+	for i, name := range dbs {
+		marks[i], args[i] = "?", name
+	}
+	return column + " IN (" + strings.Join(marks, ", ") + ")", args
+}
+
+// mysqlObjectName names an object of dbName: qualified as schema.name when
+// several databases are extracted at once
+func mysqlObjectName(dbs []string, dbName, name string) string {
+	if len(dbs) > 1 {
+		return dbName + "." + name
+	}
+	return name
+}
+
+// checkSchemasDriver rejects a list of databases to extract for drivers
+// other than MySQL and TiDB, which read one database per connection
+func checkSchemasDriver(flagName, driver string, schemas []string) error {
+	if len(schemas) > 0 && engineFamily(driver) != "mysql" {
+		return fmt.Errorf("%s is supported for MySQL and TiDB only", flagName)
+	}
+	return nil
+}
+
+// extractEvents reads the scheduled events of the extracted databases
+func (m *MySQLDialect) extractEvents(ctx context.Context, db *sql.DB, dbs []string) (map[string]*Event, error) {
+	schemaIn, args := mysqlSchemaIn("event_schema", dbs)
+	rows, err := db.QueryContext(ctx, `
+		SELECT event_schema, event_name, event_type,
+			COALESCE(CAST(execute_at AS CHAR), ''),
+			COALESCE(interval_value, ''), COALESCE(interval_field, ''),
+			COALESCE(CAST(ends AS CHAR), ''),
+			status, on_completion, event_definition
+		FROM information_schema.events
+		WHERE `+schemaIn, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make(map[string]*Event)
+	for rows.Next() {
+		var dbName, eventType, executeAt, intervalValue, intervalField, ends string
+		event := &Event{}
+		if err := rows.Scan(&dbName, &event.Name, &eventType, &executeAt, &intervalValue, &intervalField, &ends,
+			&event.Status, &event.OnCompletion, &event.Body); err != nil {
+			return nil, err
+		}
+		event.Name = mysqlObjectName(dbs, dbName, event.Name)
+		if eventType == "ONE TIME" {
+			event.Schedule = "AT " + executeAt
+		} else {
+			event.Schedule = "EVERY " + intervalValue + " " + intervalField
+			if ends != "" {
+				event.Schedule += " ENDS " + ends
+			}
+		}
+		event.Body = normalizeRoutineBody(event.Body)
+		events[event.Name] = event
+	}
+	return events, rows.Err()
+}
+
+// extractRoutines reads the stored functions and procedures of the extracted
+// databases. Procedure arguments are listed with their mode (IN, OUT, INOUT);
+// functions only have input arguments.
+func (m *MySQLDialect) extractRoutines(ctx context.Context, db *sql.DB, dbs []string) (map[string]*Routine, error) {
+	schemaIn, schemaArgs := mysqlSchemaIn("routine_schema", dbs)
+	rows, err := db.QueryContext(ctx, `
+		SELECT routine_schema, routine_name, LOWER(routine_type), COALESCE(dtd_identifier, ''), routine_body, COALESCE(routine_definition, '')
+		FROM information_schema.routines
+		WHERE `+schemaIn, schemaArgs...)
+	if err != nil {
+		return nil, err
+	}
+	routines := make(map[string]*Routine)
+	for rows.Next() {
+		var dbName string
+		routine := &Routine{}
+		if err := rows.Scan(&dbName, &routine.Name, &routine.Kind, &routine.Returns, &routine.Language, &routine.Body); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		routine.Name = mysqlObjectName(dbs, dbName, routine.Name)
+		routine.Returns = mysqlRoutineType(routine.Returns)
+		routine.Body = normalizeRoutineBody(routine.Body)
+		routines[routine.Kind+" "+routine.Name] = routine
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	schemaIn, schemaArgs = mysqlSchemaIn("specific_schema", dbs)
+	rows, err = db.QueryContext(ctx, `
+		SELECT specific_schema, specific_name, LOWER(routine_type), COALESCE(parameter_mode, ''), parameter_name, dtd_identifier
+		FROM information_schema.parameters
+		WHERE `+schemaIn+` AND ordinal_position > 0
+		ORDER BY specific_schema, specific_name, ordinal_position
+	`, schemaArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	args := make(map[string][]string)
+	argTypes := make(map[string][]string)
+	for rows.Next() {
+		var dbName, routineName, kind, mode, name, dataType string
+		if err := rows.Scan(&dbName, &routineName, &kind, &mode, &name, &dataType); err != nil {
+			return nil, err
+		}
+		routineName = kind + " " + mysqlObjectName(dbs, dbName, routineName)
+		dataType = mysqlRoutineType(dataType)
+		arg := name + " " + dataType
+		if mode != "" {
+			arg = mode + " " + arg
+		}
+		args[routineName] = append(args[routineName], arg)
+		argTypes[routineName] = append(argTypes[routineName], dataType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	keyed := make(map[string]*Routine, len(routines))
+	for key, routine := range routines {
+		routine.Arguments = strings.Join(args[key], ", ")
+		keyed[mysqlRoutineKey(routine.Kind, routine.Name, argTypes[key])] = routine
+	}
+	return keyed, nil
+}
+
+// mysqlRoutineType spells a parameter or return type the way mysqldump and
+// information_schema agree on: lower case, without character set or
+// collation
+func mysqlRoutineType(t string) string {
+	t = strings.ToLower(strings.Join(strings.Fields(t), " "))
+	for _, clause := range []string{" charset ", " character set ", " collate "} {
+		if i := strings.Index(t, clause); i >= 0 {
+			t = t[:i]
+		}
+	}
+	return t
+}
+
+// extractSequences models the AUTO_INCREMENT columns of the extracted tables
+// as sequences, so that their step and offset are compared like Postgres
+// serials
+func (m *MySQLDialect) extractSequences(ctx context.Context, db *sql.DB, dbs []string, tables map[string]*Table) (map[string]*Sequence, error) {
+	var increment, offset int64
+	if err := db.QueryRowContext(ctx, "SELECT @@auto_increment_increment, @@auto_increment_offset").Scan(&increment, &offset); err != nil {
+		return nil, err
+	}
+	schemaIn, args := mysqlSchemaIn("table_schema", dbs)
+	rows, err := db.QueryContext(ctx, `
+		SELECT table_schema, table_name, column_name, column_type
+		FROM information_schema.columns
+		WHERE `+schemaIn+` AND extra LIKE '%auto_increment%'
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sequences := make(map[string]*Sequence)
+	for rows.Next() {
+		var dbName, tableName, columnName, columnType string
+		if err := rows.Scan(&dbName, &tableName, &columnName, &columnType); err != nil {
+			return nil, err
+		}
+		tableName = mysqlObjectName(dbs, dbName, tableName)
+		if _, ok := tables[tableName]; !ok {
+			continue
+		}
+		seq := autoIncrementSequence(tableName, columnName, columnType, offset, increment)
+		sequences[seq.Name] = seq
+	}
+	return sequences, rows.Err()
+}
+
+// mysqlIntegerMax is the largest signed value of each MySQL integer type
+var mysqlIntegerMax = map[string]int64{
+	"tinyint":   math.MaxInt8,
+	"smallint":  math.MaxInt16,
+	"mediumint": 1<<23 - 1,
+	"int":       math.MaxInt32,
+	"integer":   math.MaxInt32,
+	"bigint":    math.MaxInt64,
+}
+
+// autoIncrementSequence describes the counter of an AUTO_INCREMENT column.
+// Unsigned bigint columns are capped at the signed bigint maximum, the
+// largest value a sequence can hold.
+func autoIncrementSequence(tableName, columnName, columnType string, offset, increment int64) *Sequence {
+	columnType = strings.ToLower(columnType)
+	unsigned := strings.Contains(columnType, "unsigned")
+	base, _, _ := strings.Cut(strings.Fields(columnType)[0], "(")
+	max := mysqlIntegerMax[base]
+	dataType := base
+	if unsigned {
+		dataType += " unsigned"
+		if base != "bigint" {
+			max = max*2 + 1
+		}
+	}
+	return &Sequence{
+		Name:      tableName + "_" + columnName + "_seq",
+		DataType:  dataType,
+		Start:     offset,
+		Increment: increment,
+		MinValue:  1,
+		MaxValue:  max,
+		Cache:     1,
+		OwnedBy:   tableName + "." + columnName,
+	}
+}
+
+func (m *MySQLDialect) extractionPlan(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]string, []extractStep, error) {
+	dbs, err := m.databases(ctx, db, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tables []string
+	for _, dbName := range dbs {
+		names, err := m.getTables(ctx, db, dbs, dbName, opts.Filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		tables = append(tables, names...)
+	}
+	// Per-table steps look up the database of schema.table names
+	split := func(t string) (string, string) {
+		if len(dbs) > 1 {
+			dbName, name, _ := strings.Cut(t, ".")
+			return dbName, name
+		}
+		return dbs[0], t
+	}
+	steps := m.mysqlSteps(db, split)
+	if len(dbs) > 1 {
+		for i, step := range steps {
+			if step.object != ObjectForeignKeys {
+				continue
+			}
+			// References within an extracted database are named like its tables
+			fn := step.fn
+			steps[i].fn = func(ctx context.Context, t string, table *Table) error {
+				if err := fn(ctx, t, table); err != nil {
+					return err
+				}
+				dbName, _ := split(t)
+				for _, fk := range table.ForeignKeys {
+					if !strings.Contains(fk.RefTable, ".") {
+						fk.RefTable = dbName + "." + fk.RefTable
+					}
+				}
+				return nil
+			}
+		}
+	}
+	// TiDB speaks the MySQL protocol; its table options are compared
+	// whichever of the two drivers was used
+	if tidb, err := isTiDB(ctx, db); err != nil {
+		return nil, nil, err
+	} else if tidb {
+		steps = append(steps, extractStep{ObjectTableOptions, func(ctx context.Context, t string, table *Table) error {
+			dbName, name := split(t)
+			return extractTiDBOptions(ctx, db, dbName, name, table)
+		}})
+	}
+	return tables, steps, nil
+}
+
+// mysqlSteps are the per-table extraction steps; split turns a table name
+// into its database and its name there
+func (m *MySQLDialect) mysqlSteps(db *sql.DB, split func(t string) (string, string)) []extractStep {
+	step := func(object string, fn func(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error) extractStep {
+		return extractStep{object, func(ctx context.Context, t string, table *Table) error {
+			dbName, name := split(t)
+			return fn(ctx, db, dbName, name, table)
+		}}
+	}
+	return []extractStep{
+		step(ObjectColumns, m.extractColumns),
+		step(ObjectComments, m.extractTableComment),
+		step(ObjectPartitions, m.extractPartitioning),
+		step(ObjectPrimaryKeys, m.extractPrimaryKey),
+		step(ObjectForeignKeys, m.extractForeignKeys),
+		step(ObjectUniques, m.extractUniqueConstraints),
+		step(ObjectIndexes, m.extractIndexes),
+		step(ObjectChecks, func(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error {
+			// Check constraints need MySQL 8.0.16+; ignore errors on older versions
+			_ = m.extractCheckConstraints(ctx, db, dbName, tableName, table)
+			return nil
+		}),
+		step(ObjectTriggers, m.extractTriggers),
+	}
+}
+
+// getTables lists the base tables of dbName, named as mysqlObjectName does
+func (m *MySQLDialect) getTables(ctx context.Context, db *sql.DB, dbs []string, dbName string, filter *FilterConfig) ([]string, error) {
+	if err := waitQuery(ctx); err != nil {
+		return nil, err
+	}
+	nameExpr := "table_name"
+	if len(dbs) > 1 {
+		nameExpr = "CONCAT(table_schema, '.', table_name)"
+	}
+	filterSQL, args := tableFilterSQL(filter, nameExpr, []any{dbName}, func(int) string { return "?" })
+	query := `
+		SELECT ` + nameExpr + `
+		FROM information_schema.tables
+		WHERE table_schema = ?
+		  AND table_type = 'BASE TABLE'` + filterSQL + `
+		ORDER BY table_name
+	`
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+func (m *MySQLDialect) extractColumns(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error {
+	query := `
+		SELECT
+			column_name,
+			column_type,
+			is_nullable,
+			column_default,
+			extra,
+			column_comment,
+			COALESCE(generation_expression, '')
+		FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = ?
+		ORDER BY ordinal_position
+	`
+	rows, err := db.QueryContext(ctx, query, dbName, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, dataType, isNullable, extra, comment, generationExpr string
+		var defaultVal sql.NullString
+		if err := rows.Scan(&name, &dataType, &isNullable, &defaultVal, &extra, &comment, &generationExpr); err != nil {
+			return err
+		}
+
+		col := &Column{
+			Name:        name,
+			DataType:    dataType,
+			IsNullable:  isNullable == "YES",
+			IsInvisible: strings.Contains(strings.ToUpper(extra), "INVISIBLE"),
+			Comment:     comment,
+		}
+		if strings.Contains(strings.ToLower(extra), "auto_increment") {
+			col.Identity = IdentityAutoIncrement
+		}
+		if defaultVal.Valid {
+			col.DefaultValue = &defaultVal.String
+		}
+		// extra reads "VIRTUAL GENERATED" or "STORED GENERATED"; defaults
+		// with expressions are "DEFAULT_GENERATED"
+		upperExtra := strings.ToUpper(extra)
+		for _, storage := range []string{"VIRTUAL", "STORED", "PERSISTENT"} {
+			if strings.Contains(upperExtra, storage+" GENERATED") {
+				col.IsGenerated = true
+				col.GenerationExpression = strings.ReplaceAll(generationExpr, "`", "")
+				col.GenerationStorage = strings.ToLower(storage)
+			}
+		}
+		if col.GenerationStorage == "persistent" {
+			// MariaDB's name for STORED
+			col.GenerationStorage = "stored"
+		}
+		table.Columns[name] = col
+	}
+	return rows.Err()
+}
+
+func (m *MySQLDialect) extractTableComment(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error {
+	query := `
+		SELECT table_comment
+		FROM information_schema.tables
+		WHERE table_schema = ? AND table_name = ?
+	`
+	var comment sql.NullString
+	if err := db.QueryRowContext(ctx, query, dbName, tableName).Scan(&comment); err != nil {
+		return err
+	}
+	table.Comment = comment.String
+	return nil
+}
+
+// extractPartitioning reads the partitioning of a table; subpartitions are
+// folded into their partition
+func (m *MySQLDialect) extractPartitioning(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error {
+	query := `
+		SELECT partition_name, partition_method, COALESCE(partition_expression, ''),
+			COALESCE(partition_description, '')
+		FROM information_schema.partitions
+		WHERE table_schema = ? AND table_name = ? AND partition_name IS NOT NULL
+		ORDER BY partition_ordinal_position, subpartition_ordinal_position
+	`
+	rows, err := db.QueryContext(ctx, query, dbName, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var name, method, expr, description string
+		if err := rows.Scan(&name, &method, &expr, &description); err != nil {
+			return err
+		}
+		if table.Partitioning == nil {
+			table.Partitioning = &Partitioning{Strategy: strings.ToLower(method), Key: strings.ReplaceAll(expr, "`", "")}
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		part := &Partition{Name: name}
+		switch {
+		case strings.HasPrefix(method, "RANGE") && description == "MAXVALUE":
+			part.Bound = "VALUES LESS THAN MAXVALUE"
+		case strings.HasPrefix(method, "RANGE"):
+			part.Bound = "VALUES LESS THAN (" + description + ")"
+		case strings.HasPrefix(method, "LIST"):
+			part.Bound = "VALUES IN (" + description + ")"
+		}
+		table.Partitioning.Partitions = append(table.Partitioning.Partitions, part)
+	}
+	return rows.Err()
+}
+
+func (m *MySQLDialect) extractPrimaryKey(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error {
+	query := `
+		SELECT
+			constraint_name,
+			GROUP_CONCAT(column_name ORDER BY ordinal_position) as columns
+		FROM information_schema.key_column_usage
+		WHERE table_schema = ?
+		  AND table_name = ?
+		  AND constraint_name = 'PRIMARY'
+		GROUP BY constraint_name
+	`
+	var name string
+	var columns sql.NullString
+	err := db.QueryRowContext(ctx, query, dbName, tableName).Scan(&name, &columns)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if columns.Valid && columns.String != "" {
+		table.PrimaryKey = &PrimaryKey{
+			Name:    name,
+			Columns: strings.Split(columns.String, ","),
+		}
+		// MySQL 8.0.30+ sql_generate_invisible_primary_key adds an invisible
+		// my_row_id column as the primary key of tables created without one
+		pk := table.PrimaryKey
+		if len(pk.Columns) == 1 && pk.Columns[0] == gipkColumn {
+			if col, ok := table.Columns[gipkColumn]; ok && col.IsInvisible {
+				pk.Invisible = true
+			}
+		}
+	}
+	return nil
+}
+
+// gipkColumn is the column MySQL generates for invisible primary keys
+const gipkColumn = "my_row_id"
+
+// isGIPKColumn reports whether name is the generated invisible PK column of table
+func isGIPKColumn(table *Table, name string) bool {
+	return name == gipkColumn && table.PrimaryKey != nil && table.PrimaryKey.Invisible
+}
+
+func (m *MySQLDialect) extractForeignKeys(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error {
+	query := `
+		SELECT
+			kcu.constraint_name,
+			GROUP_CONCAT(kcu.column_name ORDER BY kcu.ordinal_position) as columns,
+			kcu.referenced_table_schema,
+			kcu.referenced_table_name,
+			GROUP_CONCAT(kcu.referenced_column_name ORDER BY kcu.ordinal_position) as ref_columns,
+			rc.update_rule,
+			rc.delete_rule
+		FROM information_schema.key_column_usage kcu
+		JOIN information_schema.referential_constraints rc
+			ON kcu.constraint_name = rc.constraint_name
+			AND kcu.table_schema = rc.constraint_schema
+		WHERE kcu.table_schema = ?
+		  AND kcu.table_name = ?
+		  AND kcu.referenced_table_name IS NOT NULL
+		GROUP BY kcu.constraint_name, kcu.referenced_table_schema, kcu.referenced_table_name, rc.update_rule, rc.delete_rule
+	`
+	rows, err := db.QueryContext(ctx, query, dbName, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, columns, refSchema, refTable, refColumns, updateRule, deleteRule string
+		if err := rows.Scan(&name, &columns, &refSchema, &refTable, &refColumns, &updateRule, &deleteRule); err != nil {
+			return err
+		}
+		// A reference into another database names it
+		if refSchema != dbName {
+			refTable = refSchema + "." + refTable
+		}
+
+		fk := &ForeignKey{
+			Name:       name,
+			Columns:    strings.Split(columns, ","),
+			RefTable:   refTable,
+			RefColumns: strings.Split(refColumns, ","),
+			OnUpdate:   updateRule,
+			OnDelete:   deleteRule,
+		}
+		table.ForeignKeys[name] = fk
+	}
+	return rows.Err()
+}
+
+func (m *MySQLDialect) extractUniqueConstraints(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error {
+	// Read from statistics rather than key_column_usage, which leaves out
+	// functional key parts
+	rows, err := mysqlQueryKeyParts(func(keyParts string) (*sql.Rows, error) {
+		return db.QueryContext(ctx, `
+			SELECT
+				index_name,
+				`+keyParts+` as columns
+			FROM information_schema.statistics
+			WHERE table_schema = ?
+			  AND table_name = ?
+			  AND index_name != 'PRIMARY'
+			  AND index_name IN (
+				SELECT constraint_name
+				FROM information_schema.table_constraints
+				WHERE table_schema = ?
+				  AND table_name = ?
+				  AND constraint_type = 'UNIQUE'
+			  )
+			GROUP BY index_name
+		`, dbName, tableName, dbName, tableName)
+	})
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, columns string
+		if err := rows.Scan(&name, &columns); err != nil {
+			return err
+		}
+
+		uniq := &Unique{
+			Name:    name,
+			Columns: mysqlKeyParts(columns),
+		}
+		table.UniqueConstraints[name] = uniq
+	}
+	return rows.Err()
+}
+
+// mysqlQueryKeyParts runs a statistics query whose columns list the key parts
+// of each index. A functional key part (8.0.13+) has no column_name and is
+// spelled as its parenthesized expression, the way CREATE INDEX takes it;
+// servers without functional key parts have no expression column, so they
+// are asked for column_name alone. Expressions may contain commas, so parts
+// are separated by newlines.
+func mysqlQueryKeyParts(query func(keyParts string) (*sql.Rows, error)) (*sql.Rows, error) {
+	rows, err := query("GROUP_CONCAT(IFNULL(column_name, CONCAT('(', expression, ')')) ORDER BY seq_in_index SEPARATOR '\n')")
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1054 {
+		// ER_BAD_FIELD_ERROR: MySQL before 8.0.13 and MariaDB
+		return query("GROUP_CONCAT(column_name ORDER BY seq_in_index SEPARATOR '\n')")
+	}
+	return rows, err
+}
+
+// mysqlKeyParts splits the key parts listed by mysqlQueryKeyParts, dropping
+// the backticks the server quotes expression identifiers with
+func mysqlKeyParts(columns string) []string {
+	return strings.Split(strings.ReplaceAll(columns, "`", ""), "\n")
+}
+
+func (m *MySQLDialect) extractIndexes(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error {
+	rows, err := mysqlQueryKeyParts(func(keyParts string) (*sql.Rows, error) {
+		return db.QueryContext(ctx, `
+			SELECT
+				index_name,
+				`+keyParts+` as columns,
+				GROUP_CONCAT(IFNULL(sub_part, '') ORDER BY seq_in_index) as prefix_lengths,
+				GROUP_CONCAT(IFNULL(collation, '') ORDER BY seq_in_index) as orders,
+				MAX(non_unique) as non_unique,
+				MAX(index_type) as index_type
+			FROM information_schema.statistics
+			WHERE table_schema = ?
+			  AND table_name = ?
+			  AND index_name != 'PRIMARY'
+			  AND index_name NOT IN (
+				SELECT constraint_name
+				FROM information_schema.table_constraints
+				WHERE table_schema = ?
+				  AND table_name = ?
+				  AND constraint_type IN ('UNIQUE', 'FOREIGN KEY')
+			  )
+			GROUP BY index_name
+		`, dbName, tableName, dbName, tableName)
+	})
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, columns, prefixLengths, collations, indexType string
+		var nonUnique int
+		if err := rows.Scan(&name, &columns, &prefixLengths, &collations, &nonUnique, &indexType); err != nil {
+			return err
+		}
+
+		idx := &Index{
+			Name:          name,
+			Columns:       mysqlKeyParts(columns),
+			IsUnique:      nonUnique == 0,
+			Method:        strings.ToLower(indexType),
+			PrefixLengths: compactList(strings.Split(prefixLengths, ",")),
+		}
+		// The collation of a key part is A for ascending and D for
+		// descending (8.0+); hash and full-text parts have none
+		var orders []string
+		for _, collation := range strings.Split(collations, ",") {
+			order := ""
+			if collation == "D" {
+				order = "DESC"
+			}
+			orders = append(orders, order)
+		}
+		idx.Orders = compactList(orders)
+		// FULLTEXT and SPATIAL are kinds of index rather than methods
+		if idx.Method == IndexFullText || idx.Method == IndexSpatial {
+			idx.Kind, idx.Method = idx.Method, ""
+		}
+		table.Indexes[name] = idx
+	}
+	return rows.Err()
+}
+
+func (m *MySQLDialect) extractCheckConstraints(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error {
+	query := `
+		SELECT
+			cc.constraint_name,
+			cc.check_clause,
+			tc.enforced
+		FROM information_schema.check_constraints cc
+		JOIN information_schema.table_constraints tc
+			ON tc.constraint_schema = cc.constraint_schema
+			AND tc.constraint_name = cc.constraint_name
+		WHERE cc.constraint_schema = ?
+		  AND tc.table_name = ?
+		  AND tc.constraint_type = 'CHECK'
+	`
+	rows, err := db.QueryContext(ctx, query, dbName, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, expr, enforced string
+		if err := rows.Scan(&name, &expr, &enforced); err != nil {
+			return err
+		}
+
+		check := &CheckConstr{
+			Name:       name,
+			Expression: expr,
+		}
+		if enforced == "NO" {
+			check.Status = ConstraintNotEnforced
+		}
+		table.CheckConstraints[name] = check
+	}
+	return rows.Err()
+}
+
+func (m *MySQLDialect) extractTriggers(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error {
+	query := `
+		SELECT trigger_name, action_timing, event_manipulation, action_orientation, action_statement
+		FROM information_schema.triggers
+		WHERE event_object_schema = ? AND event_object_table = ?
+	`
+	rows, err := db.QueryContext(ctx, query, dbName, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		trigger := &Trigger{Events: make([]string, 1)}
+		if err := rows.Scan(&trigger.Name, &trigger.Timing, &trigger.Events[0], &trigger.Level, &trigger.Body); err != nil {
+			return err
+		}
+		table.Triggers[trigger.Name] = trigger
+	}
+	return rows.Err()
+}
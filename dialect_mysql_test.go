@@ -0,0 +1,14 @@
+package dbdiff
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMySQLKeyPartsKeepsExpressionsWhole(t *testing.T) {
+	got := mysqlKeyParts("(concat(`first`,' ',`last`))\nid")
+	want := []string{"(concat(first,' ',last))", "id"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("key parts = %q, want %q", got, want)
+	}
+}
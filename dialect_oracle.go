@@ -0,0 +1,369 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	_ "github.com/sijms/go-ora/v2"
+)
+
+// ============================================================================
+// ORACLE DIALECT
+// ============================================================================
+
+// OracleDialect extracts the tables of the connected user's schema (Oracle
+// 12.2 or later). Oracle stores unquoted identifiers in uppercase; those are
+// folded to lowercase so that they compare cleanly against Postgres and
+// MySQL, while quoted mixed-case names are kept as they are.
+type OracleDialect struct{}
+
+// oracleFoldSQL is the SQL counterpart of oracleName for a name column
+func oracleFoldSQL(column string) string {
+	return fmt.Sprintf("CASE WHEN %[1]s = UPPER(%[1]s) THEN LOWER(%[1]s) ELSE %[1]s END", column)
+}
+
+// oracleName folds an uppercase (unquoted) Oracle identifier to lowercase
+func oracleName(name string) string {
+	if name == strings.ToUpper(name) {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// oracleConstraintStatus maps the STATUS and VALIDATED columns of
+// ALL_CONSTRAINTS to a constraint status
+func oracleConstraintStatus(status, validated string) string {
+	switch {
+	case status == "DISABLED":
+		return ConstraintNotEnforced
+	case validated == "NOT VALIDATED":
+		return ConstraintNotValid
+	}
+	return ""
+}
+
+func oraclePlaceholder(n int) string {
+	return fmt.Sprintf(":%d", n)
+}
+
+// oracleColumns folds a comma-separated column list
+func oracleColumns(columns string) []string {
+	cols := strings.Split(columns, ",")
+	for i, c := range cols {
+		cols[i] = oracleName(c)
+	}
+	return cols
+}
+
+func (o *OracleDialect) ExtractSchema(ctx context.Context, db *sql.DB, opts ExtractOptions) (*Schema, error) {
+	ctx, stop := limitQueries(ctx, opts.MaxQPS)
+	defer stop()
+	tables, steps, err := o.extractionPlan(ctx, db, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{}
+	if schema.Tables, schema.NotComparable, err = runExtractionPlan(ctx, tables, steps, opts); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (o *OracleDialect) extractionPlan(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]string, []extractStep, error) {
+	var owner string
+	if err := db.QueryRowContext(ctx, "SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM DUAL").Scan(&owner); err != nil {
+		return nil, nil, err
+	}
+	// Catalog names of the tables, by dbdiff (folded) name
+	names, err := o.getTables(ctx, db, owner, opts.Filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	tables := make([]string, 0, len(names))
+	for name := range names {
+		tables = append(tables, name)
+	}
+	sort.Strings(tables)
+
+	step := func(fn func(ctx context.Context, db *sql.DB, owner, tableName string, table *Table) error) func(context.Context, string, *Table) error {
+		return func(ctx context.Context, t string, table *Table) error { return fn(ctx, db, owner, names[t], table) }
+	}
+	return tables, []extractStep{
+		{ObjectColumns, step(o.extractColumns)},
+		{ObjectComments, step(o.extractTableComment)},
+		{ObjectPrimaryKeys, step(o.extractPrimaryKey)},
+		{ObjectForeignKeys, step(o.extractForeignKeys)},
+		{ObjectUniques, step(o.extractUniqueConstraints)},
+		{ObjectIndexes, step(o.extractIndexes)},
+		{ObjectChecks, step(o.extractCheckConstraints)},
+	}, nil
+}
+
+func (o *OracleDialect) getTables(ctx context.Context, db *sql.DB, owner string, filter *FilterConfig) (map[string]string, error) {
+	if err := waitQuery(ctx); err != nil {
+		return nil, err
+	}
+	filterSQL, args := tableFilterSQL(filter, oracleFoldSQL("table_name"), []any{owner}, oraclePlaceholder)
+	query := `
+		SELECT table_name
+		FROM all_tables
+		WHERE owner = :1
+		  AND nested = 'NO'
+		  AND secondary = 'N'
+		  AND (iot_type IS NULL OR iot_type = 'IOT')
+		  AND table_name NOT LIKE 'BIN$%'` + filterSQL
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make(map[string]string)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables[oracleName(name)] = name
+	}
+	return tables, rows.Err()
+}
+
+func (o *OracleDialect) extractColumns(ctx context.Context, db *sql.DB, owner, tableName string, table *Table) error {
+	query := `
+		SELECT
+			c.column_name,
+			LOWER(CASE
+				WHEN c.data_type IN ('VARCHAR2', 'NVARCHAR2', 'CHAR', 'NCHAR')
+					THEN c.data_type || '(' || c.char_length || CASE WHEN c.char_used = 'B' THEN ' BYTE' END || ')'
+				WHEN c.data_type = 'RAW'
+					THEN c.data_type || '(' || c.data_length || ')'
+				WHEN c.data_type = 'NUMBER' AND c.data_precision IS NOT NULL
+					THEN c.data_type || '(' || c.data_precision || ',' || c.data_scale || ')'
+				WHEN c.data_type = 'NUMBER' AND c.data_scale = 0
+					THEN 'INTEGER'
+				ELSE c.data_type
+			END),
+			c.nullable,
+			c.data_default,
+			c.hidden_column,
+			cc.comments
+		FROM all_tab_cols c
+		LEFT JOIN all_col_comments cc
+			ON cc.owner = c.owner AND cc.table_name = c.table_name AND cc.column_name = c.column_name
+		WHERE c.owner = :1 AND c.table_name = :2
+		  AND c.user_generated = 'YES'
+		ORDER BY c.column_id
+	`
+	rows, err := db.QueryContext(ctx, query, owner, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, dataType, nullable, hidden string
+		var defaultVal, comment sql.NullString
+		if err := rows.Scan(&name, &dataType, &nullable, &defaultVal, &hidden, &comment); err != nil {
+			return err
+		}
+
+		col := &Column{
+			Name:        oracleName(name),
+			DataType:    dataType,
+			IsNullable:  nullable == "Y",
+			IsInvisible: hidden == "YES",
+			Comment:     comment.String,
+		}
+		// Oracle keeps the default as typed, including trailing whitespace
+		if d := strings.TrimSpace(defaultVal.String); defaultVal.Valid && d != "" && !strings.EqualFold(d, "NULL") {
+			col.DefaultValue = &d
+		}
+		table.Columns[col.Name] = col
+	}
+	return rows.Err()
+}
+
+func (o *OracleDialect) extractTableComment(ctx context.Context, db *sql.DB, owner, tableName string, table *Table) error {
+	query := `
+		SELECT comments
+		FROM all_tab_comments
+		WHERE owner = :1 AND table_name = :2
+	`
+	var comment sql.NullString
+	err := db.QueryRowContext(ctx, query, owner, tableName).Scan(&comment)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	table.Comment = comment.String
+	return nil
+}
+
+// oracleConstraints returns the name and columns of the constraints of one
+// type (P or U) of a table
+func (o *OracleDialect) oracleConstraints(ctx context.Context, db *sql.DB, owner, tableName, constraintType string) (map[string][]string, error) {
+	query := `
+		SELECT
+			c.constraint_name,
+			LISTAGG(cc.column_name, ',') WITHIN GROUP (ORDER BY cc.position)
+		FROM all_constraints c
+		JOIN all_cons_columns cc ON cc.owner = c.owner AND cc.constraint_name = c.constraint_name
+		WHERE c.owner = :1 AND c.table_name = :2 AND c.constraint_type = :3
+		GROUP BY c.constraint_name
+	`
+	rows, err := db.QueryContext(ctx, query, owner, tableName, constraintType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	constraints := make(map[string][]string)
+	for rows.Next() {
+		var name, columns string
+		if err := rows.Scan(&name, &columns); err != nil {
+			return nil, err
+		}
+		constraints[oracleName(name)] = oracleColumns(columns)
+	}
+	return constraints, rows.Err()
+}
+
+func (o *OracleDialect) extractPrimaryKey(ctx context.Context, db *sql.DB, owner, tableName string, table *Table) error {
+	constraints, err := o.oracleConstraints(ctx, db, owner, tableName, "P")
+	if err != nil {
+		return err
+	}
+	for name, columns := range constraints {
+		table.PrimaryKey = &PrimaryKey{Name: name, Columns: columns}
+	}
+	return nil
+}
+
+func (o *OracleDialect) extractUniqueConstraints(ctx context.Context, db *sql.DB, owner, tableName string, table *Table) error {
+	constraints, err := o.oracleConstraints(ctx, db, owner, tableName, "U")
+	if err != nil {
+		return err
+	}
+	for name, columns := range constraints {
+		table.UniqueConstraints[name] = &Unique{Name: name, Columns: columns}
+	}
+	return nil
+}
+
+func (o *OracleDialect) extractForeignKeys(ctx context.Context, db *sql.DB, owner, tableName string, table *Table) error {
+	query := `
+		SELECT
+			c.constraint_name,
+			LISTAGG(cc.column_name, ',') WITHIN GROUP (ORDER BY cc.position),
+			r.table_name,
+			LISTAGG(rc.column_name, ',') WITHIN GROUP (ORDER BY cc.position),
+			c.delete_rule,
+			c.status,
+			c.validated
+		FROM all_constraints c
+		JOIN all_cons_columns cc ON cc.owner = c.owner AND cc.constraint_name = c.constraint_name
+		JOIN all_constraints r ON r.owner = c.r_owner AND r.constraint_name = c.r_constraint_name
+		JOIN all_cons_columns rc ON rc.owner = r.owner AND rc.constraint_name = r.constraint_name AND rc.position = cc.position
+		WHERE c.owner = :1 AND c.table_name = :2 AND c.constraint_type = 'R'
+		GROUP BY c.constraint_name, r.table_name, c.delete_rule, c.status, c.validated
+	`
+	rows, err := db.QueryContext(ctx, query, owner, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, columns, refTable, refColumns, deleteRule, status, validated string
+		if err := rows.Scan(&name, &columns, &refTable, &refColumns, &deleteRule, &status, &validated); err != nil {
+			return err
+		}
+
+		fk := &ForeignKey{
+			Name:       oracleName(name),
+			Columns:    oracleColumns(columns),
+			RefTable:   oracleName(refTable),
+			RefColumns: oracleColumns(refColumns),
+			// Oracle has no ON UPDATE actions
+			OnUpdate: "NO ACTION",
+			OnDelete: deleteRule,
+			Status:   oracleConstraintStatus(status, validated),
+		}
+		table.ForeignKeys[fk.Name] = fk
+	}
+	return rows.Err()
+}
+
+func (o *OracleDialect) extractIndexes(ctx context.Context, db *sql.DB, owner, tableName string, table *Table) error {
+	query := `
+		SELECT
+			i.index_name,
+			LISTAGG(ic.column_name, ',') WITHIN GROUP (ORDER BY ic.column_position),
+			i.uniqueness
+		FROM all_indexes i
+		JOIN all_ind_columns ic ON ic.index_owner = i.owner AND ic.index_name = i.index_name
+		WHERE i.table_owner = :1 AND i.table_name = :2
+		  AND i.index_type != 'LOB'
+		  AND NOT EXISTS (
+			SELECT 1 FROM all_constraints c
+			WHERE c.owner = i.table_owner AND c.table_name = i.table_name
+			  AND c.index_name = i.index_name AND c.constraint_type IN ('P', 'U')
+		  )
+		GROUP BY i.index_name, i.uniqueness
+	`
+	rows, err := db.QueryContext(ctx, query, owner, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, columns, uniqueness string
+		if err := rows.Scan(&name, &columns, &uniqueness); err != nil {
+			return err
+		}
+
+		idx := &Index{
+			Name:     oracleName(name),
+			Columns:  oracleColumns(columns),
+			IsUnique: uniqueness == "UNIQUE",
+		}
+		table.Indexes[idx.Name] = idx
+	}
+	return rows.Err()
+}
+
+func (o *OracleDialect) extractCheckConstraints(ctx context.Context, db *sql.DB, owner, tableName string, table *Table) error {
+	// NOT NULL columns are implemented as system-named check constraints;
+	// they are already reported as nullability
+	query := `
+		SELECT constraint_name, search_condition_vc, status, validated
+		FROM all_constraints
+		WHERE owner = :1 AND table_name = :2 AND constraint_type = 'C'
+		  AND NOT (generated = 'GENERATED NAME' AND search_condition_vc LIKE '% IS NOT NULL')
+	`
+	rows, err := db.QueryContext(ctx, query, owner, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, expr, status, validated string
+		if err := rows.Scan(&name, &expr, &status, &validated); err != nil {
+			return err
+		}
+
+		check := &CheckConstr{
+			Name:       oracleName(name),
+			Expression: expr,
+			Status:     oracleConstraintStatus(status, validated),
+		}
+		table.CheckConstraints[check.Name] = check
+	}
+	return rows.Err()
+}
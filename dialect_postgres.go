@@ -0,0 +1,1118 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ============================================================================
+// POSTGRES DIALECT
+// ============================================================================
+
+type PostgresDialect struct{}
+
+func (p *PostgresDialect) ExtractSchema(ctx context.Context, db *sql.DB, opts ExtractOptions) (*Schema, error) {
+	ctx, stop := limitQueries(ctx, opts.MaxQPS)
+	defer stop()
+	tables, steps, err := p.extractionPlan(ctx, db, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{}
+	if schema.Tables, schema.NotComparable, err = runExtractionPlan(ctx, tables, steps, opts); err != nil {
+		return nil, err
+	}
+	if paced(ctx, opts.wants(ObjectTypes)) {
+		if schema.Types, err = p.extractUserTypes(ctx, db, opts); schema.skipDenied(ObjectTypes, err) != nil {
+			return nil, err
+		}
+	}
+	if paced(ctx, opts.wants(ObjectSequences)) {
+		if schema.Sequences, err = p.extractSequences(ctx, db, opts); schema.skipDenied(ObjectSequences, err) != nil {
+			return nil, err
+		}
+	}
+	if paced(ctx, opts.wants(ObjectRoutines)) {
+		if schema.Routines, err = p.extractRoutines(ctx, db, opts); schema.skipDenied(ObjectRoutines, err) != nil {
+			return nil, err
+		}
+	}
+	if paced(ctx, opts.wants(ObjectExtensions)) {
+		if schema.Extensions, err = p.extractExtensions(ctx, db); schema.skipDenied(ObjectExtensions, err) != nil {
+			return nil, err
+		}
+	}
+	if paced(ctx, opts.wants(ObjectCollations)) {
+		if schema.Collations, err = p.extractCollations(ctx, db); schema.skipDenied(ObjectCollations, err) != nil {
+			return nil, err
+		}
+	}
+	if paced(ctx, opts.IncludeForeignTables && opts.wants(ObjectForeignTables)) {
+		if schema.ForeignServers, err = p.extractForeignServers(ctx, db); schema.skipDenied(ObjectForeignTables, err) != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+func (p *PostgresDialect) extractionPlan(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]string, []extractStep, error) {
+	tables, err := p.getTables(ctx, db, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	steps := p.postgresSteps(db)
+	// YugabyteDB speaks the Postgres protocol; its sharding options are
+	// compared whichever of the two drivers was used
+	if yb, err := isYugabyte(ctx, db); err != nil {
+		return nil, nil, err
+	} else if yb {
+		steps = append(steps, extractStep{ObjectTableOptions, func(ctx context.Context, t string, table *Table) error {
+			return extractYugabyteOptions(ctx, db, t, table)
+		}})
+	}
+	if opts.IncludeForeignTables {
+		steps = append(steps, extractStep{ObjectForeignTables, func(ctx context.Context, t string, table *Table) error {
+			return p.extractForeignTable(ctx, db, t, table)
+		}})
+	}
+	return tables, steps, nil
+}
+
+func (p *PostgresDialect) postgresSteps(db *sql.DB) []extractStep {
+	return []extractStep{
+		{ObjectColumns, func(ctx context.Context, t string, table *Table) error { return p.extractColumns(ctx, db, t, table) }},
+		{ObjectComments, func(ctx context.Context, t string, table *Table) error {
+			return p.extractTableComment(ctx, db, t, table)
+		}},
+		{ObjectAccessMethods, func(ctx context.Context, t string, table *Table) error {
+			return p.extractAccessMethod(ctx, db, t, table)
+		}},
+		{ObjectReplicaIdentity, func(ctx context.Context, t string, table *Table) error {
+			return p.extractReplicaIdentity(ctx, db, t, table)
+		}},
+		{ObjectPartitions, func(ctx context.Context, t string, table *Table) error {
+			return p.extractPartitioning(ctx, db, t, table)
+		}},
+		{ObjectPartitions, func(ctx context.Context, t string, table *Table) error {
+			return p.extractInheritance(ctx, db, t, table)
+		}},
+		{ObjectPrimaryKeys, func(ctx context.Context, t string, table *Table) error { return p.extractPrimaryKey(ctx, db, t, table) }},
+		{ObjectForeignKeys, func(ctx context.Context, t string, table *Table) error {
+			return p.extractForeignKeys(ctx, db, t, table)
+		}},
+		{ObjectUniques, func(ctx context.Context, t string, table *Table) error {
+			return p.extractUniqueConstraints(ctx, db, t, table)
+		}},
+		{ObjectIndexes, func(ctx context.Context, t string, table *Table) error { return p.extractIndexes(ctx, db, t, table) }},
+		{ObjectChecks, func(ctx context.Context, t string, table *Table) error {
+			return p.extractCheckConstraints(ctx, db, t, table)
+		}},
+		{ObjectTriggers, func(ctx context.Context, t string, table *Table) error { return p.extractTriggers(ctx, db, t, table) }},
+		{ObjectRewriteRules, func(ctx context.Context, t string, table *Table) error {
+			return p.extractRewriteRules(ctx, db, t, table)
+		}},
+	}
+}
+
+func (p *PostgresDialect) getTables(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]string, error) {
+	if err := waitQuery(ctx); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = 'public'
+	`
+	if opts.IncludeForeignTables {
+		query += ` AND table_type IN ('BASE TABLE', 'FOREIGN')`
+	} else {
+		query += ` AND table_type = 'BASE TABLE'`
+	}
+	filterSQL, args := tableFilterSQL(opts.Filter, "table_name", nil, func(n int) string { return fmt.Sprintf("$%d", n) })
+	query += filterSQL
+	if !opts.IncludeExtensionObjects {
+		// Skip relations that are members of an extension (pg_depend deptype 'e')
+		query += `
+		  AND NOT EXISTS (
+			SELECT 1
+			FROM pg_depend d
+			JOIN pg_class c ON c.oid = d.objid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE d.classid = 'pg_class'::regclass
+			  AND d.deptype = 'e'
+			  AND n.nspname = table_schema
+			  AND c.relname = table_name
+		  )
+		`
+	}
+	query += ` ORDER BY table_name`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+func (p *PostgresDialect) extractColumns(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT
+			column_name,
+			data_type,
+			is_nullable,
+			column_default,
+			col_description(format('%I.%I', table_schema, table_name)::regclass, ordinal_position) AS column_comment,
+			udt_name,
+			domain_name,
+			EXISTS (
+				SELECT 1
+				FROM pg_trigger tg
+				JOIN pg_proc pr ON pr.oid = tg.tgfoid
+				WHERE tg.tgrelid = format('%I.%I', table_schema, table_name)::regclass
+				  AND pr.proname = 'lo_manage'
+				  AND tg.tgargs = convert_to(column_name, 'UTF8') || '\x00'::bytea
+			) AS lo_managed,
+			-- Read through to_jsonb: servers before Postgres 12 have neither
+			-- column and return NULL
+			to_jsonb(c) ->> 'generation_expression',
+			COALESCE(to_jsonb(a) ->> 'attgenerated', ''),
+			CASE
+				WHEN c.is_identity = 'YES' THEN 'identity ' || lower(c.identity_generation)
+				WHEN c.column_default LIKE 'nextval(%'
+				  AND pg_get_serial_sequence(format('%I.%I', c.table_schema, c.table_name), c.column_name) IS NOT NULL THEN 'serial'
+				ELSE ''
+			END,
+			c.character_maximum_length,
+			c.numeric_precision,
+			c.numeric_scale,
+			c.datetime_precision,
+			format_type(a.atttypid, a.atttypmod)
+		FROM information_schema.columns c
+		LEFT JOIN pg_attribute a
+		  ON a.attrelid = format('%I.%I', c.table_schema, c.table_name)::regclass AND a.attname = c.column_name
+		WHERE table_schema = 'public' AND table_name = $1
+		ORDER BY ordinal_position
+	`
+	rows, err := db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, dataType, isNullable, udtName, generated, identity string
+		var defaultVal, comment, domainName, generationExpr sql.NullString
+		var loManaged bool
+		var charLength, precision, scale, datetimePrecision sql.NullInt64
+		var formatType sql.NullString
+		if err := rows.Scan(&name, &dataType, &isNullable, &defaultVal, &comment, &udtName, &domainName, &loManaged,
+			&generationExpr, &generated, &identity, &charLength, &precision, &scale, &datetimePrecision, &formatType); err != nil {
+			return err
+		}
+
+		col := &Column{
+			Name:        name,
+			DataType:    dataType,
+			IsNullable:  isNullable == "YES",
+			Comment:     comment.String,
+			LargeObject: loManaged || domainName.String == "lo",
+			Identity:    identity,
+		}
+		if defaultVal.Valid {
+			col.DefaultValue = &defaultVal.String
+		}
+		// Domains report their base type as data_type; enums and extension
+		// types report USER-DEFINED
+		if domainName.Valid {
+			col.UserType = domainName.String
+		} else if dataType == "USER-DEFINED" {
+			col.UserType = udtName
+		} else if dataType == "ARRAY" {
+			// format_type() writes every dimension as a single []
+			col.ElementType = strings.TrimSuffix(formatType.String, "[]")
+		} else {
+			// Integer and float types report their binary precision and
+			// date and interval their seconds precision; these are implied
+			// by the type
+			nullable := func(n sql.NullInt64) *int64 {
+				if !n.Valid {
+					return nil
+				}
+				return &n.Int64
+			}
+			col.CharMaxLength = nullable(charLength)
+			if dataType == "numeric" {
+				col.NumericPrecision, col.NumericScale = nullable(precision), nullable(scale)
+			}
+			if strings.HasPrefix(dataType, "time") {
+				col.DatetimePrecision = nullable(datetimePrecision)
+			}
+		}
+		// attgenerated is 's' for stored and, from Postgres 18, 'v' for
+		// virtual columns
+		if generated == "s" || generated == "v" {
+			col.IsGenerated = true
+			col.GenerationExpression = generationExpr.String
+			col.GenerationStorage = map[string]string{"s": "stored", "v": "virtual"}[generated]
+		}
+		table.Columns[name] = col
+	}
+	return rows.Err()
+}
+
+// extractUserTypes reads the enums, domains and composite types of the public
+// schema
+func (p *PostgresDialect) extractUserTypes(ctx context.Context, db *sql.DB, opts ExtractOptions) (map[string]*UserType, error) {
+	types := make(map[string]*UserType)
+
+	// Types created by an extension (pg_depend deptype 'e') are left out
+	// unless extension objects are asked for
+	notExtension := ""
+	if !opts.IncludeExtensionObjects {
+		notExtension = `
+		  AND NOT EXISTS (
+			SELECT 1
+			FROM pg_depend d
+			WHERE d.classid = 'pg_type'::regclass
+			  AND d.objid = t.oid
+			  AND d.deptype = 'e'
+		  )`
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT t.typname, e.enumlabel
+		FROM pg_type t
+		JOIN pg_enum e ON e.enumtypid = t.oid
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE n.nspname = 'public'`+notExtension+`
+		ORDER BY t.typname, e.enumsortorder
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, label string
+		if err := rows.Scan(&name, &label); err != nil {
+			return nil, err
+		}
+		if types[name] == nil {
+			types[name] = &UserType{Name: name, Kind: UserTypeEnum}
+		}
+		types[name].Labels = append(types[name].Labels, label)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	domains, err := db.QueryContext(ctx, `
+		SELECT
+			t.typname,
+			format_type(t.typbasetype, t.typtypmod),
+			t.typnotnull,
+			t.typdefault,
+			array_to_string(ARRAY(
+				SELECT pg_get_constraintdef(c.oid)
+				FROM pg_constraint c
+				WHERE c.contypid = t.oid
+				ORDER BY 1
+			), E'\n')
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE t.typtype = 'd' AND n.nspname = 'public'`+notExtension+`
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer domains.Close()
+	for domains.Next() {
+		var name, baseType, checks string
+		var notNull bool
+		var def sql.NullString
+		if err := domains.Scan(&name, &baseType, &notNull, &def, &checks); err != nil {
+			return nil, err
+		}
+		ut := &UserType{Name: name, Kind: UserTypeDomain, BaseType: baseType, NotNull: notNull}
+		if def.Valid {
+			ut.Default = &def.String
+		}
+		if checks != "" {
+			ut.Checks = strings.Split(checks, "\n")
+		}
+		types[name] = ut
+	}
+	if err := domains.Err(); err != nil {
+		return nil, err
+	}
+
+	// Composite types have a pg_class entry of their own (relkind c); the
+	// row types of tables don't count
+	composites, err := db.QueryContext(ctx, `
+		SELECT t.typname, a.attname, format_type(a.atttypid, a.atttypmod)
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		JOIN pg_class c ON c.oid = t.typrelid AND c.relkind = 'c'
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+		WHERE t.typtype = 'c' AND n.nspname = 'public'`+notExtension+`
+		ORDER BY t.typname, a.attnum
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer composites.Close()
+	for composites.Next() {
+		var name string
+		attr := &TypeAttribute{}
+		if err := composites.Scan(&name, &attr.Name, &attr.Type); err != nil {
+			return nil, err
+		}
+		if types[name] == nil {
+			types[name] = &UserType{Name: name, Kind: UserTypeComposite}
+		}
+		types[name].Attributes = append(types[name].Attributes, attr)
+	}
+	return types, composites.Err()
+}
+
+// extractSequences reads the sequences of the public schema along with the
+// column owning them (serial and identity columns, OWNED BY)
+func (p *PostgresDialect) extractSequences(ctx context.Context, db *sql.DB, opts ExtractOptions) (map[string]*Sequence, error) {
+	query := `
+		SELECT
+			c.relname,
+			format_type(s.seqtypid, NULL),
+			s.seqstart,
+			s.seqincrement,
+			s.seqmin,
+			s.seqmax,
+			s.seqcache,
+			s.seqcycle,
+			COALESCE((
+				SELECT t.relname || '.' || a.attname
+				FROM pg_depend d
+				JOIN pg_class t ON t.oid = d.refobjid
+				JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+				WHERE d.classid = 'pg_class'::regclass
+				  AND d.objid = c.oid
+				  AND d.refclassid = 'pg_class'::regclass
+				  AND d.deptype IN ('a', 'i')
+				LIMIT 1
+			), '')
+		FROM pg_sequence s
+		JOIN pg_class c ON c.oid = s.seqrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public'
+	`
+	if !opts.IncludeExtensionObjects {
+		query += `
+		  AND NOT EXISTS (
+			SELECT 1
+			FROM pg_depend d
+			WHERE d.classid = 'pg_class'::regclass
+			  AND d.objid = c.oid
+			  AND d.deptype = 'e'
+		  )
+		`
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sequences := make(map[string]*Sequence)
+	for rows.Next() {
+		seq := &Sequence{}
+		if err := rows.Scan(&seq.Name, &seq.DataType, &seq.Start, &seq.Increment, &seq.MinValue, &seq.MaxValue, &seq.Cache, &seq.Cycle, &seq.OwnedBy); err != nil {
+			return nil, err
+		}
+		sequences[seq.Name] = seq
+	}
+	return sequences, rows.Err()
+}
+
+// extractExtensions reads the installed extensions, whatever schema they
+// were installed into
+func (p *PostgresDialect) extractExtensions(ctx context.Context, db *sql.DB) (map[string]*Extension, error) {
+	rows, err := db.QueryContext(ctx, "SELECT extname, extversion FROM pg_extension")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	extensions := make(map[string]*Extension)
+	for rows.Next() {
+		ext := &Extension{}
+		if err := rows.Scan(&ext.Name, &ext.Version); err != nil {
+			return nil, err
+		}
+		extensions[ext.Name] = ext
+	}
+	return extensions, rows.Err()
+}
+
+// extractCollations reads the collations the public schema uses or defines,
+// and the database's default collation as "default", with the version the
+// collation library reports now and the one recorded in the catalog
+func (p *PostgresDialect) extractCollations(ctx context.Context, db *sql.DB) (map[string]*Collation, error) {
+	var versionNum int
+	if err := db.QueryRowContext(ctx, "SELECT current_setting('server_version_num')::int").Scan(&versionNum); err != nil {
+		return nil, err
+	}
+	collations := make(map[string]*Collation)
+	// The version of the database default collation is tracked since
+	// Postgres 15
+	if versionNum >= 150000 {
+		query := `
+			SELECT datcollate, COALESCE(pg_database_collation_actual_version(oid), ''), COALESCE(datcollversion, '')
+			FROM pg_database WHERE datname = current_database()
+		`
+		c := &Collation{Name: "default", Provider: "libc"}
+		if err := db.QueryRowContext(ctx, query).Scan(&c.Locale, &c.Version, &c.RecordedVersion); err != nil {
+			return nil, err
+		}
+		collations[c.Name] = c
+	}
+
+	query := `
+		SELECT c.collname, ns.nspname,
+			CASE c.collprovider WHEN 'i' THEN 'icu' WHEN 'c' THEN 'libc' WHEN 'b' THEN 'builtin' ELSE 'default' END,
+			COALESCE(c.collcollate, to_jsonb(c) ->> 'colllocale', to_jsonb(c) ->> 'colliculocale', ''),
+			COALESCE(pg_collation_actual_version(c.oid), ''),
+			COALESCE(c.collversion, '')
+		FROM pg_collation c
+		JOIN pg_namespace ns ON ns.oid = c.collnamespace
+		WHERE c.collprovider <> 'd' AND (
+			c.collnamespace = 'public'::regnamespace
+			OR c.oid IN (
+				SELECT a.attcollation
+				FROM pg_attribute a
+				JOIN pg_class r ON r.oid = a.attrelid
+				JOIN pg_namespace n ON n.oid = r.relnamespace
+				WHERE n.nspname = 'public' AND a.attnum > 0 AND NOT a.attisdropped
+				UNION
+				SELECT unnest(i.indcollation::oid[])
+				FROM pg_index i
+				JOIN pg_class r ON r.oid = i.indrelid
+				JOIN pg_namespace n ON n.oid = r.relnamespace
+				WHERE n.nspname = 'public'
+			)
+		)
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c := &Collation{}
+		if err := rows.Scan(&c.Name, &c.Schema, &c.Provider, &c.Locale, &c.Version, &c.RecordedVersion); err != nil {
+			return nil, err
+		}
+		collations[c.Name] = c
+	}
+	return collations, rows.Err()
+}
+
+// extractRoutines reads the functions and procedures of the public schema.
+// Aggregates and window functions are skipped, as are routines created by
+// extensions unless IncludeExtensionObjects is set.
+func (p *PostgresDialect) extractRoutines(ctx context.Context, db *sql.DB, opts ExtractOptions) (map[string]*Routine, error) {
+	query := `
+		SELECT
+			p.proname,
+			CASE p.prokind WHEN 'p' THEN 'procedure' ELSE 'function' END,
+			pg_get_function_arguments(p.oid),
+			oidvectortypes(p.proargtypes),
+			COALESCE(pg_get_function_result(p.oid), ''),
+			l.lanname,
+			COALESCE(p.prosrc, '')
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		JOIN pg_language l ON l.oid = p.prolang
+		WHERE n.nspname = 'public'
+		  AND p.prokind IN ('f', 'p')
+	`
+	if !opts.IncludeExtensionObjects {
+		query += `
+		  AND NOT EXISTS (
+			SELECT 1
+			FROM pg_depend d
+			WHERE d.classid = 'pg_proc'::regclass
+			  AND d.objid = p.oid
+			  AND d.deptype = 'e'
+		  )
+		`
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	routines := make(map[string]*Routine)
+	for rows.Next() {
+		routine := &Routine{}
+		var argTypes string
+		if err := rows.Scan(&routine.Name, &routine.Kind, &routine.Arguments, &argTypes, &routine.Returns, &routine.Language, &routine.Body); err != nil {
+			return nil, err
+		}
+		routine.Body = normalizeRoutineBody(routine.Body)
+		routines[routineKey(routine.Name, []string{argTypes})] = routine
+	}
+	return routines, rows.Err()
+}
+
+func (p *PostgresDialect) extractAccessMethod(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT COALESCE(am.amname, '')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_am am ON am.oid = c.relam
+		WHERE n.nspname = 'public' AND c.relname = $1
+	`
+	return db.QueryRowContext(ctx, query, tableName).Scan(&table.AccessMethod)
+}
+
+// extractReplicaIdentity reads the REPLICA IDENTITY of a table, with the
+// index it uses for USING INDEX; foreign tables have none
+func (p *PostgresDialect) extractReplicaIdentity(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT CASE WHEN c.relkind = 'f' THEN '' ELSE c.relreplident::text END, COALESCE(i.relname, '')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_index x ON x.indrelid = c.oid AND x.indisreplident
+		LEFT JOIN pg_class i ON i.oid = x.indexrelid
+		WHERE n.nspname = 'public' AND c.relname = $1
+	`
+	var identity, index string
+	if err := db.QueryRowContext(ctx, query, tableName).Scan(&identity, &index); err != nil {
+		return err
+	}
+	switch identity {
+	case "d":
+		table.ReplicaIdentity = ReplicaIdentityDefault
+	case "f":
+		table.ReplicaIdentity = ReplicaIdentityFull
+	case "n":
+		table.ReplicaIdentity = ReplicaIdentityNothing
+	case "i":
+		table.ReplicaIdentity = "index " + index
+	}
+	return nil
+}
+
+// extractPartitioning reads the partition key and partitions of a
+// partitioned table (Postgres 10+), and the parent of a partition
+func (p *PostgresDialect) extractPartitioning(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT
+			CASE WHEN c.relkind = 'p' THEN pg_get_partkeydef(c.oid) ELSE '' END,
+			COALESCE(parent.relname, '')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_inherits i ON i.inhrelid = c.oid AND c.relispartition
+		LEFT JOIN pg_class parent ON parent.oid = i.inhparent
+		WHERE n.nspname = 'public' AND c.relname = $1
+	`
+	var keyDef string
+	if err := db.QueryRowContext(ctx, query, tableName).Scan(&keyDef, &table.PartitionOf); err != nil {
+		return err
+	}
+	if keyDef == "" {
+		return nil
+	}
+	// pg_get_partkeydef renders "RANGE (created_at)"
+	strategy, key, _ := strings.Cut(keyDef, " ")
+	table.Partitioning = &Partitioning{
+		Strategy: strings.ToLower(strategy),
+		Key:      strings.TrimSuffix(strings.TrimPrefix(key, "("), ")"),
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.relname, pg_get_expr(c.relpartbound, c.oid)
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class parent ON parent.oid = i.inhparent
+		JOIN pg_namespace n ON n.oid = parent.relnamespace
+		WHERE n.nspname = 'public' AND parent.relname = $1
+		ORDER BY c.relname
+	`, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var part Partition
+		if err := rows.Scan(&part.Name, &part.Bound); err != nil {
+			return err
+		}
+		table.Partitioning.Partitions = append(table.Partitioning.Partitions, &part)
+	}
+	return rows.Err()
+}
+
+// extractInheritance reads the parents of a table that uses INHERITS, as
+// legacy inheritance-based partitioning does. Parents outside public keep
+// their schema.
+func (p *PostgresDialect) extractInheritance(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT CASE WHEN pn.nspname = 'public' THEN parent.relname ELSE pn.nspname || '.' || parent.relname END
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_class parent ON parent.oid = i.inhparent
+		JOIN pg_namespace pn ON pn.oid = parent.relnamespace
+		WHERE n.nspname = 'public' AND c.relname = $1 AND NOT c.relispartition
+		ORDER BY i.inhseqno
+	`, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var parent string
+		if err := rows.Scan(&parent); err != nil {
+			return err
+		}
+		table.Inherits = append(table.Inherits, parent)
+	}
+	return rows.Err()
+}
+
+// extractRewriteRules reads the rewrite rules of a table from pg_rules,
+// which leaves out the _RETURN rules behind views
+func (p *PostgresDialect) extractRewriteRules(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT rulename, definition
+		FROM pg_rules
+		WHERE schemaname = 'public' AND tablename = $1
+	`, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, definition string
+		if err := rows.Scan(&name, &definition); err != nil {
+			return err
+		}
+		m := ruleDefinitionPattern.FindStringSubmatch(definition)
+		if m == nil {
+			continue
+		}
+		if table.RewriteRules == nil {
+			table.RewriteRules = make(map[string]*RewriteRule)
+		}
+		table.RewriteRules[name] = &RewriteRule{
+			Name:      name,
+			Event:     strings.ToUpper(m[1]),
+			Instead:   strings.EqualFold(m[3], "INSTEAD"),
+			Condition: normalizeRuleText(m[2]),
+			Actions:   normalizeRuleText(m[4]),
+		}
+	}
+	return rows.Err()
+}
+
+// extractForeignTable reads the server and options of a foreign table; it
+// leaves other tables alone
+func (p *PostgresDialect) extractForeignTable(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT s.srvname, COALESCE((
+			SELECT string_agg(o.option_name || '=' || o.option_value, E'\n' ORDER BY o.option_name)
+			FROM pg_options_to_table(ft.ftoptions) o
+		), '')
+		FROM pg_foreign_table ft
+		JOIN pg_class c ON c.oid = ft.ftrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_foreign_server s ON s.oid = ft.ftserver
+		WHERE n.nspname = 'public' AND c.relname = $1
+	`
+	var server, options string
+	switch err := db.QueryRowContext(ctx, query, tableName).Scan(&server, &options); {
+	case err == sql.ErrNoRows:
+		return nil
+	case err != nil:
+		return err
+	}
+	table.Foreign = &ForeignTable{Server: server}
+	if options != "" {
+		table.Foreign.Options = strings.Split(options, "\n")
+	}
+	return nil
+}
+
+// extractForeignServers reads the foreign servers with their wrapper,
+// options and the roles they have user mappings for. The options of the
+// user mappings are never read.
+func (p *PostgresDialect) extractForeignServers(ctx context.Context, db *sql.DB) (map[string]*ForeignServer, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT s.srvname, w.fdwname,
+			COALESCE((
+				SELECT string_agg(o.option_name || '=' || o.option_value, E'\n' ORDER BY o.option_name)
+				FROM pg_options_to_table(s.srvoptions) o
+			), ''),
+			COALESCE((
+				SELECT string_agg(um.usename, E'\n' ORDER BY um.usename)
+				FROM pg_user_mappings um
+				WHERE um.srvid = s.oid
+			), '')
+		FROM pg_foreign_server s
+		JOIN pg_foreign_data_wrapper w ON w.oid = s.srvfdw
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	servers := make(map[string]*ForeignServer)
+	for rows.Next() {
+		var options, mappings string
+		server := &ForeignServer{}
+		if err := rows.Scan(&server.Name, &server.Wrapper, &options, &mappings); err != nil {
+			return nil, err
+		}
+		if options != "" {
+			server.Options = strings.Split(options, "\n")
+		}
+		if mappings != "" {
+			server.UserMappings = strings.Split(mappings, "\n")
+		}
+		servers[server.Name] = server
+	}
+	return servers, rows.Err()
+}
+
+func (p *PostgresDialect) extractTableComment(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `SELECT obj_description(format('public.%I', $1::text)::regclass, 'pg_class')`
+	var comment sql.NullString
+	if err := db.QueryRowContext(ctx, query, tableName).Scan(&comment); err != nil {
+		return err
+	}
+	table.Comment = comment.String
+	return nil
+}
+
+func (p *PostgresDialect) extractPrimaryKey(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT
+			tc.constraint_name,
+			array_agg(kcu.column_name ORDER BY kcu.ordinal_position) as columns
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_name = kcu.constraint_name
+			AND tc.table_schema = kcu.table_schema
+		WHERE tc.table_schema = 'public'
+		  AND tc.table_name = $1
+		  AND tc.constraint_type = 'PRIMARY KEY'
+		GROUP BY tc.constraint_name
+	`
+	var name string
+	var columns string
+	err := db.QueryRowContext(ctx, query, tableName).Scan(&name, &columns)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Parse PostgreSQL array format: {col1,col2}
+	cols := strings.Trim(columns, "{}")
+	if cols != "" {
+		table.PrimaryKey = &PrimaryKey{
+			Name:    name,
+			Columns: strings.Split(cols, ","),
+		}
+	}
+	return nil
+}
+
+func (p *PostgresDialect) extractForeignKeys(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT
+			tc.constraint_name,
+			array_agg(kcu.column_name ORDER BY kcu.ordinal_position) as columns,
+			ccu.table_name AS foreign_table_name,
+			array_agg(ccu.column_name ORDER BY kcu.ordinal_position) as foreign_columns,
+			rc.update_rule,
+			rc.delete_rule,
+			(
+				SELECT NOT con.convalidated
+				FROM pg_constraint con
+				WHERE con.conrelid = format('public.%I', tc.table_name)::regclass
+				  AND con.conname = tc.constraint_name
+			) AS not_valid
+		FROM information_schema.table_constraints AS tc
+		JOIN information_schema.key_column_usage AS kcu
+			ON tc.constraint_name = kcu.constraint_name
+			AND tc.table_schema = kcu.table_schema
+		JOIN information_schema.constraint_column_usage AS ccu
+			ON ccu.constraint_name = tc.constraint_name
+			AND ccu.table_schema = tc.table_schema
+		JOIN information_schema.referential_constraints AS rc
+			ON rc.constraint_name = tc.constraint_name
+			AND rc.constraint_schema = tc.table_schema
+		WHERE tc.table_schema = 'public'
+		  AND tc.table_name = $1
+		  AND tc.constraint_type = 'FOREIGN KEY'
+		GROUP BY tc.constraint_name, tc.table_name, ccu.table_name, rc.update_rule, rc.delete_rule
+	`
+	rows, err := db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, columns, refTable, refColumns, updateRule, deleteRule string
+		var notValid bool
+		if err := rows.Scan(&name, &columns, &refTable, &refColumns, &updateRule, &deleteRule, &notValid); err != nil {
+			return err
+		}
+
+		cols := strings.Trim(columns, "{}")
+		refCols := strings.Trim(refColumns, "{}")
+
+		fk := &ForeignKey{
+			Name:       name,
+			Columns:    strings.Split(cols, ","),
+			RefTable:   refTable,
+			RefColumns: strings.Split(refCols, ","),
+			OnUpdate:   updateRule,
+			OnDelete:   deleteRule,
+		}
+		if notValid {
+			fk.Status = ConstraintNotValid
+		}
+		table.ForeignKeys[name] = fk
+	}
+	return rows.Err()
+}
+
+func (p *PostgresDialect) extractUniqueConstraints(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT
+			tc.constraint_name,
+			array_agg(kcu.column_name ORDER BY kcu.ordinal_position) as columns
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_name = kcu.constraint_name
+			AND tc.table_schema = kcu.table_schema
+		WHERE tc.table_schema = 'public'
+		  AND tc.table_name = $1
+		  AND tc.constraint_type = 'UNIQUE'
+		GROUP BY tc.constraint_name
+	`
+	rows, err := db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, columns string
+		if err := rows.Scan(&name, &columns); err != nil {
+			return err
+		}
+
+		cols := strings.Trim(columns, "{}")
+		uniq := &Unique{
+			Name:    name,
+			Columns: strings.Split(cols, ","),
+		}
+		table.UniqueConstraints[name] = uniq
+	}
+	return rows.Err()
+}
+
+func (p *PostgresDialect) extractIndexes(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	// One row per index column; indoption and indclass only cover the key
+	// columns, so INCLUDE columns come without an operator class
+	query := `
+		SELECT
+			i.relname as index_name,
+			ix.indisunique,
+			am.amname,
+			coalesce(array_to_string(i.reloptions, ','), ''),
+			coalesce(a.attname, pg_get_indexdef(i.oid, k.n::int, true)),
+			coalesce(format_type(a.atttypid, a.atttypmod), ''),
+			k.opclass IS NOT NULL as is_key,
+			coalesce(k.option, 0),
+			coalesce(CASE WHEN opc.opcdefault THEN '' ELSE opc.opcname END, '')
+		FROM pg_class t
+		JOIN pg_index ix ON t.oid = ix.indrelid
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_am am ON am.oid = i.relam
+		CROSS JOIN LATERAL unnest(ix.indkey::int2[], ix.indoption::int2[], ix.indclass::oid[])
+			WITH ORDINALITY AS k(attnum, option, opclass, n)
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum AND k.attnum > 0
+		LEFT JOIN pg_opclass opc ON opc.oid = k.opclass
+		LEFT JOIN pg_constraint c ON c.conindid = i.oid
+		WHERE t.relname = $1
+		  AND t.relkind IN ('r', 'p')
+		  AND c.contype IS NULL  -- Exclude constraint-backed indexes
+		ORDER BY i.relname, k.n
+	`
+	rows, err := db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	keyTypes := make(map[string]string)
+	for rows.Next() {
+		var name, method, options, column, columnType, opClass string
+		var isUnique, isKey bool
+		var option int
+		if err := rows.Scan(&name, &isUnique, &method, &options, &column, &columnType, &isKey, &option, &opClass); err != nil {
+			return err
+		}
+		keyTypes[column] = columnType
+
+		idx, ok := table.Indexes[name]
+		if !ok {
+			idx = &Index{Name: name, IsUnique: isUnique, Method: method}
+			for _, opt := range strings.Split(options, ",") {
+				if key, value, ok := strings.Cut(opt, "="); ok {
+					if idx.Options == nil {
+						idx.Options = make(map[string]string)
+					}
+					idx.Options[key] = value
+				}
+			}
+			table.Indexes[name] = idx
+		}
+		if !isKey {
+			idx.Include = append(idx.Include, column)
+			continue
+		}
+		idx.Columns = append(idx.Columns, column)
+		idx.OpClasses = append(idx.OpClasses, opClass)
+		idx.Orders = append(idx.Orders, indexOrder(option&1 != 0, option&2 != 0))
+	}
+	for _, idx := range table.Indexes {
+		idx.OpClasses = compactList(idx.OpClasses)
+		idx.Orders = compactList(idx.Orders)
+		idx.Kind = classifyIndex(idx, func(key string) string { return keyTypes[key] })
+	}
+	return rows.Err()
+}
+
+func (p *PostgresDialect) extractCheckConstraints(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT
+			con.conname as constraint_name,
+			pg_get_constraintdef(con.oid) as check_clause
+		FROM pg_constraint con
+		JOIN pg_class rel ON rel.oid = con.conrelid
+		WHERE rel.relname = $1
+		  AND con.contype = 'c'
+	`
+	rows, err := db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, expr string
+		if err := rows.Scan(&name, &expr); err != nil {
+			return err
+		}
+
+		check := &CheckConstr{Name: name}
+		check.Expression, check.Status = pgConstraintStatus(expr)
+		table.CheckConstraints[name] = check
+	}
+	return rows.Err()
+}
+
+// pgTriggerDefPattern splits pg_get_triggerdef output into the optional WHEN
+// condition and the function call
+var pgTriggerDefPattern = regexp.MustCompile(`(?s)(?: WHEN \((.*)\))? EXECUTE (?:FUNCTION|PROCEDURE) (.*)$`)
+
+// pgTriggerStatus maps pg_trigger.tgenabled to a trigger status
+var pgTriggerStatus = map[string]string{
+	"O": "",
+	"D": TriggerDisabled,
+	"R": TriggerReplica,
+	"A": TriggerAlways,
+}
+
+func (p *PostgresDialect) extractTriggers(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT t.tgname, t.tgtype, t.tgenabled, pg_get_triggerdef(t.oid)
+		FROM pg_trigger t
+		JOIN pg_class c ON c.oid = t.tgrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public'
+		  AND c.relname = $1
+		  AND NOT t.tgisinternal
+	`
+	rows, err := db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, enabled, def string
+		var tgtype int
+		if err := rows.Scan(&name, &tgtype, &enabled, &def); err != nil {
+			return err
+		}
+
+		trigger := &Trigger{Name: name, Timing: "AFTER", Level: "STATEMENT", Status: pgTriggerStatus[enabled]}
+		// tgtype bits, see TRIGGER_TYPE_* in pg_trigger.h
+		switch {
+		case tgtype&2 != 0:
+			trigger.Timing = "BEFORE"
+		case tgtype&64 != 0:
+			trigger.Timing = "INSTEAD OF"
+		}
+		if tgtype&1 != 0 {
+			trigger.Level = "ROW"
+		}
+		for _, event := range []struct {
+			bit  int
+			name string
+		}{{4, "INSERT"}, {16, "UPDATE"}, {8, "DELETE"}, {32, "TRUNCATE"}} {
+			if tgtype&event.bit != 0 {
+				trigger.Events = append(trigger.Events, event.name)
+			}
+		}
+		if m := pgTriggerDefPattern.FindStringSubmatch(def); m != nil {
+			trigger.Condition, trigger.Function = m[1], m[2]
+		}
+		table.Triggers[name] = trigger
+	}
+	return rows.Err()
+}
+
+// pgConstraintStatus splits the NOT VALID or NOT ENFORCED (Postgres 18)
+// suffix of a pg_get_constraintdef definition into a constraint status
+func pgConstraintStatus(def string) (string, string) {
+	if d, ok := strings.CutSuffix(def, " NOT VALID"); ok {
+		return d, ConstraintNotValid
+	}
+	if d, ok := strings.CutSuffix(def, " NOT ENFORCED"); ok {
+		return d, ConstraintNotEnforced
+	}
+	return def, ""
+}
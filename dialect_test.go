@@ -0,0 +1,34 @@
+package dbdiff
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimitQueriesSharesOneLimiter(t *testing.T) {
+	ctx, stop := limitQueries(context.Background(), 100)
+	defer stop()
+	if inner, _ := limitQueries(ctx, 100); inner != ctx {
+		t.Error("a limited context got a second limiter")
+	}
+	start := time.Now()
+	for range 3 {
+		if err := waitQuery(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("3 queries at 100 qps took %v", elapsed)
+	}
+	if err := waitQuery(context.Background()); err != nil {
+		t.Errorf("unlimited context: %v", err)
+	}
+
+	// Rates too high for a nanosecond interval mustn't panic
+	fast, stopFast := limitQueries(context.Background(), 1e12)
+	defer stopFast()
+	if err := waitQuery(fast); err != nil {
+		t.Errorf("1e12 qps: %v", err)
+	}
+}
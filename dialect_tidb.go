@@ -0,0 +1,94 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// TIDB DIALECT
+// ============================================================================
+
+// TiDBDialect extracts TiDB schemas: the MySQL catalog plus TiDB's table
+// options. The mysql driver detects TiDB as well; the tidb driver insists on
+// it, so a misconfigured connection does not silently compare a MySQL server.
+type TiDBDialect struct {
+	MySQLDialect
+}
+
+// TiDB table option keys
+const (
+	TiDBClusteredIndex  = "tidb_clustered_index"
+	TiDBPlacementPolicy = "tidb_placement_policy"
+	TiDBRowIDSharding   = "tidb_row_id_sharding"
+)
+
+// isTiDB reports whether the server is TiDB, whose VERSION() reads like
+// "8.0.11-TiDB-v7.5.0"
+func isTiDB(ctx context.Context, db *sql.DB) (bool, error) {
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		return false, err
+	}
+	return strings.Contains(version, "-TiDB-"), nil
+}
+
+func (t *TiDBDialect) ExtractSchema(ctx context.Context, db *sql.DB, opts ExtractOptions) (*Schema, error) {
+	ctx, stop := limitQueries(ctx, opts.MaxQPS)
+	defer stop()
+	tables, steps, err := t.extractionPlan(ctx, db, opts)
+	if err != nil {
+		return nil, err
+	}
+	dbs, err := t.databases(ctx, db, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{}
+	if schema.Tables, schema.NotComparable, err = runExtractionPlan(ctx, tables, steps, opts); err != nil {
+		return nil, err
+	}
+	if err := t.extractSchemaObjects(ctx, db, dbs, schema, opts); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (t *TiDBDialect) extractionPlan(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]string, []extractStep, error) {
+	tidb, err := isTiDB(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !tidb {
+		return nil, nil, fmt.Errorf("server is not TiDB; use the mysql driver")
+	}
+	return t.MySQLDialect.extractionPlan(ctx, db, opts)
+}
+
+// extractTiDBOptions reads whether the primary key is the clustered index,
+// the placement policy and how implicit row IDs are sharded
+func extractTiDBOptions(ctx context.Context, db *sql.DB, dbName, tableName string, table *Table) error {
+	query := `
+		SELECT tidb_pk_type, tidb_placement_policy_name, tidb_row_id_sharding_info
+		FROM information_schema.tables
+		WHERE table_schema = ? AND table_name = ?
+	`
+	var pkType, policy, sharding sql.NullString
+	err := db.QueryRowContext(ctx, query, dbName, tableName).Scan(&pkType, &policy, &sharding)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	table.Options = map[string]string{
+		TiDBClusteredIndex:  strings.ToLower(pkType.String),
+		TiDBPlacementPolicy: policy.String,
+		TiDBRowIDSharding:   sharding.String,
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// YUGABYTEDB DIALECT
+// ============================================================================
+
+// YugabyteDialect extracts YugabyteDB schemas: the Postgres catalog plus how
+// each table is sharded into tablets. The postgres driver detects YugabyteDB
+// as well; the yugabyte driver insists on it.
+type YugabyteDialect struct {
+	PostgresDialect
+}
+
+// YugabyteDB table option keys
+const (
+	YugabyteColocated = "yb_colocated"
+	YugabyteSharding  = "yb_sharding"
+	YugabyteSplit     = "yb_split"
+)
+
+// isYugabyte reports whether the server is YugabyteDB, whose version() reads
+// like "PostgreSQL 11.2-YB-2.20.1.0-b0 on x86_64-pc-linux-gnu, ..."
+func isYugabyte(ctx context.Context, db *sql.DB) (bool, error) {
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT version()").Scan(&version); err != nil {
+		return false, err
+	}
+	return strings.Contains(version, "-YB-"), nil
+}
+
+func (y *YugabyteDialect) ExtractSchema(ctx context.Context, db *sql.DB, opts ExtractOptions) (*Schema, error) {
+	if err := requireYugabyte(ctx, db); err != nil {
+		return nil, err
+	}
+	return y.PostgresDialect.ExtractSchema(ctx, db, opts)
+}
+
+func (y *YugabyteDialect) extractionPlan(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]string, []extractStep, error) {
+	if err := requireYugabyte(ctx, db); err != nil {
+		return nil, nil, err
+	}
+	return y.PostgresDialect.extractionPlan(ctx, db, opts)
+}
+
+func requireYugabyte(ctx context.Context, db *sql.DB) error {
+	yb, err := isYugabyte(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !yb {
+		return fmt.Errorf("server is not YugabyteDB; use the postgres driver")
+	}
+	return nil
+}
+
+// extractYugabyteOptions reads whether a table is colocated and, if not,
+// whether it is hash or range sharded and how it was split. Colocated tables
+// share the database's single tablet, so they have no split of their own.
+func extractYugabyteOptions(ctx context.Context, db *sql.DB, tableName string, table *Table) error {
+	query := `
+		SELECT p.is_colocated, p.num_hash_key_columns, p.num_tablets,
+		       CASE WHEN NOT p.is_colocated AND p.num_hash_key_columns = 0
+		            THEN yb_get_range_split_clause(c.oid) END
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		CROSS JOIN LATERAL yb_table_properties(c.oid) p
+		WHERE n.nspname = 'public' AND c.relname = $1
+	`
+	var colocated bool
+	var hashColumns, tablets int64
+	var rangeSplit sql.NullString
+	err := db.QueryRowContext(ctx, query, tableName).Scan(&colocated, &hashColumns, &tablets, &rangeSplit)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if colocated {
+		table.Options = map[string]string{YugabyteColocated: "true"}
+		return nil
+	}
+	table.Options = map[string]string{YugabyteColocated: "false"}
+	if hashColumns > 0 {
+		table.Options[YugabyteSharding] = "hash"
+		table.Options[YugabyteSplit] = fmt.Sprintf("SPLIT INTO %d TABLETS", tablets)
+	} else {
+		table.Options[YugabyteSharding] = "range"
+		table.Options[YugabyteSplit] = rangeSplit.String
+	}
+	return nil
+}
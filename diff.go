@@ -0,0 +1,260 @@
+package dbdiff
+
+// ============================================================================
+// DIFF - Difference representation
+// ============================================================================
+
+type SchemaDiff struct {
+	TablesOnlyInSource []string      `json:"tables_only_in_source,omitempty"`
+	TablesOnlyInTarget []string      `json:"tables_only_in_target,omitempty"`
+	TableDiffs         []*TableDiff  `json:"table_diffs,omitempty"`
+	SuppressedTables   []string      `json:"suppressed_tables,omitempty"`
+	RuleResults        []*RuleResult `json:"rule_results,omitempty"`
+	Annotations        []*Annotation `json:"annotations,omitempty"`
+	// FilteredFindings counts differences hidden by filters, normalization
+	// and suppression; see CountFilteredFindings
+	FilteredFindings int `json:"filtered_findings,omitempty"`
+	// SettingDiffs are the server settings selected with --compare-settings
+	// that differ
+	SettingDiffs []*SettingDiff `json:"setting_diffs,omitempty"`
+	// GrantDiffs are the privileges and role memberships that differ, with
+	// --compare-grants
+	GrantDiffs []*GrantDiff `json:"grant_diffs,omitempty"`
+	// PartitionGroups are the time-suffixed tables compared as one table
+	// with --match-partitions
+	PartitionGroups []*PartitionGroup `json:"partition_groups,omitempty"`
+	// ChangeSets group the findings that likely came from one migration;
+	// set with --group-changes, see GroupChangeSets
+	ChangeSets []*ChangeSet `json:"change_sets,omitempty"`
+	// SensitiveChanges are the differences of columns tagged sensitive; see
+	// SensitiveColumnChanges
+	SensitiveChanges []*SensitiveChange `json:"sensitive_changes,omitempty"`
+	// DefaultWarnings explain default differences that session settings
+	// can cause; see SessionDependentDefaults
+	DefaultWarnings []*DefaultWarning `json:"default_warnings,omitempty"`
+	// CollationWarnings are the collations whose library versions differ;
+	// see CollationVersionWarnings
+	CollationWarnings []*CollationWarning `json:"collation_warnings,omitempty"`
+	// AppendOnlyWarnings are the destructive changes to the tables the
+	// config file marks append-only; see AppendOnlyWarnings
+	AppendOnlyWarnings []*AppendOnlyWarning `json:"append_only_warnings,omitempty"`
+	// AppendOnlyRows compare the append-only tables by row count and highest
+	// key, with --compare-append-only
+	AppendOnlyRows []*AppendOnlyRows `json:"append_only_rows,omitempty"`
+	// Orphans are the dangling references within each side, with
+	// --report-orphans; see OrphanReport
+	Orphans []*OrphanedObject `json:"orphans,omitempty"`
+	// NotComparable are the objects left out because one side could not
+	// read them; see SetAsideNotComparable
+	NotComparable []*NotComparable `json:"not_comparable,omitempty"`
+	// Direction is the side the diff changes, with --direction; in a
+	// target-to-source diff "source" names the target
+	Direction string `json:"direction,omitempty"`
+
+	TypesOnlyInSource []string    `json:"types_only_in_source,omitempty"`
+	TypesOnlyInTarget []string    `json:"types_only_in_target,omitempty"`
+	TypeDiffs         []*TypeDiff `json:"type_diffs,omitempty"`
+
+	SequencesOnlyInSource []string        `json:"sequences_only_in_source,omitempty"`
+	SequencesOnlyInTarget []string        `json:"sequences_only_in_target,omitempty"`
+	SequenceDiffs         []*SequenceDiff `json:"sequence_diffs,omitempty"`
+
+	RoutinesOnlyInSource []string       `json:"routines_only_in_source,omitempty"`
+	RoutinesOnlyInTarget []string       `json:"routines_only_in_target,omitempty"`
+	RoutineDiffs         []*RoutineDiff `json:"routine_diffs,omitempty"`
+
+	EventsOnlyInSource []string     `json:"events_only_in_source,omitempty"`
+	EventsOnlyInTarget []string     `json:"events_only_in_target,omitempty"`
+	EventDiffs         []*EventDiff `json:"event_diffs,omitempty"`
+
+	ExtensionsOnlyInSource []string         `json:"extensions_only_in_source,omitempty"`
+	ExtensionsOnlyInTarget []string         `json:"extensions_only_in_target,omitempty"`
+	ExtensionDiffs         []*ExtensionDiff `json:"extension_diffs,omitempty"`
+
+	ForeignServersOnlyInSource []string             `json:"foreign_servers_only_in_source,omitempty"`
+	ForeignServersOnlyInTarget []string             `json:"foreign_servers_only_in_target,omitempty"`
+	ForeignServerDiffs         []*ForeignServerDiff `json:"foreign_server_diffs,omitempty"`
+
+	// SourceOnly and TargetOnly carry the full definitions of the objects
+	// named in the *OnlyInSource and *OnlyInTarget lists
+	SourceOnly *MissingObjects `json:"source_only,omitempty"`
+	TargetOnly *MissingObjects `json:"target_only,omitempty"`
+}
+
+// MissingObjects holds the definitions of objects that exist on one side
+// only, keyed like the name lists, so that tooling reading a report (and
+// GenerateMigrationSQL) can recreate them without extracting again
+type MissingObjects struct {
+	Tables         map[string]*Table         `json:"tables,omitempty"`
+	Types          map[string]*UserType      `json:"types,omitempty"`
+	Sequences      map[string]*Sequence      `json:"sequences,omitempty"`
+	Routines       map[string]*Routine       `json:"routines,omitempty"`
+	Events         map[string]*Event         `json:"events,omitempty"`
+	Extensions     map[string]*Extension     `json:"extensions,omitempty"`
+	ForeignServers map[string]*ForeignServer `json:"foreign_servers,omitempty"`
+}
+
+// MissingTableObjects holds the definitions of the columns, constraints,
+// indexes and triggers of a table that exist on one side only
+type MissingTableObjects struct {
+	Columns           map[string]*Column      `json:"columns,omitempty"`
+	ForeignKeys       map[string]*ForeignKey  `json:"foreign_keys,omitempty"`
+	UniqueConstraints map[string]*Unique      `json:"unique_constraints,omitempty"`
+	Indexes           map[string]*Index       `json:"indexes,omitempty"`
+	CheckConstraints  map[string]*CheckConstr `json:"check_constraints,omitempty"`
+	Triggers          map[string]*Trigger     `json:"triggers,omitempty"`
+}
+
+// TypeDiff is an enum or domain present on both sides with a different
+// definition
+type TypeDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+// SequenceDiff is a sequence present on both sides with different settings
+type SequenceDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+// RoutineDiff is a function or procedure present on both sides with a
+// different definition; Name is its signature
+type RoutineDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+// EventDiff is a scheduled event present on both sides with a different
+// schedule, status or body
+type EventDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+// ExtensionDiff is an extension installed on both sides in different
+// versions
+type ExtensionDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+// ForeignServerDiff is a foreign server present on both sides with a
+// different wrapper, options or user mappings
+type ForeignServerDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+// SettingDiff is a server setting with different values on both sides
+type SettingDiff struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+type TableDiff struct {
+	TableName                string             `json:"table_name"`
+	ColumnsOnlyInSource      []string           `json:"columns_only_in_source,omitempty"`
+	ColumnsOnlyInTarget      []string           `json:"columns_only_in_target,omitempty"`
+	ColumnDiffs              []*ColumnDiff      `json:"column_diffs,omitempty"`
+	PrimaryKeyDiff           *string            `json:"primary_key_diff,omitempty"`
+	PrimaryKeyChanges        []AttributeChange  `json:"primary_key_changes,omitempty"`
+	ForeignKeysOnlyInSource  []string           `json:"foreign_keys_only_in_source,omitempty"`
+	ForeignKeysOnlyInTarget  []string           `json:"foreign_keys_only_in_target,omitempty"`
+	ForeignKeyDiffs          []*FKDiff          `json:"foreign_key_diffs,omitempty"`
+	UniquesOnlyInSource      []string           `json:"uniques_only_in_source,omitempty"`
+	UniquesOnlyInTarget      []string           `json:"uniques_only_in_target,omitempty"`
+	UniqueDiffs              []*UniqueDiff      `json:"unique_diffs,omitempty"`
+	IndexesOnlyInSource      []string           `json:"indexes_only_in_source,omitempty"`
+	IndexesOnlyInTarget      []string           `json:"indexes_only_in_target,omitempty"`
+	IndexDiffs               []*IndexDiff       `json:"index_diffs,omitempty"`
+	ChecksOnlyInSource       []string           `json:"checks_only_in_source,omitempty"`
+	ChecksOnlyInTarget       []string           `json:"checks_only_in_target,omitempty"`
+	CheckDiffs               []*CheckDiff       `json:"check_diffs,omitempty"`
+	TriggersOnlyInSource     []string           `json:"triggers_only_in_source,omitempty"`
+	TriggersOnlyInTarget     []string           `json:"triggers_only_in_target,omitempty"`
+	TriggerDiffs             []*TriggerDiff     `json:"trigger_diffs,omitempty"`
+	RewriteRulesOnlyInSource []string           `json:"rewrite_rules_only_in_source,omitempty"`
+	RewriteRulesOnlyInTarget []string           `json:"rewrite_rules_only_in_target,omitempty"`
+	RewriteRuleDiffs         []*RewriteRuleDiff `json:"rewrite_rule_diffs,omitempty"`
+	CommentDiff              *string            `json:"comment_diff,omitempty"`
+	AccessMethodDiff         *string            `json:"access_method_diff,omitempty"`
+	ReplicaIdentityDiff      *string            `json:"replica_identity_diff,omitempty"`
+	OptionsDiff              *string            `json:"options_diff,omitempty"`
+	PartitioningDiff         *string            `json:"partitioning_diff,omitempty"`
+	InheritanceDiff          *string            `json:"inheritance_diff,omitempty"`
+	ForeignTableDiff         *string            `json:"foreign_table_diff,omitempty"`
+	// Changes are the table-level attribute changes rendered in CommentDiff
+	// through ForeignTableDiff
+	Changes []AttributeChange `json:"changes,omitempty"`
+	// SourceOnly and TargetOnly carry the definitions of the objects named
+	// in the *OnlyInSource and *OnlyInTarget lists
+	SourceOnly *MissingTableObjects `json:"source_only,omitempty"`
+	TargetOnly *MissingTableObjects `json:"target_only,omitempty"`
+}
+
+// AttributeChange is one attribute of an object that differs between the
+// source and the target, with the values as reported. Key names the entry of
+// a map-valued attribute such as an index's options, or the line of a body.
+type AttributeChange struct {
+	Attribute string `json:"attribute"`
+	Key       string `json:"key,omitempty"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Note      string `json:"note,omitempty"` // e.g. "capacity change: 255 → 191 characters"
+
+	// quoted renders From and To with %q, for values that may contain "; "
+	// or " → "; oneSided renders only the side that is set, as in "added: [id]"
+	quoted, oneSided bool
+}
+
+// The diffs of objects present on both sides carry both the rendered Diff of
+// the reports and its Changes one attribute at a time
+type ColumnDiff struct {
+	ColumnName string            `json:"column_name"`
+	Diff       string            `json:"diff"`
+	Changes    []AttributeChange `json:"changes,omitempty"`
+}
+
+type FKDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+type UniqueDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+type IndexDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+type CheckDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+type TriggerDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+type RewriteRuleDiff struct {
+	Name    string            `json:"name"`
+	Diff    string            `json:"diff"`
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
@@ -0,0 +1,168 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// DURATION ESTIMATES - Rough run times of migration statements
+// ============================================================================
+
+// tableSizer is implemented by dialects that can report the on-disk size
+// of tables, indexes included
+type tableSizer interface {
+	tableSizes(ctx context.Context, db *sql.DB) (map[string]int64, error)
+}
+
+func (p *PostgresDialect) tableSizes(ctx context.Context, db *sql.DB) (map[string]int64, error) {
+	// A partitioned table has no storage of its own; its partitions do
+	query := `
+		SELECT c.relname,
+			CASE WHEN c.relkind = 'p'
+				THEN COALESCE((SELECT sum(pg_total_relation_size(t.relid)) FROM pg_partition_tree(c.oid) t), 0)
+				ELSE pg_total_relation_size(c.oid)
+			END::bigint
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p', 'm')
+	`
+	return scanTableSizes(ctx, db, query)
+}
+
+func (m *MySQLDialect) tableSizes(ctx context.Context, db *sql.DB) (map[string]int64, error) {
+	query := `
+		SELECT table_name, COALESCE(data_length, 0) + COALESCE(index_length, 0)
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'
+	`
+	return scanTableSizes(ctx, db, query)
+}
+
+func scanTableSizes(ctx context.Context, db *sql.DB, query string) (map[string]int64, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sizes := make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var size int64
+		if err := rows.Scan(&name, &size); err != nil {
+			return nil, err
+		}
+		sizes[name] = size
+	}
+	return sizes, rows.Err()
+}
+
+// loadTableSizes reads the table sizes of a live database
+func loadTableSizes(driver, conn string) (map[string]int64, error) {
+	sizer, ok := getDialect(driver).(tableSizer)
+	if !ok {
+		return nil, fmt.Errorf("reading table sizes is not supported for %s", driver)
+	}
+	db, err := openDB(driver, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return sizer.tableSizes(context.Background(), db)
+}
+
+// lockWorkThroughput is how many bytes of table per second each kind of
+// work gets through: deliberately conservative figures for a busy server
+// on network storage
+var lockWorkThroughput = map[string]float64{
+	LockWorkRewrite:    50 << 20,
+	LockWorkIndexBuild: 100 << 20,
+	LockWorkScan:       300 << 20,
+}
+
+var lockWorkVerbs = map[string]string{
+	LockWorkRewrite:    "rewrites",
+	LockWorkIndexBuild: "indexes",
+	LockWorkScan:       "scans",
+}
+
+// durationBucket rounds an estimate to the granularity maintenance windows
+// are planned in; anything finer would be false precision
+func durationBucket(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "seconds"
+	case d < time.Hour:
+		return "minutes"
+	}
+	return "hours"
+}
+
+// formatBytes renders a size with a binary unit, e.g. "~200 GB"
+func formatBytes(n int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	size, unit := float64(n), 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	if size < 10 && unit > 0 {
+		return fmt.Sprintf("~%.1f %s", size, units[unit])
+	}
+	return fmt.Sprintf("~%.0f %s", size, units[unit])
+}
+
+// EstimateDurations annotates the statements of a migration script that
+// rewrite, index or scan a table with a rough duration, computed from the
+// size of the table in the database the script runs on, e.g.
+//
+//	-- Estimated duration: rewrites ~200 GB of orders: hours
+//	-- ALTER TABLE orders ALTER COLUMN total ...;  -- type: integer → bigint
+//
+// Statements of tables without a known size are left alone. schemas resolve
+// the tables of statements that don't name one, as for LockMatrix.
+func EstimateDurations(script, driver string, sizes map[string]int64, schemas ...*Schema) string {
+	reqs, err := LockMatrix(script, driver, schemas...)
+	if err != nil {
+		return script
+	}
+	notes := make(map[int]string)
+	for _, req := range reqs {
+		size, ok := sizes[req.Table]
+		if req.Work == "" || !ok {
+			continue
+		}
+		d := time.Duration(float64(size) / lockWorkThroughput[req.Work] * float64(time.Second))
+		notes[req.Line] = fmt.Sprintf("-- Estimated duration: %s %s of %s: %s", lockWorkVerbs[req.Work], formatBytes(size), req.Table, durationBucket(d))
+	}
+	if len(notes) == 0 {
+		return script
+	}
+
+	lines := strings.Split(script, "\n")
+	// The assumptions go at the end of the script's header comment
+	header := 0
+	for header < len(lines) && strings.HasPrefix(lines[header], "--") {
+		header++
+	}
+	var sb strings.Builder
+	for i, line := range lines {
+		if i == header {
+			fmt.Fprintf(&sb, "-- Durations are rough estimates from table sizes at %s/s for rewrites, %s/s for index builds and %s/s for scans\n",
+				strings.TrimPrefix(formatBytes(int64(lockWorkThroughput[LockWorkRewrite])), "~"),
+				strings.TrimPrefix(formatBytes(int64(lockWorkThroughput[LockWorkIndexBuild])), "~"),
+				strings.TrimPrefix(formatBytes(int64(lockWorkThroughput[LockWorkScan])), "~"))
+		}
+		if note, ok := notes[i]; ok {
+			sb.WriteString(note + "\n")
+		}
+		sb.WriteString(line)
+		if i < len(lines)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,98 @@
+package dbdiff
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ============================================================================
+// FAKE DRIVER - Built-in fixtures for testing pipelines
+// ============================================================================
+
+// fakeFixtures are the schemas served by the fake driver, as DDL. shop-drifted
+// is shop after a typical round of drift: a column added and one retyped, an
+// index dropped, a check constraint loosened and a table added.
+var fakeFixtures = map[string]string{
+	"empty": "",
+	"shop": `
+		CREATE TABLE customers (
+			id integer PRIMARY KEY,
+			email varchar(255) NOT NULL UNIQUE,
+			name text,
+			created_at timestamp NOT NULL DEFAULT now()
+		);
+		CREATE INDEX customers_created_at_idx ON customers (created_at);
+		COMMENT ON TABLE customers IS 'Registered customers';
+		CREATE TABLE products (
+			id integer PRIMARY KEY,
+			sku varchar(32) NOT NULL UNIQUE,
+			price numeric(10,2) NOT NULL CHECK (price >= 0)
+		);
+		CREATE TABLE orders (
+			id integer PRIMARY KEY,
+			customer_id integer NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+			status varchar(20) NOT NULL DEFAULT 'new',
+			total numeric(10,2) NOT NULL
+		);
+		CREATE INDEX orders_customer_id_idx ON orders (customer_id);
+		CREATE TABLE order_items (
+			order_id integer NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+			product_id integer NOT NULL REFERENCES products (id),
+			quantity integer NOT NULL CHECK (quantity > 0),
+			PRIMARY KEY (order_id, product_id)
+		);
+	`,
+	"shop-drifted": `
+		CREATE TABLE customers (
+			id integer PRIMARY KEY,
+			email varchar(255) NOT NULL UNIQUE,
+			name varchar(100),
+			phone text,
+			created_at timestamp NOT NULL DEFAULT now()
+		);
+		COMMENT ON TABLE customers IS 'Registered customers';
+		CREATE TABLE products (
+			id integer PRIMARY KEY,
+			sku varchar(32) NOT NULL UNIQUE,
+			price numeric(10,2) NOT NULL CHECK (price > -1)
+		);
+		CREATE TABLE orders (
+			id integer PRIMARY KEY,
+			customer_id integer NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+			status varchar(20) NOT NULL DEFAULT 'new',
+			total numeric(10,2) NOT NULL
+		);
+		CREATE INDEX orders_customer_id_idx ON orders (customer_id);
+		CREATE TABLE order_items (
+			order_id integer NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+			product_id integer NOT NULL REFERENCES products (id),
+			quantity integer NOT NULL CHECK (quantity > 0),
+			PRIMARY KEY (order_id, product_id)
+		);
+		CREATE TABLE coupons (
+			code varchar(16) PRIMARY KEY,
+			percent_off integer NOT NULL
+		);
+	`,
+}
+
+// fakeUnreachable is a fake "connection" that always fails, for testing how
+// pipelines handle errors
+const fakeUnreachable = "unreachable"
+
+// loadFakeSchema returns a built-in fixture by name, or reads a snapshot or
+// DDL file when conn is a path
+func loadFakeSchema(conn string) (*Schema, error) {
+	if conn == fakeUnreachable {
+		return nil, fmt.Errorf("connecting: fake database %q is unreachable", conn)
+	}
+	if ddl, ok := fakeFixtures[conn]; ok {
+		return ParseDDL(ddl)
+	}
+	if _, err := os.Stat(conn); err != nil {
+		return nil, fmt.Errorf("unknown fake fixture %q (built-in: %s, %s, or a snapshot/.sql file)",
+			conn, strings.Join(getSortedKeys(fakeFixtures), ", "), fakeUnreachable)
+	}
+	return loadSchema(schemaFileDriver(conn), conn, ExtractOptions{})
+}
@@ -0,0 +1,206 @@
+package dbdiff
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ============================================================================
+// FILTER CONFIG - Filtering options
+// ============================================================================
+
+type FilterConfig struct {
+	OnlyTables         []string            // If set, exact table names to compare; all others are ignored
+	IgnoreTables       []string            // Exact table names to ignore
+	IgnoreTablePattern *regexp.Regexp      // Regex pattern for table names to ignore
+	IgnoreTableGlobs   []string            // Table name globs (awsdms_*) to ignore, from --exclude-preset
+	IgnoreColumns      map[string][]string // Map of table -> columns to ignore
+	IgnoreIndexes      bool                // Ignore all index differences
+	IgnoreForeignKeys  bool                // Ignore all foreign key differences
+	IgnoreChecks       bool                // Ignore all check constraint differences
+	IgnoreDefaults     bool                // Ignore column default value differences
+	IgnoreTriggers     bool                // Ignore all trigger differences
+	IgnoreRoutines     bool                // Ignore all function and procedure differences
+	MatchPartitions    bool                // Compare time-suffixed tables (events_2024_05) as one table per name pattern
+	CollapsePartitions bool                // Compare declarative partitions only through their parent's partition list
+
+	NormalizeTypes        bool // Compare types case-insensitively and resolve common aliases (int4 = integer)
+	NormalizeDefaults     bool // Strip casts, quotes and parentheses from default values before comparing
+	IgnoreConstraintNames bool // Pair constraints/indexes whose definitions match even if their names differ
+	MatchByDefinition     bool // Pair constraints/indexes covering the same columns or expression, and compare the rest
+	NormalizeInvisiblePK  bool // Treat MySQL generated invisible primary keys as absent
+	// TypeEquivalence maps a normalized type to its equivalence class; types
+	// of the same class compare equal (json = jsonb across engines)
+	TypeEquivalence map[string]string
+
+	IgnoreComments          bool // Ignore table and column comment differences
+	IgnoreCommentOnlyTables bool // Suppress tables whose only differences are comments
+}
+
+// Comparison profiles accepted by --profile
+const (
+	ProfileStrict   = "strict"
+	ProfileStandard = "standard"
+	ProfileLenient  = "lenient"
+)
+
+// ApplyProfile sets the normalization options bundled by a named profile.
+// strict compares values byte-for-byte, standard tolerates cosmetic type
+// spelling differences, and lenient additionally normalizes defaults and
+// ignores constraint/index names.
+func (fc *FilterConfig) ApplyProfile(name string) error {
+	switch name {
+	case ProfileStrict:
+		fc.NormalizeTypes = false
+		fc.NormalizeDefaults = false
+		fc.IgnoreConstraintNames = false
+		fc.NormalizeInvisiblePK = false
+	case ProfileStandard:
+		fc.NormalizeTypes = true
+		fc.NormalizeDefaults = false
+		fc.IgnoreConstraintNames = false
+		fc.NormalizeInvisiblePK = true
+	case ProfileLenient:
+		fc.NormalizeTypes = true
+		fc.NormalizeDefaults = true
+		fc.IgnoreConstraintNames = true
+		fc.NormalizeInvisiblePK = true
+	default:
+		return fmt.Errorf("unknown profile %q (expected strict, standard or lenient)", name)
+	}
+	return nil
+}
+
+func NewFilterConfig() *FilterConfig {
+	return &FilterConfig{
+		IgnoreTables:  []string{},
+		IgnoreColumns: make(map[string][]string),
+	}
+}
+
+func (fc *FilterConfig) ShouldIgnoreTable(tableName string) bool {
+	if len(fc.OnlyTables) > 0 {
+		listed := false
+		for _, t := range fc.OnlyTables {
+			if t == tableName {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			return true
+		}
+	}
+	return fc.ignoredByName(tableName)
+}
+
+// ignoredByName reports whether a name is on the ignore lists: IgnoreTables,
+// IgnoreTablePattern and IgnoreTableGlobs
+func (fc *FilterConfig) ignoredByName(name string) bool {
+	// Check exact matches
+	for _, t := range fc.IgnoreTables {
+		if t == name {
+			return true
+		}
+	}
+	// Check pattern
+	if fc.IgnoreTablePattern != nil && fc.IgnoreTablePattern.MatchString(name) {
+		return true
+	}
+	for _, glob := range fc.IgnoreTableGlobs {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldIgnoreType reports whether a user type is left out. Types are
+// matched by name against the ignore lists, but --only-tables, which names
+// tables, keeps them all.
+func (fc *FilterConfig) ShouldIgnoreType(ut *UserType) bool {
+	return fc.ignoredByName(ut.Name)
+}
+
+// ShouldIgnoreExtension reports whether an extension is left out; like
+// types, extensions follow the ignore lists but not --only-tables
+func (fc *FilterConfig) ShouldIgnoreExtension(ext *Extension) bool {
+	return fc.ignoredByName(ext.Name)
+}
+
+// excludePresets are the built-in table lists of --exclude-preset. The
+// config file can redefine them or add its own under exclude_presets.
+var excludePresets = map[string][]string{
+	// Bookkeeping tables of migration tools and frameworks, and of AWS DMS
+	"migrations-tools": {
+		"schema_migrations", "ar_internal_metadata", // Rails, golang-migrate
+		"flyway_schema_history", "schema_version", // Flyway
+		"databasechangelog", "databasechangeloglock", // Liquibase
+		"django_migrations", "alembic_version",
+		"knex_migrations", "knex_migrations_lock",
+		"goose_db_version", "gorp_migrations", "SequelizeMeta",
+		"__EFMigrationsHistory", "_prisma_migrations",
+		"awsdms_*",
+	},
+}
+
+// ExcludePreset returns the table globs of a named preset, preferring the
+// config file's definition over the built-in one
+func (c *Config) ExcludePreset(name string) ([]string, error) {
+	if globs, ok := c.ExcludePresets[name]; ok {
+		return globs, nil
+	}
+	if globs, ok := excludePresets[name]; ok {
+		return globs, nil
+	}
+	names := getSortedKeys(excludePresets)
+	for name := range c.ExcludePresets {
+		if _, ok := excludePresets[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	return nil, fmt.Errorf("unknown exclude preset %q (expected %s)", name, strings.Join(names, ", "))
+}
+
+// ShouldIgnoreSequence applies the table filters to a sequence: owned
+// sequences follow their table, free-standing ones the ignore lists by name
+// (--only-tables names tables, not sequences)
+func (fc *FilterConfig) ShouldIgnoreSequence(seq *Sequence) bool {
+	if table, _, ok := splitOwnedBy(seq.OwnedBy); ok {
+		return fc.ShouldIgnoreTable(table)
+	}
+	return fc.ignoredByName(seq.Name)
+}
+
+// ShouldIgnoreRoutine reports whether a function or procedure is left out:
+// all of them with IgnoreRoutines, otherwise by name on the ignore lists;
+// --only-tables names tables and keeps them all
+func (fc *FilterConfig) ShouldIgnoreRoutine(routine *Routine) bool {
+	return fc.IgnoreRoutines || fc.ignoredByName(routine.Name)
+}
+
+// ShouldIgnoreEvent reports whether a scheduled event is left out; events
+// follow the ignore lists by name but not --only-tables
+func (fc *FilterConfig) ShouldIgnoreEvent(event *Event) bool {
+	return fc.ignoredByName(event.Name)
+}
+
+// ShouldIgnoreForeignServer reports whether a foreign server is left out;
+// servers follow the ignore lists by name but not --only-tables, which would
+// otherwise drop the servers of the listed foreign tables
+func (fc *FilterConfig) ShouldIgnoreForeignServer(server *ForeignServer) bool {
+	return fc.ignoredByName(server.Name)
+}
+
+func (fc *FilterConfig) ShouldIgnoreColumn(tableName, columnName string) bool {
+	if cols, ok := fc.IgnoreColumns[tableName]; ok {
+		for _, c := range cols {
+			if c == columnName {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package dbdiff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// FINGERPRINT - Stable hash of a normalized schema
+// ============================================================================
+
+// CanonicalSchema renders schema as sorted lines, one per object, that only
+// change when the schema does. Objects ignored by filter are left out and its
+// normalizations are applied; with IgnoreConstraintNames or MatchByDefinition
+// constraint and index names are replaced by "-".
+func CanonicalSchema(schema *Schema, filter *FilterConfig) []string {
+	var lines []string
+	add := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	objectName := func(name string) string {
+		if filter.IgnoreConstraintNames || filter.MatchByDefinition {
+			return "-"
+		}
+		return name
+	}
+	typeName := func(t string) string {
+		if filter.NormalizeTypes {
+			return normalizeType(t)
+		}
+		return t
+	}
+	defaultValue := func(d *string) string {
+		if d == nil || filter.IgnoreDefaults {
+			return ""
+		}
+		if filter.NormalizeDefaults {
+			return fmt.Sprintf(" default %q", normalizeDefault(*d))
+		}
+		return fmt.Sprintf(" default %q", *d)
+	}
+	comment := func(c string) string {
+		if filter.IgnoreComments || c == "" {
+			return ""
+		}
+		return fmt.Sprintf(" comment %q", c)
+	}
+	status := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return " " + s
+	}
+
+	for name, ut := range schema.Types {
+		if filter.ShouldIgnoreType(ut) {
+			continue
+		}
+		attributes := ""
+		if len(ut.Attributes) > 0 {
+			attributes = fmt.Sprintf(" attributes %q", typeAttributeList(ut.Attributes))
+		}
+		add("type %s %s labels %q base %q not_null %v%s checks %q%s", name, ut.Kind, ut.Labels, typeName(ut.BaseType), ut.NotNull, defaultValue(ut.Default), ut.Checks, attributes)
+	}
+	for name, seq := range schema.Sequences {
+		if filter.ShouldIgnoreSequence(seq) {
+			continue
+		}
+		add("sequence %s %s start %d increment %d min %d max %d cache %d cycle %v owned_by %q",
+			name, typeName(seq.DataType), seq.Start, seq.Increment, seq.MinValue, seq.MaxValue, seq.Cache, seq.Cycle, seq.OwnedBy)
+	}
+	for name, ext := range schema.Extensions {
+		if filter.ShouldIgnoreExtension(ext) {
+			continue
+		}
+		add("extension %s version %q", name, ext.Version)
+	}
+	for name, routine := range schema.Routines {
+		if filter.ShouldIgnoreRoutine(routine) {
+			continue
+		}
+		add("routine %s %s arguments %q returns %q language %s body %q",
+			name, routine.Kind, routine.Arguments, routine.Returns, strings.ToLower(routine.Language), routine.Body)
+	}
+	for name, event := range schema.Events {
+		if filter.ShouldIgnoreEvent(event) {
+			continue
+		}
+		add("event %s schedule %q status %s on_completion %q body %q", name, event.Schedule, event.Status, event.OnCompletion, event.Body)
+	}
+	for name, server := range schema.ForeignServers {
+		if filter.ShouldIgnoreForeignServer(server) {
+			continue
+		}
+		add("foreign server %s wrapper %s options %q user mappings %v", name, server.Wrapper, server.Options, server.UserMappings)
+	}
+	for name, table := range schema.Tables {
+		if filter.ShouldIgnoreTable(name) {
+			continue
+		}
+		line := "table " + name + comment(table.Comment)
+		if table.AccessMethod != "" {
+			line += " using " + table.AccessMethod
+		}
+		// The default identity is left out so that older snapshots, which
+		// don't record it, keep their canonical form
+		if table.ReplicaIdentity != "" && table.ReplicaIdentity != ReplicaIdentityDefault {
+			line += " replica identity " + table.ReplicaIdentity
+		}
+		for _, key := range getSortedKeys(table.Options) {
+			line += fmt.Sprintf(" option %s=%q", key, table.Options[key])
+		}
+		if table.Partitioning != nil {
+			line += " partition by " + partitionScheme(table.Partitioning)
+		}
+		if table.PartitionOf != "" {
+			line += " partition of " + table.PartitionOf
+		}
+		if len(table.Inherits) > 0 {
+			line += fmt.Sprintf(" inherits %v", table.Inherits)
+		}
+		if table.Foreign != nil {
+			line += fmt.Sprintf(" foreign server %s options %q", table.Foreign.Server, table.Foreign.Options)
+		}
+		add("%s", line)
+		if table.Partitioning != nil {
+			bounds := partitionBounds(table.Partitioning)
+			for _, part := range getSortedKeys(bounds) {
+				add("partition %s.%s %s", name, part, normalizeBound(bounds[part]))
+			}
+		}
+
+		gipk := filter.NormalizeInvisiblePK && table.PrimaryKey != nil && table.PrimaryKey.Invisible
+		for colName, col := range table.Columns {
+			if filter.ShouldIgnoreColumn(name, colName) || (gipk && isGIPKColumn(table, colName)) {
+				continue
+			}
+			storage := ""
+			if col.LargeObject {
+				storage = " large_object"
+			}
+			if col.IsGenerated {
+				expr := col.GenerationExpression
+				if filter.NormalizeDefaults {
+					expr = normalizeDefault(expr)
+				}
+				storage += fmt.Sprintf(" generated %s %q", col.GenerationStorage, expr)
+			}
+			if col.Identity != "" {
+				storage += " " + col.Identity
+			}
+			var def *string
+			if d := serialDefault(col); d != "" {
+				def = &d
+			}
+			colType := columnTypeName(col)
+			if col.UserType == "" {
+				colType = pgColumnType(col)
+			}
+			add("column %s.%s %s%s nullable %v%s%s", name, colName, typeName(colType), storage, col.IsNullable, defaultValue(def), comment(col.Comment))
+		}
+		if pk := table.PrimaryKey; pk != nil && !gipk {
+			add("primary_key %s %s %v", name, objectName(pk.Name), pk.Columns)
+		}
+		if !filter.IgnoreForeignKeys {
+			for _, fk := range table.ForeignKeys {
+				add("foreign_key %s %s %v references %s %v on delete %s on update %s%s", name, objectName(fk.Name), fk.Columns, fk.RefTable, fk.RefColumns, fk.OnDelete, fk.OnUpdate, status(fk.Status))
+			}
+		}
+		for _, uq := range table.UniqueConstraints {
+			add("unique %s %s %v", name, objectName(uq.Name), uq.Columns)
+		}
+		if !filter.IgnoreIndexes {
+			for _, idx := range table.Indexes {
+				line := fmt.Sprintf("index %s %s %v unique %v", name, objectName(idx.Name), idx.Columns, idx.IsUnique)
+				if idx.Kind != "" {
+					line += " " + idx.Kind
+				}
+				if idx.Method != "" {
+					line += " using " + idx.Method
+				}
+				if idx.OpClasses != nil {
+					line += fmt.Sprintf(" op_classes %q", idx.OpClasses)
+				}
+				if idx.Orders != nil {
+					line += fmt.Sprintf(" orders %q", idx.Orders)
+				}
+				if idx.PrefixLengths != nil {
+					line += fmt.Sprintf(" prefix_lengths %q", idx.PrefixLengths)
+				}
+				if len(idx.Include) > 0 {
+					line += fmt.Sprintf(" include %v", idx.Include)
+				}
+				for _, key := range getSortedKeys(idx.Options) {
+					line += fmt.Sprintf(" option %s=%q", key, idx.Options[key])
+				}
+				add("%s", line)
+			}
+		}
+		if !filter.IgnoreChecks {
+			for _, chk := range table.CheckConstraints {
+				add("check %s %s %q%s", name, objectName(chk.Name), chk.Expression, status(chk.Status))
+			}
+		}
+		if !filter.IgnoreTriggers {
+			for _, trg := range table.Triggers {
+				add("trigger %s %s %s %v %s when %q function %q body %q%s", name, trg.Name, trg.Timing, trg.Events, trg.Level,
+					trg.Condition, trg.Function, strings.Join(strings.Fields(trg.Body), " "), status(trg.Status))
+			}
+		}
+		for _, rule := range table.RewriteRules {
+			add("rule %s %s on %s instead %v where %q do %q", name, rule.Name, rule.Event, rule.Instead, rule.Condition, rule.Actions)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// Fingerprint returns "sha256:" followed by the hex SHA-256 of the canonical
+// form of schema (see CanonicalSchema)
+func Fingerprint(schema *Schema, filter *FilterConfig) string {
+	sum := sha256.Sum256([]byte(strings.Join(CanonicalSchema(schema, filter), "\n")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func runFingerprint(args []string) {
+	fs := flag.NewFlagSet("fingerprint", flag.ExitOnError)
+	conn := fs.String("source", "", "Database connection string, or snapshot/ddl file")
+	driver := fs.String("source-driver", "", "Database driver (postgres, yugabyte, mysql, tidb, sqlserver, oracle, firebird, bigquery, snapshot, ddl or fake)")
+	expect := fs.String("expect", "", "Fingerprint the schema must have; exits with 2 if it differs")
+	canonical := fs.Bool("canonical", false, "Print the canonical form the fingerprint is computed from")
+	parallel := fs.Bool("parallel", false, "Use parallel schema extraction")
+	filterOpts := addFilterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff fingerprint --source <conn> --source-driver <driver> [--expect sha256:...] [--canonical] [filter options]")
+		fmt.Fprintln(os.Stderr, "\nPrints a stable hash of the schema. The same filter and normalization options must")
+		fmt.Fprintln(os.Stderr, "be used when producing and checking a fingerprint.")
+	}
+	fs.Parse(args)
+
+	if *conn == "" || *driver == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	filter, err := filterOpts.build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts := filterOpts.extractOptions(filter)
+	if *parallel {
+		opts.Concurrency = parallelExtractConcurrency
+	}
+
+	schema, err := loadSchema(*driver, *conn, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading schema: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *canonical {
+		for _, line := range CanonicalSchema(schema, filter) {
+			fmt.Println(line)
+		}
+		return
+	}
+
+	fingerprint := Fingerprint(schema, filter)
+	fmt.Println(fingerprint)
+	if *expect != "" && *expect != fingerprint {
+		fmt.Fprintf(os.Stderr, "Schema fingerprint mismatch: expected %s\n", *expect)
+		os.Exit(2)
+	}
+}
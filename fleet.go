@@ -0,0 +1,537 @@
+package dbdiff
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// FLEET - Golden schema audit across many tenant databases
+// ============================================================================
+
+// Tenant statuses in a fleet report
+const (
+	TenantPass  = "pass"
+	TenantFail  = "fail"
+	TenantError = "error"
+)
+
+// FleetTarget is one tenant database listed in --targets
+type FleetTarget struct {
+	Name   string `yaml:"name" json:"name"`
+	Driver string `yaml:"driver" json:"driver"`
+	DSN    string `yaml:"dsn" json:"dsn"`
+}
+
+// FleetTargets is the on-disk format of --targets (YAML or JSON). Tenants are
+// listed statically under targets, discovered at run time, or both.
+type FleetTargets struct {
+	Targets  []FleetTarget    `yaml:"targets" json:"targets"`
+	Discover []FleetDiscovery `yaml:"discover" json:"discover"`
+}
+
+// FleetDiscovery lists tenant names at run time, either with a query on a
+// database server or from an HTTP endpoint. DSN is a template in which {name}
+// is replaced by each discovered tenant name.
+type FleetDiscovery struct {
+	Driver string `yaml:"driver" json:"driver"`
+	DSN    string `yaml:"dsn" json:"dsn"`
+
+	// Query mode: run Query (default: list all user databases) on Connect
+	Connect string `yaml:"connect" json:"connect"`
+	Query   string `yaml:"query" json:"query"`
+
+	// HTTP mode: GET URL, which returns a JSON array of names or of
+	// {name, driver, dsn} objects (missing driver/dsn come from this entry)
+	URL     string            `yaml:"url" json:"url"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+
+	// Match keeps only names matching this regex
+	Match string `yaml:"match" json:"match"`
+}
+
+// TenantResult is the outcome of comparing one tenant against the template.
+// The tenant is the diff source and the template the target, so Missing are
+// template tables the tenant lacks and Extra are tables only the tenant has.
+type TenantResult struct {
+	Name       string      `json:"name"`
+	Status     string      `json:"status"`
+	Error      string      `json:"error,omitempty"`
+	Missing    int         `json:"missing_tables"`
+	Extra      int         `json:"extra_tables"`
+	Changed    int         `json:"changed_tables"`
+	Findings   int         `json:"findings"`
+	Breaking   int         `json:"breaking"`
+	DurationMS int64       `json:"duration_ms"`
+	Diff       *SchemaDiff `json:"diff,omitempty"`
+}
+
+// FleetDrift counts how many tenants share a finding
+type FleetDrift struct {
+	Key     string `json:"key"`
+	Change  string `json:"change"`
+	Tenants int    `json:"tenants"`
+}
+
+// FleetReport is the aggregate result of a fleet audit
+type FleetReport struct {
+	Template string          `json:"template"`
+	Tenants  []*TenantResult `json:"tenants"`
+	Passed   int             `json:"passed"`
+	Failed   int             `json:"failed"`
+	Errored  int             `json:"errored"`
+	Drift    []FleetDrift    `json:"common_drift"`
+}
+
+// Default discovery queries listing the user databases of a server
+var defaultDiscoveryQueries = map[string]string{
+	"postgres":  "SELECT datname FROM pg_database WHERE NOT datistemplate AND datallowconn ORDER BY datname",
+	"mysql":     "SELECT schema_name FROM information_schema.schemata WHERE schema_name NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys') ORDER BY schema_name",
+	"tidb":      "SELECT schema_name FROM information_schema.schemata WHERE schema_name NOT IN ('mysql', 'information_schema', 'performance_schema', 'metrics_schema', 'sys') ORDER BY schema_name",
+	"yugabyte":  "SELECT datname FROM pg_database WHERE NOT datistemplate AND datallowconn AND datname NOT IN ('system_platform', 'yugabyte') ORDER BY datname",
+	"sqlserver": "SELECT name FROM sys.databases WHERE database_id > 4 AND state = 0 ORDER BY name",
+}
+
+// discoveryTimeout bounds discovery queries and HTTP requests
+const discoveryTimeout = 30 * time.Second
+
+// LoadFleetTargets reads the tenant list and runs any discovery it declares.
+// DSNs, URLs and headers may reference environment variables like in the
+// config file.
+func LoadFleetTargets(path string) ([]FleetTarget, error) {
+	data, err := readTextFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file FleetTargets
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	// Discovered DSNs are expanded by discover, before tenant names are
+	// substituted, so a name can't reference a variable
+	targets := file.Targets
+	for i := range targets {
+		targets[i].DSN = os.ExpandEnv(targets[i].DSN)
+	}
+	for i, d := range file.Discover {
+		discovered, err := d.discover()
+		if err != nil {
+			return nil, fmt.Errorf("%s: discover #%d: %w", path, i+1, err)
+		}
+		targets = append(targets, discovered...)
+	}
+
+	seen := make(map[string]bool)
+	for i := range targets {
+		t := &targets[i]
+		if t.Name == "" || t.Driver == "" || t.DSN == "" {
+			return nil, fmt.Errorf("%s: target #%d needs name, driver and dsn", path, i+1)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("%s: duplicate target %q", path, t.Name)
+		}
+		seen[t.Name] = true
+	}
+	return targets, nil
+}
+
+// discover returns one target per discovered tenant name
+func (d FleetDiscovery) discover() ([]FleetTarget, error) {
+	var match *regexp.Regexp
+	if d.Match != "" {
+		var err error
+		if match, err = regexp.Compile(d.Match); err != nil {
+			return nil, fmt.Errorf("invalid match regex: %v", err)
+		}
+	}
+
+	var found []FleetTarget
+	var err error
+	switch {
+	case d.URL != "" && d.Connect != "":
+		return nil, fmt.Errorf("use either url or connect, not both")
+	case d.URL != "":
+		found, err = d.discoverHTTP()
+	case d.Connect != "":
+		found, err = d.discoverQuery()
+	default:
+		return nil, fmt.Errorf("needs url or connect")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []FleetTarget
+	for _, t := range found {
+		if match != nil && !match.MatchString(t.Name) {
+			continue
+		}
+		if t.Driver == "" {
+			t.Driver = d.Driver
+		}
+		if t.DSN == "" {
+			t.DSN = fillDSNTemplate(os.ExpandEnv(d.DSN), t.Driver, t.Name)
+		} else {
+			t.DSN = os.ExpandEnv(t.DSN)
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// fillDSNTemplate replaces each {name} in a DSN template with a tenant name,
+// escaped for where it appears: query-escaped in URL or MySQL parameters,
+// path-escaped in the rest of a URL or MySQL DSN, and quoted as a value of a
+// Postgres or SQL Server key=value DSN
+func fillDSNTemplate(template, driver, name string) string {
+	var sb strings.Builder
+	rest := template
+	for {
+		before, after, found := strings.Cut(rest, "{name}")
+		sb.WriteString(before)
+		if !found {
+			return sb.String()
+		}
+		prefix := template[:len(template)-len(rest)+len(before)]
+		switch {
+		case strings.Contains(prefix, "?"):
+			sb.WriteString(url.QueryEscape(name))
+		case strings.Contains(prefix, "://") || engineFamily(driver) == "mysql":
+			sb.WriteString(url.PathEscape(name))
+		case engineFamily(driver) == "sqlserver":
+			sb.WriteString(`"` + strings.ReplaceAll(name, `"`, `""`) + `"`)
+		default:
+			sb.WriteString("'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(name) + "'")
+		}
+		rest = after
+	}
+}
+
+func (d FleetDiscovery) discoverQuery() ([]FleetTarget, error) {
+	query := d.Query
+	if query == "" {
+		query = defaultDiscoveryQueries[d.Driver]
+		if query == "" {
+			return nil, fmt.Errorf("no default discovery query for driver %q; set query", d.Driver)
+		}
+	}
+
+	db, err := openDB(d.Driver, os.ExpandEnv(d.Connect))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []FleetTarget
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		targets = append(targets, FleetTarget{Name: name})
+	}
+	return targets, rows.Err()
+}
+
+func (d FleetDiscovery) discoverHTTP() ([]FleetTarget, error) {
+	req, err := http.NewRequest(http.MethodGet, os.ExpandEnv(d.URL), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range d.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+
+	client := &http.Client{Timeout: discoveryTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", req.URL.Redacted(), resp.Status)
+	}
+
+	var items []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("GET %s: expected a JSON array: %w", req.URL.Redacted(), err)
+	}
+	var targets []FleetTarget
+	for _, item := range items {
+		var t FleetTarget
+		if err := json.Unmarshal(item, &t.Name); err != nil {
+			if err := json.Unmarshal(item, &t); err != nil {
+				return nil, fmt.Errorf("GET %s: items must be names or {name, driver, dsn} objects", req.URL.Redacted())
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// auditTenant extracts one tenant schema and diffs it against the template
+func auditTenant(template *Schema, target FleetTarget, filter *FilterConfig, opts ExtractOptions) *TenantResult {
+	start := time.Now()
+	result := &TenantResult{Name: target.Name}
+	defer func() { result.DurationMS = time.Since(start).Milliseconds() }()
+
+	fail := func(err error) *TenantResult {
+		result.Status = TenantError
+		result.Error = err.Error()
+		return result
+	}
+
+	dialect := getDialect(target.Driver)
+	if dialect == nil {
+		return fail(fmt.Errorf("unsupported driver %q", target.Driver))
+	}
+	db, err := openDB(target.Driver, target.DSN)
+	if err != nil {
+		return fail(err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		return fail(err)
+	}
+
+	schema, err := dialect.ExtractSchema(context.Background(), db, opts)
+	if err != nil {
+		return fail(err)
+	}
+
+	diff := ComputeDiff(schema, template, filter)
+	SortDiff(diff, SortByName)
+	res := NewResult(diff)
+	result.Missing = len(diff.TablesOnlyInTarget)
+	result.Extra = len(diff.TablesOnlyInSource)
+	result.Changed = len(diff.TableDiffs)
+	for _, f := range res.Findings() {
+		result.Findings++
+		if f.Breaking() {
+			result.Breaking++
+		}
+	}
+	result.Status = TenantPass
+	if res.HasChanges() {
+		result.Status = TenantFail
+		result.Diff = diff
+	}
+	return result
+}
+
+// RunFleet audits every target against the template with at most concurrency
+// tenants in flight. Results keep the order of targets.
+func RunFleet(template *Schema, targets []FleetTarget, filter *FilterConfig, concurrency int, opts ExtractOptions) *FleetReport {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	report := &FleetReport{Tenants: make([]*TenantResult, len(targets))}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, target FleetTarget) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			report.Tenants[i] = auditTenant(template, target, filter, opts)
+		}(i, target)
+	}
+	wg.Wait()
+
+	drift := make(map[string]*FleetDrift)
+	for _, tenant := range report.Tenants {
+		switch tenant.Status {
+		case TenantPass:
+			report.Passed++
+		case TenantFail:
+			report.Failed++
+		default:
+			report.Errored++
+		}
+		if tenant.Diff == nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, f := range NewResult(tenant.Diff).Findings() {
+			id := f.Key() + " " + f.Change
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if drift[id] == nil {
+				drift[id] = &FleetDrift{Key: f.Key(), Change: f.Change}
+			}
+			drift[id].Tenants++
+		}
+	}
+	for _, d := range drift {
+		report.Drift = append(report.Drift, *d)
+	}
+	sort.Slice(report.Drift, func(i, j int) bool {
+		if report.Drift[i].Tenants != report.Drift[j].Tenants {
+			return report.Drift[i].Tenants > report.Drift[j].Tenants
+		}
+		if report.Drift[i].Key != report.Drift[j].Key {
+			return report.Drift[i].Key < report.Drift[j].Key
+		}
+		return report.Drift[i].Change < report.Drift[j].Change
+	})
+	return report
+}
+
+func printFleetReport(w io.Writer, report *FleetReport, topDrift int) {
+	fmt.Fprintf(w, "Fleet audit: %d tenant(s) against %s\n", len(report.Tenants), report.Template)
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+
+	nameWidth := len("Tenant")
+	for _, t := range report.Tenants {
+		if len(t.Name) > nameWidth {
+			nameWidth = len(t.Name)
+		}
+	}
+	fmt.Fprintf(w, "%-*s  %-6s %8s %6s %8s %9s %9s\n", nameWidth, "Tenant", "Status", "Missing", "Extra", "Changed", "Findings", "Breaking")
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+	for _, t := range report.Tenants {
+		status := strings.ToUpper(t.Status)
+		if t.Status == TenantError {
+			fmt.Fprintf(w, "%-*s  %-6s %s\n", nameWidth, t.Name, status, t.Error)
+			continue
+		}
+		fmt.Fprintf(w, "%-*s  %-6s %8d %6d %8d %9d %9d\n", nameWidth, t.Name, status, t.Missing, t.Extra, t.Changed, t.Findings, t.Breaking)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+	fmt.Fprintf(w, "%d passed, %d failed, %d error(s)\n", report.Passed, report.Failed, report.Errored)
+
+	if len(report.Drift) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nMost common drift:")
+	for i, d := range report.Drift {
+		if topDrift > 0 && i == topDrift {
+			fmt.Fprintf(w, "  ... and %d more\n", len(report.Drift)-topDrift)
+			break
+		}
+		fmt.Fprintf(w, "  %4d tenant(s)  %s (%s)\n", d.Tenants, d.Key, d.Change)
+	}
+}
+
+func runFleet(args []string) {
+	fs := flag.NewFlagSet("fleet", flag.ExitOnError)
+	templatePath := fs.String("template", "", "Golden schema snapshot (see dbdiff snapshot)")
+	targetsPath := fs.String("targets", "", "YAML or JSON file listing tenant databases")
+	concurrency := fs.Int("concurrency", 8, "Maximum number of tenants audited at once")
+	format := fs.String("format", FormatPretty, "Output format: pretty or json")
+	top := fs.Int("top", 10, "Number of most common drift findings listed in the pretty report (0 for all)")
+	listTargets := fs.Bool("list-targets", false, "Print the static and discovered targets and exit")
+	configPath := fs.String("config", "", "Config file with ticket settings (default: discovered)")
+	createTicket := fs.String("create-ticket", "", "Open or update a jira or servicenow ticket per drifted tenant (see tickets in --config)")
+	filterOpts := addFilterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff fleet --template golden.json --targets targets.yaml [--concurrency 8] [--format pretty|json] [filter options]")
+		fmt.Fprintln(os.Stderr, "\nCompares every tenant database in --targets against the golden schema and")
+		fmt.Fprintln(os.Stderr, "reports a per-tenant pass/fail matrix plus the drift most tenants share.")
+		fmt.Fprintln(os.Stderr, "Filter and comparison options of the main command (--profile, --ignore-tables, ...) apply.")
+		fmt.Fprintln(os.Stderr, "Targets may be listed statically or discovered from a database server or HTTP endpoint;")
+		fmt.Fprintln(os.Stderr, "use --list-targets to check what discovery finds.")
+		fmt.Fprintln(os.Stderr, "With --create-ticket jira|servicenow, every drifted tenant gets a ticket; reruns update the")
+		fmt.Fprintln(os.Stderr, "open ticket of an unchanged drift instead of opening a new one.")
+		fmt.Fprintln(os.Stderr, "\nExit codes: 0 all tenants pass, 1 a tenant could not be audited, 2 drift found.")
+	}
+	fs.Parse(args)
+
+	if *targetsPath == "" || (*templatePath == "" && !*listTargets) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != FormatPretty && *format != FormatJSON {
+		fmt.Fprintf(os.Stderr, "Invalid format: %s (expected pretty or json)\n", *format)
+		os.Exit(1)
+	}
+
+	filter, err := filterOpts.build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var tickets ticketSystem
+	if *createTicket != "" {
+		cfg, err := LoadConfig(*configPath)
+		if err == nil {
+			tickets, err = newTicketSystem(*createTicket, cfg.Tickets)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	targets, err := LoadFleetTargets(*targetsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading targets: %v\n", err)
+		os.Exit(1)
+	}
+	if *listTargets {
+		for _, t := range targets {
+			fmt.Printf("%s\t%s\n", t.Name, t.Driver)
+		}
+		return
+	}
+
+	template, err := LoadSnapshot(*templatePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading template: %v\n", err)
+		os.Exit(1)
+	}
+
+	report := RunFleet(template, targets, filter, *concurrency, filterOpts.extractOptions(filter))
+	report.Template = *templatePath
+
+	if *format == FormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		printFleetReport(os.Stdout, report, *top)
+	}
+
+	if tickets != nil {
+		for _, tenant := range report.Tenants {
+			if tenant.Status == TenantFail {
+				reportTicket(tickets, *createTicket, "tenant "+tenant.Name, tenant.Diff)
+			}
+		}
+	}
+
+	switch {
+	case report.Errored > 0:
+		os.Exit(1)
+	case report.Failed > 0:
+		os.Exit(2)
+	}
+}
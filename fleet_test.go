@@ -0,0 +1,167 @@
+package dbdiff
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTargets(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "targets.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFleetTargets(t *testing.T) {
+	t.Setenv("DBDIFF_TEST_PASSWORD", "s3cret")
+	targets, err := LoadFleetTargets(writeTargets(t, `targets:
+  - name: acme
+    driver: postgres
+    dsn: postgres://app:${DBDIFF_TEST_PASSWORD}@db/acme
+  - name: globex
+    driver: mysql
+    dsn: app@tcp(db)/globex
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0].Name != "acme" || targets[1].Driver != "mysql" {
+		t.Fatalf("targets = %+v, want acme and globex", targets)
+	}
+	if targets[0].DSN != "postgres://app:s3cret@db/acme" {
+		t.Errorf("dsn = %q, want the password expanded", targets[0].DSN)
+	}
+
+	for content, want := range map[string]string{
+		"targets:\n  - name: acme\n    driver: postgres\n":                                                   "needs name, driver and dsn",
+		"targets:\n  - {name: acme, driver: postgres, dsn: a}\n  - {name: acme, driver: postgres, dsn: b}\n": `duplicate target "acme"`,
+	} {
+		if _, err := LoadFleetTargets(writeTargets(t, content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadFleetTargets(%q) = %v, want %q", content, err, want)
+		}
+	}
+}
+
+func TestRunFleetReportsTenantsThatCannotBeAudited(t *testing.T) {
+	template := &Schema{Tables: map[string]*Table{"users": newTable("users")}}
+	targets := []FleetTarget{
+		{Name: "acme", Driver: "sqlite", DSN: "file:acme"},
+		{Name: "globex", Driver: "sqlite", DSN: "file:globex"},
+	}
+	report := RunFleet(template, targets, NewFilterConfig(), 1, ExtractOptions{})
+	if report.Errored != 2 || report.Passed != 0 || report.Failed != 0 {
+		t.Fatalf("report = %+v, want both tenants errored", report)
+	}
+	for i, tenant := range report.Tenants {
+		if tenant.Name != targets[i].Name || tenant.Status != TenantError || !strings.Contains(tenant.Error, "unsupported driver") {
+			t.Errorf("tenant %d = %+v, want %s errored on its driver", i, tenant, targets[i].Name)
+		}
+	}
+}
+
+func TestPrintFleetReportListsCommonDrift(t *testing.T) {
+	report := &FleetReport{
+		Template: "golden.json",
+		Tenants: []*TenantResult{
+			{Name: "acme", Status: TenantPass},
+			{Name: "globex", Status: TenantFail, Missing: 1, Findings: 2, Breaking: 1},
+			{Name: "initech", Status: TenantError, Error: "connection refused"},
+		},
+		Passed: 1, Failed: 1, Errored: 1,
+		Drift: []FleetDrift{
+			{Key: "table:audit", Change: "only_in_target", Tenants: 1},
+			{Key: "column:users.email", Change: "changed", Tenants: 1},
+		},
+	}
+	var out bytes.Buffer
+	printFleetReport(&out, report, 1)
+	for _, want := range []string{
+		"Fleet audit: 3 tenant(s) against golden.json",
+		"initech  ERROR  connection refused",
+		"1 passed, 1 failed, 1 error(s)",
+		"table:audit (only_in_target)",
+		"... and 1 more",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestLoadFleetTargetsDiscoversOverHTTP(t *testing.T) {
+	t.Setenv("DBDIFF_TEST_TOKEN", "s3cret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `["tenant_a", "internal", "tenant_b", {"name": "tenant_c", "driver": "mysql", "dsn": "app@tcp(other)/c"}]`)
+	}))
+	defer server.Close()
+
+	targets, err := LoadFleetTargets(writeTargets(t, `targets:
+  - name: legacy
+    driver: postgres
+    dsn: postgres://app@old/legacy
+discover:
+  - url: `+server.URL+`
+    headers:
+      Authorization: Bearer ${DBDIFF_TEST_TOKEN}
+    driver: postgres
+    dsn: postgres://app@db/{name}
+    match: ^tenant_
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, target := range targets {
+		got = append(got, target.Name+" "+target.Driver+" "+target.DSN)
+	}
+	want := []string{
+		"legacy postgres postgres://app@old/legacy",
+		"tenant_a postgres postgres://app@db/tenant_a",
+		"tenant_b postgres postgres://app@db/tenant_b",
+		"tenant_c mysql app@tcp(other)/c",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("targets =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestFleetDiscoveryNeedsOneSource(t *testing.T) {
+	for _, tc := range []struct {
+		discovery FleetDiscovery
+		want      string
+	}{
+		{FleetDiscovery{Driver: "postgres"}, "needs url or connect"},
+		{FleetDiscovery{Driver: "postgres", URL: "http://localhost", Connect: "postgres://localhost"}, "not both"},
+		{FleetDiscovery{Driver: "sqlite", Connect: "file.db"}, "no default discovery query"},
+		{FleetDiscovery{URL: "http://localhost", Match: "("}, "invalid match regex"},
+	} {
+		if _, err := tc.discovery.discover(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("discover(%+v) = %v, want %q", tc.discovery, err, tc.want)
+		}
+	}
+}
+
+func TestFillDSNTemplateEscapesNames(t *testing.T) {
+	for _, tc := range []struct{ template, driver, want string }{
+		{"postgres://u@h:5432/{name}?application_name={name}", "postgres", "postgres://u@h:5432/a%2Fb%20$HOME?application_name=a%2Fb+%24HOME"},
+		{"u:p@tcp(h:3306)/{name}", "mysql", "u:p@tcp(h:3306)/a%2Fb%20$HOME"},
+		{"host=h dbname={name}", "postgres", `host=h dbname='a/b $HOME'`},
+		{"server=h;database={name}", "sqlserver", `server=h;database="a/b $HOME"`},
+	} {
+		if got := fillDSNTemplate(tc.template, tc.driver, "a/b $HOME"); got != tc.want {
+			t.Errorf("fillDSNTemplate(%q) = %q, want %q", tc.template, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// GRANTS - Table and column privileges and role memberships
+// ============================================================================
+
+// Privilege is a privilege held on a table, or on one of its columns
+type Privilege struct {
+	Grantee   string `json:"grantee"`
+	Table     string `json:"table"`
+	Column    string `json:"column,omitempty"`
+	Privilege string `json:"privilege"`
+	Grantable bool   `json:"grantable,omitempty"`
+}
+
+// RoleMembership makes Member a member of Role
+type RoleMembership struct {
+	Role        string `json:"role"`
+	Member      string `json:"member"`
+	AdminOption bool   `json:"admin_option,omitempty"`
+}
+
+// Grants are the privileges and role memberships of a database
+type Grants struct {
+	Privileges  []*Privilege      `json:"privileges,omitempty"`
+	Memberships []*RoleMembership `json:"memberships,omitempty"`
+}
+
+// GrantDiff is a privilege or role membership that differs between source
+// and target. Object is the table, table.column or, for memberships, the
+// role; Privilege is MEMBER for memberships.
+type GrantDiff struct {
+	Grantee   string `json:"grantee"`
+	Object    string `json:"object"`
+	Privilege string `json:"privilege"`
+	Change    string `json:"change"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// grantsReader is implemented by dialects that can read privileges
+type grantsReader interface {
+	grants(ctx context.Context, db *sql.DB) (*Grants, error)
+}
+
+func (p *PostgresDialect) grants(ctx context.Context, db *sql.DB) (*Grants, error) {
+	// aclexplode reads the ACLs of the catalog directly; information_schema
+	// only shows the grants the current user is involved in. Grantee 0 is
+	// PUBLIC.
+	query := `
+		SELECT COALESCE(r.rolname, 'PUBLIC'), c.relname, '', a.privilege_type, a.is_grantable
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		CROSS JOIN LATERAL aclexplode(c.relacl) a
+		LEFT JOIN pg_roles r ON r.oid = a.grantee
+		WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+		UNION ALL
+		SELECT COALESCE(r.rolname, 'PUBLIC'), c.relname, att.attname, a.privilege_type, a.is_grantable
+		FROM pg_attribute att
+		JOIN pg_class c ON c.oid = att.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		CROSS JOIN LATERAL aclexplode(att.attacl) a
+		LEFT JOIN pg_roles r ON r.oid = a.grantee
+		WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+		AND att.attnum > 0 AND NOT att.attisdropped
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	grants := &Grants{}
+	for rows.Next() {
+		priv := &Privilege{}
+		if err := rows.Scan(&priv.Grantee, &priv.Table, &priv.Column, &priv.Privilege, &priv.Grantable); err != nil {
+			return nil, err
+		}
+		grants.Privileges = append(grants.Privileges, priv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT r.rolname, m.rolname, am.admin_option
+		FROM pg_auth_members am
+		JOIN pg_roles r ON r.oid = am.roleid
+		JOIN pg_roles m ON m.oid = am.member
+		WHERE r.rolname NOT LIKE 'pg\_%'
+	`
+	rows, err = db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		m := &RoleMembership{}
+		if err := rows.Scan(&m.Role, &m.Member, &m.AdminOption); err != nil {
+			return nil, err
+		}
+		grants.Memberships = append(grants.Memberships, m)
+	}
+	return grants, rows.Err()
+}
+
+func (m *MySQLDialect) grants(ctx context.Context, db *sql.DB) (*Grants, error) {
+	query := `
+		SELECT grantee, table_name, '', privilege_type, is_grantable
+		FROM information_schema.table_privileges
+		WHERE table_schema = DATABASE()
+		UNION ALL
+		SELECT grantee, table_name, column_name, privilege_type, is_grantable
+		FROM information_schema.column_privileges
+		WHERE table_schema = DATABASE()
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	grants := &Grants{}
+	for rows.Next() {
+		priv := &Privilege{}
+		var grantable string
+		if err := rows.Scan(&priv.Grantee, &priv.Table, &priv.Column, &priv.Privilege, &grantable); err != nil {
+			return nil, err
+		}
+		priv.Grantable = grantable == "YES"
+		grants.Privileges = append(grants.Privileges, priv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Roles exist since MySQL 8.0; before, mysql.role_edges is missing
+	// (error 1146, ER_NO_SUCH_TABLE)
+	rows, err = db.QueryContext(ctx, "SELECT from_user, from_host, to_user, to_host, with_admin_option FROM mysql.role_edges")
+	if err != nil && strings.Contains(err.Error(), "Error 1146") {
+		return grants, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var fromUser, fromHost, toUser, toHost, admin string
+		if err := rows.Scan(&fromUser, &fromHost, &toUser, &toHost, &admin); err != nil {
+			return nil, err
+		}
+		grants.Memberships = append(grants.Memberships, &RoleMembership{
+			Role:        fmt.Sprintf("'%s'@'%s'", fromUser, fromHost),
+			Member:      fmt.Sprintf("'%s'@'%s'", toUser, toHost),
+			AdminOption: admin == "Y",
+		})
+	}
+	return grants, rows.Err()
+}
+
+// loadGrants reads the privileges and role memberships of a live database
+func loadGrants(driver, conn string) (*Grants, error) {
+	reader, ok := getDialect(driver).(grantsReader)
+	if !ok {
+		return nil, fmt.Errorf("reading grants is not supported for %s", driver)
+	}
+	db, err := openDB(driver, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return reader.grants(context.Background(), db)
+}
+
+// CompareGrants returns the privileges and memberships that differ, sorted
+// by grantee. Privileges on tables the filter ignores are left out.
+func CompareGrants(source, target *Grants, filter *FilterConfig) []*GrantDiff {
+	type grant struct {
+		diff      GrantDiff
+		grantable bool
+	}
+	index := func(grants *Grants) map[string]grant {
+		byKey := make(map[string]grant)
+		for _, p := range grants.Privileges {
+			if filter != nil && (filter.ShouldIgnoreTable(p.Table) || filter.ShouldIgnoreColumn(p.Table, p.Column)) {
+				continue
+			}
+			object := p.Table
+			if p.Column != "" {
+				object += "." + p.Column
+			}
+			g := grant{diff: GrantDiff{Grantee: p.Grantee, Object: object, Privilege: p.Privilege}, grantable: p.Grantable}
+			byKey[p.Grantee+"\x00"+object+"\x00"+p.Privilege] = g
+		}
+		for _, m := range grants.Memberships {
+			g := grant{diff: GrantDiff{Grantee: m.Member, Object: m.Role, Privilege: "MEMBER"}, grantable: m.AdminOption}
+			byKey[m.Member+"\x00"+m.Role+"\x00MEMBER"] = g
+		}
+		return byKey
+	}
+	src, tgt := index(source), index(target)
+
+	var diffs []*GrantDiff
+	for key, s := range src {
+		t, ok := tgt[key]
+		switch {
+		case !ok:
+			d := s.diff
+			d.Change = ChangeOnlyInSource
+			diffs = append(diffs, &d)
+		case s.grantable != t.grantable:
+			option := "grantable"
+			if s.diff.Privilege == "MEMBER" {
+				option = "admin_option"
+			}
+			d := s.diff
+			d.Change = ChangeModified
+			d.Detail = fmt.Sprintf("%s: %t → %t", option, s.grantable, t.grantable)
+			diffs = append(diffs, &d)
+		}
+	}
+	for key, t := range tgt {
+		if _, ok := src[key]; !ok {
+			d := t.diff
+			d.Change = ChangeOnlyInTarget
+			diffs = append(diffs, &d)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		a, b := diffs[i], diffs[j]
+		if a.Grantee != b.Grantee {
+			return a.Grantee < b.Grantee
+		}
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+		return a.Privilege < b.Privilege
+	})
+	return diffs
+}
+
+// grantLine renders a grant difference as "app SELECT on orders"
+func grantLine(d *GrantDiff) string {
+	if d.Privilege == "MEMBER" {
+		return fmt.Sprintf("%s member of %s", d.Grantee, d.Object)
+	}
+	return fmt.Sprintf("%s %s on %s", d.Grantee, d.Privilege, d.Object)
+}
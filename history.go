@@ -0,0 +1,239 @@
+package dbdiff
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// HISTORY - Drift over time from the audit log
+// ============================================================================
+
+// defaultHistoryTables is how many tables the trend report charts by default
+const defaultHistoryTables = 20
+
+// LoadHistory reads the diff records of an audit log written with --audit,
+// oldest first. Records of other commands and lines that are not audit
+// records are skipped; target, when set, keeps only the runs whose
+// (redacted) target contains it.
+func LoadHistory(path, target string) ([]*AuditRecord, error) {
+	data, err := readTextFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var records []*AuditRecord
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var rec AuditRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil || rec.Command != "diff" || rec.Summary == nil {
+			continue
+		}
+		if target != "" && !strings.Contains(rec.Target, target) {
+			continue
+		}
+		records = append(records, &rec)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time < records[j].Time })
+	return records, nil
+}
+
+var historyReportTemplate = template.Must(template.New("history").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Schema Drift Trend</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.breaking { color: #b00; }
+.zero { color: #999; }
+.note { font-style: italic; }
+</style>
+</head>
+<body>
+<h1>Schema Drift Trend</h1>
+{{if not .Runs}}<p>No diff runs found in the audit log.</p>{{else}}
+<p>{{len .Runs}} run(s) from {{.First}} to {{.Last}}.</p>
+<svg width="{{.Chart.Width}}" height="{{.Chart.Height}}" role="img" aria-label="Findings per run">
+<polyline fill="none" stroke="#a60" stroke-width="2" points="{{.Chart.Findings}}"/>
+<polyline fill="none" stroke="#b00" stroke-width="2" points="{{.Chart.Breaking}}"/>
+</svg>
+<p class="note">Findings (orange) and breaking findings (red) per run, peak {{.Chart.Peak}}.</p>
+<h2>By Category</h2>
+<table>
+<tr><th>Run</th><th>Target</th><th>Findings</th><th>Breaking</th>{{range .Categories}}<th>{{.}}</th>{{end}}</tr>
+{{range .Runs}}<tr><td>{{.Time}}</td><td>{{.Target}}</td><td>{{.Findings}}</td><td class="breaking">{{.Breaking}}</td>{{range .Categories}}<td{{if not .}} class="zero"{{end}}>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{if .Tables}}<h2>By Table</h2>
+<table>
+<tr><th>Table</th>{{range .Runs}}<th>{{.Time}}</th>{{end}}</tr>
+{{range .Tables}}<tr><td>{{.Name}}</td>{{range .Counts}}<td{{if not .}} class="zero"{{end}}>{{.}}</td>{{end}}</tr>
+{{end}}</table>{{if .OmittedTables}}<p class="note">{{.OmittedTables}} more table(s) with fewer findings not shown.</p>{{end}}{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+type historyRun struct {
+	Time       string
+	Target     string
+	Findings   int
+	Breaking   int
+	Categories []int
+}
+
+type historyTable struct {
+	Name   string
+	Counts []int
+}
+
+type historyChart struct {
+	Width, Height, Peak int
+	Findings, Breaking  string
+}
+
+// points renders counts as SVG polyline points scaled to the chart
+func (c historyChart) points(counts []int) string {
+	peak := c.Peak
+	if peak == 0 {
+		peak = 1
+	}
+	step := 0.0
+	if len(counts) > 1 {
+		step = float64(c.Width-20) / float64(len(counts)-1)
+	}
+	points := make([]string, len(counts))
+	for i, n := range counts {
+		x := 10 + step*float64(i)
+		y := float64(c.Height-10) - float64(n)*float64(c.Height-20)/float64(peak)
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	return strings.Join(points, " ")
+}
+
+// printHistoryHTML renders drift counts per run, by category and for the
+// maxTables tables with the most findings over all runs (0: all)
+func printHistoryHTML(w io.Writer, records []*AuditRecord, maxTables int) error {
+	categorySet := make(map[string]bool)
+	tableTotals := make(map[string]int)
+	for _, rec := range records {
+		for category := range rec.Summary.Categories {
+			categorySet[category] = true
+		}
+		for table, n := range rec.Summary.Tables {
+			tableTotals[table] += n
+		}
+	}
+	var categories, tables []string
+	for category := range categorySet {
+		categories = append(categories, category)
+	}
+	for table := range tableTotals {
+		tables = append(tables, table)
+	}
+	sort.Strings(categories)
+	sort.Strings(tables)
+	sort.SliceStable(tables, func(i, j int) bool { return tableTotals[tables[i]] > tableTotals[tables[j]] })
+
+	data := struct {
+		First, Last   string
+		Categories    []string
+		Runs          []historyRun
+		Tables        []historyTable
+		OmittedTables int
+		Chart         historyChart
+	}{Categories: categories, Chart: historyChart{Width: 800, Height: 200}}
+
+	var findings, breaking []int
+	for _, rec := range records {
+		run := historyRun{Time: rec.Time, Target: rec.Target, Findings: rec.Summary.Findings, Breaking: rec.Summary.Breaking}
+		for _, category := range categories {
+			run.Categories = append(run.Categories, rec.Summary.Categories[category])
+		}
+		data.Runs = append(data.Runs, run)
+		findings = append(findings, run.Findings)
+		breaking = append(breaking, run.Breaking)
+		if run.Findings > data.Chart.Peak {
+			data.Chart.Peak = run.Findings
+		}
+	}
+	if len(records) > 0 {
+		data.First, data.Last = records[0].Time, records[len(records)-1].Time
+	}
+	data.Chart.Findings = data.Chart.points(findings)
+	data.Chart.Breaking = data.Chart.points(breaking)
+
+	if maxTables > 0 && len(tables) > maxTables {
+		data.OmittedTables = len(tables) - maxTables
+		tables = tables[:maxTables]
+	}
+	for _, table := range tables {
+		row := historyTable{Name: table}
+		for _, rec := range records {
+			row.Counts = append(row.Counts, rec.Summary.Tables[table])
+		}
+		data.Tables = append(data.Tables, row)
+	}
+	return historyReportTemplate.Execute(w, data)
+}
+
+func runHistory(args []string) {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff history report --audit audit.jsonl [--out trend.html] [--target <match>] [--tables 20]")
+		fmt.Fprintln(os.Stderr, "\nRenders the drift recorded by runs with --audit as an HTML trend: findings per")
+		fmt.Fprintln(os.Stderr, "run by category and by table.")
+	}
+	if len(args) == 0 || args[0] != "report" {
+		usage()
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("history report", flag.ExitOnError)
+	auditPath := fs.String("audit", "", "Audit log written by diff runs with --audit")
+	outPath := fs.String("out", "", "Write the HTML report to this file (default: stdout)")
+	target := fs.String("target", "", "Only include runs whose target contains this text")
+	maxTables := fs.Int("tables", defaultHistoryTables, "Chart the tables with the most findings, 0 for all")
+	fs.Usage = func() {
+		usage()
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args[1:])
+
+	if *auditPath == "" || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	records, err := LoadHistory(*auditPath, *target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading audit log: %v\n", err)
+		os.Exit(1)
+	}
+
+	out := io.Writer(os.Stdout)
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := printHistoryHTML(out, records, *maxTables); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,251 @@
+package dbdiff
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// IMPACT - Objects transitively affected by changes to tables
+// ============================================================================
+
+// Kinds of impacted objects
+const (
+	ImpactTable    = "table"
+	ImpactTrigger  = "trigger"
+	ImpactRoutine  = "routine"
+	ImpactSequence = "sequence"
+)
+
+// ImpactedObject is an object that a change to one of the changed tables
+// can affect
+type ImpactedObject struct {
+	Kind string `json:"kind"`
+	// Name is table.trigger for triggers and the signature for routines
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+	// Depth counts the dependency hops from the nearest changed table
+	Depth int `json:"depth"`
+}
+
+// ImpactReport lists what changes to Changed can affect, nearest first
+type ImpactReport struct {
+	Changed []string          `json:"changed"`
+	Objects []*ImpactedObject `json:"objects"`
+}
+
+// Impact walks the dependencies of the changed tables in schema: tables whose
+// foreign keys reference an affected table (transitively), triggers on
+// affected tables, routines whose bodies mention one and the triggers calling
+// those, and sequences owned by their columns. Views aren't extracted, so
+// they aren't followed. Tables ignored by filter are skipped.
+func Impact(schema *Schema, changed []string, filter *FilterConfig) *ImpactReport {
+	report := &ImpactReport{Changed: changed}
+	seen := make(map[string]bool)
+	add := func(kind, name, reason string, depth int) bool {
+		if seen[kind+":"+name] {
+			return false
+		}
+		seen[kind+":"+name] = true
+		report.Objects = append(report.Objects, &ImpactedObject{Kind: kind, Name: name, Reason: reason, Depth: depth})
+		return true
+	}
+
+	type queued struct {
+		table string
+		depth int
+	}
+	var queue []queued
+	for _, name := range changed {
+		seen[ImpactTable+":"+name] = true
+		queue = append(queue, queued{name, 0})
+	}
+
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+		depth := q.depth + 1
+		mention := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(q.table) + `\b`)
+
+		for _, name := range getSortedKeys(schema.Sequences) {
+			if owner, _, _ := splitOwnedBy(schema.Sequences[name].OwnedBy); owner == q.table {
+				add(ImpactSequence, name, "owned by "+schema.Sequences[name].OwnedBy, depth)
+			}
+		}
+		if table := schema.Tables[q.table]; table != nil {
+			for _, name := range getSortedKeys(table.Triggers) {
+				add(ImpactTrigger, q.table+"."+name, "fires on "+q.table, depth)
+			}
+		}
+		for _, name := range getSortedKeys(schema.Routines) {
+			routine := schema.Routines[name]
+			if filter.ShouldIgnoreRoutine(routine) || !mention.MatchString(routine.Body) {
+				continue
+			}
+			if !add(ImpactRoutine, name, "body mentions "+q.table, depth) {
+				continue
+			}
+			// Postgres triggers calling the routine run it on their table's writes
+			for _, tableName := range getSortedKeys(schema.Tables) {
+				table := schema.Tables[tableName]
+				for _, trgName := range getSortedKeys(table.Triggers) {
+					function := table.Triggers[trgName].Function
+					function = function[strings.LastIndex(function, ".")+1:]
+					if fn, _, _ := strings.Cut(function, "("); fn == routine.Name && !filter.ShouldIgnoreTable(tableName) {
+						add(ImpactTrigger, tableName+"."+trgName, "calls "+name, depth+1)
+					}
+				}
+			}
+		}
+		for _, tableName := range getSortedKeys(schema.Tables) {
+			if filter.ShouldIgnoreTable(tableName) {
+				continue
+			}
+			table := schema.Tables[tableName]
+			// MySQL triggers carry their body
+			for _, trgName := range getSortedKeys(table.Triggers) {
+				if tableName != q.table && mention.MatchString(table.Triggers[trgName].Body) {
+					add(ImpactTrigger, tableName+"."+trgName, "body mentions "+q.table, depth)
+				}
+			}
+			for _, fkName := range getSortedKeys(table.ForeignKeys) {
+				if table.ForeignKeys[fkName].RefTable != q.table {
+					continue
+				}
+				if add(ImpactTable, tableName, fmt.Sprintf("references %s (%s)", q.table, fkName), depth) {
+					queue = append(queue, queued{tableName, depth})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(report.Objects, func(i, j int) bool {
+		a, b := report.Objects[i], report.Objects[j]
+		if a.Depth != b.Depth {
+			return a.Depth < b.Depth
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return report
+}
+
+// changedTables returns the tables of the source a diff changes or drops
+func changedTables(diff *SchemaDiff) []string {
+	tables := append([]string(nil), diff.TablesOnlyInSource...)
+	for _, td := range diff.TableDiffs {
+		tables = append(tables, td.TableName)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+func printImpact(w io.Writer, report *ImpactReport) {
+	fmt.Fprintf(w, "Impact of changes to %s\n", strings.Join(report.Changed, ", "))
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+	if len(report.Objects) == 0 {
+		fmt.Fprintln(w, "\nNo dependent objects found.")
+		return
+	}
+	depth := 0
+	for _, obj := range report.Objects {
+		if obj.Depth != depth {
+			depth = obj.Depth
+			fmt.Fprintf(w, "\nDepth %d:\n", depth)
+		}
+		fmt.Fprintf(w, "  %-8s %s  (%s)\n", obj.Kind, obj.Name, obj.Reason)
+	}
+	fmt.Fprintf(w, "\n%d dependent object(s)\n", len(report.Objects))
+}
+
+func runImpact(args []string) {
+	fs := flag.NewFlagSet("impact", flag.ExitOnError)
+	sourceConn := fs.String("source", "", "Source database connection string, or snapshot/ddl file")
+	sourceDriver := fs.String("source-driver", "", "Source database driver (postgres, yugabyte, mysql, tidb, sqlserver, oracle, firebird, bigquery, snapshot, ddl or fake)")
+	targetConn := fs.String("target", "", "With a target, start from the tables the diff changes")
+	targetDriver := fs.String("target-driver", "", "Target database driver")
+	tables := fs.String("table", "", "Comma-separated tables to start from (with --target, restricted to changed ones)")
+	format := fs.String("format", FormatPretty, "Output format: pretty or json")
+	parallel := fs.Bool("parallel", false, "Use parallel schema extraction")
+	filterOpts := addFilterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff impact --source <conn> --source-driver <driver> [--table users] [--target <conn> --target-driver <driver>] [--format pretty|json]")
+		fmt.Fprintln(os.Stderr, "\nLists the objects of the source that changes to the given tables, or to every table")
+		fmt.Fprintln(os.Stderr, "the diff against --target changes, can affect: referencing tables (transitively),")
+		fmt.Fprintln(os.Stderr, "triggers, routines mentioning them and owned sequences.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *sourceConn == "" || *sourceDriver == "" || (*targetConn == "") != (*targetDriver == "") ||
+		(*tables == "" && *targetConn == "") {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != FormatPretty && *format != FormatJSON {
+		fmt.Fprintf(os.Stderr, "Invalid format: %s (expected pretty or json)\n", *format)
+		os.Exit(1)
+	}
+	filter, err := filterOpts.build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts := filterOpts.extractOptions(filter)
+	if *parallel {
+		opts.Concurrency = parallelExtractConcurrency
+	}
+
+	source, err := loadSchema(*sourceDriver, *sourceConn, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading source schema: %v\n", err)
+		os.Exit(1)
+	}
+	changed := splitList(*tables)
+	for _, name := range changed {
+		if source.Tables[name] == nil {
+			fmt.Fprintf(os.Stderr, "Table %s not found in the source\n", name)
+			os.Exit(1)
+		}
+	}
+	if *targetConn != "" {
+		target, err := loadSchema(*targetDriver, *targetConn, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading target schema: %v\n", err)
+			os.Exit(1)
+		}
+		diffTables := changedTables(ComputeDiff(source, target, filter))
+		if len(changed) > 0 {
+			wanted := makeSet(changed)
+			changed = nil
+			for _, name := range diffTables {
+				if wanted[name] {
+					changed = append(changed, name)
+				}
+			}
+		} else {
+			changed = diffTables
+		}
+		if len(changed) == 0 {
+			fmt.Println("No table changes in the diff.")
+			return
+		}
+	}
+
+	report := Impact(source, changed, filter)
+	if *format == FormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+		return
+	}
+	printImpact(os.Stdout, report)
+}
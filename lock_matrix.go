@@ -0,0 +1,340 @@
+package dbdiff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// LOCK MATRIX - Locks a migration takes, for change review submissions
+// ============================================================================
+
+// Duration risks of a lock: how long it is likely held
+const (
+	LockRiskLow    = "low"    // catalog change only
+	LockRiskMedium = "medium" // scans the table or builds without blocking writes
+	LockRiskHigh   = "high"   // rewrites the table or blocks writes while it works
+)
+
+// What a statement does to the rows of its table while it holds its lock;
+// none for catalog changes
+const (
+	LockWorkRewrite    = "rewrite"     // writes a new copy of the table and its indexes
+	LockWorkIndexBuild = "index build" // reads the table and sorts it into an index
+	LockWorkScan       = "scan"        // reads the table to check its rows
+)
+
+// LockRequirement is a lock one migration statement takes on a table
+type LockRequirement struct {
+	Table     string `json:"table"`
+	Statement string `json:"statement"`
+	Lock      string `json:"lock"`
+	Blocks    string `json:"blocks"`
+	Risk      string `json:"risk"`
+	Reason    string `json:"reason"`
+	Work      string `json:"work,omitempty"`
+	// Suggested marks statements the script leaves commented out for review
+	Suggested bool `json:"suggested,omitempty"`
+	// Line is the 0-based line of the statement in the script
+	Line int `json:"-"`
+}
+
+// lockRule classifies the statements its pattern matches
+type lockRule struct {
+	pattern *regexp.Regexp
+	lock    string
+	risk    string
+	reason  string
+	work    string
+}
+
+// Postgres table locks, weakest first, and what each one blocks
+var pgLockBlocks = []struct{ lock, blocks string }{
+	{"SHARE UPDATE EXCLUSIVE", "other schema changes and VACUUM"},
+	{"SHARE", "writes"},
+	{"SHARE ROW EXCLUSIVE", "writes"},
+	{"ACCESS EXCLUSIVE", "reads and writes"},
+}
+
+// MySQL online DDL locks, weakest first
+var mysqlLockBlocks = []struct{ lock, blocks string }{
+	{"LOCK=NONE", "nothing but a brief metadata lock"},
+	{"LOCK=SHARED", "writes"},
+	{"LOCK=EXCLUSIVE", "reads and writes"},
+}
+
+const lockTableName = `["\x60]?(?P<table>[^\s"(;,\x60]+)["\x60]?`
+
+// lockPattern compiles a statement prefix in which {table} stands for the
+// (optionally quoted) table name
+func lockPattern(expr string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)^` + strings.ReplaceAll(expr, "{table}", lockTableName))
+}
+
+// pgLockRules follow the lock levels documented for ALTER TABLE, CREATE
+// INDEX and friends; column changes are classified by their diff instead
+var pgLockRules = []lockRule{
+	{lockPattern(`ALTER TABLE {table} SET ACCESS METHOD`), "ACCESS EXCLUSIVE", LockRiskHigh, "rewrites the table", LockWorkRewrite},
+	{lockPattern(`ALTER TABLE {table} ADD CONSTRAINT \S+ FOREIGN KEY .* NOT VALID`), "SHARE ROW EXCLUSIVE", LockRiskLow, "existing rows are not checked", ""},
+	{lockPattern(`ALTER TABLE {table} ADD CONSTRAINT \S+ FOREIGN KEY`), "SHARE ROW EXCLUSIVE", LockRiskMedium, "checks existing rows; on the referenced table too (ADD ... NOT VALID, then VALIDATE CONSTRAINT avoids this)", LockWorkScan},
+	{lockPattern(`ALTER TABLE {table} ADD CONSTRAINT \S+ (UNIQUE|PRIMARY KEY)`), "ACCESS EXCLUSIVE", LockRiskHigh, "builds an index while holding the lock (build it CONCURRENTLY, then ADD ... USING INDEX)", LockWorkIndexBuild},
+	{lockPattern(`ALTER TABLE {table} ADD CONSTRAINT \S+ CHECK .* NOT VALID`), "ACCESS EXCLUSIVE", LockRiskLow, "existing rows are not checked", ""},
+	{lockPattern(`ALTER TABLE {table} ADD CONSTRAINT \S+ CHECK`), "ACCESS EXCLUSIVE", LockRiskMedium, "checks existing rows (ADD ... NOT VALID, then VALIDATE CONSTRAINT avoids this)", LockWorkScan},
+	{lockPattern(`ALTER TABLE {table} VALIDATE CONSTRAINT`), "SHARE UPDATE EXCLUSIVE", LockRiskMedium, "checks existing rows without blocking writes", LockWorkScan},
+	{lockPattern(`ALTER TABLE {table} ATTACH PARTITION`), "SHARE UPDATE EXCLUSIVE", LockRiskMedium, "scans the partition unless a CHECK constraint proves its bound", LockWorkScan},
+	{lockPattern(`ALTER TABLE {table} (ENABLE|DISABLE) TRIGGER`), "SHARE ROW EXCLUSIVE", LockRiskLow, "catalog change only", ""},
+	{lockPattern(`ALTER TABLE {table}`), "ACCESS EXCLUSIVE", LockRiskLow, "catalog change only", ""},
+	{lockPattern(`CREATE (UNIQUE )?INDEX CONCURRENTLY (IF NOT EXISTS )?\S+ ON (ONLY )?{table}`), "SHARE UPDATE EXCLUSIVE", LockRiskMedium, "builds the index without blocking writes", LockWorkIndexBuild},
+	{lockPattern(`CREATE (UNIQUE )?INDEX (IF NOT EXISTS )?\S+ ON (ONLY )?{table}`), "SHARE", LockRiskHigh, "blocks writes while the index is built (CREATE INDEX CONCURRENTLY avoids this)", LockWorkIndexBuild},
+	{lockPattern(`CREATE TABLE \S+ PARTITION OF {table}`), "ACCESS EXCLUSIVE", LockRiskLow, "catalog change only", ""},
+	{lockPattern(`CREATE TABLE {table}`), "ACCESS EXCLUSIVE", LockRiskLow, "new table", ""},
+	{lockPattern(`CREATE TRIGGER \S+ .*\bON {table}`), "SHARE ROW EXCLUSIVE", LockRiskLow, "catalog change only", ""},
+	{lockPattern(`DROP TRIGGER \S+ ON {table}`), "ACCESS EXCLUSIVE", LockRiskLow, "catalog change only", ""},
+	{lockPattern(`DROP TABLE (IF EXISTS )?{table}`), "ACCESS EXCLUSIVE", LockRiskLow, "catalog change only", ""},
+	{lockPattern(`COMMENT ON TABLE {table}`), "SHARE UPDATE EXCLUSIVE", LockRiskLow, "catalog change only", ""},
+}
+
+// mysqlLockRules follow the InnoDB online DDL matrix of MySQL 8.0
+var mysqlLockRules = []lockRule{
+	{lockPattern(`ALTER TABLE {table} ADD COLUMN`), "LOCK=NONE", LockRiskLow, "ALGORITHM=INSTANT", ""},
+	{lockPattern(`ALTER TABLE {table} DROP COLUMN`), "LOCK=NONE", LockRiskMedium, "rebuilds the table in place (instant from 8.0.29)", LockWorkRewrite},
+	{lockPattern(`ALTER TABLE {table} ADD CONSTRAINT \S+ FOREIGN KEY`), "LOCK=SHARED", LockRiskHigh, "copies the table unless foreign_key_checks is off", LockWorkRewrite},
+	{lockPattern(`ALTER TABLE {table} ADD CONSTRAINT \S+ CHECK`), "LOCK=SHARED", LockRiskHigh, "copies the table to check existing rows", LockWorkRewrite},
+	{lockPattern(`ALTER TABLE {table} ADD CONSTRAINT \S+ UNIQUE`), "LOCK=NONE", LockRiskMedium, "builds the index in place", LockWorkIndexBuild},
+	{lockPattern(`ALTER TABLE {table} REORGANIZE PARTITION`), "LOCK=SHARED", LockRiskHigh, "copies the rows of the partition", LockWorkRewrite},
+	{lockPattern(`ALTER TABLE {table} (ADD|DROP) PARTITION`), "LOCK=SHARED", LockRiskLow, "catalog change only, for RANGE and LIST partitions", ""},
+	{lockPattern(`ALTER TABLE {table}`), "LOCK=NONE", LockRiskLow, "catalog change only", ""},
+	{lockPattern(`CREATE (UNIQUE )?INDEX \S+ ON {table}`), "LOCK=NONE", LockRiskMedium, "builds the index in place", LockWorkIndexBuild},
+	{lockPattern(`DROP INDEX \S+ ON {table}`), "LOCK=NONE", LockRiskLow, "catalog change only", ""},
+	{lockPattern(`CREATE TRIGGER \S+ .*\bON {table}`), "LOCK=EXCLUSIVE", LockRiskLow, "brief metadata lock", ""},
+	{lockPattern(`(CREATE|DROP) TABLE (IF (NOT )?EXISTS )?{table}`), "LOCK=EXCLUSIVE", LockRiskLow, "brief metadata lock", ""},
+	{lockPattern(`RENAME TABLE {table}`), "LOCK=EXCLUSIVE", LockRiskLow, "brief metadata lock", ""},
+}
+
+// columnChangePattern matches the column change suggestions of --migration
+var columnChangePattern = lockPattern(`ALTER TABLE {table} (ALTER|MODIFY) COLUMN `)
+
+// dropIndexPattern matches Postgres DROP INDEX, which names no table
+var dropIndexPattern = regexp.MustCompile(`(?i)^DROP INDEX (CONCURRENTLY )?(IF EXISTS )?(\S+?);?$`)
+
+// lockStatementPattern recognizes DDL in the script, commented out or not
+var lockStatementPattern = regexp.MustCompile(`(?i)^(--\s*)?((ALTER|CREATE|DROP|COMMENT|RENAME)\s.*)$`)
+
+// LockMatrix lists the table locks the statements of a migration script
+// take, including the ones --migration leaves commented out for review.
+// schema is the database the script runs on; it resolves the table of
+// Postgres DROP INDEX statements, which name no table.
+func LockMatrix(script, driver string, schemas ...*Schema) ([]LockRequirement, error) {
+	family := engineFamily(driver)
+	rules, blocks := pgLockRules, pgLockBlocks
+	switch family {
+	case "postgres":
+	case "mysql":
+		rules, blocks = mysqlLockRules, mysqlLockBlocks
+	default:
+		return nil, fmt.Errorf("lock matrix is not supported for %s", driver)
+	}
+	blocksOf := func(lock string) string {
+		for _, b := range blocks {
+			if b.lock == lock {
+				return b.blocks
+			}
+		}
+		return ""
+	}
+	indexTables := make(map[string]string)
+	for _, schema := range schemas {
+		if schema == nil {
+			continue
+		}
+		for tableName, table := range schema.Tables {
+			for name := range table.Indexes {
+				indexTables[name] = tableName
+			}
+		}
+	}
+
+	var reqs []LockRequirement
+	for i, line := range strings.Split(script, "\n") {
+		m := lockStatementPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		// The trailing comment explains the statement; for column changes
+		// it is the diff of the column
+		stmt, detail, _ := strings.Cut(m[2], ";  --")
+		req := LockRequirement{Statement: stmt, Suggested: m[1] != "", Line: i}
+
+		if c := columnChangePattern.FindStringSubmatch(stmt); c != nil {
+			req.Table = c[1]
+			req.Lock, req.Risk, req.Reason, req.Work = columnChangeLock(family, detail)
+		} else if d := dropIndexPattern.FindStringSubmatch(stmt); d != nil && family == "postgres" {
+			req.Table, req.Lock, req.Risk, req.Reason = indexTables[d[3]], "ACCESS EXCLUSIVE", LockRiskLow, "catalog change only"
+			if d[1] != "" {
+				req.Lock = "SHARE UPDATE EXCLUSIVE"
+			}
+		} else {
+			for _, rule := range rules {
+				if r := rule.pattern.FindStringSubmatch(stmt); r != nil {
+					req.Table, req.Lock, req.Risk, req.Reason, req.Work = r[rule.pattern.SubexpIndex("table")], rule.lock, rule.risk, rule.reason, rule.work
+					break
+				}
+			}
+		}
+		if req.Lock == "" {
+			continue
+		}
+		if req.Table == "" {
+			req.Table = "?"
+		}
+		req.Blocks = blocksOf(req.Lock)
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+// columnChangeLock classifies a column change by its diff, e.g.
+// "type: int → bigint; nullable: true → false"
+func columnChangeLock(family, detail string) (lock, risk, reason, work string) {
+	rewrite := strings.Contains(detail, "type:") || strings.Contains(detail, "generated") || strings.Contains(detail, "storage:") ||
+		strings.Contains(detail, "scale:") || modifierShrinks(detail)
+	scan := strings.Contains(detail, "nullable: true → false")
+	if family == "mysql" {
+		if rewrite || scan {
+			return "LOCK=SHARED", LockRiskHigh, "ALGORITHM=COPY: copies the table", LockWorkRewrite
+		}
+		return "LOCK=NONE", LockRiskLow, "catalog change only", ""
+	}
+	switch {
+	case rewrite:
+		return "ACCESS EXCLUSIVE", LockRiskHigh, "rewrites the table and its indexes (unless the new type is binary compatible)", LockWorkRewrite
+	case scan:
+		return "ACCESS EXCLUSIVE", LockRiskMedium, "scans the table for NULLs (a validated CHECK (c IS NOT NULL) avoids this)", LockWorkScan
+	}
+	return "ACCESS EXCLUSIVE", LockRiskLow, "catalog change only", ""
+}
+
+// modifierChangePattern matches the length and precision changes of a
+// column diff
+var modifierChangePattern = regexp.MustCompile(`(?:length|precision): (\d+|none) → (\d+|none)`)
+
+// modifierShrinks reports whether a column diff lowers or sets a length or
+// precision, which has to check or rewrite every row; raising one is a
+// catalog change
+func modifierShrinks(detail string) bool {
+	for _, m := range modifierChangePattern.FindAllStringSubmatch(detail, -1) {
+		from, _ := strconv.Atoi(m[1])
+		to, _ := strconv.Atoi(m[2])
+		if m[2] != "none" && (m[1] == "none" || to < from) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockRank orders locks and risks from weakest to strongest
+func lockRank(family, lock string) int {
+	blocks := pgLockBlocks
+	if family == "mysql" {
+		blocks = mysqlLockBlocks
+	}
+	for i, b := range blocks {
+		if b.lock == lock {
+			return i
+		}
+	}
+	return -1
+}
+
+var lockRiskRank = map[string]int{LockRiskLow: 0, LockRiskMedium: 1, LockRiskHigh: 2}
+
+// printLockMatrix writes the lock requirements as Markdown, one summary row
+// per table (strongest lock, highest risk) and then every statement. rows
+// holds the estimated row count of tables, where known.
+func printLockMatrix(w io.Writer, reqs []LockRequirement, driver string, rows map[string]int64) {
+	family := engineFamily(driver)
+	fmt.Fprintln(w, "# Migration Lock Requirements")
+	fmt.Fprintln(w)
+	if len(reqs) == 0 {
+		fmt.Fprintln(w, "The migration takes no table locks.")
+		return
+	}
+	cell := func(s string) string { return strings.ReplaceAll(s, "|", `\|`) }
+
+	perTable := make(map[string][]LockRequirement)
+	for _, req := range reqs {
+		perTable[req.Table] = append(perTable[req.Table], req)
+	}
+	tables := getSortedKeys(perTable)
+	// Riskiest tables first, for the reviewer
+	sort.SliceStable(tables, func(i, j int) bool {
+		return maxLockRisk(perTable[tables[i]]) > maxLockRisk(perTable[tables[j]])
+	})
+
+	fmt.Fprintln(w, "| Table | Strongest lock | Blocks | Duration risk | Est. rows | Statements |")
+	fmt.Fprintln(w, "|-------|----------------|--------|---------------|-----------|------------|")
+	for _, table := range tables {
+		strongest := perTable[table][0]
+		for _, req := range perTable[table][1:] {
+			if lockRank(family, req.Lock) > lockRank(family, strongest.Lock) {
+				strongest = req
+			}
+		}
+		estimate := "?"
+		if n, ok := rows[table]; ok {
+			estimate = fmt.Sprintf("~%d", n)
+		}
+		risk := []string{LockRiskLow, LockRiskMedium, LockRiskHigh}[maxLockRisk(perTable[table])]
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %d |\n", cell(table), strongest.Lock, strongest.Blocks, risk, estimate, len(perTable[table]))
+	}
+
+	for _, table := range tables {
+		fmt.Fprintf(w, "\n## %s\n\n", table)
+		fmt.Fprintln(w, "| Statement | Lock | Risk | Why |")
+		fmt.Fprintln(w, "|-----------|------|------|-----|")
+		for _, req := range perTable[table] {
+			stmt := "`" + cell(statementSummary(req.Statement)) + "`"
+			if req.Suggested {
+				stmt += " (commented out)"
+			}
+			fmt.Fprintf(w, "| %s | %s | %s | %s |\n", stmt, req.Lock, req.Risk, cell(req.Reason))
+		}
+	}
+}
+
+func maxLockRisk(reqs []LockRequirement) int {
+	highest := 0
+	for _, req := range reqs {
+		highest = max(highest, lockRiskRank[req.Risk])
+	}
+	return highest
+}
+
+// estimateTableRows returns the estimated row counts of tables in a live
+// database, for the tables the dialect can estimate
+func estimateTableRows(driver, conn string, tables []string) map[string]int64 {
+	rows := make(map[string]int64)
+	monitor, ok := getDialect(driver).(applyMonitor)
+	if !ok || isFileDriver(driver) || driver == DriverFake {
+		return rows
+	}
+	db, err := openDB(driver, conn)
+	if err != nil {
+		return rows
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, table := range tables {
+		if n, err := monitor.estimateRows(ctx, db, table); err == nil && n >= 0 {
+			rows[table] = n
+		}
+	}
+	return rows
+}
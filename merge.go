@@ -0,0 +1,282 @@
+package dbdiff
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// MERGE - Union of several snapshots
+// ============================================================================
+
+// MergeError lists the objects that more than one input defines differently
+type MergeError struct {
+	Conflicts []string
+}
+
+func (e *MergeError) Error() string {
+	return fmt.Sprintf("%d conflicting definition(s):\n  %s", len(e.Conflicts), strings.Join(e.Conflicts, "\n  "))
+}
+
+// MergeSchemas returns the union of the named schemas. An object may be
+// defined by several of them as long as the definitions are identical;
+// otherwise a *MergeError lists every conflict.
+func MergeSchemas(names []string, schemas []*Schema) (*Schema, error) {
+	merged := &Schema{Tables: make(map[string]*Table)}
+	// Input that defined each object first, for conflict messages
+	origin := make(map[string]string)
+	strict := &FilterConfig{}
+	var conflicts []string
+	conflict := func(key, name, detail string) {
+		conflicts = append(conflicts, fmt.Sprintf("%s: %s (%s vs %s)", key, detail, origin[key], name))
+	}
+
+	for i, schema := range schemas {
+		name := names[i]
+		for typeName, ut := range schema.Types {
+			key := "type " + typeName
+			if merged.Types == nil {
+				merged.Types = make(map[string]*UserType)
+			}
+			if existing, ok := merged.Types[typeName]; ok {
+				if changes, differs := diffUserTypes(map[string]*UserType{typeName: existing}, schema.Types)[typeName]; differs {
+					conflict(key, name, renderChanges(changes))
+				}
+				continue
+			}
+			merged.Types[typeName], origin[key] = ut, name
+		}
+
+		for seqName, seq := range schema.Sequences {
+			key := "sequence " + seqName
+			if merged.Sequences == nil {
+				merged.Sequences = make(map[string]*Sequence)
+			}
+			if existing, ok := merged.Sequences[seqName]; ok {
+				if changes := compareSequence(existing, seq); len(changes) > 0 {
+					conflict(key, name, renderChanges(changes))
+				}
+				continue
+			}
+			merged.Sequences[seqName], origin[key] = seq, name
+		}
+
+		for extName, ext := range schema.Extensions {
+			key := "extension " + extName
+			if merged.Extensions == nil {
+				merged.Extensions = make(map[string]*Extension)
+			}
+			if existing, ok := merged.Extensions[extName]; ok {
+				if changes := compareExtension(existing, ext); len(changes) > 0 {
+					conflict(key, name, renderChanges(changes))
+				}
+				continue
+			}
+			merged.Extensions[extName], origin[key] = ext, name
+		}
+
+		for routineName, routine := range schema.Routines {
+			key := "routine " + routineName
+			if merged.Routines == nil {
+				merged.Routines = make(map[string]*Routine)
+			}
+			if existing, ok := merged.Routines[routineName]; ok {
+				if changes := compareRoutine(existing, routine); len(changes) > 0 {
+					conflict(key, name, renderChanges(changes))
+				}
+				continue
+			}
+			merged.Routines[routineName], origin[key] = routine, name
+		}
+
+		for eventName, event := range schema.Events {
+			key := "event " + eventName
+			if merged.Events == nil {
+				merged.Events = make(map[string]*Event)
+			}
+			if existing, ok := merged.Events[eventName]; ok {
+				if changes := compareEvent(existing, event); len(changes) > 0 {
+					conflict(key, name, renderChanges(changes))
+				}
+				continue
+			}
+			merged.Events[eventName], origin[key] = event, name
+		}
+
+		for serverName, server := range schema.ForeignServers {
+			key := "foreign server " + serverName
+			if merged.ForeignServers == nil {
+				merged.ForeignServers = make(map[string]*ForeignServer)
+			}
+			if existing, ok := merged.ForeignServers[serverName]; ok {
+				if changes := compareForeignServer(existing, server); len(changes) > 0 {
+					conflict(key, name, renderChanges(changes))
+				}
+				continue
+			}
+			merged.ForeignServers[serverName], origin[key] = server, name
+		}
+
+		for tableName, table := range schema.Tables {
+			key := "table " + tableName
+			dst, ok := merged.Tables[tableName]
+			if !ok {
+				dst = newTable(tableName)
+				merged.Tables[tableName], origin[key] = dst, name
+			}
+
+			mergeObjects(dst.Columns, table.Columns, "column "+tableName+".", name, origin, conflict,
+				func(a, b *Column) []AttributeChange { return compareColumn(a, b, strict, nil) })
+			mergeObjects(dst.ForeignKeys, table.ForeignKeys, "foreign key "+tableName+".", name, origin, conflict, compareForeignKey)
+			mergeObjects(dst.UniqueConstraints, table.UniqueConstraints, "unique "+tableName+".", name, origin, conflict, compareUnique)
+			mergeObjects(dst.Indexes, table.Indexes, "index "+tableName+".", name, origin, conflict, compareIndex)
+			mergeObjects(dst.CheckConstraints, table.CheckConstraints, "check "+tableName+".", name, origin, conflict, compareCheck)
+			mergeObjects(dst.Triggers, table.Triggers, "trigger "+tableName+".", name, origin, conflict, compareTrigger)
+			if len(table.RewriteRules) > 0 {
+				if dst.RewriteRules == nil {
+					dst.RewriteRules = make(map[string]*RewriteRule)
+				}
+				mergeObjects(dst.RewriteRules, table.RewriteRules, "rule "+tableName+".", name, origin, conflict, compareRewriteRule)
+			}
+			if len(table.Inherits) > 0 {
+				inhKey := "inheritance " + tableName
+				if dst.Inherits == nil {
+					dst.Inherits, origin[inhKey] = table.Inherits, name
+				} else if !equalStringSlices(dst.Inherits, table.Inherits) {
+					conflict(inhKey, name, strings.Join(dst.Inherits, ", ")+" → "+strings.Join(table.Inherits, ", "))
+				}
+			}
+			if table.Foreign != nil {
+				ftKey := "foreign table " + tableName
+				if dst.Foreign == nil {
+					dst.Foreign, origin[ftKey] = table.Foreign, name
+				} else if from, to := describeForeignTable(dst.Foreign), describeForeignTable(table.Foreign); from != to {
+					conflict(ftKey, name, from+" → "+to)
+				}
+			}
+			if len(table.Options) > 0 {
+				if dst.Options == nil {
+					dst.Options = make(map[string]string)
+				}
+				mergeObjects(dst.Options, table.Options, "option "+tableName+".", name, origin, conflict,
+					func(a, b string) []AttributeChange {
+						if a != b {
+							return []AttributeChange{attributeChange("value", a, b)}
+						}
+						return nil
+					})
+			}
+
+			if table.PrimaryKey != nil {
+				pkKey := "primary key " + tableName
+				if dst.PrimaryKey == nil {
+					dst.PrimaryKey, origin[pkKey] = table.PrimaryKey, name
+				} else if changes := comparePrimaryKey(dst.PrimaryKey, table.PrimaryKey); len(changes) > 0 {
+					conflict(pkKey, name, renderChanges(changes))
+				}
+			}
+			if table.Comment != "" {
+				commentKey := "comment " + tableName
+				if dst.Comment == "" {
+					dst.Comment, origin[commentKey] = table.Comment, name
+				} else if dst.Comment != table.Comment {
+					conflict(commentKey, name, fmt.Sprintf("%q → %q", dst.Comment, table.Comment))
+				}
+			}
+			if table.AccessMethod != "" {
+				amKey := "access method " + tableName
+				if dst.AccessMethod == "" {
+					dst.AccessMethod, origin[amKey] = table.AccessMethod, name
+				} else if dst.AccessMethod != table.AccessMethod {
+					conflict(amKey, name, dst.AccessMethod+" → "+table.AccessMethod)
+				}
+			}
+			if table.ReplicaIdentity != "" {
+				riKey := "replica identity " + tableName
+				if dst.ReplicaIdentity == "" {
+					dst.ReplicaIdentity, origin[riKey] = table.ReplicaIdentity, name
+				} else if dst.ReplicaIdentity != table.ReplicaIdentity {
+					conflict(riKey, name, dst.ReplicaIdentity+" → "+table.ReplicaIdentity)
+				}
+			}
+			if table.Partitioning != nil || table.PartitionOf != "" {
+				partKey := "partitioning " + tableName
+				if dst.Partitioning == nil && dst.PartitionOf == "" {
+					dst.Partitioning, dst.PartitionOf, origin[partKey] = table.Partitioning, table.PartitionOf, name
+				} else if changes := comparePartitioning(dst, table); len(changes) > 0 {
+					conflict(partKey, name, renderChanges(changes))
+				}
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, &MergeError{Conflicts: conflicts}
+	}
+	return merged, nil
+}
+
+// mergeObjects copies the objects of src into dst, reporting the ones dst
+// already has with a different definition
+func mergeObjects[T any](dst, src map[string]T, prefix, input string, origin map[string]string,
+	conflict func(key, input, detail string), compare func(a, b T) []AttributeChange) {
+	for name, obj := range src {
+		key := prefix + name
+		existing, ok := dst[name]
+		if !ok {
+			dst[name], origin[key] = obj, input
+			continue
+		}
+		if changes := compare(existing, obj); len(changes) > 0 {
+			conflict(key, input, renderChanges(changes))
+		}
+	}
+}
+
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	out := fs.String("out", "", "Write the merged snapshot to this file instead of stdout (compressed for .gz/.zst)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff merge <a.json> <b.json|b.sql> [...] [--out merged.json]")
+		fmt.Fprintln(os.Stderr, "\nUnions snapshots and DDL files into one snapshot. Fails when two inputs")
+		fmt.Fprintln(os.Stderr, "define the same object differently.")
+	}
+	inputs := parseInterspersed(fs, args)
+
+	if len(inputs) < 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	schemas := make([]*Schema, len(inputs))
+	for i, input := range inputs {
+		var err error
+		if schemas[i], err = loadSchema(schemaFileDriver(input), input, ExtractOptions{}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", input, err)
+			os.Exit(1)
+		}
+	}
+
+	merged, err := MergeSchemas(inputs, schemas)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error merging: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		if err := writeSnapshot(os.Stdout, merged, CompressNone); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing snapshot: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := SaveSnapshot(merged, *out, compressionForPath(*out)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing snapshot: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Merged %d table(s) from %d input(s) into %s\n", len(merged.Tables), len(inputs), *out)
+}
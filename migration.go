@@ -0,0 +1,650 @@
+package dbdiff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ============================================================================
+// MIGRATION GENERATION
+// ============================================================================
+
+// MigrationOptions tunes the generated migration script
+type MigrationOptions struct {
+	// ArchiveBeforeDrop replaces DROP TABLE/COLUMN suggestions with rename and
+	// copy-to-archive scaffolding so dropped data stays recoverable
+	ArchiveBeforeDrop bool
+	// ArchiveSchema is the schema (Postgres) or database (MySQL) archive copies go to
+	ArchiveSchema string
+	// ArchiveSuffix is appended to renamed objects, e.g. _dropped_20261016.
+	// The caller picks it, so that the same diff always gives the same
+	// script; without one, objects are renamed with _dropped
+	ArchiveSuffix string
+	// SourceName and TargetName call the side the migration runs on and the
+	// side it is turned into in comments; default to source and target, the
+	// other way round for a target-to-source diff
+	SourceName, TargetName string
+}
+
+func GenerateMigrationSQL(diff *SchemaDiff, driver string, opts MigrationOptions) string {
+	if opts.ArchiveSchema == "" {
+		opts.ArchiveSchema = "archive"
+	}
+	if opts.ArchiveSuffix == "" {
+		opts.ArchiveSuffix = "_dropped"
+	}
+	if opts.SourceName == "" || opts.TargetName == "" {
+		opts.SourceName, opts.TargetName = "source", "target"
+		if diff.Direction == DirectionTargetToSource {
+			opts.SourceName, opts.TargetName = "target", "source"
+		}
+	}
+
+	// Extensions come first: tables, types and routines may depend on them,
+	// and foreign servers on their wrappers
+	migrations := generateExtensionMigrations(diff, driver, opts)
+	migrations = append(migrations, generateForeignServerMigrations(diff, driver, opts)...)
+
+	// Generate CREATE TABLE statements for tables only in target
+	created, covered := generateCreateMigrations(diff, driver, opts)
+	migrations = append(migrations, created...)
+	for _, tableName := range diff.TablesOnlyInTarget {
+		if covered[CategoryTable+":"+tableName] {
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("-- Table '%s' exists in %s but not in %s", tableName, opts.TargetName, opts.SourceName))
+		migrations = append(migrations, fmt.Sprintf("-- Manual review required for table: %s\n", tableName))
+	}
+
+	// Generate DROP TABLE statements for tables only in source
+	for _, tableName := range diff.TablesOnlyInSource {
+		if opts.ArchiveBeforeDrop {
+			migrations = append(migrations, archiveTableStatements(tableName, driver, opts)...)
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("-- DROP TABLE %s;  -- Table exists in %s but not in %s\n", tableName, opts.SourceName, opts.TargetName))
+	}
+
+	// Generate ALTER TABLE statements for table differences
+	for _, tableDiff := range diff.TableDiffs {
+		tableMigrations := generateTableMigrations(tableDiff, driver, opts)
+		if len(tableMigrations) > 0 {
+			migrations = append(migrations, fmt.Sprintf("-- Migrations for table: %s", tableDiff.TableName))
+			for _, g := range diff.PartitionGroups {
+				if g.Name == tableDiff.TableName {
+					migrations = append(migrations, fmt.Sprintf("-- %s stands for each of its %d partition(s) in %s; repeat for every one", g.Name, g.SourcePartitions, opts.SourceName))
+				}
+			}
+			migrations = append(migrations, tableMigrations...)
+			migrations = append(migrations, "")
+		}
+	}
+
+	migrations = append(migrations, generateTypeMigrations(diff, covered, opts)...)
+	migrations = append(migrations, generateSequenceMigrations(diff, driver, covered, opts)...)
+	migrations = append(migrations, generateRoutineMigrations(diff, driver, opts)...)
+	migrations = append(migrations, generateEventMigrations(diff, opts)...)
+
+	if len(migrations) == 0 {
+		return "-- No migrations needed\n"
+	}
+
+	header := fmt.Sprintf("-- Migration SQL generated for %s\n", driver)
+	header += "-- Review and test these statements before applying to production!\n"
+	header += "-- Some statements may need manual adjustment.\n\n"
+
+	return header + strings.Join(migrations, "\n")
+}
+
+// generateExtensionMigrations installs the extensions the target has and
+// updates those installed in another version. Dropping one would drop
+// everything using it, so that is left for review.
+func generateExtensionMigrations(diff *SchemaDiff, driver string, opts MigrationOptions) []string {
+	if driver != "postgres" {
+		return nil
+	}
+	var migrations []string
+	for _, name := range diff.ExtensionsOnlyInTarget {
+		migrations = append(migrations, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s;  -- Extension exists in %s\n", pq.QuoteIdentifier(name), opts.TargetName))
+	}
+	for _, name := range diff.ExtensionsOnlyInSource {
+		migrations = append(migrations, fmt.Sprintf("-- DROP EXTENSION %s;  -- Extension exists in %s but not in %s; drops the objects using it\n", pq.QuoteIdentifier(name), opts.SourceName, opts.TargetName))
+	}
+	for _, d := range diff.ExtensionDiffs {
+		if changes := d.Changes; len(changes) == 1 && changes[0].Attribute == "version" {
+			migrations = append(migrations, fmt.Sprintf("ALTER EXTENSION %s UPDATE TO %s;  -- %s\n", pq.QuoteIdentifier(d.Name), pq.QuoteLiteral(changes[0].To), d.Diff))
+		}
+	}
+	return migrations
+}
+
+// generateForeignServerMigrations creates the foreign servers the target
+// has from their definitions. User mappings hold credentials, so they are
+// left for review, as are dropped and changed servers.
+func generateForeignServerMigrations(diff *SchemaDiff, driver string, opts MigrationOptions) []string {
+	if driver != "postgres" {
+		return nil
+	}
+	var migrations []string
+	for _, name := range diff.ForeignServersOnlyInTarget {
+		var server *ForeignServer
+		if diff.TargetOnly != nil {
+			server = diff.TargetOnly.ForeignServers[name]
+		}
+		if server == nil {
+			migrations = append(migrations, fmt.Sprintf("-- Foreign server '%s' exists in %s but not in %s\n", name, opts.TargetName, opts.SourceName))
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("CREATE SERVER %s FOREIGN DATA WRAPPER %s%s;  -- Foreign server exists in %s", name, server.Wrapper, foreignOptionsClause(server.Options), opts.TargetName))
+		for _, role := range server.UserMappings {
+			migrations = append(migrations, fmt.Sprintf("-- CREATE USER MAPPING FOR %s SERVER %s OPTIONS (...);  -- fill in the credentials", role, name))
+		}
+		migrations = append(migrations, "")
+	}
+	for _, name := range diff.ForeignServersOnlyInSource {
+		migrations = append(migrations, fmt.Sprintf("-- DROP SERVER %s;  -- Foreign server exists in %s but not in %s\n", name, opts.SourceName, opts.TargetName))
+	}
+	for _, d := range diff.ForeignServerDiffs {
+		migrations = append(migrations, fmt.Sprintf("-- Foreign server %s differs (%s); adjust it with ALTER SERVER ... OPTIONS and the user mappings\n", d.Name, d.Diff))
+	}
+	return migrations
+}
+
+// generateCreateMigrations renders CREATE statements for the tables, types
+// and sequences that only the target has, from the definitions the diff
+// carries (see MissingObjects). It also returns the objects it created, by
+// category:name; reports without definitions and engines SchemaDDL can't
+// render leave them to manual review.
+func generateCreateMigrations(diff *SchemaDiff, driver string, opts MigrationOptions) ([]string, map[string]bool) {
+	covered := make(map[string]bool)
+	m := diff.TargetOnly
+	if m == nil {
+		return nil, covered
+	}
+	schema := &Schema{Tables: m.Tables, Types: m.Types, Sequences: m.Sequences}
+	stmts, err := SchemaDDL(schema, driver)
+	if err != nil || len(stmts) == 0 {
+		return nil, covered
+	}
+
+	pg := engineFamily(driver) == "postgres"
+	for name := range m.Tables {
+		covered[CategoryTable+":"+name] = true
+	}
+	for name, seq := range m.Sequences {
+		// MySQL counters come with their AUTO_INCREMENT column
+		owner, _, _ := splitOwnedBy(seq.OwnedBy)
+		if pg || m.Tables[owner] != nil {
+			covered[CategorySequence+":"+name] = true
+		}
+	}
+	if pg {
+		for name := range m.Types {
+			covered[CategoryType+":"+name] = true
+		}
+	}
+
+	migrations := []string{fmt.Sprintf("-- Objects that exist in %s but not in %s", opts.TargetName, opts.SourceName)}
+	for _, stmt := range stmts {
+		migrations = append(migrations, stmt+";")
+	}
+	return append(migrations, ""), covered
+}
+
+// generateTypeMigrations lists enum and domain differences for review.
+// Enum labels can only be added in place (ALTER TYPE ... ADD VALUE);
+// removing or reordering them means recreating the type.
+func generateTypeMigrations(diff *SchemaDiff, covered map[string]bool, opts MigrationOptions) []string {
+	var migrations []string
+	for _, name := range diff.TypesOnlyInTarget {
+		if covered[CategoryType+":"+name] {
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("-- Type '%s' exists in %s but not in %s", name, opts.TargetName, opts.SourceName))
+		migrations = append(migrations, fmt.Sprintf("-- Manual review required for type: %s\n", name))
+	}
+	for _, name := range diff.TypesOnlyInSource {
+		migrations = append(migrations, fmt.Sprintf("-- DROP TYPE %s;  -- Type exists in %s but not in %s (DROP DOMAIN for a domain)\n", name, opts.SourceName, opts.TargetName))
+	}
+	for _, d := range diff.TypeDiffs {
+		hint := "use ALTER DOMAIN to change it"
+		if len(d.Changes) > 0 {
+			switch d.Changes[0].Attribute {
+			case "labels":
+				hint = "use ALTER TYPE ... ADD VALUE for added labels, recreate it otherwise"
+			case "attributes":
+				hint = "use ALTER TYPE ... ADD/DROP/ALTER ATTRIBUTE"
+			}
+		}
+		migrations = append(migrations, fmt.Sprintf("-- Type %s differs (%s); %s\n", d.Name, d.Diff, hint))
+	}
+	return migrations
+}
+
+// generateSequenceMigrations turns sequence differences into ALTER SEQUENCE
+// statements for Postgres. MySQL AUTO_INCREMENT counters follow server
+// settings and column definitions, so they are only listed for review.
+func generateSequenceMigrations(diff *SchemaDiff, driver string, covered map[string]bool, opts MigrationOptions) []string {
+	var migrations []string
+	for _, name := range diff.SequencesOnlyInTarget {
+		if covered[CategorySequence+":"+name] {
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("-- Sequence '%s' exists in %s but not in %s", name, opts.TargetName, opts.SourceName))
+		migrations = append(migrations, fmt.Sprintf("-- Manual review required for sequence: %s\n", name))
+	}
+	for _, name := range diff.SequencesOnlyInSource {
+		if driver == "postgres" {
+			migrations = append(migrations, fmt.Sprintf("-- DROP SEQUENCE %s;  -- Sequence exists in %s but not in %s\n", name, opts.SourceName, opts.TargetName))
+		} else {
+			migrations = append(migrations, fmt.Sprintf("-- AUTO_INCREMENT counter '%s' exists in %s but not in %s\n", name, opts.SourceName, opts.TargetName))
+		}
+	}
+	for _, d := range diff.SequenceDiffs {
+		if driver != "postgres" {
+			migrations = append(migrations, fmt.Sprintf("-- AUTO_INCREMENT counter '%s' differs: %s\n", d.Name, d.Diff))
+			continue
+		}
+		var clauses []string
+		for _, c := range d.Changes {
+			switch c.Attribute {
+			case "data_type":
+				clauses = append(clauses, "AS "+c.To)
+			case "start":
+				clauses = append(clauses, "START WITH "+c.To)
+			case "increment":
+				clauses = append(clauses, "INCREMENT BY "+c.To)
+			case "min_value":
+				clauses = append(clauses, "MINVALUE "+c.To)
+			case "max_value":
+				clauses = append(clauses, "MAXVALUE "+c.To)
+			case "cache":
+				clauses = append(clauses, "CACHE "+c.To)
+			case "cycle":
+				if c.To == "true" {
+					clauses = append(clauses, "CYCLE")
+				} else {
+					clauses = append(clauses, "NO CYCLE")
+				}
+			case "owned_by":
+				if c.To == "" {
+					clauses = append(clauses, "OWNED BY NONE")
+				} else {
+					clauses = append(clauses, "OWNED BY "+c.To)
+				}
+			}
+		}
+		migrations = append(migrations, fmt.Sprintf("-- Migrations for sequence: %s", d.Name))
+		migrations = append(migrations, fmt.Sprintf("ALTER SEQUENCE %s %s;\n", d.Name, strings.Join(clauses, " ")))
+	}
+	return migrations
+}
+
+// generateRoutineMigrations lists routine differences for review. Bodies
+// are normalized, so they can't be replayed; changed routines have to be
+// recreated from the target's own definition.
+func generateRoutineMigrations(diff *SchemaDiff, driver string, opts MigrationOptions) []string {
+	var migrations []string
+	for _, name := range diff.RoutinesOnlyInTarget {
+		migrations = append(migrations, fmt.Sprintf("-- Routine '%s' exists in %s but not in %s", name, opts.TargetName, opts.SourceName))
+		migrations = append(migrations, fmt.Sprintf("-- Manual review required for routine: %s\n", name))
+	}
+	for _, name := range diff.RoutinesOnlyInSource {
+		if driver == "postgres" {
+			migrations = append(migrations, fmt.Sprintf("-- DROP ROUTINE %s;  -- Routine exists in %s but not in %s\n", name, opts.SourceName, opts.TargetName))
+		} else {
+			// MySQL keys lead with the routine kind
+			stmt := "DROP FUNCTION or DROP PROCEDURE"
+			routine := name
+			if kind, rest, ok := strings.Cut(name, " "); ok && (kind == RoutineFunction || kind == RoutineProcedure) {
+				stmt, routine = "DROP "+strings.ToUpper(kind), rest
+			}
+			routine, _, _ = strings.Cut(routine, "(")
+			migrations = append(migrations, fmt.Sprintf("-- %s %s;  -- Routine exists in %s but not in %s\n", stmt, routine, opts.SourceName, opts.TargetName))
+		}
+	}
+	for _, d := range diff.RoutineDiffs {
+		migrations = append(migrations, fmt.Sprintf("-- Routine %s differs (%s); recreate it from the %s definition\n", d.Name, d.Diff, opts.TargetName))
+	}
+	return migrations
+}
+
+// generateEventMigrations lists scheduled event differences for review.
+// Like routine bodies, event bodies are normalized and can't be replayed.
+func generateEventMigrations(diff *SchemaDiff, opts MigrationOptions) []string {
+	var migrations []string
+	for _, name := range diff.EventsOnlyInTarget {
+		migrations = append(migrations, fmt.Sprintf("-- Event '%s' exists in %s but not in %s", name, opts.TargetName, opts.SourceName))
+		migrations = append(migrations, fmt.Sprintf("-- Manual review required for event: %s\n", name))
+	}
+	for _, name := range diff.EventsOnlyInSource {
+		migrations = append(migrations, fmt.Sprintf("-- DROP EVENT %s;  -- Event exists in %s but not in %s\n", name, opts.SourceName, opts.TargetName))
+	}
+	for _, d := range diff.EventDiffs {
+		migrations = append(migrations, fmt.Sprintf("-- Event %s differs (%s); recreate it from the %s definition\n", d.Name, d.Diff, opts.TargetName))
+	}
+	return migrations
+}
+
+// archiveTableStatements scaffolds keeping a table's data instead of dropping
+// it: rename it out of the way, or copy it into the archive schema first
+func archiveTableStatements(tableName, driver string, opts MigrationOptions) []string {
+	archived := tableName + opts.ArchiveSuffix
+	stmts := []string{fmt.Sprintf("-- Table '%s' exists in %s but not in %s; archive it instead of dropping:", tableName, opts.SourceName, opts.TargetName)}
+	if driver == "postgres" {
+		stmts = append(stmts,
+			fmt.Sprintf("-- ALTER TABLE %s RENAME TO %s;", tableName, archived),
+			"-- or copy it to the archive schema before dropping:",
+			fmt.Sprintf("-- CREATE SCHEMA IF NOT EXISTS %s;", opts.ArchiveSchema),
+		)
+	} else {
+		stmts = append(stmts,
+			fmt.Sprintf("-- RENAME TABLE %s TO %s;", tableName, archived),
+			"-- or copy it to the archive database before dropping:",
+			fmt.Sprintf("-- CREATE DATABASE IF NOT EXISTS %s;", opts.ArchiveSchema),
+		)
+	}
+	stmts = append(stmts,
+		fmt.Sprintf("-- CREATE TABLE %s.%s AS SELECT * FROM %s;", opts.ArchiveSchema, tableName, tableName),
+		fmt.Sprintf("-- DROP TABLE %s;\n", tableName),
+	)
+	return stmts
+}
+
+// inheritanceMigrations turns an inheritance diff, "inherits: a → a, b",
+// into ALTER TABLE ... INHERIT and NO INHERIT statements
+func inheritanceMigrations(tableName string, change AttributeChange) []string {
+	parents := func(list string) []string {
+		if list == "none" {
+			return nil
+		}
+		return strings.Split(list, ", ")
+	}
+	sourceParents, targetParents := parents(change.From), parents(change.To)
+	inSource, inTarget := makeSet(sourceParents), makeSet(targetParents)
+	var migrations []string
+	for _, parent := range sourceParents {
+		if !inTarget[parent] {
+			migrations = append(migrations, fmt.Sprintf("ALTER TABLE %s NO INHERIT %s;", tableName, parent))
+		}
+	}
+	for _, parent := range targetParents {
+		if !inSource[parent] {
+			migrations = append(migrations, fmt.Sprintf("ALTER TABLE %s INHERIT %s;", tableName, parent))
+		}
+	}
+	return migrations
+}
+
+// partitionMigrations turns a partitioning diff into statements: new
+// Postgres partitions are created, everything else (detaching or dropping
+// partitions, which moves or loses rows) is left commented out
+func partitionMigrations(tableName string, changes []AttributeChange, driver string, opts MigrationOptions) []string {
+	var migrations []string
+	pg := engineFamily(driver) == "postgres"
+	for _, change := range changes {
+		name, isPartition := strings.CutPrefix(change.Attribute, "partition ")
+		switch {
+		case change.Attribute == "partitioning":
+			migrations = append(migrations, fmt.Sprintf("-- Partitioning of %s changes from %s to %s; recreate the table and copy its rows", tableName, change.From, change.To))
+		case change.Attribute == "partition_of" && pg:
+			if change.From != "none" {
+				migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s DETACH PARTITION %s;", change.From, tableName))
+			}
+			if change.To != "none" {
+				migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s ATTACH PARTITION %s FOR VALUES ...;", change.To, tableName))
+			}
+		case !isPartition:
+		case pg && change.From == "none":
+			migrations = append(migrations, fmt.Sprintf("CREATE TABLE %s PARTITION OF %s %s;", name, tableName, change.To))
+		case pg && change.To == "none":
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s DETACH PARTITION %s;  -- Partition exists in %s but not in %s", tableName, name, opts.SourceName, opts.TargetName))
+		case pg:
+			migrations = append(migrations,
+				fmt.Sprintf("-- ALTER TABLE %s DETACH PARTITION %s;", tableName, name),
+				fmt.Sprintf("-- ALTER TABLE %s ATTACH PARTITION %s %s;", tableName, name, change.To))
+		case change.From == "none":
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s ADD PARTITION (PARTITION %s %s);", tableName, name, change.To))
+		case change.To == "none":
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s DROP PARTITION %s;  -- Partition exists in %s but not in %s; drops its rows", tableName, name, opts.SourceName, opts.TargetName))
+		default:
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s REORGANIZE PARTITION %s INTO (PARTITION %s %s);", tableName, name, name, change.To))
+		}
+	}
+	return migrations
+}
+
+func generateTableMigrations(diff *TableDiff, driver string, opts MigrationOptions) []string {
+	var migrations []string
+
+	// Table access method (ALTER TABLE ... SET ACCESS METHOD needs Postgres 15+)
+	for _, change := range diff.tableChanges("access_method") {
+		if driver == "postgres" {
+			migrations = append(migrations, fmt.Sprintf("ALTER TABLE %s SET ACCESS METHOD %s;", diff.TableName, change.To))
+		}
+	}
+
+	for _, change := range diff.tableChanges("replica_identity") {
+		if driver == "postgres" {
+			migrations = append(migrations, fmt.Sprintf("ALTER TABLE %s REPLICA IDENTITY %s;", diff.TableName,
+				replicaIdentityClause(change.To, func(name string) string { return name })))
+		}
+	}
+
+	// A table turning foreign or local, or moving to another server, is
+	// recreated; the data lives elsewhere
+	if diff.ForeignTableDiff != nil {
+		migrations = append(migrations, fmt.Sprintf("-- Foreign table definition differs (%s); recreate it with CREATE FOREIGN TABLE or adjust it with ALTER FOREIGN TABLE ... OPTIONS", *diff.ForeignTableDiff))
+	}
+
+	// Partitioning; changing the scheme of a table means recreating it
+	if diff.PartitioningDiff != nil {
+		migrations = append(migrations, partitionMigrations(diff.TableName, diff.tableChanges("partitioning"), driver, opts)...)
+	}
+
+	// Inheritance; parents are attached and detached in place
+	if driver == "postgres" {
+		for _, change := range diff.tableChanges("inherits") {
+			migrations = append(migrations, inheritanceMigrations(diff.TableName, change)...)
+		}
+	}
+
+	// Table comment
+	if diff.CommentDiff != nil {
+		if driver == "postgres" {
+			migrations = append(migrations, fmt.Sprintf("-- COMMENT ON TABLE %s IS '...';  -- %s", diff.TableName, *diff.CommentDiff))
+		} else {
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s COMMENT = '...';  -- %s", diff.TableName, *diff.CommentDiff))
+		}
+	}
+
+	// Objects only in target are created from their definitions when the
+	// diff carries them
+	created := diff.TargetOnly
+	if created == nil {
+		created = &MissingTableObjects{}
+	}
+
+	// Add columns
+	for _, colName := range diff.ColumnsOnlyInTarget {
+		if col := created.Columns[colName]; col != nil {
+			migrations = append(migrations, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;  -- Column exists in %s", diff.TableName, columnClause(col, driver), opts.TargetName))
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;  -- Column exists in %s", diff.TableName, colName, opts.TargetName))
+	}
+
+	// Drop columns
+	for _, colName := range diff.ColumnsOnlyInSource {
+		if opts.ArchiveBeforeDrop {
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s RENAME COLUMN %s TO %s%s;  -- Column exists in %s but not in %s; archived instead of dropped", diff.TableName, colName, colName, opts.ArchiveSuffix, opts.SourceName, opts.TargetName))
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s DROP COLUMN %s;  -- Column exists in %s but not in %s", diff.TableName, colName, opts.SourceName, opts.TargetName))
+	}
+
+	// Modify columns
+	for _, colDiff := range diff.ColumnDiffs {
+		if driver == "postgres" {
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s ALTER COLUMN %s ...;  -- %s", diff.TableName, colDiff.ColumnName, colDiff.Diff))
+		} else {
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s MODIFY COLUMN %s ...;  -- %s", diff.TableName, colDiff.ColumnName, colDiff.Diff))
+		}
+	}
+
+	// Add indexes
+	for _, idxName := range diff.IndexesOnlyInTarget {
+		if idx := created.Indexes[idxName]; idx != nil {
+			migrations = append(migrations, fmt.Sprintf("CREATE %sINDEX %s ON %s %s;  -- Index exists in %s", indexPrefix(idx, driver), idxName, diff.TableName,
+				indexClause(idx, driver, func(name string) string { return name }), opts.TargetName))
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("-- CREATE INDEX %s ON %s (...);  -- Index exists in %s", idxName, diff.TableName, opts.TargetName))
+	}
+
+	// Drop indexes
+	for _, idxName := range diff.IndexesOnlyInSource {
+		if driver == "postgres" {
+			migrations = append(migrations, fmt.Sprintf("-- DROP INDEX %s;  -- Index exists in %s but not in %s", idxName, opts.SourceName, opts.TargetName))
+		} else {
+			migrations = append(migrations, fmt.Sprintf("-- DROP INDEX %s ON %s;  -- Index exists in %s but not in %s", idxName, diff.TableName, opts.SourceName, opts.TargetName))
+		}
+	}
+
+	// Add foreign keys
+	for _, fkName := range diff.ForeignKeysOnlyInTarget {
+		if fk := created.ForeignKeys[fkName]; fk != nil {
+			stmt := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+				diff.TableName, fkName, strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", "))
+			if fk.OnDelete != "" {
+				stmt += " ON DELETE " + fk.OnDelete
+			}
+			if fk.OnUpdate != "" {
+				stmt += " ON UPDATE " + fk.OnUpdate
+			}
+			migrations = append(migrations, stmt+";  -- FK exists in "+opts.TargetName)
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (...) REFERENCES ...;  -- FK exists in %s", diff.TableName, fkName, opts.TargetName))
+	}
+
+	// Drop foreign keys
+	for _, fkName := range diff.ForeignKeysOnlyInSource {
+		if driver == "postgres" {
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s DROP CONSTRAINT %s;  -- FK exists in %s but not in %s", diff.TableName, fkName, opts.SourceName, opts.TargetName))
+		} else {
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s DROP FOREIGN KEY %s;  -- FK exists in %s but not in %s", diff.TableName, fkName, opts.SourceName, opts.TargetName))
+		}
+	}
+
+	// Add unique constraints
+	for _, uqName := range diff.UniquesOnlyInTarget {
+		if uq := created.UniqueConstraints[uqName]; uq != nil {
+			migrations = append(migrations, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (%s);  -- Unique constraint exists in %s", diff.TableName, uqName, strings.Join(uq.Columns, ", "), opts.TargetName))
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (...);  -- Unique constraint exists in %s", diff.TableName, uqName, opts.TargetName))
+	}
+
+	// Drop unique constraints
+	for _, uqName := range diff.UniquesOnlyInSource {
+		if driver == "postgres" {
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s DROP CONSTRAINT %s;  -- Unique constraint exists in %s but not in %s", diff.TableName, uqName, opts.SourceName, opts.TargetName))
+		} else {
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s DROP INDEX %s;  -- Unique constraint exists in %s but not in %s", diff.TableName, uqName, opts.SourceName, opts.TargetName))
+		}
+	}
+
+	// Add check constraints
+	for _, chkName := range diff.ChecksOnlyInTarget {
+		if chk := created.CheckConstraints[chkName]; chk != nil {
+			migrations = append(migrations, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;  -- Check constraint exists in %s", diff.TableName, chkName, checkClause(chk.Expression), opts.TargetName))
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s ADD CONSTRAINT %s CHECK (...);  -- Check constraint exists in %s", diff.TableName, chkName, opts.TargetName))
+	}
+
+	// Drop check constraints
+	for _, chkName := range diff.ChecksOnlyInSource {
+		if driver == "postgres" {
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s DROP CONSTRAINT %s;  -- Check constraint exists in %s but not in %s", diff.TableName, chkName, opts.SourceName, opts.TargetName))
+		} else {
+			migrations = append(migrations, fmt.Sprintf("-- ALTER TABLE %s DROP CHECK %s;  -- Check constraint exists in %s but not in %s", diff.TableName, chkName, opts.SourceName, opts.TargetName))
+		}
+	}
+
+	// Add triggers
+	for _, trgName := range diff.TriggersOnlyInTarget {
+		migrations = append(migrations, fmt.Sprintf("-- CREATE TRIGGER %s ... ON %s ...;  -- Trigger exists in %s", trgName, diff.TableName, opts.TargetName))
+	}
+
+	// Drop triggers
+	for _, trgName := range diff.TriggersOnlyInSource {
+		if driver == "postgres" {
+			migrations = append(migrations, fmt.Sprintf("-- DROP TRIGGER %s ON %s;  -- Trigger exists in %s but not in %s", trgName, diff.TableName, opts.SourceName, opts.TargetName))
+		} else {
+			migrations = append(migrations, fmt.Sprintf("-- DROP TRIGGER %s;  -- Trigger exists in %s but not in %s", trgName, opts.SourceName, opts.TargetName))
+		}
+	}
+
+	// Modified triggers: Postgres can switch the enabled state in place,
+	// anything else means recreating the trigger
+	for _, d := range diff.TriggerDiffs {
+		changes := d.Changes
+		if driver == "postgres" && len(changes) == 1 && changes[0].Attribute == "status" {
+			action := map[string]string{
+				"enabled":       "ENABLE",
+				TriggerDisabled: "DISABLE",
+				TriggerReplica:  "ENABLE REPLICA",
+				TriggerAlways:   "ENABLE ALWAYS",
+			}[changes[0].To]
+			migrations = append(migrations, fmt.Sprintf("ALTER TABLE %s %s TRIGGER %s;", diff.TableName, action, d.Name))
+			continue
+		}
+		migrations = append(migrations, fmt.Sprintf("-- Trigger %s differs (%s); drop and recreate it from the %s definition", d.Name, d.Diff, opts.TargetName))
+	}
+
+	// Rewrite rules (Postgres only)
+	for _, name := range diff.RewriteRulesOnlyInTarget {
+		migrations = append(migrations, fmt.Sprintf("-- CREATE RULE %s AS ON ... TO %s DO ...;  -- Rule exists in %s", name, diff.TableName, opts.TargetName))
+	}
+	for _, name := range diff.RewriteRulesOnlyInSource {
+		migrations = append(migrations, fmt.Sprintf("-- DROP RULE %s ON %s;  -- Rule exists in %s but not in %s", name, diff.TableName, opts.SourceName, opts.TargetName))
+	}
+	for _, d := range diff.RewriteRuleDiffs {
+		migrations = append(migrations, fmt.Sprintf("-- Rule %s differs (%s); recreate it with CREATE OR REPLACE RULE from the %s definition", d.Name, d.Diff, opts.TargetName))
+	}
+
+	return migrations
+}
+
+// columnClause renders a column definition for ALTER TABLE ... ADD COLUMN
+func columnClause(col *Column, driver string) string {
+	pg := engineFamily(driver) == "postgres"
+	dataType := col.DataType
+	if pg && col.UserType != "" {
+		dataType = col.UserType
+	} else if pg {
+		dataType = pgColumnType(col)
+	}
+	def := col.Name + " " + dataType
+	switch {
+	case col.IsGenerated && pg:
+		def += " GENERATED ALWAYS AS (" + col.GenerationExpression + ") " + strings.ToUpper(col.GenerationStorage)
+	case col.IsGenerated:
+		def += " AS (" + col.GenerationExpression + ") " + strings.ToUpper(col.GenerationStorage)
+	case pg && strings.HasPrefix(col.Identity, "identity"):
+		def += " GENERATED " + strings.ToUpper(strings.TrimPrefix(col.Identity, "identity ")) + " AS IDENTITY"
+	case col.Identity == IdentityAutoIncrement:
+		def += " AUTO_INCREMENT"
+	}
+	if !col.IsNullable {
+		def += " NOT NULL"
+	}
+	if col.DefaultValue != nil && !col.IsGenerated {
+		def += " DEFAULT " + *col.DefaultValue
+	}
+	return def
+}
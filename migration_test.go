@@ -0,0 +1,64 @@
+package dbdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateMigrationSQLNamesSidesOfDirection(t *testing.T) {
+	diff := &SchemaDiff{
+		TablesOnlyInSource: []string{"audit"},
+		TablesOnlyInTarget: []string{"legacy"},
+	}
+	for _, tc := range []struct {
+		direction string
+		want      []string
+	}{
+		{"", []string{
+			"-- Table 'legacy' exists in target but not in source",
+			"-- DROP TABLE audit;  -- Table exists in source but not in target",
+		}},
+		{DirectionTargetToSource, []string{
+			"-- Table 'legacy' exists in source but not in target",
+			"-- DROP TABLE audit;  -- Table exists in target but not in source",
+		}},
+	} {
+		diff.Direction = tc.direction
+		sql := GenerateMigrationSQL(diff, "postgres", MigrationOptions{})
+		for _, want := range tc.want {
+			if !strings.Contains(sql, want) {
+				t.Errorf("direction %q: migration lacks %q:\n%s", tc.direction, want, sql)
+			}
+		}
+	}
+}
+
+func TestGenerateMigrationSQLQuotesExtensions(t *testing.T) {
+	diff := &SchemaDiff{
+		ExtensionsOnlyInTarget: []string{`my"ext`},
+		ExtensionsOnlyInSource: []string{"uuid-ossp"},
+		ExtensionDiffs:         []*ExtensionDiff{{Name: "postgis", Diff: "version: 3.3 → 3.4'beta", Changes: []AttributeChange{attributeChange("version", "3.3", "3.4'beta")}}},
+	}
+	sql := GenerateMigrationSQL(diff, "postgres", MigrationOptions{})
+	for _, want := range []string{
+		`CREATE EXTENSION IF NOT EXISTS "my""ext";`,
+		`-- DROP EXTENSION "uuid-ossp";`,
+		`ALTER EXTENSION "postgis" UPDATE TO '3.4''beta';`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("migration lacks %s:\n%s", want, sql)
+		}
+	}
+}
+
+func TestGenerateMigrationSQLArchivesWithCallerSuffix(t *testing.T) {
+	diff := &SchemaDiff{TablesOnlyInSource: []string{"audit"}}
+	sql := GenerateMigrationSQL(diff, "postgres", MigrationOptions{ArchiveBeforeDrop: true, ArchiveSuffix: "_dropped_20261016"})
+	if want := "-- ALTER TABLE audit RENAME TO audit_dropped_20261016;"; !strings.Contains(sql, want) {
+		t.Errorf("migration lacks %s:\n%s", want, sql)
+	}
+	// Without a suffix the script does not depend on the day it is generated
+	if sql := GenerateMigrationSQL(diff, "postgres", MigrationOptions{ArchiveBeforeDrop: true}); !strings.Contains(sql, "RENAME TO audit_dropped;") {
+		t.Errorf("migration without a suffix:\n%s", sql)
+	}
+}
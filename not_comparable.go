@@ -0,0 +1,112 @@
+package dbdiff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ============================================================================
+// NOT COMPARABLE OBJECTS - Tables and catalogs the extracting role can't read
+// ============================================================================
+
+// NotComparable is a table, or a whole catalog such as "sequences", that
+// could not be extracted because the role lacks privileges on it. Kind is
+// "table" with the table as Name, or the object type of the catalog with an
+// empty Name.
+type NotComparable struct {
+	Side   string `json:"side,omitempty"` // source or target, in diffs
+	Kind   string `json:"kind"`
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// isPermissionDenied reports whether err comes from a missing privilege
+// rather than a broken connection or query
+func isPermissionDenied(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{
+		"permission denied",       // Postgres (SQLSTATE 42501)
+		"command denied to user",  // MySQL 1142, 1143 and 1370
+		"access denied",           // MySQL 1044 and 1227, BigQuery
+		"permission was denied",   // SQL Server
+		"insufficient privileges", // Oracle ORA-01031
+		"no permission for",       // Firebird
+	} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipDenied records a catalog the role may not read as not comparable and
+// returns nil for it; any other error is returned as is
+func (s *Schema) skipDenied(object string, err error) error {
+	if err == nil || !isPermissionDenied(err) {
+		return err
+	}
+	s.NotComparable = append(s.NotComparable, &NotComparable{Kind: object, Reason: err.Error()})
+	return nil
+}
+
+// SetAsideNotComparable removes what either side could not read from both
+// schemas, so that a table the role may not see on one side is not reported
+// as missing there, and returns those objects for the report
+func SetAsideNotComparable(source, target *Schema) []*NotComparable {
+	var objects []*NotComparable
+	for _, side := range []struct {
+		name   string
+		schema *Schema
+	}{{"source", source}, {"target", target}} {
+		for _, nc := range side.schema.NotComparable {
+			o := *nc
+			o.Side = side.name
+			objects = append(objects, &o)
+			for _, schema := range []*Schema{source, target} {
+				if o.Kind == CategoryTable {
+					delete(schema.Tables, o.Name)
+					continue
+				}
+				switch o.Kind {
+				case ObjectTypes:
+					schema.Types = nil
+				case ObjectSequences:
+					schema.Sequences = nil
+				case ObjectRoutines:
+					schema.Routines = nil
+				case ObjectExtensions:
+					schema.Extensions = nil
+				case ObjectCollations:
+					schema.Collations = nil
+				case ObjectEvents:
+					schema.Events = nil
+				case ObjectForeignTables:
+					schema.ForeignServers = nil
+				}
+			}
+		}
+	}
+	return objects
+}
+
+// printNotComparable lists the objects left out of the comparison for lack
+// of privileges, with how many there are on each side
+func printNotComparable(w io.Writer, objects []*NotComparable) {
+	if len(objects) == 0 {
+		return
+	}
+	counts := make(map[string]int)
+	for _, o := range objects {
+		counts[o.Side]++
+	}
+	fmt.Fprintf(w, "\n⛔ Not comparable (permission denied): %d object(s) skipped, %d in source, %d in target\n",
+		len(objects), counts["source"], counts["target"])
+	for _, o := range objects {
+		name := "all " + strings.ReplaceAll(o.Kind, "_", " ")
+		if o.Kind == CategoryTable {
+			name = "table " + o.Name
+		}
+		fmt.Fprintf(w, "  ? %s %s: %s\n", o.Side, name, o.Reason)
+	}
+}
@@ -0,0 +1,229 @@
+package dbdiff
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ============================================================================
+// ORPHANED OBJECTS - Dangling references left by half-applied migrations
+// ============================================================================
+
+// OrphanedObject is an object of one database that refers to something the
+// same database doesn't have: a foreign key to a dropped table, an index on
+// a dropped column, a sequence nothing uses. Category is one of the finding
+// categories and Name is table.object for objects of a table.
+type OrphanedObject struct {
+	Side     string `json:"side"` // source or target
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Detail   string `json:"detail"`
+}
+
+// expressionIdentifierPattern matches the identifiers of an index
+// expression with what follows them, so that function calls and casts can
+// be told apart from column references
+var expressionIdentifierPattern = regexp.MustCompile(`(::\s*)?"?\b([A-Za-z_][A-Za-z0-9_$]*)\b"?(\s*\()?`)
+
+// plainIdentifierPattern matches a key part that is a column name rather
+// than an expression
+var plainIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// expressionMentionsColumn reports whether an index expression refers to
+// any column of table; string literals, function names and cast types
+// don't count
+func expressionMentionsColumn(expr string, table *Table) bool {
+	var literal strings.Builder
+	inString := false
+	for _, r := range expr {
+		if r == '\'' {
+			inString = !inString
+			continue
+		}
+		if !inString {
+			literal.WriteRune(r)
+		}
+	}
+	for _, m := range expressionIdentifierPattern.FindAllStringSubmatch(literal.String(), -1) {
+		if m[1] != "" || m[3] != "" {
+			continue
+		}
+		if _, ok := table.Columns[m[2]]; ok {
+			return true
+		}
+		if _, ok := table.Columns[strings.ToLower(m[2])]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// OrphanReport checks source and target on their own for objects whose
+// counterparts are missing in the same database. Both sides can match and
+// still carry the same leftovers of a migration that stopped halfway.
+func OrphanReport(source, target *Schema, filter *FilterConfig) []*OrphanedObject {
+	var orphans []*OrphanedObject
+	for _, side := range []struct {
+		name   string
+		schema *Schema
+	}{{"source", source}, {"target", target}} {
+		for _, o := range FindOrphans(side.schema, filter) {
+			o.Side = side.name
+			orphans = append(orphans, o)
+		}
+	}
+	return orphans
+}
+
+// FindOrphans lists the dangling references of one schema: foreign keys to
+// missing tables or columns (missing because dropped or because the filters
+// excluded them), keys and indexes over missing columns or expressions over
+// none of the table's columns, partitions and children of missing parents,
+// replica identities using missing indexes, foreign tables of missing
+// servers, and sequences owned by a missing column or by nothing at all and
+// used by no column default
+func FindOrphans(schema *Schema, filter *FilterConfig) []*OrphanedObject {
+	var orphans []*OrphanedObject
+	add := func(category, name, format string, args ...any) {
+		orphans = append(orphans, &OrphanedObject{Category: category, Name: name, Detail: fmt.Sprintf(format, args...)})
+	}
+	missingTable := func(name string) string {
+		if filter.ShouldIgnoreTable(name) {
+			return fmt.Sprintf("missing table %s (excluded by filters)", name)
+		}
+		return "missing table " + name
+	}
+	missingColumns := func(table *Table, columns []string) []string {
+		var missing []string
+		for _, col := range columns {
+			if _, ok := table.Columns[col]; !ok {
+				missing = append(missing, col)
+			}
+		}
+		return missing
+	}
+
+	for _, tableName := range getSortedKeys(schema.Tables) {
+		if filter.ShouldIgnoreTable(tableName) {
+			continue
+		}
+		table := schema.Tables[tableName]
+
+		for _, name := range getSortedKeys(table.ForeignKeys) {
+			if filter.IgnoreForeignKeys {
+				break
+			}
+			fk := table.ForeignKeys[name]
+			// Tables of other schemas are never extracted
+			if strings.Contains(fk.RefTable, ".") {
+				continue
+			}
+			ref, ok := schema.Tables[fk.RefTable]
+			switch {
+			case !ok:
+				add(CategoryForeignKey, tableName+"."+name, "references %s", missingTable(fk.RefTable))
+			case len(missingColumns(ref, fk.RefColumns)) > 0:
+				add(CategoryForeignKey, tableName+"."+name, "references missing column(s) %s of %s", strings.Join(missingColumns(ref, fk.RefColumns), ", "), fk.RefTable)
+			}
+			if missing := missingColumns(table, fk.Columns); len(missing) > 0 {
+				add(CategoryForeignKey, tableName+"."+name, "on missing column(s) %s", strings.Join(missing, ", "))
+			}
+		}
+
+		if pk := table.PrimaryKey; pk != nil {
+			if missing := missingColumns(table, pk.Columns); len(missing) > 0 {
+				add(CategoryPrimaryKey, tableName+"."+pk.Name, "on missing column(s) %s", strings.Join(missing, ", "))
+			}
+		}
+		for _, name := range getSortedKeys(table.UniqueConstraints) {
+			if missing := missingColumns(table, table.UniqueConstraints[name].Columns); len(missing) > 0 {
+				add(CategoryUnique, tableName+"."+name, "on missing column(s) %s", strings.Join(missing, ", "))
+			}
+		}
+		for _, name := range getSortedKeys(table.Indexes) {
+			if filter.IgnoreIndexes {
+				break
+			}
+			idx := table.Indexes[name]
+			var missing []string
+			for _, col := range append(append([]string{}, idx.Columns...), idx.Include...) {
+				if _, ok := table.Columns[col]; ok {
+					continue
+				}
+				if plainIdentifierPattern.MatchString(col) || !expressionMentionsColumn(col, table) {
+					missing = append(missing, col)
+				}
+			}
+			if len(missing) > 0 {
+				add(CategoryIndex, tableName+"."+name, "on missing column(s) or expression(s) %s", strings.Join(missing, ", "))
+			}
+		}
+
+		if table.PartitionOf != "" && schema.Tables[table.PartitionOf] == nil {
+			add(CategoryTable, tableName, "partition of %s", missingTable(table.PartitionOf))
+		}
+		for _, parent := range table.Inherits {
+			if !strings.Contains(parent, ".") && schema.Tables[parent] == nil {
+				add(CategoryTable, tableName, "inherits from %s", missingTable(parent))
+			}
+		}
+		if index, ok := strings.CutPrefix(table.ReplicaIdentity, "index "); ok {
+			_, isIndex := table.Indexes[index]
+			_, isUnique := table.UniqueConstraints[index]
+			if !isIndex && !isUnique && (table.PrimaryKey == nil || table.PrimaryKey.Name != index) {
+				add(CategoryTable, tableName, "replica identity uses missing index %s", index)
+			}
+		}
+		if ft := table.Foreign; ft != nil && schema.ForeignServers != nil && schema.ForeignServers[ft.Server] == nil {
+			add(CategoryTable, tableName, "foreign table of missing server %s", ft.Server)
+		}
+	}
+
+	for _, name := range getSortedKeys(schema.Sequences) {
+		if filter.ShouldIgnoreTable(name) {
+			continue
+		}
+		seq := schema.Sequences[name]
+		if seq.OwnedBy != "" {
+			tableName, colName, _ := splitOwnedBy(seq.OwnedBy)
+			table := schema.Tables[tableName]
+			switch {
+			case table == nil:
+				add(CategorySequence, name, "owned by a column of %s", missingTable(tableName))
+			case table.Columns[colName] == nil:
+				add(CategorySequence, name, "owned by missing column %s", seq.OwnedBy)
+			}
+			continue
+		}
+		used := false
+		for _, table := range schema.Tables {
+			for _, col := range table.Columns {
+				if defaultSequence(col) == name || col.DefaultValue != nil && (strings.Contains(*col.DefaultValue, "'"+name+"'") || strings.Contains(*col.DefaultValue, "."+name+"'")) {
+					used = true
+				}
+			}
+		}
+		if !used {
+			add(CategorySequence, name, "owned by no column and used by no column default")
+		}
+	}
+	return orphans
+}
+
+// printOrphans lists the orphaned objects of each side
+func printOrphans(w io.Writer, orphans []*OrphanedObject) {
+	if len(orphans) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n🧟 Orphaned objects:")
+	side := ""
+	for _, o := range orphans {
+		if o.Side != side {
+			side = o.Side
+			fmt.Fprintf(w, "  %s:\n", strings.ToUpper(side))
+		}
+		fmt.Fprintf(w, "    ! %s %s: %s\n", strings.ReplaceAll(o.Category, "_", " "), o.Name, o.Detail)
+	}
+}
@@ -0,0 +1,696 @@
+package dbdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+)
+
+// ============================================================================
+// OUTPUT FORMATTING
+// ============================================================================
+
+// Output formats accepted by --format
+const (
+	FormatPretty   = "pretty"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	// FormatLockMatrix summarizes the locks of the migration instead of the diff
+	FormatLockMatrix = "lock-matrix"
+	// FormatBadge is a shields.io endpoint badge counting the findings, and
+	// FormatBadgeSVG the same badge rendered directly
+	FormatBadge    = "badge"
+	FormatBadgeSVG = "badge-svg"
+)
+
+// Directions accepted by --direction: which side the report and migration
+// change to match the other
+const (
+	DirectionSourceToTarget = "source-to-target"
+	// DirectionTargetToSource compares the target as the first side, so
+	// that the diff and migration turn the target into the source
+	DirectionTargetToSource = "target-to-source"
+	// DirectionBoth reports in source-to-target order with the actions for
+	// either side, and migrates both ways
+	DirectionBoth = "both"
+)
+
+// sideHeadings label the objects present on one side of a diff only
+type sideHeadings struct {
+	onlyInSource, onlyInTarget string
+}
+
+// directionHeadings phrases the one-sided headings for a diff's direction:
+// where the objects are and, with a direction, what happens to them. In a
+// target-to-source diff the first side is the target.
+func directionHeadings(direction string) sideHeadings {
+	switch direction {
+	case DirectionSourceToTarget:
+		return sideHeadings{"only in SOURCE (drop from source)", "only in TARGET (create in source)"}
+	case DirectionTargetToSource:
+		return sideHeadings{"only in TARGET (drop from target)", "only in SOURCE (create in target)"}
+	case DirectionBoth:
+		return sideHeadings{
+			"only in SOURCE (drop from source, or create in target)",
+			"only in TARGET (create in source, or drop from target)",
+		}
+	}
+	return sideHeadings{"only in SOURCE", "only in TARGET"}
+}
+
+// directionSummary describes a diff's direction in one line, or is empty
+// without one
+func directionSummary(direction string) string {
+	switch direction {
+	case DirectionSourceToTarget:
+		return "Changes to make the source match the target"
+	case DirectionTargetToSource:
+		return "Changes to make the target match the source"
+	case DirectionBoth:
+		return "Changes to make either side match the other"
+	}
+	return ""
+}
+
+func PrintDiff(diff *SchemaDiff, format string) {
+	if err := WriteDiff(os.Stdout, diff, format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// WriteDiff renders diff to w in the given format
+func WriteDiff(w io.Writer, diff *SchemaDiff, format string) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, diff)
+	case FormatMarkdown:
+		printMarkdown(w, diff)
+	case FormatHTML:
+		return printHTML(w, diff)
+	case FormatBadge:
+		return writeBadge(w, diff)
+	case FormatBadgeSVG:
+		return writeBadgeSVG(w, diff)
+	case FormatPretty, "":
+		printPretty(w, diff)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, diff *SchemaDiff) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diffJSON(diff))
+}
+
+// Badge is the JSON of a shields.io endpoint badge
+// (https://shields.io/badges/endpoint-badge)
+type Badge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// NewBadge summarizes diff for a badge: "schema drift: 0" in green, the number
+// of findings in orange, or in red when any of them is breaking
+func NewBadge(diff *SchemaDiff) *Badge {
+	badge := &Badge{SchemaVersion: 1, Label: "schema drift", Message: "0", Color: "brightgreen"}
+	findings, breaking := 0, 0
+	for _, f := range NewResult(diff).Findings() {
+		findings++
+		if f.Breaking() {
+			breaking++
+		}
+	}
+	if findings == 0 {
+		return badge
+	}
+	badge.Message, badge.Color = fmt.Sprintf("%d findings", findings), "orange"
+	if findings == 1 {
+		badge.Message = "1 finding"
+	}
+	if breaking > 0 {
+		badge.Message += fmt.Sprintf(", %d breaking", breaking)
+		badge.Color = "red"
+	}
+	return badge
+}
+
+func writeBadge(w io.Writer, diff *SchemaDiff) error {
+	return json.NewEncoder(w).Encode(NewBadge(diff))
+}
+
+// badgeColors are the shields.io colors NewBadge uses
+var badgeColors = map[string]string{
+	"brightgreen": "#4c1",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+}
+
+// writeBadgeSVG renders the badge in the flat shields.io style, for places
+// that embed a file rather than query an endpoint. Text widths are estimated
+// from the character count, as the font is not known here.
+func writeBadgeSVG(w io.Writer, diff *SchemaDiff) error {
+	badge := NewBadge(diff)
+	textWidth := func(s string) int { return 7*len(s) + 10 }
+	lw, mw := textWidth(badge.Label), textWidth(badge.Message)
+	label, message := template.HTMLEscapeString(badge.Label), template.HTMLEscapeString(badge.Message)
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">
+<title>%s: %s</title>
+<rect width="%d" height="20" fill="#555"/>
+<rect x="%d" width="%d" height="20" fill="%s"/>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%d" y="14">%s</text>
+<text x="%d" y="14">%s</text>
+</g>
+</svg>
+`, lw+mw, label, message, label, message, lw, lw, mw, badgeColors[badge.Color], lw/2, label, lw+mw/2, message)
+	return err
+}
+
+func printPretty(w io.Writer, diff *SchemaDiff) {
+	if isDiffEmpty(diff) {
+		fmt.Fprintln(w, "✓ No schema differences found")
+		printOrphans(w, diff.Orphans)
+		printNotComparable(w, diff.NotComparable)
+		printSuppressed(w, diff)
+		return
+	}
+
+	fmt.Fprintln(w, "Schema Differences Found:")
+	if summary := directionSummary(diff.Direction); summary != "" {
+		fmt.Fprintln(w, summary)
+	}
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+	headings := directionHeadings(diff.Direction)
+
+	if len(diff.ChangeSets) > 0 {
+		printChangeSets(w, diff)
+		printPrettyFooter(w, diff)
+		return
+	}
+
+	// Tables only in source
+	if len(diff.TablesOnlyInSource) > 0 {
+		fmt.Fprintf(w, "\n📋 Tables %s:\n", headings.onlyInSource)
+		for _, table := range diff.TablesOnlyInSource {
+			fmt.Fprintf(w, "  - %s\n", table)
+		}
+	}
+
+	// Tables only in target
+	if len(diff.TablesOnlyInTarget) > 0 {
+		fmt.Fprintf(w, "\n📋 Tables %s:\n", headings.onlyInTarget)
+		for _, table := range diff.TablesOnlyInTarget {
+			fmt.Fprintf(w, "  + %s\n", table)
+		}
+	}
+
+	// Table differences
+	for _, tableDiff := range diff.TableDiffs {
+		fmt.Fprintf(w, "\n📊 Table: %s\n", tableDiff.TableName)
+		fmt.Fprintln(w, strings.Repeat("-", 80))
+
+		if tableDiff.AccessMethodDiff != nil {
+			fmt.Fprintf(w, "  Table %s\n", *tableDiff.AccessMethodDiff)
+		}
+		if tableDiff.ReplicaIdentityDiff != nil {
+			fmt.Fprintf(w, "  Table %s\n", *tableDiff.ReplicaIdentityDiff)
+		}
+		if tableDiff.OptionsDiff != nil {
+			fmt.Fprintf(w, "  Table %s\n", *tableDiff.OptionsDiff)
+		}
+		if tableDiff.PartitioningDiff != nil {
+			fmt.Fprintf(w, "  Table %s\n", *tableDiff.PartitioningDiff)
+		}
+		if tableDiff.InheritanceDiff != nil {
+			fmt.Fprintf(w, "  Table %s\n", *tableDiff.InheritanceDiff)
+		}
+		if tableDiff.ForeignTableDiff != nil {
+			fmt.Fprintf(w, "  Table %s\n", *tableDiff.ForeignTableDiff)
+		}
+		if tableDiff.CommentDiff != nil {
+			fmt.Fprintf(w, "  Table %s\n", *tableDiff.CommentDiff)
+		}
+
+		// Columns
+		if len(tableDiff.ColumnsOnlyInSource) > 0 {
+			fmt.Fprintf(w, "  Columns %s:\n", headings.onlyInSource)
+			for _, col := range tableDiff.ColumnsOnlyInSource {
+				fmt.Fprintf(w, "    - %s\n", col)
+			}
+		}
+
+		if len(tableDiff.ColumnsOnlyInTarget) > 0 {
+			fmt.Fprintf(w, "  Columns %s:\n", headings.onlyInTarget)
+			for _, col := range tableDiff.ColumnsOnlyInTarget {
+				fmt.Fprintf(w, "    + %s\n", col)
+			}
+		}
+
+		if len(tableDiff.ColumnDiffs) > 0 {
+			fmt.Fprintln(w, "  Column differences:")
+			for _, colDiff := range tableDiff.ColumnDiffs {
+				fmt.Fprintf(w, "    ~ %s: %s\n", colDiff.ColumnName, colDiff.Diff)
+			}
+		}
+
+		// Primary Key
+		if tableDiff.PrimaryKeyDiff != nil {
+			fmt.Fprintf(w, "  Primary Key: %s\n", *tableDiff.PrimaryKeyDiff)
+		}
+
+		// Foreign Keys
+		printConstraintDiffs(w, headings, "Foreign Keys", tableDiff.ForeignKeysOnlyInSource, tableDiff.ForeignKeysOnlyInTarget, tableDiff.ForeignKeyDiffs)
+
+		// Unique Constraints
+		printConstraintDiffs(w, headings, "Unique Constraints", tableDiff.UniquesOnlyInSource, tableDiff.UniquesOnlyInTarget, tableDiff.UniqueDiffs)
+
+		// Indexes
+		printConstraintDiffs(w, headings, "Indexes", tableDiff.IndexesOnlyInSource, tableDiff.IndexesOnlyInTarget, tableDiff.IndexDiffs)
+
+		// Check Constraints
+		printConstraintDiffs(w, headings, "Check Constraints", tableDiff.ChecksOnlyInSource, tableDiff.ChecksOnlyInTarget, tableDiff.CheckDiffs)
+
+		// Triggers
+		printConstraintDiffs(w, headings, "Triggers", tableDiff.TriggersOnlyInSource, tableDiff.TriggersOnlyInTarget, tableDiff.TriggerDiffs)
+
+		// Rewrite rules
+		printConstraintDiffs(w, headings, "Rules", tableDiff.RewriteRulesOnlyInSource, tableDiff.RewriteRulesOnlyInTarget, tableDiff.RewriteRuleDiffs)
+	}
+
+	if len(diff.TypesOnlyInSource) > 0 || len(diff.TypesOnlyInTarget) > 0 || len(diff.TypeDiffs) > 0 {
+		fmt.Fprintln(w, "\n🏷️  Types:")
+		fmt.Fprintln(w, strings.Repeat("-", 80))
+		printConstraintDiffs(w, headings, "Types", diff.TypesOnlyInSource, diff.TypesOnlyInTarget, diff.TypeDiffs)
+	}
+
+	if len(diff.SequencesOnlyInSource) > 0 || len(diff.SequencesOnlyInTarget) > 0 || len(diff.SequenceDiffs) > 0 {
+		fmt.Fprintln(w, "\n🔢 Sequences:")
+		fmt.Fprintln(w, strings.Repeat("-", 80))
+		printConstraintDiffs(w, headings, "Sequences", diff.SequencesOnlyInSource, diff.SequencesOnlyInTarget, diff.SequenceDiffs)
+	}
+
+	if len(diff.RoutinesOnlyInSource) > 0 || len(diff.RoutinesOnlyInTarget) > 0 || len(diff.RoutineDiffs) > 0 {
+		fmt.Fprintln(w, "\n🧩 Routines:")
+		fmt.Fprintln(w, strings.Repeat("-", 80))
+		printConstraintDiffs(w, headings, "Routines", diff.RoutinesOnlyInSource, diff.RoutinesOnlyInTarget, diff.RoutineDiffs)
+	}
+
+	if len(diff.EventsOnlyInSource) > 0 || len(diff.EventsOnlyInTarget) > 0 || len(diff.EventDiffs) > 0 {
+		fmt.Fprintln(w, "\n⏰ Events:")
+		fmt.Fprintln(w, strings.Repeat("-", 80))
+		printConstraintDiffs(w, headings, "Events", diff.EventsOnlyInSource, diff.EventsOnlyInTarget, diff.EventDiffs)
+	}
+
+	if len(diff.ExtensionsOnlyInSource) > 0 || len(diff.ExtensionsOnlyInTarget) > 0 || len(diff.ExtensionDiffs) > 0 {
+		fmt.Fprintln(w, "\n🔌 Extensions:")
+		fmt.Fprintln(w, strings.Repeat("-", 80))
+		printConstraintDiffs(w, headings, "Extensions", diff.ExtensionsOnlyInSource, diff.ExtensionsOnlyInTarget, diff.ExtensionDiffs)
+	}
+
+	if len(diff.ForeignServersOnlyInSource) > 0 || len(diff.ForeignServersOnlyInTarget) > 0 || len(diff.ForeignServerDiffs) > 0 {
+		fmt.Fprintln(w, "\n🌐 Foreign servers:")
+		fmt.Fprintln(w, strings.Repeat("-", 80))
+		printConstraintDiffs(w, headings, "Foreign servers", diff.ForeignServersOnlyInSource, diff.ForeignServersOnlyInTarget, diff.ForeignServerDiffs)
+	}
+
+	printPrettyFooter(w, diff)
+}
+
+// printPrettyFooter prints what follows the differences: settings, grants,
+// constraint status, rules, annotations and hidden findings
+func printPrettyFooter(w io.Writer, diff *SchemaDiff) {
+	if len(diff.SettingDiffs) > 0 {
+		fmt.Fprintln(w, "\n⚙️  Server settings:")
+		for _, d := range diff.SettingDiffs {
+			fmt.Fprintf(w, "  ~ %s: %q → %q\n", d.Name, d.Source, d.Target)
+		}
+	}
+	if len(diff.GrantDiffs) > 0 {
+		fmt.Fprintln(w, "\n🔑 Grants:")
+		symbols := map[string]string{ChangeOnlyInSource: "-", ChangeOnlyInTarget: "+", ChangeModified: "~"}
+		for _, d := range diff.GrantDiffs {
+			line := fmt.Sprintf("  %s %s", symbols[d.Change], grantLine(d))
+			if d.Detail != "" {
+				line += ": " + d.Detail
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	printConstraintStatus(w, diff)
+	printSensitiveChanges(w, diff.SensitiveChanges)
+	printDefaultWarnings(w, diff.DefaultWarnings)
+	printCollationWarnings(w, diff.CollationWarnings)
+	printAppendOnly(w, diff.AppendOnlyWarnings, diff.AppendOnlyRows)
+	printOrphans(w, diff.Orphans)
+	printNotComparable(w, diff.NotComparable)
+	printRuleResults(w, diff.RuleResults)
+	printAnnotations(w, diff.Annotations)
+
+	printSuppressed(w, diff)
+
+	fmt.Fprintln(w)
+}
+
+// printChangeSets prints the findings of a diff grouped into change sets
+func printChangeSets(w io.Writer, diff *SchemaDiff) {
+	byKey := make(map[string]Finding)
+	for _, f := range NewResult(diff).Findings() {
+		byKey[f.Key()] = f
+	}
+	fmt.Fprintf(w, "%d change set(s), %d finding(s)\n", len(diff.ChangeSets), len(byKey))
+	markers := map[string]string{ChangeOnlyInSource: "-", ChangeOnlyInTarget: "+", ChangeModified: "~"}
+	for i, cs := range diff.ChangeSets {
+		fmt.Fprintf(w, "\n📦 [%d] %s (%d finding(s))\n", i+1, cs.Label, len(cs.Findings))
+		for _, key := range cs.Findings {
+			f := byKey[key]
+			fmt.Fprintf(w, "  %s %s\n", markers[f.Change], describeFinding(f))
+		}
+	}
+}
+
+// printConstraintStatus repeats the constraints present on both sides that
+// are not validated or not enforced on one of them: the constraint exists,
+// but existing rows may violate it
+func printConstraintStatus(w io.Writer, diff *SchemaDiff) {
+	findings := NewResult(diff).ConstraintStatusFindings()
+	if len(findings) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n⚠️  Constraint validation status:")
+	for _, f := range findings {
+		fmt.Fprintf(w, "  ~ %s.%s (%s): %s\n", f.Table, f.Name, strings.ReplaceAll(f.Category, "_", " "), f.Detail)
+	}
+}
+
+func printConstraintDiffs[T interface {
+	GetName() string
+	GetDiff() string
+}](
+	w io.Writer,
+	headings sideHeadings,
+	label string,
+	onlyInSource, onlyInTarget []string,
+	diffs []T,
+) {
+	hasAny := len(onlyInSource) > 0 || len(onlyInTarget) > 0 || len(diffs) > 0
+	if !hasAny {
+		return
+	}
+
+	if len(onlyInSource) > 0 {
+		fmt.Fprintf(w, "  %s %s:\n", label, headings.onlyInSource)
+		for _, name := range onlyInSource {
+			fmt.Fprintf(w, "    - %s\n", name)
+		}
+	}
+
+	if len(onlyInTarget) > 0 {
+		fmt.Fprintf(w, "  %s %s:\n", label, headings.onlyInTarget)
+		for _, name := range onlyInTarget {
+			fmt.Fprintf(w, "    + %s\n", name)
+		}
+	}
+
+	if len(diffs) > 0 {
+		fmt.Fprintf(w, "  %s differences:\n", label)
+		for _, d := range diffs {
+			fmt.Fprintf(w, "    ~ %s: %s\n", d.GetName(), d.GetDiff())
+		}
+	}
+}
+
+func printSuppressed(w io.Writer, diff *SchemaDiff) {
+	if len(diff.PartitionGroups) > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, g := range diff.PartitionGroups {
+		fmt.Fprintf(w, "(%s: %d partition(s) in source, %d in target compared as one table)\n", g.Name, g.SourcePartitions, g.TargetPartitions)
+	}
+	if len(diff.SuppressedTables) > 0 {
+		fmt.Fprintf(w, "\n(%d table(s) with comment-only differences suppressed)\n", len(diff.SuppressedTables))
+	}
+	if diff.FilteredFindings > 0 {
+		if isDiffEmpty(diff) {
+			fmt.Fprintf(w, "Note: the schemas are not identical; %d difference(s) were hidden by filters or normalization\n", diff.FilteredFindings)
+		} else {
+			fmt.Fprintf(w, "\n(%d more difference(s) hidden by filters or normalization)\n", diff.FilteredFindings)
+		}
+	}
+}
+
+func printRuleResults(w io.Writer, results []*RuleResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "\n🚦 Policy rules:")
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+	for _, r := range results {
+		fmt.Fprintf(w, "  [%s] %s", strings.ToUpper(r.Action), r.Rule)
+		if r.Message != "" {
+			fmt.Fprintf(w, ": %s", r.Message)
+		}
+		fmt.Fprintln(w)
+		for _, m := range r.Matches {
+			fmt.Fprintf(w, "    • %s\n", m)
+		}
+	}
+}
+
+func printAnnotations(w io.Writer, annotations []*Annotation) {
+	if len(annotations) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "\n📝 Reviewer notes:")
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+	for _, a := range annotations {
+		fmt.Fprintf(w, "  %s: %s\n", a.Key, a.Comment)
+	}
+}
+
+// groupFindings splits findings into added/dropped tables and per-table
+// groups, preserving report order. Findings not tied to a table (sequences)
+// are grouped under the empty table name.
+func groupFindings(diff *SchemaDiff) (tables []Finding, byTable []string, perTable map[string][]Finding) {
+	perTable = make(map[string][]Finding)
+	for _, f := range NewResult(diff).Findings() {
+		if f.Category == CategoryTable && f.Change != ChangeModified {
+			tables = append(tables, f)
+			continue
+		}
+		if _, ok := perTable[f.Table]; !ok {
+			byTable = append(byTable, f.Table)
+		}
+		perTable[f.Table] = append(perTable[f.Table], f)
+	}
+	return tables, byTable, perTable
+}
+
+func printMarkdown(w io.Writer, diff *SchemaDiff) {
+	fmt.Fprintln(w, "# Schema Differences")
+	fmt.Fprintln(w)
+	if summary := directionSummary(diff.Direction); summary != "" {
+		fmt.Fprintf(w, "_%s._\n\n", summary)
+	}
+	if isDiffEmpty(diff) {
+		fmt.Fprintln(w, "No schema differences found.")
+		if diff.FilteredFindings > 0 {
+			fmt.Fprintf(w, "\n_%d difference(s) were hidden by filters or normalization._\n", diff.FilteredFindings)
+		}
+		return
+	}
+
+	notes := annotationIndex(diff)
+	cell := func(s string) string { return strings.ReplaceAll(s, "|", `\|`) }
+	row := func(f Finding, first string) {
+		fmt.Fprintf(w, "| %s | %s | %s | %s |\n", cell(first), f.Change, cell(f.Detail), cell(notes[f.Key()]))
+	}
+
+	tables, order, perTable := groupFindings(diff)
+	if len(tables) > 0 {
+		fmt.Fprintln(w, "## Tables")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Table | Change | Detail | Note |")
+		fmt.Fprintln(w, "|-------|--------|--------|------|")
+		for _, f := range tables {
+			row(f, f.Name)
+		}
+		fmt.Fprintln(w)
+	}
+
+	for _, table := range order {
+		if table == "" {
+			fmt.Fprintln(w, "## Schema Objects")
+			fmt.Fprintln(w)
+		} else {
+			fmt.Fprintf(w, "## Table `%s`\n\n", table)
+		}
+		fmt.Fprintln(w, "| Object | Change | Detail | Note |")
+		fmt.Fprintln(w, "|--------|--------|--------|------|")
+		for _, f := range perTable[table] {
+			row(f, f.Category+" `"+f.Name+"`")
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(diff.SettingDiffs) > 0 {
+		fmt.Fprintln(w, "## Server Settings")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Setting | Source | Target |")
+		fmt.Fprintln(w, "|---------|--------|--------|")
+		for _, d := range diff.SettingDiffs {
+			fmt.Fprintf(w, "| %s | `%s` | `%s` |\n", cell(d.Name), cell(d.Source), cell(d.Target))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(diff.GrantDiffs) > 0 {
+		fmt.Fprintln(w, "## Grants")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Grantee | Privilege | Object | Change | Detail |")
+		fmt.Fprintln(w, "|---------|-----------|--------|--------|--------|")
+		for _, d := range diff.GrantDiffs {
+			fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", cell(d.Grantee), d.Privilege, cell(d.Object), d.Change, cell(d.Detail))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(diff.RuleResults) > 0 {
+		fmt.Fprintln(w, "## Policy Rules")
+		fmt.Fprintln(w)
+		for _, r := range diff.RuleResults {
+			fmt.Fprintf(w, "- **%s** `%s`", strings.ToUpper(r.Action), r.Rule)
+			if r.Message != "" {
+				fmt.Fprintf(w, ": %s", r.Message)
+			}
+			fmt.Fprintln(w)
+			for _, m := range r.Matches {
+				fmt.Fprintf(w, "  - %s\n", m)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Schema Differences</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.only_in_source { color: #b00; }
+.only_in_target { color: #070; }
+.changed { color: #a60; }
+.note { font-style: italic; }
+</style>
+</head>
+<body>
+<h1>Schema Differences</h1>
+{{if .Empty}}<p>No schema differences found.</p>{{end}}
+{{if .Filtered}}<p class="note">{{.Filtered}} difference(s) were hidden by filters or normalization.</p>{{end}}
+{{if .Tables}}<h2>Tables</h2>
+<table>
+<tr><th>Table</th><th>Change</th><th>Note</th></tr>
+{{range .Tables}}<tr><td>{{.Finding.Name}}</td><td class="{{.Finding.Change}}">{{.Finding.Change}}</td><td class="note">{{.Note}}</td></tr>
+{{end}}</table>{{end}}
+{{range .Groups}}<h2>{{if .Table}}Table {{.Table}}{{else}}Schema Objects{{end}}</h2>
+<table>
+<tr><th>Category</th><th>Name</th><th>Change</th><th>Detail</th><th>Note</th></tr>
+{{range .Rows}}<tr><td>{{.Finding.Category}}</td><td>{{.Finding.Name}}</td><td class="{{.Finding.Change}}">{{.Finding.Change}}</td><td>{{.Finding.Detail}}</td><td class="note">{{.Note}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Settings}}<h2>Server Settings</h2>
+<table>
+<tr><th>Setting</th><th>Source</th><th>Target</th></tr>
+{{range .Settings}}<tr><td>{{.Name}}</td><td>{{.Source}}</td><td>{{.Target}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Grants}}<h2>Grants</h2>
+<table>
+<tr><th>Grantee</th><th>Privilege</th><th>Object</th><th>Change</th><th>Detail</th></tr>
+{{range .Grants}}<tr><td>{{.Grantee}}</td><td>{{.Privilege}}</td><td>{{.Object}}</td><td>{{.Change}}</td><td>{{.Detail}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Rules}}<h2>Policy Rules</h2>
+<ul>
+{{range .Rules}}<li><strong>{{.Action}}</strong> {{.Rule}}{{if .Message}}: {{.Message}}{{end}}<ul>{{range .Matches}}<li>{{.}}</li>{{end}}</ul></li>
+{{end}}</ul>{{end}}
+</body>
+</html>
+`))
+
+type htmlRow struct {
+	Finding Finding
+	Note    string
+}
+
+type htmlGroup struct {
+	Table string
+	Rows  []htmlRow
+}
+
+func printHTML(w io.Writer, diff *SchemaDiff) error {
+	notes := annotationIndex(diff)
+	tables, order, perTable := groupFindings(diff)
+
+	data := struct {
+		Empty    bool
+		Filtered int
+		Tables   []htmlRow
+		Groups   []htmlGroup
+		Settings []*SettingDiff
+		Grants   []*GrantDiff
+		Rules    []*RuleResult
+	}{Empty: isDiffEmpty(diff), Filtered: diff.FilteredFindings, Settings: diff.SettingDiffs, Grants: diff.GrantDiffs, Rules: diff.RuleResults}
+
+	for _, f := range tables {
+		data.Tables = append(data.Tables, htmlRow{Finding: f, Note: notes[f.Key()]})
+	}
+	for _, table := range order {
+		group := htmlGroup{Table: table}
+		for _, f := range perTable[table] {
+			group.Rows = append(group.Rows, htmlRow{Finding: f, Note: notes[f.Key()]})
+		}
+		data.Groups = append(data.Groups, group)
+	}
+	return htmlReportTemplate.Execute(w, data)
+}
+
+// Implement interface methods for diff types
+func (d *FKDiff) GetName() string            { return d.Name }
+func (d *FKDiff) GetDiff() string            { return d.Diff }
+func (d *UniqueDiff) GetName() string        { return d.Name }
+func (d *UniqueDiff) GetDiff() string        { return d.Diff }
+func (d *IndexDiff) GetName() string         { return d.Name }
+func (d *IndexDiff) GetDiff() string         { return d.Diff }
+func (d *CheckDiff) GetName() string         { return d.Name }
+func (d *CheckDiff) GetDiff() string         { return d.Diff }
+func (d *TriggerDiff) GetName() string       { return d.Name }
+func (d *TriggerDiff) GetDiff() string       { return d.Diff }
+func (d *RewriteRuleDiff) GetName() string   { return d.Name }
+func (d *RewriteRuleDiff) GetDiff() string   { return d.Diff }
+func (d *TypeDiff) GetName() string          { return d.Name }
+func (d *TypeDiff) GetDiff() string          { return d.Diff }
+func (d *SequenceDiff) GetName() string      { return d.Name }
+func (d *SequenceDiff) GetDiff() string      { return d.Diff }
+func (d *RoutineDiff) GetName() string       { return d.Name }
+func (d *RoutineDiff) GetDiff() string       { return d.Diff }
+func (d *EventDiff) GetName() string         { return d.Name }
+func (d *EventDiff) GetDiff() string         { return d.Diff }
+func (d *ForeignServerDiff) GetName() string { return d.Name }
+func (d *ForeignServerDiff) GetDiff() string { return d.Diff }
+func (d *ExtensionDiff) GetName() string     { return d.Name }
+func (d *ExtensionDiff) GetDiff() string     { return d.Diff }
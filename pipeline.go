@@ -0,0 +1,69 @@
+package dbdiff
+
+import "maps"
+
+// ============================================================================
+// PIPELINE - The steps every comparison goes through
+// ============================================================================
+
+// prepareSchemas readies two extracted schemas for comparison with the
+// settings of cfg: the side the migration does not run on takes the schema
+// names of the other, sensitive columns are marked, and what either side could
+// not read is set aside and returned for the report. The schemas are changed in
+// place.
+func prepareSchemas(source, target *Schema, cfg *Config, direction string) (*Schema, *Schema, []*NotComparable, error) {
+	// The target is renamed into the source's schemas, so that reports and
+	// migrations use the names of the database the migration runs on
+	targetNames, err := invertSchemaMap(cfg.SchemaMap)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if direction == DirectionTargetToSource {
+		// The migration runs on the target, so the source takes its names
+		source = MapSchemas(source, cfg.SchemaMap)
+	} else {
+		target = MapSchemas(target, targetNames)
+	}
+	MarkSensitiveColumns(source, cfg.Sensitive)
+	MarkSensitiveColumns(target, cfg.Sensitive)
+	return source, target, SetAsideNotComparable(source, target), nil
+}
+
+// diffOptions selects the optional steps of diffSchemas
+type diffOptions struct {
+	Direction     string
+	NotComparable []*NotComparable
+	Orphans       bool
+}
+
+// diffSchemas diffs from, the side that changes, against to, with the
+// warnings every report carries: sensitive columns (whose defaults are
+// masked), session-dependent defaults, collation versions and append-only
+// tables. Steps that add to the diff run before finishDiff.
+func diffSchemas(from, to *Schema, filter *FilterConfig, cfg *Config, opts diffOptions) *SchemaDiff {
+	diff := ComputeDiff(from, to, filter)
+	diff.Direction = opts.Direction
+	diff.NotComparable = opts.NotComparable
+	diff.SensitiveChanges = SensitiveColumnChanges(diff, from, to)
+	diff.DefaultWarnings = SessionDependentDefaults(diff, from, to)
+	diff.CollationWarnings = CollationVersionWarnings(from, to)
+	diff.AppendOnlyWarnings = AppendOnlyWarnings(diff, cfg.AppendOnly)
+	if opts.Orphans {
+		diff.Orphans = OrphanReport(from, to, filter)
+	}
+	return diff
+}
+
+// finishDiff counts the findings the filters left out and sorts the diff
+func finishDiff(diff *SchemaDiff, from, to *Schema, sortMode string) error {
+	diff.FilteredFindings = CountFilteredFindings(from, to, diff)
+	return SortDiff(diff, sortMode)
+}
+
+// shallowSchema copies schema so that prepareSchemas can set objects aside
+// without touching the original, e.g. a snapshot cached by serve
+func shallowSchema(schema *Schema) *Schema {
+	s := *schema
+	s.Tables = maps.Clone(schema.Tables)
+	return &s
+}
@@ -0,0 +1,473 @@
+package dbdiff
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// QUICK CHECK - Cheap aggregate comparison before a full diff
+// ============================================================================
+
+// Object kinds listed by quick mode
+const (
+	quickTable      = "table"
+	quickColumn     = "column"
+	quickIndex      = "index"
+	quickPrimaryKey = "primary_key"
+	quickForeignKey = "foreign_key"
+	quickUnique     = "unique"
+	quickCheck      = "check"
+)
+
+// schemaObject is a named object of a table as listed by quick mode
+type schemaObject struct {
+	Table, Kind, Name string
+}
+
+// objectLister is implemented by dialects that can list the names of every
+// table's objects in a few catalog queries instead of several per table
+type objectLister interface {
+	listObjects(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]schemaObject, error)
+}
+
+// TableStats are the object counts of a table and a checksum of its sorted
+// object names
+type TableStats struct {
+	Columns     int    `json:"columns"`
+	Indexes     int    `json:"indexes"`
+	Constraints int    `json:"constraints"`
+	Checksum    string `json:"checksum"`
+}
+
+// SchemaStats summarizes a schema for dbdiff quick
+type SchemaStats struct {
+	Tables   map[string]*TableStats `json:"tables"`
+	Checksum string                 `json:"checksum"`
+}
+
+// QuickTableDelta is a table whose counts or object names differ
+type QuickTableDelta struct {
+	Table  string      `json:"table"`
+	Source *TableStats `json:"source"`
+	Target *TableStats `json:"target"`
+}
+
+// QuickDelta is the result of comparing two SchemaStats
+type QuickDelta struct {
+	SourceTables int               `json:"source_tables"`
+	TargetTables int               `json:"target_tables"`
+	OnlyInSource []string          `json:"only_in_source,omitempty"`
+	OnlyInTarget []string          `json:"only_in_target,omitempty"`
+	Changed      []QuickTableDelta `json:"changed,omitempty"`
+}
+
+// Match reports whether both schemas have the same tables, counts and names
+func (d *QuickDelta) Match() bool {
+	return len(d.OnlyInSource) == 0 && len(d.OnlyInTarget) == 0 && len(d.Changed) == 0
+}
+
+func (p *PostgresDialect) listObjects(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]schemaObject, error) {
+	filterSQL, args := tableFilterSQL(opts.Filter, "c.relname", nil, func(n int) string { return fmt.Sprintf("$%d", n) })
+	if !opts.IncludeExtensionObjects {
+		filterSQL += `
+			  AND NOT EXISTS (
+				SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e'
+			  )`
+	}
+	query := `
+		WITH t AS (
+			SELECT c.oid, c.relname
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = 'public'
+			  AND c.relkind IN ('r', 'p')` + filterSQL + `
+		)
+		SELECT t.relname::text, 'table', t.relname::text FROM t
+		UNION ALL
+		SELECT t.relname::text, 'column', a.attname::text
+		FROM t JOIN pg_attribute a ON a.attrelid = t.oid
+		WHERE a.attnum > 0 AND NOT a.attisdropped
+		UNION ALL
+		SELECT t.relname::text, 'index', i.relname::text
+		FROM t
+		JOIN pg_index x ON x.indrelid = t.oid
+		JOIN pg_class i ON i.oid = x.indexrelid
+		WHERE NOT EXISTS (SELECT 1 FROM pg_constraint k WHERE k.conindid = i.oid)
+		UNION ALL
+		SELECT t.relname::text,
+			CASE k.contype WHEN 'p' THEN 'primary_key' WHEN 'f' THEN 'foreign_key' WHEN 'u' THEN 'unique' ELSE 'check' END,
+			k.conname::text
+		FROM t JOIN pg_constraint k ON k.conrelid = t.oid
+		WHERE k.contype IN ('p', 'f', 'u', 'c')
+	`
+	return queryObjects(ctx, db, []string{query}, nil, args, opts.Filter)
+}
+
+func (m *MySQLDialect) listObjects(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]schemaObject, error) {
+	// Without --source-schemas/--target-schemas only the current database is
+	// listed, under plain table names
+	schemaIn, nameExpr, schemaArgs := "table_schema = DATABASE()", "table_name", []any(nil)
+	if len(opts.Schemas) > 0 {
+		schemaIn, schemaArgs = mysqlSchemaIn("table_schema", opts.Schemas)
+		if len(opts.Schemas) > 1 {
+			nameExpr = "CONCAT(table_schema, '.', table_name)"
+		}
+	}
+	filterSQL, args := tableFilterSQL(opts.Filter, nameExpr, nil, func(int) string { return "?" })
+	// Separate queries: a UNION over information_schema views can fail with
+	// "Illegal mix of collations" on some servers
+	queries := []string{
+		`SELECT ` + nameExpr + `, 'table', ` + nameExpr + `
+		FROM information_schema.tables
+		WHERE ` + schemaIn + `
+		  AND table_type = 'BASE TABLE'` + filterSQL,
+		`SELECT ` + nameExpr + `, 'column', column_name
+		FROM information_schema.columns
+		WHERE ` + schemaIn,
+		`SELECT DISTINCT ` + nameExpr + `, 'index', index_name
+		FROM information_schema.statistics s
+		WHERE ` + schemaIn + `
+		  AND index_name != 'PRIMARY'
+		  AND NOT EXISTS (
+			SELECT 1 FROM information_schema.table_constraints c
+			WHERE c.table_schema = s.table_schema
+			  AND c.table_name = s.table_name
+			  AND c.constraint_name = s.index_name
+			  AND c.constraint_type IN ('UNIQUE', 'FOREIGN KEY')
+		  )`,
+		`SELECT ` + nameExpr + `,
+			CASE constraint_type
+				WHEN 'PRIMARY KEY' THEN 'primary_key'
+				WHEN 'FOREIGN KEY' THEN 'foreign_key'
+				WHEN 'UNIQUE' THEN 'unique'
+				ELSE 'check'
+			END,
+			constraint_name
+		FROM information_schema.table_constraints
+		WHERE ` + schemaIn,
+	}
+	return queryObjects(ctx, db, queries, schemaArgs, args, opts.Filter)
+}
+
+func (d *MSSQLDialect) listObjects(ctx context.Context, db *sql.DB, opts ExtractOptions) ([]schemaObject, error) {
+	filterSQL, args := tableFilterSQL(opts.Filter, mssqlTableName, nil, mssqlPlaceholder)
+	query := `
+		WITH tbl AS (
+			SELECT t.object_id, ` + mssqlTableName + ` AS name
+			FROM sys.tables t
+			JOIN sys.schemas s ON s.schema_id = t.schema_id
+			WHERE t.is_ms_shipped = 0` + filterSQL + `
+		)
+		SELECT tbl.name, 'table', tbl.name FROM tbl
+		UNION ALL
+		SELECT tbl.name, 'column', c.name FROM tbl JOIN sys.columns c ON c.object_id = tbl.object_id
+		UNION ALL
+		SELECT tbl.name, 'index', i.name
+		FROM tbl JOIN sys.indexes i ON i.object_id = tbl.object_id
+		WHERE i.type > 0 AND i.is_primary_key = 0 AND i.is_unique_constraint = 0 AND i.is_hypothetical = 0
+		UNION ALL
+		SELECT tbl.name,
+			CASE o.type WHEN 'PK' THEN 'primary_key' WHEN 'F' THEN 'foreign_key' WHEN 'UQ' THEN 'unique' ELSE 'check' END,
+			o.name
+		FROM tbl JOIN sys.objects o ON o.parent_object_id = tbl.object_id
+		WHERE o.type IN ('PK', 'F', 'UQ', 'C')
+	`
+	return queryObjects(ctx, db, []string{query}, nil, args, opts.Filter)
+}
+
+// queryObjects runs queries returning (table, kind, name) rows; common are
+// passed to every query and args after them to the first one, which lists
+// the tables. Objects of unlisted tables (views, filtered tables) are dropped.
+func queryObjects(ctx context.Context, db *sql.DB, queries []string, common, args []any, filter *FilterConfig) ([]schemaObject, error) {
+	var objects []schemaObject
+	for i, query := range queries {
+		queryArgs := common
+		if i == 0 {
+			queryArgs = append(append([]any(nil), common...), args...)
+		}
+		rows, err := db.QueryContext(ctx, query, queryArgs...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var obj schemaObject
+			if err := rows.Scan(&obj.Table, &obj.Kind, &obj.Name); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			objects = append(objects, obj)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	tables := make(map[string]bool)
+	for _, obj := range objects {
+		if obj.Kind == quickTable && (filter == nil || !filter.ShouldIgnoreTable(obj.Table)) {
+			tables[obj.Table] = true
+		}
+	}
+	kept := objects[:0]
+	for _, obj := range objects {
+		if tables[obj.Table] {
+			kept = append(kept, obj)
+		}
+	}
+	return kept, nil
+}
+
+// schemaObjects lists the objects of an already loaded schema, e.g. a
+// snapshot or DDL file
+func schemaObjects(schema *Schema) []schemaObject {
+	var objects []schemaObject
+	for name, table := range schema.Tables {
+		objects = append(objects, schemaObject{name, quickTable, name})
+		for col := range table.Columns {
+			objects = append(objects, schemaObject{name, quickColumn, col})
+		}
+		for idx := range table.Indexes {
+			objects = append(objects, schemaObject{name, quickIndex, idx})
+		}
+		if table.PrimaryKey != nil {
+			objects = append(objects, schemaObject{name, quickPrimaryKey, table.PrimaryKey.Name})
+		}
+		for fk := range table.ForeignKeys {
+			objects = append(objects, schemaObject{name, quickForeignKey, fk})
+		}
+		for uq := range table.UniqueConstraints {
+			objects = append(objects, schemaObject{name, quickUnique, uq})
+		}
+		for chk := range table.CheckConstraints {
+			objects = append(objects, schemaObject{name, quickCheck, chk})
+		}
+	}
+	return objects
+}
+
+// BuildSchemaStats counts the objects of each table and checksums their
+// sorted names, leaving out whatever filter ignores
+func BuildSchemaStats(objects []schemaObject, filter *FilterConfig) *SchemaStats {
+	names := make(map[string][]string)
+	stats := &SchemaStats{Tables: make(map[string]*TableStats)}
+	for _, obj := range objects {
+		if filter.ShouldIgnoreTable(obj.Table) {
+			continue
+		}
+		ts := stats.Tables[obj.Table]
+		if ts == nil {
+			ts = &TableStats{}
+			stats.Tables[obj.Table] = ts
+		}
+		switch obj.Kind {
+		case quickTable:
+			continue
+		case quickColumn:
+			if filter.ShouldIgnoreColumn(obj.Table, obj.Name) {
+				continue
+			}
+			ts.Columns++
+		case quickIndex:
+			if filter.IgnoreIndexes {
+				continue
+			}
+			ts.Indexes++
+		case quickForeignKey:
+			if filter.IgnoreForeignKeys {
+				continue
+			}
+			ts.Constraints++
+		case quickCheck:
+			if filter.IgnoreChecks {
+				continue
+			}
+			ts.Constraints++
+		default:
+			ts.Constraints++
+		}
+		names[obj.Table] = append(names[obj.Table], obj.Kind+":"+obj.Name)
+	}
+
+	tables := make([]string, 0, len(stats.Tables))
+	for table, ts := range stats.Tables {
+		ts.Checksum = checksumLines(names[table])
+		tables = append(tables, table+":"+ts.Checksum)
+	}
+	stats.Checksum = checksumLines(tables)
+	return stats
+}
+
+// checksumLines returns a short hex SHA-256 of the sorted lines
+func checksumLines(lines []string) string {
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// CompareStats compares table sets, per-table counts and name checksums
+func CompareStats(source, target *SchemaStats) *QuickDelta {
+	delta := &QuickDelta{SourceTables: len(source.Tables), TargetTables: len(target.Tables)}
+	if source.Checksum == target.Checksum {
+		return delta
+	}
+	for name, s := range source.Tables {
+		t, ok := target.Tables[name]
+		switch {
+		case !ok:
+			delta.OnlyInSource = append(delta.OnlyInSource, name)
+		case *s != *t:
+			delta.Changed = append(delta.Changed, QuickTableDelta{Table: name, Source: s, Target: t})
+		}
+	}
+	for name := range target.Tables {
+		if _, ok := source.Tables[name]; !ok {
+			delta.OnlyInTarget = append(delta.OnlyInTarget, name)
+		}
+	}
+	sort.Strings(delta.OnlyInSource)
+	sort.Strings(delta.OnlyInTarget)
+	sort.Slice(delta.Changed, func(i, j int) bool { return delta.Changed[i].Table < delta.Changed[j].Table })
+	return delta
+}
+
+// loadSchemaStats lists the objects of one side of a quick comparison
+func loadSchemaStats(driver, conn string, opts ExtractOptions) (*SchemaStats, error) {
+	if isFileDriver(driver) {
+		schema, err := loadSchema(driver, conn, opts)
+		if err != nil {
+			return nil, err
+		}
+		return BuildSchemaStats(schemaObjects(schema), opts.Filter), nil
+	}
+
+	lister, ok := getDialect(driver).(objectLister)
+	if !ok {
+		return nil, fmt.Errorf("unsupported driver: %s", driver)
+	}
+	db, err := openDB(driver, conn)
+	if err != nil {
+		return nil, fmt.Errorf("connecting: %w", err)
+	}
+	defer db.Close()
+
+	objects, err := lister.listObjects(context.Background(), db, opts)
+	if err != nil {
+		return nil, err
+	}
+	return BuildSchemaStats(objects, opts.Filter), nil
+}
+
+func printQuickDelta(w io.Writer, delta *QuickDelta) {
+	fmt.Fprintf(w, "Quick check: %d source table(s), %d target table(s)\n", delta.SourceTables, delta.TargetTables)
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+	if delta.Match() {
+		fmt.Fprintln(w, "✓ Tables, object counts and object names match.")
+		fmt.Fprintln(w, "  Types, defaults and definitions were not compared; run the full diff to be sure.")
+		return
+	}
+
+	if len(delta.OnlyInSource) > 0 {
+		fmt.Fprintf(w, "\nTables only in source (%d):\n", len(delta.OnlyInSource))
+		for _, name := range delta.OnlyInSource {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+	}
+	if len(delta.OnlyInTarget) > 0 {
+		fmt.Fprintf(w, "\nTables only in target (%d):\n", len(delta.OnlyInTarget))
+		for _, name := range delta.OnlyInTarget {
+			fmt.Fprintf(w, "  + %s\n", name)
+		}
+	}
+	if len(delta.Changed) > 0 {
+		fmt.Fprintf(w, "\nTables with different objects (%d):\n", len(delta.Changed))
+		for _, c := range delta.Changed {
+			var parts []string
+			count := func(label string, s, t int) {
+				if s != t {
+					parts = append(parts, fmt.Sprintf("%s %d → %d", label, s, t))
+				}
+			}
+			count("columns", c.Source.Columns, c.Target.Columns)
+			count("indexes", c.Source.Indexes, c.Target.Indexes)
+			count("constraints", c.Source.Constraints, c.Target.Constraints)
+			if len(parts) == 0 {
+				parts = append(parts, "object names differ")
+			}
+			fmt.Fprintf(w, "  ~ %s: %s\n", c.Table, strings.Join(parts, ", "))
+		}
+	}
+	fmt.Fprintln(w, "\nSchemas differ; run the full diff for details.")
+}
+
+func runQuick(args []string) {
+	fs := flag.NewFlagSet("quick", flag.ExitOnError)
+	sourceConn := fs.String("source", "", "Source database connection string, or snapshot/ddl file")
+	sourceDriver := fs.String("source-driver", "", "Source database driver (postgres, yugabyte, mysql, tidb, sqlserver, oracle, firebird, bigquery, snapshot, ddl or fake)")
+	targetConn := fs.String("target", "", "Target database connection string, or snapshot/ddl file")
+	targetDriver := fs.String("target-driver", "", "Target database driver (postgres, yugabyte, mysql, tidb, sqlserver, oracle, firebird, bigquery, snapshot, ddl or fake)")
+	format := fs.String("format", FormatPretty, "Output format: pretty or json")
+	noUnicode := fs.Bool("no-unicode", false, "Use ASCII instead of symbols in the pretty output")
+	filterOpts := addFilterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff quick --source <conn> --source-driver <driver> --target <conn> --target-driver <driver> [--format pretty|json]")
+		fmt.Fprintln(os.Stderr, "\nCompares table lists, per-table column/index/constraint counts and a checksum of")
+		fmt.Fprintln(os.Stderr, "object names in a few catalog queries. Exits with 2 when they differ, so it can")
+		fmt.Fprintln(os.Stderr, "gate a full diff. Table, column, index, foreign key and check filters apply.")
+	}
+	fs.Parse(args)
+
+	if *sourceConn == "" || *sourceDriver == "" || *targetConn == "" || *targetDriver == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != FormatPretty && *format != FormatJSON {
+		fmt.Fprintf(os.Stderr, "Invalid format: %s (expected pretty or json)\n", *format)
+		os.Exit(1)
+	}
+	if *sourceConn == "-" && *targetConn == "-" {
+		fmt.Fprintln(os.Stderr, "Only one of --source and --target can read from stdin")
+		os.Exit(1)
+	}
+	filter, err := filterOpts.build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts := filterOpts.extractOptions(filter)
+
+	source, err := loadSchemaStats(*sourceDriver, *sourceConn, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading source schema: %v\n", err)
+		os.Exit(1)
+	}
+	target, err := loadSchemaStats(*targetDriver, *targetConn, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading target schema: %v\n", err)
+		os.Exit(1)
+	}
+
+	delta := CompareStats(source, target)
+	if *format == FormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(delta)
+	} else {
+		printQuickDelta(consoleOutput(os.Stdout, *noUnicode), delta)
+	}
+
+	if !delta.Match() {
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,129 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ============================================================================
+// REPLICA SELECTION - Keep catalog scans off primaries
+// ============================================================================
+
+// defaultMaxReplicaLag is the replication lag above which a replica is not
+// trusted to reflect the primary's schema
+const defaultMaxReplicaLag = 30 * time.Second
+
+// replicaChecker is implemented by dialects that can tell whether a server
+// is a replica and how far behind its primary it is
+type replicaChecker interface {
+	replicaStatus(ctx context.Context, db *sql.DB) (isReplica bool, lag time.Duration, err error)
+}
+
+func (p *PostgresDialect) replicaStatus(ctx context.Context, db *sql.DB) (bool, time.Duration, error) {
+	// An idle primary replays nothing, so lag only counts while WAL that was
+	// received is still waiting to be replayed
+	query := `
+		SELECT
+			pg_is_in_recovery(),
+			CASE
+				WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+			END
+	`
+	var inRecovery bool
+	var seconds float64
+	if err := db.QueryRowContext(ctx, query).Scan(&inRecovery, &seconds); err != nil {
+		return false, 0, err
+	}
+	return inRecovery, time.Duration(seconds * float64(time.Second)), nil
+}
+
+func (m *MySQLDialect) replicaStatus(ctx context.Context, db *sql.DB) (bool, time.Duration, error) {
+	// SHOW REPLICA STATUS needs MySQL 8.0.22+; older servers and MariaDB
+	// only know the SLAVE spelling
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		if rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS"); err != nil {
+			return false, 0, err
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, 0, err
+	}
+	if !rows.Next() {
+		return false, 0, rows.Err()
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return false, 0, err
+	}
+	for i, col := range columns {
+		if col != "Seconds_Behind_Source" && col != "Seconds_Behind_Master" {
+			continue
+		}
+		if !values[i].Valid {
+			return true, 0, fmt.Errorf("replication is not running")
+		}
+		seconds, err := strconv.Atoi(values[i].String)
+		if err != nil {
+			return true, 0, fmt.Errorf("parsing %s: %w", col, err)
+		}
+		return true, time.Duration(seconds) * time.Second, nil
+	}
+	return true, 0, fmt.Errorf("replica status has no Seconds_Behind_Source column")
+}
+
+// checkReplica verifies that conn is a replica lagging at most maxLag
+func checkReplica(driver, conn string, maxLag time.Duration) error {
+	checker, ok := getDialect(driver).(replicaChecker)
+	if !ok {
+		return fmt.Errorf("replica checks are not supported for %s", driver)
+	}
+	db, err := openDB(driver, conn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	isReplica, lag, err := checker.replicaStatus(ctx, db)
+	switch {
+	case err != nil:
+		return err
+	case !isReplica:
+		return fmt.Errorf("server is not a replica")
+	case lag > maxLag:
+		return fmt.Errorf("replication lag %s exceeds %s", lag.Round(time.Second), maxLag)
+	}
+	return nil
+}
+
+// chooseConnection returns replica when it passes checkReplica, otherwise
+// primary when fallback is allowed. The choice is reported on stderr.
+func chooseConnection(side, driver, primary, replica string, maxLag time.Duration, fallback bool) (string, error) {
+	if replica == "" {
+		return primary, nil
+	}
+	err := checkReplica(driver, replica, maxLag)
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "Extracting %s schema from its replica\n", side)
+		return replica, nil
+	}
+	if !fallback {
+		return "", fmt.Errorf("%s replica: %w", side, err)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s replica unusable (%v); falling back to the primary\n", side, err)
+	return primary, nil
+}
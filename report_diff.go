@@ -0,0 +1,150 @@
+package dbdiff
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ============================================================================
+// REPORT DIFF - Drift progress between two saved reports
+// ============================================================================
+
+// FindingChange is a finding present in both reports with a different detail
+type FindingChange struct {
+	Old Finding `json:"old"`
+	New Finding `json:"new"`
+}
+
+// ReportDelta classifies the findings of two saved --json reports
+type ReportDelta struct {
+	New       []Finding       `json:"new"`
+	Resolved  []Finding       `json:"resolved"`
+	Changed   []FindingChange `json:"changed"`
+	Unchanged []Finding       `json:"unchanged"`
+}
+
+// LoadReport reads a diff saved with --json
+func LoadReport(path string) (*SchemaDiff, error) {
+	data, err := readTextFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var diff SchemaDiff
+	if err := json.Unmarshal(data, &diff); err != nil {
+		return nil, fmt.Errorf("parsing report %s: %w", path, err)
+	}
+	return &diff, nil
+}
+
+// CompareReports matches findings of two reports by object and kind of
+// change. A finding whose detail differs between the reports (e.g. a column
+// type that drifted further) is reported as changed.
+func CompareReports(before, after *SchemaDiff) *ReportDelta {
+	identity := func(f Finding) string { return f.Key() + " " + f.Change }
+
+	oldFindings := NewResult(before).Findings()
+	oldByID := make(map[string]Finding, len(oldFindings))
+	for _, f := range oldFindings {
+		oldByID[identity(f)] = f
+	}
+
+	delta := &ReportDelta{}
+	seen := make(map[string]bool)
+	for _, f := range NewResult(after).Findings() {
+		id := identity(f)
+		seen[id] = true
+		prev, ok := oldByID[id]
+		switch {
+		case !ok:
+			delta.New = append(delta.New, f)
+		case prev.Detail != f.Detail:
+			delta.Changed = append(delta.Changed, FindingChange{Old: prev, New: f})
+		default:
+			delta.Unchanged = append(delta.Unchanged, f)
+		}
+	}
+	for _, f := range oldFindings {
+		if !seen[identity(f)] {
+			delta.Resolved = append(delta.Resolved, f)
+		}
+	}
+	return delta
+}
+
+func printReportDelta(w io.Writer, delta *ReportDelta, showUnchanged bool) {
+	fmt.Fprintf(w, "Drift progress: %d new, %d resolved, %d changed, %d unchanged\n",
+		len(delta.New), len(delta.Resolved), len(delta.Changed), len(delta.Unchanged))
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+
+	section := func(title, marker string, findings []Finding) {
+		if len(findings) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "\n%s (%d):\n", title, len(findings))
+		for _, f := range findings {
+			fmt.Fprintf(w, "  %s %s\n", marker, describeFinding(f))
+		}
+	}
+	section("New", "+", delta.New)
+	section("Resolved", "✓", delta.Resolved)
+	if len(delta.Changed) > 0 {
+		fmt.Fprintf(w, "\nChanged (%d):\n", len(delta.Changed))
+		for _, c := range delta.Changed {
+			fmt.Fprintf(w, "  ~ %s\n", describeFinding(c.New))
+			fmt.Fprintf(w, "      was: %s\n", c.Old.Detail)
+		}
+	}
+	if showUnchanged {
+		section("Unchanged", "=", delta.Unchanged)
+	}
+}
+
+func runReportDiff(args []string) {
+	fs := flag.NewFlagSet("report-diff", flag.ExitOnError)
+	format := fs.String("format", FormatPretty, "Output format: pretty or json")
+	showUnchanged := fs.Bool("show-unchanged", false, "List unchanged findings in the pretty output")
+	noUnicode := fs.Bool("no-unicode", false, "Use ASCII instead of symbols in the pretty output")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff report-diff [--format pretty|json] [--show-unchanged] [--no-unicode] <old.json> <new.json>")
+		fmt.Fprintln(os.Stderr, "\nCompares two reports saved with --json and lists new, resolved, changed and")
+		fmt.Fprintln(os.Stderr, "unchanged drift. Exits with 2 when the new report has drift the old one did not.")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != FormatPretty && *format != FormatJSON {
+		fmt.Fprintf(os.Stderr, "Invalid format: %s (expected pretty or json)\n", *format)
+		os.Exit(1)
+	}
+
+	before, err := LoadReport(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading report: %v\n", err)
+		os.Exit(1)
+	}
+	after, err := LoadReport(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading report: %v\n", err)
+		os.Exit(1)
+	}
+
+	delta := CompareReports(before, after)
+	if *format == FormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(delta)
+	} else {
+		printReportDelta(consoleOutput(os.Stdout, *noUnicode), delta, *showUnchanged)
+	}
+
+	if len(delta.New) > 0 {
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,728 @@
+package dbdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// RESULT - Library-facing view of a diff
+// ============================================================================
+
+// Finding categories
+const (
+	CategoryTable       = "table"
+	CategoryColumn      = "column"
+	CategoryPrimaryKey  = "primary_key"
+	CategoryForeignKey  = "foreign_key"
+	CategoryUnique      = "unique"
+	CategoryIndex       = "index"
+	CategoryCheck       = "check"
+	CategoryTrigger     = "trigger"
+	CategoryRewriteRule = "rewrite_rule"
+	// CategoryType, CategorySequence, CategoryRoutine, CategoryEvent,
+	// CategoryExtension and CategoryForeignServer findings are not tied to a
+	// table; their Table is empty
+	CategoryType          = "type"
+	CategorySequence      = "sequence"
+	CategoryRoutine       = "routine"
+	CategoryEvent         = "event"
+	CategoryExtension     = "extension"
+	CategoryForeignServer = "foreign_server"
+)
+
+// Finding changes
+const (
+	ChangeOnlyInSource = "only_in_source"
+	ChangeOnlyInTarget = "only_in_target"
+	ChangeModified     = "changed"
+)
+
+// Finding is a single difference flattened out of a SchemaDiff
+type Finding struct {
+	Table    string `json:"table"`
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Change   string `json:"change"`
+	Detail   string `json:"detail,omitempty"`
+	// Changes are the attribute changes rendered in Detail
+	Changes []AttributeChange `json:"changes,omitempty"`
+}
+
+// Key identifies the object a finding is about, independent of its detail
+func (f Finding) Key() string {
+	if f.Table == "" {
+		return f.Category + ":" + f.Name
+	}
+	return f.Category + ":" + f.Table + "." + f.Name
+}
+
+// Breaking reports whether applying this finding to the source can break
+// existing applications or data: dropping tables or columns, changing column
+// types, tightening nullability, changing the primary key or partitioning,
+// or adding constraints that existing rows may violate.
+func (f Finding) Breaking() bool {
+	switch f.Category {
+	case CategoryTable, CategoryColumn, CategoryType:
+		if f.Change == ChangeOnlyInSource {
+			return true
+		}
+		for _, c := range f.Changes {
+			if breakingChange(diffCode(f.Category, f.Change, c.Attribute).Kind, c) {
+				return true
+			}
+		}
+	case CategoryPrimaryKey:
+		return true
+	case CategoryForeignKey, CategoryUnique, CategoryCheck:
+		return f.Change != ChangeOnlyInSource
+	case CategorySequence, CategoryRoutine, CategoryEvent, CategoryExtension, CategoryForeignServer:
+		return f.Change == ChangeOnlyInSource
+	}
+	return false
+}
+
+// breakingChange reports whether a change of the given code kind to a table,
+// column or type rejects or loses existing rows
+func breakingChange(kind string, c AttributeChange) bool {
+	switch kind {
+	case "column_type_changed":
+		// A change of the user type a column uses is the type's finding
+		return c.Attribute == "type"
+	case "column_nullable_changed":
+		return c.From == "true" && c.To == "false"
+	case "column_generated_changed", "type_not_null_changed":
+		return c.From == "false" && c.To == "true"
+	// A new partitioning scheme, dropped partitions and narrower bounds
+	case "table_partitioning_changed", "table_partition_changed":
+		return c.From != "none"
+	// Removed enum labels and tighter domains reject existing values
+	case "type_labels_changed", "type_attributes_changed":
+		return strings.HasPrefix(c.Note, "removed [") || strings.Contains(c.Note, ", removed [")
+	case "type_checks_changed", "type_base_type_changed":
+		return true
+	}
+	return false
+}
+
+// tableChangeField names the TableDiff field a table-level attribute change
+// is rendered in; partition bounds and parents belong to the partitioning
+func tableChangeField(attribute string) string {
+	if strings.HasPrefix(attribute, "partition") {
+		return "partitioning"
+	}
+	return attribute
+}
+
+// DiffCode is the stable identifier of a kind of difference, for automation
+// that allows or alerts on classes of drift without parsing Detail
+type DiffCode struct {
+	Code string `json:"code"` // e.g. DBD101
+	Kind string `json:"kind"` // e.g. column_type_changed
+}
+
+// diffCodeCategories assigns each category a block of 20 codes starting at
+// base: base+0 is a change of an attribute not listed, base+1... the listed
+// attributes in order, base+18 only in source and base+19 only in target.
+// Codes are published; never reorder or remove attributes, only append.
+var diffCodeCategories = map[string]struct {
+	base       int
+	attributes []string
+}{
+	CategoryTable:         {0, []string{"comment", "access_method", "replica_identity", "options", "partitioning", "partition", "partition_of", "inherits", "foreign"}},
+	CategoryColumn:        {100, []string{"type", "nullable", "default", "identity", "sequence", "generated", "generation_expression", "generation_storage", "storage", "length", "precision", "scale", "datetime_precision", "comment"}},
+	CategoryPrimaryKey:    {200, []string{"added", "removed", "columns"}},
+	CategoryForeignKey:    {220, []string{"columns", "ref_table", "ref_columns", "on_delete", "on_update", "status"}},
+	CategoryUnique:        {240, []string{"columns"}},
+	CategoryCheck:         {260, []string{"expression", "status"}},
+	CategoryIndex:         {300, []string{"columns", "unique", "kind", "method", "op_classes", "orders", "prefix_lengths", "include", "options"}},
+	CategoryTrigger:       {400, []string{"timing", "events", "level", "condition", "function", "body", "status"}},
+	CategoryRewriteRule:   {420, []string{"event", "instead", "condition", "actions"}},
+	CategoryType:          {500, []string{"kind", "labels", "base_type", "not_null", "default", "checks", "attributes"}},
+	CategorySequence:      {520, []string{"data_type", "start", "increment", "min_value", "max_value", "cache", "cycle", "owned_by"}},
+	CategoryRoutine:       {600, []string{"kind", "arguments", "returns", "language", "body"}},
+	CategoryEvent:         {620, []string{"schedule", "status", "on_completion", "body"}},
+	CategoryExtension:     {700, []string{"version"}},
+	CategoryForeignServer: {720, []string{"wrapper", "options", "user_mappings"}},
+}
+
+// diffCode returns the code of a change to category. attribute is the
+// attribute changed by a ChangeModified finding; only its first word counts,
+// so "partition p2024: ..." is a partition bounds change and "type status_t
+// labels: ..." a column type change.
+func diffCode(category, change, attribute string) DiffCode {
+	block, ok := diffCodeCategories[category]
+	if !ok {
+		return DiffCode{}
+	}
+	offset, kind := 0, category+"_changed"
+	switch change {
+	case ChangeOnlyInSource:
+		offset, kind = 18, category+"_only_in_source"
+	case ChangeOnlyInTarget:
+		offset, kind = 19, category+"_only_in_target"
+	default:
+		attribute, _, _ = strings.Cut(attribute, " ")
+		for i, attr := range block.attributes {
+			if attr == attribute {
+				offset, kind = i+1, category+"_"+attr+"_changed"
+				break
+			}
+		}
+	}
+	return DiffCode{Code: fmt.Sprintf("DBD%03d", block.base+offset), Kind: kind}
+}
+
+// Codes returns the codes of the finding: one per attribute a modified object
+// changes, so "type: integer → bigint; nullable: true → false" on a column is
+// DBD101 column_type_changed and DBD102 column_nullable_changed
+func (f Finding) Codes() []DiffCode {
+	if f.Change != ChangeModified {
+		return []DiffCode{diffCode(f.Category, f.Change, "")}
+	}
+	var codes []DiffCode
+	seen := make(map[string]bool)
+	for _, c := range f.Changes {
+		code := diffCode(f.Category, f.Change, c.Attribute)
+		if !seen[code.Code] {
+			seen[code.Code] = true
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		codes = append(codes, diffCode(f.Category, f.Change, ""))
+	}
+	return codes
+}
+
+// CodedFinding is a finding with its codes, as listed under "findings" in
+// JSON output
+type CodedFinding struct {
+	Finding
+	Codes []DiffCode `json:"codes"`
+}
+
+// diffJSON is the document of --json output: the diff with its findings and
+// their codes
+func diffJSON(diff *SchemaDiff) any {
+	var findings []CodedFinding
+	for _, f := range NewResult(diff).Findings() {
+		findings = append(findings, CodedFinding{Finding: f, Codes: f.Codes()})
+	}
+	return struct {
+		*SchemaDiff
+		Findings []CodedFinding `json:"findings,omitempty"`
+	}{diff, findings}
+}
+
+// Result wraps a SchemaDiff with helpers for applications embedding dbdiff
+type Result struct {
+	Diff *SchemaDiff
+}
+
+// NewResult wraps diff; a nil diff is treated as empty
+func NewResult(diff *SchemaDiff) *Result {
+	if diff == nil {
+		diff = &SchemaDiff{}
+	}
+	return &Result{Diff: diff}
+}
+
+// HasChanges reports whether any difference was found
+func (r *Result) HasChanges() bool {
+	return !isDiffEmpty(r.Diff)
+}
+
+// HasBreakingChanges reports whether any finding is breaking
+func (r *Result) HasBreakingChanges() bool {
+	for _, f := range r.Findings() {
+		if f.Breaking() {
+			return true
+		}
+	}
+	return false
+}
+
+// ByTable returns the differences of a table present on both sides, or nil
+func (r *Result) ByTable(name string) *TableDiff {
+	for _, td := range r.Diff.TableDiffs {
+		if td.TableName == name {
+			return td
+		}
+	}
+	return nil
+}
+
+// Findings flattens the diff in report order
+func (r *Result) Findings() []Finding {
+	var findings []Finding
+	for _, name := range r.Diff.TablesOnlyInSource {
+		findings = append(findings, Finding{Table: name, Category: CategoryTable, Name: name, Change: ChangeOnlyInSource})
+	}
+	for _, name := range r.Diff.TablesOnlyInTarget {
+		findings = append(findings, Finding{Table: name, Category: CategoryTable, Name: name, Change: ChangeOnlyInTarget})
+	}
+	for _, td := range r.Diff.TableDiffs {
+		findings = append(findings, tableFindings(td)...)
+	}
+	for _, name := range r.Diff.TypesOnlyInSource {
+		findings = append(findings, Finding{Category: CategoryType, Name: name, Change: ChangeOnlyInSource})
+	}
+	for _, name := range r.Diff.TypesOnlyInTarget {
+		findings = append(findings, Finding{Category: CategoryType, Name: name, Change: ChangeOnlyInTarget})
+	}
+	for _, d := range r.Diff.TypeDiffs {
+		findings = append(findings, Finding{Category: CategoryType, Name: d.Name, Change: ChangeModified, Detail: d.Diff, Changes: d.Changes})
+	}
+	for _, name := range r.Diff.SequencesOnlyInSource {
+		findings = append(findings, Finding{Category: CategorySequence, Name: name, Change: ChangeOnlyInSource})
+	}
+	for _, name := range r.Diff.SequencesOnlyInTarget {
+		findings = append(findings, Finding{Category: CategorySequence, Name: name, Change: ChangeOnlyInTarget})
+	}
+	for _, d := range r.Diff.SequenceDiffs {
+		findings = append(findings, Finding{Category: CategorySequence, Name: d.Name, Change: ChangeModified, Detail: d.Diff, Changes: d.Changes})
+	}
+	for _, name := range r.Diff.RoutinesOnlyInSource {
+		findings = append(findings, Finding{Category: CategoryRoutine, Name: name, Change: ChangeOnlyInSource})
+	}
+	for _, name := range r.Diff.RoutinesOnlyInTarget {
+		findings = append(findings, Finding{Category: CategoryRoutine, Name: name, Change: ChangeOnlyInTarget})
+	}
+	for _, d := range r.Diff.RoutineDiffs {
+		findings = append(findings, Finding{Category: CategoryRoutine, Name: d.Name, Change: ChangeModified, Detail: d.Diff, Changes: d.Changes})
+	}
+	for _, name := range r.Diff.EventsOnlyInSource {
+		findings = append(findings, Finding{Category: CategoryEvent, Name: name, Change: ChangeOnlyInSource})
+	}
+	for _, name := range r.Diff.EventsOnlyInTarget {
+		findings = append(findings, Finding{Category: CategoryEvent, Name: name, Change: ChangeOnlyInTarget})
+	}
+	for _, d := range r.Diff.EventDiffs {
+		findings = append(findings, Finding{Category: CategoryEvent, Name: d.Name, Change: ChangeModified, Detail: d.Diff, Changes: d.Changes})
+	}
+	for _, name := range r.Diff.ExtensionsOnlyInSource {
+		findings = append(findings, Finding{Category: CategoryExtension, Name: name, Change: ChangeOnlyInSource})
+	}
+	for _, name := range r.Diff.ExtensionsOnlyInTarget {
+		findings = append(findings, Finding{Category: CategoryExtension, Name: name, Change: ChangeOnlyInTarget})
+	}
+	for _, d := range r.Diff.ExtensionDiffs {
+		findings = append(findings, Finding{Category: CategoryExtension, Name: d.Name, Change: ChangeModified, Detail: d.Diff, Changes: d.Changes})
+	}
+	for _, name := range r.Diff.ForeignServersOnlyInSource {
+		findings = append(findings, Finding{Category: CategoryForeignServer, Name: name, Change: ChangeOnlyInSource})
+	}
+	for _, name := range r.Diff.ForeignServersOnlyInTarget {
+		findings = append(findings, Finding{Category: CategoryForeignServer, Name: name, Change: ChangeOnlyInTarget})
+	}
+	for _, d := range r.Diff.ForeignServerDiffs {
+		findings = append(findings, Finding{Category: CategoryForeignServer, Name: d.Name, Change: ChangeModified, Detail: d.Diff, Changes: d.Changes})
+	}
+	return findings
+}
+
+func tableFindings(td *TableDiff) []Finding {
+	var findings []Finding
+	add := func(category, change string, names []string) {
+		for _, name := range names {
+			findings = append(findings, Finding{Table: td.TableName, Category: category, Name: name, Change: change})
+		}
+	}
+	changed := func(category, name, detail string, changes []AttributeChange) {
+		findings = append(findings, Finding{Table: td.TableName, Category: category, Name: name, Change: ChangeModified, Detail: detail, Changes: changes})
+	}
+
+	for _, field := range []struct {
+		name string
+		diff *string
+	}{
+		{"access_method", td.AccessMethodDiff},
+		{"replica_identity", td.ReplicaIdentityDiff},
+		{"options", td.OptionsDiff},
+		{"partitioning", td.PartitioningDiff},
+		{"inherits", td.InheritanceDiff},
+		{"foreign", td.ForeignTableDiff},
+		{"comment", td.CommentDiff},
+	} {
+		if field.diff != nil {
+			changed(CategoryTable, td.TableName, *field.diff, td.tableChanges(field.name))
+		}
+	}
+	add(CategoryColumn, ChangeOnlyInSource, td.ColumnsOnlyInSource)
+	add(CategoryColumn, ChangeOnlyInTarget, td.ColumnsOnlyInTarget)
+	for _, d := range td.ColumnDiffs {
+		changed(CategoryColumn, d.ColumnName, d.Diff, d.Changes)
+	}
+	if td.PrimaryKeyDiff != nil {
+		changed(CategoryPrimaryKey, "PRIMARY KEY", *td.PrimaryKeyDiff, td.PrimaryKeyChanges)
+	}
+	add(CategoryForeignKey, ChangeOnlyInSource, td.ForeignKeysOnlyInSource)
+	add(CategoryForeignKey, ChangeOnlyInTarget, td.ForeignKeysOnlyInTarget)
+	for _, d := range td.ForeignKeyDiffs {
+		changed(CategoryForeignKey, d.Name, d.Diff, d.Changes)
+	}
+	add(CategoryUnique, ChangeOnlyInSource, td.UniquesOnlyInSource)
+	add(CategoryUnique, ChangeOnlyInTarget, td.UniquesOnlyInTarget)
+	for _, d := range td.UniqueDiffs {
+		changed(CategoryUnique, d.Name, d.Diff, d.Changes)
+	}
+	add(CategoryIndex, ChangeOnlyInSource, td.IndexesOnlyInSource)
+	add(CategoryIndex, ChangeOnlyInTarget, td.IndexesOnlyInTarget)
+	for _, d := range td.IndexDiffs {
+		changed(CategoryIndex, d.Name, d.Diff, d.Changes)
+	}
+	add(CategoryCheck, ChangeOnlyInSource, td.ChecksOnlyInSource)
+	add(CategoryCheck, ChangeOnlyInTarget, td.ChecksOnlyInTarget)
+	for _, d := range td.CheckDiffs {
+		changed(CategoryCheck, d.Name, d.Diff, d.Changes)
+	}
+	add(CategoryTrigger, ChangeOnlyInSource, td.TriggersOnlyInSource)
+	add(CategoryTrigger, ChangeOnlyInTarget, td.TriggersOnlyInTarget)
+	for _, d := range td.TriggerDiffs {
+		changed(CategoryTrigger, d.Name, d.Diff, d.Changes)
+	}
+	add(CategoryRewriteRule, ChangeOnlyInSource, td.RewriteRulesOnlyInSource)
+	add(CategoryRewriteRule, ChangeOnlyInTarget, td.RewriteRulesOnlyInTarget)
+	for _, d := range td.RewriteRuleDiffs {
+		changed(CategoryRewriteRule, d.Name, d.Diff, d.Changes)
+	}
+	return findings
+}
+
+// tableChanges returns the table-level changes rendered in the field named
+// by tableChangeField
+func (td *TableDiff) tableChanges(field string) []AttributeChange {
+	var changes []AttributeChange
+	for _, c := range td.Changes {
+		if tableChangeField(c.Attribute) == field {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// ConstraintStatusFindings returns the foreign keys and check constraints
+// whose validation status differs, with Detail reduced to that status change
+// (e.g. "validated → NOT VALID")
+func (r *Result) ConstraintStatusFindings() []Finding {
+	var findings []Finding
+	for _, f := range r.Findings() {
+		if f.Change != ChangeModified || (f.Category != CategoryForeignKey && f.Category != CategoryCheck) {
+			continue
+		}
+		for _, c := range f.Changes {
+			if c.Attribute == "status" {
+				f.Detail, f.Changes = c.value(), []AttributeChange{c}
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+// Filter returns a new Result containing only the findings matching pred.
+// The report metadata that isn't about findings (settings, grants, rule
+// results, partition groups, orphans, ...) is kept as it is; annotations,
+// change sets and the sensitive-column, default and append-only warnings
+// are kept for the findings that remain.
+func (r *Result) Filter(pred func(Finding) bool) *Result {
+	var kept []Finding
+	for _, f := range r.Findings() {
+		if pred(f) {
+			kept = append(kept, f)
+		}
+	}
+	filtered := diffFromFindings(kept)
+	keepMissingObjects(filtered, r.Diff)
+	keepMetadata(filtered, r.Diff, kept)
+	return NewResult(filtered)
+}
+
+// keepMetadata copies the report metadata of src to dst, keeping the
+// entries about findings or tables only where they are among kept
+func keepMetadata(dst, src *SchemaDiff, kept []Finding) {
+	dst.Direction = src.Direction
+	dst.FilteredFindings = src.FilteredFindings
+	dst.SuppressedTables = src.SuppressedTables
+	dst.NotComparable = src.NotComparable
+	dst.RuleResults = src.RuleResults
+	dst.SettingDiffs, dst.GrantDiffs = src.SettingDiffs, src.GrantDiffs
+	dst.PartitionGroups = src.PartitionGroups
+	dst.CollationWarnings = src.CollationWarnings
+	dst.AppendOnlyRows = src.AppendOnlyRows
+	dst.Orphans = src.Orphans
+
+	keys, tables, columns := make(map[string]bool), make(map[string]bool), make(map[string]bool)
+	for _, f := range kept {
+		keys[f.Key()] = true
+		switch f.Category {
+		case CategoryTable:
+			tables[f.Name] = true
+		case CategoryColumn:
+			tables[f.Table] = true
+			columns[f.Table+"."+f.Name] = true
+		default:
+			if f.Table != "" {
+				tables[f.Table] = true
+			}
+		}
+	}
+	for _, a := range src.Annotations {
+		if keys[a.Key] {
+			dst.Annotations = append(dst.Annotations, a)
+		}
+	}
+	for _, cs := range src.ChangeSets {
+		var findings []string
+		for _, key := range cs.Findings {
+			if keys[key] {
+				findings = append(findings, key)
+			}
+		}
+		if len(findings) > 0 {
+			dst.ChangeSets = append(dst.ChangeSets, &ChangeSet{Label: cs.Label, Findings: findings})
+		}
+	}
+	for _, c := range src.SensitiveChanges {
+		if columns[c.Table+"."+c.Column] {
+			dst.SensitiveChanges = append(dst.SensitiveChanges, c)
+		}
+	}
+	for _, w := range src.DefaultWarnings {
+		if columns[w.Table+"."+w.Column] {
+			dst.DefaultWarnings = append(dst.DefaultWarnings, w)
+		}
+	}
+	for _, w := range src.AppendOnlyWarnings {
+		if tables[w.Table] {
+			dst.AppendOnlyWarnings = append(dst.AppendOnlyWarnings, w)
+		}
+	}
+}
+
+// keepMissingObjects copies the definitions of src's one-sided objects that
+// dst still names
+func keepMissingObjects(dst, src *SchemaDiff) {
+	if m := src.SourceOnly; m != nil {
+		dst.SourceOnly = missingObjects(m.schema(), nil, dst.TablesOnlyInSource, dst.TypesOnlyInSource, dst.SequencesOnlyInSource, dst.RoutinesOnlyInSource, dst.EventsOnlyInSource, dst.ExtensionsOnlyInSource, dst.ForeignServersOnlyInSource)
+	}
+	if m := src.TargetOnly; m != nil {
+		dst.TargetOnly = missingObjects(m.schema(), nil, dst.TablesOnlyInTarget, dst.TypesOnlyInTarget, dst.SequencesOnlyInTarget, dst.RoutinesOnlyInTarget, dst.EventsOnlyInTarget, dst.ExtensionsOnlyInTarget, dst.ForeignServersOnlyInTarget)
+	}
+	srcTables := make(map[string]*TableDiff)
+	for _, td := range src.TableDiffs {
+		srcTables[td.TableName] = td
+	}
+	for _, td := range dst.TableDiffs {
+		orig := srcTables[td.TableName]
+		if orig == nil {
+			continue
+		}
+		if m := orig.SourceOnly; m != nil {
+			td.SourceOnly = missingTableObjects(m.table(), td.ColumnsOnlyInSource, td.ForeignKeysOnlyInSource,
+				td.UniquesOnlyInSource, td.IndexesOnlyInSource, td.ChecksOnlyInSource, td.TriggersOnlyInSource)
+		}
+		if m := orig.TargetOnly; m != nil {
+			td.TargetOnly = missingTableObjects(m.table(), td.ColumnsOnlyInTarget, td.ForeignKeysOnlyInTarget,
+				td.UniquesOnlyInTarget, td.IndexesOnlyInTarget, td.ChecksOnlyInTarget, td.TriggersOnlyInTarget)
+		}
+	}
+}
+
+// schema wraps the objects in a Schema so they can be picked from again
+func (m *MissingObjects) schema() *Schema {
+	return &Schema{Tables: m.Tables, Types: m.Types, Sequences: m.Sequences, Routines: m.Routines, Events: m.Events, Extensions: m.Extensions,
+		ForeignServers: m.ForeignServers}
+}
+
+// table wraps the objects in a Table so they can be picked from again
+func (m *MissingTableObjects) table() *Table {
+	return &Table{Columns: m.Columns, ForeignKeys: m.ForeignKeys, UniqueConstraints: m.UniqueConstraints,
+		Indexes: m.Indexes, CheckConstraints: m.CheckConstraints, Triggers: m.Triggers}
+}
+
+// diffFromFindings rebuilds a SchemaDiff from flattened findings
+func diffFromFindings(findings []Finding) *SchemaDiff {
+	diff := &SchemaDiff{}
+	tableDiffs := make(map[string]*TableDiff)
+	var order []string
+
+	for _, f := range findings {
+		if f.Category == CategoryType {
+			switch f.Change {
+			case ChangeOnlyInSource:
+				diff.TypesOnlyInSource = append(diff.TypesOnlyInSource, f.Name)
+			case ChangeOnlyInTarget:
+				diff.TypesOnlyInTarget = append(diff.TypesOnlyInTarget, f.Name)
+			default:
+				diff.TypeDiffs = append(diff.TypeDiffs, &TypeDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+			continue
+		}
+		if f.Category == CategorySequence {
+			switch f.Change {
+			case ChangeOnlyInSource:
+				diff.SequencesOnlyInSource = append(diff.SequencesOnlyInSource, f.Name)
+			case ChangeOnlyInTarget:
+				diff.SequencesOnlyInTarget = append(diff.SequencesOnlyInTarget, f.Name)
+			default:
+				diff.SequenceDiffs = append(diff.SequenceDiffs, &SequenceDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+			continue
+		}
+		if f.Category == CategoryRoutine {
+			switch f.Change {
+			case ChangeOnlyInSource:
+				diff.RoutinesOnlyInSource = append(diff.RoutinesOnlyInSource, f.Name)
+			case ChangeOnlyInTarget:
+				diff.RoutinesOnlyInTarget = append(diff.RoutinesOnlyInTarget, f.Name)
+			default:
+				diff.RoutineDiffs = append(diff.RoutineDiffs, &RoutineDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+			continue
+		}
+		if f.Category == CategoryEvent {
+			switch f.Change {
+			case ChangeOnlyInSource:
+				diff.EventsOnlyInSource = append(diff.EventsOnlyInSource, f.Name)
+			case ChangeOnlyInTarget:
+				diff.EventsOnlyInTarget = append(diff.EventsOnlyInTarget, f.Name)
+			default:
+				diff.EventDiffs = append(diff.EventDiffs, &EventDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+			continue
+		}
+		if f.Category == CategoryExtension {
+			switch f.Change {
+			case ChangeOnlyInSource:
+				diff.ExtensionsOnlyInSource = append(diff.ExtensionsOnlyInSource, f.Name)
+			case ChangeOnlyInTarget:
+				diff.ExtensionsOnlyInTarget = append(diff.ExtensionsOnlyInTarget, f.Name)
+			default:
+				diff.ExtensionDiffs = append(diff.ExtensionDiffs, &ExtensionDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+			continue
+		}
+		if f.Category == CategoryForeignServer {
+			switch f.Change {
+			case ChangeOnlyInSource:
+				diff.ForeignServersOnlyInSource = append(diff.ForeignServersOnlyInSource, f.Name)
+			case ChangeOnlyInTarget:
+				diff.ForeignServersOnlyInTarget = append(diff.ForeignServersOnlyInTarget, f.Name)
+			default:
+				diff.ForeignServerDiffs = append(diff.ForeignServerDiffs, &ForeignServerDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+			continue
+		}
+		if f.Category == CategoryTable && f.Change != ChangeModified {
+			if f.Change == ChangeOnlyInSource {
+				diff.TablesOnlyInSource = append(diff.TablesOnlyInSource, f.Name)
+			} else {
+				diff.TablesOnlyInTarget = append(diff.TablesOnlyInTarget, f.Name)
+			}
+			continue
+		}
+
+		td, ok := tableDiffs[f.Table]
+		if !ok {
+			td = &TableDiff{TableName: f.Table}
+			tableDiffs[f.Table] = td
+			order = append(order, f.Table)
+		}
+
+		var onlyInSource, onlyInTarget *[]string
+		switch f.Category {
+		case CategoryTable:
+			detail, field := f.Detail, ""
+			if len(f.Changes) > 0 {
+				field = tableChangeField(f.Changes[0].Attribute)
+			}
+			switch field {
+			case "access_method":
+				td.AccessMethodDiff = &detail
+			case "replica_identity":
+				td.ReplicaIdentityDiff = &detail
+			case "options":
+				td.OptionsDiff = &detail
+			case "partitioning":
+				td.PartitioningDiff = &detail
+			case "inherits":
+				td.InheritanceDiff = &detail
+			case "foreign":
+				td.ForeignTableDiff = &detail
+			default:
+				td.CommentDiff = &detail
+			}
+			td.Changes = append(td.Changes, f.Changes...)
+			continue
+		case CategoryColumn:
+			onlyInSource, onlyInTarget = &td.ColumnsOnlyInSource, &td.ColumnsOnlyInTarget
+			if f.Change == ChangeModified {
+				td.ColumnDiffs = append(td.ColumnDiffs, &ColumnDiff{ColumnName: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+		case CategoryPrimaryKey:
+			detail := f.Detail
+			td.PrimaryKeyDiff, td.PrimaryKeyChanges = &detail, f.Changes
+			continue
+		case CategoryForeignKey:
+			onlyInSource, onlyInTarget = &td.ForeignKeysOnlyInSource, &td.ForeignKeysOnlyInTarget
+			if f.Change == ChangeModified {
+				td.ForeignKeyDiffs = append(td.ForeignKeyDiffs, &FKDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+		case CategoryUnique:
+			onlyInSource, onlyInTarget = &td.UniquesOnlyInSource, &td.UniquesOnlyInTarget
+			if f.Change == ChangeModified {
+				td.UniqueDiffs = append(td.UniqueDiffs, &UniqueDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+		case CategoryIndex:
+			onlyInSource, onlyInTarget = &td.IndexesOnlyInSource, &td.IndexesOnlyInTarget
+			if f.Change == ChangeModified {
+				td.IndexDiffs = append(td.IndexDiffs, &IndexDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+		case CategoryCheck:
+			onlyInSource, onlyInTarget = &td.ChecksOnlyInSource, &td.ChecksOnlyInTarget
+			if f.Change == ChangeModified {
+				td.CheckDiffs = append(td.CheckDiffs, &CheckDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+		case CategoryTrigger:
+			onlyInSource, onlyInTarget = &td.TriggersOnlyInSource, &td.TriggersOnlyInTarget
+			if f.Change == ChangeModified {
+				td.TriggerDiffs = append(td.TriggerDiffs, &TriggerDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+		case CategoryRewriteRule:
+			onlyInSource, onlyInTarget = &td.RewriteRulesOnlyInSource, &td.RewriteRulesOnlyInTarget
+			if f.Change == ChangeModified {
+				td.RewriteRuleDiffs = append(td.RewriteRuleDiffs, &RewriteRuleDiff{Name: f.Name, Diff: f.Detail, Changes: f.Changes})
+			}
+		default:
+			continue
+		}
+
+		switch f.Change {
+		case ChangeOnlyInSource:
+			*onlyInSource = append(*onlyInSource, f.Name)
+		case ChangeOnlyInTarget:
+			*onlyInTarget = append(*onlyInTarget, f.Name)
+		}
+	}
+
+	for _, name := range order {
+		diff.TableDiffs = append(diff.TableDiffs, tableDiffs[name])
+	}
+	return diff
+}
+
+// MarshalJSON encodes the result exactly like --json output
+func (r *Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(diffJSON(r.Diff))
+}
+
+// MarshalText renders the result like the default pretty output
+func (r *Result) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	printPretty(&buf, r.Diff)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,94 @@
+package dbdiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFilterKeepsSettingsAndGrants(t *testing.T) {
+	diff := &SchemaDiff{
+		TablesOnlyInSource: []string{"old"},
+		SettingDiffs:       []*SettingDiff{{Name: "sql_mode", Source: "STRICT_TRANS_TABLES", Target: ""}},
+		GrantDiffs:         []*GrantDiff{{Grantee: "app", Object: "orders", Privilege: "SELECT", Change: ChangeOnlyInSource}},
+	}
+	filtered := NewResult(diff).Filter(func(f Finding) bool { return false }).Diff
+	if len(filtered.TablesOnlyInSource) != 0 {
+		t.Errorf("tables only in source = %v, want none", filtered.TablesOnlyInSource)
+	}
+	if len(filtered.SettingDiffs) != 1 || len(filtered.GrantDiffs) != 1 {
+		t.Errorf("settings = %v, grants = %v, want both kept", filtered.SettingDiffs, filtered.GrantDiffs)
+	}
+}
+
+func TestFilterKeepsMetadata(t *testing.T) {
+	emailDiff := "type: varchar(255) → varchar(191)"
+	changes := []AttributeChange{{Attribute: "type", From: "varchar(255)", To: "varchar(191)", Note: "capacity change: 255 → 191 characters"}}
+	diff := &SchemaDiff{
+		TablesOnlyInSource: []string{"old"},
+		TableDiffs: []*TableDiff{
+			{TableName: "users", ColumnDiffs: []*ColumnDiff{{ColumnName: "email", Diff: emailDiff, Changes: changes}}},
+			{TableName: "orders", ColumnsOnlyInSource: []string{"note"}},
+		},
+		SuppressedTables:   []string{"audit"},
+		RuleResults:        []*RuleResult{{Rule: "no-drops", Action: "fail", Matches: []string{"table old only in source"}}},
+		Annotations:        []*Annotation{{Key: "column:users.email", Comment: "planned"}, {Key: "column:orders.note", Comment: "legacy"}},
+		FilteredFindings:   3,
+		SettingDiffs:       []*SettingDiff{{Name: "sql_mode", Source: "STRICT_TRANS_TABLES"}},
+		GrantDiffs:         []*GrantDiff{{Grantee: "app", Object: "orders", Privilege: "SELECT", Change: ChangeOnlyInSource}},
+		PartitionGroups:    []*PartitionGroup{{Name: "events", SourcePartitions: 3, TargetPartitions: 2}},
+		ChangeSets:         []*ChangeSet{{Label: "users", Findings: []string{"column:users.email", "column:orders.note"}}},
+		SensitiveChanges:   []*SensitiveChange{{Table: "users", Column: "email", Change: ChangeModified, Detail: emailDiff}},
+		DefaultWarnings:    []*DefaultWarning{{Table: "orders", Column: "note", Detail: "default", Setting: "time_zone", Warning: "depends on the session"}},
+		CollationWarnings:  []*CollationWarning{{Collation: "en_US", Source: "153.14", Target: "153.120", Warning: "indexes may need a rebuild"}},
+		AppendOnlyWarnings: []*AppendOnlyWarning{{Table: "orders", Warning: "column note is dropped"}},
+		AppendOnlyRows:     []*AppendOnlyRows{{Table: "orders", SourceRows: 10, TargetRows: 8}},
+		Orphans:            []*OrphanedObject{{Side: "source", Category: CategoryForeignKey, Name: "fk_user", Detail: "references missing table"}},
+		NotComparable:      []*NotComparable{{Side: "target", Kind: "routine", Name: "f", Reason: "permission denied"}},
+		Direction:          DirectionTargetToSource,
+	}
+
+	want, err := json.Marshal(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(NewResult(diff).Filter(func(Finding) bool { return true }).Diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Filter(all) =\n%s\nwant\n%s", got, want)
+	}
+
+	users := NewResult(diff).Filter(func(f Finding) bool { return f.Table == "users" }).Diff
+	if len(users.Annotations) != 1 || users.Annotations[0].Key != "column:users.email" {
+		t.Errorf("annotations = %v, want only users.email", users.Annotations)
+	}
+	if len(users.ChangeSets) != 1 || len(users.ChangeSets[0].Findings) != 1 {
+		t.Errorf("change sets = %v, want one with users.email", users.ChangeSets)
+	}
+	if len(users.SensitiveChanges) != 1 || len(users.DefaultWarnings) != 0 || len(users.AppendOnlyWarnings) != 0 {
+		t.Errorf("sensitive = %v, defaults = %v, append-only = %v, want only the users entries",
+			users.SensitiveChanges, users.DefaultWarnings, users.AppendOnlyWarnings)
+	}
+	if len(users.Orphans) != 1 || users.Direction != DirectionTargetToSource {
+		t.Errorf("orphans = %v, direction = %q, want both kept", users.Orphans, users.Direction)
+	}
+}
+
+func TestBreakingUsesAttributeChanges(t *testing.T) {
+	source, target := newTable("users"), newTable("users")
+	source.Columns["status"] = &Column{Name: "status", DataType: "text", Comment: "type: x"}
+	target.Columns["status"] = &Column{Name: "status", DataType: "text", Comment: "nullable: true → false"}
+	source.Columns["id"] = &Column{Name: "id", DataType: "integer", IsNullable: true}
+	target.Columns["id"] = &Column{Name: "id", DataType: "integer"}
+	diff := ComputeDiff(&Schema{Tables: map[string]*Table{"users": source}}, &Schema{Tables: map[string]*Table{"users": target}}, NewFilterConfig())
+
+	breaking := make(map[string]bool)
+	for _, f := range NewResult(diff).Findings() {
+		breaking[f.Name] = f.Breaking()
+	}
+	if want := map[string]bool{"status": false, "id": true}; !reflect.DeepEqual(breaking, want) {
+		t.Errorf("breaking = %v, want %v", breaking, want)
+	}
+}
@@ -0,0 +1,402 @@
+package dbdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// RULES - Custom drift policies
+// ============================================================================
+
+// Rule actions
+const (
+	RuleActionFail = "fail"
+	RuleActionWarn = "warn"
+)
+
+// Rule is a user-defined policy evaluated against every finding. When is an
+// expression over the finding fields table, category, name, change, detail,
+// breaking, attribute, from, to, code and kind, e.g.
+//
+//	category == "column" && table matches "billing_*" && attribute == "nullable" && to == "true"
+//
+// Operators: == != matches (glob) =~ (regex) contains && || ! and parentheses.
+type Rule struct {
+	Name    string `json:"name"`
+	When    string `json:"when"`
+	Action  string `json:"action"`
+	Message string `json:"message,omitempty"`
+
+	expr ruleExpr
+}
+
+// RuleSet is the on-disk format of --rules
+type RuleSet struct {
+	Rules []*Rule `json:"rules"`
+}
+
+// RuleResult records which findings a rule matched
+type RuleResult struct {
+	Rule    string   `json:"rule"`
+	Action  string   `json:"action"`
+	Message string   `json:"message,omitempty"`
+	Matches []string `json:"matches"`
+}
+
+// LoadRules reads and compiles a JSON rule file
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := readTextFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rs RuleSet
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for i, rule := range rs.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		switch rule.Action {
+		case "":
+			rule.Action = RuleActionFail
+		case RuleActionFail, RuleActionWarn:
+		default:
+			return nil, fmt.Errorf("rule %s: unknown action %q (expected fail or warn)", rule.Name, rule.Action)
+		}
+		expr, err := parseRuleExpr(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		rule.expr = expr
+	}
+	return &rs, nil
+}
+
+// Evaluate runs every rule against the findings of result and returns the
+// rules that matched at least one finding
+func (rs *RuleSet) Evaluate(result *Result) []*RuleResult {
+	findings := result.Findings()
+	var results []*RuleResult
+	for _, rule := range rs.Rules {
+		var matches []string
+		for _, f := range findings {
+			if rule.matches(f) {
+				matches = append(matches, describeFinding(f))
+			}
+		}
+		if len(matches) > 0 {
+			results = append(results, &RuleResult{Rule: rule.Name, Action: rule.Action, Message: rule.Message, Matches: matches})
+		}
+	}
+	return results
+}
+
+// matches evaluates the rule once per attribute change of the finding, so
+// attribute/from/to always refer to the same change
+func (r *Rule) matches(f Finding) bool {
+	env := map[string]string{
+		"table":    f.Table,
+		"category": f.Category,
+		"name":     f.Name,
+		"change":   f.Change,
+		"detail":   f.Detail,
+		"breaking": strconv.FormatBool(f.Breaking()),
+	}
+	changes := f.Changes
+	if len(changes) == 0 {
+		changes = []AttributeChange{{}}
+	}
+	for _, c := range changes {
+		env["attribute"], env["from"], env["to"] = c.Attribute, c.From, c.To
+		code := diffCode(f.Category, f.Change, c.Attribute)
+		env["code"], env["kind"] = code.Code, code.Kind
+		if truthy(r.expr(env)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RulesFailed reports whether any matched rule has the fail action
+func RulesFailed(results []*RuleResult) bool {
+	for _, r := range results {
+		if r.Action == RuleActionFail {
+			return true
+		}
+	}
+	return false
+}
+
+// EvaluateRegoPolicy evaluates the diff JSON against a Rego policy file or
+// directory using the opa CLI. Policies declare `package dbdiff` and produce
+// messages from `deny` (fail) and `warn` rules, conftest style:
+//
+//	deny[msg] { t := input.tables_only_in_source[_]; msg := sprintf("table %s dropped", [t]) }
+func EvaluateRegoPolicy(policyPath string, diff *SchemaDiff) ([]*RuleResult, error) {
+	opa, err := exec.LookPath("opa")
+	if err != nil {
+		return nil, fmt.Errorf("--policy requires the opa binary on PATH: %w", err)
+	}
+
+	input, err := json.Marshal(diffJSON(diff))
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(opa, "eval", "--format", "json", "--stdin-input", "--data", policyPath, "data.dbdiff")
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("opa eval: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var resp struct {
+		Result []struct {
+			Expressions []struct {
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("parsing opa output: %w", err)
+	}
+	if len(resp.Result) == 0 || len(resp.Result[0].Expressions) == 0 {
+		return nil, nil
+	}
+	pkg := resp.Result[0].Expressions[0].Value
+
+	var results []*RuleResult
+	for _, rule := range []struct{ name, action string }{{"deny", RuleActionFail}, {"warn", RuleActionWarn}} {
+		raw, ok := pkg[rule.name]
+		if !ok {
+			continue
+		}
+		var values []json.RawMessage
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, fmt.Errorf("data.dbdiff.%s must be a set or array: %w", rule.name, err)
+		}
+		var messages []string
+		for _, v := range values {
+			var msg string
+			if json.Unmarshal(v, &msg) != nil {
+				msg = string(v)
+			}
+			messages = append(messages, msg)
+		}
+		if len(messages) > 0 {
+			sort.Strings(messages)
+			results = append(results, &RuleResult{
+				Rule:    filepath.Base(policyPath) + ":" + rule.name,
+				Action:  rule.action,
+				Matches: messages,
+			})
+		}
+	}
+	return results, nil
+}
+
+func describeFinding(f Finding) string {
+	desc := fmt.Sprintf("%s %s.%s %s", f.Category, f.Table, f.Name, f.Change)
+	if f.Category == CategoryTable || f.Table == "" {
+		desc = fmt.Sprintf("%s %s %s", f.Category, f.Name, f.Change)
+	}
+	if f.Detail != "" {
+		desc += " (" + f.Detail + ")"
+	}
+	return desc
+}
+
+// ruleExpr evaluates to a string; booleans are "true"/"false"
+type ruleExpr func(env map[string]string) string
+
+func truthy(v string) bool { return v == "true" }
+
+type ruleParser struct {
+	tokens []string
+	pos    int
+}
+
+var ruleTokenPattern = regexp.MustCompile(`\s*("(?:[^"\\]|\\.)*"|&&|\|\||==|!=|=~|!|\(|\)|[A-Za-z_][A-Za-z0-9_]*)`)
+
+func parseRuleExpr(src string) (ruleExpr, error) {
+	p := &ruleParser{}
+	rest := src
+	for strings.TrimSpace(rest) != "" {
+		m := ruleTokenPattern.FindStringSubmatchIndex(rest)
+		if m == nil || m[0] != 0 {
+			return nil, fmt.Errorf("unexpected input at %q", strings.TrimSpace(rest))
+		}
+		p.tokens = append(p.tokens, rest[m[2]:m[3]])
+		rest = rest[m[1]:]
+	}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+func (p *ruleParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *ruleParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *ruleParser) parseOr() (ruleExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(env map[string]string) string {
+			return strconv.FormatBool(truthy(l(env)) || truthy(right(env)))
+		}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseAnd() (ruleExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(env map[string]string) string {
+			return strconv.FormatBool(truthy(l(env)) && truthy(right(env)))
+		}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseUnary() (ruleExpr, error) {
+	switch p.peek() {
+	case "!":
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(env map[string]string) string { return strconv.FormatBool(!truthy(inner(env))) }, nil
+	case "(":
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *ruleParser) parseComparison() (ruleExpr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peek()
+	switch op {
+	case "==", "!=", "=~", "matches", "contains":
+		p.next()
+	default:
+		return left, nil
+	}
+
+	// Regex and glob operands are compiled once, up front
+	if op == "=~" || op == "matches" {
+		if p.pos >= len(p.tokens) || !strings.HasPrefix(p.peek(), `"`) {
+			return nil, fmt.Errorf("%s expects a string literal pattern", op)
+		}
+		pattern, _ := strconv.Unquote(p.next())
+		if op == "=~" {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			return func(env map[string]string) string { return strconv.FormatBool(re.MatchString(left(env))) }, nil
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		return func(env map[string]string) string {
+			ok, _ := path.Match(pattern, left(env))
+			return strconv.FormatBool(ok)
+		}, nil
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "==":
+		return func(env map[string]string) string { return strconv.FormatBool(left(env) == right(env)) }, nil
+	case "!=":
+		return func(env map[string]string) string { return strconv.FormatBool(left(env) != right(env)) }, nil
+	default:
+		return func(env map[string]string) string { return strconv.FormatBool(strings.Contains(left(env), right(env))) }, nil
+	}
+}
+
+var ruleFields = map[string]bool{
+	"table": true, "category": true, "name": true, "change": true, "detail": true,
+	"breaking": true, "attribute": true, "from": true, "to": true, "code": true, "kind": true,
+}
+
+func (p *ruleParser) parseOperand() (ruleExpr, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case strings.HasPrefix(tok, `"`):
+		lit, err := strconv.Unquote(tok)
+		if err != nil {
+			return nil, err
+		}
+		return func(map[string]string) string { return lit }, nil
+	case tok == "true" || tok == "false":
+		return func(map[string]string) string { return tok }, nil
+	case ruleFields[tok]:
+		return func(env map[string]string) string { return env[tok] }, nil
+	}
+	return nil, fmt.Errorf("unknown field %q", tok)
+}
@@ -0,0 +1,91 @@
+package dbdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRuleExpr(t *testing.T) {
+	env := map[string]string{"table": "billing_invoices", "category": "column", "name": "amount", "change": "modified", "breaking": "true"}
+	for _, tc := range []struct{ expr, want string }{
+		{`table`, "billing_invoices"},
+		{`"a \"quoted\" literal"`, `a "quoted" literal`},
+		{`category == "column"`, "true"},
+		{`category != "column"`, "false"},
+		{`table matches "billing_*"`, "true"},
+		{`table matches "billing"`, "false"},
+		{`name =~ "^am"`, "true"},
+		{`table contains "invoice"`, "true"},
+		{`table contains name`, "false"},
+		{`breaking`, "true"},
+		{`detail == ""`, "true"},
+		// && binds tighter than ||
+		{`true || false && false`, "true"},
+		{`false && false || true`, "true"},
+		{`(true || false) && false`, "false"},
+		// ! binds tighter than && and looser than comparisons
+		{`!false && false`, "false"},
+		{`!category == "index"`, "true"},
+		{`!(breaking && change == "modified")`, "false"},
+		{`!!breaking`, "true"},
+		{`category == "column" && (table matches "audit_*" || name == "amount") && !(change == "only_in_source")`, "true"},
+	} {
+		expr, err := parseRuleExpr(tc.expr)
+		if err != nil {
+			t.Errorf("parseRuleExpr(%s): %v", tc.expr, err)
+			continue
+		}
+		if got := expr(env); got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestParseRuleExprErrors(t *testing.T) {
+	for _, tc := range []struct{ expr, want string }{
+		{``, "empty expression"},
+		{`   `, "empty expression"},
+		{`tabel == "users"`, `unknown field "tabel"`},
+		{`table == users`, `unknown field "users"`},
+		{`table ==`, "unexpected end of expression"},
+		{`table == "a" )`, `unexpected ")"`},
+		{`table == "a" name`, `unexpected "name"`},
+		{`(table == "a"`, "missing closing parenthesis"},
+		{`table @ "a"`, `unexpected input at "@ \"a\""`},
+		{`table == "unterminated`, `unexpected input at "\"unterminated"`},
+		{`name =~ table`, "=~ expects a string literal pattern"},
+		{`name matches`, "matches expects a string literal pattern"},
+		{`name =~ "("`, "missing closing )"},
+		{`name matches "["`, `invalid glob "["`},
+	} {
+		_, err := parseRuleExpr(tc.expr)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("parseRuleExpr(%s) error = %v, want %q", tc.expr, err, tc.want)
+		}
+	}
+}
+
+func TestRuleMatchesEachAttributeChange(t *testing.T) {
+	changes := []AttributeChange{attributeChange("type", "integer", "bigint"), attributeChange("nullable", false, true)}
+	f := Finding{Table: "billing", Category: CategoryColumn, Name: "amount", Change: ChangeModified, Detail: renderChanges(changes), Changes: changes}
+	for _, tc := range []struct {
+		when string
+		want bool
+	}{
+		{`attribute == "nullable" && to == "true"`, true},
+		{`attribute == "type" && to == "bigint"`, true},
+		// attribute, from and to always describe the same change
+		{`attribute == "type" && to == "true"`, false},
+		{`detail contains "nullable" && table == "billing"`, true},
+		{`category == "index"`, false},
+	} {
+		expr, err := parseRuleExpr(tc.when)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule := &Rule{Name: "r", When: tc.when, expr: expr}
+		if got := rule.matches(f); got != tc.want {
+			t.Errorf("%s matches = %v, want %v", tc.when, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package dbdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// SCHEMA MAPPING - Comparing schemas that live under different names
+// ============================================================================
+
+// mapQualifiedName renames the schema of a schema.name reference through
+// mapping; unqualified and unmapped names are returned as is
+func mapQualifiedName(name string, mapping map[string]string) string {
+	if schemaName, rest, ok := strings.Cut(name, "."); ok {
+		if mapped, ok := mapping[schemaName]; ok {
+			return mapped + "." + rest
+		}
+	}
+	return name
+}
+
+// parseSchemaMap reads the source=target pairs of --schema-map, e.g.
+// "tenant_a=tenant_b,shared=shared_v2"
+func parseSchemaMap(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, pair := range splitList(value) {
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid schema mapping %q (expected source=target)", pair)
+		}
+		if other, ok := mapping[from]; ok && other != to {
+			return nil, fmt.Errorf("schema %s is mapped to both %s and %s", from, other, to)
+		}
+		mapping[from] = to
+	}
+	return mapping, nil
+}
+
+// invertSchemaMap turns a source-to-target schema mapping around. Two source
+// schemas can't map to the same target schema.
+func invertSchemaMap(mapping map[string]string) (map[string]string, error) {
+	inverted := make(map[string]string, len(mapping))
+	for _, from := range getSortedKeys(mapping) {
+		to := mapping[from]
+		if other, ok := inverted[to]; ok {
+			return nil, fmt.Errorf("schemas %s and %s both map to %s", other, from, to)
+		}
+		inverted[to] = from
+	}
+	return inverted, nil
+}
+
+// MapSchemas returns schema with its table names and the references to them
+// renamed through mapping: foreign key targets, partition parents and the
+// tables owning sequences. A foreign key to app_v2.accounts then pairs with
+// one to app.accounts instead of showing up as a ref_table change. schema
+// itself is left untouched.
+func MapSchemas(schema *Schema, mapping map[string]string) *Schema {
+	if len(mapping) == 0 {
+		return schema
+	}
+	mapped := *schema
+	mapped.Tables = make(map[string]*Table, len(schema.Tables))
+	for name, table := range schema.Tables {
+		t := *table
+		t.Name = mapQualifiedName(table.Name, mapping)
+		t.PartitionOf = mapQualifiedName(table.PartitionOf, mapping)
+		t.ForeignKeys = make(map[string]*ForeignKey, len(table.ForeignKeys))
+		for fkName, fk := range table.ForeignKeys {
+			f := *fk
+			f.RefTable = mapQualifiedName(fk.RefTable, mapping)
+			t.ForeignKeys[fkName] = &f
+		}
+		mapped.Tables[mapQualifiedName(name, mapping)] = &t
+	}
+	if schema.Sequences != nil {
+		mapped.Sequences = make(map[string]*Sequence, len(schema.Sequences))
+		for name, seq := range schema.Sequences {
+			s := *seq
+			s.OwnedBy = mapQualifiedName(seq.OwnedBy, mapping)
+			mapped.Sequences[name] = &s
+		}
+	}
+	mapped.NotComparable = nil
+	for _, nc := range schema.NotComparable {
+		n := *nc
+		if n.Kind == CategoryTable {
+			n.Name = mapQualifiedName(nc.Name, mapping)
+		}
+		mapped.NotComparable = append(mapped.NotComparable, &n)
+	}
+	return &mapped
+}
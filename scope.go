@@ -0,0 +1,81 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// SCOPE QUERIES - Table lists driven by the database's own metadata
+// ============================================================================
+
+// loadScope runs a user-provided query on a live database and returns the
+// table names in the first column of its rows. Other columns are ignored, so
+// a query may also select e.g. the owning team.
+func loadScope(driver, conn, query string) ([]string, error) {
+	if isFileDriver(driver) {
+		return nil, fmt.Errorf("scope queries need a live database, not %s", driver)
+	}
+	db, err := openDB(driver, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.QueryContext(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("scope query returns no columns")
+	}
+	var tables []string
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		var name sql.NullString
+		values[0] = &name
+		for i := 1; i < len(values); i++ {
+			values[i] = new(interface{})
+		}
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+		if name.Valid && strings.TrimSpace(name.String) != "" {
+			tables = append(tables, strings.TrimSpace(name.String))
+		}
+	}
+	return tables, rows.Err()
+}
+
+// ApplyScope restricts the filter to the tables of the scope queries: the
+// union of both sides' results, so a table dropped on one side still shows
+// up as missing. With --only-tables as well, only tables in both are
+// compared. An empty scope is an error rather than "compare everything".
+func ApplyScope(filter *FilterConfig, scopes ...[]string) error {
+	tables := make(map[string]bool)
+	for _, scope := range scopes {
+		for _, name := range scope {
+			tables[name] = true
+		}
+	}
+	if len(filter.OnlyTables) > 0 {
+		listed := makeSet(filter.OnlyTables)
+		for name := range tables {
+			if !listed[name] {
+				delete(tables, name)
+			}
+		}
+	}
+	if len(tables) == 0 {
+		return fmt.Errorf("the scope queries select no tables to compare")
+	}
+	filter.OnlyTables = getSortedKeys(tables)
+	return nil
+}
@@ -0,0 +1,203 @@
+package dbdiff
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// SENSITIVE COLUMNS - Columns holding personal or secret data
+// ============================================================================
+
+// SensitiveConfig tags columns as sensitive, in the config file:
+//
+//	sensitive:
+//	  columns: [users.ssn, "*.password_hash", "payments.card_*"]
+//	  comment_markers: ["[confidential]"]
+//
+// Columns whose comment carries one of defaultSensitiveMarkers are sensitive
+// too.
+type SensitiveConfig struct {
+	// Columns are table.column patterns with path.Match wildcards
+	Columns []string `yaml:"columns" json:"columns"`
+	// CommentMarkers are extra case-insensitive comment markers
+	CommentMarkers []string `yaml:"comment_markers" json:"comment_markers"`
+}
+
+// defaultSensitiveMarkers tag a column as sensitive from its comment
+var defaultSensitiveMarkers = []string{"@sensitive", "[sensitive]", "[pii]", "@pii"}
+
+// protectedTypePattern matches column types that suggest the value is stored
+// encrypted, hashed or masked: binary types holding ciphertext, and types
+// or domains named for it
+var protectedTypePattern = regexp.MustCompile(`(?i)bytea|binary|blob|encrypt|cipher|crypt|secret|hash|vault|mask`)
+
+// MarkSensitiveColumns sets Column.Sensitive for the columns cfg or their
+// comments tag as sensitive. Columns already marked are not written to, so
+// that marking a schema again only reads it.
+func MarkSensitiveColumns(schema *Schema, cfg SensitiveConfig) {
+	markers := append(append([]string{}, defaultSensitiveMarkers...), cfg.CommentMarkers...)
+	for tableName, table := range schema.Tables {
+		for colName, col := range table.Columns {
+			if !col.Sensitive && isSensitiveColumn(tableName+"."+colName, col.Comment, cfg.Columns, markers) {
+				col.Sensitive = true
+			}
+		}
+	}
+}
+
+// isSensitiveColumn reports whether a table.column name matches one of
+// patterns or its comment carries one of markers
+func isSensitiveColumn(name, comment string, patterns, markers []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	comment = strings.ToLower(comment)
+	for _, marker := range markers {
+		if marker != "" && strings.Contains(comment, strings.ToLower(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SensitiveChange is a difference in a column tagged sensitive on either
+// side. Warning is set when the change weakens its protection.
+type SensitiveChange struct {
+	Table   string `json:"table"`
+	Column  string `json:"column"`
+	Change  string `json:"change"`
+	Detail  string `json:"detail,omitempty"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// SensitiveColumnChanges collects the differences of sensitive columns and
+// masks their default values in diff, including the definitions of one-sided
+// tables and columns, so that literals of sensitive columns never reach a
+// report
+func SensitiveColumnChanges(diff *SchemaDiff, source, target *Schema) []*SensitiveChange {
+	column := func(schema *Schema, table, name string) *Column {
+		if t := schema.Tables[table]; t != nil {
+			return t.Columns[name]
+		}
+		return nil
+	}
+	var changes []*SensitiveChange
+	for _, td := range diff.TableDiffs {
+		for _, name := range td.ColumnsOnlyInSource {
+			if col := column(source, td.TableName, name); col != nil && col.Sensitive {
+				changes = append(changes, &SensitiveChange{Table: td.TableName, Column: name, Change: ChangeOnlyInSource})
+			}
+		}
+		for _, name := range td.ColumnsOnlyInTarget {
+			if col := column(target, td.TableName, name); col != nil && col.Sensitive {
+				changes = append(changes, &SensitiveChange{Table: td.TableName, Column: name, Change: ChangeOnlyInTarget})
+			}
+		}
+		for _, cd := range td.ColumnDiffs {
+			src, tgt := column(source, td.TableName, cd.ColumnName), column(target, td.TableName, cd.ColumnName)
+			if src == nil || tgt == nil || !(src.Sensitive || tgt.Sensitive) {
+				continue
+			}
+			maskDefaults(cd.Changes)
+			cd.Diff = renderChanges(cd.Changes)
+			change := &SensitiveChange{Table: td.TableName, Column: cd.ColumnName, Change: ChangeModified, Detail: cd.Diff}
+			switch {
+			case protectedTypePattern.MatchString(columnTypeName(src)) && !protectedTypePattern.MatchString(columnTypeName(tgt)):
+				change.Warning = fmt.Sprintf("loses its encryption-related type: %s → %s", columnTypeName(src), columnTypeName(tgt))
+			case src.Sensitive && !tgt.Sensitive:
+				change.Warning = "no longer tagged sensitive in target"
+			}
+			changes = append(changes, change)
+		}
+		if td.SourceOnly != nil {
+			maskSensitiveDefaults(td.SourceOnly.Columns)
+		}
+		if td.TargetOnly != nil {
+			maskSensitiveDefaults(td.TargetOnly.Columns)
+		}
+	}
+	for _, m := range []*MissingObjects{diff.SourceOnly, diff.TargetOnly} {
+		if m == nil {
+			continue
+		}
+		for name, table := range m.Tables {
+			m.Tables[name] = maskedTable(table)
+		}
+	}
+	return changes
+}
+
+// maskSensitiveSchema returns a copy of schema with the defaults of its
+// sensitive columns masked, for showing a snapshot without their literals
+func maskSensitiveSchema(schema *Schema) *Schema {
+	masked := *schema
+	masked.Tables = make(map[string]*Table, len(schema.Tables))
+	for name, table := range schema.Tables {
+		masked.Tables[name] = maskedTable(table)
+	}
+	return &masked
+}
+
+// maskedTable copies table with the defaults of its sensitive columns masked
+func maskedTable(table *Table) *Table {
+	masked := *table
+	masked.Columns = maps.Clone(table.Columns)
+	maskSensitiveDefaults(masked.Columns)
+	return &masked
+}
+
+// maskedDefault stands in for the default of a sensitive column
+const maskedDefault = "***"
+
+// maskSensitiveDefaults replaces the sensitive columns with a default by
+// copies whose default is masked, leaving the extracted schema untouched.
+// Migrations created from such a definition carry the mask and have to be
+// completed by hand.
+func maskSensitiveDefaults(columns map[string]*Column) {
+	for name, col := range columns {
+		if col.Sensitive && col.DefaultValue != nil {
+			masked := *col
+			masked.DefaultValue = new(string)
+			*masked.DefaultValue = maskedDefault
+			columns[name] = &masked
+		}
+	}
+}
+
+// maskDefaults hides the values of the default changes of a column
+func maskDefaults(changes []AttributeChange) {
+	for i, c := range changes {
+		if c.Attribute == "default" {
+			changes[i] = attributeChange(c.Attribute, "***", "***")
+		}
+	}
+}
+
+// printSensitiveChanges lists the changes of sensitive columns, warnings first
+func printSensitiveChanges(w io.Writer, changes []*SensitiveChange) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n🔒 Sensitive columns:")
+	sorted := append([]*SensitiveChange{}, changes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Warning != "" && sorted[j].Warning == "" })
+	symbols := map[string]string{ChangeOnlyInSource: "-", ChangeOnlyInTarget: "+", ChangeModified: "~"}
+	for _, c := range sorted {
+		line := fmt.Sprintf("  %s %s.%s", symbols[c.Change], c.Table, c.Column)
+		if c.Detail != "" {
+			line += ": " + c.Detail
+		}
+		fmt.Fprintln(w, line)
+		if c.Warning != "" {
+			fmt.Fprintf(w, "      warning: %s\n", c.Warning)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package dbdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSensitiveColumnChangesMasksOneSidedDefaults(t *testing.T) {
+	ssn := "'123-45-6789'"
+	column := func() *Column {
+		return &Column{Name: "ssn", DataType: "text", IsNullable: true, DefaultValue: &ssn, Comment: "[pii]"}
+	}
+	id := &Column{Name: "id", DataType: "integer"}
+	source := &Schema{Tables: map[string]*Table{
+		"users":   {Name: "users", Columns: map[string]*Column{"id": id, "ssn": column()}},
+		"archive": {Name: "archive", Columns: map[string]*Column{"ssn": column()}},
+	}}
+	target := &Schema{Tables: map[string]*Table{"users": {Name: "users", Columns: map[string]*Column{"id": id}}}}
+	MarkSensitiveColumns(source, SensitiveConfig{})
+
+	diff := ComputeDiff(source, target, &FilterConfig{})
+	diff.SensitiveChanges = SensitiveColumnChanges(diff, source, target)
+
+	result := NewResult(diff)
+	data, err := result.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := result.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for format, out := range map[string]string{"json": string(data), "text": string(text)} {
+		if strings.Contains(out, "123-45-6789") {
+			t.Errorf("%s output contains the default of a sensitive column:\n%s", format, out)
+		}
+	}
+	if !strings.Contains(string(data), `"default_value": "***"`) && !strings.Contains(string(data), `"default_value":"***"`) {
+		t.Errorf("json output doesn't mask the default:\n%s", data)
+	}
+	if got := *source.Tables["users"].Columns["ssn"].DefaultValue; got != ssn {
+		t.Errorf("source schema default = %s, want it untouched", got)
+	}
+}
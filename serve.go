@@ -0,0 +1,364 @@
+package dbdiff
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// SERVE - Long-running diff daemon with warm snapshots
+// ============================================================================
+
+// cachedSnapshot is the latest extracted schema of a registered connection
+type cachedSnapshot struct {
+	Schema      *Schema
+	ExtractedAt time.Time
+	Duration    time.Duration
+	Err         error
+}
+
+// snapshotCache keeps warm schema snapshots for registered connections
+type snapshotCache struct {
+	mu          sync.RWMutex
+	cfg         *Config
+	connections map[string]*ConnectionConfig
+	dbs         map[string]*sql.DB
+	snapshots   map[string]*cachedSnapshot
+	refreshing  map[string]*sync.Mutex
+}
+
+func newSnapshotCache(cfg *Config) *snapshotCache {
+	c := &snapshotCache{
+		cfg:         cfg,
+		connections: cfg.Connections,
+		dbs:         make(map[string]*sql.DB),
+		snapshots:   make(map[string]*cachedSnapshot),
+		refreshing:  make(map[string]*sync.Mutex),
+	}
+	for name := range cfg.Connections {
+		c.refreshing[name] = &sync.Mutex{}
+	}
+	return c
+}
+
+// refresh re-extracts one connection's schema, giving up when ctx is done.
+// Concurrent refreshes of the same connection are serialized; the previous
+// snapshot keeps being served.
+func (c *snapshotCache) refresh(ctx context.Context, name string) error {
+	conn, ok := c.connections[name]
+	if !ok {
+		return fmt.Errorf("unknown connection %q", name)
+	}
+	lock := c.refreshing[name]
+	lock.Lock()
+	defer lock.Unlock()
+
+	c.mu.RLock()
+	db := c.dbs[name]
+	c.mu.RUnlock()
+
+	if db == nil {
+		var err error
+		db, err = openDB(conn.Driver, conn.DSN)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.dbs[name] = db
+		c.mu.Unlock()
+	}
+
+	dialect := getDialect(conn.Driver)
+	if dialect == nil {
+		return fmt.Errorf("unsupported driver %q", conn.Driver)
+	}
+
+	start := time.Now()
+	schema, err := dialect.ExtractSchema(ctx, db, ExtractOptions{Concurrency: parallelExtractConcurrency})
+	if schema != nil {
+		// Marked before it is cached, so that the requests comparing it
+		// concurrently only read its columns
+		MarkSensitiveColumns(schema, c.cfg.Sensitive)
+	}
+	snap := &cachedSnapshot{Schema: schema, ExtractedAt: time.Now(), Duration: time.Since(start), Err: err}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		// Keep serving the last good schema, but surface the failure
+		if prev, ok := c.snapshots[name]; ok && prev.Schema != nil {
+			snap.Schema, snap.ExtractedAt = prev.Schema, prev.ExtractedAt
+		}
+	}
+	c.snapshots[name] = snap
+	return err
+}
+
+func (c *snapshotCache) refreshAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for name := range c.connections {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := c.refresh(ctx, name); err != nil {
+				fmt.Fprintf(os.Stderr, "serve: refreshing %s: %v\n", name, err)
+			}
+		}(name)
+	}
+	wg.Wait()
+}
+
+func (c *snapshotCache) get(name string) (*cachedSnapshot, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	snap, ok := c.snapshots[name]
+	return snap, ok
+}
+
+func (c *snapshotCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, db := range c.dbs {
+		db.Close()
+	}
+}
+
+func (c *snapshotCache) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	mux.HandleFunc("/connections", func(w http.ResponseWriter, r *http.Request) {
+		type status struct {
+			Name        string `json:"name"`
+			Driver      string `json:"driver"`
+			Tables      int    `json:"tables"`
+			ExtractedAt string `json:"extracted_at,omitempty"`
+			DurationMS  int64  `json:"extraction_ms,omitempty"`
+			Error       string `json:"error,omitempty"`
+		}
+		var statuses []status
+		for _, name := range getSortedKeys(c.connections) {
+			st := status{Name: name, Driver: c.connections[name].Driver}
+			if snap, ok := c.get(name); ok {
+				if snap.Schema != nil {
+					st.Tables = len(snap.Schema.Tables)
+					st.ExtractedAt = snap.ExtractedAt.UTC().Format(time.RFC3339)
+				}
+				st.DurationMS = snap.Duration.Milliseconds()
+				if snap.Err != nil {
+					st.Error = snap.Err.Error()
+				}
+			}
+			statuses = append(statuses, st)
+		}
+		writeJSONResponse(w, http.StatusOK, statuses)
+	})
+
+	mux.HandleFunc("/snapshots/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/snapshots/")
+		snap, ok := c.get(name)
+		if !ok || snap.Schema == nil {
+			http.Error(w, fmt.Sprintf("no snapshot for %q", name), http.StatusNotFound)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, maskSensitiveSchema(snap.Schema))
+	})
+
+	mux.HandleFunc("/refresh", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		if _, ok := c.connections[name]; name != "" && !ok {
+			http.Error(w, fmt.Sprintf("unknown connection %q", name), http.StatusNotFound)
+			return
+		}
+		// Extraction stops when the client goes away
+		if name == "" {
+			c.refreshAll(r.Context())
+		} else if err := c.refresh(r.Context(), name); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// GET /diff?source=a&target=b[&format=json|pretty|markdown|html][&profile=...][&exclude_preset=...]
+	mux.HandleFunc("/diff", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		source, sok := c.get(q.Get("source"))
+		target, tok := c.get(q.Get("target"))
+		if !sok || !tok || source.Schema == nil || target.Schema == nil {
+			http.Error(w, "source and target must name connections with a snapshot", http.StatusNotFound)
+			return
+		}
+
+		filter := NewFilterConfig()
+		if profile := q.Get("profile"); profile != "" {
+			if err := filter.ApplyProfile(profile); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		for _, name := range splitList(q.Get("exclude_preset")) {
+			globs, err := c.cfg.ExcludePreset(name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			filter.IgnoreTableGlobs = append(filter.IgnoreTableGlobs, globs...)
+		}
+		sourceDriver, targetDriver := c.connections[q.Get("source")].Driver, c.connections[q.Get("target")].Driver
+		if engineFamily(sourceDriver) != engineFamily(targetDriver) {
+			filter.TypeEquivalence = typeEquivalence(defaultTypeEquivalences, c.cfg.TypeEquivalences)
+		}
+		sortMode := q.Get("sort")
+		format := q.Get("format")
+		if format == "" {
+			format = FormatJSON
+		}
+
+		// The cached snapshots are shared by concurrent requests, so objects
+		// are set aside from copies
+		from, to, notComparable, err := prepareSchemas(shallowSchema(source.Schema), shallowSchema(target.Schema), c.cfg, DirectionSourceToTarget)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		diff := diffSchemas(from, to, filter, c.cfg, diffOptions{Direction: DirectionSourceToTarget, NotComparable: notComparable})
+		if err := finishDiff(diff, from, to, sortMode); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch format {
+		case FormatJSON:
+			w.Header().Set("Content-Type", "application/json")
+		case FormatHTML:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		case FormatBadge:
+			w.Header().Set("Content-Type", "application/json")
+		case FormatBadgeSVG:
+			w.Header().Set("Content-Type", "image/svg+xml")
+		default:
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		w.Header().Set("X-Source-Extracted-At", source.ExtractedAt.UTC().Format(time.RFC3339))
+		w.Header().Set("X-Target-Extracted-At", target.ExtractedAt.UTC().Format(time.RFC3339))
+		if err := WriteDiff(w, diff, format); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	})
+
+	return mux
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := fs.String("config", "", "Config file with named connections (default: discovered dbdiff.yaml)")
+	listen := fs.String("listen", "", "Address to listen on (default: serve.listen from config or 127.0.0.1:8080)")
+	refresh := fs.String("refresh", "", "Snapshot refresh interval (default: serve.refresh from config or 10m)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff serve [--config dbdiff.yaml] [--listen 127.0.0.1:8080] [--refresh 10m]")
+		fmt.Fprintln(os.Stderr, "\nKeeps warm schema snapshots of every connection in the config file and")
+		fmt.Fprintln(os.Stderr, "serves diffs between them over HTTP:")
+		fmt.Fprintln(os.Stderr, "  GET  /connections                       snapshot status per connection")
+		fmt.Fprintln(os.Stderr, "  GET  /diff?source=a&target=b            diff (format=json|pretty|markdown|html, profile, sort, exclude_preset)")
+		fmt.Fprintln(os.Stderr, "  GET  /snapshots/<name>                  cached schema as JSON")
+		fmt.Fprintln(os.Stderr, "  POST /refresh[?name=a]                  re-extract now")
+		fmt.Fprintln(os.Stderr, "  GET  /healthz")
+		fmt.Fprintln(os.Stderr, "\nWith serve.token or DBDIFF_SERVE_TOKEN set, every endpoint but /healthz")
+		fmt.Fprintln(os.Stderr, "requires an \"Authorization: Bearer <token>\" header.")
+	}
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if len(cfg.Connections) == 0 {
+		fmt.Fprintln(os.Stderr, "serve needs at least one entry under connections: in the config file")
+		os.Exit(1)
+	}
+
+	addr := firstNonEmpty(*listen, cfg.Serve.Listen, "127.0.0.1:8080")
+	token := firstNonEmpty(os.Getenv("DBDIFF_SERVE_TOKEN"), cfg.Serve.Token)
+	interval, err := time.ParseDuration(firstNonEmpty(*refresh, cfg.Serve.Refresh, "10m"))
+	if err != nil || interval <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid refresh interval: %v\n", err)
+		os.Exit(1)
+	}
+
+	cache := newSnapshotCache(cfg)
+	defer cache.close()
+
+	go func() {
+		for {
+			cache.refreshAll(context.Background())
+			time.Sleep(interval)
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "dbdiff serve: %d connection(s), refresh every %s, listening on %s\n", len(cfg.Connections), interval, addr)
+	if token == "" && !isLoopbackAddr(addr) {
+		fmt.Fprintln(os.Stderr, "Warning: listening beyond loopback without a token; set serve.token or DBDIFF_SERVE_TOKEN")
+	}
+	if err := http.ListenAndServe(addr, requireToken(token, cache.handler())); err != nil {
+		fmt.Fprintf(os.Stderr, "Error serving: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// requireToken rejects requests other than /healthz that don't carry token as
+// a bearer token; an empty token lets every request through
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if r.URL.Path != "/healthz" && (!ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackAddr reports whether a listen address only accepts local
+// connections
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
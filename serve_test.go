@@ -0,0 +1,179 @@
+package dbdiff
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCache returns a snapshot cache serving the given schemas as if
+// they had been extracted
+func newTestCache(schemas map[string]*Schema) *snapshotCache {
+	connections := make(map[string]*ConnectionConfig)
+	for name := range schemas {
+		connections[name] = &ConnectionConfig{Driver: "postgres", DSN: "postgres://localhost/" + name}
+	}
+	cache := newSnapshotCache(&Config{Connections: connections})
+	for name, schema := range schemas {
+		MarkSensitiveColumns(schema, cache.cfg.Sensitive)
+		cache.snapshots[name] = &cachedSnapshot{Schema: schema, ExtractedAt: time.Now()}
+	}
+	return cache
+}
+
+func TestServeDiffsCachedSnapshots(t *testing.T) {
+	prod := &Schema{Tables: map[string]*Table{"users": newTable("users"), "audit": newTable("audit")}}
+	staging := &Schema{Tables: map[string]*Table{"users": newTable("users")}}
+	server := httptest.NewServer(newTestCache(map[string]*Schema{"prod": prod, "staging": staging}).handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/diff?source=prod&target=staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Source-Extracted-At") == "" {
+		t.Fatalf("GET /diff = %s, headers %v", resp.Status, resp.Header)
+	}
+	var diff SchemaDiff
+	if err := json.NewDecoder(resp.Body).Decode(&diff); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(diff.TablesOnlyInSource, ",") != "audit" || len(diff.TablesOnlyInTarget) != 0 {
+		t.Errorf("diff = %+v, want audit only in source", diff)
+	}
+}
+
+func TestServeMasksSensitiveColumns(t *testing.T) {
+	prod := &Schema{Tables: map[string]*Table{"users": newTable("users")}}
+	staging := &Schema{Tables: map[string]*Table{"users": newTable("users")}}
+	prodToken, stagingToken := "'prod-token'", "'staging-token'"
+	prod.Tables["users"].Columns["api_key"] = &Column{Name: "api_key", DataType: "text", Comment: "[pii]", DefaultValue: &prodToken}
+	staging.Tables["users"].Columns["api_key"] = &Column{Name: "api_key", DataType: "text", Comment: "[pii]", DefaultValue: &stagingToken}
+	server := httptest.NewServer(newTestCache(map[string]*Schema{"prod": prod, "staging": staging}).handler())
+	defer server.Close()
+
+	for _, path := range []string{"/diff?source=prod&target=staging", "/snapshots/prod"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || strings.Contains(string(body), "token") {
+			t.Errorf("GET %s = %s\n%s\nwant the defaults masked", path, resp.Status, body)
+		}
+	}
+	if *prod.Tables["users"].Columns["api_key"].DefaultValue != prodToken {
+		t.Error("masking changed the cached snapshot")
+	}
+}
+
+func TestServeRejectsUnknownConnectionsAndMethods(t *testing.T) {
+	server := httptest.NewServer(newTestCache(map[string]*Schema{"prod": {Tables: map[string]*Table{}}}).handler())
+	defer server.Close()
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/diff?source=prod&target=missing", http.StatusNotFound},
+		{http.MethodGet, "/diff?source=prod&target=prod&profile=nope", http.StatusBadRequest},
+		{http.MethodGet, "/snapshots/missing", http.StatusNotFound},
+		{http.MethodGet, "/snapshots/prod", http.StatusOK},
+		{http.MethodGet, "/refresh", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/refresh?name=missing", http.StatusNotFound},
+		{http.MethodGet, "/healthz", http.StatusOK},
+	} {
+		req, _ := http.NewRequest(tc.method, server.URL+tc.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.path, resp.StatusCode, tc.want)
+		}
+	}
+}
+
+func TestServeListsConnectionStatus(t *testing.T) {
+	cache := newTestCache(map[string]*Schema{"prod": {Tables: map[string]*Table{"users": newTable("users")}}})
+	cache.connections["replica"] = &ConnectionConfig{Driver: "mysql", DSN: "u@tcp(replica)/app"}
+	server := httptest.NewServer(cache.handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/connections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var statuses []struct {
+		Name   string `json:"name"`
+		Driver string `json:"driver"`
+		Tables int    `json:"tables"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0].Name != "prod" || statuses[0].Tables != 1 || statuses[1].Name != "replica" || statuses[1].Tables != 0 {
+		t.Errorf("connections = %+v, want prod with one table and replica without a snapshot", statuses)
+	}
+}
+
+func TestLoadConfigExpandsAndValidatesConnections(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DBDIFF_TEST_PASSWORD", "s3cret")
+
+	path := filepath.Join(dir, "dbdiff.yaml")
+	os.WriteFile(path, []byte("connections:\n  prod:\n    driver: postgres\n    dsn: postgres://app:${DBDIFF_TEST_PASSWORD}@db/app\nserve:\n  refresh: 5m\n"), 0o644)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Connections["prod"].DSN; got != "postgres://app:s3cret@db/app" {
+		t.Errorf("dsn = %q, want the password expanded", got)
+	}
+	if cfg.Serve.Refresh != "5m" {
+		t.Errorf("refresh = %q, want 5m", cfg.Serve.Refresh)
+	}
+
+	os.WriteFile(path, []byte("connections:\n  prod:\n    driver: postgres\n"), 0o644)
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "needs driver and dsn") {
+		t.Errorf("LoadConfig without dsn = %v, want an error", err)
+	}
+}
+
+func TestRequireTokenGuardsRefresh(t *testing.T) {
+	handler := requireToken("s3cret", newSnapshotCache(&Config{}).handler())
+	for _, tc := range []struct {
+		method, path, auth string
+		want               int
+	}{
+		{http.MethodPost, "/refresh", "", http.StatusUnauthorized},
+		{http.MethodPost, "/refresh", "Bearer wrong", http.StatusUnauthorized},
+		{http.MethodPost, "/refresh", "s3cret", http.StatusUnauthorized},
+		{http.MethodPost, "/refresh", "Bearer s3cret", http.StatusNoContent},
+		{http.MethodGet, "/connections", "", http.StatusUnauthorized},
+		{http.MethodGet, "/healthz", "", http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s with %q = %d, want %d", tc.method, tc.path, tc.auth, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package dbdiff
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// SESSION DEFAULTS - Defaults whose rendering depends on session settings
+// ============================================================================
+
+// DefaultWarning explains a default value difference that may come from the
+// settings of the server or session that read or created the schema rather
+// than from the schema itself
+type DefaultWarning struct {
+	Table   string `json:"table"`
+	Column  string `json:"column"`
+	Detail  string `json:"detail"`
+	Setting string `json:"setting"`
+	Warning string `json:"warning"`
+}
+
+var (
+	zeroDatePattern     = regexp.MustCompile(`^0000-00-00`)
+	isoDatePattern      = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+	dateLiteralPattern  = regexp.MustCompile(`^\d{1,4}[-/.]\d{1,2}[-/.]\d{1,4}|^[A-Za-z]{3} [A-Za-z]{3} \d`)
+	defaultLiteralQuote = regexp.MustCompile(`^'(.*)'(::.*)?$`)
+)
+
+// SessionDependentDefaults collects the default value differences of diff
+// that session settings can explain: MySQL zero dates (sql_mode) and
+// implicit TIMESTAMP defaults (explicit_defaults_for_timestamp), and the
+// Postgres output settings bytea_output, TimeZone, DateStyle, IntervalStyle
+// and extra_float_digits
+func SessionDependentDefaults(diff *SchemaDiff, source, target *Schema) []*DefaultWarning {
+	column := func(schema *Schema, table, name string) *Column {
+		if t := schema.Tables[table]; t != nil {
+			return t.Columns[name]
+		}
+		return nil
+	}
+	var warnings []*DefaultWarning
+	for _, td := range diff.TableDiffs {
+		for _, cd := range td.ColumnDiffs {
+			var detail string
+			for _, c := range cd.Changes {
+				if c.Attribute == "default" {
+					detail = c.String()
+				}
+			}
+			src, tgt := column(source, td.TableName, cd.ColumnName), column(target, td.TableName, cd.ColumnName)
+			if detail == "" || src == nil || tgt == nil {
+				continue
+			}
+			if setting, warning := sessionDependentDefault(src, tgt); setting != "" {
+				warnings = append(warnings, &DefaultWarning{Table: td.TableName, Column: cd.ColumnName, Detail: detail, Setting: setting, Warning: warning})
+			}
+		}
+	}
+	return warnings
+}
+
+// sessionDependentDefault returns the setting that can explain why the
+// defaults of two columns differ, and why
+func sessionDependentDefault(src, tgt *Column) (setting, warning string) {
+	literal := func(c *Column) string {
+		if c.DefaultValue == nil {
+			return ""
+		}
+		d := strings.TrimSpace(*c.DefaultValue)
+		if m := defaultLiteralQuote.FindStringSubmatch(d); m != nil {
+			return m[1]
+		}
+		return d
+	}
+	typ := strings.ToLower(columnTypeName(src) + " " + columnTypeName(tgt))
+	s, t := literal(src), literal(tgt)
+	dateType := strings.Contains(typ, "date") || strings.Contains(typ, "timestamp")
+
+	switch {
+	case dateType && (zeroDatePattern.MatchString(s) || zeroDatePattern.MatchString(t)):
+		return "sql_mode", "zero dates are rejected under NO_ZERO_DATE and strict sql_mode, so servers and dumps running with another sql_mode keep, rewrite or drop them"
+	case strings.Contains(typ, "timestamp") && (src.DefaultValue == nil) != (tgt.DefaultValue == nil) &&
+		normalizeDefault(s+t) == "current_timestamp":
+		return "explicit_defaults_for_timestamp", "with explicit_defaults_for_timestamp off, MySQL gives the first TIMESTAMP NOT NULL column an implicit DEFAULT CURRENT_TIMESTAMP"
+	case strings.Contains(typ, "bytea") && s != "" && t != "":
+		return "bytea_output", "bytea literals are shown in hex (\\x...) or escape format depending on bytea_output"
+	case (strings.Contains(typ, "timestamptz") || strings.Contains(typ, "with time zone")) && isoDatePattern.MatchString(s) && isoDatePattern.MatchString(t):
+		return "TimeZone", "timestamptz literals are shown in the session's TimeZone, so the same instant can read differently"
+	case dateType && s != "" && t != "" && (isDateLiteral(s) || isDateLiteral(t)) && !(isoDatePattern.MatchString(s) && isoDatePattern.MatchString(t)):
+		return "DateStyle", "date literals are shown in the session's DateStyle (ISO, SQL, Postgres or German)"
+	case strings.Contains(typ, "interval") && s != "" && t != "":
+		return "IntervalStyle", "interval literals are shown in the session's IntervalStyle (postgres, iso_8601, sql_standard)"
+	case strings.Contains(typ, "float") || strings.Contains(typ, "real") || strings.Contains(typ, "double"):
+		a, errA := strconv.ParseFloat(s, 64)
+		b, errB := strconv.ParseFloat(t, 64)
+		if errA == nil && errB == nil && math.Abs(a-b) <= 1e-6*math.Max(math.Abs(a), math.Abs(b)) {
+			return "extra_float_digits", "floating point values are rounded for display according to extra_float_digits"
+		}
+	}
+	return "", ""
+}
+
+// isDateLiteral reports whether a default reads as a date in any DateStyle
+func isDateLiteral(s string) bool {
+	return dateLiteralPattern.MatchString(s)
+}
+
+// printDefaultWarnings lists the default differences session settings can
+// explain
+func printDefaultWarnings(w io.Writer, warnings []*DefaultWarning) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n⚠️  Session-dependent defaults:")
+	for _, dw := range warnings {
+		fmt.Fprintf(w, "  ~ %s.%s: %s\n", dw.Table, dw.Column, dw.Detail)
+		fmt.Fprintf(w, "      warning: depends on %s: %s\n", dw.Setting, dw.Warning)
+	}
+}
@@ -0,0 +1,88 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// SERVER SETTINGS - Configuration that changes how identical DDL behaves
+// ============================================================================
+
+// settingsReader is implemented by dialects that can read server settings
+type settingsReader interface {
+	serverSettings(ctx context.Context, db *sql.DB, names []string) (map[string]string, error)
+}
+
+func (p *PostgresDialect) serverSettings(ctx context.Context, db *sql.DB, names []string) (map[string]string, error) {
+	settings := make(map[string]string)
+	for _, name := range names {
+		// missing_ok: unknown settings read as NULL instead of failing
+		var value sql.NullString
+		if err := db.QueryRowContext(ctx, "SELECT current_setting($1, true)", name).Scan(&value); err != nil {
+			return nil, err
+		}
+		if value.Valid {
+			settings[name] = value.String
+		}
+	}
+	return settings, nil
+}
+
+func (m *MySQLDialect) serverSettings(ctx context.Context, db *sql.DB, names []string) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, "SHOW VARIABLES")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wanted := make(map[string]string, len(names))
+	for _, name := range names {
+		wanted[strings.ToLower(name)] = name
+	}
+	settings := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		if requested, ok := wanted[strings.ToLower(name)]; ok {
+			settings[requested] = value
+		}
+	}
+	return settings, rows.Err()
+}
+
+// loadSettings reads the named settings of a live database
+func loadSettings(driver, conn string, names []string) (map[string]string, error) {
+	reader, ok := getDialect(driver).(settingsReader)
+	if !ok {
+		return nil, fmt.Errorf("reading settings is not supported for %s", driver)
+	}
+	db, err := openDB(driver, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return reader.serverSettings(context.Background(), db, names)
+}
+
+// CompareSettings returns the settings whose values differ, in the order of
+// names. A setting the server does not know reads as "(unset)".
+func CompareSettings(names []string, source, target map[string]string) []*SettingDiff {
+	value := func(settings map[string]string, name string) string {
+		if v, ok := settings[name]; ok {
+			return v
+		}
+		return "(unset)"
+	}
+	var diffs []*SettingDiff
+	for _, name := range names {
+		if s, t := value(source, name), value(target, name); s != t {
+			diffs = append(diffs, &SettingDiff{Name: name, Source: s, Target: t})
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,280 @@
+package dbdiff
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ============================================================================
+// SNAPSHOTS - Schemas saved to disk
+// ============================================================================
+
+// Snapshot compression formats accepted by dbdiff snapshot --compress
+const (
+	CompressNone = "none"
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// compressionExt returns the file extension conventionally used for a
+// compression format
+func compressionExt(compression string) string {
+	switch compression {
+	case CompressGzip:
+		return ".gz"
+	case CompressZstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// compressionForPath picks the compression format from a file name's
+// extension
+func compressionForPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		return CompressGzip
+	case strings.HasSuffix(path, ".zst"):
+		return CompressZstd
+	}
+	return CompressNone
+}
+
+// snapshotOutput picks the file and compression `dbdiff snapshot --out`
+// writes. Without --compress, a .gz or .zst extension of out decides, and
+// other names get gzip and a .gz extension; an explicit --compress that
+// contradicts the extension is an error rather than a doubled or misleading
+// extension.
+func snapshotOutput(out, compress string, compressSet bool) (path, compression string, err error) {
+	if ext := compressionForPath(out); ext != CompressNone {
+		if compressSet && compress != ext {
+			return "", "", fmt.Errorf("--out %s names a %s file but --compress is %s", out, ext, compress)
+		}
+		return out, ext, nil
+	}
+	return out + compressionExt(compress), compress, nil
+}
+
+// SaveSnapshot writes schema as indented JSON, the same document served by
+// `dbdiff serve` under /snapshots/<name>, compressed with gzip or zstd
+// unless compression is CompressNone
+func SaveSnapshot(schema *Schema, path, compression string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeSnapshot(f, schema, compression); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeSnapshot(w io.Writer, schema *Schema, compression string) error {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	var zw io.WriteCloser
+	switch compression {
+	case CompressNone:
+		_, err := w.Write(data)
+		return err
+	case CompressGzip:
+		zw, _ = gzip.NewWriterLevel(w, gzip.BestCompression)
+	case CompressZstd:
+		if zw, err = zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBetterCompression)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown compression %q (expected gzip, zstd or none)", compression)
+	}
+	if _, err := zw.Write(data); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// LoadSnapshot reads a schema written by SaveSnapshot
+func LoadSnapshot(path string) (*Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	schema, err := decodeSnapshot(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// decompress transparently unwraps gzip or zstd data, recognised by its magic
+// number so that compressed snapshots also work on stdin or under any name
+func decompress(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case bytes.HasPrefix(data, zstdMagic):
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	}
+	return data, nil
+}
+
+func decodeSnapshot(r io.Reader) (*Schema, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = decompress(data); err != nil {
+		return nil, fmt.Errorf("decompressing snapshot: %w", err)
+	}
+	var schema Schema
+	if err := json.Unmarshal(decodeText(data), &schema); err != nil {
+		return nil, fmt.Errorf("parsing snapshot: %w", err)
+	}
+	if schema.Tables == nil {
+		schema.Tables = make(map[string]*Table)
+	}
+	// Hand-written snapshots may omit empty maps; the diff engine expects them
+	for name, table := range schema.Tables {
+		loaded := newTable(name)
+		if table != nil {
+			loaded.PrimaryKey = table.PrimaryKey
+			loaded.Comment = table.Comment
+			loaded.AccessMethod = table.AccessMethod
+			loaded.ReplicaIdentity = table.ReplicaIdentity
+			loaded.Options = table.Options
+			loaded.Partitioning = table.Partitioning
+			loaded.PartitionOf = table.PartitionOf
+			loaded.Inherits = table.Inherits
+			loaded.RewriteRules = table.RewriteRules
+			loaded.Foreign = table.Foreign
+			mergeInto(loaded.Columns, table.Columns)
+			mergeInto(loaded.ForeignKeys, table.ForeignKeys)
+			mergeInto(loaded.UniqueConstraints, table.UniqueConstraints)
+			mergeInto(loaded.Indexes, table.Indexes)
+			mergeInto(loaded.CheckConstraints, table.CheckConstraints)
+			mergeInto(loaded.Triggers, table.Triggers)
+		}
+		schema.Tables[name] = loaded
+	}
+	return &schema, nil
+}
+
+func mergeInto[T any](dst, src map[string]T) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+func runSnapshot(args []string) {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	conn := fs.String("source", "", "Database connection string")
+	driver := fs.String("source-driver", "", "Database driver (postgres or mysql)")
+	out := fs.String("out", "", "File to write the snapshot to (default: stdout)")
+	parallel := fs.Bool("parallel", false, "Use parallel schema extraction")
+	includeExtensionObjects := fs.Bool("include-extension-objects", false, "Include tables owned by Postgres extensions")
+	includeEvents := fs.Bool("include-events", false, "Include MySQL scheduled events")
+	includeForeignTables := fs.Bool("include-foreign-tables", false, "Include Postgres foreign tables and servers")
+	schemas := fs.String("schemas", "", "Comma-separated MySQL databases to extract instead of the connection's current one")
+	maxQPS := fs.Float64("max-qps", 0, "Limit catalog queries per second (0 = unlimited)")
+	compress := fs.String("compress", CompressGzip, "Compression of --out: gzip, zstd or none (stdout is uncompressed unless set)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff snapshot --source <conn> --source-driver <driver> [--out golden.json] [--compress gzip|zstd|none] [--parallel]")
+		fmt.Fprintln(os.Stderr, "\nExtracts a schema and saves it as JSON, e.g. as the --template of dbdiff fleet.")
+		fmt.Fprintln(os.Stderr, "Files are gzip-compressed by default and get a .gz or .zst extension; an --out ending in")
+		fmt.Fprintln(os.Stderr, ".gz or .zst picks the compression itself.")
+	}
+	fs.Parse(args)
+
+	if *conn == "" || *driver == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	compressSet := false
+	fs.Visit(func(f *flag.Flag) { compressSet = compressSet || f.Name == "compress" })
+	if *compress != CompressGzip && *compress != CompressZstd && *compress != CompressNone {
+		fmt.Fprintf(os.Stderr, "Invalid --compress %q (expected gzip, zstd or none)\n", *compress)
+		os.Exit(1)
+	}
+	var path, compression string
+	if *out != "" {
+		var err error
+		if path, compression, err = snapshotOutput(*out, *compress, compressSet); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	dialect := getDialect(*driver)
+	if dialect == nil {
+		fmt.Fprintf(os.Stderr, "Unsupported driver: %s\n", *driver)
+		os.Exit(1)
+	}
+	if err := checkSchemasDriver("--schemas", *driver, splitList(*schemas)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	db, err := openDB(*driver, *conn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	opts := ExtractOptions{IncludeExtensionObjects: *includeExtensionObjects, IncludeEvents: *includeEvents,
+		IncludeForeignTables: *includeForeignTables, MaxQPS: *maxQPS, Schemas: splitList(*schemas)}
+	if *parallel {
+		opts.Concurrency = parallelExtractConcurrency
+	}
+	schema, err := dialect.ExtractSchema(context.Background(), db, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error extracting schema: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		compression := CompressNone
+		if compressSet {
+			compression = *compress
+		}
+		if err := writeSnapshot(os.Stdout, schema, compression); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing snapshot: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := SaveSnapshot(schema, path, compression); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing snapshot: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d tables to %s\n", len(schema.Tables), path)
+}
@@ -0,0 +1,75 @@
+package dbdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	schema, err := ParseDDL("CREATE TABLE users (id integer PRIMARY KEY, email text NOT NULL);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		compression string
+		magic       []byte
+	}{
+		{CompressGzip, gzipMagic},
+		{CompressZstd, zstdMagic},
+		{CompressNone, []byte("{")},
+	} {
+		path := filepath.Join(t.TempDir(), "schema.json"+compressionExt(tc.compression))
+		if err := SaveSnapshot(schema, path, tc.compression); err != nil {
+			t.Fatalf("%s: %v", tc.compression, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, tc.magic) {
+			t.Errorf("%s: file starts with % x, want % x", tc.compression, data[:min(len(data), 4)], tc.magic)
+		}
+		loaded, err := LoadSnapshot(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.compression, err)
+		}
+		if got, _ := json.Marshal(loaded); string(got) != string(want) {
+			t.Errorf("%s: loaded\n%s\nwant\n%s", tc.compression, got, want)
+		}
+	}
+}
+
+func TestSnapshotOutput(t *testing.T) {
+	for _, tc := range []struct {
+		out, compress          string
+		set                    bool
+		path, compression, err string
+	}{
+		{"golden.json", CompressGzip, false, "golden.json.gz", CompressGzip, ""},
+		{"golden.json", CompressZstd, true, "golden.json.zst", CompressZstd, ""},
+		{"golden.json", CompressNone, true, "golden.json", CompressNone, ""},
+		{"golden.json.zst", CompressGzip, false, "golden.json.zst", CompressZstd, ""},
+		{"golden.json.gz", CompressGzip, true, "golden.json.gz", CompressGzip, ""},
+		{"golden.json.zst", CompressGzip, true, "", "", "--out golden.json.zst names a zstd file but --compress is gzip"},
+		{"schema.gz", CompressNone, true, "", "", "--out schema.gz names a gzip file but --compress is none"},
+	} {
+		path, compression, err := snapshotOutput(tc.out, tc.compress, tc.set)
+		msg := ""
+		if err != nil {
+			msg = err.Error()
+		}
+		if msg != tc.err {
+			t.Errorf("--out %s --compress %s: error = %q, want %q", tc.out, tc.compress, msg, tc.err)
+		}
+		if path != tc.path || compression != tc.compression {
+			t.Errorf("--out %s --compress %s (set %v) = %s, %s, want %s, %s", tc.out, tc.compress, tc.set, path, compression, tc.path, tc.compression)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package dbdiff
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ============================================================================
+// DIFF ORDERING - Deterministic report ordering
+// ============================================================================
+
+// Sort modes accepted by --sort
+const (
+	SortByName     = "name"
+	SortBySeverity = "severity"
+	SortBySize     = "size"
+)
+
+// SortDiff normalizes the ordering of every list in the diff and then orders
+// table diffs according to mode. Names within a table are always sorted, so
+// the same pair of schemas always produces byte-identical output.
+func SortDiff(diff *SchemaDiff, mode string) error {
+	sort.Strings(diff.TablesOnlyInSource)
+	sort.Strings(diff.TablesOnlyInTarget)
+	sort.Strings(diff.TypesOnlyInSource)
+	sort.Strings(diff.TypesOnlyInTarget)
+	sort.Slice(diff.TypeDiffs, func(i, j int) bool { return diff.TypeDiffs[i].Name < diff.TypeDiffs[j].Name })
+	sort.Strings(diff.SequencesOnlyInSource)
+	sort.Strings(diff.SequencesOnlyInTarget)
+	sort.Slice(diff.SequenceDiffs, func(i, j int) bool { return diff.SequenceDiffs[i].Name < diff.SequenceDiffs[j].Name })
+	sort.Strings(diff.RoutinesOnlyInSource)
+	sort.Strings(diff.RoutinesOnlyInTarget)
+	sort.Slice(diff.RoutineDiffs, func(i, j int) bool { return diff.RoutineDiffs[i].Name < diff.RoutineDiffs[j].Name })
+	sort.Strings(diff.EventsOnlyInSource)
+	sort.Strings(diff.EventsOnlyInTarget)
+	sort.Slice(diff.EventDiffs, func(i, j int) bool { return diff.EventDiffs[i].Name < diff.EventDiffs[j].Name })
+	sort.Strings(diff.ExtensionsOnlyInSource)
+	sort.Strings(diff.ExtensionsOnlyInTarget)
+	sort.Slice(diff.ExtensionDiffs, func(i, j int) bool { return diff.ExtensionDiffs[i].Name < diff.ExtensionDiffs[j].Name })
+	sort.Strings(diff.ForeignServersOnlyInSource)
+	sort.Strings(diff.ForeignServersOnlyInTarget)
+	sort.Slice(diff.ForeignServerDiffs, func(i, j int) bool { return diff.ForeignServerDiffs[i].Name < diff.ForeignServerDiffs[j].Name })
+	sort.Strings(diff.SuppressedTables)
+
+	// The lists of the other report steps follow the order of catalog
+	// queries and of the objects they were found in
+	sortByKeys(diff.SettingDiffs, func(d *SettingDiff) []string { return []string{d.Name} })
+	sortByKeys(diff.GrantDiffs, func(d *GrantDiff) []string { return []string{d.Object, d.Grantee, d.Privilege, d.Change} })
+	sortByKeys(diff.Orphans, func(o *OrphanedObject) []string { return []string{o.Side, o.Category, o.Name, o.Detail} })
+	sortByKeys(diff.NotComparable, func(o *NotComparable) []string { return []string{o.Side, o.Kind, o.Name} })
+	// Warnings keep their order within a table
+	sortByKeys(diff.SensitiveChanges, func(c *SensitiveChange) []string { return []string{c.Table, c.Column} })
+	sortByKeys(diff.DefaultWarnings, func(w *DefaultWarning) []string { return []string{w.Table, w.Column} })
+	sortByKeys(diff.CollationWarnings, func(w *CollationWarning) []string { return []string{w.Collation} })
+	sortByKeys(diff.AppendOnlyWarnings, func(w *AppendOnlyWarning) []string { return []string{w.Table} })
+	sortByKeys(diff.AppendOnlyRows, func(r *AppendOnlyRows) []string { return []string{r.Table} })
+
+	for _, td := range diff.TableDiffs {
+		sortTableDiff(td)
+	}
+
+	var less func(a, b *TableDiff) bool
+	switch mode {
+	case "", SortByName:
+		less = func(a, b *TableDiff) bool { return false }
+	case SortBySeverity:
+		less = func(a, b *TableDiff) bool { return tableDiffSeverity(a) > tableDiffSeverity(b) }
+	case SortBySize:
+		less = func(a, b *TableDiff) bool { return tableDiffSize(a) > tableDiffSize(b) }
+	default:
+		return fmt.Errorf("unknown sort mode %q (expected name, severity or size)", mode)
+	}
+
+	sort.SliceStable(diff.TableDiffs, func(i, j int) bool {
+		a, b := diff.TableDiffs[i], diff.TableDiffs[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.TableName < b.TableName
+	})
+	return nil
+}
+
+// sortByKeys orders items by the keys of each, compared in turn; items with
+// equal keys keep their order
+func sortByKeys[T any](items []T, keys func(T) []string) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := keys(items[i]), keys(items[j])
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+}
+
+func sortTableDiff(td *TableDiff) {
+	for _, names := range [][]string{
+		td.ColumnsOnlyInSource, td.ColumnsOnlyInTarget,
+		td.ForeignKeysOnlyInSource, td.ForeignKeysOnlyInTarget,
+		td.UniquesOnlyInSource, td.UniquesOnlyInTarget,
+		td.IndexesOnlyInSource, td.IndexesOnlyInTarget,
+		td.ChecksOnlyInSource, td.ChecksOnlyInTarget,
+		td.TriggersOnlyInSource, td.TriggersOnlyInTarget,
+		td.RewriteRulesOnlyInSource, td.RewriteRulesOnlyInTarget,
+	} {
+		sort.Strings(names)
+	}
+	sort.SliceStable(td.ColumnDiffs, func(i, j int) bool { return td.ColumnDiffs[i].ColumnName < td.ColumnDiffs[j].ColumnName })
+	sort.SliceStable(td.ForeignKeyDiffs, func(i, j int) bool { return td.ForeignKeyDiffs[i].Name < td.ForeignKeyDiffs[j].Name })
+	sort.SliceStable(td.UniqueDiffs, func(i, j int) bool { return td.UniqueDiffs[i].Name < td.UniqueDiffs[j].Name })
+	sort.SliceStable(td.IndexDiffs, func(i, j int) bool { return td.IndexDiffs[i].Name < td.IndexDiffs[j].Name })
+	sort.SliceStable(td.CheckDiffs, func(i, j int) bool { return td.CheckDiffs[i].Name < td.CheckDiffs[j].Name })
+	sort.SliceStable(td.TriggerDiffs, func(i, j int) bool { return td.TriggerDiffs[i].Name < td.TriggerDiffs[j].Name })
+	sort.SliceStable(td.RewriteRuleDiffs, func(i, j int) bool { return td.RewriteRuleDiffs[i].Name < td.RewriteRuleDiffs[j].Name })
+}
+
+// tableDiffSeverity weighs findings by how disruptive they are to apply:
+// removals and key changes outrank modifications, which outrank additions.
+func tableDiffSeverity(td *TableDiff) int {
+	score := 0
+	score += 5 * len(td.ColumnsOnlyInSource)
+	score += 3 * len(td.ColumnDiffs)
+	score += 1 * len(td.ColumnsOnlyInTarget)
+	if td.PrimaryKeyDiff != nil {
+		score += 5
+	}
+	score += 3 * (len(td.ForeignKeysOnlyInSource) + len(td.UniquesOnlyInSource))
+	score += 2 * (len(td.ForeignKeyDiffs) + len(td.UniqueDiffs) + len(td.CheckDiffs))
+	score += 2 * (len(td.ForeignKeysOnlyInTarget) + len(td.UniquesOnlyInTarget) + len(td.ChecksOnlyInTarget))
+	score += 1 * (len(td.IndexesOnlyInSource) + len(td.IndexesOnlyInTarget) + len(td.IndexDiffs) + len(td.ChecksOnlyInSource))
+	score += 1 * (len(td.TriggersOnlyInSource) + len(td.TriggersOnlyInTarget) + len(td.TriggerDiffs))
+	score += 1 * (len(td.RewriteRulesOnlyInSource) + len(td.RewriteRulesOnlyInTarget) + len(td.RewriteRuleDiffs))
+	if td.AccessMethodDiff != nil {
+		score += 1
+	}
+	if td.ReplicaIdentityDiff != nil {
+		score += 1
+	}
+	if td.OptionsDiff != nil {
+		score += 1
+	}
+	if td.PartitioningDiff != nil {
+		score += 3
+	}
+	if td.InheritanceDiff != nil {
+		score += 3
+	}
+	if td.ForeignTableDiff != nil {
+		score += 3
+	}
+	return score
+}
+
+// tableDiffSize is the number of individual findings in a table diff
+func tableDiffSize(td *TableDiff) int {
+	size := len(td.ColumnsOnlyInSource) + len(td.ColumnsOnlyInTarget) + len(td.ColumnDiffs) +
+		len(td.ForeignKeysOnlyInSource) + len(td.ForeignKeysOnlyInTarget) + len(td.ForeignKeyDiffs) +
+		len(td.UniquesOnlyInSource) + len(td.UniquesOnlyInTarget) + len(td.UniqueDiffs) +
+		len(td.IndexesOnlyInSource) + len(td.IndexesOnlyInTarget) + len(td.IndexDiffs) +
+		len(td.ChecksOnlyInSource) + len(td.ChecksOnlyInTarget) + len(td.CheckDiffs) +
+		len(td.TriggersOnlyInSource) + len(td.TriggersOnlyInTarget) + len(td.TriggerDiffs) +
+		len(td.RewriteRulesOnlyInSource) + len(td.RewriteRulesOnlyInTarget) + len(td.RewriteRuleDiffs)
+	if td.PrimaryKeyDiff != nil {
+		size++
+	}
+	if td.CommentDiff != nil {
+		size++
+	}
+	if td.AccessMethodDiff != nil {
+		size++
+	}
+	if td.ReplicaIdentityDiff != nil {
+		size++
+	}
+	if td.OptionsDiff != nil {
+		size++
+	}
+	if td.PartitioningDiff != nil {
+		size++
+	}
+	if td.InheritanceDiff != nil {
+		size++
+	}
+	if td.ForeignTableDiff != nil {
+		size++
+	}
+	return size
+}
@@ -0,0 +1,54 @@
+package dbdiff
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestSortDiffIsDeterministic(t *testing.T) {
+	// run builds the diff of the same schemas with the lists of the report
+	// steps in the given order, as two runs may read them
+	run := func(reverse bool) string {
+		source := &Schema{Tables: map[string]*Table{"users": newTable("users"), "orders": newTable("orders")}}
+		target := &Schema{Tables: map[string]*Table{"users": newTable("users")}}
+		diff := ComputeDiff(source, target, NewFilterConfig())
+		diff.SettingDiffs = []*SettingDiff{{Name: "time_zone", Source: "UTC"}, {Name: "sql_mode", Source: "STRICT_TRANS_TABLES"}}
+		diff.GrantDiffs = []*GrantDiff{
+			{Grantee: "app", Object: "users", Privilege: "UPDATE", Change: ChangeOnlyInSource},
+			{Grantee: "app", Object: "users", Privilege: "SELECT", Change: ChangeOnlyInSource},
+			{Grantee: "admin", Object: "orders", Privilege: "SELECT", Change: ChangeOnlyInTarget},
+		}
+		diff.Orphans = []*OrphanedObject{
+			{Side: "target", Category: CategoryForeignKey, Name: "fk_b", Detail: "missing table b"},
+			{Side: "source", Category: CategoryIndex, Name: "idx_a", Detail: "missing column a"},
+		}
+		diff.NotComparable = []*NotComparable{{Side: "target", Kind: CategoryTable, Name: "payroll"}, {Side: "source", Kind: ObjectRoutines}}
+		diff.SensitiveChanges = []*SensitiveChange{{Table: "users", Column: "ssn"}, {Table: "users", Column: "email"}}
+		diff.DefaultWarnings = []*DefaultWarning{{Table: "users", Column: "created"}, {Table: "orders", Column: "created"}}
+		diff.CollationWarnings = []*CollationWarning{{Collation: "en_US"}, {Collation: "de_DE"}}
+		diff.AppendOnlyWarnings = []*AppendOnlyWarning{{Table: "events", Warning: "a"}, {Table: "audit", Warning: "b"}}
+		if reverse {
+			slices.Reverse(diff.SettingDiffs)
+			slices.Reverse(diff.GrantDiffs)
+			slices.Reverse(diff.Orphans)
+			slices.Reverse(diff.NotComparable)
+			slices.Reverse(diff.SensitiveChanges)
+			slices.Reverse(diff.DefaultWarnings)
+			slices.Reverse(diff.CollationWarnings)
+			slices.Reverse(diff.AppendOnlyWarnings)
+		}
+		if err := SortDiff(diff, SortByName); err != nil {
+			t.Fatal(err)
+		}
+		out, err := json.Marshal(diffJSON(diff))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out)
+	}
+
+	if first, second := run(false), run(true); first != second {
+		t.Errorf("two runs differ:\n%s\n%s", first, second)
+	}
+}
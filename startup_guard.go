@@ -0,0 +1,98 @@
+package dbdiff
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// STARTUP GUARD - Refuse to run against a drifted schema
+// ============================================================================
+
+// VerifyOptions configures VerifyOnStartup
+type VerifyOptions struct {
+	// Driver is the dialect of db: postgres or mysql
+	Driver string
+	// Filter selects what is compared; nil compares everything strictly
+	Filter *FilterConfig
+	// Extract tunes extraction of the live schema
+	Extract ExtractOptions
+	// AllowNonBreaking tolerates drift that can't break the service, e.g.
+	// an extra index or table added by a newer deployment
+	AllowNonBreaking bool
+}
+
+// DriftError is returned by VerifyOnStartup when the database schema differs
+// from the expected snapshot
+type DriftError struct {
+	Expected string  // fingerprint of the snapshot
+	Actual   string  // fingerprint of the database
+	Result   *Result // differences, snapshot as source and database as target
+}
+
+// maxDriftErrorFindings bounds the findings listed in DriftError.Error
+const maxDriftErrorFindings = 5
+
+func (e *DriftError) Error() string {
+	findings := e.Result.Findings()
+	breaking := 0
+	for _, f := range findings {
+		if f.Breaking() {
+			breaking++
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "schema drift: %d difference(s), %d breaking (database %s, expected %s)", len(findings), breaking, e.Actual, e.Expected)
+	for i, f := range findings {
+		if i == maxDriftErrorFindings {
+			fmt.Fprintf(&sb, "; and %d more", len(findings)-i)
+			break
+		}
+		sb.WriteString("; ")
+		sb.WriteString(describeFinding(f))
+	}
+	return sb.String()
+}
+
+// VerifyOnStartup extracts the schema of db and compares it with
+// embeddedSnapshot, a snapshot written by dbdiff snapshot (typically
+// embedded with go:embed). It returns a *DriftError if they differ, so that
+// a service can refuse to boot against a schema it wasn't built for.
+func VerifyOnStartup(ctx context.Context, db *sql.DB, embeddedSnapshot []byte, opts VerifyOptions) error {
+	expected, err := decodeSnapshot(bytes.NewReader(embeddedSnapshot))
+	if err != nil {
+		return fmt.Errorf("reading embedded snapshot: %w", err)
+	}
+	dialect := getDialect(opts.Driver)
+	if dialect == nil {
+		return fmt.Errorf("unsupported driver %q", opts.Driver)
+	}
+	filter := opts.Filter
+	if filter == nil {
+		filter = NewFilterConfig()
+	}
+	extract := opts.Extract
+	if extract.Filter == nil {
+		extract.Filter = filter
+	}
+
+	actual, err := dialect.ExtractSchema(ctx, db, extract)
+	if err != nil {
+		return fmt.Errorf("extracting schema: %w", err)
+	}
+
+	expectedFP, actualFP := Fingerprint(expected, filter), Fingerprint(actual, filter)
+	if expectedFP == actualFP {
+		return nil
+	}
+	// With the snapshot as source, objects the service expects but the
+	// database lacks are the breaking ones
+	result := NewResult(ComputeDiff(expected, actual, filter))
+	if !result.HasChanges() || (opts.AllowNonBreaking && !result.HasBreakingChanges()) {
+		return nil
+	}
+	return &DriftError{Expected: expectedFP, Actual: actualFP, Result: result}
+}
@@ -0,0 +1,54 @@
+package dbdiff
+
+import (
+	"fmt"
+)
+
+// ============================================================================
+// TABLE COUNT GUARD - Catch runs against the wrong database
+// ============================================================================
+
+// defaultMinTableRatio is the smallest share of the other side's tables a
+// schema may have before dbdiff suspects the wrong database
+const defaultMinTableRatio = 0.2
+
+// minGuardedTables is the table count below which only an empty side trips
+// the guard; small schemas legitimately differ a lot
+const minGuardedTables = 10
+
+// CheckTableCounts returns an error when one schema has no tables while the
+// other has some, or fewer than minRatio times the tables of the other. The
+// huge diff that follows usually means a connection points at the wrong
+// database or schema. Tables the filter ignores are not counted; a minRatio
+// of 0 disables the check.
+func CheckTableCounts(source, target *Schema, filter *FilterConfig, minRatio float64) error {
+	if minRatio <= 0 {
+		return nil
+	}
+	count := func(schema *Schema) int {
+		n := 0
+		for name := range schema.Tables {
+			if filter == nil || !filter.ShouldIgnoreTable(name) {
+				n++
+			}
+		}
+		return n
+	}
+	sides := [2]struct {
+		name   string
+		tables int
+	}{{"source", count(source)}, {"target", count(target)}}
+	if sides[0].tables < sides[1].tables {
+		sides[0], sides[1] = sides[1], sides[0]
+	}
+	larger, smaller := sides[0], sides[1]
+	switch {
+	case larger.tables == 0:
+		return nil
+	case smaller.tables == 0:
+		return fmt.Errorf("%s has %d table(s) but %s has none", larger.name, larger.tables, smaller.name)
+	case larger.tables >= minGuardedTables && float64(smaller.tables) < minRatio*float64(larger.tables):
+		return fmt.Errorf("%s has %d table(s) but %s only %d (below --min-table-ratio %g)", larger.name, larger.tables, smaller.name, smaller.tables, minRatio)
+	}
+	return nil
+}
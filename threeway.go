@@ -0,0 +1,333 @@
+package dbdiff
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ============================================================================
+// THREE-WAY DIFF - Independent changes since a common baseline
+// ============================================================================
+
+// ThreeWayConflict is an object the source and the target both changed since
+// the baseline, in different ways
+type ThreeWayConflict struct {
+	Source Finding `json:"source"` // baseline → source
+	Target Finding `json:"target"` // baseline → target
+	// Resolution is the side whose change the merge keeps, or manual; see
+	// ResolveConflicts
+	Resolution string `json:"resolution,omitempty"`
+}
+
+// Resolutions of a three-way conflict
+const (
+	ResolveSource = "source"
+	ResolveTarget = "target"
+	// ResolveManual leaves the conflict to be merged by hand
+	ResolveManual = "manual"
+)
+
+// ThreeWayReport classifies the changes made to a baseline schema by two
+// sides that migrated independently. Findings compare the baseline (source
+// of the finding) with the side that changed it (target of the finding).
+type ThreeWayReport struct {
+	ChangedInSource []Finding          `json:"changed_in_source"`
+	ChangedInTarget []Finding          `json:"changed_in_target"`
+	ChangedInBoth   []Finding          `json:"changed_in_both"` // the same change made on both sides
+	Conflicts       []ThreeWayConflict `json:"conflicts"`
+}
+
+// CompareThreeWay diffs the source and the target against their common
+// baseline and matches the changes by object. An object changed on one side
+// only is that side's change; one changed on both is a conflict unless both
+// ended up with the same definition. Dropping a table conflicts with any
+// change the other side made to it.
+func CompareThreeWay(baseline, source, target *Schema, filter *FilterConfig) *ThreeWayReport {
+	sourceChanges := NewResult(ComputeDiff(baseline, source, filter)).Findings()
+	targetChanges := NewResult(ComputeDiff(baseline, target, filter)).Findings()
+
+	// Differences between the two sides decide whether changes to the same
+	// object agree
+	differs := make(map[string]bool)
+	tableDiffers := make(map[string]bool)
+	for _, f := range NewResult(ComputeDiff(source, target, filter)).Findings() {
+		differs[threeWayKey(f)] = true
+		tableDiffers[f.Table] = true
+	}
+	agree := func(f Finding) bool {
+		if f.Category == CategoryTable && f.Change == ChangeOnlyInTarget {
+			// Added on both sides: every object of the table counts
+			return !tableDiffers[f.Name]
+		}
+		return !differs[threeWayKey(f)]
+	}
+	dropped := func(findings []Finding) map[string]*Finding {
+		tables := make(map[string]*Finding)
+		for i, f := range findings {
+			if f.Category == CategoryTable && f.Change == ChangeOnlyInSource {
+				tables[f.Name] = &findings[i]
+			}
+		}
+		return tables
+	}
+	droppedBySource, droppedByTarget := dropped(sourceChanges), dropped(targetChanges)
+	// Tables with changes to their objects, which conflict with dropping them
+	touched := func(findings []Finding) map[string]bool {
+		tables := make(map[string]bool)
+		for _, f := range findings {
+			if f.Category != CategoryTable {
+				tables[f.Table] = true
+			}
+		}
+		return tables
+	}
+	touchedBySource, touchedByTarget := touched(sourceChanges), touched(targetChanges)
+
+	report := &ThreeWayReport{}
+	targetByKey := make(map[string]Finding)
+	for _, f := range targetChanges {
+		targetByKey[threeWayKey(f)] = f
+	}
+	matched := make(map[string]bool)
+	for _, f := range sourceChanges {
+		other, ok := targetByKey[threeWayKey(f)]
+		switch {
+		case ok:
+			matched[threeWayKey(f)] = true
+			if agree(f) {
+				report.ChangedInBoth = append(report.ChangedInBoth, f)
+			} else {
+				report.Conflicts = append(report.Conflicts, ThreeWayConflict{Source: f, Target: other})
+			}
+		case f.Category != CategoryTable && droppedByTarget[f.Table] != nil:
+			report.Conflicts = append(report.Conflicts, ThreeWayConflict{Source: f, Target: *droppedByTarget[f.Table]})
+		case f.Category == CategoryTable && f.Change == ChangeOnlyInSource && touchedByTarget[f.Name]:
+			// Listed with the changes of the target it conflicts with
+		default:
+			report.ChangedInSource = append(report.ChangedInSource, f)
+		}
+	}
+	for _, f := range targetChanges {
+		switch {
+		case matched[threeWayKey(f)]:
+		case f.Category != CategoryTable && droppedBySource[f.Table] != nil:
+			report.Conflicts = append(report.Conflicts, ThreeWayConflict{Source: *droppedBySource[f.Table], Target: f})
+		case f.Category == CategoryTable && f.Change == ChangeOnlyInSource && touchedBySource[f.Name]:
+		default:
+			report.ChangedInTarget = append(report.ChangedInTarget, f)
+		}
+	}
+	return report
+}
+
+// threeWayKey identifies a change across the two sides. The table-level
+// changes of a table (options, comment, partitioning, ...) share its key, so
+// they are told apart by their attribute; the partition attributes all come
+// from the partitioning of the table.
+func threeWayKey(f Finding) string {
+	if f.Category != CategoryTable || f.Change != ChangeModified {
+		return f.Key()
+	}
+	if len(f.Changes) == 0 {
+		return f.Key()
+	}
+	return f.Key() + ":" + tableChangeField(f.Changes[0].Attribute)
+}
+
+// ResolveConflicts interactively asks which side's change to keep for every
+// unresolved conflict. An empty answer leaves the conflict to be merged by
+// hand, and so do the remaining ones when the input ends.
+func ResolveConflicts(report *ThreeWayReport, in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	for i := range report.Conflicts {
+		c := &report.Conflicts[i]
+		if c.Resolution != "" {
+			continue
+		}
+		fmt.Fprintf(out, "Conflict %d of %d:\n  source: %s\n  target: %s\n", i+1, len(report.Conflicts), describeFinding(c.Source), describeFinding(c.Target))
+		for c.Resolution == "" {
+			fmt.Fprint(out, "  keep [s]ource, [t]arget or [m]anual: ")
+			if !scanner.Scan() {
+				return
+			}
+			switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+			case "s", ResolveSource:
+				c.Resolution = ResolveSource
+			case "t", ResolveTarget:
+				c.Resolution = ResolveTarget
+			case "", "m", ResolveManual:
+				c.Resolution = ResolveManual
+			}
+		}
+	}
+}
+
+// Unresolved returns the conflicts left to be merged by hand
+func (r *ThreeWayReport) Unresolved() []ThreeWayConflict {
+	var manual []ThreeWayConflict
+	for _, c := range r.Conflicts {
+		if c.Resolution != ResolveSource && c.Resolution != ResolveTarget {
+			manual = append(manual, c)
+		}
+	}
+	return manual
+}
+
+// MergeDiff is the diff turning the target into the merge of both sides:
+// the changes made in the source only, and the conflicts resolved in the
+// source's favor. Like a target-to-source diff, its first side is the
+// target; unresolved conflicts are left out.
+func MergeDiff(report *ThreeWayReport, source, target *Schema, filter *FilterConfig) *SchemaDiff {
+	take := make(map[string]bool)
+	// Tables both sides added, differently: all of the source's wins
+	takeTable := make(map[string]bool)
+	for _, f := range report.ChangedInSource {
+		take[threeWayKey(f)] = true
+	}
+	for _, c := range report.Conflicts {
+		if c.Resolution != ResolveSource {
+			continue
+		}
+		take[threeWayKey(c.Source)] = true
+		take[threeWayKey(c.Target)] = true
+		if c.Source.Category == CategoryTable && c.Source.Change == ChangeOnlyInTarget {
+			takeTable[c.Source.Name] = true
+		}
+	}
+	merge := NewResult(ComputeDiff(target, source, filter)).Filter(func(f Finding) bool {
+		return take[threeWayKey(f)] || takeTable[f.Table]
+	}).Diff
+	merge.Direction = DirectionTargetToSource
+	return merge
+}
+
+func printThreeWayReport(w io.Writer, report *ThreeWayReport, baseline string) {
+	fmt.Fprintf(w, "Changes since %s: %d in source only, %d in target only, %d in both, %d conflict(s)\n", baseline,
+		len(report.ChangedInSource), len(report.ChangedInTarget), len(report.ChangedInBoth), len(report.Conflicts))
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+
+	section := func(title, marker string, findings []Finding) {
+		if len(findings) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "\n%s (%d):\n", title, len(findings))
+		for _, f := range findings {
+			fmt.Fprintf(w, "  %s %s\n", marker, describeFinding(f))
+		}
+	}
+	section("Changed in source only", "<", report.ChangedInSource)
+	section("Changed in target only", ">", report.ChangedInTarget)
+	section("Changed the same way in both", "=", report.ChangedInBoth)
+	if len(report.Conflicts) > 0 {
+		fmt.Fprintf(w, "\nConflicts (%d):\n", len(report.Conflicts))
+		for _, c := range report.Conflicts {
+			fmt.Fprintf(w, "  ! source: %s\n", describeFinding(c.Source))
+			fmt.Fprintf(w, "    target: %s\n", describeFinding(c.Target))
+			if c.Resolution != "" {
+				fmt.Fprintf(w, "    resolved: %s\n", c.Resolution)
+			}
+		}
+	}
+}
+
+func runThreeWay(args []string) {
+	fs := flag.NewFlagSet("three-way", flag.ExitOnError)
+	baselineFlag := fs.String("baseline", "", "Schema both sides started from: a connection name from the config file, or a snapshot/DDL file")
+	sourceFlag := fs.String("source", "", "Source side: a connection name from the config file, or a snapshot/DDL file")
+	targetFlag := fs.String("target", "", "Target side: a connection name from the config file, or a snapshot/DDL file")
+	configPath := fs.String("config", "", "Config file with named connections (default: discovered)")
+	format := fs.String("format", FormatPretty, "Output format: pretty or json")
+	parallel := fs.Bool("parallel", false, "Use parallel schema extraction")
+	noUnicode := fs.Bool("no-unicode", false, "Use ASCII instead of symbols in the pretty output")
+	resolve := fs.Bool("resolve", false, "Ask which side's change to keep for each conflict")
+	generateMigration := fs.Bool("migration", false, "Generate the SQL merging the source's changes into the target instead of the report")
+	filterOpts := addFilterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff three-way --baseline base.json --source a --target b [--config dbdiff.yaml] [--format pretty|json] [--resolve] [--migration] [filter options]")
+		fmt.Fprintln(os.Stderr, "\nClassifies each change since the baseline as made in the source only, in the target only,")
+		fmt.Fprintln(os.Stderr, "in both the same way, or as a conflict. --resolve asks which side wins each conflict, and")
+		fmt.Fprintln(os.Stderr, "--migration writes the SQL bringing the source's changes and winning conflicts to the target.")
+		fmt.Fprintln(os.Stderr, "Exits with 2 when conflicts are left unresolved.")
+	}
+	fs.Parse(args)
+
+	if *baselineFlag == "" || *sourceFlag == "" || *targetFlag == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != FormatPretty && *format != FormatJSON {
+		fmt.Fprintf(os.Stderr, "Invalid format: %s (expected pretty or json)\n", *format)
+		os.Exit(1)
+	}
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	filter, err := filterOpts.build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts := filterOpts.extractOptions(filter)
+	if *parallel {
+		opts.Concurrency = parallelExtractConcurrency
+	}
+
+	names := []string{*baselineFlag, *sourceFlag, *targetFlag}
+	schemas := make([]*Schema, len(names))
+	drivers := make([]string, len(names))
+	for i, name := range names {
+		driver, conn, err := resolveSchemaSource(name, cfg)
+		if err == nil {
+			schemas[i], err = loadSchema(driver, conn, opts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		drivers[i] = driver
+	}
+	// The merge runs on the target
+	driver := migrationDriver(drivers[2], drivers[1])
+	if *generateMigration {
+		if err := migrationDialectError(driver); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	report := CompareThreeWay(schemas[0], schemas[1], schemas[2], filter)
+	if *resolve {
+		ResolveConflicts(report, os.Stdin, os.Stderr)
+	}
+	manual := report.Unresolved()
+	switch {
+	case *generateMigration:
+		merge := MergeDiff(report, schemas[1], schemas[2], filter)
+		out := consoleOutput(os.Stdout, *noUnicode)
+		if len(manual) > 0 {
+			fmt.Fprintln(out, "-- Conflicts to merge by hand:")
+			for _, c := range manual {
+				fmt.Fprintf(out, "--   source: %s\n--   target: %s\n", describeFinding(c.Source), describeFinding(c.Target))
+			}
+			fmt.Fprintln(out)
+		}
+		fmt.Fprint(out, GenerateMigrationSQL(merge, driver, MigrationOptions{}))
+	case *format == FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	default:
+		printThreeWayReport(consoleOutput(os.Stdout, *noUnicode), report, *baselineFlag)
+	}
+
+	if len(manual) > 0 {
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,141 @@
+package dbdiff
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// threeWaySchema builds tables from "table: column type, column type" specs
+func threeWaySchema(specs ...string) *Schema {
+	schema := &Schema{Tables: make(map[string]*Table)}
+	for _, spec := range specs {
+		name, columns, _ := strings.Cut(spec, ": ")
+		table := newTable(name)
+		for _, column := range strings.Split(columns, ", ") {
+			colName, dataType, _ := strings.Cut(column, " ")
+			table.Columns[colName] = &Column{Name: colName, DataType: dataType, IsNullable: true}
+		}
+		schema.Tables[name] = table
+	}
+	return schema
+}
+
+func describeThreeWay(findings []Finding) []string {
+	var described []string
+	for _, f := range findings {
+		described = append(described, f.Key()+" "+f.Change)
+	}
+	sort.Strings(described)
+	return described
+}
+
+func TestCompareThreeWayClassifiesChanges(t *testing.T) {
+	baseline := threeWaySchema("users: id integer, email varchar(100)", "legacy: id integer, note text")
+	source := threeWaySchema("users: id integer, email text, phone text", "legacy: id integer, note varchar(50)", "audit: id integer")
+	target := threeWaySchema("users: id integer, email varchar(200), age integer", "audit: id integer")
+
+	report := CompareThreeWay(baseline, source, target, NewFilterConfig())
+
+	for _, tc := range []struct {
+		group    string
+		findings []Finding
+		want     []string
+	}{
+		{"source only", report.ChangedInSource, []string{"column:users.phone only_in_target"}},
+		{"target only", report.ChangedInTarget, []string{"column:users.age only_in_target"}},
+		{"both", report.ChangedInBoth, []string{"table:audit.audit only_in_target"}},
+	} {
+		if got := describeThreeWay(tc.findings); strings.Join(got, "; ") != strings.Join(tc.want, "; ") {
+			t.Errorf("changed in %s = %v, want %v", tc.group, got, tc.want)
+		}
+	}
+
+	// email changed differently on both sides; legacy.note changed in the
+	// source but the table was dropped in the target
+	var conflicts []string
+	for _, c := range report.Conflicts {
+		conflicts = append(conflicts, c.Source.Key()+" / "+c.Target.Key())
+	}
+	sort.Strings(conflicts)
+	if want := "column:legacy.note / table:legacy.legacy; column:users.email / column:users.email"; strings.Join(conflicts, "; ") != want {
+		t.Errorf("conflicts = %v, want %s", conflicts, want)
+	}
+}
+
+func TestCompareThreeWayAgreesOnTheSameChange(t *testing.T) {
+	baseline := threeWaySchema("users: id integer, email varchar(100)")
+	both := threeWaySchema("users: id integer, email text")
+
+	report := CompareThreeWay(baseline, both, both, NewFilterConfig())
+	if len(report.Conflicts) != 0 || len(report.ChangedInSource) != 0 || len(report.ChangedInTarget) != 0 || len(report.ChangedInBoth) != 1 {
+		t.Errorf("report = %+v, want the email change in both", report)
+	}
+
+	var out bytes.Buffer
+	printThreeWayReport(&out, report, "release-12.json")
+	if !strings.Contains(out.String(), "Changes since release-12.json: 0 in source only, 0 in target only, 1 in both, 0 conflict(s)") {
+		t.Errorf("report:\n%s", out.String())
+	}
+}
+
+func TestCompareThreeWayTellsTableLevelChangesApart(t *testing.T) {
+	table := func(options map[string]string, comment string) *Schema {
+		return &Schema{Tables: map[string]*Table{"t": {
+			Name:    "t",
+			Columns: map[string]*Column{"id": {Name: "id", DataType: "integer", IsNullable: true}},
+			Options: options,
+			Comment: comment,
+		}}}
+	}
+	baseline := table(map[string]string{"fillfactor": "100"}, "")
+	source := table(map[string]string{"fillfactor": "70"}, "")
+	target := table(map[string]string{"fillfactor": "70"}, "orders")
+
+	report := CompareThreeWay(baseline, source, target, &FilterConfig{})
+	if len(report.Conflicts) != 0 {
+		t.Errorf("conflicts = %+v, want none", report.Conflicts)
+	}
+	if len(report.ChangedInBoth) != 1 || !strings.HasPrefix(report.ChangedInBoth[0].Detail, "options:") {
+		t.Errorf("changed in both = %+v, want the options change", report.ChangedInBoth)
+	}
+	if len(report.ChangedInTarget) != 1 || !strings.HasPrefix(report.ChangedInTarget[0].Detail, "comment:") {
+		t.Errorf("changed in target = %+v, want the comment change", report.ChangedInTarget)
+	}
+}
+
+func TestMergeDiffKeepsResolvedSide(t *testing.T) {
+	schema := func(types map[string]string) *Schema {
+		table := &Table{Name: "users", Columns: map[string]*Column{}}
+		for name, dataType := range types {
+			table.Columns[name] = &Column{Name: name, DataType: dataType, IsNullable: true}
+		}
+		return &Schema{Tables: map[string]*Table{"users": table}}
+	}
+	baseline := schema(map[string]string{"email": "varchar(100)", "name": "text", "age": "integer"})
+	source := schema(map[string]string{"email": "text", "name": "varchar(50)", "age": "bigint"})
+	target := schema(map[string]string{"email": "varchar(200)", "name": "varchar(60)", "age": "integer"})
+	filter := &FilterConfig{}
+
+	report := CompareThreeWay(baseline, source, target, filter)
+	if len(report.Conflicts) != 2 {
+		t.Fatalf("conflicts = %+v, want email and name", report.Conflicts)
+	}
+	ResolveConflicts(report, strings.NewReader("s\nm\n"), io.Discard)
+	if got := report.Unresolved(); len(got) != 1 {
+		t.Errorf("unresolved = %+v, want one", got)
+	}
+
+	merge := MergeDiff(report, source, target, filter)
+	changed := make(map[string]bool)
+	for _, f := range NewResult(merge).Findings() {
+		changed[f.Name] = true
+	}
+	// age changed in the source only, email was resolved to the source and
+	// name is left to be merged by hand
+	if !changed["age"] || !changed[report.Conflicts[0].Source.Name] || changed[report.Conflicts[1].Source.Name] || len(changed) != 2 {
+		t.Errorf("merge changes %v, want age and %s", changed, report.Conflicts[0].Source.Name)
+	}
+}
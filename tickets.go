@@ -0,0 +1,289 @@
+package dbdiff
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// TICKETS - Drift tickets in Jira or ServiceNow
+// ============================================================================
+
+// Ticket systems accepted by --create-ticket
+const (
+	TicketJira       = "jira"
+	TicketServiceNow = "servicenow"
+)
+
+// TicketConfig is the tickets section of the config file
+type TicketConfig struct {
+	Jira       *JiraConfig       `yaml:"jira" json:"jira"`
+	ServiceNow *ServiceNowConfig `yaml:"servicenow" json:"servicenow"`
+}
+
+// JiraConfig selects the Jira project drift tickets are filed in. With User
+// set, Token is an API token used with basic auth; without it, a personal
+// access token sent as a bearer token.
+type JiraConfig struct {
+	URL       string   `yaml:"url" json:"url"`
+	Project   string   `yaml:"project" json:"project"`
+	IssueType string   `yaml:"issue_type" json:"issue_type"`
+	User      string   `yaml:"user" json:"user"`
+	Token     string   `yaml:"token" json:"token"`
+	Labels    []string `yaml:"labels" json:"labels"`
+}
+
+// ServiceNowConfig selects the ServiceNow table (incident by default) drift
+// tickets are filed in
+type ServiceNowConfig struct {
+	URL             string `yaml:"url" json:"url"`
+	Table           string `yaml:"table" json:"table"`
+	User            string `yaml:"user" json:"user"`
+	Password        string `yaml:"password" json:"password"`
+	AssignmentGroup string `yaml:"assignment_group" json:"assignment_group"`
+}
+
+// DriftTicket is the content of a drift ticket. Fingerprint identifies the
+// drift: while it stays the same, the open ticket carrying it is updated
+// instead of a new one being opened.
+type DriftTicket struct {
+	Fingerprint string
+	Summary     string
+	Description string
+}
+
+// ticketSystem finds, opens and updates drift tickets
+type ticketSystem interface {
+	// find returns the id of the open ticket for fingerprint, or ""
+	find(ctx context.Context, fingerprint string) (string, error)
+	create(ctx context.Context, t *DriftTicket) (string, error)
+	update(ctx context.Context, id string, t *DriftTicket) error
+}
+
+// newTicketSystem returns the configured ticket system named by --create-ticket
+func newTicketSystem(name string, cfg TicketConfig) (ticketSystem, error) {
+	switch name {
+	case TicketJira:
+		if cfg.Jira == nil || cfg.Jira.URL == "" || cfg.Jira.Project == "" {
+			return nil, fmt.Errorf("--create-ticket jira needs tickets.jira.url and tickets.jira.project in the config file")
+		}
+		return cfg.Jira, nil
+	case TicketServiceNow:
+		if cfg.ServiceNow == nil || cfg.ServiceNow.URL == "" {
+			return nil, fmt.Errorf("--create-ticket servicenow needs tickets.servicenow.url in the config file")
+		}
+		return cfg.ServiceNow, nil
+	}
+	return nil, fmt.Errorf("unknown ticket system %q (expected jira or servicenow)", name)
+}
+
+// DriftFingerprint returns "sha256:" followed by the hex SHA-256 of subject
+// and the findings of diff, so the same drift on the same database always
+// yields the same fingerprint
+func DriftFingerprint(subject string, diff *SchemaDiff) string {
+	lines := []string{subject}
+	for _, f := range NewResult(diff).Findings() {
+		lines = append(lines, describeFinding(f))
+	}
+	sort.Strings(lines[1:])
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// driftLabel is the ticket label (Jira) or correlation id (ServiceNow)
+// carrying a drift fingerprint
+func driftLabel(fingerprint string) string {
+	return "dbdiff-" + strings.TrimPrefix(fingerprint, "sha256:")[:16]
+}
+
+// newDriftTicket describes the drift of subject with the pretty report
+func newDriftTicket(subject string, diff *SchemaDiff) *DriftTicket {
+	var report bytes.Buffer
+	printPretty(&report, diff)
+	fingerprint := DriftFingerprint(subject, diff)
+	return &DriftTicket{
+		Fingerprint: fingerprint,
+		Summary:     fmt.Sprintf("Schema drift: %s (%d difference(s))", subject, len(NewResult(diff).Findings())),
+		Description: fmt.Sprintf("dbdiff found schema drift on %s.\n\nDrift fingerprint: %s\nLast seen: %s\n\n%s",
+			subject, fingerprint, time.Now().UTC().Format(time.RFC3339), report.String()),
+	}
+}
+
+// FileDriftTicket opens a ticket for the drift of subject, or updates the open
+// ticket already filed for the same drift. It returns the ticket id and
+// whether it was newly opened.
+func FileDriftTicket(system ticketSystem, subject string, diff *SchemaDiff) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+
+	ticket := newDriftTicket(subject, diff)
+	id, err := system.find(ctx, ticket.Fingerprint)
+	if err != nil {
+		return "", false, err
+	}
+	if id != "" {
+		return id, false, system.update(ctx, id, ticket)
+	}
+	id, err = system.create(ctx, ticket)
+	return id, true, err
+}
+
+// ticketRequest sends body as JSON and decodes the JSON response into out.
+// Without a user, secret is sent as a bearer token.
+func ticketRequest(ctx context.Context, method, endpoint, user, secret string, body, out any) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req.SetBasicAuth(user, secret)
+	} else if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s %s", method, req.URL.Redacted(), resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (j *JiraConfig) endpoint(path string) string {
+	return strings.TrimSuffix(j.URL, "/") + "/rest/api/2/" + path
+}
+
+func (j *JiraConfig) find(ctx context.Context, fingerprint string) (string, error) {
+	jql := fmt.Sprintf(`project = "%s" AND labels = "%s" AND statusCategory != Done ORDER BY created DESC`, j.Project, driftLabel(fingerprint))
+	var result struct {
+		Issues []struct {
+			Key string `json:"key"`
+		} `json:"issues"`
+	}
+	query := url.Values{"jql": {jql}, "fields": {"key"}, "maxResults": {"1"}}
+	if err := ticketRequest(ctx, http.MethodGet, j.endpoint("search?"+query.Encode()), j.User, j.Token, nil, &result); err != nil {
+		return "", err
+	}
+	if len(result.Issues) == 0 {
+		return "", nil
+	}
+	return result.Issues[0].Key, nil
+}
+
+func (j *JiraConfig) create(ctx context.Context, t *DriftTicket) (string, error) {
+	issueType := j.IssueType
+	if issueType == "" {
+		issueType = "Task"
+	}
+	fields := map[string]any{
+		"project":     map[string]string{"key": j.Project},
+		"issuetype":   map[string]string{"name": issueType},
+		"summary":     t.Summary,
+		"description": "{noformat}\n" + t.Description + "\n{noformat}",
+		"labels":      append([]string{"dbdiff", driftLabel(t.Fingerprint)}, j.Labels...),
+	}
+	var created struct {
+		Key string `json:"key"`
+	}
+	err := ticketRequest(ctx, http.MethodPost, j.endpoint("issue"), j.User, j.Token, map[string]any{"fields": fields}, &created)
+	return created.Key, err
+}
+
+func (j *JiraConfig) update(ctx context.Context, id string, t *DriftTicket) error {
+	fields := map[string]any{"description": "{noformat}\n" + t.Description + "\n{noformat}"}
+	return ticketRequest(ctx, http.MethodPut, j.endpoint("issue/"+url.PathEscape(id)), j.User, j.Token, map[string]any{"fields": fields}, nil)
+}
+
+func (s *ServiceNowConfig) endpoint(path string) string {
+	table := s.Table
+	if table == "" {
+		table = "incident"
+	}
+	return strings.TrimSuffix(s.URL, "/") + "/api/now/table/" + table + path
+}
+
+func (s *ServiceNowConfig) find(ctx context.Context, fingerprint string) (string, error) {
+	query := url.Values{
+		"sysparm_query":  {"active=true^correlation_id=" + driftLabel(fingerprint)},
+		"sysparm_fields": {"sys_id"},
+		"sysparm_limit":  {"1"},
+	}
+	var result struct {
+		Result []struct {
+			SysID string `json:"sys_id"`
+		} `json:"result"`
+	}
+	if err := ticketRequest(ctx, http.MethodGet, s.endpoint("?"+query.Encode()), s.User, s.Password, nil, &result); err != nil {
+		return "", err
+	}
+	if len(result.Result) == 0 {
+		return "", nil
+	}
+	return result.Result[0].SysID, nil
+}
+
+func (s *ServiceNowConfig) create(ctx context.Context, t *DriftTicket) (string, error) {
+	record := map[string]string{
+		"short_description":   t.Summary,
+		"description":         t.Description,
+		"correlation_id":      driftLabel(t.Fingerprint),
+		"correlation_display": "dbdiff",
+	}
+	if s.AssignmentGroup != "" {
+		record["assignment_group"] = s.AssignmentGroup
+	}
+	var created struct {
+		Result struct {
+			SysID string `json:"sys_id"`
+		} `json:"result"`
+	}
+	err := ticketRequest(ctx, http.MethodPost, s.endpoint(""), s.User, s.Password, record, &created)
+	return created.Result.SysID, err
+}
+
+func (s *ServiceNowConfig) update(ctx context.Context, id string, t *DriftTicket) error {
+	record := map[string]string{"description": t.Description}
+	return ticketRequest(ctx, http.MethodPatch, s.endpoint("/"+url.PathEscape(id)), s.User, s.Password, record, nil)
+}
+
+// reportTicket files the drift ticket for subject and logs the outcome to
+// stderr; failures are reported but don't change the exit code
+func reportTicket(system ticketSystem, name, subject string, diff *SchemaDiff) {
+	id, created, err := FileDriftTicket(system, subject, diff)
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error filing %s ticket for %s: %v\n", name, subject, err)
+	case created:
+		fmt.Fprintf(os.Stderr, "Opened %s ticket %s for drift on %s\n", name, id, subject)
+	default:
+		fmt.Fprintf(os.Stderr, "Updated %s ticket %s for drift on %s\n", name, id, subject)
+	}
+}
@@ -0,0 +1,129 @@
+package dbdiff
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeJira is a Jira server keeping issues in memory, searchable by label
+type fakeJira struct {
+	mu      sync.Mutex
+	issues  map[string][]string // key -> labels
+	updates int
+}
+
+func (f *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer pat" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/search":
+		var found []map[string]string
+		for key, labels := range f.issues {
+			for _, label := range labels {
+				if strings.Contains(r.URL.Query().Get("jql"), `labels = "`+label+`"`) {
+					found = append(found, map[string]string{"key": key})
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"issues": found})
+	case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue":
+		var body struct {
+			Fields struct {
+				Labels []string `json:"labels"`
+			} `json:"fields"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		key := "OPS-" + string(rune('1'+len(f.issues)))
+		f.issues[key] = body.Fields.Labels
+		json.NewEncoder(w).Encode(map[string]string{"key": key})
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/rest/api/2/issue/"):
+		if f.issues[strings.TrimPrefix(r.URL.Path, "/rest/api/2/issue/")] == nil {
+			http.NotFound(w, r)
+			return
+		}
+		f.updates++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+	}
+}
+
+func TestFileDriftTicketUpdatesTheTicketOfTheSameDrift(t *testing.T) {
+	jira := &fakeJira{issues: make(map[string][]string)}
+	server := httptest.NewServer(jira)
+	defer server.Close()
+	system, err := newTicketSystem(TicketJira, TicketConfig{Jira: &JiraConfig{URL: server.URL, Project: "OPS", Token: "pat"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drift := &SchemaDiff{TablesOnlyInSource: []string{"audit"}}
+	id, created, err := FileDriftTicket(system, "prod vs staging", drift)
+	if err != nil || !created || id != "OPS-1" {
+		t.Fatalf("first ticket = %q, created %v, %v; want OPS-1 opened", id, created, err)
+	}
+	id, created, err = FileDriftTicket(system, "prod vs staging", drift)
+	if err != nil || created || id != "OPS-1" || jira.updates != 1 {
+		t.Fatalf("same drift = %q, created %v, %v; want OPS-1 updated", id, created, err)
+	}
+
+	// Other drift, or the same drift elsewhere, gets its own ticket
+	other := &SchemaDiff{TablesOnlyInSource: []string{"audit", "events"}}
+	if id, created, err := FileDriftTicket(system, "prod vs staging", other); err != nil || !created || id != "OPS-2" {
+		t.Errorf("other drift = %q, created %v, %v; want OPS-2 opened", id, created, err)
+	}
+	if id, created, err := FileDriftTicket(system, "prod vs qa", drift); err != nil || !created || id != "OPS-3" {
+		t.Errorf("other database = %q, created %v, %v; want OPS-3 opened", id, created, err)
+	}
+}
+
+func TestServiceNowTicketsCarryDriftLabel(t *testing.T) {
+	var created map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "svc" || password != "pw" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]any{"result": []any{}})
+		case http.MethodPost:
+			json.NewDecoder(r.Body).Decode(&created)
+			json.NewEncoder(w).Encode(map[string]any{"result": map[string]string{"sys_id": "abc123"}})
+		}
+	}))
+	defer server.Close()
+	system, err := newTicketSystem(TicketServiceNow, TicketConfig{ServiceNow: &ServiceNowConfig{URL: server.URL, User: "svc", Password: "pw", AssignmentGroup: "dba"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drift := &SchemaDiff{TablesOnlyInTarget: []string{"audit"}}
+	id, isNew, err := FileDriftTicket(system, "prod vs staging", drift)
+	if err != nil || !isNew || id != "abc123" {
+		t.Fatalf("ticket = %q, created %v, %v; want abc123 opened", id, isNew, err)
+	}
+	if want := driftLabel(DriftFingerprint("prod vs staging", drift)); created["correlation_id"] != want || created["assignment_group"] != "dba" {
+		t.Errorf("created record = %v, want correlation_id %s and the assignment group", created, want)
+	}
+}
+
+func TestNewTicketSystemNeedsConfig(t *testing.T) {
+	for name, cfg := range map[string]TicketConfig{
+		TicketJira:       {Jira: &JiraConfig{URL: "https://jira.example.com"}},
+		TicketServiceNow: {},
+		"github":         {},
+	} {
+		if _, err := newTicketSystem(name, cfg); err == nil {
+			t.Errorf("newTicketSystem(%q) without its settings succeeded", name)
+		}
+	}
+}
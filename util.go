@@ -0,0 +1,466 @@
+package dbdiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ============================================================================
+// UTILITY FUNCTIONS
+// ============================================================================
+
+// splitList splits a comma-separated flag value into trimmed, non-empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseInterspersed parses args with fs, also accepting flags after
+// positional arguments, and returns the positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// readTextFile reads a file written by an editor or shell redirection; see
+// decodeText
+func readTextFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeText(data), nil
+}
+
+// decodeText strips a UTF-8 byte order mark and converts UTF-16 text (what
+// Windows PowerShell 5 writes for `dbdiff ... > file`) to UTF-8
+func decodeText(data []byte) []byte {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return data[3:]
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		order = binary.BigEndian
+	default:
+		return data
+	}
+	units := make([]uint16, (len(data)-2)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2+2*i:])
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// asciiReplacer spells out the symbols of console output for terminals that
+// can't render them
+var asciiReplacer = strings.NewReplacer(
+	"→", "->",
+	"✓", "OK",
+	"✗", "X",
+	"↷", ">>",
+	"↻", "~",
+	"…", "...",
+	"•", "-",
+	"📋 ", "",
+	"📊 ", "",
+	"🚦 ", "",
+	"📝 ", "",
+	"🏷️  ", "",
+	"🔢 ", "",
+	"📦 ", "",
+	"🧩 ", "",
+	"⏰ ", "",
+	"⏳ ", "",
+	"🔌 ", "",
+	"🌐 ", "",
+	"🧟 ", "",
+	"🔒 ", "",
+	"⚠️  ", "",
+	"⚙️  ", "",
+	"🔑 ", "",
+	"⛔ ", "",
+)
+
+// asciiWriter transliterates everything written through it with asciiReplacer
+type asciiWriter struct {
+	w io.Writer
+}
+
+func (a asciiWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(a.w, asciiReplacer.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// consoleOutput wraps w (stdout or stderr) for human-readable output,
+// restricting it to ASCII when noUnicode is set or the console can't display
+// Unicode
+func consoleOutput(w io.Writer, noUnicode bool) io.Writer {
+	if noUnicode || !consoleSupportsUnicode() {
+		return asciiWriter{w: w}
+	}
+	return w
+}
+
+// consoleSupportsUnicode reports false for classic Windows consoles (cmd.exe,
+// PowerShell in conhost), whose legacy code pages garble symbols and emoji.
+// Windows Terminal, ConEmu and terminals that set TERM render them fine.
+func consoleSupportsUnicode() bool {
+	if runtime.GOOS != "windows" {
+		return true
+	}
+	return os.Getenv("WT_SESSION") != "" || os.Getenv("ConEmuANSI") == "ON" ||
+		os.Getenv("TERM_PROGRAM") != "" || os.Getenv("TERM") != ""
+}
+
+func getSortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func makeSet(items []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+func equalStringSlices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// typeAliases maps common spellings of a type to one canonical name
+var typeAliases = map[string]string{
+	"int":                         "integer",
+	"int4":                        "integer",
+	"int8":                        "bigint",
+	"int2":                        "smallint",
+	"bool":                        "boolean",
+	"character varying":           "varchar",
+	"character":                   "char",
+	"float8":                      "double precision",
+	"double":                      "double precision",
+	"float4":                      "real",
+	"decimal":                     "numeric",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+	"time without time zone":      "time",
+	"time with time zone":         "timetz",
+	"varchar2":                    "varchar",
+	"nvarchar2":                   "nvarchar",
+}
+
+// defaultTypeEquivalences are the types that hold the same data on different
+// engines. They apply when comparing across engines (see
+// --type-equivalence); the config file can add more classes.
+var defaultTypeEquivalences = [][]string{
+	{"json", "jsonb"},
+	{"text", "longtext", "clob", "nvarchar(max)", "blob sub_type text"},
+	{"bytea", "blob", "longblob", "varbinary(max)"},
+}
+
+// typeEquivalence indexes equivalence classes by normalized type; each type
+// maps to the first type of its class. A class naming a type that is already
+// classified extends that type's class, so the config file can add e.g.
+// mediumtext to the text class with [text, mediumtext].
+func typeEquivalence(classes ...[][]string) map[string]string {
+	classOf := make(map[string]string)
+	for _, group := range classes {
+		for _, class := range group {
+			if len(class) == 0 {
+				continue
+			}
+			rep := normalizeType(class[0])
+			for _, t := range class {
+				if existing, ok := classOf[normalizeType(t)]; ok {
+					rep = existing
+					break
+				}
+			}
+			for _, t := range class {
+				classOf[normalizeType(t)] = rep
+			}
+		}
+	}
+	return classOf
+}
+
+// engineFamily groups drivers that read the same engine's catalog
+func engineFamily(driver string) string {
+	switch driver {
+	case "yugabyte":
+		return "postgres"
+	case "tidb":
+		return "mysql"
+	case "mssql":
+		return "sqlserver"
+	}
+	return driver
+}
+
+var (
+	charLengthPattern   = regexp.MustCompile(`^(n?varchar2?|n?char)\((\d+)(?: (byte|char))?\)$`)
+	whitespacePattern   = regexp.MustCompile(`\s+`)
+	intDisplayWidth     = regexp.MustCompile(`^((?:tiny|small|medium|big)?int)\(\d+\)`)
+	defaultCastPattern  = regexp.MustCompile(`::[a-z_ ]+(\(\d+(,\s*\d+)?\))?(\[\])?`)
+	defaultQuotePattern = regexp.MustCompile(`^'(.*)'$`)
+	// N'text' on SQL Server, _utf8mb4'text' for MySQL expression defaults
+	defaultIntroducerPattern = regexp.MustCompile(`^(n|_[a-z0-9]+)'`)
+	// now(6), current_timestamp(6) and the like keep their precision
+	defaultPrecisionPattern = regexp.MustCompile(`^([a-z_]+)\((\d+)\)$`)
+	// nextval('seq'::regclass), also in the ('seq'::text)::regclass form of
+	// servers before Postgres 8.1
+	nextvalPattern = regexp.MustCompile(`(?i)^nextval\(\s*\(?\s*'((?:[^']|'')+)'(?:\s*::\s*text\s*\))?(?:\s*::\s*regclass)?\s*\)$`)
+)
+
+// normalizeType lowercases a type, collapses whitespace, drops MySQL integer
+// display widths and resolves aliases so equivalent spellings compare equal
+func normalizeType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(whitespacePattern.ReplaceAllString(t, " ")))
+	if t != "tinyint(1)" {
+		t = intDisplayWidth.ReplaceAllString(t, "$1")
+	}
+	// Prefer the longest alias so "character varying" wins over "character"
+	best := ""
+	for alias := range typeAliases {
+		if len(alias) > len(best) && (t == alias || strings.HasPrefix(t, alias+"(") || strings.HasPrefix(t, alias+" ") || strings.HasPrefix(t, alias+"[")) {
+			best = alias
+		}
+	}
+	if best != "" {
+		t = typeAliases[best] + t[len(best):]
+	}
+	// Oracle's explicit CHAR length semantics is what a bare length means
+	// everywhere else
+	return strings.Replace(t, " char)", ")", 1)
+}
+
+// utf8mb4IndexLength is the longest utf8mb4 varchar whose index fits the
+// 767 byte key prefix limit of older MySQL row formats
+const utf8mb4IndexLength = 191
+
+// charLength parses a character type into its family (varchar or char, with
+// the n prefix of national types), its length and whether that length counts
+// bytes (Oracle BYTE semantics) rather than characters
+func charLength(t string) (family string, length int, bytes bool, ok bool) {
+	m := charLengthPattern.FindStringSubmatch(normalizeType(t))
+	if m == nil {
+		return "", 0, false, false
+	}
+	length, _ = strconv.Atoi(m[2])
+	return strings.TrimSuffix(m[1], "2"), length, m[3] == "byte", true
+}
+
+// lengthSemantics explains a length difference between two character types
+// as an encoding artifact or a real capacity change. An artifact is a byte
+// length that holds exactly the other side's characters at four bytes each,
+// as with an Oracle VARCHAR2(400 BYTE) column mirroring a varchar(100);
+// --normalize-types treats it as equal. MySQL's utf8mb4 varchar(191)
+// convention is pointed out but still a capacity change.
+func lengthSemantics(source, target string) (artifact bool, note string) {
+	sf, sn, sb, sok := charLength(source)
+	tf, tn, tb, tok := charLength(target)
+	if !sok || !tok || sf != tf {
+		return false, ""
+	}
+	unit := func(bytes bool) string {
+		if bytes {
+			return "bytes"
+		}
+		return "characters"
+	}
+	switch {
+	case sb == tb && sn == tn:
+		return false, ""
+	case sb == tb && !sb && (sn == utf8mb4IndexLength || tn == utf8mb4IndexLength):
+		return false, fmt.Sprintf("capacity change: %d → %d characters; varchar(%d) is the utf8mb4 index length convention", sn, tn, utf8mb4IndexLength)
+	case sb == tb:
+		return false, fmt.Sprintf("capacity change: %d → %d %s", sn, tn, unit(sb))
+	case sb && sn == 4*tn, tb && tn == 4*sn:
+		return true, fmt.Sprintf("encoding artifact: %d %s → %d %s, the same capacity in 4-byte characters", sn, unit(sb), tn, unit(tb))
+	default:
+		return false, fmt.Sprintf("capacity change: %d %s → %d %s", sn, unit(sb), tn, unit(tb))
+	}
+}
+
+// defaultSynonyms maps the spellings engines use for the same default
+// function to one name
+var defaultSynonyms = map[string]string{
+	// PostgreSQL
+	"now":                   "current_timestamp",
+	"transaction_timestamp": "current_timestamp",
+	"localtimestamp":        "current_timestamp",
+	// MySQL
+	"current_timestamp": "current_timestamp",
+	"localtime":         "current_timestamp",
+	"curdate":           "current_date",
+	"current_date":      "current_date",
+	"curtime":           "current_time",
+	"current_time":      "current_time",
+	"uuid":              "uuid",
+	// SQL Server
+	"getdate":       "current_timestamp",
+	"sysdatetime":   "current_timestamp",
+	"newid":         "uuid",
+	"getutcdate":    "utc_timestamp",
+	"utc_timestamp": "utc_timestamp",
+	// Oracle
+	"sysdate":      "current_timestamp",
+	"systimestamp": "current_timestamp",
+	"sys_guid":     "uuid",
+	// PostgreSQL extensions
+	"gen_random_uuid":  "uuid",
+	"uuid_generate_v4": "uuid",
+}
+
+// normalizeDefault reduces a default expression to a comparable form by
+// stripping casts, wrapping parentheses, quotes and string introducers, and
+// by spelling each engine's current time and UUID functions one way. An
+// explicit NULL default is no default.
+func normalizeDefault(d string) string {
+	d = strings.ToLower(strings.TrimSpace(d))
+	d = defaultCastPattern.ReplaceAllString(d, "")
+	for strings.HasPrefix(d, "(") && strings.HasSuffix(d, ")") {
+		d = strings.TrimSpace(d[1 : len(d)-1])
+	}
+	d = strings.ReplaceAll(d, `\'`, "'")
+	d = defaultIntroducerPattern.ReplaceAllString(d, "'")
+	d = defaultQuotePattern.ReplaceAllString(d, "$1")
+	if d == "null" {
+		return ""
+	}
+	name, precision := strings.TrimSuffix(d, "()"), ""
+	if m := defaultPrecisionPattern.FindStringSubmatch(d); m != nil {
+		name, precision = m[1], "("+m[2]+")"
+	}
+	if synonym, ok := defaultSynonyms[name]; ok {
+		d = synonym + precision
+	}
+	return d
+}
+
+// removeNames returns items without any of the given names
+func removeNames(items []string, names ...string) []string {
+	drop := makeSet(names)
+	var kept []string
+	for _, item := range items {
+		if !drop[item] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func isTableDiffEmpty(diff *TableDiff) bool {
+	return len(diff.ColumnsOnlyInSource) == 0 &&
+		len(diff.ColumnsOnlyInTarget) == 0 &&
+		len(diff.ColumnDiffs) == 0 &&
+		diff.PrimaryKeyDiff == nil &&
+		len(diff.ForeignKeysOnlyInSource) == 0 &&
+		len(diff.ForeignKeysOnlyInTarget) == 0 &&
+		len(diff.ForeignKeyDiffs) == 0 &&
+		len(diff.UniquesOnlyInSource) == 0 &&
+		len(diff.UniquesOnlyInTarget) == 0 &&
+		len(diff.UniqueDiffs) == 0 &&
+		len(diff.IndexesOnlyInSource) == 0 &&
+		len(diff.IndexesOnlyInTarget) == 0 &&
+		len(diff.IndexDiffs) == 0 &&
+		len(diff.ChecksOnlyInSource) == 0 &&
+		len(diff.ChecksOnlyInTarget) == 0 &&
+		len(diff.CheckDiffs) == 0 &&
+		len(diff.TriggersOnlyInSource) == 0 &&
+		len(diff.TriggersOnlyInTarget) == 0 &&
+		len(diff.TriggerDiffs) == 0 &&
+		diff.CommentDiff == nil &&
+		diff.AccessMethodDiff == nil &&
+		diff.ReplicaIdentityDiff == nil &&
+		diff.OptionsDiff == nil &&
+		diff.PartitioningDiff == nil &&
+		diff.InheritanceDiff == nil &&
+		diff.ForeignTableDiff == nil &&
+		len(diff.RewriteRulesOnlyInSource) == 0 &&
+		len(diff.RewriteRulesOnlyInTarget) == 0 &&
+		len(diff.RewriteRuleDiffs) == 0
+}
+
+// isCommentOnlyTableDiff reports whether every difference of a table is a
+// table or column comment change
+func isCommentOnlyTableDiff(diff *TableDiff) bool {
+	rest := *diff
+	rest.CommentDiff = nil
+	rest.ColumnDiffs = nil
+	hasComment := diff.CommentDiff != nil
+	for _, colDiff := range diff.ColumnDiffs {
+		for _, change := range colDiff.Changes {
+			if change.Attribute != "comment" {
+				return false
+			}
+		}
+		hasComment = true
+	}
+	return hasComment && isTableDiffEmpty(&rest)
+}
+
+func isDiffEmpty(diff *SchemaDiff) bool {
+	return len(diff.TablesOnlyInSource) == 0 &&
+		len(diff.TablesOnlyInTarget) == 0 &&
+		len(diff.TableDiffs) == 0 &&
+		len(diff.TypesOnlyInSource) == 0 &&
+		len(diff.TypesOnlyInTarget) == 0 &&
+		len(diff.TypeDiffs) == 0 &&
+		len(diff.SequencesOnlyInSource) == 0 &&
+		len(diff.SequencesOnlyInTarget) == 0 &&
+		len(diff.SequenceDiffs) == 0 &&
+		len(diff.RoutinesOnlyInSource) == 0 &&
+		len(diff.RoutinesOnlyInTarget) == 0 &&
+		len(diff.RoutineDiffs) == 0 &&
+		len(diff.EventsOnlyInSource) == 0 &&
+		len(diff.EventsOnlyInTarget) == 0 &&
+		len(diff.EventDiffs) == 0 &&
+		len(diff.ExtensionsOnlyInSource) == 0 &&
+		len(diff.ExtensionsOnlyInTarget) == 0 &&
+		len(diff.ExtensionDiffs) == 0 &&
+		len(diff.ForeignServersOnlyInSource) == 0 &&
+		len(diff.ForeignServersOnlyInTarget) == 0 &&
+		len(diff.ForeignServerDiffs) == 0 &&
+		len(diff.SettingDiffs) == 0 &&
+		len(diff.GrantDiffs) == 0
+}
@@ -0,0 +1,404 @@
+package dbdiff
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ============================================================================
+// VERIFY MIGRATION - Replay a generated migration on a scratch database
+// ============================================================================
+
+// SchemaDDL renders the CREATE statements that rebuild schema on a Postgres
+// or MySQL database: extensions, types and sequences first, then tables with their
+// columns, keys and checks, then indexes and finally foreign keys, so that
+// no statement refers to an object created after it. Triggers and routines
+// are left out; their definitions aren't kept verbatim.
+func SchemaDDL(schema *Schema, driver string) ([]string, error) {
+	family := engineFamily(driver)
+	if family != "postgres" && family != "mysql" {
+		return nil, fmt.Errorf("cannot render DDL for %s", driver)
+	}
+	pg := family == "postgres"
+	quote := func(name string) string {
+		if pg {
+			return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		}
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	quoteList := func(names []string) string {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = quote(name)
+		}
+		return strings.Join(quoted, ", ")
+	}
+
+	// Sequences of identity columns are created with their column
+	identitySeqs := make(map[string]*Sequence)
+	for _, seq := range schema.Sequences {
+		tableName, colName, _ := splitOwnedBy(seq.OwnedBy)
+		if table := schema.Tables[tableName]; table != nil && table.Columns[colName] != nil &&
+			strings.HasPrefix(table.Columns[colName].Identity, "identity") {
+			identitySeqs[seq.OwnedBy] = seq
+		}
+	}
+
+	var stmts []string
+	if pg {
+		for _, name := range getSortedKeys(schema.Extensions) {
+			stmt := fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", quote(name))
+			if v := schema.Extensions[name].Version; v != "" {
+				stmt += " VERSION " + pq.QuoteLiteral(v)
+			}
+			stmts = append(stmts, stmt)
+		}
+		// User mappings need credentials, which are never extracted
+		for _, name := range getSortedKeys(schema.ForeignServers) {
+			server := schema.ForeignServers[name]
+			stmts = append(stmts, fmt.Sprintf("CREATE SERVER %s FOREIGN DATA WRAPPER %s%s", quote(name), quote(server.Wrapper), foreignOptionsClause(server.Options)))
+		}
+		for _, name := range getSortedKeys(schema.Types) {
+			ut := schema.Types[name]
+			if ut.Kind == UserTypeEnum {
+				labels := make([]string, len(ut.Labels))
+				for i, label := range ut.Labels {
+					labels[i] = "'" + strings.ReplaceAll(label, "'", "''") + "'"
+				}
+				stmts = append(stmts, fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", name, strings.Join(labels, ", ")))
+				continue
+			}
+			if ut.Kind == UserTypeComposite {
+				attrs := make([]string, len(ut.Attributes))
+				for i, a := range ut.Attributes {
+					attrs[i] = quote(a.Name) + " " + a.Type
+				}
+				stmts = append(stmts, fmt.Sprintf("CREATE TYPE %s AS (%s)", name, strings.Join(attrs, ", ")))
+				continue
+			}
+			stmt := fmt.Sprintf("CREATE DOMAIN %s AS %s", name, ut.BaseType)
+			if ut.Default != nil {
+				stmt += " DEFAULT " + *ut.Default
+			}
+			if ut.NotNull {
+				stmt += " NOT NULL"
+			}
+			for _, c := range ut.Checks {
+				stmt += " " + checkClause(c)
+			}
+			stmts = append(stmts, stmt)
+		}
+		// AUTO_INCREMENT counters of MySQL come with their columns
+		for _, name := range getSortedKeys(schema.Sequences) {
+			seq := schema.Sequences[name]
+			if identitySeqs[seq.OwnedBy] == seq {
+				continue
+			}
+			stmt := fmt.Sprintf("CREATE SEQUENCE %s AS %s START WITH %d INCREMENT BY %d MINVALUE %d MAXVALUE %d CACHE %d",
+				name, seq.DataType, seq.Start, seq.Increment, seq.MinValue, seq.MaxValue, seq.Cache)
+			if seq.Cycle {
+				stmt += " CYCLE"
+			}
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	var attaches, indexes, foreignKeys []string
+	for _, tableName := range getSortedKeys(schema.Tables) {
+		table := schema.Tables[tableName]
+		var defs []string
+		for _, colName := range getSortedKeys(table.Columns) {
+			col := table.Columns[colName]
+			dataType := col.DataType
+			if pg && col.UserType != "" {
+				dataType = col.UserType
+			} else if pg {
+				dataType = pgColumnType(col)
+			}
+			def := quote(col.Name) + " " + dataType
+			switch {
+			case col.IsGenerated && pg:
+				def += " GENERATED ALWAYS AS (" + col.GenerationExpression + ") " + strings.ToUpper(col.GenerationStorage)
+			case col.IsGenerated:
+				def += " AS (" + col.GenerationExpression + ") " + strings.ToUpper(col.GenerationStorage)
+			case pg && strings.HasPrefix(col.Identity, "identity"):
+				def += " GENERATED " + strings.ToUpper(strings.TrimPrefix(col.Identity, "identity ")) + " AS IDENTITY"
+				if seq := identitySeqs[tableName+"."+colName]; seq != nil {
+					cycle := ""
+					if seq.Cycle {
+						cycle = " CYCLE"
+					}
+					def += fmt.Sprintf(" (SEQUENCE NAME %s START WITH %d INCREMENT BY %d MINVALUE %d MAXVALUE %d CACHE %d%s)",
+						seq.Name, seq.Start, seq.Increment, seq.MinValue, seq.MaxValue, seq.Cache, cycle)
+				}
+			}
+			if !col.IsNullable {
+				def += " NOT NULL"
+			}
+			if col.DefaultValue != nil && !col.IsGenerated {
+				def += " DEFAULT " + *col.DefaultValue
+			}
+			if !pg && schema.Sequences[tableName+"_"+colName+"_seq"] != nil {
+				def += " AUTO_INCREMENT"
+			}
+			defs = append(defs, def)
+		}
+		if pk := table.PrimaryKey; pk != nil {
+			if pg && pk.Name != "" {
+				defs = append(defs, fmt.Sprintf("CONSTRAINT %s PRIMARY KEY (%s)", quote(pk.Name), quoteList(pk.Columns)))
+			} else {
+				defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", quoteList(pk.Columns)))
+			}
+		}
+		for _, name := range getSortedKeys(table.UniqueConstraints) {
+			defs = append(defs, fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", quote(name), quoteList(table.UniqueConstraints[name].Columns)))
+		}
+		for _, name := range getSortedKeys(table.CheckConstraints) {
+			defs = append(defs, fmt.Sprintf("CONSTRAINT %s %s", quote(name), checkClause(table.CheckConstraints[name].Expression)))
+		}
+		stmt := fmt.Sprintf("CREATE TABLE %s (\n    %s\n)", quote(tableName), strings.Join(defs, ",\n    "))
+		if ft := table.Foreign; pg && ft != nil {
+			stmt = fmt.Sprintf("CREATE FOREIGN TABLE %s (\n    %s\n) SERVER %s%s", quote(tableName), strings.Join(defs, ",\n    "),
+				quote(ft.Server), foreignOptionsClause(ft.Options))
+		}
+		if part := table.Partitioning; part != nil {
+			stmt += fmt.Sprintf(" PARTITION BY %s (%s)", strings.ToUpper(part.Strategy), part.Key)
+			switch {
+			case pg:
+				// Postgres partitions are tables of their own, attached below
+				for _, child := range part.Partitions {
+					if schema.Tables[child.Name] != nil {
+						attaches = append(attaches, fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s %s", quote(tableName), quote(child.Name), child.Bound))
+					}
+				}
+			case len(part.Partitions) > 0 && part.Partitions[0].Bound == "":
+				stmt += fmt.Sprintf(" PARTITIONS %d", len(part.Partitions))
+			case len(part.Partitions) > 0:
+				partDefs := make([]string, len(part.Partitions))
+				for i, child := range part.Partitions {
+					partDefs[i] = "PARTITION " + quote(child.Name) + " " + child.Bound
+				}
+				stmt += " (\n    " + strings.Join(partDefs, ",\n    ") + "\n)"
+			}
+		}
+		stmts = append(stmts, stmt)
+		// Inheriting tables already have their parents' columns, so they
+		// are linked once every table exists
+		for _, parent := range table.Inherits {
+			if pg && !strings.Contains(parent, ".") {
+				attaches = append(attaches, fmt.Sprintf("ALTER TABLE %s INHERIT %s", quote(tableName), quote(parent)))
+			}
+		}
+
+		if pg {
+			if table.Comment != "" {
+				stmts = append(stmts, fmt.Sprintf("COMMENT ON TABLE %s IS '%s'", quote(tableName), strings.ReplaceAll(table.Comment, "'", "''")))
+			}
+			for _, colName := range getSortedKeys(table.Columns) {
+				if comment := table.Columns[colName].Comment; comment != "" {
+					stmts = append(stmts, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS '%s'", quote(tableName), quote(colName), strings.ReplaceAll(comment, "'", "''")))
+				}
+			}
+		}
+
+		for _, name := range getSortedKeys(table.Indexes) {
+			idx := table.Indexes[name]
+			indexes = append(indexes, fmt.Sprintf("CREATE %sINDEX %s ON %s %s", indexPrefix(idx, driver), quote(name), quote(tableName), indexClause(idx, driver, quote)))
+		}
+		// USING INDEX needs the index, so the identity follows the indexes
+		if pg && table.ReplicaIdentity != "" && table.ReplicaIdentity != ReplicaIdentityDefault {
+			indexes = append(indexes, fmt.Sprintf("ALTER TABLE %s REPLICA IDENTITY %s", quote(tableName), replicaIdentityClause(table.ReplicaIdentity, quote)))
+		}
+		for _, name := range getSortedKeys(table.RewriteRules) {
+			if rule := table.RewriteRules[name]; pg {
+				indexes = append(indexes, rewriteRuleStatement(quote(name), quote(tableName), rule))
+			}
+		}
+		for _, name := range getSortedKeys(table.ForeignKeys) {
+			fk := table.ForeignKeys[name]
+			stmt := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+				quote(tableName), quote(name), quoteList(fk.Columns), quote(fk.RefTable), quoteList(fk.RefColumns))
+			if fk.OnDelete != "" {
+				stmt += " ON DELETE " + fk.OnDelete
+			}
+			if fk.OnUpdate != "" {
+				stmt += " ON UPDATE " + fk.OnUpdate
+			}
+			foreignKeys = append(foreignKeys, stmt)
+		}
+	}
+	stmts = append(stmts, attaches...)
+	stmts = append(stmts, indexes...)
+	stmts = append(stmts, foreignKeys...)
+
+	if pg {
+		for _, name := range getSortedKeys(schema.Sequences) {
+			if owner := schema.Sequences[name].OwnedBy; owner != "" && identitySeqs[owner] == nil {
+				stmts = append(stmts, fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s", name, owner))
+			}
+		}
+	}
+	return stmts, nil
+}
+
+// checkClause renders a check constraint expression as a CHECK clause;
+// Postgres extraction already includes the keyword
+func checkClause(expr string) string {
+	if strings.HasPrefix(strings.ToUpper(expr), "CHECK") {
+		return expr
+	}
+	return "CHECK (" + expr + ")"
+}
+
+// VerifyResult is the outcome of replaying a migration on a scratch database
+type VerifyResult struct {
+	// Statements is the number of migration statements applied
+	Statements int `json:"statements"`
+	// Error is the error of the statement the migration stopped at
+	Error string `json:"error,omitempty"`
+	// Remaining is what still differs between the migrated scratch database
+	// and the target; empty when the migration converges
+	Remaining *SchemaDiff `json:"remaining"`
+}
+
+// VerifyMigration rebuilds source on the empty scratch database, applies the
+// migration generated for source → target and diffs the result against
+// target again. Anything left over is what the generator missed or only
+// emitted as a comment for manual review. A failing migration statement
+// stops the migration but is reported in the result like the leftovers,
+// as it is just as much a generator bug.
+func VerifyMigration(ctx context.Context, source, target *Schema, driver string, db *sql.DB, opts ExtractOptions, out io.Writer) (*VerifyResult, error) {
+	dialect := getDialect(driver)
+	if dialect == nil {
+		return nil, fmt.Errorf("unsupported driver: %s", driver)
+	}
+	existing, err := dialect.ExtractSchema(ctx, db, ExtractOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("inspecting scratch database: %w", err)
+	}
+	if len(existing.Tables) > 0 {
+		return nil, fmt.Errorf("scratch database is not empty (%d tables); point --scratch at an empty database", len(existing.Tables))
+	}
+
+	clone, err := SchemaDDL(source, driver)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range clone {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("cloning source: %s: %w", statementSummary(stmt), err)
+		}
+	}
+	fmt.Fprintf(out, "Cloned source into scratch database (%d statements)\n", len(clone))
+
+	diff := ComputeDiff(source, target, opts.Filter)
+	stmts := MigrationStatements(GenerateMigrationSQL(diff, engineFamily(driver), MigrationOptions{}), driver)
+	result := &VerifyResult{}
+	result.Statements, err = ApplyMigration(ctx, driver, db, stmts, ApplyOptions{Out: out})
+	if err != nil {
+		result.Error = fmt.Sprintf("%v (%d of %d statements applied)", err, result.Statements, len(stmts))
+	}
+
+	migrated, err := dialect.ExtractSchema(ctx, db, opts)
+	if err != nil {
+		return nil, fmt.Errorf("extracting scratch database: %w", err)
+	}
+	result.Remaining = ComputeDiff(migrated, target, opts.Filter)
+	return result, nil
+}
+
+func runVerifyMigration(args []string) {
+	fs := flag.NewFlagSet("verify-migration", flag.ExitOnError)
+	sourceConn := fs.String("source", "", "Source database connection string, or file with snapshot/ddl")
+	sourceDriver := fs.String("source-driver", "", "Source database driver")
+	targetConn := fs.String("target", "", "Target database connection string, or file with snapshot/ddl")
+	targetDriver := fs.String("target-driver", "", "Target database driver")
+	scratchConn := fs.String("scratch", "", "Connection string of an empty scratch database to replay the migration on")
+	scratchDriver := fs.String("scratch-driver", "", "Scratch database driver: postgres, yugabyte, mysql or tidb (default: the source driver)")
+	format := fs.String("format", FormatPretty, "Output format: pretty or json")
+	noUnicode := fs.Bool("no-unicode", false, "Use ASCII instead of symbols in the pretty output")
+	ff := addFilterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dbdiff verify-migration --source <conn> --source-driver <driver> --target <conn> --target-driver <driver> --scratch <conn> [--scratch-driver <driver>]")
+		fmt.Fprintln(os.Stderr, "\nRebuilds the source schema in an empty scratch database, applies the generated")
+		fmt.Fprintln(os.Stderr, "migration and diffs the result against the target again. Exits 0 if the")
+		fmt.Fprintln(os.Stderr, "migration converges and 2 if differences remain.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *sourceConn == "" || *sourceDriver == "" || *targetConn == "" || *targetDriver == "" || *scratchConn == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *scratchDriver == "" {
+		*scratchDriver = *sourceDriver
+	}
+	if isFileDriver(*scratchDriver) {
+		fmt.Fprintln(os.Stderr, "--scratch-driver is required when the source is a file")
+		os.Exit(1)
+	}
+	if *format != FormatPretty && *format != FormatJSON {
+		fmt.Fprintf(os.Stderr, "Invalid format: %s (expected pretty or json)\n", *format)
+		os.Exit(1)
+	}
+	filter, err := ff.build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts := ff.extractOptions(filter)
+
+	source, err := loadSchema(*sourceDriver, *sourceConn, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading source schema: %v\n", err)
+		os.Exit(1)
+	}
+	target, err := loadSchema(*targetDriver, *targetConn, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading target schema: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := openDB(*scratchDriver, *scratchConn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to scratch database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	result, err := VerifyMigration(context.Background(), source, target, *scratchDriver, db, opts, consoleOutput(os.Stderr, *noUnicode))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	converged := result.Error == "" && isDiffEmpty(result.Remaining)
+	w := consoleOutput(os.Stdout, *noUnicode)
+	if *format == FormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+	} else if converged {
+		fmt.Fprintf(w, "✓ Migration converges: %d statement(s) applied, no differences left\n", result.Statements)
+	} else {
+		fmt.Fprintf(w, "✗ Migration does not converge: %d statement(s) applied\n", result.Statements)
+		if result.Error != "" {
+			fmt.Fprintf(w, "  migration failed: %s\n", result.Error)
+		}
+		fmt.Fprintln(w)
+		printPretty(w, result.Remaining)
+	}
+	if !converged {
+		db.Close()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,75 @@
+package dbdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+// verifyFixture is a schema with a type, a sequence and two tables linked by
+// a foreign key
+func verifyFixture() *Schema {
+	users := newTable("users")
+	users.Columns["id"] = &Column{Name: "id", DataType: "integer"}
+	users.Columns["status"] = &Column{Name: "status", DataType: "USER-DEFINED", UserType: "user_status", IsNullable: true}
+	users.PrimaryKey = &PrimaryKey{Name: "users_pkey", Columns: []string{"id"}}
+	users.Comment = "app's users"
+
+	orders := newTable("orders")
+	orders.Columns["id"] = &Column{Name: "id", DataType: "integer"}
+	orders.Columns["user_id"] = &Column{Name: "user_id", DataType: "integer", IsNullable: true}
+	orders.PrimaryKey = &PrimaryKey{Name: "orders_pkey", Columns: []string{"id"}}
+	orders.Indexes["idx_orders_user"] = &Index{Name: "idx_orders_user", Columns: []string{"user_id"}}
+	orders.ForeignKeys["orders_user_fk"] = &ForeignKey{Name: "orders_user_fk", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}, OnDelete: "CASCADE"}
+
+	return &Schema{
+		Tables:    map[string]*Table{"users": users, "orders": orders},
+		Types:     map[string]*UserType{"user_status": {Name: "user_status", Kind: UserTypeEnum, Labels: []string{"active", "banned"}}},
+		Sequences: map[string]*Sequence{"order_no": {Name: "order_no", DataType: "bigint", Start: 1, Increment: 1, MinValue: 1, MaxValue: 1000, Cache: 1}},
+	}
+}
+
+func TestSchemaDDLCreatesObjectsBeforeTheirUse(t *testing.T) {
+	stmts, err := SchemaDDL(verifyFixture(), "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	position := func(prefix string) int {
+		for i, stmt := range stmts {
+			if strings.HasPrefix(stmt, prefix) {
+				return i
+			}
+		}
+		t.Fatalf("no statement starts with %q in\n%s", prefix, strings.Join(stmts, ";\n"))
+		return -1
+	}
+	typ := position("CREATE TYPE user_status AS ENUM ('active', 'banned')")
+	seq := position("CREATE SEQUENCE order_no AS bigint")
+	users := position(`CREATE TABLE "users"`)
+	orders := position(`CREATE TABLE "orders"`)
+	comment := position(`COMMENT ON TABLE "users" IS 'app''s users'`)
+	index := position(`CREATE INDEX "idx_orders_user" ON "orders" ("user_id")`)
+	fk := position(`ALTER TABLE "orders" ADD CONSTRAINT "orders_user_fk" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE`)
+	if typ > users || seq > orders || comment < users || index < users || fk < index || fk < orders {
+		t.Errorf("statements out of order:\n%s", strings.Join(stmts, ";\n"))
+	}
+	if !strings.Contains(stmts[users], `"status" user_status`) || !strings.Contains(stmts[users], `CONSTRAINT "users_pkey" PRIMARY KEY ("id")`) {
+		t.Errorf("users table = %s, want the enum column and named primary key", stmts[users])
+	}
+}
+
+func TestSchemaDDLQuotesForMySQL(t *testing.T) {
+	table := newTable("order")
+	table.Columns["key"] = &Column{Name: "key", DataType: "varchar(10)"}
+	table.PrimaryKey = &PrimaryKey{Name: "PRIMARY", Columns: []string{"key"}}
+	stmts, err := SchemaDDL(&Schema{Tables: map[string]*Table{"order": table}}, "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "CREATE TABLE `order` (\n    `key` varchar(10) NOT NULL,\n    PRIMARY KEY (`key`)\n)"; len(stmts) != 1 || stmts[0] != want {
+		t.Errorf("statements = %q, want %q", stmts, want)
+	}
+
+	if _, err := SchemaDDL(verifyFixture(), "sqlserver"); err == nil {
+		t.Error("SchemaDDL rendered SQL Server DDL")
+	}
+}